@@ -42,6 +42,24 @@ const (
 	EnvRecyclePolicyTaskStatus = "success"
 	EnvRecyclePolicyNever      = "never"
 
+	// status of the preview environments of the pull requests
+	PreviewEnvStatusCreating = "creating"
+	PreviewEnvStatusRunning  = "running"
+	PreviewEnvStatusFailed   = "failed"
+	PreviewEnvStatusDeleted  = "deleted"
+
+	// in-cluster controllers syncing the gitops environments, and the sync status reported by them
+	GitOpsControllerArgoCD    = "argocd"
+	GitOpsControllerFlux      = "flux"
+	GitOpsSyncStatusSynced    = "Synced"
+	GitOpsSyncStatusOutOfSync = "OutOfSync"
+	GitOpsSyncStatusUnknown   = "Unknown"
+
+	// how the environment snapshots are taken, the rollback snapshot records the env before it's rolled back
+	EnvSnapshotTypeManual   = "manual"
+	EnvSnapshotTypeDeploy   = "deploy"
+	EnvSnapshotTypeRollback = "rollback"
+
 	// 定时器的所属job类型
 	WorkflowCronjob    = "workflow"
 	WorkflowV4Cronjob  = "workflow_v4"
@@ -125,6 +143,8 @@ const (
 	StatusWaitingApprove Status = "waitforapprove"
 	StatusDebugBefore    Status = "debug_before"
 	StatusDebugAfter     Status = "debug_after"
+	// StatusDebugOnFailure means the job failed and its pod is kept for debugging
+	StatusDebugOnFailure Status = "debug_on_failure"
 )
 
 func FailedStatus() []Status {
@@ -185,10 +205,17 @@ const (
 	StepArchive           StepType = "archive"
 	StepArchiveDistribute StepType = "archive_distribute"
 	StepJunitReport       StepType = "junit_report"
+	StepTestReport        StepType = "test_report"
+	StepTestImpact        StepType = "test_impact"
 	StepHtmlReport        StepType = "html_report"
 	StepTarArchive        StepType = "tar_archive"
 	StepSonarCheck        StepType = "sonar_check"
 	StepDistributeImage   StepType = "distribute_image"
+	StepCacheRestore      StepType = "cache_restore"
+	StepCacheSave         StepType = "cache_save"
+	StepSBOMScan          StepType = "sbom_scan"
+	StepImageSign         StepType = "image_sign"
+	StepArtifactPublish   StepType = "artifact_publish"
 	StepDebugBefore       StepType = "debug_before"
 	StepDebugAfter        StepType = "debug_after"
 )
@@ -242,6 +269,14 @@ const (
 	DingTalkApproval ApprovalType = "dingtalk"
 )
 
+// ApprovalTimeoutAction is the decision made automatically when no one handled the approval before timeout
+type ApprovalTimeoutAction string
+
+const (
+	ApprovalTimeoutApprove ApprovalTimeoutAction = "approve"
+	ApprovalTimeoutReject  ApprovalTimeoutAction = "reject"
+)
+
 type ApproveOrReject string
 
 const (
@@ -434,6 +469,7 @@ const (
 
 const (
 	TestJobJunitReportStepName   = "junit-report-step"
+	TestJobTestReportStepName    = "test-report-step"
 	TestJobHTMLReportStepName    = "html-report-step"
 	TestJobArchiveResultStepName = "archive-result-step"
 	TestJobObjectStorageStepName = "object-storage-step"
@@ -447,6 +483,24 @@ const (
 	ForceRun      JobRunPolicy = "force_run"       // force run this job
 )
 
+type MatrixAxisType string
+
+const (
+	MatrixAxisEnv     MatrixAxisType = ""         // axis value is injected into the job as an env var
+	MatrixAxisBuildOS MatrixAxisType = "build_os" // axis value is the id of a basic image the job runs on
+)
+
+// JobFailureReason is the reason why a job task failed, it decides whether the job task should be retried
+type JobFailureReason string
+
+const (
+	JobFailureTimeout    JobFailureReason = "timeout"     // job task timed out
+	JobFailurePodEvicted JobFailureReason = "pod_evicted" // pod of the job task was evicted by kubernetes
+	JobFailureExitCode   JobFailureReason = "exit_code"   // a step of the job task exited with non-zero code
+)
+
+const MaxJobRetryAttempts = 10
+
 const DefaultDeleteDeploymentTimeout = 10 * time.Minute
 
 // Service creation source for openAPI
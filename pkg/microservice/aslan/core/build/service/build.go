@@ -19,6 +19,7 @@ package service
 import (
 	"errors"
 	"fmt"
+	"regexp"
 	"strings"
 	"time"
 
@@ -36,8 +37,12 @@ import (
 	e "github.com/koderover/zadig/pkg/tool/errors"
 	"github.com/koderover/zadig/pkg/tool/log"
 	"github.com/koderover/zadig/pkg/types"
+	"github.com/koderover/zadig/pkg/types/step"
 )
 
+// artifactOutputNameRegex is the same as the output names of the workflow jobs
+var artifactOutputNameRegex = regexp.MustCompile("^[a-zA-Z0-9_]{1,64}$")
+
 type BuildResp struct {
 	ID          string                              `json:"id"`
 	Name        string                              `json:"name"`
@@ -577,6 +582,14 @@ func correctFields(build *commonmodels.Build) error {
 	if build.PostBuild != nil && build.PostBuild.DockerBuild != nil {
 		build.PostBuild.DockerBuild.DockerFile = strings.Trim(build.PostBuild.DockerBuild.DockerFile, " ")
 		build.PostBuild.DockerBuild.WorkDir = strings.Trim(build.PostBuild.DockerBuild.WorkDir, " ")
+		if err := checkDockerBuilder(build.PostBuild.DockerBuild); err != nil {
+			return e.ErrInvalidParam.AddErr(err)
+		}
+	}
+	if build.PostBuild != nil {
+		if err := checkArtifactPublishes(build.PostBuild.ArtifactPublishes); err != nil {
+			return e.ErrInvalidParam.AddErr(err)
+		}
 	}
 	if build.TemplateID == "" {
 		for _, repo := range build.Repos {
@@ -667,3 +680,46 @@ func hasDuplicateTargets(serviceModuleTargets []*commonmodels.ServiceModuleTarge
 	}
 	return false
 }
+
+func checkDockerBuilder(dockerBuild *commonmodels.DockerBuild) error {
+	spec := &step.StepDockerBuildSpec{
+		BuilderMode:        dockerBuild.BuilderMode,
+		BuildKitUnconfined: dockerBuild.BuildKitUnconfined,
+		Platforms:          dockerBuild.Platforms,
+		CacheRepo:          dockerBuild.CacheRepo,
+	}
+	for _, secret := range dockerBuild.Secrets {
+		spec.Secrets = append(spec.Secrets, &step.DockerBuildSecret{ID: secret.ID, Env: secret.Env})
+	}
+	if dockerBuild.SBOMScan != nil && dockerBuild.SBOMScan.Enabled {
+		if !step.IsValidSeverity(dockerBuild.SBOMScan.SeverityThreshold) {
+			return fmt.Errorf("unknown severity threshold %s", dockerBuild.SBOMScan.SeverityThreshold)
+		}
+		format := dockerBuild.SBOMScan.SBOMFormat
+		if format != "" && format != step.SBOMFormatSPDX && format != step.SBOMFormatCycloneDX {
+			return fmt.Errorf("unknown sbom format %s", format)
+		}
+	}
+	if dockerBuild.SigningKeyID != "" {
+		if _, err := commonrepo.NewImageSigningKeyColl().FindByID(dockerBuild.SigningKeyID); err != nil {
+			return fmt.Errorf("image signing key %s not found", dockerBuild.SigningKeyID)
+		}
+	}
+	return spec.ValidateBuilder()
+}
+
+func checkArtifactPublishes(publishes []*commonmodels.ArtifactPublish) error {
+	for _, publish := range publishes {
+		registry, err := commonrepo.NewArtifactRegistryColl().FindByID(publish.RegistryID)
+		if err != nil {
+			return fmt.Errorf("artifact registry %s not found", publish.RegistryID)
+		}
+		if publish.OutputName != "" && !artifactOutputNameRegex.MatchString(publish.OutputName) {
+			return fmt.Errorf("output name %s must match %s", publish.OutputName, artifactOutputNameRegex)
+		}
+		if registry.Type == step.ArtifactTypeOCI && (publish.Repository == "" || len(publish.Files) == 0) {
+			return fmt.Errorf("repository and files are required by the oci artifact of registry %s", registry.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ArtifactRegistry is a maven, npm, pypi or oci registry the artifact publish step of the build jobs publishes to.
+// Type is one of step.ArtifactTypeMaven, step.ArtifactTypeNpm, step.ArtifactTypePyPI and step.ArtifactTypeOCI.
+type ArtifactRegistry struct {
+	ID   primitive.ObjectID `bson:"_id,omitempty"         json:"id"`
+	Name string             `bson:"name"                  json:"name"`
+	Type string             `bson:"type"                  json:"type"`
+	// Address is the url of the repository, or the host of the oci registry
+	Address string `bson:"address"               json:"address"`
+	// Password is used as the token if Username is empty
+	Username   string `bson:"username"              json:"username"`
+	Password   string `bson:"password"              json:"password"`
+	Insecure   bool   `bson:"insecure"              json:"insecure"`
+	UpdatedBy  string `bson:"updated_by"            json:"updated_by"`
+	UpdateTime int64  `bson:"update_time"           json:"update_time"`
+}
+
+func (ArtifactRegistry) TableName() string {
+	return "artifact_registry"
+}
@@ -84,6 +84,9 @@ type PreBuild struct {
 	StrategyID string `bson:"strategy_id"                     json:"strategy_id"`
 	// UseHostDockerDaemon determines is dockerDaemon on host node is used in pod
 	UseHostDockerDaemon bool `bson:"use_host_docker_daemon" json:"use_host_docker_daemon"`
+	// Infrastructure is kubernetes by default, the build runs on a vm runner whose labels contain all the VMLabels if it is vm
+	Infrastructure string   `bson:"infrastructure,omitempty" json:"infrastructure,omitempty"`
+	VMLabels       []string `bson:"vm_labels,omitempty"      json:"vm_labels,omitempty"`
 
 	// TODO: Deprecated.
 	Namespace string `bson:"namespace"                       json:"namespace"`
@@ -104,9 +107,25 @@ type PostBuild struct {
 	DockerBuild         *DockerBuild         `bson:"docker_build,omitempty" json:"docker_build"`
 	ObjectStorageUpload *ObjectStorageUpload `bson:"object_storage_upload"  json:"object_storage_upload"`
 	FileArchive         *FileArchive         `bson:"file_archive,omitempty" json:"file_archive,omitempty"`
+	ArtifactPublishes   []*ArtifactPublish   `bson:"artifact_publishes"     json:"artifact_publishes"`
 	Scripts             string               `bson:"scripts"                json:"scripts"`
 }
 
+// ArtifactPublish publishes the build outputs to an artifact registry, the coordinates of the published artifacts
+// are recorded in the job output OutputName
+type ArtifactPublish struct {
+	RegistryID string `bson:"registry_id"   json:"registry_id"`
+	// Path is the dir of the maven or npm project, relative to the workspace
+	Path string `bson:"path"          json:"path"`
+	// Files are the globs of the pypi distributions or the files of the oci artifact
+	Files []string `bson:"files"         json:"files"`
+	// Repository, Tag and ArtifactType describe the oci artifact, variables are supported
+	Repository   string `bson:"repository"    json:"repository"`
+	Tag          string `bson:"tag"           json:"tag"`
+	ArtifactType string `bson:"artifact_type" json:"artifact_type"`
+	OutputName   string `bson:"output_name"   json:"output_name"`
+}
+
 type FileArchive struct {
 	FileLocation string `bson:"file_location" json:"file_location"`
 }
@@ -130,6 +149,34 @@ type DockerBuild struct {
 	TemplateID string `bson:"template_id"            json:"template_id"`
 	// TemplateName is the name of the template dockerfile
 	TemplateName string `bson:"template_name"        json:"template_name"`
+	// BuilderMode is one of docker and buildkit, buildkit builds the image without docker daemon
+	BuilderMode string `bson:"builder_mode"          json:"builder_mode"`
+	// BuildKitUnconfined disables the seccomp and apparmor profiles of the rootless buildkitd sidecar
+	BuildKitUnconfined bool `bson:"buildkit_unconfined"   json:"buildkit_unconfined"`
+	// Platforms are the comma separated target platforms, e.g. linux/amd64,linux/arm64
+	Platforms string `bson:"platforms"               json:"platforms"`
+	// CacheRepo is the registry repository the layer cache is imported from and exported to
+	CacheRepo string `bson:"cache_repo"              json:"cache_repo"`
+	// Secrets are mounted in RUN --mount=type=secret with the values of the build envs
+	Secrets []*DockerBuildSecret `bson:"secrets"   json:"secrets"`
+	// SBOMScan generates the sbom of the built image and scans its vulnerabilities if it is enabled
+	SBOMScan *SBOMScan `bson:"sbom_scan"            json:"sbom_scan"`
+	// SigningKeyID signs the built image with the image signing key if it is set
+	SigningKeyID string `bson:"signing_key_id"    json:"signing_key_id"`
+}
+
+type SBOMScan struct {
+	Enabled    bool   `bson:"enabled"     json:"enabled"`
+	SBOMFormat string `bson:"sbom_format" json:"sbom_format"`
+	// SeverityThreshold fails the build if any vulnerability of the severity or higher is found
+	SeverityThreshold string `bson:"severity_threshold" json:"severity_threshold"`
+	// VulnDBURL is the listing url of the locally mirrored vulnerability database
+	VulnDBURL string `bson:"vuln_db_url" json:"vuln_db_url"`
+}
+
+type DockerBuildSecret struct {
+	ID  string `bson:"id"  json:"id"`
+	Env string `bson:"env" json:"env"`
 }
 
 type JenkinsBuild struct {
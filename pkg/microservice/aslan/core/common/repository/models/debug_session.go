@@ -0,0 +1,41 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DebugSession is a terminal session into the pod of a workflow job task, the transcript is the terminal output
+// with the secret envs masked
+type DebugSession struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty"  json:"id"`
+	WorkflowName string             `bson:"workflow_name"  json:"workflow_name"`
+	TaskID       int64              `bson:"task_id"        json:"task_id"`
+	JobName      string             `bson:"job_name"       json:"job_name"`
+	PodName      string             `bson:"pod_name"       json:"pod_name"`
+	UserName     string             `bson:"user_name"      json:"user_name"`
+	StartTime    int64              `bson:"start_time"     json:"start_time"`
+	EndTime      int64              `bson:"end_time"       json:"end_time"`
+	Transcript   string             `bson:"transcript"     json:"transcript"`
+	// Truncated means the transcript is above the size limit, and only its beginning is kept
+	Truncated bool `bson:"truncated"      json:"truncated"`
+}
+
+func (DebugSession) TableName() string {
+	return "workflow_debug_session"
+}
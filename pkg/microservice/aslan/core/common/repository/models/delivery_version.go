@@ -45,6 +45,8 @@ type DeliveryVersion struct {
 	CreatedBy           string                   `bson:"created_by"              json:"createdBy"`
 	CreatedAt           int64                    `bson:"created_at"              json:"created_at"`
 	DeletedAt           int64                    `bson:"deleted_at"              json:"deleted_at"`
+	// ImageScanIDs are the ids of the sbom scans of the images in the version, the scans are saved in the image_scan collection
+	ImageScanIDs []string `bson:"image_scan_ids"          json:"image_scan_ids"`
 }
 
 func (DeliveryVersion) TableName() string {
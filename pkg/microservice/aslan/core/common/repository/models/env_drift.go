@@ -0,0 +1,48 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// EnvDrift is the drift of the resources of an environment from the manifests last rendered by zadig,
+// it's refreshed by every detection
+type EnvDrift struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty"  json:"id"`
+	ProjectName string             `bson:"project_name"   json:"project_name"`
+	EnvName     string             `bson:"env_name"       json:"env_name"`
+	Production  bool               `bson:"production"     json:"production"`
+	Resources   []*DriftResource   `bson:"resources"      json:"resources"`
+	DetectTime  int64              `bson:"detect_time"    json:"detect_time"`
+}
+
+type DriftResource struct {
+	ServiceName string `bson:"service_name"  json:"service_name"`
+	Kind        string `bson:"kind"          json:"kind"`
+	Name        string `bson:"name"          json:"name"`
+	// Missing is true if the resource is deleted from the cluster
+	Missing bool `bson:"missing"       json:"missing"`
+	// Fields are the paths of the fields changed in the cluster, like spec.template.spec.containers[app].image
+	Fields []string `bson:"fields"        json:"fields"`
+	// DetectTime is the time the drift of the resource is first detected
+	DetectTime int64 `bson:"detect_time"   json:"detect_time"`
+}
+
+func (EnvDrift) TableName() string {
+	return "env_drift"
+}
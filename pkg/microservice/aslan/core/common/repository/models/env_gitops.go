@@ -0,0 +1,60 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+type EnvGitOpsConfig struct {
+	Enabled       bool   `bson:"enabled"               json:"enabled"`
+	CodehostID    int    `bson:"codehost_id"           json:"codehost_id"`
+	RepoOwner     string `bson:"repo_owner"            json:"repo_owner"`
+	RepoNamespace string `bson:"repo_namespace"        json:"repo_namespace"`
+	RepoName      string `bson:"repo_name"             json:"repo_name"`
+	Branch        string `bson:"branch"                json:"branch"`
+	// Path is the dir the env is rendered to, the manifests of a k8s yaml service are written to <path>/<service>.yaml
+	// and the values of a helm release to <path>/<release>/values.yaml
+	Path string `bson:"path"                  json:"path"`
+	// MergeRequest opens a merge request to the branch for each change instead of pushing to it
+	MergeRequest bool `bson:"merge_request"         json:"merge_request"`
+	// Controller is the in-cluster gitops controller syncing the path, argocd or flux, the sync status of the env is
+	// read from its argocd application or flux kustomization
+	Controller           string `bson:"controller"            json:"controller"`
+	ApplicationNamespace string `bson:"application_namespace" json:"application_namespace"`
+	ApplicationName      string `bson:"application_name"      json:"application_name"`
+}
+
+type EnvGitOpsStatus struct {
+	// Revision is the last commit pushed by zadig, MergeRequestURL is set if it's waiting to be merged
+	Revision        string `bson:"revision"          json:"revision"`
+	MergeRequestURL string `bson:"merge_request_url" json:"merge_request_url"`
+	CommitTime      int64  `bson:"commit_time"       json:"commit_time"`
+	// SyncStatus, SyncedRevision and HealthStatus are reported by the gitops controller
+	SyncStatus     string `bson:"sync_status"       json:"sync_status"`
+	SyncedRevision string `bson:"synced_revision"   json:"synced_revision"`
+	HealthStatus   string `bson:"health_status"     json:"health_status"`
+	Message        string `bson:"message"           json:"message"`
+	UpdateTime     int64  `bson:"update_time"       json:"update_time"`
+}
+
+func (c *EnvGitOpsConfig) IsEnabled() bool {
+	return c != nil && c.Enabled
+}
+
+func (c *EnvGitOpsConfig) GetRepoNamespace() string {
+	if c.RepoNamespace != "" {
+		return c.RepoNamespace
+	}
+	return c.RepoOwner
+}
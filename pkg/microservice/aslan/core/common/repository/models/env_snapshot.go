@@ -0,0 +1,64 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// EnvSnapshot is the state of an environment at a point in time, the env can be rolled back to it as a whole
+type EnvSnapshot struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty"            json:"id"`
+	ProjectName string             `bson:"project_name"             json:"project_name"`
+	EnvName     string             `bson:"env_name"                 json:"env_name"`
+	Production  bool               `bson:"production"               json:"production"`
+	Type        string             `bson:"type"                     json:"type"`
+	Description string             `bson:"description"              json:"description"`
+	// WorkflowName and TaskID are the workflow task whose deploy job took the snapshot
+	WorkflowName string `bson:"workflow_name,omitempty"  json:"workflow_name,omitempty"`
+	TaskID       int64  `bson:"task_id,omitempty"        json:"task_id,omitempty"`
+	// Services are the services of the env with their revisions and images
+	Services              [][]*ProductService `bson:"services"                 json:"services"`
+	ServiceDeployStrategy map[string]string   `bson:"service_deploy_strategy"  json:"service_deploy_strategy"`
+	// RenderSet keeps the env-level variables, the service variables and the helm values of the env
+	RenderSet *RenderSet `bson:"render_set"               json:"render_set"`
+	// ServiceManifests are the rendered yaml of the k8s services and the merged values of the helm services
+	ServiceManifests []*SnapshotServiceManifest `bson:"service_manifests"        json:"service_manifests"`
+	// EnvResources refer to the versions of the configmaps, secrets, ingresses and pvcs managed in the env
+	EnvResources []*SnapshotEnvResource `bson:"env_resources"            json:"env_resources"`
+	CreatedBy    string                 `bson:"created_by"               json:"created_by"`
+	CreateTime   int64                  `bson:"create_time"              json:"create_time"`
+}
+
+type SnapshotServiceManifest struct {
+	ServiceName string `bson:"service_name"  json:"service_name"`
+	// ReleaseName is set for the helm charts deployed from chart repositories
+	ReleaseName string `bson:"release_name"  json:"release_name"`
+	Type        string `bson:"type"          json:"type"`
+	Yaml        string `bson:"yaml"          json:"yaml"`
+}
+
+type SnapshotEnvResource struct {
+	Type string `bson:"type"         json:"type"`
+	Name string `bson:"name"         json:"name"`
+	// ResourceID is the id of the version of the resource in env_resource
+	ResourceID string `bson:"resource_id"  json:"resource_id"`
+}
+
+func (EnvSnapshot) TableName() string {
+	return "env_snapshot"
+}
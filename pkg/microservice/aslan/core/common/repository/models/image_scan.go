@@ -0,0 +1,45 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/koderover/zadig/pkg/types/step"
+)
+
+// ImageScan is the sbom and the vulnerabilities of an image found by the sbom_scan step of workflow v4,
+// the ids of the scans are attached to the delivery versions containing the image.
+type ImageScan struct {
+	ID                primitive.ObjectID           `bson:"_id,omitempty"        json:"id,omitempty"`
+	ImageName         string                       `bson:"image_name"           json:"image_name"`
+	ProjectName       string                       `bson:"project_name"         json:"project_name"`
+	WorkflowName      string                       `bson:"workflow_name"        json:"workflow_name"`
+	TaskID            int64                        `bson:"task_id"              json:"task_id"`
+	JobName           string                       `bson:"job_name"             json:"job_name"`
+	SBOMFormat        string                       `bson:"sbom_format"          json:"sbom_format"`
+	SBOM              string                       `bson:"sbom"                 json:"sbom"`
+	SeverityThreshold string                       `bson:"severity_threshold"   json:"severity_threshold"`
+	Passed            bool                         `bson:"passed"               json:"passed"`
+	SeverityCount     map[string]int               `bson:"severity_count"       json:"severity_count"`
+	Findings          []*step.VulnerabilityFinding `bson:"findings"             json:"findings"`
+	CreatedAt         int64                        `bson:"created_at"           json:"created_at"`
+}
+
+func (ImageScan) TableName() string {
+	return "image_scan"
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/koderover/zadig/pkg/types/step"
+)
+
+// ImageSigningKey is used by the image sign step to sign images, and by the signature policy of the deploy jobs to
+// verify them. Mode is one of step.ImageSignModeKey and step.ImageSignModeKeyless.
+type ImageSigningKey struct {
+	ID   primitive.ObjectID `bson:"_id,omitempty"         json:"id"`
+	Name string             `bson:"name"                  json:"name"`
+	Mode string             `bson:"mode"                  json:"mode"`
+	// key mode, the key pair is generated by `cosign generate-key-pair`
+	PublicKey  string `bson:"public_key"            json:"public_key"`
+	PrivateKey string `bson:"private_key"           json:"private_key"`
+	Password   string `bson:"password"              json:"password"`
+	// keyless mode, signatures are trusted if the certificates are issued by FulcioRootCA to Identity from OIDCIssuer,
+	// and the signatures are recorded in the rekor of RekorPublicKey while the certificates are valid
+	FulcioURL        string `bson:"fulcio_url"            json:"fulcio_url"`
+	FulcioRootCA     string `bson:"fulcio_root_ca"        json:"fulcio_root_ca"`
+	OIDCIssuer       string `bson:"oidc_issuer"           json:"oidc_issuer"`
+	Identity         string `bson:"identity"              json:"identity"`
+	IdentityTokenEnv string `bson:"identity_token_env"    json:"identity_token_env"`
+	RekorURL         string `bson:"rekor_url"             json:"rekor_url"`
+	RekorPublicKey   string `bson:"rekor_public_key"      json:"rekor_public_key"`
+	UpdatedBy        string `bson:"updated_by"            json:"updated_by"`
+	UpdateTime       int64  `bson:"update_time"           json:"update_time"`
+}
+
+func (ImageSigningKey) TableName() string {
+	return "image_signing_key"
+}
+
+// ToStepSigningKey returns the key used by the image sign step without the private key and the password
+func (k *ImageSigningKey) ToStepSigningKey() *step.ImageSigningKey {
+	return &step.ImageSigningKey{
+		Name:             k.Name,
+		Mode:             k.Mode,
+		FulcioURL:        k.FulcioURL,
+		RekorURL:         k.RekorURL,
+		IdentityTokenEnv: k.IdentityTokenEnv,
+	}
+}
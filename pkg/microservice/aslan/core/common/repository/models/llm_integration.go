@@ -23,6 +23,8 @@ import (
 type LLMIntegration struct {
 	ID          primitive.ObjectID `bson:"_id,omitempty"  json:"id"`
 	Name        string             `bson:"name"           json:"name"`
+	Provider    string             `bson:"provider"       json:"provider"`
+	Model       string             `bson:"model"          json:"model"`
 	Token       string             `bson:"token"          json:"token"`
 	BaseURL     string             `bson:"base_url"       json:"base_url"`
 	EnableProxy bool               `bson:"enable_proxy"   json:"enable_proxy"`
@@ -30,6 +32,14 @@ type LLMIntegration struct {
 	UpdateTime  int64              `bson:"update_time"    json:"update_time"`
 }
 
+// GetProvider returns the provider supported by pkg/tool/llm, the name was used as the provider before
+func (llm LLMIntegration) GetProvider() string {
+	if llm.Provider == "" {
+		return llm.Name
+	}
+	return llm.Provider
+}
+
 func (llm LLMIntegration) TableName() string {
 	return "llm_integration"
 }
@@ -0,0 +1,41 @@
+/*
+ * Copyright 2023 The KodeRover Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// NativeApprovalInstance is a pending native approval shared by all aslan replicas
+type NativeApprovalInstance struct {
+	ID primitive.ObjectID `bson:"_id,omitempty"   json:"id"`
+	// Key is {workflow name}-{task id}-{stage name} for workflow stages, or the instance code for release plans
+	Key      string          `bson:"key"             json:"key"`
+	Approval *NativeApproval `bson:"approval"        json:"approval"`
+	// Version is increased on every update, an update based on a stale version is rejected
+	Version    int64 `bson:"version"         json:"version"`
+	CreateTime int64 `bson:"create_time"     json:"create_time"`
+	UpdateTime int64 `bson:"update_time"     json:"update_time"`
+	// ExpireAt is when the approval is deleted by the ttl index, it is extended on every update
+	ExpireAt time.Time `bson:"expire_at"       json:"expire_at"`
+}
+
+func (NativeApprovalInstance) TableName() string {
+	return "native_approval"
+}
@@ -42,6 +42,7 @@ type Notification struct {
 	IsWorkflowV4 bool                `bson:"is_workflowv4"                json:"is_workflowv4"`
 	ErrInfo      string              `bson:"err_info"                     json:"err_info"`
 	PrTask       *PrTaskInfo         `bson:"pr_task_info,omitempty"       json:"pr_task_info,omitempty"`
+	PreviewEnv   *PreviewEnvInfo     `bson:"preview_env,omitempty"        json:"preview_env,omitempty"`
 	Label        string              `bson:"label"                        json:"label"  `
 	Revision     string              `bson:"revision"                     json:"revision"`
 	RepoOwner    string              `bson:"repo_owner"                   json:"repo_owner"`
@@ -55,6 +56,32 @@ type PrTaskInfo struct {
 	ProductName      string `bson:"product_name,omitempty"              json:"product_name,omitempty"`
 }
 
+// PreviewEnvInfo is the preview environment of the pull request, the comment of the notification only shows it
+type PreviewEnvInfo struct {
+	ProjectName string   `bson:"project_name"   json:"project_name"`
+	EnvName     string   `bson:"env_name"       json:"env_name"`
+	Status      string   `bson:"status"         json:"status"`
+	Error       string   `bson:"error"          json:"error"`
+	AccessURL   string   `bson:"access_url"     json:"access_url"`
+	IdleTTL     int64    `bson:"idle_ttl"       json:"idle_ttl"`
+	Services    []string `bson:"services"       json:"services"`
+}
+
+func (p PreviewEnvInfo) StatusVerbose() string {
+	switch p.Status {
+	case config.PreviewEnvStatusCreating:
+		return "创建中"
+	case config.PreviewEnvStatusRunning:
+		return "运行中"
+	case config.PreviewEnvStatusFailed:
+		return "失败"
+	case config.PreviewEnvStatusDeleted:
+		return "已销毁"
+	default:
+		return "未知"
+	}
+}
+
 type NotificationTask struct {
 	ProductName         string            `bson:"product_name"            json:"product_name"`
 	WorkflowName        string            `bson:"workflow_name"           json:"workflow_name"`
@@ -101,6 +128,10 @@ func (n *Notification) ToString() string {
 }
 
 func (n *Notification) CreateCommentBody() (comment string, err error) {
+	if n.PreviewEnv != nil {
+		return n.createPreviewEnvCommentBody()
+	}
+
 	hasTest := false
 	for _, task := range n.Tasks {
 		task.EncodedDisplayName = url.QueryEscape(task.WorkflowDisplayName)
@@ -191,6 +222,29 @@ func (n *Notification) CreateCommentBody() (comment string, err error) {
 	return buffer.String(), nil
 }
 
+func (n *Notification) createPreviewEnvCommentBody() (string, error) {
+	tmplSource := "预览环境：[{{.Env.EnvName}}]({{.BaseURI}}/v1/projects/detail/{{.Env.ProjectName}}/envs/detail?envName={{.Env.EnvName}}) 状态：{{.Env.StatusVerbose}} \n\n" +
+		"{{if .Env.Error}}错误信息：{{.Env.Error}} \n\n{{end}}" +
+		"{{if and .Env.AccessURL (ne .Env.Status .Deleted)}}访问地址：{{.Env.AccessURL}} \n\n{{end}}" +
+		"{{if .Env.Services}}更新的服务：{{range $i, $svc := .Env.Services}}{{if $i}}, {{end}}{{$svc}}{{end}} \n\n{{end}}" +
+		"{{if ne .Env.Status .Deleted}}环境在 PR 关闭或合并后销毁{{if .Env.IdleTTL}}，{{.Env.IdleTTL}} 小时无更新后自动回收{{end}}{{end}}"
+
+	tmpl := template.Must(template.New("comment").Parse(tmplSource))
+	buffer := bytes.NewBufferString("")
+	if err := tmpl.Execute(buffer, struct {
+		Env     *PreviewEnvInfo
+		BaseURI string
+		Deleted string
+	}{
+		n.PreviewEnv,
+		n.BaseURI,
+		config.PreviewEnvStatusDeleted,
+	}); err != nil {
+		return "", err
+	}
+	return buffer.String(), nil
+}
+
 func getEnvRecyclePolicy(policy string) string {
 	switch policy {
 	case config.EnvRecyclePolicyAlways:
@@ -0,0 +1,73 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PreviewEnvConfig creates a preview environment copied from the base environment for every pull request matched by
+// the hook, the services changed by the pull request are built by the workflow and deployed to it.
+type PreviewEnvConfig struct {
+	Enabled bool   `bson:"enabled"        json:"enabled"`
+	BaseEnv string `bson:"base_env"       json:"base_env"`
+	// IdleTTL is the hours the preview environment is kept without any push to the pull request,
+	// 0 means it is only deleted when the pull request is closed or merged
+	IdleTTL int64 `bson:"idle_ttl"       json:"idle_ttl"`
+	// URLTemplate is the access url commented on the pull request, {{.EnvName}}, {{.Namespace}} and {{.PR}} are rendered
+	URLTemplate string `bson:"url_template"   json:"url_template"`
+	// ServicePaths are the source paths of the services in the repo, a service is changed if any of its paths
+	// matches the changed files, the services without paths are changed by any change of the repo they are built from
+	ServicePaths []*PreviewServicePath `bson:"service_paths"  json:"service_paths"`
+}
+
+type PreviewServicePath struct {
+	ServiceName   string `bson:"service_name"    json:"service_name"`
+	ServiceModule string `bson:"service_module"  json:"service_module"`
+	// Paths are the globs of the source files, "**" matches any number of directories
+	Paths []string `bson:"paths"           json:"paths"`
+}
+
+// PreviewEnv is the preview environment of a pull request, it is deleted when the pull request is closed or merged,
+// or when it is idle for longer than the idle ttl
+type PreviewEnv struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty"     json:"id"`
+	ProjectName   string             `bson:"project_name"      json:"project_name"`
+	WorkflowName  string             `bson:"workflow_name"     json:"workflow_name"`
+	EnvName       string             `bson:"env_name"          json:"env_name"`
+	BaseEnv       string             `bson:"base_env"          json:"base_env"`
+	CodehostID    int                `bson:"codehost_id"       json:"codehost_id"`
+	RepoOwner     string             `bson:"repo_owner"        json:"repo_owner"`
+	RepoNamespace string             `bson:"repo_namespace"    json:"repo_namespace"`
+	RepoName      string             `bson:"repo_name"         json:"repo_name"`
+	PrID          int                `bson:"pr_id"             json:"pr_id"`
+	Status        string             `bson:"status"            json:"status"`
+	Error         string             `bson:"error"             json:"error"`
+	AccessURL     string             `bson:"access_url"        json:"access_url"`
+	IdleTTL       int64              `bson:"idle_ttl"          json:"idle_ttl"`
+	// Services are the services deployed to the preview environment by the last triggered task
+	Services       []string `bson:"services"          json:"services"`
+	BaseURI        string   `bson:"base_uri"          json:"base_uri"`
+	NotificationID string   `bson:"notification_id"   json:"notification_id"`
+	LastActiveTime int64    `bson:"last_active_time"  json:"last_active_time"`
+	CreateTime     int64    `bson:"create_time"       json:"create_time"`
+	UpdateTime     int64    `bson:"update_time"       json:"update_time"`
+}
+
+func (PreviewEnv) TableName() string {
+	return "preview_env"
+}
@@ -74,6 +74,12 @@ type Product struct {
 
 	// New Since v1.19.0, env sleep configs
 	PreSleepStatus map[string]int `bson:"pre_sleep_status" json:"pre_sleep_status"`
+	// SleepStatus records the state of the workloads before the env sleeps, it's restored when the env wakes up
+	SleepStatus *EnvSleepStatus `bson:"sleep_status,omitempty" json:"sleep_status,omitempty"`
+
+	// GitOps commits the rendered manifests and values of the env to a git repository instead of applying them
+	GitOps       *EnvGitOpsConfig `bson:"gitops,omitempty"        json:"gitops,omitempty"`
+	GitOpsStatus *EnvGitOpsStatus `bson:"gitops_status,omitempty" json:"gitops_status,omitempty"`
 
 	// For production environment
 	Production bool   `json:"production" bson:"production"`
@@ -85,6 +91,7 @@ type NotificationEvent string
 const (
 	NotificationEventAnalyzerNoraml   NotificationEvent = "notification_event_analyzer_normal"
 	NotificationEventAnalyzerAbnormal NotificationEvent = "notification_event_analyzer_abnormal"
+	NotificationEventEnvDrift         NotificationEvent = "notification_event_env_drift"
 )
 
 type WebHookType string
@@ -143,6 +150,38 @@ type RenderInfo struct {
 	Description string `bson:"description"              json:"description"`
 }
 
+type EnvSleepStatus struct {
+	Workloads []*SleepWorkload `bson:"workloads"                json:"workloads"`
+	HPAs      []*SleepHPA      `bson:"hpas"                     json:"hpas"`
+	CronJobs  []*SleepCronJob  `bson:"cronjobs"                 json:"cronjobs"`
+	SleepTime int64            `bson:"sleep_time"               json:"sleep_time"`
+}
+
+// SleepWorkload is the replicas of a deployment or statefulset before the env sleeps
+type SleepWorkload struct {
+	Kind      string `bson:"kind"                     json:"kind"`
+	Namespace string `bson:"namespace"                json:"namespace"`
+	Name      string `bson:"name"                     json:"name"`
+	Replicas  int    `bson:"replicas"                 json:"replicas"`
+}
+
+// SleepHPA is the replicas range of a hpa before the env sleeps, it's pinned to 1 while sleeping so that the hpa
+// is disabled by the zero replicas of its target even if it allows scaling to zero
+type SleepHPA struct {
+	Namespace   string `bson:"namespace"                json:"namespace"`
+	Name        string `bson:"name"                     json:"name"`
+	TargetKind  string `bson:"target_kind"              json:"target_kind"`
+	TargetName  string `bson:"target_name"              json:"target_name"`
+	MinReplicas int32  `bson:"min_replicas"             json:"min_replicas"`
+	MaxReplicas int32  `bson:"max_replicas"             json:"max_replicas"`
+}
+
+// SleepCronJob is the suspend state of a cronjob before the env sleeps, cronjobs suspended before are not resumed
+type SleepCronJob struct {
+	Name      string `bson:"name"                     json:"name"`
+	Suspended bool   `bson:"suspended"                json:"suspended"`
+}
+
 type ProductAuth struct {
 	Type        ProductAuthType     `bson:"type"          json:"type"`
 	Name        string              `bson:"name"          json:"name"`
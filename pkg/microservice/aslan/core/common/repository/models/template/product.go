@@ -76,6 +76,8 @@ type Product struct {
 	GlobalVariables            []*commontypes.ServiceVariableKV `bson:"global_variables,omitempty"          json:"global_variables,omitempty"`                       // New since 1.18.0 used to store global variables for test services
 	ProductionGlobalVariables  []*commontypes.ServiceVariableKV `bson:"production_global_variables,omitempty"          json:"production_global_variables,omitempty"` // New since 1.18.0 used to store global variables for production services
 	Public                     bool                             `bson:"public,omitempty"                    json:"public"`
+	// DisableEnvDriftDetection skips the envs of the project in the periodic drift detection
+	DisableEnvDriftDetection bool `bson:"disable_env_drift_detection"         json:"disable_env_drift_detection"`
 }
 
 type ServiceInfo struct {
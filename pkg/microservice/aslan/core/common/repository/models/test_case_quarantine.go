@@ -0,0 +1,44 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// TestCaseQuarantine is a case of a test which is detected as flaky or added by users.
+// The failures of the quarantined cases do not fail the testing jobs.
+type TestCaseQuarantine struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty"        json:"id,omitempty"`
+	ProjectName string             `bson:"project_name"         json:"project_name"`
+	TestName    string             `bson:"test_name"            json:"test_name"`
+	// CaseName is the full name of the case in the format of {suite}.{name}
+	CaseName    string `bson:"case_name"            json:"case_name"`
+	Quarantined bool   `bson:"quarantined"          json:"quarantined"`
+	Reason      string `bson:"reason"               json:"reason"`
+	// Flaky is set if the case passed and failed on the same commit, or passed after reruns
+	Flaky           bool   `bson:"flaky"                json:"flaky"`
+	FlakyCount      int    `bson:"flaky_count"          json:"flaky_count"`
+	LastFlakyCommit string `bson:"last_flaky_commit"    json:"last_flaky_commit"`
+	LastFlakyBranch string `bson:"last_flaky_branch"    json:"last_flaky_branch"`
+	LastFlakyTime   int64  `bson:"last_flaky_time"      json:"last_flaky_time"`
+	UpdatedBy       string `bson:"updated_by"           json:"updated_by"`
+	CreateTime      int64  `bson:"create_time"          json:"create_time"`
+	UpdateTime      int64  `bson:"update_time"          json:"update_time"`
+}
+
+func (TestCaseQuarantine) TableName() string {
+	return "test_case_quarantine"
+}
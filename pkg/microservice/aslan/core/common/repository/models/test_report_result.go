@@ -0,0 +1,43 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/koderover/zadig/pkg/types/step"
+)
+
+// TestReportResult is the normalized test report of a testing job task, parsed by the test_report step of workflow v4
+type TestReportResult struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty"        json:"id,omitempty"`
+	ProjectName  string             `bson:"project_name"         json:"project_name"`
+	WorkflowName string             `bson:"workflow_name"        json:"workflow_name"`
+	TaskID       int64              `bson:"task_id"              json:"task_id"`
+	JobName      string             `bson:"job_name"             json:"job_name"`
+	TestName     string             `bson:"test_name"            json:"test_name"`
+	// the code of the first repository of the test, flaky cases are detected by the results of the same commit
+	RepoName        string `bson:"repo_name"            json:"repo_name"`
+	Branch          string `bson:"branch"               json:"branch"`
+	CommitID        string `bson:"commit_id"            json:"commit_id"`
+	step.TestReport `bson:",inline" json:",inline"`
+	CreatedAt       int64 `bson:"created_at"           json:"created_at"`
+}
+
+func (TestReportResult) TableName() string {
+	return "test_report_result"
+}
@@ -21,6 +21,7 @@ import (
 
 	"github.com/koderover/zadig/pkg/setting"
 	"github.com/koderover/zadig/pkg/types"
+	"github.com/koderover/zadig/pkg/types/step"
 )
 
 type Testing struct {
@@ -38,6 +39,12 @@ type Testing struct {
 	UpdateBy    string              `bson:"update_by"                json:"update_by"`
 	// Junit 测试报告
 	TestResultPath string `bson:"test_result_path"         json:"test_result_path"`
+	// TestResultFormat is the format of the files in TestResultPath, they are merged as ginkgo junit reports if it is empty
+	TestResultFormat string `bson:"test_result_format"       json:"test_result_format"`
+	// CoverageReportPaths are the cobertura xml or lcov files of the test
+	CoverageReportPaths []string `bson:"coverage_report_paths"    json:"coverage_report_paths"`
+	// TestImpact runs the tests affected by the changed files of the pull request only
+	TestImpact *TestImpact `bson:"test_impact,omitempty"    json:"test_impact,omitempty"`
 	// html 测试报告
 	TestReportPath string `bson:"test_report_path"         json:"test_report_path"`
 	Threshold      int    `bson:"threshold"                json:"threshold"`
@@ -69,6 +76,20 @@ type Testing struct {
 	Outputs                  []*Output `bson:"outputs"                   json:"outputs"`
 }
 
+// TestImpact configures the test impact analysis of the pull requests, the affected test targets are exposed to the
+// test scripts by the TEST_IMPACT_FULL and TEST_IMPACT_TARGETS envs and the targets file in the workspace.
+// The full suite is run if the task is not triggered by a pull request.
+type TestImpact struct {
+	Enabled  bool                      `bson:"enabled"         json:"enabled"`
+	Mappings []*step.TestImpactMapping `bson:"mappings"        json:"mappings"`
+	// IgnorePaths are the globs of the changed files which affect no test
+	IgnorePaths []string `bson:"ignore_paths"    json:"ignore_paths"`
+	// GoImportGraph adds the go packages whose tests import the changed go files
+	GoImportGraph bool `bson:"go_import_graph" json:"go_import_graph"`
+	// OutputFile is the targets file relative to the workspace
+	OutputFile string `bson:"output_file"     json:"output_file"`
+}
+
 type TestingHookCtrl struct {
 	Enabled bool           `bson:"enabled" json:"enabled"`
 	Items   []*TestingHook `bson:"items" json:"items"`
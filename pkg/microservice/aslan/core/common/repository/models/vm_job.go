@@ -0,0 +1,66 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// VMJob is a workflow job queued for the vm runners, the first runner whose labels contain all the labels of the job
+// picks it. JobCtx is the yaml job context of the jobexecutor, which has the secret envs, so it is removed once the job
+// is picked or finished.
+type VMJob struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty"         json:"id"`
+	WorkflowName string             `bson:"workflow_name"         json:"workflow_name"`
+	ProjectName  string             `bson:"project_name"          json:"project_name"`
+	TaskID       int64              `bson:"task_id"               json:"task_id"`
+	JobName      string             `bson:"job_name"              json:"job_name"`
+	Labels       []string           `bson:"labels"                json:"labels"`
+	JobCtx       string             `bson:"job_ctx"               json:"-"`
+	Status       string             `bson:"status"                json:"status"`
+	// RunnerID is the runner running the job, RunnerName is kept for display since the runner may be renamed
+	RunnerID   string `bson:"runner_id"             json:"runner_id"`
+	RunnerName string `bson:"runner_name"           json:"runner_name"`
+	// Outputs is the json job outputs of the jobexecutor
+	Outputs    string `bson:"outputs"               json:"outputs"`
+	ExitCode   int    `bson:"exit_code"             json:"exit_code"`
+	Error      string `bson:"error"                 json:"error"`
+	CreateTime int64  `bson:"create_time"           json:"create_time"`
+	StartTime  int64  `bson:"start_time"            json:"start_time"`
+	EndTime    int64  `bson:"end_time"              json:"end_time"`
+	// ExpireAt is set when the job is finished, the finished jobs are removed by the ttl index
+	ExpireAt time.Time `bson:"expire_at,omitempty"   json:"-"`
+}
+
+func (VMJob) TableName() string {
+	return "vm_job"
+}
+
+// VMJobLog is a chunk of the log of the vm job, the log is uploaded to the s3 storage and the chunks are removed
+// when the job is done
+type VMJobLog struct {
+	ID      primitive.ObjectID `bson:"_id,omitempty"         json:"id"`
+	JobID   string             `bson:"job_id"                json:"job_id"`
+	Seq     int64              `bson:"seq"                   json:"seq"`
+	Content string             `bson:"content"               json:"content"`
+}
+
+func (VMJobLog) TableName() string {
+	return "vm_job_log"
+}
@@ -0,0 +1,47 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// VMRunner is a self-hosted vm or bare-metal host which runs the workflow jobs whose vm labels are all in its labels.
+// The runner agent authenticates with the token, only the sha256 of the token is stored.
+type VMRunner struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty"         json:"id"`
+	Name        string             `bson:"name"                  json:"name"`
+	Description string             `bson:"description"           json:"description"`
+	Labels      []string           `bson:"labels"                json:"labels"`
+	TokenHash   string             `bson:"token_hash"            json:"-"`
+	// Token is returned only when the runner is created
+	Token string `bson:"-"                     json:"token,omitempty"`
+	// Status is online if the agent sent a heartbeat recently, it is computed when the runners are listed
+	Status            string `bson:"-"                     json:"status"`
+	Hostname          string `bson:"hostname"              json:"hostname"`
+	IP                string `bson:"ip"                    json:"ip"`
+	OS                string `bson:"os"                    json:"os"`
+	Arch              string `bson:"arch"                  json:"arch"`
+	AgentVersion      string `bson:"agent_version"         json:"agent_version"`
+	LastHeartbeatTime int64  `bson:"last_heartbeat_time"   json:"last_heartbeat_time"`
+	UpdatedBy         string `bson:"updated_by"            json:"updated_by"`
+	UpdateTime        int64  `bson:"update_time"           json:"update_time"`
+}
+
+func (VMRunner) TableName() string {
+	return "vm_runner"
+}
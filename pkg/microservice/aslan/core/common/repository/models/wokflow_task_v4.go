@@ -90,6 +90,44 @@ type JobTask struct {
 	BreakpointBefore bool                     `bson:"breakpoint_before"   json:"breakpoint_before"`
 	BreakpointAfter  bool                     `bson:"breakpoint_after"    json:"breakpoint_after"`
 	ServiceModules   []*WorkflowServiceModule `bson:"service_modules"     json:"service_modules"`
+	// OriginName is the name of the workflow job which this job task is generated from
+	OriginName string `bson:"origin_name"         json:"origin_name"`
+	// DependsOn is the list of workflow job names that must pass before this job task starts
+	DependsOn []string `bson:"depends_on"          json:"depends_on"`
+	// RunIf is the condition expression of the workflow job, job task will be skipped if it is evaluated to false
+	RunIf string `bson:"run_if"              json:"run_if"`
+	// Matrix is set when the job task is expanded from a job with matrix
+	Matrix *JobTaskMatrix `bson:"matrix,omitempty"    json:"matrix,omitempty"`
+	// RetryPolicy is copied from the workflow job, failed job task is retried according to it
+	RetryPolicy *JobRetryPolicy `bson:"retry_policy,omitempty" json:"retry_policy,omitempty"`
+	// FailureReason and ExitCode are set by the job controller when the job task failed
+	FailureReason config.JobFailureReason `bson:"failure_reason,omitempty" json:"failure_reason,omitempty"`
+	ExitCode      int                     `bson:"exit_code,omitempty"      json:"exit_code,omitempty"`
+	// Attempts is the history of every run of the job task when it has a retry policy
+	Attempts []*JobTaskAttempt `bson:"attempts,omitempty"      json:"attempts,omitempty"`
+	// DebugOnFailureTTL is the seconds the pod is kept for debugging after the job failed, 0 means disabled
+	DebugOnFailureTTL int64 `bson:"debug_on_failure_ttl,omitempty" json:"debug_on_failure_ttl,omitempty"`
+}
+
+type JobTaskAttempt struct {
+	Attempt       int                     `bson:"attempt"              json:"attempt"`
+	Status        config.Status           `bson:"status"               json:"status"`
+	Error         string                  `bson:"error"                json:"error"`
+	FailureReason config.JobFailureReason `bson:"failure_reason"       json:"failure_reason"`
+	ExitCode      int                     `bson:"exit_code"            json:"exit_code"`
+	StartTime     int64                   `bson:"start_time"           json:"start_time"`
+	EndTime       int64                   `bson:"end_time"             json:"end_time"`
+	K8sJobName    string                  `bson:"k8s_job_name"         json:"k8s_job_name"`
+	// LogName is the name to query the log of this attempt, the latest attempt uses the job task name
+	LogName string `bson:"log_name"             json:"log_name"`
+}
+
+type JobTaskMatrix struct {
+	// Parent is the name of the job task before expanding, job tasks with the same parent are shown as its children
+	Parent      string            `bson:"parent"              json:"parent"`
+	Values      map[string]string `bson:"values"              json:"values"`
+	FailFast    bool              `bson:"fail_fast"           json:"fail_fast"`
+	MaxParallel int               `bson:"max_parallel"        json:"max_parallel"`
 }
 
 type TaskJobInfo struct {
@@ -185,6 +223,7 @@ type JobTaskDeploySpec struct {
 	Timeout            int                             `bson:"timeout"                          json:"timeout"                             yaml:"timeout"`
 	ReplaceResources   []Resource                      `bson:"replace_resources"                json:"replace_resources"                   yaml:"replace_resources"`
 	RelatedPodLabels   []map[string]string             `bson:"-"                                json:"-"                                   yaml:"-"`
+	SignaturePolicy    *ImageSignaturePolicy           `bson:"signature_policy"                 json:"signature_policy"                    yaml:"signature_policy"`
 	// for compatibility
 	ServiceModule string `bson:"service_module"                   json:"service_module"                      yaml:"-"`
 	Image         string `bson:"image"                            json:"image"                               yaml:"-"`
@@ -224,6 +263,7 @@ type JobTaskHelmDeploySpec struct {
 	ReleaseName        string                   `bson:"release_name"                     json:"release_name"                        yaml:"release_name"`
 	Timeout            int                      `bson:"timeout"                          json:"timeout"                             yaml:"timeout"`
 	ReplaceResources   []Resource               `bson:"replace_resources"                json:"replace_resources"                   yaml:"replace_resources"`
+	SignaturePolicy    *ImageSignaturePolicy    `bson:"signature_policy"                 json:"signature_policy"                    yaml:"signature_policy"`
 }
 
 type JobTaskHelmChartDeploySpec struct {
@@ -574,6 +614,8 @@ type WorkflowTaskCtx struct {
 	WorkflowTaskCreatorEmail    string
 	WorkflowTaskCreatorMobile   string
 	WorkflowKeyVals             []*KeyVal
+	WorkflowParams              []*Param
+	HookPayload                 *HookPayload
 	GlobalContextGet            func(key string) (string, bool)
 	GlobalContextSet            func(key, value string)
 	GlobalContextEach           func(f func(k, v string) bool)
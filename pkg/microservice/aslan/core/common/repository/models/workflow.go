@@ -334,6 +334,22 @@ type NotifyCtl struct {
 	LarkUserIDs     []string `bson:"lark_user_ids,omitempty"       yaml:"lark_user_ids,omitempty"       json:"lark_user_ids,omitempty"`
 	IsAtAll         bool     `bson:"is_at_all,omitempty"           yaml:"is_at_all,omitempty"           json:"is_at_all,omitempty"`
 	NotifyTypes     []string `bson:"notify_type"                   yaml:"notify_type"                   json:"notify_type"`
+	SlackWebHook    string   `bson:"slack_webhook,omitempty"       yaml:"slack_webhook,omitempty"       json:"slack_webhook,omitempty"`
+	TeamsWebHook    string   `bson:"teams_webhook,omitempty"       yaml:"teams_webhook,omitempty"       json:"teams_webhook,omitempty"`
+	// WebHookNotify is the generic json webhook, only available for workflow v4
+	WebHookNotify *WebHookNotify `bson:"webhook_notify,omitempty"      yaml:"webhook_notify,omitempty"      json:"webhook_notify,omitempty"`
+	// MessageTemplates are go templates of the message text of slack, teams and generic webhook, keyed by the notify type
+	MessageTemplates map[string]string `bson:"message_templates,omitempty"   yaml:"message_templates,omitempty"   json:"message_templates,omitempty"`
+}
+
+type WebHookNotify struct {
+	// ID identifies the webhook notify across the updates of the workflow, it is generated when the workflow is saved
+	ID      string `bson:"id"                            yaml:"id,omitempty"                  json:"id"`
+	Address string `bson:"address"                       yaml:"address"                       json:"address"`
+	// Secret is used to sign the request body with HMAC-SHA256, the signature is set in the X-Zadig-Signature header.
+	// It is only stored encrypted in EncryptedSecret and is masked in the api responses.
+	Secret          string `bson:"-"                             yaml:"secret,omitempty"              json:"secret,omitempty"`
+	EncryptedSecret string `bson:"encrypted_secret,omitempty"    yaml:"-"                             json:"-"`
 }
 
 type TaskInfo struct {
@@ -376,6 +392,8 @@ type HookPayload struct {
 	DeliveryID     string `bson:"delivery_id"      json:"delivery_id,omitempty"`
 	CodehostID     int    `bson:"codehost_id"      json:"codehost_id"`
 	EventType      string `bson:"event_type"       json:"event_type"`
+	// ChangedFiles are the files changed by the pull request, used by the test impact analysis
+	ChangedFiles []string `bson:"changed_files"    json:"changed_files,omitempty"`
 }
 
 type TargetArgs struct {
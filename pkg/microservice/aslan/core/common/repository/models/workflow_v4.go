@@ -34,6 +34,7 @@ import (
 	"github.com/koderover/zadig/pkg/tool/guanceyun"
 	"github.com/koderover/zadig/pkg/tool/lark"
 	"github.com/koderover/zadig/pkg/types"
+	"github.com/koderover/zadig/pkg/types/job"
 )
 
 type WorkflowV4 struct {
@@ -65,6 +66,14 @@ type WorkflowV4 struct {
 	// -1 means no limit
 	ConcurrencyLimit int          `bson:"concurrency_limit"   yaml:"concurrency_limit"   json:"concurrency_limit"`
 	CustomField      *CustomField `bson:"custom_field"        yaml:"-"                   json:"custom_field"`
+	// DebugOnFailure keeps the pods of the failed freestyle, build, testing and scanning jobs for debugging
+	DebugOnFailure *DebugOnFailure `bson:"debug_on_failure,omitempty" yaml:"debug_on_failure,omitempty" json:"debug_on_failure,omitempty"`
+}
+
+type DebugOnFailure struct {
+	Enabled bool `bson:"enabled"            yaml:"enabled"            json:"enabled"`
+	// TTL is the minutes the pod of the failed job is kept, the debug sessions are closed when it expires
+	TTL int64 `bson:"ttl"                yaml:"ttl"                json:"ttl"`
 }
 
 func (w *WorkflowV4) UpdateHash() {
@@ -107,6 +116,59 @@ type Approval struct {
 	NativeApproval   *NativeApproval     `bson:"native_approval"             yaml:"native_approval,omitempty"     json:"native_approval,omitempty"`
 	LarkApproval     *LarkApproval       `bson:"lark_approval"               yaml:"lark_approval,omitempty"       json:"lark_approval,omitempty"`
 	DingTalkApproval *DingTalkApproval   `bson:"dingtalk_approval"           yaml:"dingtalk_approval,omitempty"   json:"dingtalk_approval,omitempty"`
+	// Escalations are applied in order when the approval is still pending after the given minutes
+	Escalations []*ApprovalEscalation `bson:"escalations,omitempty"       yaml:"escalations,omitempty"         json:"escalations,omitempty"`
+	// ReminderInterval is the interval in minutes to resend the approval notifications, 0 means no reminder
+	ReminderInterval int `bson:"reminder_interval,omitempty" yaml:"reminder_interval,omitempty"   json:"reminder_interval,omitempty"`
+	// TimeoutAction is applied when the approval timeout, empty means the stage fails
+	TimeoutAction config.ApprovalTimeoutAction `bson:"timeout_action,omitempty"    yaml:"timeout_action,omitempty"      json:"timeout_action,omitempty"`
+}
+
+// ApprovalEscalation notifies another approver group when the approval is not finished in time,
+// the group can be zadig users, a lark approval or a dingtalk approval whatever the approval type of the stage is,
+// and one decision of the group finishes the approval.
+type ApprovalEscalation struct {
+	// After is the minutes since the approval started
+	After int `bson:"after"                       yaml:"after"                      json:"after"`
+	// Type is the approval type of the escalated group, native if not set
+	Type config.ApprovalType `bson:"type,omitempty"              yaml:"type,omitempty"             json:"type,omitempty"`
+	// ApproveUsers are the escalated zadig users of a native escalation
+	ApproveUsers     []*User           `bson:"approve_users"               yaml:"approve_users"              json:"approve_users"`
+	LarkApproval     *LarkApproval     `bson:"lark_approval,omitempty"     yaml:"lark_approval,omitempty"    json:"lark_approval,omitempty"`
+	DingTalkApproval *DingTalkApproval `bson:"dingtalk_approval,omitempty" yaml:"dingtalk_approval,omitempty" json:"dingtalk_approval,omitempty"`
+	Escalated        bool              `bson:"escalated"                   yaml:"-"                          json:"escalated"`
+	EscalateTime     int64             `bson:"escalate_time"               yaml:"-"                          json:"escalate_time"`
+}
+
+// GetType returns the approval type of the escalated group
+func (e *ApprovalEscalation) GetType() config.ApprovalType {
+	if e.Type == "" {
+		return config.NativeApproval
+	}
+	return e.Type
+}
+
+// GetTimeout returns the timeout in minutes of the approval, it is 60 if not set
+func (a *Approval) GetTimeout() int {
+	timeout := 0
+	switch a.Type {
+	case config.NativeApproval:
+		if a.NativeApproval != nil {
+			timeout = a.NativeApproval.Timeout
+		}
+	case config.LarkApproval:
+		if a.LarkApproval != nil {
+			timeout = a.LarkApproval.Timeout
+		}
+	case config.DingTalkApproval:
+		if a.DingTalkApproval != nil {
+			timeout = a.DingTalkApproval.Timeout
+		}
+	}
+	if timeout == 0 {
+		return 60
+	}
+	return timeout
 }
 
 type NativeApproval struct {
@@ -211,6 +273,28 @@ type Job struct {
 	Spec           interface{}              `bson:"spec"           yaml:"spec"       json:"spec"`
 	RunPolicy      config.JobRunPolicy      `bson:"run_policy"     yaml:"run_policy" json:"run_policy"`
 	ServiceModules []*WorkflowServiceModule `bson:"service_modules"                  json:"service_modules"`
+	// DependsOn is the list of job names that must pass before this job starts, jobs in earlier stages are allowed.
+	// if no job in the workflow declares it, stages are executed strictly in order.
+	DependsOn []string `bson:"depends_on,omitempty" yaml:"depends_on,omitempty" json:"depends_on,omitempty"`
+	// RunIf is a condition expression evaluated right before the job starts, the job is skipped if it is false.
+	// e.g. [workflow.params.env] == 'prod' && [hook.event_type] != 'pr'
+	RunIf string `bson:"run_if,omitempty"     yaml:"run_if,omitempty"     json:"run_if,omitempty"`
+	// RetryPolicy retries the failed job task in the same workflow task.
+	RetryPolicy *JobRetryPolicy `bson:"retry_policy,omitempty" yaml:"retry_policy,omitempty" json:"retry_policy,omitempty"`
+}
+
+type JobRetryPolicy struct {
+	// MaxAttempts is the max number of times the job task runs, including the first run.
+	MaxAttempts int `bson:"max_attempts"       yaml:"max_attempts"       json:"max_attempts"`
+	// Backoff is the seconds to wait before the first retry, it is multiplied by BackoffFactor for every following retry.
+	Backoff       int64   `bson:"backoff"            yaml:"backoff"            json:"backoff"`
+	BackoffFactor float64 `bson:"backoff_factor"     yaml:"backoff_factor"     json:"backoff_factor"`
+	// MaxBackoff is the max seconds to wait between two attempts, 0 means no limit.
+	MaxBackoff int64 `bson:"max_backoff"        yaml:"max_backoff"        json:"max_backoff"`
+	// RetryOn is the list of failure reasons which should be retried.
+	RetryOn []config.JobFailureReason `bson:"retry_on"           yaml:"retry_on"           json:"retry_on"`
+	// ExitCodes limits the exit codes to retry on when RetryOn contains exit_code, empty means any non-zero exit code.
+	ExitCodes []int `bson:"exit_codes"         yaml:"exit_codes"         json:"exit_codes"`
 }
 
 type WorkflowServiceModule struct {
@@ -252,6 +336,23 @@ type FreestyleJobSpec struct {
 type ZadigBuildJobSpec struct {
 	DockerRegistryID string             `bson:"docker_registry_id"     yaml:"docker_registry_id"     json:"docker_registry_id"`
 	ServiceAndBuilds []*ServiceAndBuild `bson:"service_and_builds"     yaml:"service_and_builds"     json:"service_and_builds"`
+	Matrix           *JobMatrix         `bson:"matrix,omitempty"       yaml:"matrix,omitempty"       json:"matrix,omitempty"`
+}
+
+// JobMatrix fans every job task of a job out into one job task per combination of the axis values.
+type JobMatrix struct {
+	Axes []*MatrixAxis `bson:"axes"           yaml:"axes"           json:"axes"`
+	// FailFast cancels the other job tasks expanded from the same job task once one of them failed
+	FailFast bool `bson:"fail_fast"      yaml:"fail_fast"      json:"fail_fast"`
+	// MaxParallel is the max number of job tasks expanded from the same job task running at the same time, 0 means no limit.
+	MaxParallel int `bson:"max_parallel"   yaml:"max_parallel"   json:"max_parallel"`
+}
+
+type MatrixAxis struct {
+	// Name is the env key of the axis value, like GO_VERSION
+	Name   string                `bson:"name"           yaml:"name"           json:"name"`
+	Type   config.MatrixAxisType `bson:"type"           yaml:"type"           json:"type"`
+	Values []string              `bson:"values"         yaml:"values"         json:"values"`
 }
 
 type ServiceAndBuild struct {
@@ -280,6 +381,14 @@ type ZadigDeployJobSpec struct {
 	OriginJobName    string             `bson:"origin_job_name"      yaml:"origin_job_name"      json:"origin_job_name"`
 	ServiceAndImages []*ServiceAndImage `bson:"service_and_images"   yaml:"service_and_images"   json:"service_and_images"`
 	Services         []*DeployService   `bson:"services"             yaml:"services"             json:"services"`
+	// SignaturePolicy refuses to deploy the images which are unsigned or signed by untrusted keys
+	SignaturePolicy *ImageSignaturePolicy `bson:"signature_policy"     yaml:"signature_policy"     json:"signature_policy"`
+}
+
+type ImageSignaturePolicy struct {
+	Enabled bool `bson:"enabled"              yaml:"enabled"              json:"enabled"`
+	// TrustedKeyIDs are the ids of the image signing keys, an image must be signed by any of them
+	TrustedKeyIDs []string `bson:"trusted_key_ids"      yaml:"trusted_key_ids"      json:"trusted_key_ids"`
 }
 
 type ZadigHelmChartDeployJobSpec struct {
@@ -350,6 +459,8 @@ type ZadigDistributeImageJobSpec struct {
 	StrategyID               string `bson:"strategy_id"                    json:"strategy_id"                   yaml:"strategy_id"`
 	EnableTargetImageTagRule bool   `bson:"enable_target_image_tag_rule" json:"enable_target_image_tag_rule" yaml:"enable_target_image_tag_rule"`
 	TargetImageTagRule       string `bson:"target_image_tag_rule"        json:"target_image_tag_rule"        yaml:"target_image_tag_rule"`
+	// SigningKeyID signs the distributed images with the image signing key if it is set
+	SigningKeyID string `bson:"signing_key_id"               json:"signing_key_id"               yaml:"signing_key_id"`
 }
 
 type DistributeTarget struct {
@@ -372,6 +483,7 @@ type ZadigTestingJobSpec struct {
 	TargetServices  []*ServiceTestTarget    `bson:"target_services"  yaml:"target_services"  json:"target_services"`
 	TestModules     []*TestModule           `bson:"test_modules"     yaml:"test_modules"     json:"test_modules"`
 	ServiceAndTests []*ServiceAndTest       `bson:"service_and_tests" yaml:"service_and_tests" json:"service_and_tests"`
+	Matrix          *JobMatrix              `bson:"matrix,omitempty" yaml:"matrix,omitempty" json:"matrix,omitempty"`
 }
 
 type ServiceAndTest struct {
@@ -733,6 +845,9 @@ type JobProperties struct {
 	ShareStorageInfo    *ShareStorageInfo    `bson:"share_storage_info"     json:"share_storage_info"    yaml:"share_storage_info"`
 	ShareStorageDetails []*StorageDetail     `bson:"share_storage_details"  json:"share_storage_details" yaml:"-"`
 	UseHostDockerDaemon bool                 `bson:"use_host_docker_daemon,omitempty" json:"use_host_docker_daemon,omitempty" yaml:"use_host_docker_daemon"`
+	// Infrastructure is kubernetes by default, the job runs on a vm runner whose labels contain all the VMLabels if it is vm
+	Infrastructure string   `bson:"infrastructure,omitempty" json:"infrastructure,omitempty" yaml:"infrastructure,omitempty"`
+	VMLabels       []string `bson:"vm_labels,omitempty"      json:"vm_labels,omitempty"      yaml:"vm_labels,omitempty"`
 }
 
 type Step struct {
@@ -745,6 +860,20 @@ type Step struct {
 type Output struct {
 	Name        string `bson:"name"           json:"name"             yaml:"name"`
 	Description string `bson:"description"    json:"description"      yaml:"description"`
+	// Type is one of string, number, bool, json and file, untyped outputs are not validated
+	Type string `bson:"type,omitempty"       json:"type,omitempty"       yaml:"type,omitempty"`
+	// Schema is the json schema of json outputs
+	Schema    string `bson:"schema,omitempty"     json:"schema,omitempty"     yaml:"schema,omitempty"`
+	MaxLength int    `bson:"max_length,omitempty" json:"max_length,omitempty" yaml:"max_length,omitempty"`
+}
+
+func (o *Output) ToSpec() *job.OutputSpec {
+	return &job.OutputSpec{
+		Name:      o.Name,
+		Type:      o.Type,
+		Schema:    o.Schema,
+		MaxLength: o.MaxLength,
+	}
 }
 
 type WorkflowV4Hook struct {
@@ -757,6 +886,8 @@ type WorkflowV4Hook struct {
 	Repos               []*types.Repository `bson:"-"                         json:"repos,omitempty"`
 	IsManual            bool                `bson:"is_manual"                 json:"is_manual"`
 	WorkflowArg         *WorkflowV4         `bson:"workflow_arg"              json:"workflow_arg"`
+	// PreviewEnv deploys the pull requests matched by the hook to their own preview environments
+	PreviewEnv *PreviewEnvConfig `bson:"preview_env,omitempty"     json:"preview_env,omitempty"`
 }
 
 type JiraHook struct {
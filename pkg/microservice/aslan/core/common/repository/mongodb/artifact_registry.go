@@ -0,0 +1,119 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type ArtifactRegistryColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewArtifactRegistryColl() *ArtifactRegistryColl {
+	name := models.ArtifactRegistry{}.TableName()
+	return &ArtifactRegistryColl{
+		Collection: mongotool.Database(config.MongoDatabase()).Collection(name),
+		coll:       name,
+	}
+}
+
+func (c *ArtifactRegistryColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *ArtifactRegistryColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys:    bson.M{"name": 1},
+		Options: options.Index().SetUnique(true),
+	}
+
+	_, err := c.Indexes().CreateOne(ctx, mod)
+	return err
+}
+
+func (c *ArtifactRegistryColl) Create(args *models.ArtifactRegistry) error {
+	if args == nil {
+		return errors.New("nil artifact registry args")
+	}
+
+	args.UpdateTime = time.Now().Unix()
+	_, err := c.InsertOne(context.TODO(), args)
+	return err
+}
+
+func (c *ArtifactRegistryColl) FindByID(id string) (*models.ArtifactRegistry, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := new(models.ArtifactRegistry)
+	err = c.FindOne(context.TODO(), bson.M{"_id": oid}).Decode(resp)
+	return resp, err
+}
+
+// List returns the registries of the type, all the registries are returned if the type is empty
+func (c *ArtifactRegistryColl) List(registryType string) ([]*models.ArtifactRegistry, error) {
+	query := bson.M{}
+	if registryType != "" {
+		query["type"] = registryType
+	}
+
+	resp := make([]*models.ArtifactRegistry, 0)
+	cursor, err := c.Collection.Find(context.TODO(), query, options.Find().SetSort(bson.D{{"name", 1}}))
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &resp)
+	return resp, err
+}
+
+func (c *ArtifactRegistryColl) Update(id string, args *models.ArtifactRegistry) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	args.ID = oid
+	args.UpdateTime = time.Now().Unix()
+	_, err = c.UpdateOne(context.TODO(), bson.M{"_id": oid}, bson.M{"$set": args})
+	return err
+}
+
+func (c *ArtifactRegistryColl) Delete(id string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.DeleteOne(context.TODO(), bson.M{"_id": oid})
+	return err
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type DebugSessionColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewDebugSessionColl() *DebugSessionColl {
+	name := models.DebugSession{}.TableName()
+	return &DebugSessionColl{
+		Collection: mongotool.Database(config.MongoDatabase()).Collection(name),
+		coll:       name,
+	}
+}
+
+func (c *DebugSessionColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *DebugSessionColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys: bson.D{
+			bson.E{Key: "workflow_name", Value: 1},
+			bson.E{Key: "task_id", Value: 1},
+		},
+		Options: options.Index().SetUnique(false),
+	}
+
+	_, err := c.Indexes().CreateOne(ctx, mod)
+	return err
+}
+
+func (c *DebugSessionColl) Create(args *models.DebugSession) error {
+	if args == nil {
+		return errors.New("nil debug session args")
+	}
+
+	_, err := c.InsertOne(context.TODO(), args)
+	return err
+}
+
+// ListByTask returns the debug sessions of the workflow task without the transcripts
+func (c *DebugSessionColl) ListByTask(workflowName string, taskID int64) ([]*models.DebugSession, error) {
+	query := bson.M{"workflow_name": workflowName, "task_id": taskID}
+	opts := options.Find().SetSort(bson.D{{"start_time", -1}}).SetProjection(bson.M{"transcript": 0})
+
+	resp := make([]*models.DebugSession, 0)
+	cursor, err := c.Collection.Find(context.TODO(), query, opts)
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &resp)
+	return resp, err
+}
+
+func (c *DebugSessionColl) FindByID(id string) (*models.DebugSession, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := new(models.DebugSession)
+	err = c.FindOne(context.TODO(), bson.M{"_id": oid}).Decode(resp)
+	return resp, err
+}
@@ -239,6 +239,14 @@ func (c *DeliveryVersionColl) Update(args *models.DeliveryVersion) error {
 	return err
 }
 
+// AddImageScan adds the id of the image scan to the delivery version with the id, it's added only once
+func (c *DeliveryVersionColl) AddImageScan(id primitive.ObjectID, scanID string) error {
+	query := bson.M{"_id": id, "deleted_at": 0}
+	change := bson.M{"$addToSet": bson.M{"image_scan_ids": scanID}}
+	_, err := c.UpdateOne(context.TODO(), query, change)
+	return err
+}
+
 func (c *DeliveryVersionColl) FindProducts() ([]string, error) {
 	resp := make([]string, 0)
 	query := bson.M{"deleted_at": 0}
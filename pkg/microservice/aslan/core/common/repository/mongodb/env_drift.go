@@ -0,0 +1,90 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type EnvDriftColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewEnvDriftColl() *EnvDriftColl {
+	name := models.EnvDrift{}.TableName()
+	return &EnvDriftColl{
+		Collection: mongotool.Database(config.MongoDatabase()).Collection(name),
+		coll:       name,
+	}
+}
+
+func (c *EnvDriftColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *EnvDriftColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys: bson.D{
+			bson.E{Key: "project_name", Value: 1},
+			bson.E{Key: "env_name", Value: 1},
+		},
+		Options: options.Index().SetUnique(true),
+	}
+
+	_, err := c.Indexes().CreateOne(ctx, mod)
+	return err
+}
+
+func (c *EnvDriftColl) Find(projectName, envName string) (*models.EnvDrift, error) {
+	query := bson.M{"project_name": projectName, "env_name": envName}
+
+	resp := new(models.EnvDrift)
+	err := c.FindOne(context.TODO(), query).Decode(resp)
+	return resp, err
+}
+
+func (c *EnvDriftColl) Upsert(args *models.EnvDrift) error {
+	if args == nil {
+		return errors.New("nil env drift args")
+	}
+
+	query := bson.M{"project_name": args.ProjectName, "env_name": args.EnvName}
+	change := bson.M{"$set": bson.M{
+		"production":  args.Production,
+		"resources":   args.Resources,
+		"detect_time": args.DetectTime,
+	}}
+	_, err := c.UpdateOne(context.TODO(), query, change, options.Update().SetUpsert(true))
+	return err
+}
+
+func (c *EnvDriftColl) Delete(projectName, envName string) error {
+	query := bson.M{"project_name": projectName, "env_name": envName}
+	_, err := c.DeleteOne(context.TODO(), query)
+	return err
+}
@@ -0,0 +1,171 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type EnvSnapshotListOption struct {
+	ProjectName string
+	EnvName     string
+	Type        string
+	PageNum     int64
+	PageSize    int64
+}
+
+type EnvSnapshotColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewEnvSnapshotColl() *EnvSnapshotColl {
+	name := models.EnvSnapshot{}.TableName()
+	return &EnvSnapshotColl{
+		Collection: mongotool.Database(config.MongoDatabase()).Collection(name),
+		coll:       name,
+	}
+}
+
+func (c *EnvSnapshotColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *EnvSnapshotColl) EnsureIndex(ctx context.Context) error {
+	mod := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				bson.E{Key: "project_name", Value: 1},
+				bson.E{Key: "env_name", Value: 1},
+				bson.E{Key: "create_time", Value: -1},
+			},
+		},
+		// only one snapshot of the env is taken for a workflow task
+		{
+			Keys: bson.D{
+				bson.E{Key: "project_name", Value: 1},
+				bson.E{Key: "env_name", Value: 1},
+				bson.E{Key: "workflow_name", Value: 1},
+				bson.E{Key: "task_id", Value: 1},
+			},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"workflow_name": bson.M{"$gt": ""}}),
+		},
+	}
+
+	_, err := c.Indexes().CreateMany(ctx, mod)
+	return err
+}
+
+func (c *EnvSnapshotColl) Create(args *models.EnvSnapshot) error {
+	if args == nil {
+		return errors.New("nil env snapshot args")
+	}
+
+	args.CreateTime = time.Now().Unix()
+	res, err := c.InsertOne(context.TODO(), args)
+	if err != nil {
+		return err
+	}
+	if oid, ok := res.InsertedID.(primitive.ObjectID); ok {
+		args.ID = oid
+	}
+	return nil
+}
+
+// FindByTask returns the snapshot of the env taken for the workflow task
+func (c *EnvSnapshotColl) FindByTask(projectName, envName, workflowName string, taskID int64) (*models.EnvSnapshot, error) {
+	query := bson.M{"project_name": projectName, "env_name": envName, "workflow_name": workflowName, "task_id": taskID}
+	opts := options.FindOne().SetProjection(bson.M{"service_manifests": 0, "render_set": 0})
+
+	resp := new(models.EnvSnapshot)
+	err := c.FindOne(context.TODO(), query, opts).Decode(resp)
+	return resp, err
+}
+
+func (c *EnvSnapshotColl) FindByID(id string) (*models.EnvSnapshot, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := new(models.EnvSnapshot)
+	err = c.FindOne(context.TODO(), bson.M{"_id": oid}).Decode(resp)
+	return resp, err
+}
+
+// List returns the snapshots sorted by the create time in descending order, the rendered manifests and the render
+// set are not returned
+func (c *EnvSnapshotColl) List(opt *EnvSnapshotListOption) ([]*models.EnvSnapshot, int64, error) {
+	query := bson.M{"project_name": opt.ProjectName, "env_name": opt.EnvName}
+	if opt.Type != "" {
+		query["type"] = opt.Type
+	}
+
+	count, err := c.CountDocuments(context.TODO(), query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().SetSort(bson.D{{"create_time", -1}}).SetProjection(bson.M{"service_manifests": 0, "render_set": 0})
+	if opt.PageNum > 0 && opt.PageSize > 0 {
+		opts.SetSkip((opt.PageNum - 1) * opt.PageSize)
+		opts.SetLimit(opt.PageSize)
+	}
+
+	resp := make([]*models.EnvSnapshot, 0)
+	cursor, err := c.Collection.Find(context.TODO(), query, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	err = cursor.All(context.TODO(), &resp)
+	return resp, count, err
+}
+
+// DeleteOutdated keeps the latest snapshots of the env and deletes the others
+func (c *EnvSnapshotColl) DeleteOutdated(projectName, envName string, keep int64) error {
+	query := bson.M{"project_name": projectName, "env_name": envName}
+	opts := options.FindOne().SetSort(bson.D{{"create_time", -1}}).SetSkip(keep - 1).SetProjection(bson.M{"create_time": 1})
+
+	oldest := new(models.EnvSnapshot)
+	if err := c.FindOne(context.TODO(), query, opts).Decode(oldest); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+		return err
+	}
+
+	query["create_time"] = bson.M{"$lt": oldest.CreateTime}
+	_, err := c.DeleteMany(context.TODO(), query)
+	return err
+}
+
+func (c *EnvSnapshotColl) DeleteByEnv(projectName, envName string) error {
+	_, err := c.DeleteMany(context.TODO(), bson.M{"project_name": projectName, "env_name": envName})
+	return err
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type ImageScanColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewImageScanColl() *ImageScanColl {
+	name := models.ImageScan{}.TableName()
+	return &ImageScanColl{
+		Collection: mongotool.Database(config.MongoDatabase()).Collection(name),
+		coll:       name,
+	}
+}
+
+func (c *ImageScanColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *ImageScanColl) EnsureIndex(ctx context.Context) error {
+	mod := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				bson.E{Key: "image_name", Value: 1},
+				bson.E{Key: "created_at", Value: -1},
+			},
+			Options: options.Index().SetUnique(false),
+		},
+	}
+
+	_, err := c.Indexes().CreateMany(ctx, mod)
+	return err
+}
+
+func (c *ImageScanColl) Create(args *models.ImageScan) error {
+	if args == nil {
+		return errors.New("nil image_scan args")
+	}
+
+	res, err := c.InsertOne(context.TODO(), args)
+	if err != nil {
+		return err
+	}
+	if oid, ok := res.InsertedID.(primitive.ObjectID); ok {
+		args.ID = oid
+	}
+	return nil
+}
+
+func (c *ImageScanColl) FindByID(id string) (*models.ImageScan, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := new(models.ImageScan)
+	err = c.FindOne(context.TODO(), bson.M{"_id": oid}).Decode(resp)
+	return resp, err
+}
+
+// ListByIDs returns the scans with the ids, the sboms are not returned
+func (c *ImageScanColl) ListByIDs(ids []string) ([]*models.ImageScan, error) {
+	resp := make([]*models.ImageScan, 0)
+	oids := make([]primitive.ObjectID, 0, len(ids))
+	for _, id := range ids {
+		oid, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return nil, err
+		}
+		oids = append(oids, oid)
+	}
+	if len(oids) == 0 {
+		return resp, nil
+	}
+
+	opts := options.Find().SetSort(bson.D{{"created_at", -1}}).SetProjection(bson.M{"sbom": 0})
+	cursor, err := c.Collection.Find(context.TODO(), bson.M{"_id": bson.M{"$in": oids}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &resp)
+	return resp, err
+}
+
+// FindLatestByImage returns the last scan of the image, mongo.ErrNoDocuments is returned if the image is not scanned
+func (c *ImageScanColl) FindLatestByImage(imageName string) (*models.ImageScan, error) {
+	resp := new(models.ImageScan)
+	opts := options.FindOne().SetSort(bson.D{{"created_at", -1}})
+	err := c.FindOne(context.TODO(), bson.M{"image_name": imageName}, opts).Decode(resp)
+	return resp, err
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type ImageSigningKeyColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewImageSigningKeyColl() *ImageSigningKeyColl {
+	name := models.ImageSigningKey{}.TableName()
+	return &ImageSigningKeyColl{
+		Collection: mongotool.Database(config.MongoDatabase()).Collection(name),
+		coll:       name,
+	}
+}
+
+func (c *ImageSigningKeyColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *ImageSigningKeyColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys:    bson.M{"name": 1},
+		Options: options.Index().SetUnique(true),
+	}
+
+	_, err := c.Indexes().CreateOne(ctx, mod)
+	return err
+}
+
+func (c *ImageSigningKeyColl) Create(args *models.ImageSigningKey) error {
+	if args == nil {
+		return errors.New("nil image signing key args")
+	}
+
+	args.UpdateTime = time.Now().Unix()
+	_, err := c.InsertOne(context.TODO(), args)
+	return err
+}
+
+func (c *ImageSigningKeyColl) FindByID(id string) (*models.ImageSigningKey, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := new(models.ImageSigningKey)
+	err = c.FindOne(context.TODO(), bson.M{"_id": oid}).Decode(resp)
+	return resp, err
+}
+
+func (c *ImageSigningKeyColl) List() ([]*models.ImageSigningKey, error) {
+	resp := make([]*models.ImageSigningKey, 0)
+	cursor, err := c.Collection.Find(context.TODO(), bson.M{}, options.Find().SetSort(bson.D{{"name", 1}}))
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &resp)
+	return resp, err
+}
+
+func (c *ImageSigningKeyColl) ListByIDs(ids []string) ([]*models.ImageSigningKey, error) {
+	oids := make([]primitive.ObjectID, 0, len(ids))
+	for _, id := range ids {
+		oid, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return nil, err
+		}
+		oids = append(oids, oid)
+	}
+
+	resp := make([]*models.ImageSigningKey, 0)
+	cursor, err := c.Collection.Find(context.TODO(), bson.M{"_id": bson.M{"$in": oids}})
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &resp)
+	return resp, err
+}
+
+func (c *ImageSigningKeyColl) Update(id string, args *models.ImageSigningKey) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	args.ID = oid
+	args.UpdateTime = time.Now().Unix()
+	_, err = c.UpdateOne(context.TODO(), bson.M{"_id": oid}, bson.M{"$set": args})
+	return err
+}
+
+func (c *ImageSigningKeyColl) Delete(id string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.DeleteOne(context.TODO(), bson.M{"_id": oid})
+	return err
+}
@@ -0,0 +1,114 @@
+/*
+ * Copyright 2023 The KodeRover Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+// nativeApprovalTTL is how long an approval is kept after its last update. Finished approvals are deleted
+// right away, the ttl index cleans up the ones left behind, e.g. by an aslan restart or an abandoned release plan.
+const nativeApprovalTTL = 30 * 24 * time.Hour
+
+// ErrVersionConflict is returned when the native approval was updated by others after it was read
+var ErrVersionConflict = errors.New("native approval was modified by others")
+
+type NativeApprovalColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewNativeApprovalColl() *NativeApprovalColl {
+	name := models.NativeApprovalInstance{}.TableName()
+	return &NativeApprovalColl{
+		Collection: mongotool.Database(config.MongoDatabase()).Collection(name),
+		coll:       name,
+	}
+}
+
+func (c *NativeApprovalColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *NativeApprovalColl) EnsureIndex(ctx context.Context) error {
+	mod := []mongo.IndexModel{
+		{
+			Keys:    bson.M{"key": 1},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.M{"expire_at": 1},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+	_, err := c.Indexes().CreateMany(ctx, mod)
+	return err
+}
+
+// Upsert resets the approval of the key, it is used when the approval starts or is restored
+func (c *NativeApprovalColl) Upsert(ctx context.Context, key string, approval *models.NativeApproval) error {
+	if approval == nil {
+		return errors.New("nil native approval")
+	}
+	now := time.Now().Unix()
+	query := bson.M{"key": key}
+	update := bson.M{
+		"$set":         bson.M{"approval": approval, "update_time": now, "expire_at": time.Now().Add(nativeApprovalTTL)},
+		"$inc":         bson.M{"version": 1},
+		"$setOnInsert": bson.M{"create_time": now},
+	}
+	_, err := c.UpdateOne(ctx, query, update, options.Update().SetUpsert(true))
+	return err
+}
+
+func (c *NativeApprovalColl) GetByKey(ctx context.Context, key string) (*models.NativeApprovalInstance, error) {
+	resp := new(models.NativeApprovalInstance)
+	return resp, c.FindOne(ctx, bson.M{"key": key}).Decode(resp)
+}
+
+// UpdateWithVersion saves the approval only if nobody else updated it since the version was read
+func (c *NativeApprovalColl) UpdateWithVersion(ctx context.Context, key string, version int64, approval *models.NativeApproval) error {
+	query := bson.M{"key": key, "version": version}
+	update := bson.M{
+		"$set": bson.M{"approval": approval, "update_time": time.Now().Unix(), "expire_at": time.Now().Add(nativeApprovalTTL)},
+		"$inc": bson.M{"version": 1},
+	}
+	res, err := c.UpdateOne(ctx, query, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+func (c *NativeApprovalColl) DeleteByKey(ctx context.Context, key string) error {
+	_, err := c.DeleteOne(ctx, bson.M{"key": key})
+	return err
+}
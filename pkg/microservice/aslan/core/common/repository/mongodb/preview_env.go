@@ -0,0 +1,193 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type PreviewEnvFindOption struct {
+	ProjectName   string
+	WorkflowName  string
+	RepoNamespace string
+	RepoName      string
+	PrID          int
+}
+
+type PreviewEnvListOption struct {
+	ProjectName   string
+	RepoNamespace string
+	RepoName      string
+	PrID          int
+}
+
+type PreviewEnvColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewPreviewEnvColl() *PreviewEnvColl {
+	name := models.PreviewEnv{}.TableName()
+	return &PreviewEnvColl{
+		Collection: mongotool.Database(config.MongoDatabase()).Collection(name),
+		coll:       name,
+	}
+}
+
+func (c *PreviewEnvColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *PreviewEnvColl) EnsureIndex(ctx context.Context) error {
+	mods := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				bson.E{Key: "project_name", Value: 1},
+				bson.E{Key: "workflow_name", Value: 1},
+				bson.E{Key: "repo_namespace", Value: 1},
+				bson.E{Key: "repo_name", Value: 1},
+				bson.E{Key: "pr_id", Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.M{"last_active_time": 1},
+		},
+	}
+
+	_, err := c.Indexes().CreateMany(ctx, mods)
+	return err
+}
+
+// Create inserts the preview env, it fails with a duplicate key error if the workflow already has a preview env for
+// the pull request
+func (c *PreviewEnvColl) Create(args *models.PreviewEnv) error {
+	if args == nil {
+		return errors.New("nil preview env args")
+	}
+
+	now := time.Now().Unix()
+	args.CreateTime = now
+	args.UpdateTime = now
+	args.LastActiveTime = now
+	res, err := c.InsertOne(context.TODO(), args)
+	if err != nil {
+		return err
+	}
+	if oid, ok := res.InsertedID.(primitive.ObjectID); ok {
+		args.ID = oid
+	}
+	return nil
+}
+
+func (c *PreviewEnvColl) Find(opt *PreviewEnvFindOption) (*models.PreviewEnv, error) {
+	query := bson.M{
+		"project_name":   opt.ProjectName,
+		"workflow_name":  opt.WorkflowName,
+		"repo_namespace": opt.RepoNamespace,
+		"repo_name":      opt.RepoName,
+		"pr_id":          opt.PrID,
+	}
+
+	resp := new(models.PreviewEnv)
+	err := c.FindOne(context.TODO(), query).Decode(resp)
+	return resp, err
+}
+
+func (c *PreviewEnvColl) List(opt *PreviewEnvListOption) ([]*models.PreviewEnv, error) {
+	query := bson.M{}
+	if opt.ProjectName != "" {
+		query["project_name"] = opt.ProjectName
+	}
+	if opt.RepoNamespace != "" {
+		query["repo_namespace"] = opt.RepoNamespace
+	}
+	if opt.RepoName != "" {
+		query["repo_name"] = opt.RepoName
+	}
+	if opt.PrID != 0 {
+		query["pr_id"] = opt.PrID
+	}
+
+	resp := make([]*models.PreviewEnv, 0)
+	cursor, err := c.Collection.Find(context.TODO(), query, options.Find().SetSort(bson.D{{"create_time", -1}}))
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &resp)
+	return resp, err
+}
+
+// ListIdle lists the preview envs with an idle ttl which are not active since the time
+func (c *PreviewEnvColl) ListIdle(before int64) ([]*models.PreviewEnv, error) {
+	query := bson.M{
+		"idle_ttl":         bson.M{"$gt": 0},
+		"last_active_time": bson.M{"$lt": before},
+	}
+
+	resp := make([]*models.PreviewEnv, 0)
+	cursor, err := c.Collection.Find(context.TODO(), query)
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &resp)
+	return resp, err
+}
+
+func (c *PreviewEnvColl) UpdateStatus(id primitive.ObjectID, status, errMsg string) error {
+	change := bson.M{
+		"status":      status,
+		"error":       errMsg,
+		"update_time": time.Now().Unix(),
+	}
+	_, err := c.UpdateOne(context.TODO(), bson.M{"_id": id}, bson.M{"$set": change})
+	return err
+}
+
+func (c *PreviewEnvColl) UpdateNotification(id primitive.ObjectID, notificationID string) error {
+	_, err := c.UpdateOne(context.TODO(), bson.M{"_id": id}, bson.M{"$set": bson.M{"notification_id": notificationID}})
+	return err
+}
+
+// Touch records a push to the pull request and the services deployed by it
+func (c *PreviewEnvColl) Touch(id primitive.ObjectID, services []string) error {
+	now := time.Now().Unix()
+	change := bson.M{
+		"services":         services,
+		"last_active_time": now,
+		"update_time":      now,
+	}
+	_, err := c.UpdateOne(context.TODO(), bson.M{"_id": id}, bson.M{"$set": change})
+	return err
+}
+
+func (c *PreviewEnvColl) Delete(id primitive.ObjectID) error {
+	_, err := c.DeleteOne(context.TODO(), bson.M{"_id": id})
+	return err
+}
@@ -394,6 +394,17 @@ func (c *ProductColl) Update(args *models.Product) error {
 	return err
 }
 
+// UpdateSleepStatus records the sleep status of the env, it's unset if the status is nil when the env wakes up
+func (c *ProductColl) UpdateSleepStatus(productName, envName string, sleepStatus *models.EnvSleepStatus) error {
+	query := bson.M{"env_name": envName, "product_name": productName}
+	change := bson.M{"$set": bson.M{"sleep_status": sleepStatus}}
+	if sleepStatus == nil {
+		change = bson.M{"$unset": bson.M{"sleep_status": ""}}
+	}
+	_, err := c.UpdateOne(context.TODO(), query, change)
+	return err
+}
+
 func (c *ProductColl) Create(args *models.Product) error {
 	// avoid panic issue
 	if args == nil {
@@ -568,3 +579,24 @@ func (c *ProductColl) UpdateConfigs(envName, productName string, analysisConfig
 
 	return err
 }
+
+func (c *ProductColl) UpdateGitOps(envName, productName string, gitOps *models.EnvGitOpsConfig) error {
+	query := bson.M{"env_name": envName, "product_name": productName}
+	change := bson.M{"$set": bson.M{
+		"gitops":      gitOps,
+		"update_time": time.Now().Unix(),
+	}}
+	_, err := c.UpdateOne(context.TODO(), query, change)
+
+	return err
+}
+
+func (c *ProductColl) UpdateGitOpsStatus(envName, productName string, status *models.EnvGitOpsStatus) error {
+	query := bson.M{"env_name": envName, "product_name": productName}
+	change := bson.M{"$set": bson.M{
+		"gitops_status": status,
+	}}
+	_, err := c.UpdateOne(context.TODO(), query, change)
+
+	return err
+}
@@ -462,6 +462,7 @@ func (c *ProductColl) Update(productName string, args *template.Product) error {
 		"global_variables":                 args.GlobalVariables,
 		"production_global_variables":      args.ProductionGlobalVariables,
 		"public":                           args.Public,
+		"disable_env_drift_detection":      args.DisableEnvDriftDetection,
 	}}
 
 	_, err := c.UpdateOne(context.TODO(), query, change)
@@ -0,0 +1,180 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type TestCaseQuarantineListOption struct {
+	ProjectName string
+	TestName    string
+	// Quarantined filters the cases by the quarantine status if it is not nil
+	Quarantined *bool
+}
+
+type TestCaseQuarantineColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewTestCaseQuarantineColl() *TestCaseQuarantineColl {
+	name := models.TestCaseQuarantine{}.TableName()
+	return &TestCaseQuarantineColl{
+		Collection: mongotool.Database(config.MongoDatabase()).Collection(name),
+		coll:       name,
+	}
+}
+
+func (c *TestCaseQuarantineColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *TestCaseQuarantineColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys: bson.D{
+			bson.E{Key: "project_name", Value: 1},
+			bson.E{Key: "test_name", Value: 1},
+			bson.E{Key: "case_name", Value: 1},
+		},
+		Options: options.Index().SetUnique(true),
+	}
+
+	_, err := c.Indexes().CreateOne(ctx, mod)
+	return err
+}
+
+func (c *TestCaseQuarantineColl) Create(args *models.TestCaseQuarantine) error {
+	if args == nil {
+		return errors.New("nil test_case_quarantine args")
+	}
+
+	args.CreateTime = time.Now().Unix()
+	args.UpdateTime = time.Now().Unix()
+	_, err := c.InsertOne(context.TODO(), args)
+	return err
+}
+
+func (c *TestCaseQuarantineColl) FindByID(id string) (*models.TestCaseQuarantine, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := new(models.TestCaseQuarantine)
+	err = c.FindOne(context.TODO(), bson.M{"_id": oid}).Decode(resp)
+	return resp, err
+}
+
+func (c *TestCaseQuarantineColl) List(opt *TestCaseQuarantineListOption) ([]*models.TestCaseQuarantine, error) {
+	query := bson.M{}
+	if opt.ProjectName != "" {
+		query["project_name"] = opt.ProjectName
+	}
+	if opt.TestName != "" {
+		query["test_name"] = opt.TestName
+	}
+	if opt.Quarantined != nil {
+		query["quarantined"] = *opt.Quarantined
+	}
+
+	resp := make([]*models.TestCaseQuarantine, 0)
+	opts := options.Find().SetSort(bson.D{{"test_name", 1}, {"case_name", 1}})
+	cursor, err := c.Collection.Find(context.TODO(), query, opts)
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &resp)
+	return resp, err
+}
+
+// ListQuarantinedCaseNames returns the full names of the quarantined cases of the test
+func (c *TestCaseQuarantineColl) ListQuarantinedCaseNames(projectName, testName string) ([]string, error) {
+	quarantined := true
+	cases, err := c.List(&TestCaseQuarantineListOption{ProjectName: projectName, TestName: testName, Quarantined: &quarantined})
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(cases))
+	for _, testCase := range cases {
+		names = append(names, testCase.CaseName)
+	}
+	return names, nil
+}
+
+// MarkFlaky records the flaky run of the case, the case is created without quarantine if it does not exist
+func (c *TestCaseQuarantineColl) MarkFlaky(projectName, testName, caseName, branch, commitID string) error {
+	now := time.Now().Unix()
+	query := bson.M{"project_name": projectName, "test_name": testName, "case_name": caseName}
+	change := bson.M{
+		"$set": bson.M{
+			"flaky":             true,
+			"last_flaky_commit": commitID,
+			"last_flaky_branch": branch,
+			"last_flaky_time":   now,
+			"update_time":       now,
+		},
+		"$inc": bson.M{"flaky_count": 1},
+		"$setOnInsert": bson.M{
+			"quarantined": false,
+			"reason":      "",
+			"updated_by":  "",
+			"create_time": now,
+		},
+	}
+	_, err := c.UpdateOne(context.TODO(), query, change, options.Update().SetUpsert(true))
+	return err
+}
+
+// UpdateQuarantine changes the quarantine status of the case
+func (c *TestCaseQuarantineColl) UpdateQuarantine(id string, quarantined bool, reason, updatedBy string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	change := bson.M{"$set": bson.M{
+		"quarantined": quarantined,
+		"reason":      reason,
+		"updated_by":  updatedBy,
+		"update_time": time.Now().Unix(),
+	}}
+	_, err = c.UpdateOne(context.TODO(), bson.M{"_id": oid}, change)
+	return err
+}
+
+func (c *TestCaseQuarantineColl) Delete(id string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.DeleteOne(context.TODO(), bson.M{"_id": oid})
+	return err
+}
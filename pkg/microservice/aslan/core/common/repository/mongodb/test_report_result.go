@@ -0,0 +1,137 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type TestReportResultListOption struct {
+	ProjectName string
+	TestName    string
+	CommitID    string
+	StartTime   int64
+	EndTime     int64
+	// ExcludeCases skips the cases of the reports if only the summary is needed
+	ExcludeCases bool
+}
+
+type TestReportResultColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewTestReportResultColl() *TestReportResultColl {
+	name := models.TestReportResult{}.TableName()
+	return &TestReportResultColl{
+		Collection: mongotool.Database(config.MongoDatabase()).Collection(name),
+		coll:       name,
+	}
+}
+
+func (c *TestReportResultColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *TestReportResultColl) EnsureIndex(ctx context.Context) error {
+	mod := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				bson.E{Key: "project_name", Value: 1},
+				bson.E{Key: "test_name", Value: 1},
+				bson.E{Key: "created_at", Value: 1},
+			},
+			Options: options.Index().SetUnique(false),
+		},
+		{
+			Keys: bson.D{
+				bson.E{Key: "project_name", Value: 1},
+				bson.E{Key: "test_name", Value: 1},
+				bson.E{Key: "commit_id", Value: 1},
+			},
+			Options: options.Index().SetUnique(false),
+		},
+		{
+			Keys: bson.D{
+				bson.E{Key: "workflow_name", Value: 1},
+				bson.E{Key: "task_id", Value: 1},
+				bson.E{Key: "job_name", Value: 1},
+			},
+			Options: options.Index().SetUnique(false),
+		},
+	}
+
+	_, err := c.Indexes().CreateMany(ctx, mod)
+	return err
+}
+
+func (c *TestReportResultColl) Create(args *models.TestReportResult) error {
+	if args == nil {
+		return errors.New("nil test_report_result args")
+	}
+
+	args.ID = primitive.NewObjectID()
+	_, err := c.InsertOne(context.TODO(), args)
+	return err
+}
+
+// List returns the reports in the order of creation
+func (c *TestReportResultColl) List(opt *TestReportResultListOption) ([]*models.TestReportResult, error) {
+	query := bson.M{}
+	if opt.ProjectName != "" {
+		query["project_name"] = opt.ProjectName
+	}
+	if opt.TestName != "" {
+		query["test_name"] = opt.TestName
+	}
+	if opt.CommitID != "" {
+		query["commit_id"] = opt.CommitID
+	}
+	timeRange := bson.M{}
+	if opt.StartTime > 0 {
+		timeRange["$gte"] = opt.StartTime
+	}
+	if opt.EndTime > 0 {
+		timeRange["$lte"] = opt.EndTime
+	}
+	if len(timeRange) > 0 {
+		query["created_at"] = timeRange
+	}
+
+	opts := options.Find().SetSort(bson.D{{"created_at", 1}})
+	if opt.ExcludeCases {
+		opts.SetProjection(bson.M{"cases": 0})
+	}
+	resp := make([]*models.TestReportResult, 0)
+	cursor, err := c.Collection.Find(context.TODO(), query, opts)
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &resp)
+	return resp, err
+}
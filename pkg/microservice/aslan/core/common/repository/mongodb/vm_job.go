@@ -0,0 +1,182 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+// vmJobTTL is how long a finished vm job is kept, it is only read by the job log and the job controller afterwards
+const vmJobTTL = 30 * 24 * time.Hour
+
+type VMJobColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewVMJobColl() *VMJobColl {
+	name := models.VMJob{}.TableName()
+	return &VMJobColl{
+		Collection: mongotool.Database(config.MongoDatabase()).Collection(name),
+		coll:       name,
+	}
+}
+
+func (c *VMJobColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *VMJobColl) EnsureIndex(ctx context.Context) error {
+	mods := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				bson.E{Key: "status", Value: 1},
+				bson.E{Key: "create_time", Value: 1},
+			},
+			Options: options.Index().SetUnique(false),
+		},
+		{
+			Keys: bson.D{
+				bson.E{Key: "workflow_name", Value: 1},
+				bson.E{Key: "task_id", Value: 1},
+				bson.E{Key: "job_name", Value: 1},
+			},
+			Options: options.Index().SetUnique(false),
+		},
+		{
+			Keys:    bson.M{"expire_at": 1},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+
+	_, err := c.Indexes().CreateMany(ctx, mods)
+	return err
+}
+
+func (c *VMJobColl) Create(args *models.VMJob) error {
+	if args == nil {
+		return errors.New("nil vm job args")
+	}
+
+	args.Status = string(config.StatusCreated)
+	args.CreateTime = time.Now().Unix()
+	res, err := c.InsertOne(context.TODO(), args)
+	if err != nil {
+		return err
+	}
+	args.ID = res.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (c *VMJobColl) FindByID(id string) (*models.VMJob, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := new(models.VMJob)
+	err = c.FindOne(context.TODO(), bson.M{"_id": oid}).Decode(resp)
+	return resp, err
+}
+
+// FindLatest returns the latest vm job of the job of the workflow task, a job has several vm jobs if it is retried
+func (c *VMJobColl) FindLatest(workflowName string, taskID int64, jobName string) (*models.VMJob, error) {
+	query := bson.M{"workflow_name": workflowName, "task_id": taskID, "job_name": jobName}
+	opts := options.FindOne().SetSort(bson.D{{"create_time", -1}})
+
+	resp := new(models.VMJob)
+	err := c.FindOne(context.TODO(), query, opts).Decode(resp)
+	return resp, err
+}
+
+// Poll assigns the oldest created job whose labels are all in the labels of the runner to the runner,
+// nil is returned if there is no such job. The job context is returned and removed from the job at the same time.
+func (c *VMJobColl) Poll(runner *models.VMRunner) (*models.VMJob, error) {
+	query := vmJobPollQuery(runner.Labels)
+	now := time.Now().Unix()
+	change := bson.M{
+		"$set": bson.M{
+			"status":      string(config.StatusRunning),
+			"runner_id":   runner.ID.Hex(),
+			"runner_name": runner.Name,
+			"start_time":  now,
+		},
+		"$unset": bson.M{"job_ctx": ""},
+	}
+	opts := options.FindOneAndUpdate().SetSort(bson.D{{"create_time", 1}}).SetReturnDocument(options.Before)
+
+	resp := new(models.VMJob)
+	err := c.FindOneAndUpdate(context.TODO(), query, change, opts).Decode(resp)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	resp.Status, resp.RunnerID, resp.RunnerName, resp.StartTime = string(config.StatusRunning), runner.ID.Hex(), runner.Name, now
+	return resp, nil
+}
+
+// vmJobPollQuery matches the created jobs which have no label out of the labels of the runner,
+// jobs without labels can be run by any runner
+func vmJobPollQuery(labels []string) bson.M {
+	if labels == nil {
+		// $nin requires an array
+		labels = []string{}
+	}
+	return bson.M{
+		"status": string(config.StatusCreated),
+		"labels": bson.M{"$not": bson.M{"$elemMatch": bson.M{"$nin": labels}}},
+	}
+}
+
+// Finish sets the result of the job if it is not finished yet, false is returned if the job is already finished.
+// The job context of the job which is never picked is removed.
+func (c *VMJobColl) Finish(id primitive.ObjectID, status config.Status, outputs string, exitCode int, errMsg string) (bool, error) {
+	query := bson.M{
+		"_id":    id,
+		"status": bson.M{"$in": []string{string(config.StatusCreated), string(config.StatusRunning)}},
+	}
+	change := bson.M{
+		"$set": bson.M{
+			"status":    string(status),
+			"outputs":   outputs,
+			"exit_code": exitCode,
+			"error":     errMsg,
+			"end_time":  time.Now().Unix(),
+			"expire_at": time.Now().Add(vmJobTTL),
+		},
+		"$unset": bson.M{"job_ctx": ""},
+	}
+	res, err := c.UpdateOne(context.TODO(), query, change)
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount > 0, nil
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type VMJobLogColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewVMJobLogColl() *VMJobLogColl {
+	name := models.VMJobLog{}.TableName()
+	return &VMJobLogColl{
+		Collection: mongotool.Database(config.MongoDatabase()).Collection(name),
+		coll:       name,
+	}
+}
+
+func (c *VMJobLogColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *VMJobLogColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys: bson.D{
+			bson.E{Key: "job_id", Value: 1},
+			bson.E{Key: "seq", Value: 1},
+		},
+		Options: options.Index().SetUnique(true),
+	}
+
+	_, err := c.Indexes().CreateOne(ctx, mod)
+	return err
+}
+
+// Upsert saves the chunk of the log, the chunks resent by the runner are saved only once
+func (c *VMJobLogColl) Upsert(args *models.VMJobLog) error {
+	query := bson.M{"job_id": args.JobID, "seq": args.Seq}
+	change := bson.M{"$set": bson.M{"content": args.Content}}
+	_, err := c.UpdateOne(context.TODO(), query, change, options.Update().SetUpsert(true))
+	return err
+}
+
+// List returns the chunks of the log of the job whose seq is greater than afterSeq in order
+func (c *VMJobLogColl) List(jobID string, afterSeq int64) ([]*models.VMJobLog, error) {
+	query := bson.M{"job_id": jobID, "seq": bson.M{"$gt": afterSeq}}
+
+	resp := make([]*models.VMJobLog, 0)
+	cursor, err := c.Collection.Find(context.TODO(), query, options.Find().SetSort(bson.D{{"seq", 1}}))
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &resp)
+	return resp, err
+}
+
+func (c *VMJobLogColl) DeleteByJob(jobID string) error {
+	_, err := c.DeleteMany(context.TODO(), bson.M{"job_id": jobID})
+	return err
+}
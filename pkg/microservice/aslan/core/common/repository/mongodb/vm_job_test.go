@@ -0,0 +1,49 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+)
+
+var _ = Describe("Testing vm job poll query", func() {
+
+	It("should only match the created jobs whose labels are all in the labels of the runner", func() {
+		query := vmJobPollQuery([]string{"linux", "gpu"})
+		Expect(query["status"]).To(Equal(string(config.StatusCreated)))
+		Expect(query["labels"]).To(Equal(bson.M{
+			"$not": bson.M{"$elemMatch": bson.M{"$nin": []string{"linux", "gpu"}}},
+		}))
+	})
+
+	It("should only match the jobs without labels for runners without labels", func() {
+		query := vmJobPollQuery(nil)
+		// a nil $nin is rejected by mongo, an empty one makes any label of the job unmatched
+		Expect(query["labels"]).To(Equal(bson.M{
+			"$not": bson.M{"$elemMatch": bson.M{"$nin": []string{}}},
+		}))
+	})
+
+	It("should be a valid bson document", func() {
+		_, err := bson.Marshal(vmJobPollQuery([]string{"linux"}))
+		Expect(err).NotTo(HaveOccurred())
+	})
+})
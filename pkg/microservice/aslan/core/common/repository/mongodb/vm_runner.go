@@ -0,0 +1,156 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type VMRunnerColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewVMRunnerColl() *VMRunnerColl {
+	name := models.VMRunner{}.TableName()
+	return &VMRunnerColl{
+		Collection: mongotool.Database(config.MongoDatabase()).Collection(name),
+		coll:       name,
+	}
+}
+
+func (c *VMRunnerColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *VMRunnerColl) EnsureIndex(ctx context.Context) error {
+	mods := []mongo.IndexModel{
+		{
+			Keys:    bson.M{"name": 1},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.M{"token_hash": 1},
+			Options: options.Index().SetUnique(true),
+		},
+	}
+
+	_, err := c.Indexes().CreateMany(ctx, mods)
+	return err
+}
+
+func (c *VMRunnerColl) Create(args *models.VMRunner) error {
+	if args == nil {
+		return errors.New("nil vm runner args")
+	}
+
+	args.UpdateTime = time.Now().Unix()
+	_, err := c.InsertOne(context.TODO(), args)
+	return err
+}
+
+func (c *VMRunnerColl) FindByID(id string) (*models.VMRunner, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := new(models.VMRunner)
+	err = c.FindOne(context.TODO(), bson.M{"_id": oid}).Decode(resp)
+	return resp, err
+}
+
+func (c *VMRunnerColl) FindByName(name string) (*models.VMRunner, error) {
+	resp := new(models.VMRunner)
+	err := c.FindOne(context.TODO(), bson.M{"name": name}).Decode(resp)
+	return resp, err
+}
+
+func (c *VMRunnerColl) FindByTokenHash(tokenHash string) (*models.VMRunner, error) {
+	resp := new(models.VMRunner)
+	err := c.FindOne(context.TODO(), bson.M{"token_hash": tokenHash}).Decode(resp)
+	return resp, err
+}
+
+func (c *VMRunnerColl) List() ([]*models.VMRunner, error) {
+	resp := make([]*models.VMRunner, 0)
+	cursor, err := c.Collection.Find(context.TODO(), bson.M{}, options.Find().SetSort(bson.D{{"name", 1}}))
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &resp)
+	return resp, err
+}
+
+// Update updates the name, description and labels of the runner
+func (c *VMRunnerColl) Update(id string, args *models.VMRunner) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	change := bson.M{
+		"name":        args.Name,
+		"description": args.Description,
+		"labels":      args.Labels,
+		"updated_by":  args.UpdatedBy,
+		"update_time": time.Now().Unix(),
+	}
+	_, err = c.UpdateOne(context.TODO(), bson.M{"_id": oid}, bson.M{"$set": change})
+	return err
+}
+
+// UpdateHost records the host the agent of the runner is running on
+func (c *VMRunnerColl) UpdateHost(id primitive.ObjectID, hostname, ip, os, arch, version string) error {
+	change := bson.M{
+		"hostname":            hostname,
+		"ip":                  ip,
+		"os":                  os,
+		"arch":                arch,
+		"agent_version":       version,
+		"last_heartbeat_time": time.Now().Unix(),
+	}
+	_, err := c.UpdateOne(context.TODO(), bson.M{"_id": id}, bson.M{"$set": change})
+	return err
+}
+
+func (c *VMRunnerColl) Heartbeat(id primitive.ObjectID) error {
+	_, err := c.UpdateOne(context.TODO(), bson.M{"_id": id}, bson.M{"$set": bson.M{"last_heartbeat_time": time.Now().Unix()}})
+	return err
+}
+
+func (c *VMRunnerColl) Delete(id string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.DeleteOne(context.TODO(), bson.M{"_id": oid})
+	return err
+}
@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/google/uuid"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -31,6 +32,7 @@ import (
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
 	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/crypto"
 	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
 )
 
@@ -154,6 +156,9 @@ func (c *WorkflowV4Coll) BulkCreate(args []*models.WorkflowV4) error {
 		arg.CreateTime = time.Now().Unix()
 		arg.UpdateTime = time.Now().Unix()
 		arg.UpdateHash()
+		if err := encryptWebHookNotifySecrets(arg); err != nil {
+			return err
+		}
 		ois = append(ois, arg)
 	}
 
@@ -167,6 +172,9 @@ func (c *WorkflowV4Coll) Create(obj *models.WorkflowV4) (string, error) {
 	}
 
 	obj.UpdateHash()
+	if err := encryptWebHookNotifySecrets(obj); err != nil {
+		return "", err
+	}
 
 	res, err := c.InsertOne(context.TODO(), obj)
 	if err != nil {
@@ -299,6 +307,9 @@ func (c *WorkflowV4Coll) Update(idString string, obj *models.WorkflowV4) error {
 		return fmt.Errorf("invalid id")
 	}
 	obj.UpdateHash()
+	if err := encryptWebHookNotifySecrets(obj); err != nil {
+		return err
+	}
 	filter := bson.M{"_id": id}
 	update := bson.M{"$set": obj}
 
@@ -352,3 +363,27 @@ func (c *WorkflowV4Coll) GetJobNameList(projectName, workflowName, jobType strin
 	}
 	return names, nil
 }
+
+// encryptWebHookNotifySecrets encrypts the plain webhook notify secrets into EncryptedSecret,
+// a masked secret means it is unchanged and the EncryptedSecret is kept.
+// The new webhook notifies get their ids here, the masked secrets are matched by the id when they are submitted back.
+func encryptWebHookNotifySecrets(obj *models.WorkflowV4) error {
+	for _, notify := range obj.NotifyCtls {
+		if notify == nil || notify.WebHookNotify == nil {
+			continue
+		}
+		if notify.WebHookNotify.ID == "" {
+			notify.WebHookNotify.ID = uuid.New().String()
+		}
+		secret := notify.WebHookNotify.Secret
+		if secret == "" || secret == setting.MaskValue {
+			continue
+		}
+		encrypted, err := crypto.AesEncrypt(secret)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt webhook notify secret: %s", err)
+		}
+		notify.WebHookNotify.EncryptedSecret = encrypted
+	}
+	return nil
+}
@@ -17,51 +17,111 @@
 package approval
 
 import (
+	"context"
 	"fmt"
-	"sync"
 	"time"
 
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/mongo"
+
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
 )
 
-type ApproveMap struct {
-	m map[string]*ApproveWithLock
-	sync.RWMutex
-}
+// maxApproveConflictRetry is the max times to retry when the approval is modified by another aslan replica at the same time
+const maxApproveConflictRetry = 5
+
+// ErrApprovalNotFound is returned when there is no pending native approval for the key
+var ErrApprovalNotFound = errors.New("native approval not found")
 
-type ApproveWithLock struct {
+// ApprovalSnapshot is a snapshot of the native approval persisted in mongodb, it is not locked.
+// Version is used to detect concurrent updates from other aslan replicas, see DoApproval.
+type ApprovalSnapshot struct {
 	Approval *commonmodels.NativeApproval
-	sync.RWMutex
+	Version  int64
 }
 
-var GlobalApproveMap ApproveMap
+// SetApproval persists the native approval so that it can be approved from any aslan replica
+func SetApproval(key string, approval *commonmodels.NativeApproval) error {
+	return mongodb.NewNativeApprovalColl().Upsert(context.Background(), key, approval)
+}
 
-func init() {
-	GlobalApproveMap.m = make(map[string]*ApproveWithLock, 0)
+// GetApproval reads the latest native approval of the key
+func GetApproval(key string) (*ApprovalSnapshot, error) {
+	instance, err := mongodb.NewNativeApprovalColl().GetByKey(context.Background(), key)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrApprovalNotFound
+		}
+		return nil, err
+	}
+	return &ApprovalSnapshot{Approval: instance.Approval, Version: instance.Version}, nil
 }
 
-func (c *ApproveMap) SetApproval(key string, value *ApproveWithLock) {
-	c.Lock()
-	defer c.Unlock()
-	c.m[key] = value
+func DeleteApproval(key string) error {
+	return mongodb.NewNativeApprovalColl().DeleteByKey(context.Background(), key)
 }
 
-func (c *ApproveMap) GetApproval(key string) (*ApproveWithLock, bool) {
-	c.RLock()
-	defer c.RUnlock()
-	v, existed := c.m[key]
-	return v, existed
+// DoApproval records the decision of the user and returns the updated approval,
+// it is retried with the latest data when another replica updated the approval at the same time.
+func DoApproval(key, userName, userID, comment string, approve bool) (*ApprovalSnapshot, error) {
+	for i := 0; i < maxApproveConflictRetry; i++ {
+		snapshot, err := GetApproval(key)
+		if err != nil {
+			return nil, err
+		}
+		if err := snapshot.DoApproval(userName, userID, comment, approve); err != nil {
+			return nil, err
+		}
+		err = mongodb.NewNativeApprovalColl().UpdateWithVersion(context.Background(), key, snapshot.Version, snapshot.Approval)
+		if err == nil {
+			snapshot.Version++
+			return snapshot, nil
+		}
+		if err != mongodb.ErrVersionConflict {
+			return nil, errors.Wrap(err, "update native approval")
+		}
+	}
+	return nil, fmt.Errorf("approval is busy, please try again later")
 }
-func (c *ApproveMap) DeleteApproval(key string) {
-	c.Lock()
-	defer c.Unlock()
-	delete(c.m, key)
+
+// AddApproveUsers adds the users who are not approvers yet to the native approval of the key,
+// it is used to escalate the approval to another approver group.
+func AddApproveUsers(key string, users []*commonmodels.User) (*ApprovalSnapshot, error) {
+	for i := 0; i < maxApproveConflictRetry; i++ {
+		snapshot, err := GetApproval(key)
+		if err != nil {
+			return nil, err
+		}
+		snapshot.AddApproveUsers(users)
+		err = mongodb.NewNativeApprovalColl().UpdateWithVersion(context.Background(), key, snapshot.Version, snapshot.Approval)
+		if err == nil {
+			snapshot.Version++
+			return snapshot, nil
+		}
+		if err != mongodb.ErrVersionConflict {
+			return nil, errors.Wrap(err, "update native approval")
+		}
+	}
+	return nil, fmt.Errorf("approval is busy, please try again later")
+}
+
+func (c *ApprovalSnapshot) AddApproveUsers(users []*commonmodels.User) {
+	existed := make(map[string]bool)
+	for _, user := range c.Approval.ApproveUsers {
+		existed[user.UserID] = true
+	}
+	for _, user := range users {
+		if existed[user.UserID] {
+			continue
+		}
+		existed[user.UserID] = true
+		c.Approval.ApproveUsers = append(c.Approval.ApproveUsers, &commonmodels.User{UserID: user.UserID, UserName: user.UserName})
+	}
 }
 
-func (c *ApproveWithLock) IsApproval() (bool, int, error) {
-	c.Lock()
-	defer c.Unlock()
+func (c *ApprovalSnapshot) IsApproval() (bool, int, error) {
 	ApproveCount := 0
 	for _, user := range c.Approval.ApproveUsers {
 		if user.RejectOrApprove == config.Reject {
@@ -79,9 +139,7 @@ func (c *ApproveWithLock) IsApproval() (bool, int, error) {
 	return false, ApproveCount, nil
 }
 
-func (c *ApproveWithLock) DoApproval(userName, userID, comment string, appvove bool) error {
-	c.Lock()
-	defer c.Unlock()
+func (c *ApprovalSnapshot) DoApproval(userName, userID, comment string, appvove bool) error {
 	for _, user := range c.Approval.ApproveUsers {
 		if user.UserID != userID {
 			continue
@@ -0,0 +1,90 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instantmessage
+
+import (
+	"fmt"
+)
+
+const (
+	slackType            = "slack"
+	slackBlockHeader     = "header"
+	slackBlockSection    = "section"
+	slackBlockActions    = "actions"
+	slackTextPlain       = "plain_text"
+	slackTextMarkdown    = "mrkdwn"
+	slackElementButton   = "button"
+	slackMaxHeaderLength = 150
+)
+
+// SlackMessage is the message of slack incoming webhook in block kit
+type SlackMessage struct {
+	Text   string        `json:"text"`
+	Blocks []*SlackBlock `json:"blocks"`
+}
+
+type SlackBlock struct {
+	Type     string          `json:"type"`
+	Text     *SlackText      `json:"text,omitempty"`
+	Fields   []*SlackText    `json:"fields,omitempty"`
+	Elements []*SlackElement `json:"elements,omitempty"`
+}
+
+type SlackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type SlackElement struct {
+	Type string     `json:"type"`
+	Text *SlackText `json:"text"`
+	URL  string     `json:"url"`
+}
+
+func (w *Service) sendSlackMessage(uri string, message *workflowTaskMessage) error {
+	_, err := w.SendMessageRequest(uri, getSlackMessage(message))
+	return err
+}
+
+func getSlackMessage(message *workflowTaskMessage) *SlackMessage {
+	header := message.Title
+	// slack limits the header by characters, truncating by bytes may split a multi-byte character
+	if runes := []rune(header); len(runes) > slackMaxHeaderLength {
+		header = string(runes[:slackMaxHeaderLength])
+	}
+	fields := []*SlackText{}
+	for _, field := range message.Fields {
+		fields = append(fields, &SlackText{Type: slackTextMarkdown, Text: fmt.Sprintf("*%s*\n%s", field.Name, field.Value)})
+	}
+	blocks := []*SlackBlock{
+		{Type: slackBlockHeader, Text: &SlackText{Type: slackTextPlain, Text: header}},
+		{Type: slackBlockSection, Fields: fields},
+	}
+	if message.Text != "" {
+		blocks = append(blocks, &SlackBlock{Type: slackBlockSection, Text: &SlackText{Type: slackTextMarkdown, Text: message.Text}})
+	}
+	blocks = append(blocks, &SlackBlock{
+		Type: slackBlockActions,
+		Elements: []*SlackElement{{
+			Type: slackElementButton,
+			Text: &SlackText{Type: slackTextPlain, Text: "View Details"},
+			URL:  message.DetailURL,
+		}},
+	})
+
+	return &SlackMessage{Text: message.Title, Blocks: blocks}
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instantmessage
+
+import (
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+)
+
+const (
+	teamsType                = "msteams"
+	teamsMessageType         = "message"
+	teamsAdaptiveCardType    = "AdaptiveCard"
+	teamsAdaptiveCardVersion = "1.4"
+	teamsAdaptiveCardSchema  = "http://adaptivecards.io/schemas/adaptive-card.json"
+	teamsAdaptiveCardContent = "application/vnd.microsoft.card.adaptive"
+	teamsElementTextBlock    = "TextBlock"
+	teamsElementFactSet      = "FactSet"
+	teamsActionOpenURL       = "Action.OpenUrl"
+	teamsTextWeightBolder    = "Bolder"
+	teamsTextSizeMedium      = "Medium"
+	teamsTextColorGood       = "Good"
+	teamsTextColorAttention  = "Attention"
+	teamsTextColorWarning    = "Warning"
+)
+
+// TeamsMessage is the message of microsoft teams incoming webhook with an adaptive card attachment
+type TeamsMessage struct {
+	Type        string             `json:"type"`
+	Attachments []*TeamsAttachment `json:"attachments"`
+}
+
+type TeamsAttachment struct {
+	ContentType string             `json:"contentType"`
+	Content     *TeamsAdaptiveCard `json:"content"`
+}
+
+type TeamsAdaptiveCard struct {
+	Schema  string          `json:"$schema"`
+	Type    string          `json:"type"`
+	Version string          `json:"version"`
+	Body    []*TeamsElement `json:"body"`
+	Actions []*TeamsAction  `json:"actions,omitempty"`
+}
+
+type TeamsElement struct {
+	Type   string       `json:"type"`
+	Text   string       `json:"text,omitempty"`
+	Weight string       `json:"weight,omitempty"`
+	Size   string       `json:"size,omitempty"`
+	Color  string       `json:"color,omitempty"`
+	Wrap   bool         `json:"wrap,omitempty"`
+	Facts  []*TeamsFact `json:"facts,omitempty"`
+}
+
+type TeamsFact struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+type TeamsAction struct {
+	Type  string `json:"type"`
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+func (w *Service) sendTeamsMessage(uri string, message *workflowTaskMessage) error {
+	_, err := w.SendMessageRequest(uri, getTeamsMessage(message))
+	return err
+}
+
+func getTeamsMessage(message *workflowTaskMessage) *TeamsMessage {
+	facts := []*TeamsFact{}
+	for _, field := range message.Fields {
+		facts = append(facts, &TeamsFact{Title: field.Name, Value: field.Value})
+	}
+	body := []*TeamsElement{
+		{Type: teamsElementTextBlock, Text: message.Title, Weight: teamsTextWeightBolder, Size: teamsTextSizeMedium, Color: getTeamsTextColor(message), Wrap: true},
+		{Type: teamsElementFactSet, Facts: facts},
+	}
+	if message.Text != "" {
+		body = append(body, &TeamsElement{Type: teamsElementTextBlock, Text: message.Text, Wrap: true})
+	}
+
+	return &TeamsMessage{
+		Type: teamsMessageType,
+		Attachments: []*TeamsAttachment{{
+			ContentType: teamsAdaptiveCardContent,
+			Content: &TeamsAdaptiveCard{
+				Schema:  teamsAdaptiveCardSchema,
+				Type:    teamsAdaptiveCardType,
+				Version: teamsAdaptiveCardVersion,
+				Body:    body,
+				Actions: []*TeamsAction{{Type: teamsActionOpenURL, Title: "View Details", URL: message.DetailURL}},
+			},
+		}},
+	}
+}
+
+func getTeamsTextColor(message *workflowTaskMessage) string {
+	if message.NotifyType == string(config.StatusWaitingApprove) {
+		return teamsTextColorWarning
+	}
+	switch message.Task.Status {
+	case config.StatusPassed, config.StatusCreated:
+		return teamsTextColorGood
+	case config.StatusFailed, config.StatusTimeout, config.StatusReject:
+		return teamsTextColorAttention
+	default:
+		return teamsTextColorWarning
+	}
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instantmessage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/tool/crypto"
+	"github.com/koderover/zadig/pkg/tool/httpclient"
+)
+
+const (
+	webHookNotifyType = "webhook"
+
+	webHookEventHeader     = "X-Zadig-Event"
+	webHookTimestampHeader = "X-Zadig-Timestamp"
+	// webHookSignatureHeader is in the format of sha256={hex encoded HMAC-SHA256 of "{timestamp}.{body}"}
+	webHookSignatureHeader = "X-Zadig-Signature"
+)
+
+// WebHookMessage is the json body posted to the generic webhook
+type WebHookMessage struct {
+	Event               string            `json:"event"`
+	Title               string            `json:"title"`
+	Text                string            `json:"text"`
+	DetailURL           string            `json:"detail_url"`
+	ProjectName         string            `json:"project_name"`
+	WorkflowName        string            `json:"workflow_name"`
+	WorkflowDisplayName string            `json:"workflow_display_name"`
+	TaskID              int64             `json:"task_id"`
+	Status              config.Status     `json:"status"`
+	Creator             string            `json:"creator"`
+	StartTime           int64             `json:"start_time"`
+	EndTime             int64             `json:"end_time"`
+	Fields              map[string]string `json:"fields"`
+	Jobs                []*WebHookJob     `json:"jobs"`
+}
+
+type WebHookJob struct {
+	Stage   string        `json:"stage"`
+	Name    string        `json:"name"`
+	JobType string        `json:"job_type"`
+	Status  config.Status `json:"status"`
+	Error   string        `json:"error,omitempty"`
+}
+
+func (w *Service) sendWebHookMessage(notify *models.WebHookNotify, message *workflowTaskMessage) error {
+	task := message.Task
+	body := &WebHookMessage{
+		Event:               message.NotifyType,
+		Title:               message.Title,
+		Text:                message.Text,
+		DetailURL:           message.DetailURL,
+		ProjectName:         task.ProjectName,
+		WorkflowName:        task.WorkflowName,
+		WorkflowDisplayName: task.WorkflowDisplayName,
+		TaskID:              task.TaskID,
+		Status:              task.Status,
+		Creator:             task.TaskCreator,
+		StartTime:           task.StartTime,
+		EndTime:             task.EndTime,
+		Fields:              map[string]string{},
+		Jobs:                []*WebHookJob{},
+	}
+	for _, field := range message.Fields {
+		body.Fields[field.Name] = field.Value
+	}
+	for _, stage := range task.Stages {
+		for _, job := range stage.Jobs {
+			body.Jobs = append(body.Jobs, &WebHookJob{
+				Stage:   stage.Name,
+				Name:    job.Name,
+				JobType: job.JobType,
+				Status:  job.Status,
+				Error:   job.Error,
+			})
+		}
+	}
+	// the body is marshaled here so that the signature is computed on the exact bytes sent
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	headers := map[string]string{
+		"Content-Type":     "application/json",
+		webHookEventHeader: message.NotifyType,
+	}
+	if notify.EncryptedSecret != "" {
+		secret, err := crypto.AesDecrypt(notify.EncryptedSecret)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt webhook secret: %s", err)
+		}
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		headers[webHookTimestampHeader] = timestamp
+		headers[webHookSignatureHeader] = "sha256=" + signWebHookPayload(secret, timestamp, payload)
+	}
+
+	_, err = w.SendMessageRequest(notify.Address, payload, httpclient.SetHeaders(headers))
+	return err
+}
+
+func signWebHookPayload(secret, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
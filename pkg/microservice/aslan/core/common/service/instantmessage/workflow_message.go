@@ -0,0 +1,197 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instantmessage
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+
+	configbase "github.com/koderover/zadig/pkg/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+)
+
+// workflowTaskMessage is the IM independent content of a workflow task notification,
+// it is rendered to the message of slack, teams and the generic webhook.
+type workflowTaskMessage struct {
+	NotifyType string
+	Title      string
+	// Text is rendered from the message template of the notify type, it lists the jobs if there is no template
+	Text      string
+	Fields    []*messageField
+	DetailURL string
+	Task      *models.WorkflowTask
+}
+
+type messageField struct {
+	Name  string
+	Value string
+}
+
+// messageTemplateArgs is the data used to render the message templates of NotifyCtl
+type messageTemplateArgs struct {
+	Task               *models.WorkflowTask
+	NotifyType         string
+	BaseURI            string
+	DetailURL          string
+	TotalTime          int64
+	EscalatedApprovers string
+}
+
+var messageTemplateFuncs = template.FuncMap{
+	"taskStatus": messageStatusText,
+	"getStartTime": func(startTime int64) string {
+		return time.Unix(startTime, 0).Format("2006-01-02 15:04:05")
+	},
+	"getDuration": func(seconds int64) string {
+		return (time.Duration(seconds) * time.Second).String()
+	},
+}
+
+// IsWorkflowMessageType returns true if the webhook type is only supported by workflow v4 notifications
+func IsWorkflowMessageType(webHookType string) bool {
+	return webHookType == slackType || webHookType == teamsType || webHookType == webHookNotifyType
+}
+
+// LintNotifyCtl checks the address and message templates of the slack, teams and generic webhook notifications
+func LintNotifyCtl(notify *models.NotifyCtl) error {
+	switch notify.WebHookType {
+	case slackType:
+		if notify.SlackWebHook == "" {
+			return fmt.Errorf("slack webhook should not be empty")
+		}
+	case teamsType:
+		if notify.TeamsWebHook == "" {
+			return fmt.Errorf("teams webhook should not be empty")
+		}
+	case webHookNotifyType:
+		if notify.WebHookNotify == nil || notify.WebHookNotify.Address == "" {
+			return fmt.Errorf("webhook address should not be empty")
+		}
+	}
+	for notifyType, tpl := range notify.MessageTemplates {
+		if _, err := template.New(notifyType).Funcs(messageTemplateFuncs).Parse(tpl); err != nil {
+			return fmt.Errorf("invalid message template of %s: %v", notifyType, err)
+		}
+	}
+	return nil
+}
+
+func messageStatusText(status config.Status) string {
+	switch status {
+	case config.StatusPassed:
+		return "passed"
+	case config.StatusCancelled:
+		return "cancelled"
+	case config.StatusTimeout:
+		return "timeout"
+	case config.StatusReject:
+		return "rejected"
+	case config.StatusCreated:
+		return "started"
+	case config.StatusWaitingApprove:
+		return "waiting for approval"
+	case "":
+		return "not run"
+	}
+	return "failed"
+}
+
+func getWorkflowTaskMessage(notify *models.NotifyCtl, task *models.WorkflowTask, notifyType string, escalatedApprovers []string) (*workflowTaskMessage, error) {
+	detailURL := fmt.Sprintf("%s/v1/projects/detail/%s/pipelines/custom/%s/%d?display_name=%s",
+		configbase.SystemAddress(), task.ProjectName, task.WorkflowName, task.TaskID, url.PathEscape(task.WorkflowDisplayName))
+	totalTime := time.Now().Unix() - task.StartTime
+	if task.EndTime > 0 {
+		totalTime = task.EndTime - task.StartTime
+	}
+
+	message := &workflowTaskMessage{
+		NotifyType: notifyType,
+		DetailURL:  detailURL,
+		Task:       task,
+		Fields: []*messageField{
+			{Name: "Creator", Value: task.TaskCreator},
+			{Name: "Project", Value: task.ProjectName},
+			{Name: "Start Time", Value: time.Unix(task.StartTime, 0).Format("2006-01-02 15:04:05")},
+			{Name: "Duration", Value: (time.Duration(totalTime) * time.Second).String()},
+		},
+	}
+	switch {
+	case len(escalatedApprovers) > 0:
+		message.Title = fmt.Sprintf("Workflow %s #%d approval is escalated", task.WorkflowDisplayName, task.TaskID)
+		message.Fields = append(message.Fields, &messageField{Name: "Escalated Approvers", Value: strings.Join(escalatedApprovers, ",")})
+	case notifyType == string(config.StatusWaitingApprove):
+		message.Title = fmt.Sprintf("Workflow %s #%d is waiting for approval", task.WorkflowDisplayName, task.TaskID)
+	default:
+		message.Title = fmt.Sprintf("Workflow %s #%d %s", task.WorkflowDisplayName, task.TaskID, messageStatusText(task.Status))
+	}
+
+	tpl, ok := notify.MessageTemplates[notifyType]
+	if !ok {
+		jobs := []string{}
+		for _, stage := range task.Stages {
+			for _, job := range stage.Jobs {
+				jobs = append(jobs, fmt.Sprintf("%s: %s", job.Name, messageStatusText(job.Status)))
+			}
+		}
+		message.Text = strings.Join(jobs, "\n")
+		return message, nil
+	}
+
+	tmpl, err := template.New(notifyType).Funcs(messageTemplateFuncs).Parse(tpl)
+	if err != nil {
+		return nil, fmt.Errorf("parse message template of %s error: %v", notifyType, err)
+	}
+	buffer := &bytes.Buffer{}
+	err = tmpl.Execute(buffer, &messageTemplateArgs{
+		Task:               task,
+		NotifyType:         notifyType,
+		BaseURI:            configbase.SystemAddress(),
+		DetailURL:          detailURL,
+		TotalTime:          totalTime,
+		EscalatedApprovers: strings.Join(escalatedApprovers, ","),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("execute message template of %s error: %v", notifyType, err)
+	}
+	message.Text = buffer.String()
+	return message, nil
+}
+
+func (w *Service) sendWorkflowTaskMessage(notify *models.NotifyCtl, task *models.WorkflowTask, notifyType string, escalatedApprovers []string) error {
+	message, err := getWorkflowTaskMessage(notify, task, notifyType, escalatedApprovers)
+	if err != nil {
+		return err
+	}
+	switch notify.WebHookType {
+	case slackType:
+		return w.sendSlackMessage(notify.SlackWebHook, message)
+	case teamsType:
+		return w.sendTeamsMessage(notify.TeamsWebHook, message)
+	case webHookNotifyType:
+		if notify.WebHookNotify == nil {
+			return fmt.Errorf("webhook address not found")
+		}
+		return w.sendWebHookMessage(notify.WebHookNotify, message)
+	default:
+		return fmt.Errorf("unsupported webhook type %s", notify.WebHookType)
+	}
+}
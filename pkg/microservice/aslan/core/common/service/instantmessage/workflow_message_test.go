@@ -0,0 +1,142 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instantmessage
+
+import (
+	"encoding/json"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+)
+
+var _ = Describe("Testing workflow task messages", func() {
+	task := &models.WorkflowTask{
+		TaskID:              12,
+		WorkflowName:        "deploy",
+		WorkflowDisplayName: "部署",
+		ProjectName:         "demo",
+		TaskCreator:         "admin",
+		Status:              config.StatusFailed,
+		StartTime:           1700000000,
+		EndTime:             1700000090,
+		Stages: []*models.StageTask{{
+			Name: "build",
+			Jobs: []*models.JobTask{
+				{Name: "build-app", Status: config.StatusPassed},
+				{Name: "deploy-app", Status: config.StatusFailed},
+			},
+		}},
+	}
+	templateNotify := &models.NotifyCtl{MessageTemplates: map[string]string{
+		string(config.StatusFailed): "{{.Task.WorkflowDisplayName}} #{{.Task.TaskID}} {{taskStatus .Task.Status}} in {{getDuration .TotalTime}}",
+	}}
+
+	Context("message template", func() {
+		It("should render the text from the template of the notify type", func() {
+			message, err := getWorkflowTaskMessage(templateNotify, task, string(config.StatusFailed), nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(message.Title).To(Equal("Workflow 部署 #12 failed"))
+			Expect(message.Text).To(Equal("部署 #12 failed in 1m30s"))
+		})
+		It("should list the jobs without a template", func() {
+			message, err := getWorkflowTaskMessage(&models.NotifyCtl{}, task, string(config.StatusFailed), nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(message.Text).To(Equal("build-app: passed\ndeploy-app: failed"))
+		})
+		It("should fail if the template can't be executed", func() {
+			notify := &models.NotifyCtl{MessageTemplates: map[string]string{string(config.StatusFailed): "{{.Task.Unknown}}"}}
+			_, err := getWorkflowTaskMessage(notify, task, string(config.StatusFailed), nil)
+			Expect(err).Should(HaveOccurred())
+		})
+	})
+
+	Context("slack", func() {
+		It("should render the message in block kit", func() {
+			message, err := getWorkflowTaskMessage(templateNotify, task, string(config.StatusFailed), []string{"ops"})
+			Expect(err).ShouldNot(HaveOccurred())
+			body, err := json.Marshal(getSlackMessage(message))
+			Expect(err).ShouldNot(HaveOccurred())
+
+			slackMessage := &SlackMessage{}
+			Expect(json.Unmarshal(body, slackMessage)).To(Succeed())
+			Expect(slackMessage.Text).To(Equal("Workflow 部署 #12 approval is escalated"))
+			Expect(slackMessage.Blocks).To(HaveLen(4))
+			Expect(slackMessage.Blocks[0]).To(Equal(&SlackBlock{Type: slackBlockHeader, Text: &SlackText{Type: slackTextPlain, Text: slackMessage.Text}}))
+			Expect(slackMessage.Blocks[1].Type).To(Equal(slackBlockSection))
+			Expect(slackMessage.Blocks[1].Fields).To(ContainElements(
+				&SlackText{Type: slackTextMarkdown, Text: "*Creator*\nadmin"},
+				&SlackText{Type: slackTextMarkdown, Text: "*Escalated Approvers*\nops"},
+			))
+			Expect(slackMessage.Blocks[2]).To(Equal(&SlackBlock{Type: slackBlockSection, Text: &SlackText{Type: slackTextMarkdown, Text: "部署 #12 failed in 1m30s"}}))
+			Expect(slackMessage.Blocks[3].Type).To(Equal(slackBlockActions))
+			Expect(slackMessage.Blocks[3].Elements).To(Equal([]*SlackElement{{
+				Type: slackElementButton,
+				Text: &SlackText{Type: slackTextPlain, Text: "View Details"},
+				URL:  message.DetailURL,
+			}}))
+		})
+		It("should truncate the header by characters", func() {
+			header := getSlackMessage(&workflowTaskMessage{Title: strings.Repeat("部署", slackMaxHeaderLength)}).Blocks[0].Text.Text
+			Expect(header).To(Equal(strings.Repeat("部署", slackMaxHeaderLength/2)))
+		})
+	})
+
+	Context("teams", func() {
+		It("should render the message in an adaptive card", func() {
+			message, err := getWorkflowTaskMessage(templateNotify, task, string(config.StatusFailed), nil)
+			Expect(err).ShouldNot(HaveOccurred())
+			body, err := json.Marshal(getTeamsMessage(message))
+			Expect(err).ShouldNot(HaveOccurred())
+
+			teamsMessage := &TeamsMessage{}
+			Expect(json.Unmarshal(body, teamsMessage)).To(Succeed())
+			Expect(teamsMessage.Type).To(Equal(teamsMessageType))
+			Expect(teamsMessage.Attachments).To(HaveLen(1))
+			Expect(teamsMessage.Attachments[0].ContentType).To(Equal(teamsAdaptiveCardContent))
+
+			card := teamsMessage.Attachments[0].Content
+			Expect(card.Schema).To(Equal(teamsAdaptiveCardSchema))
+			Expect(card.Type).To(Equal(teamsAdaptiveCardType))
+			Expect(card.Version).To(Equal(teamsAdaptiveCardVersion))
+			Expect(card.Body).To(HaveLen(3))
+			Expect(card.Body[0]).To(Equal(&TeamsElement{
+				Type:   teamsElementTextBlock,
+				Text:   "Workflow 部署 #12 failed",
+				Weight: teamsTextWeightBolder,
+				Size:   teamsTextSizeMedium,
+				Color:  teamsTextColorAttention,
+				Wrap:   true,
+			}))
+			Expect(card.Body[1].Type).To(Equal(teamsElementFactSet))
+			Expect(card.Body[1].Facts).To(ContainElements(
+				&TeamsFact{Title: "Project", Value: "demo"},
+				&TeamsFact{Title: "Duration", Value: "1m30s"},
+			))
+			Expect(card.Body[2]).To(Equal(&TeamsElement{Type: teamsElementTextBlock, Text: "部署 #12 failed in 1m30s", Wrap: true}))
+			Expect(card.Actions).To(Equal([]*TeamsAction{{Type: teamsActionOpenURL, Title: "View Details", URL: message.DetailURL}}))
+		})
+		It("should color the title by the notify type and the task status", func() {
+			Expect(getTeamsTextColor(&workflowTaskMessage{NotifyType: string(config.StatusWaitingApprove), Task: task})).To(Equal(teamsTextColorWarning))
+			Expect(getTeamsTextColor(&workflowTaskMessage{Task: &models.WorkflowTask{Status: config.StatusPassed}})).To(Equal(teamsTextColorGood))
+			Expect(getTeamsTextColor(&workflowTaskMessage{Task: task})).To(Equal(teamsTextColorAttention))
+		})
+	})
+})
@@ -38,6 +38,15 @@ import (
 )
 
 func (w *Service) SendWorkflowTaskAproveNotifications(workflowName string, taskID int64) error {
+	return w.sendWorkflowTaskApproveNotifications(workflowName, taskID, nil)
+}
+
+// SendWorkflowTaskApproveEscalationNotifications tells the escalated approvers that the approval is waiting for them
+func (w *Service) SendWorkflowTaskApproveEscalationNotifications(workflowName string, taskID int64, approvers []string) error {
+	return w.sendWorkflowTaskApproveNotifications(workflowName, taskID, approvers)
+}
+
+func (w *Service) sendWorkflowTaskApproveNotifications(workflowName string, taskID int64, escalatedApprovers []string) error {
 	resp, err := w.workflowV4Coll.Find(workflowName)
 	if err != nil {
 		errMsg := fmt.Sprintf("failed to find workflowv4, err: %s", err)
@@ -58,7 +67,13 @@ func (w *Service) SendWorkflowTaskAproveNotifications(workflowName string, taskI
 		if !notify.Enabled {
 			continue
 		}
-		title, content, larkCard, err := w.getApproveNotificationContent(notify, task)
+		if IsWorkflowMessageType(notify.WebHookType) {
+			if err := w.sendWorkflowTaskMessage(notify, task, string(config.StatusWaitingApprove), escalatedApprovers); err != nil {
+				log.Errorf("failed to send %s notification, err: %s", notify.WebHookType, err)
+			}
+			continue
+		}
+		title, content, larkCard, err := w.getApproveNotificationContent(notify, task, escalatedApprovers)
 		if err != nil {
 			errMsg := fmt.Sprintf("failed to get notification content, err: %s", err)
 			log.Error(errMsg)
@@ -103,6 +118,16 @@ func (w *Service) SendWorkflowTaskNotifications(task *models.WorkflowTask) error
 		}
 		statusSets := sets.NewString(notify.NotifyTypes...)
 		if statusSets.Has(string(task.Status)) || (statusChanged && statusSets.Has(string(config.StatusChanged))) {
+			if IsWorkflowMessageType(notify.WebHookType) {
+				notifyType := string(task.Status)
+				if !statusSets.Has(notifyType) {
+					notifyType = string(config.StatusChanged)
+				}
+				if err := w.sendWorkflowTaskMessage(notify, task, notifyType, nil); err != nil {
+					log.Errorf("failed to send %s notification, err: %s", notify.WebHookType, err)
+				}
+				continue
+			}
 			title, content, larkCard, err := w.getNotificationContent(notify, task)
 			if err != nil {
 				errMsg := fmt.Sprintf("failed to get notification content, err: %s", err)
@@ -116,13 +141,14 @@ func (w *Service) SendWorkflowTaskNotifications(task *models.WorkflowTask) error
 	}
 	return nil
 }
-func (w *Service) getApproveNotificationContent(notify *models.NotifyCtl, task *models.WorkflowTask) (string, string, *LarkCard, error) {
+func (w *Service) getApproveNotificationContent(notify *models.NotifyCtl, task *models.WorkflowTask, escalatedApprovers []string) (string, string, *LarkCard, error) {
 	workflowNotification := &workflowTaskNotification{
 		Task:               task,
 		EncodedDisplayName: url.PathEscape(task.WorkflowDisplayName),
 		BaseURI:            configbase.SystemAddress(),
 		WebHookType:        notify.WebHookType,
 		TotalTime:          time.Now().Unix() - task.StartTime,
+		EscalatedApprovers: strings.Join(escalatedApprovers, ","),
 	}
 
 	tplTitle := "{{if ne .WebHookType \"feishu\"}}#### {{end}}{{getIcon .Task.Status }}{{if eq .WebHookType \"wechat\"}}<font color=\"markdownColorInfo\">工作流{{.Task.WorkflowDisplayName}} #{{.Task.TaskID}} 等待审批</font>{{else}}工作流 {{.Task.WorkflowDisplayName}} #{{.Task.TaskID}} 等待审批{{end}} \n"
@@ -131,6 +157,10 @@ func (w *Service) getApproveNotificationContent(notify *models.NotifyCtl, task *
 		"{{if eq .WebHookType \"dingding\"}}##### {{end}}**开始时间**：{{ getStartTime .Task.StartTime}} \n",
 		"{{if eq .WebHookType \"dingding\"}}##### {{end}}**持续时间**：{{ getDuration .TotalTime}} \n",
 	}
+	if len(escalatedApprovers) > 0 {
+		tplTitle = "{{if ne .WebHookType \"feishu\"}}#### {{end}}{{getIcon .Task.Status }}{{if eq .WebHookType \"wechat\"}}<font color=\"markdownColorInfo\">工作流{{.Task.WorkflowDisplayName}} #{{.Task.TaskID}} 审批已升级</font>{{else}}工作流 {{.Task.WorkflowDisplayName}} #{{.Task.TaskID}} 审批已升级{{end}} \n"
+		tplBaseInfo = append(tplBaseInfo, "{{if eq .WebHookType \"dingding\"}}##### {{end}}**升级审批人**：{{.EscalatedApprovers}} \n")
+	}
 	title, err := getWorkflowTaskTplExec(tplTitle, workflowNotification)
 	if err != nil {
 		return "", "", nil, err
@@ -330,6 +360,7 @@ type workflowTaskNotification struct {
 	BaseURI            string               `json:"base_uri"`
 	WebHookType        string               `json:"web_hook_type"`
 	TotalTime          int64                `json:"total_time"`
+	EscalatedApprovers string               `json:"escalated_approvers"`
 }
 
 func getWorkflowTaskTplExec(tplcontent string, args *workflowTaskNotification) (string, error) {
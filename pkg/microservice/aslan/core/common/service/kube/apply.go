@@ -71,6 +71,8 @@ type ResourceApplyParam struct {
 	InjectSecrets    bool
 	SharedEnvHandler SharedEnvHandler
 	Uninstall        bool
+	// GitOpsBatch commits the manifests with the other services of the deploy if the env is in gitops mode
+	GitOpsBatch *GitOpsBatchJob
 }
 
 func DeploymentSelectorLabelExists(resourceName, namespace string, informer informers.SharedInformerFactory, log *zap.SugaredLogger) bool {
@@ -236,6 +238,14 @@ func CreateOrPatchResource(applyParam *ResourceApplyParam, log *zap.SugaredLogge
 		return nil, err
 	}
 
+	// the manifests of the gitops env are committed to its repository and applied by the gitops controller
+	if productInfo.GitOps.IsEnabled() {
+		if err := commitGitOpsManifests(applyParam, resources); err != nil {
+			return nil, errors.Wrapf(err, "failed to commit manifests to gitops repository")
+		}
+		return resources, nil
+	}
+
 	clientSet, errGetClientSet := kubeclient.GetKubeClientSet(config.HubServerAddress(), productInfo.ClusterID)
 	if errGetClientSet != nil {
 		err = errors.WithMessagef(errGetClientSet, "failed to init k8s clientset")
@@ -0,0 +1,374 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/yaml"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/github"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/shared/client/systemconfig"
+	gitlabtool "github.com/koderover/zadig/pkg/tool/git/gitlab"
+	"github.com/koderover/zadig/pkg/tool/kube/getter"
+	"github.com/koderover/zadig/pkg/util"
+)
+
+var (
+	argoCDApplicationGVK = schema.GroupVersionKind{
+		Group:   "argoproj.io",
+		Version: "v1alpha1",
+		Kind:    "Application",
+	}
+	fluxKustomizationGVKs = []schema.GroupVersionKind{
+		{Group: "kustomize.toolkit.fluxcd.io", Version: "v1", Kind: "Kustomization"},
+		{Group: "kustomize.toolkit.fluxcd.io", Version: "v1beta2", Kind: "Kustomization"},
+	}
+)
+
+// GitOpsManifestPath is the file the manifests of the k8s yaml service are committed to
+func GitOpsManifestPath(gitOps *commonmodels.EnvGitOpsConfig, serviceName string) string {
+	return path.Join(gitOps.Path, serviceName+".yaml")
+}
+
+// GitOpsValuesPath is the file the values of the helm release are committed to
+func GitOpsValuesPath(gitOps *commonmodels.EnvGitOpsConfig, releaseName string) string {
+	return path.Join(gitOps.Path, releaseName, "values.yaml")
+}
+
+type gitOpsBatchKey struct{}
+
+// GitOpsBatch collects the files of the services deployed by the jobs of a workflow stage, the files of each env are
+// committed to its gitops repository in a single commit and merge request by Commit after the jobs finish
+type GitOpsBatch struct {
+	// name identifies the batch in the branch and the commit message, e.g. the workflow task
+	name   string
+	commit GitOpsCommitFunc
+
+	mu   sync.Mutex
+	envs map[string]*gitOpsBatchEnv
+}
+
+type gitOpsBatchEnv struct {
+	productName string
+	envName     string
+	gitOps      *commonmodels.EnvGitOpsConfig
+	files       map[string]*string
+	changes     []string
+	jobs        sets.String
+}
+
+// GitOpsBatchJob adds the files of a job to the batch
+type GitOpsBatchJob struct {
+	batch   *GitOpsBatch
+	jobName string
+}
+
+// GitOpsCommitFunc commits the files of the env to its gitops repository, the file is removed if its content is nil
+type GitOpsCommitFunc func(productName, envName, batchName string, gitOps *commonmodels.EnvGitOpsConfig, files map[string]*string, message string) error
+
+func NewGitOpsBatch(name string) *GitOpsBatch {
+	return NewGitOpsBatchWithCommitter(name, commitGitOpsFiles)
+}
+
+// NewGitOpsBatchWithCommitter creates the batch whose files are committed by the committer
+func NewGitOpsBatchWithCommitter(name string, commit GitOpsCommitFunc) *GitOpsBatch {
+	return &GitOpsBatch{name: name, commit: commit, envs: make(map[string]*gitOpsBatchEnv)}
+}
+
+func ContextWithGitOpsBatch(ctx context.Context, batch *GitOpsBatch) context.Context {
+	return context.WithValue(ctx, gitOpsBatchKey{}, batch)
+}
+
+// GitOpsBatchFromContext returns nil if the files are committed by each deploy
+func GitOpsBatchFromContext(ctx context.Context) *GitOpsBatch {
+	batch, _ := ctx.Value(gitOpsBatchKey{}).(*GitOpsBatch)
+	return batch
+}
+
+func (b *GitOpsBatch) Job(jobName string) *GitOpsBatchJob {
+	if b == nil {
+		return nil
+	}
+	return &GitOpsBatchJob{batch: b, jobName: jobName}
+}
+
+// Add adds the files of the services deployed by the job in the env to the batch
+func (j *GitOpsBatchJob) Add(productName, envName string, gitOps *commonmodels.EnvGitOpsConfig, files map[string]*string, change string) {
+	b := j.batch
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	key := productName + "/" + envName
+	env, ok := b.envs[key]
+	if !ok {
+		env = &gitOpsBatchEnv{
+			productName: productName,
+			envName:     envName,
+			gitOps:      gitOps,
+			files:       make(map[string]*string),
+			jobs:        sets.NewString(),
+		}
+		b.envs[key] = env
+	}
+	for filePath, content := range files {
+		env.files[filePath] = content
+	}
+	env.changes = append(env.changes, change)
+	env.jobs.Insert(j.jobName)
+}
+
+// Commit commits the files collected for each env, the errors are returned by the names of the jobs whose files are
+// not committed
+func (b *GitOpsBatch) Commit() map[string]error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	errs := make(map[string]error)
+	for _, env := range b.envs {
+		sort.Strings(env.changes)
+		message := fmt.Sprintf("Deploy %s/%s by %s\n\n%s", env.productName, env.envName, b.name, strings.Join(env.changes, "\n"))
+		if err := b.commit(env.productName, env.envName, b.name, env.gitOps, env.files, message); err != nil {
+			for _, jobName := range env.jobs.List() {
+				errs[jobName] = err
+			}
+		}
+	}
+	b.envs = make(map[string]*gitOpsBatchEnv)
+	return errs
+}
+
+// commitGitOpsManifests commits the rendered manifests of the service to the gitops repository of the env instead of
+// applying them, the file of the service is removed when it's uninstalled. The files are committed with the other
+// services of the deploy if the deploy is batched.
+func commitGitOpsManifests(applyParam *ResourceApplyParam, resources []*unstructured.Unstructured) error {
+	productInfo := applyParam.ProductInfo
+	filePath := GitOpsManifestPath(productInfo.GitOps, applyParam.ServiceName)
+	if applyParam.Uninstall {
+		return addGitOpsFiles(applyParam.GitOpsBatch, productInfo.ProductName, productInfo.EnvName, productInfo.GitOps,
+			map[string]*string{filePath: nil}, fmt.Sprintf("Remove service %s", applyParam.ServiceName))
+	}
+
+	labels := GetPredefinedLabels(productInfo.ProductName, applyParam.ServiceName)
+	manifests := make([]string, 0, len(resources))
+	for _, u := range resources {
+		u.SetNamespace(productInfo.Namespace)
+		if applyParam.AddZadigLabel {
+			u.SetLabels(MergeLabels(labels, u.GetLabels()))
+			switch u.GetKind() {
+			case setting.Deployment, setting.StatefulSet:
+				podLabels, _, _ := unstructured.NestedStringMap(u.Object, "spec", "template", "metadata", "labels")
+				if err := unstructured.SetNestedStringMap(u.Object, MergeLabels(labels, podLabels), "spec", "template", "metadata", "labels"); err != nil {
+					return fmt.Errorf("failed to set pod labels of %s/%s: %s", u.GetKind(), u.GetName(), err)
+				}
+			}
+		}
+		manifest, err := yaml.Marshal(u.Object)
+		if err != nil {
+			return fmt.Errorf("failed to marshal %s/%s: %s", u.GetKind(), u.GetName(), err)
+		}
+		manifests = append(manifests, string(manifest))
+	}
+	content := strings.Join(manifests, "---\n")
+
+	return addGitOpsFiles(applyParam.GitOpsBatch, productInfo.ProductName, productInfo.EnvName, productInfo.GitOps,
+		map[string]*string{filePath: &content}, fmt.Sprintf("Update service %s", applyParam.ServiceName))
+}
+
+// commitGitOpsValues commits the values of the helm release to the gitops repository of the env instead of installing
+// it, the chart is expected to be referenced by the gitops controller
+func commitGitOpsValues(param *ReleaseInstallParam) error {
+	content := param.MergedValues
+	if param.RenderChart != nil {
+		content = fmt.Sprintf("# chart: %s, version: %s\n%s", param.RenderChart.ChartName, param.RenderChart.ChartVersion, content)
+	}
+	return addGitOpsFiles(param.GitOpsBatch, param.ProductName, param.EnvName, param.GitOps,
+		map[string]*string{GitOpsValuesPath(param.GitOps, param.ReleaseName): &content}, fmt.Sprintf("Update release %s", param.ReleaseName))
+}
+
+// addGitOpsFiles adds the files to the batch, or commits them at once if the deploy is not batched
+func addGitOpsFiles(batch *GitOpsBatchJob, productName, envName string, gitOps *commonmodels.EnvGitOpsConfig, files map[string]*string, change string) error {
+	if batch != nil {
+		batch.Add(productName, envName, gitOps, files, change)
+		return nil
+	}
+	return commitGitOpsFiles(productName, envName, fmt.Sprint(time.Now().Unix()), gitOps, files, fmt.Sprintf("%s in %s/%s", change, productName, envName))
+}
+
+// commitGitOpsFiles pushes the files to the branch of the gitops repository, or opens a merge request to it from a new
+// branch named after the batch, and records the commit in the gitops status of the env
+func commitGitOpsFiles(productName, envName, batchName string, gitOps *commonmodels.EnvGitOpsConfig, files map[string]*string, message string) error {
+	codehost, err := systemconfig.New().GetCodeHost(gitOps.CodehostID)
+	if err != nil {
+		return fmt.Errorf("failed to get codehost %d of gitops: %s", gitOps.CodehostID, err)
+	}
+
+	branch := gitOps.Branch
+	title := strings.SplitN(message, "\n", 2)[0]
+	if gitOps.MergeRequest {
+		// the random suffix keeps the branch unique if the batch is committed again, e.g. the task is restarted
+		branch = fmt.Sprintf("zadig/%s-%s-%s-%s", productName, envName, batchName, strings.ToLower(util.GetRandomString(6)))
+	}
+	status := &commonmodels.EnvGitOpsStatus{
+		SyncStatus: config.GitOpsSyncStatusOutOfSync,
+		CommitTime: time.Now().Unix(),
+		UpdateTime: time.Now().Unix(),
+	}
+
+	switch strings.ToLower(codehost.Type) {
+	case setting.SourceFromGitlab:
+		cli, err := gitlabtool.NewClient(codehost.ID, codehost.Address, codehost.AccessToken, config.ProxyHTTPSAddr(), codehost.EnableProxy)
+		if err != nil {
+			return fmt.Errorf("failed to create gitlab client: %s", err)
+		}
+		commit, err := cli.CommitFiles(gitOps.GetRepoNamespace(), gitOps.RepoName, branch, gitOps.Branch, message, files)
+		if err != nil {
+			return fmt.Errorf("failed to commit to %s/%s: %s", gitOps.GetRepoNamespace(), gitOps.RepoName, err)
+		}
+		if commit == nil {
+			return nil
+		}
+		status.Revision = commit.ID
+		if gitOps.MergeRequest {
+			mr, err := cli.CreateMergeRequest(gitOps.GetRepoNamespace(), gitOps.RepoName, branch, gitOps.Branch, title, message)
+			if err != nil {
+				return fmt.Errorf("failed to create merge request to %s/%s: %s", gitOps.GetRepoNamespace(), gitOps.RepoName, err)
+			}
+			status.MergeRequestURL = mr.WebURL
+		}
+	case setting.SourceFromGithub:
+		ctx := context.TODO()
+		cli := github.NewClient(codehost.AccessToken, config.ProxyHTTPSAddr(), codehost.EnableProxy)
+		commit, err := cli.CommitFiles(ctx, gitOps.GetRepoNamespace(), gitOps.RepoName, branch, gitOps.Branch, message, files)
+		if err != nil {
+			return fmt.Errorf("failed to commit to %s/%s: %s", gitOps.GetRepoNamespace(), gitOps.RepoName, err)
+		}
+		if commit == nil {
+			return nil
+		}
+		status.Revision = commit.GetSHA()
+		if gitOps.MergeRequest {
+			pr, err := cli.CreatePullRequest(ctx, gitOps.GetRepoNamespace(), gitOps.RepoName, branch, gitOps.Branch, title, message)
+			if err != nil {
+				return fmt.Errorf("failed to create pull request to %s/%s: %s", gitOps.GetRepoNamespace(), gitOps.RepoName, err)
+			}
+			status.MergeRequestURL = pr.GetHTMLURL()
+		}
+	default:
+		return fmt.Errorf("codehost type %s is not supported by gitops", codehost.Type)
+	}
+
+	return commonrepo.NewProductColl().UpdateGitOpsStatus(envName, productName, status)
+}
+
+// GetGitOpsSyncStatus reads the sync status of the env from the argocd application or the flux kustomization syncing it,
+// the env is out of sync until the controller applies the last revision pushed by zadig
+func GetGitOpsSyncStatus(productInfo *commonmodels.Product) (*commonmodels.EnvGitOpsStatus, error) {
+	status := &commonmodels.EnvGitOpsStatus{SyncStatus: config.GitOpsSyncStatusUnknown}
+	if productInfo.GitOpsStatus != nil {
+		*status = *productInfo.GitOpsStatus
+	}
+	status.UpdateTime = time.Now().Unix()
+
+	gitOps := productInfo.GitOps
+	reader, err := GetKubeAPIReader(productInfo.ClusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch gitOps.Controller {
+	case config.GitOpsControllerArgoCD:
+		app := &unstructured.Unstructured{}
+		app.SetGroupVersionKind(argoCDApplicationGVK)
+		found, err := getter.GetResourceInCache(gitOps.ApplicationNamespace, gitOps.ApplicationName, app, reader)
+		if err != nil || !found {
+			return nil, fmt.Errorf("failed to get argocd application %s/%s, found: %v, err: %v", gitOps.ApplicationNamespace, gitOps.ApplicationName, found, err)
+		}
+		syncStatus, _, _ := unstructured.NestedString(app.Object, "status", "sync", "status")
+		status.SyncedRevision, _, _ = unstructured.NestedString(app.Object, "status", "sync", "revision")
+		status.HealthStatus, _, _ = unstructured.NestedString(app.Object, "status", "health", "status")
+		status.Message, _, _ = unstructured.NestedString(app.Object, "status", "operationState", "message")
+		status.SyncStatus = config.GitOpsSyncStatusOutOfSync
+		if syncStatus == config.GitOpsSyncStatusSynced && revisionApplied(status) {
+			status.SyncStatus = config.GitOpsSyncStatusSynced
+		}
+	case config.GitOpsControllerFlux:
+		var kustomization *unstructured.Unstructured
+		for _, gvk := range fluxKustomizationGVKs {
+			u := &unstructured.Unstructured{}
+			u.SetGroupVersionKind(gvk)
+			found, err := getter.GetResourceInCache(gitOps.ApplicationNamespace, gitOps.ApplicationName, u, reader)
+			if err == nil && found {
+				kustomization = u
+				break
+			}
+		}
+		if kustomization == nil {
+			return nil, fmt.Errorf("failed to get flux kustomization %s/%s", gitOps.ApplicationNamespace, gitOps.ApplicationName)
+		}
+		status.SyncedRevision, _, _ = unstructured.NestedString(kustomization.Object, "status", "lastAppliedRevision")
+		conditions, _, _ := unstructured.NestedSlice(kustomization.Object, "status", "conditions")
+		ready := "Unknown"
+		for _, c := range conditions {
+			condition, ok := c.(map[string]interface{})
+			if !ok || condition["type"] != "Ready" {
+				continue
+			}
+			ready, _ = condition["status"].(string)
+			status.Message, _ = condition["message"].(string)
+		}
+		switch ready {
+		case "True":
+			status.HealthStatus = "Healthy"
+		case "False":
+			status.HealthStatus = "Degraded"
+		default:
+			status.HealthStatus = "Progressing"
+		}
+		status.SyncStatus = config.GitOpsSyncStatusOutOfSync
+		if ready == "True" && revisionApplied(status) {
+			status.SyncStatus = config.GitOpsSyncStatusSynced
+		}
+	default:
+		return nil, fmt.Errorf("gitops controller %s is not supported", gitOps.Controller)
+	}
+
+	return status, nil
+}
+
+// revisionApplied checks the revision pushed by zadig against the one applied by the controller, which is a sha, or a
+// sha prefixed with the branch by flux. The commit of a merge request may be squashed when merged, so only the controller
+// is trusted then.
+func revisionApplied(status *commonmodels.EnvGitOpsStatus) bool {
+	if status.Revision == "" || status.MergeRequestURL != "" {
+		return true
+	}
+	return status.SyncedRevision != "" && strings.HasSuffix(status.SyncedRevision, status.Revision)
+}
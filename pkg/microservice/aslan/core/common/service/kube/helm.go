@@ -70,6 +70,11 @@ type ReleaseInstallParam struct {
 	Timeout        int
 	DryRun         bool
 	Production     bool
+	EnvName        string
+	// GitOps commits the values of the release to the gitops repository of the env instead of installing it
+	GitOps *commonmodels.EnvGitOpsConfig
+	// GitOpsBatch commits the values with the other services of the deploy
+	GitOpsBatch *GitOpsBatchJob
 }
 
 func GetValidMatchData(spec *commonmodels.ImagePathSpec) map[string]string {
@@ -87,6 +92,10 @@ func GetValidMatchData(spec *commonmodels.ImagePathSpec) map[string]string {
 }
 
 func InstallOrUpgradeHelmChartWithValues(param *ReleaseInstallParam, isRetry bool, helmClient *helmtool.HelmClient) error {
+	if param.GitOps.IsEnabled() {
+		return commitGitOpsValues(param)
+	}
+
 	namespace, valuesYaml, renderChart, serviceObj := param.Namespace, param.MergedValues, param.RenderChart, param.ServiceObj
 	base := config.LocalServicePathWithRevision(serviceObj.ProductName, serviceObj.ServiceName, fmt.Sprint(serviceObj.Revision), param.Production)
 	if param.IsChartInstall {
@@ -229,9 +238,10 @@ func GeneMergedValues(productSvc *commonmodels.ProductService, renderSet *common
 	return mergedValuesYaml, nil
 }
 
-// UpgradeHelmRelease upgrades helm release with some specific images
+// UpgradeHelmRelease upgrades helm release with some specific images, the values of the gitops env are added to the
+// gitops batch if it's not nil
 func UpgradeHelmRelease(product *commonmodels.Product, renderSet *commonmodels.RenderSet, productSvc *commonmodels.ProductService,
-	svcTemp *commonmodels.Service, images []string, timeout int) error {
+	svcTemp *commonmodels.Service, images []string, timeout int, gitOpsBatch *GitOpsBatchJob) error {
 	chartInfoMap := renderSet.GetChartRenderMap()
 	chartDeployInfoMap := renderSet.GetChartDeployRenderMap()
 
@@ -300,6 +310,9 @@ func UpgradeHelmRelease(product *commonmodels.Product, renderSet *commonmodels.R
 		ServiceObj:   svcTemp,
 		Timeout:      timeout,
 		Production:   product.Production,
+		EnvName:      product.EnvName,
+		GitOps:       product.GitOps,
+		GitOpsBatch:  gitOpsBatch,
 	}
 	if !productSvc.FromZadig() {
 		param.IsChartInstall = true
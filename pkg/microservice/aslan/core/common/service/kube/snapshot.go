@@ -0,0 +1,208 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/mongo"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/setting"
+	helmtool "github.com/koderover/zadig/pkg/tool/helmclient"
+	"github.com/koderover/zadig/pkg/tool/log"
+)
+
+// envSnapshotRetention is the number of the latest snapshots kept for an env
+const envSnapshotRetention = 100
+
+type EnvSnapshotOption struct {
+	Type        string
+	Description string
+	CreatedBy   string
+	// WorkflowName and TaskID are set if the snapshot is taken by a deploy job, only one snapshot of the env is taken
+	// for a workflow task
+	WorkflowName string
+	TaskID       int64
+}
+
+// CreateEnvSnapshot takes a snapshot of the env and saves it
+func CreateEnvSnapshot(env *commonmodels.Product, opt *EnvSnapshotOption) (*commonmodels.EnvSnapshot, error) {
+	if opt.WorkflowName != "" {
+		snapshot, err := commonrepo.NewEnvSnapshotColl().FindByTask(env.ProductName, env.EnvName, opt.WorkflowName, opt.TaskID)
+		if err == nil {
+			return snapshot, nil
+		}
+		if err != mongo.ErrNoDocuments {
+			return nil, errors.Wrapf(err, "failed to find snapshot of workflow task %s/%d", opt.WorkflowName, opt.TaskID)
+		}
+	}
+
+	snapshot, err := BuildEnvSnapshot(env)
+	if err != nil {
+		return nil, err
+	}
+	snapshot.Type = opt.Type
+	snapshot.Description = opt.Description
+	snapshot.CreatedBy = opt.CreatedBy
+	snapshot.WorkflowName = opt.WorkflowName
+	snapshot.TaskID = opt.TaskID
+	if err := commonrepo.NewEnvSnapshotColl().Create(snapshot); err != nil {
+		// the snapshot of the workflow task has been taken by another deploy job of the task
+		if opt.WorkflowName != "" && mongo.IsDuplicateKeyError(err) {
+			existing, findErr := commonrepo.NewEnvSnapshotColl().FindByTask(env.ProductName, env.EnvName, opt.WorkflowName, opt.TaskID)
+			if findErr != nil {
+				return nil, errors.Wrapf(findErr, "failed to find snapshot of workflow task %s/%d", opt.WorkflowName, opt.TaskID)
+			}
+			return existing, nil
+		}
+		return nil, errors.Wrapf(err, "failed to create snapshot of env %s/%s", env.ProductName, env.EnvName)
+	}
+
+	if err := commonrepo.NewEnvSnapshotColl().DeleteOutdated(env.ProductName, env.EnvName, envSnapshotRetention); err != nil {
+		log.Errorf("failed to delete outdated snapshots of env %s/%s, err: %s", env.ProductName, env.EnvName, err)
+	}
+	return snapshot, nil
+}
+
+// BuildEnvSnapshot captures the current state of the env without saving it
+func BuildEnvSnapshot(env *commonmodels.Product) (*commonmodels.EnvSnapshot, error) {
+	var renderSet *commonmodels.RenderSet
+	if env.Render != nil {
+		var err error
+		renderSet, err = commonrepo.NewRenderSetColl().Find(&commonrepo.RenderSetFindOption{
+			ProductTmpl: env.ProductName,
+			EnvName:     env.EnvName,
+			Name:        env.Render.Name,
+			Revision:    env.Render.Revision,
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to find renderset %s/%d", env.Render.Name, env.Render.Revision)
+		}
+	}
+	resources, err := BuildSnapshotEnvResources(env)
+	if err != nil {
+		return nil, err
+	}
+	return buildEnvSnapshot(env, renderSet, resources, func(serviceName string) (string, error) {
+		renderedYaml, _, err := FetchCurrentAppliedYaml(&GeneSvcYamlOption{ProductName: env.ProductName, EnvName: env.EnvName, ServiceName: serviceName})
+		return renderedYaml, err
+	})
+}
+
+// buildEnvSnapshot builds the snapshot of the env from its render set and resources, appliedYaml returns the manifest
+// of the k8s yaml service applied in the env
+func buildEnvSnapshot(env *commonmodels.Product, renderSet *commonmodels.RenderSet, resources []*commonmodels.SnapshotEnvResource, appliedYaml func(serviceName string) (string, error)) (*commonmodels.EnvSnapshot, error) {
+	snapshot := &commonmodels.EnvSnapshot{
+		ProjectName:           env.ProductName,
+		EnvName:               env.EnvName,
+		Production:            env.Production,
+		Services:              env.Services,
+		ServiceDeployStrategy: env.ServiceDeployStrategy,
+		RenderSet:             renderSet,
+		ServiceManifests:      make([]*commonmodels.SnapshotServiceManifest, 0),
+		EnvResources:          resources,
+	}
+
+	for _, group := range env.Services {
+		for _, svc := range group {
+			manifest, err := buildSnapshotServiceManifest(env, renderSet, svc, appliedYaml)
+			if err != nil {
+				return nil, err
+			}
+			if manifest != nil {
+				snapshot.ServiceManifests = append(snapshot.ServiceManifests, manifest)
+			}
+		}
+	}
+	return snapshot, nil
+}
+
+// BuildSnapshotEnvResources returns the latest versions of the resources of the env which are not deleted
+func BuildSnapshotEnvResources(env *commonmodels.Product) ([]*commonmodels.SnapshotEnvResource, error) {
+	resources, err := commonrepo.NewEnvResourceColl().List(&commonrepo.QueryEnvResourceOption{
+		ProductName: env.ProductName,
+		EnvName:     env.EnvName,
+		IsSort:      true,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list resources of env %s/%s", env.ProductName, env.EnvName)
+	}
+	return latestSnapshotEnvResources(resources), nil
+}
+
+// latestSnapshotEnvResources picks the latest versions of the resources, which are sorted by the create time
+func latestSnapshotEnvResources(resources []*commonmodels.EnvResource) []*commonmodels.SnapshotEnvResource {
+	ret := make([]*commonmodels.SnapshotEnvResource, 0)
+	visited := sets.NewString()
+	for _, resource := range resources {
+		key := resource.Type + "/" + resource.Name
+		if visited.Has(key) {
+			continue
+		}
+		visited.Insert(key)
+		if resource.DeletedAt != 0 {
+			continue
+		}
+		ret = append(ret, &commonmodels.SnapshotEnvResource{
+			Type:       resource.Type,
+			Name:       resource.Name,
+			ResourceID: resource.ID.Hex(),
+		})
+	}
+	return ret
+}
+
+func buildSnapshotServiceManifest(env *commonmodels.Product, renderSet *commonmodels.RenderSet, svc *commonmodels.ProductService, appliedYaml func(serviceName string) (string, error)) (*commonmodels.SnapshotServiceManifest, error) {
+	manifest := &commonmodels.SnapshotServiceManifest{
+		ServiceName: svc.ServiceName,
+		ReleaseName: svc.ReleaseName,
+		Type:        svc.Type,
+	}
+
+	switch svc.Type {
+	case setting.K8SDeployType:
+		renderedYaml, err := appliedYaml(svc.ServiceName)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to render service %s", svc.ServiceName)
+		}
+		manifest.Yaml = renderedYaml
+	case setting.HelmDeployType, setting.HelmChartDeployType:
+		if renderSet == nil {
+			return nil, fmt.Errorf("renderset of env %s/%s not found", env.ProductName, env.EnvName)
+		}
+		chart := renderSet.GetChartRenderMap()[svc.ServiceName]
+		if !svc.FromZadig() {
+			chart = renderSet.GetChartDeployRenderMap()[svc.ReleaseName]
+		}
+		// the service is not installed yet
+		if chart == nil {
+			return nil, nil
+		}
+		values, err := helmtool.MergeOverrideValues("", renderSet.DefaultValues, chart.GetOverrideYaml(), chart.OverrideValues, nil)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to merge values of service %s", svc.ServiceName)
+		}
+		manifest.Yaml = values
+	default:
+		return nil, nil
+	}
+	return manifest, nil
+}
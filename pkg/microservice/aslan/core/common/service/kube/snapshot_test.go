@@ -0,0 +1,120 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	templatemodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models/template"
+	"github.com/koderover/zadig/pkg/setting"
+)
+
+var _ = Describe("Testing env snapshot", func() {
+
+	Describe("test taking the snapshot of an env", func() {
+		appliedYaml := func(serviceName string) (string, error) {
+			return "kind: Deployment\nname: " + serviceName, nil
+		}
+
+		It("should record the applied manifests of the k8s yaml services", func() {
+			env := &commonmodels.Product{
+				ProductName: "project",
+				EnvName:     "dev",
+				Services: [][]*commonmodels.ProductService{
+					{{ServiceName: "a", Type: setting.K8SDeployType, Revision: 1}},
+					{{ServiceName: "b", Type: setting.K8SDeployType, Revision: 2}},
+				},
+				ServiceDeployStrategy: map[string]string{"b": setting.ServiceDeployStrategyImport},
+			}
+			resources := []*commonmodels.SnapshotEnvResource{{Type: "ConfigMap", Name: "cm", ResourceID: "1"}}
+
+			snapshot, err := buildEnvSnapshot(env, nil, resources, appliedYaml)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(snapshot.ProjectName).To(Equal("project"))
+			Expect(snapshot.EnvName).To(Equal("dev"))
+			Expect(snapshot.Services).To(Equal(env.Services))
+			Expect(snapshot.ServiceDeployStrategy).To(Equal(env.ServiceDeployStrategy))
+			Expect(snapshot.EnvResources).To(Equal(resources))
+			Expect(snapshot.ServiceManifests).To(Equal([]*commonmodels.SnapshotServiceManifest{
+				{ServiceName: "a", Type: setting.K8SDeployType, Yaml: "kind: Deployment\nname: a"},
+				{ServiceName: "b", Type: setting.K8SDeployType, Yaml: "kind: Deployment\nname: b"},
+			}))
+		})
+
+		It("should record the merged values of the installed helm services and charts", func() {
+			env := &commonmodels.Product{
+				ProductName: "project",
+				EnvName:     "dev",
+				Services: [][]*commonmodels.ProductService{{
+					{ServiceName: "a", Type: setting.HelmDeployType},
+					{ServiceName: "not-installed", Type: setting.HelmDeployType},
+					{ServiceName: "chart", ReleaseName: "chart-release", Type: setting.HelmChartDeployType},
+				}},
+			}
+			renderSet := &commonmodels.RenderSet{
+				DefaultValues: "replicas: 1\nlogLevel: info\n",
+				ChartInfos: []*templatemodels.ServiceRender{
+					{ServiceName: "a", OverrideYaml: &templatemodels.CustomYaml{YamlContent: "replicas: 2\n"}},
+					{ReleaseName: "chart-release", IsHelmChartDeploy: true, OverrideValues: `[{"key":"image.tag","value":"v2"}]`},
+				},
+			}
+
+			snapshot, err := buildEnvSnapshot(env, renderSet, []*commonmodels.SnapshotEnvResource{}, appliedYaml)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(snapshot.RenderSet).To(Equal(renderSet))
+			Expect(snapshot.ServiceManifests).To(HaveLen(2))
+			Expect(snapshot.ServiceManifests[0].ServiceName).To(Equal("a"))
+			Expect(snapshot.ServiceManifests[0].Yaml).To(ContainSubstring("replicas: 2"))
+			Expect(snapshot.ServiceManifests[0].Yaml).To(ContainSubstring("logLevel: info"))
+			Expect(snapshot.ServiceManifests[1].ReleaseName).To(Equal("chart-release"))
+			Expect(snapshot.ServiceManifests[1].Yaml).To(ContainSubstring("tag: v2"))
+		})
+
+		It("should fail if the manifest of a service can't be rendered", func() {
+			env := &commonmodels.Product{
+				Services: [][]*commonmodels.ProductService{{{ServiceName: "a", Type: setting.K8SDeployType}}},
+			}
+			_, err := buildEnvSnapshot(env, nil, nil, func(string) (string, error) {
+				return "", errors.New("template not found")
+			})
+			Expect(err).Should(HaveOccurred())
+
+			env.Services = [][]*commonmodels.ProductService{{{ServiceName: "a", Type: setting.HelmDeployType}}}
+			_, err = buildEnvSnapshot(env, nil, nil, appliedYaml)
+			Expect(err).Should(HaveOccurred())
+		})
+
+		It("should only record the latest versions of the env resources which are not deleted", func() {
+			ids := []primitive.ObjectID{primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()}
+			resources := latestSnapshotEnvResources([]*commonmodels.EnvResource{
+				{ID: ids[0], Type: "ConfigMap", Name: "cm"},
+				{ID: ids[1], Type: "ConfigMap", Name: "cm"},
+				{ID: ids[2], Type: "Secret", Name: "deleted", DeletedAt: 1},
+				{ID: ids[3], Type: "Secret", Name: "cm"},
+			})
+			Expect(resources).To(Equal([]*commonmodels.SnapshotEnvResource{
+				{Type: "ConfigMap", Name: "cm", ResourceID: ids[0].Hex()},
+				{Type: "Secret", Name: "cm", ResourceID: ids[3].Hex()},
+			}))
+		})
+	})
+})
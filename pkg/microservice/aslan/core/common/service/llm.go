@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
 	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
 	"github.com/koderover/zadig/pkg/tool/llm"
 )
@@ -14,9 +15,14 @@ func GetLLMClient(ctx context.Context, name string) (llm.ILLM, error) {
 	if err != nil {
 		return nil, fmt.Errorf("Could find the llm integration for %s: %w", name, err)
 	}
+	return newLLMClient(llmIntegration)
+}
 
+func newLLMClient(llmIntegration *models.LLMIntegration) (llm.ILLM, error) {
+	name := llmIntegration.Name
 	llmConfig := llm.LLMConfig{
 		Name:    llmIntegration.Name,
+		Model:   llmIntegration.Model,
 		Token:   llmIntegration.Token,
 		BaseURL: llmIntegration.BaseURL,
 	}
@@ -24,7 +30,7 @@ func GetLLMClient(ctx context.Context, name string) (llm.ILLM, error) {
 		llmConfig.Proxy = config.ProxyHTTPSAddr()
 	}
 
-	llmClient, err := llm.NewClient(name)
+	llmClient, err := llm.NewClient(llmIntegration.GetProvider())
 	if err != nil {
 		return nil, fmt.Errorf("Could not create the llm client for %s: %w", name, err)
 	}
@@ -37,6 +43,14 @@ func GetLLMClient(ctx context.Context, name string) (llm.ILLM, error) {
 	return llmClient, nil
 }
 
+// GetDefaultLLMClient returns the client of the llm integration, only one llm integration can be created for now
 func GetDefaultLLMClient(ctx context.Context) (llm.ILLM, error) {
-	return GetLLMClient(ctx, "openai")
+	llmIntegrations, err := commonrepo.NewLLMIntegrationColl().FindAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Could not list the llm integrations: %w", err)
+	}
+	if len(llmIntegrations) == 0 {
+		return nil, fmt.Errorf("Could find the llm integration")
+	}
+	return newLLMClient(llmIntegrations[0])
 }
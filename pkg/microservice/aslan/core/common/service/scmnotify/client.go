@@ -24,6 +24,7 @@ import (
 	"strings"
 
 	giteeClient "gitee.com/openeuler/go-gitee/gitee"
+	gogithub "github.com/google/go-github/v35/github"
 	"github.com/pkg/errors"
 	"github.com/xanzy/go-gitlab"
 	"go.uber.org/zap"
@@ -31,6 +32,7 @@ import (
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/gitee"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/github"
 	"github.com/koderover/zadig/pkg/setting"
 	"github.com/koderover/zadig/pkg/shared/client/systemconfig"
 	"github.com/koderover/zadig/pkg/tool/gerrit"
@@ -187,6 +189,31 @@ func (c *Client) Comment(notify *models.Notification) error {
 		if err != nil {
 			return fmt.Errorf("failed to comment gitee due to %s/%d %v", notify.ProjectID, notify.PrID, err)
 		}
+	} else if strings.ToLower(codeHostDetail.Type) == setting.SourceFromGithub {
+		cli := github.NewClient(codeHostDetail.AccessToken, config.ProxyHTTPSAddr(), codeHostDetail.EnableProxy)
+		if notify.CommentID == "" {
+			// create comment
+			var issueComment *gogithub.IssueComment
+			issueComment, _, err = cli.Issues.CreateComment(context.Background(),
+				notify.RepoOwner, notify.RepoName, notify.PrID, &gogithub.IssueComment{Body: &comment},
+			)
+			if err == nil {
+				notify.CommentID = strconv.FormatInt(issueComment.GetID(), 10)
+			}
+		} else {
+			// update comment
+			commentID, parseErr := strconv.ParseInt(notify.CommentID, 10, 64)
+			if parseErr != nil {
+				return fmt.Errorf("failed to parse commentID %v,err: %s", notify.CommentID, parseErr)
+			}
+			_, _, err = cli.Issues.EditComment(context.Background(),
+				notify.RepoOwner, notify.RepoName, commentID, &gogithub.IssueComment{Body: &comment},
+			)
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to comment github due to %s/%d %v", notify.ProjectID, notify.PrID, err)
+		}
 	} else {
 		return fmt.Errorf("non gitlab source not supported to comment")
 	}
@@ -651,6 +651,55 @@ func (s *Service) UpdateEnvAndTaskWebhookComment(workflowArgs *models.WorkflowTa
 	return nil
 }
 
+// UpdatePreviewEnvComment comments the status and the access url of the preview env on its pull request,
+// the comment is created once and updated in place afterwards
+func (s *Service) UpdatePreviewEnvComment(previewEnv *models.PreviewEnv, logger *zap.SugaredLogger) error {
+	info := &models.PreviewEnvInfo{
+		ProjectName: previewEnv.ProjectName,
+		EnvName:     previewEnv.EnvName,
+		Status:      previewEnv.Status,
+		Error:       previewEnv.Error,
+		AccessURL:   previewEnv.AccessURL,
+		IdleTTL:     previewEnv.IdleTTL,
+		Services:    previewEnv.Services,
+	}
+
+	if previewEnv.NotificationID != "" {
+		notification, err := s.Coll.Find(previewEnv.NotificationID)
+		if err != nil {
+			logger.Errorf("UpdatePreviewEnvComment can't find notification by id %s %s", previewEnv.NotificationID, err)
+			return err
+		}
+		notification.PreviewEnv = info
+		if err := s.Client.Comment(notification); err != nil {
+			logger.Errorf("UpdatePreviewEnvComment failed to comment %s, %v", notification.ToString(), err)
+			return err
+		}
+		return s.Coll.Upsert(notification)
+	}
+
+	notification := &models.Notification{
+		CodehostID:   previewEnv.CodehostID,
+		PrID:         previewEnv.PrID,
+		ProjectID:    strings.TrimLeft(previewEnv.RepoNamespace+"/"+previewEnv.RepoName, "/"),
+		BaseURI:      previewEnv.BaseURI,
+		IsWorkflowV4: true,
+		PreviewEnv:   info,
+		RepoOwner:    previewEnv.RepoOwner,
+		RepoName:     previewEnv.RepoName,
+	}
+	if err := s.Client.Comment(notification); err != nil {
+		logger.Errorf("failed to comment to %s %v", notification.ToString(), err)
+		return err
+	}
+	if err := s.Coll.Create(notification); err != nil {
+		logger.Errorf("failed to save %s %v", notification.ToString(), err)
+		return err
+	}
+	previewEnv.NotificationID = notification.ID.Hex()
+	return mongodb.NewPreviewEnvColl().UpdateNotification(previewEnv.ID, previewEnv.NotificationID)
+}
+
 func (s *Service) CreateGitCheckForWorkflowV4(workflowArgs *models.WorkflowV4, taskID int64, log *zap.SugaredLogger) error {
 	hook := workflowArgs.HookPayload
 
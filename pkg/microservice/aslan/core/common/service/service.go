@@ -137,7 +137,8 @@ type EnvServices struct {
 }
 
 var (
-	imageParseRegex = regexp.MustCompile(`(?P<repo>.+/)?(?P<image>[^:]+){1}(:)?(?P<tag>.+)?`)
+	// the digest of an image pinned by the signature policy, e.g. repo/image:tag@sha256:hex, is matched separately
+	imageParseRegex = regexp.MustCompile(`(?P<repo>.+/)?(?P<image>[^:@]+){1}(:)?(?P<tag>[^@]+)?(?:@(?P<digest>.+))?`)
 )
 
 func GetCreateFromChartTemplate(createFrom interface{}) (*models.CreateFromChartTemplate, error) {
@@ -0,0 +1,109 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jobcontroller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/regclient/regclient/config"
+	"go.uber.org/zap"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/registry"
+	"github.com/koderover/zadig/pkg/tool/cosign"
+	"github.com/koderover/zadig/pkg/types/step"
+)
+
+// verifyImageSignatures refuses the images which are unsigned or not signed by the trusted keys of the policy,
+// nothing is checked if the policy is disabled. The verified images are returned pinned to their digests, they
+// must be deployed instead of the tags which may be moved after the check.
+func verifyImageSignatures(ctx context.Context, policy *commonmodels.ImageSignaturePolicy, images []string, logger *zap.SugaredLogger) (map[string]string, error) {
+	pinnedImages := make(map[string]string)
+	if policy == nil || !policy.Enabled || len(images) == 0 {
+		return pinnedImages, nil
+	}
+	keys, err := commonrepo.NewImageSigningKeyColl().ListByIDs(policy.TrustedKeyIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find trusted signing keys: %s", err)
+	}
+	verifiers := make([]cosign.Verifier, 0, len(keys))
+	for _, key := range keys {
+		var verifier cosign.Verifier
+		switch key.Mode {
+		case step.ImageSignModeKey:
+			verifier, err = cosign.NewKeyVerifier(key.Name, key.PublicKey)
+		case step.ImageSignModeKeyless:
+			verifier, err = cosign.NewCertificateVerifier(key.Name, key.FulcioRootCA, key.RekorPublicKey, key.Identity, key.OIDCIssuer)
+		default:
+			err = fmt.Errorf("unknown signing mode %s", key.Mode)
+		}
+		if err != nil {
+			logger.Errorf("invalid signing key %s: %s", key.Name, err)
+			continue
+		}
+		verifiers = append(verifiers, verifier)
+	}
+	if len(verifiers) == 0 {
+		return nil, fmt.Errorf("no trusted signing key is found for the signature policy")
+	}
+
+	// the credentials are read when the job runs, so they are not saved in the task
+	registries, err := commonrepo.NewRegistryNamespaceColl().FindAll(&commonrepo.FindRegOps{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list registries: %s", err)
+	}
+	hosts := make([]config.Host, 0, len(registries))
+	for _, reg := range registries {
+		if err := registry.SetRealCredential(reg); err != nil {
+			logger.Errorf("failed to get credential of registry %s: %s", reg.RegAddr, err)
+			continue
+		}
+		host := config.HostNewName(reg.RegAddr)
+		host.User = reg.AccessKey
+		host.Pass = reg.SecretKey
+		if reg.AdvancedSetting != nil {
+			host.RegCert = reg.AdvancedSetting.TLSCert
+			if !reg.AdvancedSetting.TLSEnabled {
+				host.TLS = config.TLSInsecure
+			}
+		}
+		if strings.HasPrefix(reg.RegAddr, "http://") {
+			host.TLS = config.TLSDisabled
+		}
+		hosts = append(hosts, *host)
+	}
+
+	client := cosign.NewClient(hosts)
+	errList := new(multierror.Error)
+	for _, image := range images {
+		keyName, imageDigest, err := client.VerifyImage(ctx, image, verifiers)
+		if err != nil {
+			errList = multierror.Append(errList, err)
+			continue
+		}
+		pinnedImages[image] = cosign.PinDigest(image, imageDigest)
+		logger.Infof("signature of image %s is verified by key %s, deploying %s", image, keyName, pinnedImages[image])
+	}
+	if err := errList.ErrorOrNil(); err != nil {
+		return nil, err
+	}
+	return pinnedImages, nil
+}
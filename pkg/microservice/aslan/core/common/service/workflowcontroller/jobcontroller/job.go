@@ -33,6 +33,7 @@ import (
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
 	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/kube"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/util"
 	"github.com/koderover/zadig/pkg/util/rand"
 )
@@ -109,12 +110,34 @@ func runJob(ctx context.Context, job *commonmodels.JobTask, workflowCtx *commonm
 	workflowCtx.GlobalContextEach(func(k, v string) bool {
 		b, _ := json.Marshal(job)
 		v = strings.Trim(v, "\n")
+		// the value is escaped since it is rendered into json strings, e.g. json outputs contain quotes
+		if escaped, err := json.Marshal(v); err == nil {
+			v = string(escaped[1 : len(escaped)-1])
+		}
 		replacedString := strings.ReplaceAll(string(b), k, v)
 		if err := json.Unmarshal([]byte(replacedString), &job); err != nil {
 			logger.Errorf("unmarshal job error: %v", err)
 		}
 		return true
 	})
+	if job.RunIf != "" {
+		run, err := evaluateRunIf(job.RunIf, workflowCtx)
+		if err != nil {
+			job.StartTime = time.Now().Unix()
+			job.EndTime = time.Now().Unix()
+			logError(job, fmt.Sprintf("evaluate run_if of job %s error: %v", job.Name, err), logger)
+			ack()
+			return
+		}
+		if !run {
+			job.Status = config.StatusSkipped
+			job.StartTime = time.Now().Unix()
+			job.EndTime = time.Now().Unix()
+			logger.Infof("skip job: %s, run_if condition %s is false", job.Name, job.RunIf)
+			ack()
+			return
+		}
+	}
 	job.Status = config.StatusPrepare
 	job.StartTime = time.Now().Unix()
 	job.K8sJobName = getJobName(workflowCtx.WorkflowName, workflowCtx.TaskID)
@@ -140,14 +163,29 @@ func runJob(ctx context.Context, job *commonmodels.JobTask, workflowCtx *commonm
 		}
 	}(&jobCtl)
 
-	jobCtl.Run(ctx)
+	runJobWithRetry(ctx, job, jobCtl, workflowCtx, logger, ack)
 }
 
+// newGitOpsBatch creates the batch of the gitops files of RunJobs, it's replaced in tests
+var newGitOpsBatch = kube.NewGitOpsBatch
+
+// RunJobs runs the jobs and commits the files of the gitops envs deployed by them to the gitops repositories at last,
+// the jobs are failed if their files are not committed
 func RunJobs(ctx context.Context, jobs []*commonmodels.JobTask, workflowCtx *commonmodels.WorkflowTaskCtx, concurrency int, logger *zap.SugaredLogger, ack func()) {
+	gitOpsBatch := newGitOpsBatch(fmt.Sprintf("%s-%d", workflowCtx.WorkflowName, workflowCtx.TaskID))
+	defer commitGitOpsBatch(gitOpsBatch, jobs, logger, ack)
+	ctx = kube.ContextWithGitOpsBatch(ctx, gitOpsBatch)
+
 	if concurrency == 1 {
-		for _, job := range jobs {
-			runJob(ctx, job, workflowCtx, logger, ack)
+		groups := newMatrixGroups()
+		defer groups.cancel()
+		failed := false
+		for i, job := range jobs {
+			runMatrixJob(ctx, job, groups, workflowCtx, logger, ack)
 			if jobStatusFailed(job.Status) {
+				failed = true
+			}
+			if failed && !continueMatrix(jobs, i) {
 				return
 			}
 		}
@@ -157,6 +195,46 @@ func RunJobs(ctx context.Context, jobs []*commonmodels.JobTask, workflowCtx *com
 	jobPool.Run()
 }
 
+type jobSemaphoreKey struct{}
+
+// ContextWithJobSemaphore makes the job tasks run with the returned context share the semaphore,
+// so the running job tasks of a workflow task never exceed its concurrency however the jobs are grouped.
+func ContextWithJobSemaphore(ctx context.Context, sem chan struct{}) context.Context {
+	return context.WithValue(ctx, jobSemaphoreKey{}, sem)
+}
+
+// runJobWithSemaphore runs the job task after it acquired the semaphore of the context if there is one
+func runJobWithSemaphore(ctx context.Context, job *commonmodels.JobTask, workflowCtx *commonmodels.WorkflowTaskCtx, logger *zap.SugaredLogger, ack func()) {
+	sem, _ := ctx.Value(jobSemaphoreKey{}).(chan struct{})
+	if sem != nil && job.Status != config.StatusPassed {
+		select {
+		case sem <- struct{}{}:
+			defer func() { <-sem }()
+		case <-ctx.Done():
+			job.Status = config.StatusCancelled
+			job.StartTime = time.Now().Unix()
+			job.EndTime = time.Now().Unix()
+			logger.Infof("job: %s is cancelled before it started", job.Name)
+			ack()
+			return
+		}
+	}
+	runJob(ctx, job, workflowCtx, logger, ack)
+}
+
+func commitGitOpsBatch(batch *kube.GitOpsBatch, jobs []*commonmodels.JobTask, logger *zap.SugaredLogger, ack func()) {
+	errs := batch.Commit()
+	if len(errs) == 0 {
+		return
+	}
+	for _, job := range jobs {
+		if err, ok := errs[job.Name]; ok {
+			logError(job, fmt.Sprintf("failed to commit to gitops repository: %s", err), logger)
+		}
+	}
+	ack()
+}
+
 func CleanWorkflowJobs(ctx context.Context, workflowTask *commonmodels.WorkflowTask, workflowCtx *commonmodels.WorkflowTaskCtx, logger *zap.SugaredLogger, ack func()) {
 	for _, stage := range workflowTask.Stages {
 		for _, job := range stage.Jobs {
@@ -177,19 +255,22 @@ type Pool struct {
 	ack         func()
 	ctx         context.Context
 	wg          sync.WaitGroup
+	// matrixGroups limits the concurrency of job tasks expanded from the same job task
+	matrixGroups *matrixGroups
 }
 
 // NewPool initializes a new pool with the given tasks and
 // at the given concurrency.
 func NewPool(ctx context.Context, jobs []*commonmodels.JobTask, workflowCtx *commonmodels.WorkflowTaskCtx, concurrency int, logger *zap.SugaredLogger, ack func()) *Pool {
 	return &Pool{
-		Jobs:        jobs,
-		concurrency: concurrency,
-		workflowCtx: workflowCtx,
-		jobsChan:    make(chan *commonmodels.JobTask),
-		logger:      logger,
-		ack:         ack,
-		ctx:         ctx,
+		Jobs:         jobs,
+		concurrency:  concurrency,
+		workflowCtx:  workflowCtx,
+		jobsChan:     make(chan *commonmodels.JobTask),
+		logger:       logger,
+		ack:          ack,
+		ctx:          ctx,
+		matrixGroups: newMatrixGroups(),
 	}
 }
 
@@ -209,12 +290,13 @@ func (p *Pool) Run() {
 	close(p.jobsChan)
 
 	p.wg.Wait()
+	p.matrixGroups.cancel()
 }
 
 // The work loop for any single goroutine.
 func (p *Pool) work() {
 	for job := range p.jobsChan {
-		runJob(p.ctx, job, p.workflowCtx, p.logger, p.ack)
+		runMatrixJob(p.ctx, job, p.matrixGroups, p.workflowCtx, p.logger, p.ack)
 		p.wg.Done()
 	}
 }
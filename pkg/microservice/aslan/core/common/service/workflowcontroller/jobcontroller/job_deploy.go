@@ -67,6 +67,9 @@ type DeployJobCtl struct {
 	istioClient *versionedclient.Clientset
 	jobTaskSpec *commonmodels.JobTaskDeploySpec
 	ack         func()
+	// gitOps is true if the manifests are committed to the gitops repository of the env rather than applied
+	gitOps      bool
+	gitOpsBatch *kube.GitOpsBatchJob
 }
 
 func NewDeployJobCtl(job *commonmodels.JobTask, workflowCtx *commonmodels.WorkflowTaskCtx, ack func(), logger *zap.SugaredLogger) *DeployJobCtl {
@@ -92,7 +95,7 @@ func (c *DeployJobCtl) Run(ctx context.Context) {
 	if err := c.run(ctx); err != nil {
 		return
 	}
-	if c.jobTaskSpec.SkipCheckRunStatus {
+	if c.jobTaskSpec.SkipCheckRunStatus || c.gitOps {
 		c.job.Status = config.StatusPassed
 		return
 	}
@@ -117,9 +120,29 @@ func (c *DeployJobCtl) run(ctx context.Context) error {
 		logError(c.job, msg, c.logger)
 		return errors.New(msg)
 	}
+	snapshotEnvBeforeDeploy(env, c.workflowCtx, c.logger)
+	if slices.Contains(c.jobTaskSpec.DeployContents, config.DeployImage) {
+		images := []string{}
+		for _, serviceImage := range c.jobTaskSpec.ServiceAndImages {
+			images = append(images, serviceImage.Image)
+		}
+		pinnedImages, err := verifyImageSignatures(ctx, c.jobTaskSpec.SignaturePolicy, images, c.logger)
+		if err != nil {
+			msg := fmt.Sprintf("refuse to deploy images: %v", err)
+			logError(c.job, msg, c.logger)
+			return errors.New(msg)
+		}
+		for _, serviceImage := range c.jobTaskSpec.ServiceAndImages {
+			if pinnedImage, ok := pinnedImages[serviceImage.Image]; ok {
+				serviceImage.Image = pinnedImage
+			}
+		}
+	}
 
 	c.namespace = env.Namespace
 	c.jobTaskSpec.ClusterID = env.ClusterID
+	c.gitOps = env.GitOps.IsEnabled()
+	c.gitOpsBatch = kube.GitOpsBatchFromContext(ctx).Job(c.job.Name)
 
 	c.restConfig, err = kubeclient.GetRESTConfig(config.HubServerAddress(), c.jobTaskSpec.ClusterID)
 	if err != nil {
@@ -209,7 +232,8 @@ func (c *DeployJobCtl) run(ctx context.Context) error {
 		}
 
 		// if not only deploy image, we will redeploy service
-		if !onlyDeployImage(c.jobTaskSpec.DeployContents) {
+		// the images of the gitops env are updated by committing the rendered manifests as well
+		if !onlyDeployImage(c.jobTaskSpec.DeployContents) || c.gitOps {
 			if err := c.updateSystemService(env, currentYaml, updatedYaml, c.jobTaskSpec.VariableKVs, revision, containers, updateRevision); err != nil {
 				logError(c.job, err.Error(), c.logger)
 				return err
@@ -270,6 +294,7 @@ func (c *DeployJobCtl) updateSystemService(env *commonmodels.Product, currentYam
 		AddZadigLabel:       addZadigLabel,
 		InjectSecrets:       true,
 		SharedEnvHandler:    nil,
+		GitOpsBatch:         c.gitOpsBatch,
 		ProductInfo:         env}, c.logger)
 
 	if err != nil {
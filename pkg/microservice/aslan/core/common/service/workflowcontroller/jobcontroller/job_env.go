@@ -17,16 +17,20 @@ limitations under the License.
 package jobcontroller
 
 import (
+	"fmt"
 	"sync"
 
 	"github.com/pkg/errors"
+	"go.uber.org/zap"
 
 	"github.com/koderover/zadig/pkg/util/converter"
 
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models/template"
 	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
 	templaterepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb/template"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/kube"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/render"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/repository"
 	commontypes "github.com/koderover/zadig/pkg/microservice/aslan/core/common/types"
@@ -239,3 +243,18 @@ func UpdateProductServiceDeployInfo(deployInfo *ProductServiceDeployInfo) error
 	}
 	return nil
 }
+
+// snapshotEnvBeforeDeploy takes a snapshot of the env before the first deploy job of the workflow task changes it,
+// the deployment is not blocked if it fails
+func snapshotEnvBeforeDeploy(env *models.Product, workflowCtx *models.WorkflowTaskCtx, logger *zap.SugaredLogger) {
+	_, err := kube.CreateEnvSnapshot(env, &kube.EnvSnapshotOption{
+		Type:         config.EnvSnapshotTypeDeploy,
+		Description:  fmt.Sprintf("%s #%d", workflowCtx.WorkflowDisplayName, workflowCtx.TaskID),
+		CreatedBy:    workflowCtx.WorkflowTaskCreatorUsername,
+		WorkflowName: workflowCtx.WorkflowName,
+		TaskID:       workflowCtx.TaskID,
+	})
+	if err != nil {
+		logger.Errorf("failed to take snapshot of env %s/%s, err: %s", env.ProductName, env.EnvName, err)
+	}
+}
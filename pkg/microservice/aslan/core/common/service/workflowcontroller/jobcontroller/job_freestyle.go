@@ -26,6 +26,7 @@ import (
 
 	"go.uber.org/zap"
 	"gopkg.in/yaml.v2"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
@@ -42,6 +43,9 @@ import (
 	krkubeclient "github.com/koderover/zadig/pkg/tool/kube/client"
 	"github.com/koderover/zadig/pkg/tool/kube/informer"
 	"github.com/koderover/zadig/pkg/tool/kube/updater"
+	commontypes "github.com/koderover/zadig/pkg/types"
+	jobtypes "github.com/koderover/zadig/pkg/types/job"
+	"github.com/koderover/zadig/pkg/types/step"
 )
 
 const (
@@ -61,6 +65,7 @@ type FreestyleJobCtl struct {
 	paths       *string
 	jobTaskSpec *commonmodels.JobTaskFreestyleSpec
 	ack         func()
+	vmJob       *commonmodels.VMJob
 }
 
 func NewFreestyleJobCtl(job *commonmodels.JobTask, workflowCtx *commonmodels.WorkflowTaskCtx, ack func(), logger *zap.SugaredLogger) *FreestyleJobCtl {
@@ -82,10 +87,33 @@ func NewFreestyleJobCtl(job *commonmodels.JobTask, workflowCtx *commonmodels.Wor
 
 func (c *FreestyleJobCtl) Clean(ctx context.Context) {}
 
+func (c *FreestyleJobCtl) cleanAttempt() error {
+	// the job task failed before the k8s job was created
+	if c.kubeclient == nil {
+		return nil
+	}
+	jobLabel := &JobLabel{
+		JobType: string(c.job.JobType),
+		JobName: c.job.K8sJobName,
+	}
+	if err := ensureDeleteJob(c.jobTaskSpec.Properties.Namespace, jobLabel, c.kubeclient); err != nil {
+		return err
+	}
+	return ensureDeleteConfigMap(c.jobTaskSpec.Properties.Namespace, jobLabel, c.kubeclient)
+}
+
 func (c *FreestyleJobCtl) Run(ctx context.Context) {
 	if err := c.prepare(ctx); err != nil {
 		return
 	}
+	if c.jobTaskSpec.Properties.Infrastructure == commontypes.JobVMInfrastructure {
+		if err := c.runVM(ctx); err != nil {
+			return
+		}
+		c.waitVM(ctx)
+		c.completeVM(ctx)
+		return
+	}
 	if err := c.run(ctx); err != nil {
 		return
 	}
@@ -172,7 +200,12 @@ func (c *FreestyleJobCtl) run(ctx context.Context) error {
 
 	c.jobTaskSpec.Properties.DockerHost = dockerHost
 
-	jobCtxBytes, err := yaml.Marshal(BuildJobExcutorContext(c.jobTaskSpec, c.job, c.workflowCtx, c.logger))
+	jobCtx, err := BuildJobExcutorContext(c.jobTaskSpec, c.job, c.workflowCtx, c.logger)
+	if err != nil {
+		logError(c.job, err.Error(), c.logger)
+		return err
+	}
+	jobCtxBytes, err := yaml.Marshal(jobCtx)
 	if err != nil {
 		msg := fmt.Sprintf("cannot Jobexcutor.Context data: %v", err)
 		logError(c.job, msg, c.logger)
@@ -244,7 +277,8 @@ func (c *FreestyleJobCtl) run(ctx context.Context) error {
 
 func (c *FreestyleJobCtl) wait(ctx context.Context) {
 	var err error
-	taskTimeout := time.After(time.Duration(c.jobTaskSpec.Properties.Timeout) * time.Minute)
+	// the job waits for debugging after it failed, so the debug ttl is not counted in the timeout
+	taskTimeout := time.After(time.Duration(c.jobTaskSpec.Properties.Timeout)*time.Minute + time.Duration(c.job.DebugOnFailureTTL)*time.Second)
 	c.job.Status, err = waitJobStart(ctx, c.jobTaskSpec.Properties.Namespace, c.job.K8sJobName, c.kubeclient, c.apiServer, taskTimeout, c.logger)
 	if err != nil {
 		c.job.Error = err.Error()
@@ -295,7 +329,7 @@ func (c *FreestyleJobCtl) complete(ctx context.Context) {
 	}
 }
 
-func BuildJobExcutorContext(jobTaskSpec *commonmodels.JobTaskFreestyleSpec, job *commonmodels.JobTask, workflowCtx *commonmodels.WorkflowTaskCtx, logger *zap.SugaredLogger) *JobContext {
+func BuildJobExcutorContext(jobTaskSpec *commonmodels.JobTaskFreestyleSpec, job *commonmodels.JobTask, workflowCtx *commonmodels.WorkflowTaskCtx, logger *zap.SugaredLogger) (*JobContext, error) {
 	var envVars, secretEnvVars []string
 	for _, env := range jobTaskSpec.Properties.Envs {
 		if env.IsCredential {
@@ -304,24 +338,62 @@ func BuildJobExcutorContext(jobTaskSpec *commonmodels.JobTaskFreestyleSpec, job
 		}
 		envVars = append(envVars, strings.Join([]string{env.Key, env.Value}, "="))
 	}
+	signingKeyEnvs, err := imageSigningKeySecretEnvs(jobTaskSpec.Steps)
+	if err != nil {
+		return nil, err
+	}
+	secretEnvVars = append(secretEnvVars, signingKeyEnvs...)
 
 	outputs := []string{}
+	outputSpecs := []*jobtypes.OutputSpec{}
 	for _, output := range job.Outputs {
 		outputs = append(outputs, output.Name)
+		if output.Type != "" {
+			outputSpecs = append(outputSpecs, output.ToSpec())
+		}
 	}
 
 	return &JobContext{
-		Name:          job.Name,
-		Envs:          envVars,
-		SecretEnvs:    secretEnvVars,
-		WorkflowName:  workflowCtx.WorkflowName,
-		Workspace:     workflowCtx.Workspace,
-		TaskID:        workflowCtx.TaskID,
-		Outputs:       outputs,
-		Steps:         jobTaskSpec.Steps,
-		Paths:         jobTaskSpec.Properties.Paths,
-		ConfigMapName: job.K8sJobName,
+		Name:              job.Name,
+		Envs:              envVars,
+		SecretEnvs:        secretEnvVars,
+		WorkflowName:      workflowCtx.WorkflowName,
+		Workspace:         workflowCtx.Workspace,
+		TaskID:            workflowCtx.TaskID,
+		Outputs:           outputs,
+		OutputSpecs:       outputSpecs,
+		Steps:             jobTaskSpec.Steps,
+		Paths:             jobTaskSpec.Properties.Paths,
+		ConfigMapName:     job.K8sJobName,
+		DebugOnFailureTTL: job.DebugOnFailureTTL,
+	}, nil
+}
+
+// imageSigningKeySecretEnvs returns the private keys and passwords of the image sign steps as secret envs, they are
+// looked up when the job runs so they are never saved in the workflow task
+func imageSigningKeySecretEnvs(steps []*commonmodels.StepTask) ([]string, error) {
+	envs := []string{}
+	added := sets.NewString()
+	for _, stepTask := range steps {
+		if stepTask.StepType != config.StepImageSign {
+			continue
+		}
+		spec, ok := stepTask.Spec.(*step.StepImageSignSpec)
+		if !ok || spec.SigningKeyID == "" || added.Has(spec.SigningKeyID) {
+			continue
+		}
+		signingKey, err := mongodb.NewImageSigningKeyColl().FindByID(spec.SigningKeyID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find image signing key %s: %v", spec.SigningKeyID, err)
+		}
+		if signingKey.Mode != step.ImageSignModeKey {
+			continue
+		}
+		keyEnv, passwordEnv := step.ImageSigningKeyEnvs(spec.SigningKeyID)
+		envs = append(envs, keyEnv+"="+signingKey.PrivateKey, passwordEnv+"="+signingKey.Password)
+		added.Insert(spec.SigningKeyID)
 	}
+	return envs, nil
 }
 
 func (c *FreestyleJobCtl) SaveInfo(ctx context.Context) error {
@@ -80,6 +80,7 @@ func (c *HelmChartDeployJobCtl) Run(ctx context.Context) {
 		logError(c.job, msg, c.logger)
 		return
 	}
+	snapshotEnvBeforeDeploy(productInfo, c.workflowCtx, c.logger)
 
 	c.namespace = productInfo.Namespace
 	c.jobTaskSpec.ClusterID = productInfo.ClusterID
@@ -133,7 +134,7 @@ func (c *HelmChartDeployJobCtl) Run(ctx context.Context) {
 
 	done := make(chan bool)
 	go func(chan bool) {
-		if err = kube.UpgradeHelmRelease(productInfo, renderSet, productChartService, nil, nil, timeOut); err != nil {
+		if err = kube.UpgradeHelmRelease(productInfo, renderSet, productChartService, nil, nil, timeOut, kube.GitOpsBatchFromContext(ctx).Job(c.job.Name)); err != nil {
 			err = errors.WithMessagef(
 				err,
 				"failed to upgrade helm chart %s/%s",
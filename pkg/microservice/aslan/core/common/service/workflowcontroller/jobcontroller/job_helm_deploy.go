@@ -84,6 +84,7 @@ func (c *HelmDeployJobCtl) Run(ctx context.Context) {
 		logError(c.job, msg, c.logger)
 		return
 	}
+	snapshotEnvBeforeDeploy(productInfo, c.workflowCtx, c.logger)
 
 	c.namespace = productInfo.Namespace
 	c.jobTaskSpec.ClusterID = productInfo.ClusterID
@@ -105,6 +106,18 @@ func (c *HelmDeployJobCtl) Run(ctx context.Context) {
 			})
 		}
 	}
+	pinnedImages, err := verifyImageSignatures(ctx, c.jobTaskSpec.SignaturePolicy, images, c.logger)
+	if err != nil {
+		logError(c.job, fmt.Sprintf("refuse to deploy images: %v", err), c.logger)
+		return
+	}
+	for i, image := range images {
+		if pinnedImage, ok := pinnedImages[image]; ok {
+			images[i] = pinnedImage
+			containers[i].Image = pinnedImage
+			c.jobTaskSpec.ImageAndModules[i].Image = pinnedImage
+		}
+	}
 
 	param := &kube.ResourceApplyParam{
 		ProductInfo:           productInfo,
@@ -197,7 +210,7 @@ func (c *HelmDeployJobCtl) Run(ctx context.Context) {
 
 	done := make(chan bool)
 	go func(chan bool) {
-		if err = kube.UpgradeHelmRelease(productInfo, renderSet, productService, svcTemplate, param.Images, param.Timeout); err != nil {
+		if err = kube.UpgradeHelmRelease(productInfo, renderSet, productService, svcTemplate, param.Images, param.Timeout, kube.GitOpsBatchFromContext(ctx).Job(c.job.Name)); err != nil {
 			err = errors.WithMessagef(
 				err,
 				"failed to upgrade helm chart %s/%s",
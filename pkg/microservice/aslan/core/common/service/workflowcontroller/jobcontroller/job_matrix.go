@@ -0,0 +1,108 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jobcontroller
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+)
+
+// matrixGroup holds the job tasks expanded from the same job task,
+// it limits how many of them run at the same time and cancels the others when fail fast is enabled.
+type matrixGroup struct {
+	sem    chan struct{}
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+type matrixGroups struct {
+	sync.Mutex
+	groups map[string]*matrixGroup
+}
+
+func newMatrixGroups() *matrixGroups {
+	return &matrixGroups{groups: make(map[string]*matrixGroup)}
+}
+
+func (g *matrixGroups) get(ctx context.Context, matrix *commonmodels.JobTaskMatrix) *matrixGroup {
+	g.Lock()
+	defer g.Unlock()
+	if group, ok := g.groups[matrix.Parent]; ok {
+		return group
+	}
+	group := &matrixGroup{}
+	group.ctx, group.cancel = context.WithCancel(ctx)
+	if matrix.MaxParallel > 0 {
+		group.sem = make(chan struct{}, matrix.MaxParallel)
+	}
+	g.groups[matrix.Parent] = group
+	return group
+}
+
+func (g *matrixGroups) cancel() {
+	g.Lock()
+	defer g.Unlock()
+	for _, group := range g.groups {
+		group.cancel()
+	}
+}
+
+func runMatrixJob(ctx context.Context, job *commonmodels.JobTask, groups *matrixGroups, workflowCtx *commonmodels.WorkflowTaskCtx, logger *zap.SugaredLogger, ack func()) {
+	if job.Matrix == nil || job.Status == config.StatusPassed {
+		runJobWithSemaphore(ctx, job, workflowCtx, logger, ack)
+		return
+	}
+	group := groups.get(ctx, job.Matrix)
+	if group.sem != nil {
+		select {
+		case group.sem <- struct{}{}:
+			defer func() { <-group.sem }()
+		case <-group.ctx.Done():
+		}
+	}
+	// the group was cancelled by a failed job task or the workflow task before this one started
+	if group.ctx.Err() != nil {
+		job.Status = config.StatusCancelled
+		job.StartTime = time.Now().Unix()
+		job.EndTime = time.Now().Unix()
+		logger.Infof("matrix job: %s expanded from %s is cancelled", job.Name, job.Matrix.Parent)
+		ack()
+		return
+	}
+	runJobWithSemaphore(group.ctx, job, workflowCtx, logger, ack)
+	if job.Matrix.FailFast && jobStatusFailed(job.Status) {
+		logger.Infof("matrix job: %s failed, cancel the other jobs expanded from %s", job.Name, job.Matrix.Parent)
+		group.cancel()
+	}
+}
+
+// continueMatrix returns true if the next job is expanded from the same job task as the failed one
+// and the matrix does not fail fast, the rest of the matrix should still run in sequential mode.
+func continueMatrix(jobs []*commonmodels.JobTask, index int) bool {
+	job := jobs[index]
+	if job.Matrix == nil || job.Matrix.FailFast || index+1 >= len(jobs) {
+		return false
+	}
+	next := jobs[index+1]
+	return next.Matrix != nil && next.Matrix.Parent == job.Matrix.Parent
+}
@@ -80,6 +80,21 @@ func (c *PluginJobCtl) prepare(ctx context.Context) {
 
 func (c *PluginJobCtl) Clean(ctx context.Context) {}
 
+func (c *PluginJobCtl) cleanAttempt() error {
+	// the job task failed before the k8s job was created
+	if c.kubeclient == nil {
+		return nil
+	}
+	jobLabel := &JobLabel{
+		JobType: string(c.job.JobType),
+		JobName: c.job.K8sJobName,
+	}
+	if err := ensureDeleteJob(c.jobTaskSpec.Properties.Namespace, jobLabel, c.kubeclient); err != nil {
+		return err
+	}
+	return ensureDeleteConfigMap(c.jobTaskSpec.Properties.Namespace, jobLabel, c.kubeclient)
+}
+
 func (c *PluginJobCtl) Run(ctx context.Context) {
 	c.prepare(ctx)
 	if err := c.run(ctx); err != nil {
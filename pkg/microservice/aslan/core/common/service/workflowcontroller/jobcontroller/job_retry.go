@@ -0,0 +1,176 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jobcontroller
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commontypes "github.com/koderover/zadig/pkg/types"
+)
+
+const podEvictedReason = "Evicted"
+
+// attemptCleaner is implemented by the job controllers which run the job task in a k8s job,
+// the k8s job and pods of a failed attempt are deleted before the next attempt starts.
+type attemptCleaner interface {
+	cleanAttempt() error
+}
+
+// runJobWithRetry runs the job task until it passed or the retry policy gives up,
+// every attempt is recorded in the job task, logs of the retried attempts are kept with the attempt suffix.
+func runJobWithRetry(ctx context.Context, job *commonmodels.JobTask, jobCtl JobCtl, workflowCtx *commonmodels.WorkflowTaskCtx, logger *zap.SugaredLogger, ack func()) {
+	policy := job.RetryPolicy
+	if policy == nil || policy.MaxAttempts <= 1 {
+		jobCtl.Run(ctx)
+		return
+	}
+
+	for attempt := 1; ; attempt++ {
+		record := &commonmodels.JobTaskAttempt{
+			// attempts from the previous runs are kept when the workflow task is restarted
+			Attempt:    len(job.Attempts) + 1,
+			StartTime:  time.Now().Unix(),
+			K8sJobName: job.K8sJobName,
+			LogName:    job.Name,
+		}
+		job.Attempts = append(job.Attempts, record)
+
+		jobCtl.Run(ctx)
+
+		if job.Status == config.StatusTimeout {
+			job.FailureReason = config.JobFailureTimeout
+		}
+		record.Status = job.Status
+		record.Error = job.Error
+		record.FailureReason = job.FailureReason
+		record.ExitCode = job.ExitCode
+		record.EndTime = time.Now().Unix()
+
+		if attempt >= policy.MaxAttempts || ctx.Err() != nil || !shouldRetryJob(policy, job) {
+			return
+		}
+
+		// the next attempt overwrites the log of the job task, so keep a copy of the failed one
+		logName := fmt.Sprintf("%s-attempt-%d", job.Name, record.Attempt)
+		if err := copyJobLog(workflowCtx.WorkflowName, workflowCtx.TaskID, job.Name, logName); err != nil {
+			logger.Errorf("keep log of job: %s attempt %d error: %v", job.Name, record.Attempt, err)
+		} else {
+			record.LogName = logName
+		}
+
+		cleanJobAttempt(jobCtl, job, logger)
+
+		backoff := jobRetryBackoff(policy, attempt)
+		logger.Infof("job: %s failed, reason: %s, retry in %s, attempt %d/%d", job.Name, job.FailureReason, backoff, attempt+1, policy.MaxAttempts)
+		job.Status = config.StatusPrepare
+		job.Error = ""
+		job.FailureReason = ""
+		job.ExitCode = 0
+		ack()
+
+		select {
+		case <-ctx.Done():
+			job.Status = config.StatusCancelled
+			return
+		case <-time.After(backoff):
+		}
+
+		job.Retry++
+		job.K8sJobName = getJobName(workflowCtx.WorkflowName, workflowCtx.TaskID)
+		ack()
+		jobCtl = initJobCtl(job, workflowCtx, logger, ack)
+	}
+}
+
+func cleanJobAttempt(jobCtl JobCtl, job *commonmodels.JobTask, logger *zap.SugaredLogger) {
+	cleaner, ok := jobCtl.(attemptCleaner)
+	if !ok {
+		return
+	}
+	if err := cleaner.cleanAttempt(); err != nil {
+		logger.Errorf("clean k8s job: %s of job: %s error: %v", job.K8sJobName, job.Name, err)
+	}
+}
+
+func shouldRetryJob(policy *commonmodels.JobRetryPolicy, job *commonmodels.JobTask) bool {
+	if job.Status != config.StatusFailed && job.Status != config.StatusTimeout {
+		return false
+	}
+	for _, reason := range policy.RetryOn {
+		if reason != job.FailureReason {
+			continue
+		}
+		if reason != config.JobFailureExitCode || len(policy.ExitCodes) == 0 {
+			return true
+		}
+		for _, code := range policy.ExitCodes {
+			if code == job.ExitCode {
+				return true
+			}
+		}
+		return false
+	}
+	return false
+}
+
+// jobRetryBackoff returns the time to wait before the next attempt, it grows exponentially with the backoff factor.
+func jobRetryBackoff(policy *commonmodels.JobRetryPolicy, attempt int) time.Duration {
+	factor := policy.BackoffFactor
+	if factor < 1 {
+		factor = 1
+	}
+	seconds := float64(policy.Backoff) * math.Pow(factor, float64(attempt-1))
+	if policy.MaxBackoff > 0 && seconds > float64(policy.MaxBackoff) {
+		seconds = float64(policy.MaxBackoff)
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// setJobFailureDetail records why the k8s job failed, it returns the error message for the job task.
+func setJobFailureDetail(jobTask *commonmodels.JobTask, pods []*corev1.Pod, cm *corev1.ConfigMap) string {
+	for _, pod := range pods {
+		if pod.Status.Reason == podEvictedReason {
+			jobTask.FailureReason = config.JobFailurePodEvicted
+			return fmt.Sprintf("pod %s was evicted: %s", pod.Name, pod.Status.Message)
+		}
+	}
+	if cm == nil {
+		return ""
+	}
+	if code, err := strconv.Atoi(cm.Data[commontypes.JobExitCodeKey]); err == nil && code != 0 {
+		jobTask.FailureReason = config.JobFailureExitCode
+		jobTask.ExitCode = code
+	}
+	return ""
+}
+
+func copyJobLog(workflowName string, taskID int64, jobName, newName string) error {
+	store, s3client, err := getJobLogStore(workflowName, taskID)
+	if err != nil {
+		return err
+	}
+	return s3client.CopyObject(store.Bucket, getJobLogObjectKey(store, jobName), getJobLogObjectKey(store, newName))
+}
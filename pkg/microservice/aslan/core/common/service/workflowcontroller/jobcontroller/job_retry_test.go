@@ -0,0 +1,117 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jobcontroller
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+)
+
+type fakeRetryJobCtl struct {
+	cleaned  int
+	cleanErr error
+}
+
+func (c *fakeRetryJobCtl) Run(ctx context.Context)            {}
+func (c *fakeRetryJobCtl) Clean(ctx context.Context)          {}
+func (c *fakeRetryJobCtl) SaveInfo(ctx context.Context) error { return nil }
+func (c *fakeRetryJobCtl) cleanAttempt() error {
+	c.cleaned++
+	return c.cleanErr
+}
+
+type fakeJobCtl struct{}
+
+func (c *fakeJobCtl) Run(ctx context.Context)            {}
+func (c *fakeJobCtl) Clean(ctx context.Context)          {}
+func (c *fakeJobCtl) SaveInfo(ctx context.Context) error { return nil }
+
+var _ = Describe("Testing job retry", func() {
+
+	Context("shouldRetryJob", func() {
+		policy := &commonmodels.JobRetryPolicy{
+			RetryOn:   []config.JobFailureReason{config.JobFailurePodEvicted, config.JobFailureExitCode},
+			ExitCodes: []int{137},
+		}
+
+		DescribeTable("should retry only the failures in the policy",
+			func(policy *commonmodels.JobRetryPolicy, job *commonmodels.JobTask, expected bool) {
+				Expect(shouldRetryJob(policy, job)).To(Equal(expected))
+			},
+			Entry("passed job", policy, &commonmodels.JobTask{Status: config.StatusPassed}, false),
+			Entry("cancelled job", policy, &commonmodels.JobTask{Status: config.StatusCancelled, FailureReason: config.JobFailurePodEvicted}, false),
+			Entry("evicted pod", policy, &commonmodels.JobTask{Status: config.StatusFailed, FailureReason: config.JobFailurePodEvicted}, true),
+			Entry("listed exit code", policy, &commonmodels.JobTask{Status: config.StatusFailed, FailureReason: config.JobFailureExitCode, ExitCode: 137}, true),
+			Entry("unlisted exit code", policy, &commonmodels.JobTask{Status: config.StatusFailed, FailureReason: config.JobFailureExitCode, ExitCode: 1}, false),
+			Entry("any exit code", &commonmodels.JobRetryPolicy{RetryOn: []config.JobFailureReason{config.JobFailureExitCode}},
+				&commonmodels.JobTask{Status: config.StatusFailed, FailureReason: config.JobFailureExitCode, ExitCode: 1}, true),
+			Entry("timeout not in the policy", policy, &commonmodels.JobTask{Status: config.StatusTimeout, FailureReason: config.JobFailureTimeout}, false),
+			Entry("timeout", &commonmodels.JobRetryPolicy{RetryOn: []config.JobFailureReason{config.JobFailureTimeout}},
+				&commonmodels.JobTask{Status: config.StatusTimeout, FailureReason: config.JobFailureTimeout}, true),
+			Entry("failure without reason", policy, &commonmodels.JobTask{Status: config.StatusFailed}, false),
+		)
+	})
+
+	Context("jobRetryBackoff", func() {
+		It("should grow the backoff by the factor", func() {
+			policy := &commonmodels.JobRetryPolicy{Backoff: 10, BackoffFactor: 2}
+			Expect(jobRetryBackoff(policy, 1)).To(Equal(10 * time.Second))
+			Expect(jobRetryBackoff(policy, 2)).To(Equal(20 * time.Second))
+			Expect(jobRetryBackoff(policy, 4)).To(Equal(80 * time.Second))
+		})
+
+		It("should keep the backoff constant with a factor less than 1", func() {
+			policy := &commonmodels.JobRetryPolicy{Backoff: 10, BackoffFactor: 0.5}
+			Expect(jobRetryBackoff(policy, 3)).To(Equal(10 * time.Second))
+		})
+
+		It("should limit the backoff by the max backoff", func() {
+			policy := &commonmodels.JobRetryPolicy{Backoff: 10, BackoffFactor: 3, MaxBackoff: 60}
+			Expect(jobRetryBackoff(policy, 2)).To(Equal(30 * time.Second))
+			Expect(jobRetryBackoff(policy, 3)).To(Equal(60 * time.Second))
+		})
+	})
+
+	Context("cleanJobAttempt", func() {
+		logger := zap.NewNop().Sugar()
+		job := &commonmodels.JobTask{Name: "build", K8sJobName: "build-1-abc"}
+
+		It("should clean the k8s job of the failed attempt", func() {
+			jobCtl := &fakeRetryJobCtl{}
+			cleanJobAttempt(jobCtl, job, logger)
+			Expect(jobCtl.cleaned).To(Equal(1))
+		})
+
+		It("should not block the retry when the clean up fails", func() {
+			jobCtl := &fakeRetryJobCtl{cleanErr: errors.New("timeout")}
+			Expect(func() { cleanJobAttempt(jobCtl, job, logger) }).NotTo(Panic())
+			Expect(jobCtl.cleaned).To(Equal(1))
+		})
+
+		It("should skip the job controllers without k8s jobs", func() {
+			Expect(func() { cleanJobAttempt(&fakeJobCtl{}, job, logger) }).NotTo(Panic())
+		})
+	})
+})
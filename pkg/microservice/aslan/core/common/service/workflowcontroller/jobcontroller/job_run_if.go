@@ -0,0 +1,148 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jobcontroller
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Knetic/govaluate"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+)
+
+// runIfParameters returns an error for unknown variables,
+// so that a typo in a condition fails the job instead of silently skipping or running it.
+type runIfParameters map[string]interface{}
+
+func (p runIfParameters) Get(name string) (interface{}, error) {
+	if value, ok := p[name]; ok {
+		return value, nil
+	}
+	return nil, fmt.Errorf("unknown variable [%s]", name)
+}
+
+// runIfFixedVariables are the variables of run_if expressions which are always set
+var runIfFixedVariables = []string{
+	"project", "workflow.name", "workflow.task.id", "workflow.task.creator",
+	"hook.owner", "hook.repo", "hook.branch", "hook.ref", "hook.is_pr", "hook.merge_request_id", "hook.commit_id", "hook.event_type",
+}
+
+const (
+	runIfParamPrefix  = "workflow.params."
+	runIfKeyValPrefix = "workflow.keyvals."
+	runIfJobPrefix    = "job."
+	runIfOutputInfix  = ".output."
+)
+
+// LintRunIf checks the run_if expression of a job when the workflow is saved,
+// the variables referenced must be known to the workflow.
+func LintRunIf(expression string, workflow *commonmodels.WorkflowV4) error {
+	evaluable, err := govaluate.NewEvaluableExpression(expression)
+	if err != nil {
+		return fmt.Errorf("invalid expression: %v", err)
+	}
+	known := sets.NewString(runIfFixedVariables...)
+	for _, param := range workflow.Params {
+		known.Insert(runIfParamPrefix + param.Name)
+	}
+	for _, kv := range workflow.KeyVals {
+		known.Insert(runIfKeyValPrefix + kv.Key)
+	}
+	jobNames := sets.NewString()
+	for _, stage := range workflow.Stages {
+		for _, job := range stage.Jobs {
+			jobNames.Insert(job.Name)
+		}
+	}
+	for _, name := range evaluable.Vars() {
+		if known.Has(name) {
+			continue
+		}
+		if !strings.HasPrefix(name, runIfJobPrefix) || !strings.Contains(name, runIfOutputInfix) {
+			return fmt.Errorf("unknown variable [%s]", name)
+		}
+		// the job key starts with the job name, e.g. job.<job name>.<service>.<module>.output.<name>
+		jobKey := strings.TrimPrefix(name[:strings.Index(name, runIfOutputInfix)], runIfJobPrefix)
+		if !jobNames.Has(strings.SplitN(jobKey, ".", 2)[0]) {
+			return fmt.Errorf("variable [%s] refers to an unknown job", name)
+		}
+	}
+	return nil
+}
+
+// evaluateRunIf evaluates the run_if expression of a job, variables are referenced with brackets:
+//   - [project], [workflow.name], [workflow.task.id], [workflow.task.creator]
+//   - [workflow.params.<name>] and [workflow.keyvals.<key>]
+//   - [job.<job key>.output.<name>] for outputs of upstream jobs
+//   - [hook.event_type], [hook.branch], [hook.ref], [hook.is_pr] and so on when triggered by webhook
+func evaluateRunIf(expression string, workflowCtx *commonmodels.WorkflowTaskCtx) (bool, error) {
+	evaluable, err := govaluate.NewEvaluableExpression(expression)
+	if err != nil {
+		return false, fmt.Errorf("invalid expression: %v", err)
+	}
+	result, err := evaluable.Eval(getRunIfParameters(workflowCtx))
+	if err != nil {
+		return false, err
+	}
+	run, ok := result.(bool)
+	if !ok {
+		return false, fmt.Errorf("expression result %v is not a bool value", result)
+	}
+	return run, nil
+}
+
+func getRunIfParameters(workflowCtx *commonmodels.WorkflowTaskCtx) runIfParameters {
+	params := runIfParameters{
+		"project":               workflowCtx.ProjectName,
+		"workflow.name":         workflowCtx.WorkflowName,
+		"workflow.task.id":      fmt.Sprintf("%d", workflowCtx.TaskID),
+		"workflow.task.creator": workflowCtx.WorkflowTaskCreatorUsername,
+		// the hook variables are empty when the task is not triggered by webhook
+		"hook.owner":            "",
+		"hook.repo":             "",
+		"hook.branch":           "",
+		"hook.ref":              "",
+		"hook.is_pr":            false,
+		"hook.merge_request_id": "",
+		"hook.commit_id":        "",
+		"hook.event_type":       "",
+	}
+	for _, param := range workflowCtx.WorkflowParams {
+		params[runIfParamPrefix+param.Name] = param.Value
+	}
+	for _, kv := range workflowCtx.WorkflowKeyVals {
+		params[runIfKeyValPrefix+kv.Key] = kv.Value
+	}
+	workflowCtx.GlobalContextEach(func(k, v string) bool {
+		k = strings.TrimSuffix(strings.TrimPrefix(k, "{{."), "}}")
+		params[k] = strings.Trim(v, "\n")
+		return true
+	})
+	if hook := workflowCtx.HookPayload; hook != nil {
+		params["hook.owner"] = hook.Owner
+		params["hook.repo"] = hook.Repo
+		params["hook.branch"] = hook.Branch
+		params["hook.ref"] = hook.Ref
+		params["hook.is_pr"] = hook.IsPr
+		params["hook.merge_request_id"] = hook.MergeRequestID
+		params["hook.commit_id"] = hook.CommitID
+		params["hook.event_type"] = hook.EventType
+	}
+	return params
+}
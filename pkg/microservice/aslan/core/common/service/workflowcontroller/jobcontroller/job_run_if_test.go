@@ -0,0 +1,105 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jobcontroller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+)
+
+func runIfWorkflowCtx(globalContext map[string]string, hook *commonmodels.HookPayload) *commonmodels.WorkflowTaskCtx {
+	return &commonmodels.WorkflowTaskCtx{
+		ProjectName:     "demo",
+		WorkflowName:    "build",
+		TaskID:          7,
+		WorkflowParams:  []*commonmodels.Param{{Name: "env", Value: "dev"}},
+		WorkflowKeyVals: []*commonmodels.KeyVal{{Key: "REGION", Value: "cn"}},
+		HookPayload:     hook,
+		GlobalContextEach: func(f func(k, v string) bool) {
+			for k, v := range globalContext {
+				if !f(k, v) {
+					return
+				}
+			}
+		},
+	}
+}
+
+var _ = Describe("Testing job run_if", func() {
+
+	Context("evaluateRunIf", func() {
+		It("should evaluate the workflow, hook and output variables", func() {
+			workflowCtx := runIfWorkflowCtx(
+				map[string]string{"{{.job.build.svc.module.output.IMAGE}}": "nginx:1.25\n"},
+				&commonmodels.HookPayload{Branch: "main", IsPr: true},
+			)
+			run, err := evaluateRunIf("[project] == 'demo' && [workflow.task.id] == '7' && [workflow.params.env] == 'dev' && [workflow.keyvals.REGION] == 'cn'", workflowCtx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(run).To(BeTrue())
+
+			run, err = evaluateRunIf("[hook.branch] == 'main' && [hook.is_pr] && [job.build.svc.module.output.IMAGE] == 'nginx:1.25'", workflowCtx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(run).To(BeTrue())
+		})
+
+		It("should evaluate the hook variables of a task not triggered by webhook", func() {
+			run, err := evaluateRunIf("[hook.event_type] == 'push' || [hook.is_pr]", runIfWorkflowCtx(nil, nil))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(run).To(BeFalse())
+		})
+
+		It("should fail on unknown variables", func() {
+			_, err := evaluateRunIf("[workflow.params.enviroment] == 'dev'", runIfWorkflowCtx(nil, nil))
+			Expect(err).To(MatchError(ContainSubstring("unknown variable [workflow.params.enviroment]")))
+		})
+
+		It("should fail on non bool results", func() {
+			_, err := evaluateRunIf("[project]", runIfWorkflowCtx(nil, nil))
+			Expect(err).To(MatchError(ContainSubstring("not a bool value")))
+		})
+	})
+
+	Context("LintRunIf", func() {
+		workflow := &commonmodels.WorkflowV4{
+			Params:  []*commonmodels.Param{{Name: "env"}},
+			KeyVals: []*commonmodels.KeyVal{{Key: "REGION"}},
+			Stages: []*commonmodels.WorkflowStage{{
+				Jobs: []*commonmodels.Job{{Name: "build"}, {Name: "deploy"}},
+			}},
+		}
+
+		It("should accept known variables", func() {
+			Expect(LintRunIf("[workflow.params.env] == 'dev' && [workflow.keyvals.REGION] == 'cn' && [hook.is_pr]", workflow)).To(Succeed())
+			Expect(LintRunIf("[job.build.svc.module.output.IMAGE] != '' && [project] == 'demo'", workflow)).To(Succeed())
+		})
+
+		It("should reject invalid expressions", func() {
+			Expect(LintRunIf("[project] ==", workflow)).To(MatchError(ContainSubstring("invalid expression")))
+		})
+
+		It("should reject unknown variables", func() {
+			Expect(LintRunIf("[workflow.params.enviroment] == 'dev'", workflow)).To(MatchError(ContainSubstring("unknown variable")))
+			Expect(LintRunIf("[hook.brnach] == 'main'", workflow)).To(MatchError(ContainSubstring("unknown variable")))
+		})
+
+		It("should reject outputs of unknown jobs", func() {
+			Expect(LintRunIf("[job.test.output.RESULT] == 'ok'", workflow)).To(MatchError(ContainSubstring("unknown job")))
+		})
+	})
+})
@@ -0,0 +1,178 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jobcontroller
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/kube"
+)
+
+var _ = Describe("Testing job semaphore", func() {
+
+	It("should cancel the job task if the workflow is cancelled while it waits for the semaphore", func() {
+		sem := make(chan struct{}, 1)
+		sem <- struct{}{}
+		ctx, cancel := context.WithCancel(ContextWithJobSemaphore(context.Background(), sem))
+		cancel()
+
+		acked := 0
+		job := &commonmodels.JobTask{Name: "build"}
+		runJobWithSemaphore(ctx, job, &commonmodels.WorkflowTaskCtx{}, zap.NewNop().Sugar(), func() { acked++ })
+		Expect(job.Status).To(Equal(config.StatusCancelled))
+		Expect(acked).To(Equal(1))
+		Expect(sem).To(HaveLen(1))
+	})
+
+	It("should not take the semaphore for passed job tasks", func() {
+		sem := make(chan struct{})
+		ctx := ContextWithJobSemaphore(context.Background(), sem)
+
+		job := &commonmodels.JobTask{Name: "build", Status: config.StatusPassed}
+		runJobWithSemaphore(ctx, job, &commonmodels.WorkflowTaskCtx{}, zap.NewNop().Sugar(), func() {})
+		Expect(job.Status).To(Equal(config.StatusPassed))
+	})
+})
+
+type gitOpsCommit struct {
+	envName string
+	files   []string
+	message string
+}
+
+// fakeGitOpsCommitter records the commits of the batch and fails the commits to the envs in failedEnvs
+type fakeGitOpsCommitter struct {
+	mu         sync.Mutex
+	commits    []*gitOpsCommit
+	failedEnvs map[string]bool
+}
+
+func (c *fakeGitOpsCommitter) commit(productName, envName, batchName string, gitOps *commonmodels.EnvGitOpsConfig, files map[string]*string, message string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	commit := &gitOpsCommit{envName: envName, message: message}
+	for filePath := range files {
+		commit.files = append(commit.files, filePath)
+	}
+	c.commits = append(c.commits, commit)
+	if c.failedEnvs[envName] {
+		return errors.New("push rejected")
+	}
+	return nil
+}
+
+var _ = Describe("Testing gitops batch of the jobs", func() {
+	var (
+		committer *fakeGitOpsCommitter
+		jobs      []*commonmodels.JobTask
+		acked     int
+		// deploys are added to the batch as if they are deployed by the jobs
+		deploys    func(batch *kube.GitOpsBatch)
+		oldBatchFn func(name string) *kube.GitOpsBatch
+	)
+	gitOps := &commonmodels.EnvGitOpsConfig{Path: "envs"}
+	content := "kind: Deployment"
+
+	BeforeEach(func() {
+		committer = &fakeGitOpsCommitter{failedEnvs: map[string]bool{}}
+		jobs = []*commonmodels.JobTask{
+			{Name: "deploy-a", Status: config.StatusPassed},
+			{Name: "deploy-b", Status: config.StatusPassed},
+			{Name: "deploy-c", Status: config.StatusPassed},
+			{Name: "build", Status: config.StatusPassed},
+		}
+		acked = 0
+		deploys = func(batch *kube.GitOpsBatch) {}
+		oldBatchFn = newGitOpsBatch
+		newGitOpsBatch = func(name string) *kube.GitOpsBatch {
+			batch := kube.NewGitOpsBatchWithCommitter(name, committer.commit)
+			deploys(batch)
+			return batch
+		}
+	})
+
+	AfterEach(func() {
+		newGitOpsBatch = oldBatchFn
+	})
+
+	runJobs := func() {
+		workflowCtx := &commonmodels.WorkflowTaskCtx{WorkflowName: "deploy", TaskID: 1}
+		RunJobs(context.Background(), jobs, workflowCtx, 1, zap.NewNop().Sugar(), func() { acked++ })
+	}
+
+	It("should commit the deploys of the jobs to the same env in one commit", func() {
+		deploys = func(batch *kube.GitOpsBatch) {
+			batch.Job("deploy-a").Add("project", "dev", gitOps, map[string]*string{"envs/a.yaml": &content}, "Update service a")
+			batch.Job("deploy-b").Add("project", "dev", gitOps, map[string]*string{"envs/b.yaml": &content}, "Update service b")
+			batch.Job("deploy-c").Add("project", "prod", gitOps, map[string]*string{"envs/c.yaml": &content}, "Update service c")
+		}
+		runJobs()
+
+		Expect(committer.commits).To(HaveLen(2))
+		commits := map[string]*gitOpsCommit{}
+		for _, commit := range committer.commits {
+			commits[commit.envName] = commit
+		}
+		Expect(commits["dev"].files).To(ConsistOf("envs/a.yaml", "envs/b.yaml"))
+		Expect(commits["dev"].message).To(Equal("Deploy project/dev by deploy-1\n\nUpdate service a\nUpdate service b"))
+		Expect(commits["prod"].files).To(ConsistOf("envs/c.yaml"))
+		for _, job := range jobs {
+			Expect(job.Status).To(Equal(config.StatusPassed))
+		}
+		Expect(acked).To(Equal(0))
+	})
+
+	It("should not commit anything if no job deploys to gitops envs", func() {
+		Expect(kube.GitOpsBatchFromContext(context.Background()).Job("deploy-a")).To(BeNil())
+		runJobs()
+
+		Expect(committer.commits).To(BeEmpty())
+		for _, job := range jobs {
+			Expect(job.Status).To(Equal(config.StatusPassed))
+			Expect(job.Error).To(BeEmpty())
+		}
+		Expect(acked).To(Equal(0))
+	})
+
+	It("should fail the jobs whose deploys are not committed", func() {
+		committer.failedEnvs["dev"] = true
+		deploys = func(batch *kube.GitOpsBatch) {
+			batch.Job("deploy-a").Add("project", "dev", gitOps, map[string]*string{"envs/a.yaml": &content}, "Update service a")
+			batch.Job("deploy-b").Add("project", "dev", gitOps, map[string]*string{"envs/b.yaml": nil}, "Remove service b")
+			batch.Job("deploy-c").Add("project", "prod", gitOps, map[string]*string{"envs/c.yaml": &content}, "Update service c")
+		}
+		runJobs()
+
+		Expect(committer.commits).To(HaveLen(2))
+		for _, job := range jobs[:2] {
+			Expect(job.Status).To(Equal(config.StatusFailed))
+			Expect(job.Error).To(Equal("failed to commit to gitops repository: push rejected"))
+		}
+		for _, job := range jobs[2:] {
+			Expect(job.Status).To(Equal(config.StatusPassed))
+		}
+		Expect(acked).To(Equal(1))
+	})
+})
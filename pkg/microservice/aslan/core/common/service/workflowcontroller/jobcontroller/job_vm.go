@@ -0,0 +1,177 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jobcontroller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/workflowcontroller/stepcontroller"
+	"github.com/koderover/zadig/pkg/types/job"
+)
+
+const (
+	vmJobCheckInterval = 3 * time.Second
+	// vmRunnerOfflineTimeout is how long a running vm job waits for the heartbeat of its runner before it fails
+	vmRunnerOfflineTimeout = 2 * time.Minute
+)
+
+// runVM queues the job for the vm runners instead of creating a kubernetes job
+func (c *FreestyleJobCtl) runVM(ctx context.Context) error {
+	jobCtx, err := BuildJobExcutorContext(c.jobTaskSpec, c.job, c.workflowCtx, c.logger)
+	if err != nil {
+		logError(c.job, err.Error(), c.logger)
+		return err
+	}
+	// debugging is only supported in the pods of the kubernetes jobs
+	jobCtx.DebugOnFailureTTL = 0
+	c.job.DebugOnFailureTTL = 0
+	jobCtxBytes, err := yaml.Marshal(jobCtx)
+	if err != nil {
+		msg := fmt.Sprintf("cannot Jobexcutor.Context data: %v", err)
+		logError(c.job, msg, c.logger)
+		return errors.New(msg)
+	}
+
+	vmJob := &commonmodels.VMJob{
+		WorkflowName: c.workflowCtx.WorkflowName,
+		ProjectName:  c.workflowCtx.ProjectName,
+		TaskID:       c.workflowCtx.TaskID,
+		JobName:      c.job.Name,
+		Labels:       c.jobTaskSpec.Properties.VMLabels,
+		JobCtx:       string(jobCtxBytes),
+	}
+	if err := commonrepo.NewVMJobColl().Create(vmJob); err != nil {
+		msg := fmt.Sprintf("create vm job error: %v", err)
+		logError(c.job, msg, c.logger)
+		return errors.New(msg)
+	}
+	c.vmJob = vmJob
+	c.logger.Infof("succeed to create vm job %s for job %s", vmJob.ID.Hex(), c.job.Name)
+	return nil
+}
+
+func (c *FreestyleJobCtl) waitVM(ctx context.Context) {
+	vmJobColl := commonrepo.NewVMJobColl()
+	taskTimeout := time.After(time.Duration(c.jobTaskSpec.Properties.Timeout) * time.Minute)
+	ticker := time.NewTicker(vmJobCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			c.finishVMJob(config.StatusCancelled, "")
+			c.job.Status = config.StatusCancelled
+			return
+		case <-taskTimeout:
+			c.finishVMJob(config.StatusTimeout, "")
+			c.job.Status, c.job.Error = config.StatusTimeout, "timeout waiting for the vm job to finish"
+			return
+		case <-ticker.C:
+			vmJob, err := vmJobColl.FindByID(c.vmJob.ID.Hex())
+			if err != nil {
+				c.logger.Errorf("failed to find vm job %s: %s", c.vmJob.ID.Hex(), err)
+				continue
+			}
+			c.vmJob = vmJob
+
+			switch config.Status(vmJob.Status) {
+			case config.StatusCreated:
+			case config.StatusRunning:
+				if c.job.Status != config.StatusRunning {
+					c.job.Status = config.StatusRunning
+					c.ack()
+				}
+				runner, err := commonrepo.NewVMRunnerColl().FindByID(vmJob.RunnerID)
+				if err == nil && time.Since(time.Unix(runner.LastHeartbeatTime, 0)) < vmRunnerOfflineTimeout {
+					continue
+				}
+				msg := fmt.Sprintf("vm runner %s is offline", vmJob.RunnerName)
+				c.finishVMJob(config.StatusFailed, msg)
+				c.job.Status, c.job.Error = config.StatusFailed, msg
+				return
+			default:
+				c.job.Status, c.job.Error = config.Status(vmJob.Status), vmJob.Error
+				if c.job.Status == config.StatusFailed && vmJob.ExitCode != 0 {
+					c.job.FailureReason = config.JobFailureExitCode
+					c.job.ExitCode = vmJob.ExitCode
+				}
+				return
+			}
+		}
+	}
+}
+
+// finishVMJob stops the vm job, the runner stops it when it sends the log next time
+func (c *FreestyleJobCtl) finishVMJob(status config.Status, errMsg string) {
+	if _, err := commonrepo.NewVMJobColl().Finish(c.vmJob.ID, status, "", 0, errMsg); err != nil {
+		c.logger.Errorf("failed to finish vm job %s: %s", c.vmJob.ID.Hex(), err)
+	}
+}
+
+func (c *FreestyleJobCtl) completeVM(ctx context.Context) {
+	if c.job.Status == config.StatusPassed {
+		outputs := []*job.JobOutput{}
+		err := json.Unmarshal([]byte(c.vmJob.Outputs), &outputs)
+		if err == nil {
+			err = writeOutputs(outputs, c.job, c.workflowCtx)
+		}
+		if err != nil {
+			c.logger.Error(err)
+			c.job.Status, c.job.Error = config.StatusFailed, errors.Wrap(err, "get job outputs").Error()
+		}
+	}
+
+	if err := saveVMJobLog(c.vmJob.ID.Hex(), c.workflowCtx.WorkflowName, c.job.Name, c.workflowCtx.TaskID); err != nil {
+		c.logger.Error(err)
+		if c.job.Error == "" {
+			c.job.Error = err.Error()
+		}
+		return
+	}
+	if err := stepcontroller.SummarizeSteps(ctx, c.workflowCtx, &c.jobTaskSpec.Properties.Paths, c.job.Name, c.jobTaskSpec.Steps, c.logger); err != nil {
+		c.logger.Error(err)
+		c.job.Error = err.Error()
+		return
+	}
+}
+
+// saveVMJobLog uploads the log sent by the runner to the s3 storage like the log of the kubernetes jobs
+func saveVMJobLog(vmJobID, workflowName, jobName string, taskID int64) error {
+	logColl := commonrepo.NewVMJobLogColl()
+	chunks, err := logColl.List(vmJobID, -1)
+	if err != nil {
+		return fmt.Errorf("failed to list vm job logs: %s", err)
+	}
+	buf := new(bytes.Buffer)
+	for _, chunk := range chunks {
+		buf.WriteString(chunk.Content)
+	}
+	if err := uploadJobLog(buf, workflowName, jobName, taskID); err != nil {
+		return err
+	}
+	return logColl.DeleteByJob(vmJobID)
+}
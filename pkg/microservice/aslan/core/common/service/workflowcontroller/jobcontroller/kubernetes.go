@@ -54,6 +54,7 @@ import (
 	"github.com/koderover/zadig/pkg/setting"
 	kubeclient "github.com/koderover/zadig/pkg/shared/kube/client"
 	"github.com/koderover/zadig/pkg/shared/kube/wrapper"
+	"github.com/koderover/zadig/pkg/tool/joboutput"
 	"github.com/koderover/zadig/pkg/tool/kube/containerlog"
 	"github.com/koderover/zadig/pkg/tool/kube/getter"
 	"github.com/koderover/zadig/pkg/tool/kube/podexec"
@@ -62,6 +63,7 @@ import (
 	s3tool "github.com/koderover/zadig/pkg/tool/s3"
 	commontypes "github.com/koderover/zadig/pkg/types"
 	"github.com/koderover/zadig/pkg/types/job"
+	"github.com/koderover/zadig/pkg/types/step"
 	"github.com/koderover/zadig/pkg/util"
 )
 
@@ -83,6 +85,15 @@ const (
 	// build job outputs key
 	IMAGEKEY    = "IMAGE"
 	IMAGETAGKEY = "imageTag"
+
+	// BuildKitImage runs the rootless buildkitd sidecar of the docker build steps in buildkit mode
+	BuildKitImage          = "moby/buildkit:v0.11.6-rootless"
+	buildKitContainerName  = "buildkitd"
+	buildKitAddr           = "tcp://127.0.0.1:1234"
+	buildKitVolumeName     = "buildkit"
+	buildKitDir            = "/zadig-buildkit"
+	buildKitExitFile       = buildKitDir + "/exit"
+	buildKitRootlessUserID = 1000
 )
 
 func GetK8sClients(hubServerAddr, clusterID string) (crClient.Client, kubernetes.Interface, *rest.Config, crClient.Reader, error) {
@@ -409,6 +420,9 @@ func buildJob(jobType, jobImage, jobName, clusterID, currentNamespace string, re
 	if jobTask.BreakpointAfter {
 		jobExecutorBootingScript += fmt.Sprintf("touch %sdebug/breakpoint_after;", ZadigContextDir)
 	}
+	if jobTask.DebugOnFailureTTL > 0 {
+		jobExecutorBootingScript += fmt.Sprintf("touch %sdebug/breakpoint_%s;", ZadigContextDir, commontypes.JobDebugStatusOnFailure)
+	}
 	jobExecutorBootingScript += jobExecutorBinaryFile
 
 	labels := getJobLabels(&JobLabel{
@@ -433,7 +447,7 @@ func buildJob(jobType, jobImage, jobName, clusterID, currentNamespace string, re
 			// in case finished zombie job not cleaned up by zadig
 			TTLSecondsAfterFinished: int32Ptr(3600),
 			// in case zombie job never stop
-			ActiveDeadlineSeconds: int64Ptr(jobTaskSpec.Properties.Timeout*60 + 3600),
+			ActiveDeadlineSeconds: int64Ptr(jobTaskSpec.Properties.Timeout*60 + jobTask.DebugOnFailureTTL + 3600),
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
 					Labels: labels,
@@ -503,10 +517,82 @@ func buildJob(jobType, jobImage, jobName, clusterID, currentNamespace string, re
 			SubPath:   jobTaskSpec.Properties.Cache.NFSProperties.Subpath,
 		})
 	}
+	setBuildKitSidecar(job, jobTaskSpec.Steps)
 	ensureVolumeMounts(job)
 	return job, nil
 }
 
+// setBuildKitSidecar starts a rootless buildkitd sidecar for the docker build steps in buildkit mode. buildkitd runs
+// rootless only as a non-root user, while the job container runs as root, so it can't be started in the job container.
+// The sidecar exits after the job container exits, or the pod of the job never completes.
+func setBuildKitSidecar(job *batchv1.Job, steps []*commonmodels.StepTask) {
+	unconfined, found := false, false
+	for _, stepTask := range steps {
+		if stepTask.StepType != config.StepDockerBuild {
+			continue
+		}
+		spec := &step.StepDockerBuildSpec{}
+		if err := commonmodels.IToi(stepTask.Spec, spec); err != nil {
+			log.Errorf("failed to decode docker build step %s, error: %v", stepTask.Name, err)
+			continue
+		}
+		if spec.GetBuilderMode() != step.DockerBuilderModeBuildKit {
+			continue
+		}
+		found = true
+		unconfined = unconfined || spec.BuildKitUnconfined
+	}
+	if !found {
+		return
+	}
+
+	podSpec := &job.Spec.Template.Spec
+	podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+		Name: buildKitVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			EmptyDir: &corev1.EmptyDirVolumeSource{},
+		},
+	})
+	volumeMount := corev1.VolumeMount{
+		Name:      buildKitVolumeName,
+		MountPath: buildKitDir,
+	}
+
+	container := &podSpec.Containers[0]
+	container.Env = append(container.Env, corev1.EnvVar{Name: step.BuildKitHostEnv, Value: buildKitAddr})
+	container.VolumeMounts = append(container.VolumeMounts, volumeMount)
+	if len(container.Args) > 0 {
+		container.Args[0] = fmt.Sprintf("%s; code=$?; touch %s; exit $code", container.Args[0], buildKitExitFile)
+	}
+
+	sidecar := corev1.Container{
+		ImagePullPolicy: corev1.PullIfNotPresent,
+		Name:            buildKitContainerName,
+		Image:           BuildKitImage,
+		Command:         []string{"/bin/sh", "-c"},
+		Args: []string{fmt.Sprintf("rootlesskit buildkitd --addr %s --oci-worker-no-process-sandbox & while [ ! -f %s ]; do sleep 1; done",
+			buildKitAddr, buildKitExitFile)},
+		Env: []corev1.EnvVar{
+			{Name: "XDG_RUNTIME_DIR", Value: fmt.Sprintf("/run/user/%d", buildKitRootlessUserID)},
+		},
+		VolumeMounts: []corev1.VolumeMount{volumeMount},
+		SecurityContext: &corev1.SecurityContext{
+			RunAsUser:  int64Ptr(buildKitRootlessUserID),
+			RunAsGroup: int64Ptr(buildKitRootlessUserID),
+		},
+	}
+	// the default seccomp and apparmor profiles of some clusters block the creation of user namespaces,
+	// the sidecar is still unprivileged with the unconfined profiles
+	if unconfined {
+		sidecar.SecurityContext.SeccompProfile = &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeUnconfined}
+		if job.Spec.Template.Annotations == nil {
+			job.Spec.Template.Annotations = map[string]string{}
+		}
+		job.Spec.Template.Annotations["container.apparmor.security.beta.kubernetes.io/"+buildKitContainerName] = "unconfined"
+	}
+	podSpec.Containers = append(podSpec.Containers, sidecar)
+}
+
 func BuildCleanJob(jobName, clusterID, workflowName string, taskID int64) (*batchv1.Job, error) {
 	workspace := "/workspace"
 	shareStorageDir := commontypes.GetShareStorageSubPathPrefix(workflowName, taskID)
@@ -961,7 +1047,7 @@ func waitJobEndByCheckingConfigMap(ctx context.Context, taskTimeout <-chan time.
 						continue
 					}
 					if ipod.Failed() {
-						return config.StatusFailed, ""
+						return config.StatusFailed, setJobFailureDetail(jobTask, []*corev1.Pod{pod}, cm)
 					}
 					if !ipod.Finished() {
 						// check container whether is stuck in debug stage by checking stage file, if so, update job status to debug
@@ -972,8 +1058,13 @@ func waitJobEndByCheckingConfigMap(ctx context.Context, taskTimeout <-chan time.
 						case commontypes.JobDebugStatusAfter:
 							jobTask.Status = config.StatusDebugAfter
 							ack()
+						case commontypes.JobDebugStatusOnFailure:
+							if jobTask.Status != config.StatusDebugOnFailure {
+								jobTask.Status = config.StatusDebugOnFailure
+								ack()
+							}
 						case commontypes.JobDebugStatusNotIn:
-							if jobTask.Status == config.StatusDebugBefore || jobTask.Status == config.StatusDebugAfter {
+							if jobTask.Status == config.StatusDebugBefore || jobTask.Status == config.StatusDebugAfter || jobTask.Status == config.StatusDebugOnFailure {
 								jobTask.Status = config.StatusRunning
 								ack()
 							}
@@ -983,12 +1074,14 @@ func waitJobEndByCheckingConfigMap(ctx context.Context, taskTimeout <-chan time.
 			case job.Status.Succeeded != 0:
 				return config.StatusPassed, ""
 			case job.Status.Failed != 0:
-				return config.StatusFailed, ""
+				// pods are kept after the job failed, they tell whether the pod was evicted
+				pods, _ := podLister.List(labels.Set{"job-name": jobName}.AsSelector())
+				return config.StatusFailed, setJobFailureDetail(jobTask, pods, cm)
 			}
 			if status, ok := cm.Data[commontypes.JobResultKey]; ok {
 				switch commontypes.JobStatus(status) {
 				case commontypes.JobFail:
-					return config.StatusFailed, ""
+					return config.StatusFailed, setJobFailureDetail(jobTask, nil, cm)
 				default:
 					return config.StatusPassed, ""
 				}
@@ -1027,8 +1120,7 @@ func getJobOutputFromTerminalMsg(namespace, containerName string, jobTask *commo
 			}
 		}
 	}
-	writeOutputs(outputs, jobTask.Key, workflowCtx)
-	return nil
+	return writeOutputs(outputs, jobTask, workflowCtx)
 }
 
 func getJobOutputFromConfigMap(namespace, containerName string, jobTask *commonmodels.JobTask, workflowCtx *commonmodels.WorkflowTaskCtx, informer informers.SharedInformerFactory) error {
@@ -1046,11 +1138,10 @@ func getJobOutputFromConfigMap(namespace, containerName string, jobTask *commonm
 		return errors.Wrap(err, "unmarshal outputs")
 	}
 
-	writeOutputs(outputs, jobTask.Key, workflowCtx)
-	return nil
+	return writeOutputs(outputs, jobTask, workflowCtx)
 }
 
-func writeOutputs(outputs []*job.JobOutput, outputKey string, workflowCtx *commonmodels.WorkflowTaskCtx) {
+func writeOutputs(outputs []*job.JobOutput, jobTask *commonmodels.JobTask, workflowCtx *commonmodels.WorkflowTaskCtx) error {
 	// write jobs output info to globalcontext so other job can use like this {{.job.jobKey.output.outputName}}
 	outputsMap := make(map[string]*job.JobOutput)
 	for _, output := range outputs {
@@ -1061,9 +1152,36 @@ func writeOutputs(outputs []*job.JobOutput, outputKey string, workflowCtx *commo
 			tag.Value = getTagFromImageName(image.Value)
 		}
 	}
+	specMap := make(map[string]*job.OutputSpec)
+	for _, output := range jobTask.Outputs {
+		if output.Type != "" {
+			specMap[output.Name] = output.ToSpec()
+		}
+	}
 	for _, output := range outputsMap {
-		workflowCtx.GlobalContextSet(job.GetJobOutputKey(outputKey, output.Name), output.Value)
+		spec, ok := specMap[output.Name]
+		if !ok {
+			continue
+		}
+		if err := joboutput.Validate(spec, output.Value); err != nil {
+			return err
+		}
+	}
+	for _, output := range outputsMap {
+		workflowCtx.GlobalContextSet(job.GetJobOutputKey(jobTask.Key, output.Name), output.Value)
+		if spec, ok := specMap[output.Name]; !ok || spec.Type != job.OutputTypeJSON {
+			continue
+		}
+		// the json paths of json outputs are referenced like {{.job.jobKey.output.outputName.a.b}}
+		values, err := joboutput.Flatten(output.Value)
+		if err != nil {
+			return err
+		}
+		for path, value := range values {
+			workflowCtx.GlobalContextSet(job.GetJobOutputKey(jobTask.Key, output.Name+"."+path), value)
+		}
 	}
+	return nil
 }
 
 func getTagFromImageName(imageName string) string {
@@ -1105,33 +1223,21 @@ func saveContainerLog(namespace, clusterID, workflowName, jobName string, taskID
 	if err := containerlog.GetContainerLogs(namespace, pods[0].Name, pods[0].Spec.Containers[0].Name, false, int64(0), buf, clientSet); err != nil {
 		return fmt.Errorf("failed to get container logs: %s", err)
 	}
+	return uploadJobLog(buf, workflowName, jobName, taskID)
+}
 
-	store, err := commonrepo.NewS3StorageColl().FindDefault()
-	if err != nil {
-		return fmt.Errorf("failed to get default s3 storage: %s", err)
-	}
-
+// uploadJobLog uploads the log of the job to the log directory of the workflow task in the default s3 storage
+func uploadJobLog(buf *bytes.Buffer, workflowName, jobName string, taskID int64) error {
 	if tempFileName, err := util.GenerateTmpFile(); err == nil {
 		defer func() {
 			_ = os.Remove(tempFileName)
 		}()
 		if err = saveFile(buf, tempFileName); err == nil {
-
-			if store.Subfolder != "" {
-				store.Subfolder = fmt.Sprintf("%s/%s/%d/%s", store.Subfolder, strings.ToLower(workflowName), taskID, "log")
-			} else {
-				store.Subfolder = fmt.Sprintf("%s/%d/%s", strings.ToLower(workflowName), taskID, "log")
-			}
-			forcedPathStyle := true
-			if store.Provider == setting.ProviderSourceAli {
-				forcedPathStyle = false
-			}
-			s3client, err := s3tool.NewClient(store.Endpoint, store.Ak, store.Sk, store.Region, store.Insecure, forcedPathStyle)
+			store, s3client, err := getJobLogStore(workflowName, taskID)
 			if err != nil {
-				return fmt.Errorf("saveContainerLog s3 create client error: %v", err)
+				return fmt.Errorf("saveContainerLog %v", err)
 			}
-			fileName := strings.Replace(strings.ToLower(jobName), "_", "-", -1)
-			objectKey := GetObjectPath(store.Subfolder, fileName+".log")
+			objectKey := getJobLogObjectKey(store, jobName)
 			if err = s3client.Upload(
 				store.Bucket,
 				tempFileName,
@@ -1148,6 +1254,33 @@ func saveContainerLog(namespace, clusterID, workflowName, jobName string, taskID
 	return nil
 }
 
+// getJobLogStore returns the default s3 storage whose subfolder is the log directory of the workflow task
+func getJobLogStore(workflowName string, taskID int64) (*commonmodels.S3Storage, *s3tool.Client, error) {
+	store, err := commonrepo.NewS3StorageColl().FindDefault()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get default s3 storage: %s", err)
+	}
+	if store.Subfolder != "" {
+		store.Subfolder = fmt.Sprintf("%s/%s/%d/%s", store.Subfolder, strings.ToLower(workflowName), taskID, "log")
+	} else {
+		store.Subfolder = fmt.Sprintf("%s/%d/%s", strings.ToLower(workflowName), taskID, "log")
+	}
+	forcedPathStyle := true
+	if store.Provider == setting.ProviderSourceAli {
+		forcedPathStyle = false
+	}
+	s3client, err := s3tool.NewClient(store.Endpoint, store.Ak, store.Sk, store.Region, store.Insecure, forcedPathStyle)
+	if err != nil {
+		return nil, nil, fmt.Errorf("s3 create client error: %v", err)
+	}
+	return store, s3client, nil
+}
+
+func getJobLogObjectKey(store *commonmodels.S3Storage, jobName string) string {
+	fileName := strings.Replace(strings.ToLower(jobName), "_", "-", -1)
+	return GetObjectPath(store.Subfolder, fileName+".log")
+}
+
 func GetObjectPath(subFolder, name string) string {
 	// target should not be started with /
 	if subFolder != "" {
@@ -0,0 +1,89 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jobcontroller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/types/step"
+)
+
+func newBuildJob() *batchv1.Job {
+	return &batchv1.Job{
+		Spec: batchv1.JobSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "build", Args: []string{JobExecutorFile}}},
+				},
+			},
+		},
+	}
+}
+
+func dockerBuildStep(spec *step.StepDockerBuildSpec) *commonmodels.StepTask {
+	return &commonmodels.StepTask{Name: "docker-build", StepType: config.StepDockerBuild, Spec: spec}
+}
+
+var _ = Describe("Testing buildkit sidecar", func() {
+
+	It("should not add the sidecar for the docker builder", func() {
+		job := newBuildJob()
+		setBuildKitSidecar(job, []*commonmodels.StepTask{dockerBuildStep(&step.StepDockerBuildSpec{})})
+		Expect(job.Spec.Template.Spec.Containers).To(HaveLen(1))
+		Expect(job.Spec.Template.Spec.Volumes).To(BeEmpty())
+	})
+
+	It("should run buildkitd as non-root user in the sidecar", func() {
+		job := newBuildJob()
+		setBuildKitSidecar(job, []*commonmodels.StepTask{dockerBuildStep(&step.StepDockerBuildSpec{BuilderMode: step.DockerBuilderModeBuildKit})})
+
+		containers := job.Spec.Template.Spec.Containers
+		Expect(containers).To(HaveLen(2))
+		Expect(containers[0].Env).To(ContainElement(corev1.EnvVar{Name: step.BuildKitHostEnv, Value: buildKitAddr}))
+		Expect(containers[0].Args[0]).To(Equal(JobExecutorFile + "; code=$?; touch " + buildKitExitFile + "; exit $code"))
+		Expect(containers[0].SecurityContext).To(BeNil())
+
+		sidecar := containers[1]
+		Expect(sidecar.Image).To(Equal(BuildKitImage))
+		Expect(*sidecar.SecurityContext.RunAsUser).To(BeEquivalentTo(buildKitRootlessUserID))
+		Expect(*sidecar.SecurityContext.RunAsGroup).To(BeEquivalentTo(buildKitRootlessUserID))
+		Expect(sidecar.SecurityContext.SeccompProfile).To(BeNil())
+		Expect(sidecar.Env).To(ContainElement(corev1.EnvVar{Name: "XDG_RUNTIME_DIR", Value: "/run/user/1000"}))
+		Expect(sidecar.Args[0]).To(ContainSubstring("--addr " + buildKitAddr))
+		Expect(sidecar.Args[0]).To(ContainSubstring("while [ ! -f " + buildKitExitFile + " ]"))
+		Expect(sidecar.VolumeMounts).To(Equal(containers[0].VolumeMounts))
+		Expect(job.Spec.Template.Annotations).To(BeEmpty())
+	})
+
+	It("should run the sidecar with the unconfined profiles if any build opts in", func() {
+		job := newBuildJob()
+		setBuildKitSidecar(job, []*commonmodels.StepTask{
+			dockerBuildStep(&step.StepDockerBuildSpec{BuilderMode: step.DockerBuilderModeBuildKit}),
+			dockerBuildStep(&step.StepDockerBuildSpec{BuilderMode: step.DockerBuilderModeBuildKit, BuildKitUnconfined: true}),
+		})
+
+		containers := job.Spec.Template.Spec.Containers
+		Expect(containers).To(HaveLen(2))
+		Expect(containers[1].SecurityContext.SeccompProfile.Type).To(Equal(corev1.SeccompProfileTypeUnconfined))
+		Expect(job.Spec.Template.Annotations).To(HaveKeyWithValue("container.apparmor.security.beta.kubernetes.io/"+buildKitContainerName, "unconfined"))
+	})
+})
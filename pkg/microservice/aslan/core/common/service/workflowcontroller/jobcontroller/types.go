@@ -18,6 +18,7 @@ package jobcontroller
 
 import (
 	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/types/job"
 )
 
 type JobContext struct {
@@ -40,6 +41,10 @@ type JobContext struct {
 
 	Steps   []*commonmodels.StepTask `yaml:"steps"`
 	Outputs []string                 `yaml:"outputs"`
+	// OutputSpecs are the typed outputs, the executor validates them before the job finishes
+	OutputSpecs []*job.OutputSpec `yaml:"output_specs"`
+	// DebugOnFailureTTL is the seconds the job waits for debugging after a step failed, 0 means disabled
+	DebugOnFailureTTL int64 `yaml:"debug_on_failure_ttl"`
 }
 
 type EnvVar []string
@@ -66,6 +66,11 @@ func runStage(ctx context.Context, stage *commonmodels.StageTask, workflowCtx *c
 }
 
 func RunStages(ctx context.Context, stages []*commonmodels.StageTask, workflowCtx *commonmodels.WorkflowTaskCtx, concurrency int, logger *zap.SugaredLogger, ack func()) {
+	// once any job declares depends_on, jobs are scheduled as a DAG instead of stage by stage.
+	if dagEnabled(stages) {
+		RunStagesDAG(ctx, stages, workflowCtx, concurrency, logger, ack)
+		return
+	}
 	for _, stage := range stages {
 		// should skip passed stage when workflow task be restarted
 		if stage.Status == config.StatusPassed {
@@ -80,11 +85,11 @@ func RunStages(ctx context.Context, stages []*commonmodels.StageTask, workflowCt
 
 func ApproveStage(workflowName, stageName, userName, userID, comment string, taskID int64, approve bool) error {
 	approveKey := fmt.Sprintf("%s-%d-%s", workflowName, taskID, stageName)
-	approveWithL, ok := approvalservice.GlobalApproveMap.GetApproval(approveKey)
-	if !ok {
+	_, err := approvalservice.DoApproval(approveKey, userName, userID, comment, approve)
+	if err == approvalservice.ErrApprovalNotFound {
 		return fmt.Errorf("workflow %s ID %d stage %s do not need approve", workflowName, taskID, stageName)
 	}
-	return approveWithL.DoApproval(userName, userID, comment, approve)
+	return err
 }
 
 func waitForApprove(ctx context.Context, stage *commonmodels.StageTask, workflowCtx *commonmodels.WorkflowTaskCtx, logger *zap.SugaredLogger, ack func()) (err error) {
@@ -138,10 +143,18 @@ func waitForNativeApprove(ctx context.Context, stage *commonmodels.StageTask, wo
 		approval.Timeout = 60
 	}
 	approveKey := fmt.Sprintf("%s-%d-%s", workflowCtx.WorkflowName, workflowCtx.TaskID, stage.Name)
-	approveWithL := &approvalservice.ApproveWithLock{Approval: approval}
-	approvalservice.GlobalApproveMap.SetApproval(approveKey, approveWithL)
+	// approval is persisted so that the approve request can be handled by any aslan replica,
+	// approvals made before aslan restarted are kept in the stage and restored here.
+	if err := approvalservice.SetApproval(approveKey, approval); err != nil {
+		stage.Status = config.StatusFailed
+		return errors.Wrap(err, "save native approval")
+	}
+	escalator := newApprovalEscalator(stage, workflowCtx, nil, logger, ack)
 	defer func() {
-		approvalservice.GlobalApproveMap.DeleteApproval(approveKey)
+		if err := approvalservice.DeleteApproval(approveKey); err != nil {
+			logger.Errorf("delete native approval %s error: %v", approveKey, err)
+		}
+		escalator.cleanup()
 		ack()
 	}()
 	if err := instantmessage.NewWeChatClient().SendWorkflowTaskAproveNotifications(workflowCtx.WorkflowName, workflowCtx.TaskID); err != nil {
@@ -158,10 +171,20 @@ func waitForNativeApprove(ctx context.Context, stage *commonmodels.StageTask, wo
 			return fmt.Errorf("workflow was canceled")
 
 		case <-timeout:
-			stage.Status = config.StatusTimeout
-			return fmt.Errorf("workflow timeout")
+			return escalator.timeout(config.StatusTimeout)
 		default:
-			approved, approveCount, err := approveWithL.IsApproval()
+			escalator.check()
+			if done, err := escalator.escalationResult(); done {
+				return err
+			}
+			snapshot, err := approvalservice.GetApproval(approveKey)
+			if err != nil {
+				logger.Errorf("get native approval %s error: %v", approveKey, err)
+				continue
+			}
+			// sync the approval results made on any replica into the stage
+			stage.Approval.NativeApproval = snapshot.Approval
+			approved, approveCount, err := snapshot.IsApproval()
 			if err != nil {
 				stage.Status = config.StatusReject
 				return err
@@ -188,73 +211,19 @@ func waitForLarkApprove(ctx context.Context, stage *commonmodels.StageTask, work
 		approval.Timeout = 60
 	}
 
-	data, err := mongodb.NewIMAppColl().GetByID(context.Background(), approval.ID)
-	if err != nil {
-		stage.Status = config.StatusFailed
-		return errors.Wrap(err, "get lark im app data")
-	}
-	approvalCode := data.LarkApprovalCodeList[approval.GetNodeTypeKey()]
-	if approvalCode == "" {
-		stage.Status = config.StatusFailed
-		log.Errorf("failed to find approval code for node type %s", approval.GetNodeTypeKey())
-		return errors.Errorf("failed to find approval code for node type %s", approval.GetNodeTypeKey())
-	}
-
-	client := lark.NewClient(data.AppID, data.AppSecret)
-
-	detailURL := fmt.Sprintf("%s/v1/projects/detail/%s/pipelines/custom/%s/%d?display_name=%s",
-		configbase.SystemAddress(),
-		workflowCtx.ProjectName,
-		workflowCtx.WorkflowName,
-		workflowCtx.TaskID,
-		url.QueryEscape(workflowCtx.WorkflowDisplayName),
-	)
-	descForm := ""
-	if stage.Approval.Description != "" {
-		descForm = fmt.Sprintf("\n描述: %s", stage.Approval.Description)
-	}
-	formContent := fmt.Sprintf("项目名称: %s\n工作流名称: %s\n阶段名称: %s%s\n\n更多详见: %s",
-		workflowCtx.ProjectName, workflowCtx.WorkflowDisplayName, stage.Name, descForm, detailURL)
-
-	var userID string
-	if approval.DefaultApprovalInitiator == nil {
-		userID, err = client.GetUserOpenIDByEmailOrMobile(lark.QueryTypeMobile, workflowCtx.WorkflowTaskCreatorMobile)
-		if err != nil {
-			stage.Status = config.StatusFailed
-			return errors.Wrapf(err, "get user lark id by mobile-%s", workflowCtx.WorkflowTaskCreatorMobile)
-		}
-	} else {
-		userID = approval.DefaultApprovalInitiator.ID
-		formContent = fmt.Sprintf("审批发起人: %s\n%s", workflowCtx.WorkflowTaskCreatorUsername, formContent)
-	}
-	log.Infof("waitForLarkApprove: ApproveNodes num %d", len(approval.ApprovalNodes))
-	instance, err := client.CreateApprovalInstance(&lark.CreateApprovalInstanceArgs{
-		ApprovalCode: approvalCode,
-		UserOpenID:   userID,
-		Nodes:        approval.GetLarkApprovalNode(),
-		FormContent:  formContent,
-	})
+	larkInstance, err := createLarkApprovalInstance(approval, stage, workflowCtx)
 	if err != nil {
-		log.Errorf("waitForLarkApprove: create instance failed: %v", err)
 		stage.Status = config.StatusFailed
-		return errors.Wrap(err, "create approval instance")
+		return err
 	}
+	client, instance := larkInstance.client, larkInstance.instanceID
 	log.Infof("waitForLarkApprove: create instance success, id %s", instance)
 
 	if err := instantmessage.NewWeChatClient().SendWorkflowTaskAproveNotifications(workflowCtx.WorkflowName, workflowCtx.TaskID); err != nil {
 		logger.Errorf("send approve notification failed, error: %v", err)
 	}
 
-	cancelApproval := func() {
-		err := client.CancelApprovalInstance(&lark.CancelApprovalInstanceArgs{
-			ApprovalID: approvalCode,
-			InstanceID: instance,
-			UserID:     userID,
-		})
-		if err != nil {
-			log.Errorf("cancel approval %s error: %v", instance, err)
-		}
-	}
+	cancelApproval := larkInstance.cancel
 
 	checkNodeStatus := func(node *commonmodels.LarkApprovalNode) (config.ApproveOrReject, error) {
 		switch node.Type {
@@ -332,8 +301,10 @@ func waitForLarkApprove(ctx context.Context, stage *commonmodels.StageTask, work
 		return finalResult != "", finalResult == config.Approve, nil
 	}
 
+	escalator := newApprovalEscalator(stage, workflowCtx, larkInstance, logger, ack)
 	defer func() {
 		larkservice.RemoveLarkApprovalInstanceManager(instance)
+		escalator.cleanup()
 	}()
 	timeout := time.After(time.Duration(approval.Timeout) * time.Minute)
 	for {
@@ -344,9 +315,8 @@ func waitForLarkApprove(ctx context.Context, stage *commonmodels.StageTask, work
 			cancelApproval()
 			return fmt.Errorf("workflow was canceled")
 		case <-timeout:
-			stage.Status = config.StatusCancelled
 			cancelApproval()
-			return fmt.Errorf("workflow timeout")
+			return escalator.timeout(config.StatusCancelled)
 		default:
 			done, isApprove, err := approvalUpdate(approval)
 			if err != nil {
@@ -370,6 +340,13 @@ func waitForLarkApprove(ctx context.Context, stage *commonmodels.StageTask, work
 				stage.Status = config.StatusFailed
 				return errors.New("check final approval status failed")
 			}
+
+			// the approval is escalated with the results just updated, so it's not escalated after it's made
+			escalator.check()
+			if done, err := escalator.escalationResult(); done {
+				cancelApproval()
+				return err
+			}
 		}
 	}
 }
@@ -385,73 +362,21 @@ func waitForDingTalkApprove(ctx context.Context, stage *commonmodels.StageTask,
 		approval.Timeout = 60
 	}
 
-	data, err := mongodb.NewIMAppColl().GetByID(context.Background(), approval.ID)
-	if err != nil {
-		stage.Status = config.StatusFailed
-		return errors.Wrap(err, "get dingtalk im data")
-	}
-
-	client := dingtalk.NewClient(data.DingTalkAppKey, data.DingTalkAppSecret)
-
-	detailURL := fmt.Sprintf("%s/v1/projects/detail/%s/pipelines/custom/%s/%d?display_name=%s",
-		configbase.SystemAddress(),
-		workflowCtx.ProjectName,
-		workflowCtx.WorkflowName,
-		workflowCtx.TaskID,
-		url.QueryEscape(workflowCtx.WorkflowDisplayName),
-	)
-	descForm := ""
-	if stage.Approval.Description != "" {
-		descForm = fmt.Sprintf("\n描述: %s", stage.Approval.Description)
-	}
-	formContent := fmt.Sprintf("项目名称: %s\n工作流名称: %s\n阶段名称: %s%s\n\n更多详见: %s",
-		workflowCtx.ProjectName, workflowCtx.WorkflowDisplayName, stage.Name, descForm, detailURL)
-
-	var userID string
-	if approval.DefaultApprovalInitiator == nil {
-		userIDResp, err := client.GetUserIDByMobile(workflowCtx.WorkflowTaskCreatorMobile)
-		if err != nil {
-			stage.Status = config.StatusFailed
-			return errors.Wrapf(err, "get user dingtalk id by mobile-%s", workflowCtx.WorkflowTaskCreatorMobile)
-		}
-		userID = userIDResp.UserID
-	} else {
-		userID = approval.DefaultApprovalInitiator.ID
-		formContent = fmt.Sprintf("审批发起人: %s\n%s", workflowCtx.WorkflowTaskCreatorUsername, formContent)
-	}
-
-	log.Infof("waitForDingTalkApprove: ApproveNode num %d", len(approval.ApprovalNodes))
-	instanceResp, err := client.CreateApprovalInstance(&dingtalk.CreateApprovalInstanceArgs{
-		ProcessCode:      data.DingTalkDefaultApprovalFormCode,
-		OriginatorUserID: userID,
-		ApproverNodeList: func() (nodeList []*dingtalk.ApprovalNode) {
-			for _, node := range approval.ApprovalNodes {
-				var userIDList []string
-				for _, user := range node.ApproveUsers {
-					userIDList = append(userIDList, user.ID)
-				}
-				nodeList = append(nodeList, &dingtalk.ApprovalNode{
-					UserIDs:    userIDList,
-					ActionType: node.Type,
-				})
-			}
-			return
-		}(),
-		FormContent: formContent,
-	})
+	dingTalkInstance, err := createDingTalkApprovalInstance(approval, stage, workflowCtx)
 	if err != nil {
-		log.Errorf("waitForDingTalkApprove: create instance failed: %v", err)
 		stage.Status = config.StatusFailed
-		return errors.Wrap(err, "create approval instance")
+		return err
 	}
-	instanceID := instanceResp.InstanceID
+	client, instanceID := dingTalkInstance.client, dingTalkInstance.instanceID
 	log.Infof("waitForDingTalkApprove: create instance success, id %s", instanceID)
 
 	if err := instantmessage.NewWeChatClient().SendWorkflowTaskAproveNotifications(workflowCtx.WorkflowName, workflowCtx.TaskID); err != nil {
 		logger.Errorf("send approve notification failed, error: %v", err)
 	}
+	escalator := newApprovalEscalator(stage, workflowCtx, dingTalkInstance, logger, ack)
 	defer func() {
 		dingservice.RemoveDingTalkApprovalManager(instanceID)
+		escalator.cleanup()
 	}()
 
 	resultMap := map[string]config.ApproveOrReject{
@@ -491,10 +416,11 @@ func waitForDingTalkApprove(ctx context.Context, stage *commonmodels.StageTask,
 		select {
 		case <-ctx.Done():
 			stage.Status = config.StatusCancelled
+			dingTalkInstance.terminate()
 			return fmt.Errorf("workflow was canceled")
 		case <-timeout:
-			stage.Status = config.StatusCancelled
-			return fmt.Errorf("workflow timeout")
+			dingTalkInstance.terminate()
+			return escalator.timeout(config.StatusCancelled)
 		default:
 			userApprovalResult := dingservice.GetDingTalkApprovalManager(instanceID).GetAllUserApprovalResults()
 			userUpdated := false
@@ -544,8 +470,163 @@ func waitForDingTalkApprove(ctx context.Context, stage *commonmodels.StageTask,
 					return errors.Wrap(err, "get unexpected instance final info")
 				}
 			}
+
+			// the approval is escalated with the results just updated, so it's not escalated after it's made
+			escalator.check()
+			if done, err := escalator.escalationResult(); done {
+				dingTalkInstance.terminate()
+				return err
+			}
+		}
+	}
+}
+
+// larkApprovalInstance is a lark approval instance created for the stage approval or an escalated approver group
+type larkApprovalInstance struct {
+	client       *lark.Client
+	approval     *commonmodels.LarkApproval
+	approvalCode string
+	// userID is the open id of the approval initiator
+	userID     string
+	instanceID string
+}
+
+func createLarkApprovalInstance(approval *commonmodels.LarkApproval, stage *commonmodels.StageTask, workflowCtx *commonmodels.WorkflowTaskCtx) (*larkApprovalInstance, error) {
+	data, err := mongodb.NewIMAppColl().GetByID(context.Background(), approval.ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "get lark im app data")
+	}
+	approvalCode := data.LarkApprovalCodeList[approval.GetNodeTypeKey()]
+	if approvalCode == "" {
+		log.Errorf("failed to find approval code for node type %s", approval.GetNodeTypeKey())
+		return nil, errors.Errorf("failed to find approval code for node type %s", approval.GetNodeTypeKey())
+	}
+
+	client := lark.NewClient(data.AppID, data.AppSecret)
+
+	formContent := approvalFormContent(stage, workflowCtx)
+	var userID string
+	if approval.DefaultApprovalInitiator == nil {
+		userID, err = client.GetUserOpenIDByEmailOrMobile(lark.QueryTypeMobile, workflowCtx.WorkflowTaskCreatorMobile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "get user lark id by mobile-%s", workflowCtx.WorkflowTaskCreatorMobile)
+		}
+	} else {
+		userID = approval.DefaultApprovalInitiator.ID
+		formContent = fmt.Sprintf("审批发起人: %s\n%s", workflowCtx.WorkflowTaskCreatorUsername, formContent)
+	}
+	log.Infof("createLarkApprovalInstance: ApproveNodes num %d", len(approval.ApprovalNodes))
+	instance, err := client.CreateApprovalInstance(&lark.CreateApprovalInstanceArgs{
+		ApprovalCode: approvalCode,
+		UserOpenID:   userID,
+		Nodes:        approval.GetLarkApprovalNode(),
+		FormContent:  formContent,
+	})
+	if err != nil {
+		log.Errorf("createLarkApprovalInstance: create instance failed: %v", err)
+		return nil, errors.Wrap(err, "create approval instance")
+	}
+	return &larkApprovalInstance{
+		client:       client,
+		approval:     approval,
+		approvalCode: approvalCode,
+		userID:       userID,
+		instanceID:   instance,
+	}, nil
+}
+
+func (i *larkApprovalInstance) cancel() {
+	err := i.client.CancelApprovalInstance(&lark.CancelApprovalInstanceArgs{
+		ApprovalID: i.approvalCode,
+		InstanceID: i.instanceID,
+		UserID:     i.userID,
+	})
+	if err != nil {
+		log.Errorf("cancel approval %s error: %v", i.instanceID, err)
+	}
+}
+
+// dingTalkApprovalInstance is a dingtalk approval instance created for the stage approval or an escalated approver group
+type dingTalkApprovalInstance struct {
+	client     *dingtalk.Client
+	approval   *commonmodels.DingTalkApproval
+	instanceID string
+}
+
+func createDingTalkApprovalInstance(approval *commonmodels.DingTalkApproval, stage *commonmodels.StageTask, workflowCtx *commonmodels.WorkflowTaskCtx) (*dingTalkApprovalInstance, error) {
+	data, err := mongodb.NewIMAppColl().GetByID(context.Background(), approval.ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "get dingtalk im data")
+	}
+
+	client := dingtalk.NewClient(data.DingTalkAppKey, data.DingTalkAppSecret)
+
+	formContent := approvalFormContent(stage, workflowCtx)
+	var userID string
+	if approval.DefaultApprovalInitiator == nil {
+		userIDResp, err := client.GetUserIDByMobile(workflowCtx.WorkflowTaskCreatorMobile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "get user dingtalk id by mobile-%s", workflowCtx.WorkflowTaskCreatorMobile)
 		}
+		userID = userIDResp.UserID
+	} else {
+		userID = approval.DefaultApprovalInitiator.ID
+		formContent = fmt.Sprintf("审批发起人: %s\n%s", workflowCtx.WorkflowTaskCreatorUsername, formContent)
+	}
+
+	log.Infof("createDingTalkApprovalInstance: ApproveNode num %d", len(approval.ApprovalNodes))
+	instanceResp, err := client.CreateApprovalInstance(&dingtalk.CreateApprovalInstanceArgs{
+		ProcessCode:      data.DingTalkDefaultApprovalFormCode,
+		OriginatorUserID: userID,
+		ApproverNodeList: func() (nodeList []*dingtalk.ApprovalNode) {
+			for _, node := range approval.ApprovalNodes {
+				var userIDList []string
+				for _, user := range node.ApproveUsers {
+					userIDList = append(userIDList, user.ID)
+				}
+				nodeList = append(nodeList, &dingtalk.ApprovalNode{
+					UserIDs:    userIDList,
+					ActionType: node.Type,
+				})
+			}
+			return
+		}(),
+		FormContent: formContent,
+	})
+	if err != nil {
+		log.Errorf("createDingTalkApprovalInstance: create instance failed: %v", err)
+		return nil, errors.Wrap(err, "create approval instance")
+	}
+	return &dingTalkApprovalInstance{
+		client:     client,
+		approval:   approval,
+		instanceID: instanceResp.InstanceID,
+	}, nil
+}
+
+func (i *dingTalkApprovalInstance) terminate() {
+	if err := i.client.TerminateApprovalInstance(i.instanceID, "工作流审批已结束"); err != nil {
+		log.Errorf("terminate approval %s error: %v", i.instanceID, err)
+	}
+}
+
+func approvalDetailURL(workflowCtx *commonmodels.WorkflowTaskCtx) string {
+	return fmt.Sprintf("%s/v1/projects/detail/%s/pipelines/custom/%s/%d?display_name=%s",
+		configbase.SystemAddress(),
+		workflowCtx.ProjectName,
+		workflowCtx.WorkflowName,
+		workflowCtx.TaskID,
+		url.QueryEscape(workflowCtx.WorkflowDisplayName),
+	)
+}
+
+func approvalFormContent(stage *commonmodels.StageTask, workflowCtx *commonmodels.WorkflowTaskCtx) string {
+	descForm := ""
+	if stage.Approval.Description != "" {
+		descForm = fmt.Sprintf("\n描述: %s", stage.Approval.Description)
 	}
+	return fmt.Sprintf("项目名称: %s\n工作流名称: %s\n阶段名称: %s%s\n\n更多详见: %s",
+		workflowCtx.ProjectName, workflowCtx.WorkflowDisplayName, stage.Name, descForm, approvalDetailURL(workflowCtx))
 }
 
 func statusFailed(status config.Status) bool {
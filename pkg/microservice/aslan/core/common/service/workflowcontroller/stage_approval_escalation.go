@@ -0,0 +1,431 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflowcontroller
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	approvalservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/approval"
+	dingservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/dingtalk"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/instantmessage"
+	larkservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/lark"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/notify"
+	"github.com/koderover/zadig/pkg/tool/lark"
+)
+
+// escalationCheckInterval is the interval to query the results of the lark and dingtalk approval instances of the escalated groups
+const escalationCheckInterval = 10 * time.Second
+
+// approvalInstance is a lark or dingtalk approval instance
+type approvalInstance interface {
+	// result returns the final decision of the instance, it is empty while the instance is pending
+	result() (config.ApproveOrReject, error)
+	// remind sends the text to the approvers who have not made the decision
+	remind(text string) error
+	// close cancels the instance if it is still pending
+	close()
+}
+
+// approvalEscalator sends the reminders and escalates the approval while the stage is waiting for approval.
+// escalated zadig users approve the stage in zadig, so for lark and dingtalk approvals they are saved in a native approval
+// with the same key as the native approval of the stage. escalated lark and dingtalk groups approve in their own
+// approval instances. one decision of the escalated groups finishes the approval.
+type approvalEscalator struct {
+	stage       *commonmodels.StageTask
+	workflowCtx *commonmodels.WorkflowTaskCtx
+	logger      *zap.SugaredLogger
+	ack         func()
+	approveKey  string
+	startTime   time.Time
+	lastRemind  time.Time
+	lastCheck   time.Time
+	// stageInstance is the lark or dingtalk approval instance of the stage, nil for native approvals
+	stageInstance approvalInstance
+	// escalated is true when a native approval was created for the escalated users of a lark or dingtalk approval
+	escalated bool
+	// instances are the approval instances of the escalated lark and dingtalk groups
+	instances []approvalInstance
+
+	// createInstance and notifyUsers are replaced in tests
+	createInstance func(escalation *commonmodels.ApprovalEscalation) (approvalInstance, error)
+	notifyUsers    func(userNames []string, title, content string)
+}
+
+func newApprovalEscalator(stage *commonmodels.StageTask, workflowCtx *commonmodels.WorkflowTaskCtx, stageInstance approvalInstance, logger *zap.SugaredLogger, ack func()) *approvalEscalator {
+	now := time.Now()
+	// escalations are restarted with the approval when the workflow task is restarted
+	for _, escalation := range stage.Approval.Escalations {
+		escalation.Escalated = false
+		escalation.EscalateTime = 0
+	}
+	e := &approvalEscalator{
+		stage:         stage,
+		workflowCtx:   workflowCtx,
+		logger:        logger,
+		ack:           ack,
+		approveKey:    fmt.Sprintf("%s-%d-%s", workflowCtx.WorkflowName, workflowCtx.TaskID, stage.Name),
+		startTime:     now,
+		lastRemind:    now,
+		stageInstance: stageInstance,
+	}
+	e.createInstance = e.createEscalationInstance
+	e.notifyUsers = func(userNames []string, title, content string) {
+		for _, userName := range userNames {
+			notify.SendMessage(userName, title, content, "", logger)
+		}
+	}
+	return e
+}
+
+// check sends the reminder and escalates the approval if it is time to, it is called on every poll of the approval.
+func (e *approvalEscalator) check() {
+	approval := e.stage.Approval
+	now := time.Now()
+	if approval.ReminderInterval > 0 && now.Sub(e.lastRemind) >= time.Duration(approval.ReminderInterval)*time.Minute {
+		e.lastRemind = now
+		e.remind()
+	}
+
+	for _, escalation := range approval.Escalations {
+		if escalation.Escalated || now.Sub(e.startTime) < time.Duration(escalation.After)*time.Minute {
+			continue
+		}
+		if err := e.escalate(escalation); err != nil {
+			// try again on the next poll
+			e.logger.Errorf("escalate approval %s error: %v", e.approveKey, err)
+			return
+		}
+		escalation.Escalated = true
+		escalation.EscalateTime = now.Unix()
+		e.ack()
+
+		approvers := escalationApprovers(escalation)
+		e.logger.Infof("approval of stage %s is escalated to %v", e.stage.Name, approvers)
+		if err := instantmessage.NewWeChatClient().SendWorkflowTaskApproveEscalationNotifications(e.workflowCtx.WorkflowName, e.workflowCtx.TaskID, approvers); err != nil {
+			e.logger.Errorf("send approve escalation notification failed, error: %v", err)
+		}
+	}
+}
+
+// remind sends the reminder to the approvers of the stage and of the escalated groups who have not made the decision
+func (e *approvalEscalator) remind() {
+	title := fmt.Sprintf("工作流 %s #%d 等待审批", e.workflowCtx.WorkflowDisplayName, e.workflowCtx.TaskID)
+	text := fmt.Sprintf("%s\n%s", title, approvalFormContent(e.stage, e.workflowCtx))
+
+	if userNames := pendingNativeApprovers(e.stage.Approval); len(userNames) > 0 {
+		e.notifyUsers(userNames, title, text)
+	}
+	instances := e.instances
+	if e.stageInstance != nil {
+		instances = append([]approvalInstance{e.stageInstance}, instances...)
+	}
+	for _, instance := range instances {
+		if err := instance.remind(text); err != nil {
+			e.logger.Errorf("send approve reminder failed, error: %v", err)
+		}
+	}
+}
+
+func (e *approvalEscalator) escalate(escalation *commonmodels.ApprovalEscalation) error {
+	if escalation.GetType() != config.NativeApproval {
+		instance, err := e.createInstance(escalation)
+		if err != nil {
+			return err
+		}
+		e.instances = append(e.instances, instance)
+		return nil
+	}
+
+	if e.stage.Approval.Type == config.NativeApproval {
+		snapshot, err := approvalservice.AddApproveUsers(e.approveKey, escalation.ApproveUsers)
+		if err != nil {
+			return err
+		}
+		e.stage.Approval.NativeApproval = snapshot.Approval
+		return nil
+	}
+
+	if e.escalated {
+		_, err := approvalservice.AddApproveUsers(e.approveKey, escalation.ApproveUsers)
+		return err
+	}
+	snapshot := &approvalservice.ApprovalSnapshot{Approval: &commonmodels.NativeApproval{NeededApprovers: 1}}
+	snapshot.AddApproveUsers(escalation.ApproveUsers)
+	if err := approvalservice.SetApproval(e.approveKey, snapshot.Approval); err != nil {
+		return err
+	}
+	e.escalated = true
+	return nil
+}
+
+func (e *approvalEscalator) createEscalationInstance(escalation *commonmodels.ApprovalEscalation) (approvalInstance, error) {
+	switch escalation.GetType() {
+	case config.LarkApproval:
+		if escalation.LarkApproval == nil {
+			return nil, errors.New("lark approval of the escalation not found")
+		}
+		return createLarkApprovalInstance(escalation.LarkApproval, e.stage, e.workflowCtx)
+	case config.DingTalkApproval:
+		if escalation.DingTalkApproval == nil {
+			return nil, errors.New("dingtalk approval of the escalation not found")
+		}
+		return createDingTalkApprovalInstance(escalation.DingTalkApproval, e.stage, e.workflowCtx)
+	default:
+		return nil, errors.Errorf("invalid escalation approval type %s", escalation.Type)
+	}
+}
+
+// escalationResult returns whether the escalated groups have made the decision, the error is not nil if it is rejected.
+func (e *approvalEscalator) escalationResult() (done bool, err error) {
+	if e.escalated {
+		snapshot, err := approvalservice.GetApproval(e.approveKey)
+		if err != nil {
+			e.logger.Errorf("get escalated approval %s error: %v", e.approveKey, err)
+		} else {
+			e.syncEscalationResults(snapshot.Approval)
+			approved, _, err := snapshot.IsApproval()
+			if err != nil {
+				e.stage.Status = config.StatusReject
+				return true, err
+			}
+			if approved {
+				return true, nil
+			}
+		}
+	}
+
+	if len(e.instances) == 0 || time.Since(e.lastCheck) < escalationCheckInterval {
+		return false, nil
+	}
+	e.lastCheck = time.Now()
+	for _, instance := range e.instances {
+		result, err := instance.result()
+		if err != nil {
+			e.logger.Errorf("get escalated approval instance result error: %v", err)
+			continue
+		}
+		switch result {
+		case config.Approve:
+			return true, nil
+		case config.Reject:
+			e.stage.Status = config.StatusReject
+			return true, errors.New("Approval has been rejected by the escalated approvers")
+		}
+	}
+	return false, nil
+}
+
+// syncEscalationResults shows the decisions of the escalated users in the stage
+func (e *approvalEscalator) syncEscalationResults(nativeApproval *commonmodels.NativeApproval) {
+	results := make(map[string]*commonmodels.User)
+	for _, user := range nativeApproval.ApproveUsers {
+		results[user.UserID] = user
+	}
+	updated := false
+	for _, escalation := range e.stage.Approval.Escalations {
+		if escalation.GetType() != config.NativeApproval {
+			continue
+		}
+		for _, user := range escalation.ApproveUsers {
+			result, ok := results[user.UserID]
+			if !ok || result.RejectOrApprove == "" || user.RejectOrApprove != "" {
+				continue
+			}
+			user.RejectOrApprove = result.RejectOrApprove
+			user.Comment = result.Comment
+			user.OperationTime = result.OperationTime
+			updated = true
+		}
+	}
+	if updated {
+		e.ack()
+	}
+}
+
+// cleanup deletes the native approval and cancels the approval instances created for the escalated groups
+func (e *approvalEscalator) cleanup() {
+	for _, instance := range e.instances {
+		instance.close()
+	}
+	if !e.escalated {
+		return
+	}
+	if err := approvalservice.DeleteApproval(e.approveKey); err != nil {
+		e.logger.Errorf("delete escalated approval %s error: %v", e.approveKey, err)
+	}
+}
+
+// timeout applies the timeout action of the approval, the stage goes on if the approval is approved automatically.
+func (e *approvalEscalator) timeout(defaultStatus config.Status) error {
+	switch e.stage.Approval.TimeoutAction {
+	case config.ApprovalTimeoutApprove:
+		e.logger.Infof("approval of stage %s timeout, approved automatically", e.stage.Name)
+		return nil
+	case config.ApprovalTimeoutReject:
+		e.stage.Status = config.StatusReject
+		return errors.New("approval timeout, rejected automatically")
+	default:
+		e.stage.Status = defaultStatus
+		return fmt.Errorf("workflow timeout")
+	}
+}
+
+// escalationApprovers returns the names of the approvers of the escalated group
+func escalationApprovers(escalation *commonmodels.ApprovalEscalation) []string {
+	names := []string{}
+	switch escalation.GetType() {
+	case config.NativeApproval:
+		for _, user := range escalation.ApproveUsers {
+			names = append(names, user.UserName)
+		}
+	case config.LarkApproval:
+		if escalation.LarkApproval != nil {
+			for _, node := range escalation.LarkApproval.ApprovalNodes {
+				for _, user := range node.ApproveUsers {
+					names = append(names, user.Name)
+				}
+			}
+		}
+	case config.DingTalkApproval:
+		if escalation.DingTalkApproval != nil {
+			for _, node := range escalation.DingTalkApproval.ApprovalNodes {
+				for _, user := range node.ApproveUsers {
+					names = append(names, user.Name)
+				}
+			}
+		}
+	}
+	return names
+}
+
+// pendingNativeApprovers returns the names of the zadig users who have not made the decision,
+// they are the users of the native approval of the stage, or the escalated users of a lark or dingtalk approval.
+func pendingNativeApprovers(approval *commonmodels.Approval) []string {
+	users := []*commonmodels.User{}
+	if approval.Type == config.NativeApproval {
+		// escalated users are added to the native approval of the stage
+		if approval.NativeApproval != nil {
+			users = approval.NativeApproval.ApproveUsers
+		}
+	} else {
+		for _, escalation := range approval.Escalations {
+			if escalation.Escalated && escalation.GetType() == config.NativeApproval {
+				users = append(users, escalation.ApproveUsers...)
+			}
+		}
+	}
+
+	names := []string{}
+	seen := make(map[string]bool)
+	for _, user := range users {
+		if user.RejectOrApprove != "" || seen[user.UserID] {
+			continue
+		}
+		seen[user.UserID] = true
+		names = append(names, user.UserName)
+	}
+	return names
+}
+
+// pendingLarkApprovers returns the open ids of the users of the current node who have not made the decision
+func pendingLarkApprovers(approval *commonmodels.LarkApproval) []string {
+	ids := []string{}
+	for _, node := range approval.ApprovalNodes {
+		if node.RejectOrApprove != "" {
+			continue
+		}
+		for _, user := range node.ApproveUsers {
+			if user.RejectOrApprove == "" {
+				ids = append(ids, user.ID)
+			}
+		}
+		break
+	}
+	return ids
+}
+
+// pendingDingTalkApprovers returns the user ids of the users of the current node who have not made the decision
+func pendingDingTalkApprovers(approval *commonmodels.DingTalkApproval) []string {
+	ids := []string{}
+	for _, node := range approval.ApprovalNodes {
+		if node.RejectOrApprove != "" {
+			continue
+		}
+		for _, user := range node.ApproveUsers {
+			if user.RejectOrApprove == "" {
+				ids = append(ids, user.ID)
+			}
+		}
+		break
+	}
+	return ids
+}
+
+func (i *larkApprovalInstance) result() (config.ApproveOrReject, error) {
+	instance, err := i.client.GetApprovalInstance(&lark.GetApprovalInstanceArgs{InstanceID: i.instanceID})
+	if err != nil {
+		return "", err
+	}
+	return instance.ApproveOrReject, nil
+}
+
+func (i *larkApprovalInstance) remind(text string) error {
+	ids := pendingLarkApprovers(i.approval)
+	if len(ids) == 0 {
+		return nil
+	}
+	return i.client.SendTextMessage(ids, text)
+}
+
+func (i *larkApprovalInstance) close() {
+	i.cancel()
+	larkservice.RemoveLarkApprovalInstanceManager(i.instanceID)
+}
+
+func (i *dingTalkApprovalInstance) result() (config.ApproveOrReject, error) {
+	instance, err := i.client.GetApprovalInstance(i.instanceID)
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case instance.Status == "COMPLETED" && instance.Result == "agree":
+		return config.Approve, nil
+	case instance.Status == "COMPLETED" && instance.Result == "refuse", instance.Status == "TERMINATED":
+		return config.Reject, nil
+	default:
+		return "", nil
+	}
+}
+
+func (i *dingTalkApprovalInstance) remind(text string) error {
+	ids := pendingDingTalkApprovers(i.approval)
+	if len(ids) == 0 {
+		return nil
+	}
+	return i.client.SendTextMessage(ids, text)
+}
+
+func (i *dingTalkApprovalInstance) close() {
+	i.terminate()
+	dingservice.RemoveDingTalkApprovalManager(i.instanceID)
+}
@@ -0,0 +1,239 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflowcontroller
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/tool/lark"
+)
+
+type fakeApprovalInstance struct {
+	decision config.ApproveOrReject
+	reminded []string
+	closed   bool
+}
+
+func (f *fakeApprovalInstance) result() (config.ApproveOrReject, error) {
+	return f.decision, nil
+}
+
+func (f *fakeApprovalInstance) remind(text string) error {
+	f.reminded = append(f.reminded, text)
+	return nil
+}
+
+func (f *fakeApprovalInstance) close() {
+	f.closed = true
+}
+
+func larkUser(id string, result config.ApproveOrReject) *commonmodels.LarkApprovalUser {
+	return &commonmodels.LarkApprovalUser{UserInfo: lark.UserInfo{ID: id, Name: id}, RejectOrApprove: result}
+}
+
+var _ = Describe("Testing approval escalation", func() {
+
+	var (
+		stage       *commonmodels.StageTask
+		workflowCtx *commonmodels.WorkflowTaskCtx
+		escalator   *approvalEscalator
+		created     []*commonmodels.ApprovalEscalation
+		instance    *fakeApprovalInstance
+		notified    []string
+		acked       int
+	)
+
+	BeforeEach(func() {
+		stage = &commonmodels.StageTask{
+			Name: "release",
+			Approval: &commonmodels.Approval{
+				Enabled: true,
+				Type:    config.NativeApproval,
+				NativeApproval: &commonmodels.NativeApproval{
+					NeededApprovers: 1,
+					ApproveUsers: []*commonmodels.User{
+						{UserID: "1", UserName: "alice", RejectOrApprove: config.Approve},
+						{UserID: "2", UserName: "bob"},
+					},
+				},
+				Escalations: []*commonmodels.ApprovalEscalation{
+					{
+						After: 30,
+						Type:  config.LarkApproval,
+						LarkApproval: &commonmodels.LarkApproval{
+							ApprovalNodes: []*commonmodels.LarkApprovalNode{
+								{Type: "OR", ApproveUsers: []*commonmodels.LarkApprovalUser{larkUser("ou_1", ""), larkUser("ou_2", "")}},
+							},
+						},
+						Escalated: true,
+					},
+				},
+			},
+		}
+		workflowCtx = &commonmodels.WorkflowTaskCtx{WorkflowName: "deploy", WorkflowDisplayName: "deploy", TaskID: 7, ProjectName: "demo"}
+		created, notified, acked = nil, nil, 0
+		instance = &fakeApprovalInstance{}
+
+		escalator = newApprovalEscalator(stage, workflowCtx, nil, zap.NewNop().Sugar(), func() { acked++ })
+		escalator.createInstance = func(escalation *commonmodels.ApprovalEscalation) (approvalInstance, error) {
+			created = append(created, escalation)
+			return instance, nil
+		}
+		escalator.notifyUsers = func(userNames []string, title, content string) {
+			notified = append(notified, userNames...)
+		}
+	})
+
+	Context("newApprovalEscalator", func() {
+		It("should restart the escalations", func() {
+			Expect(stage.Approval.Escalations[0].Escalated).To(BeFalse())
+			Expect(escalator.approveKey).To(Equal("deploy-7-release"))
+		})
+	})
+
+	Context("escalate", func() {
+		It("should create an approval instance for lark escalations", func() {
+			Expect(escalator.escalate(stage.Approval.Escalations[0])).To(Succeed())
+			Expect(created).To(HaveLen(1))
+			Expect(escalator.instances).To(ConsistOf(instance))
+			Expect(escalator.escalated).To(BeFalse())
+		})
+	})
+
+	Context("escalationResult", func() {
+		BeforeEach(func() {
+			Expect(escalator.escalate(stage.Approval.Escalations[0])).To(Succeed())
+		})
+
+		It("should wait while the escalated instance is pending", func() {
+			done, err := escalator.escalationResult()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(done).To(BeFalse())
+		})
+
+		It("should finish the approval when the escalated group approves", func() {
+			instance.decision = config.Approve
+			done, err := escalator.escalationResult()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(done).To(BeTrue())
+		})
+
+		It("should reject the stage when the escalated group rejects", func() {
+			instance.decision = config.Reject
+			done, err := escalator.escalationResult()
+			Expect(err).To(HaveOccurred())
+			Expect(done).To(BeTrue())
+			Expect(stage.Status).To(Equal(config.StatusReject))
+		})
+
+		It("should not query the instances again before the check interval", func() {
+			_, _ = escalator.escalationResult()
+			instance.decision = config.Approve
+			done, _ := escalator.escalationResult()
+			Expect(done).To(BeFalse())
+
+			escalator.lastCheck = time.Now().Add(-escalationCheckInterval)
+			done, _ = escalator.escalationResult()
+			Expect(done).To(BeTrue())
+		})
+
+		It("should close the escalated instances on cleanup", func() {
+			escalator.cleanup()
+			Expect(instance.closed).To(BeTrue())
+		})
+	})
+
+	Context("remind", func() {
+		It("should remind the pending approvers of the stage and the escalated groups", func() {
+			Expect(escalator.escalate(stage.Approval.Escalations[0])).To(Succeed())
+			stageInstance := &fakeApprovalInstance{}
+			escalator.stageInstance = stageInstance
+
+			escalator.remind()
+			Expect(notified).To(Equal([]string{"bob"}))
+			Expect(stageInstance.reminded).To(HaveLen(1))
+			Expect(instance.reminded).To(HaveLen(1))
+			Expect(instance.reminded[0]).To(ContainSubstring("release"))
+		})
+	})
+
+	Context("timeout", func() {
+		It("should approve automatically", func() {
+			stage.Approval.TimeoutAction = config.ApprovalTimeoutApprove
+			Expect(escalator.timeout(config.StatusTimeout)).To(Succeed())
+		})
+		It("should reject automatically", func() {
+			stage.Approval.TimeoutAction = config.ApprovalTimeoutReject
+			Expect(escalator.timeout(config.StatusTimeout)).NotTo(Succeed())
+			Expect(stage.Status).To(Equal(config.StatusReject))
+		})
+		It("should fail with the default status", func() {
+			Expect(escalator.timeout(config.StatusTimeout)).NotTo(Succeed())
+			Expect(stage.Status).To(Equal(config.StatusTimeout))
+		})
+	})
+
+	Context("pending approvers", func() {
+		It("should return the escalated zadig users of a lark approval", func() {
+			approval := &commonmodels.Approval{
+				Type: config.LarkApproval,
+				Escalations: []*commonmodels.ApprovalEscalation{
+					{Escalated: true, ApproveUsers: []*commonmodels.User{{UserID: "1", UserName: "alice"}, {UserID: "2", UserName: "bob", RejectOrApprove: config.Reject}}},
+					{Escalated: true, ApproveUsers: []*commonmodels.User{{UserID: "1", UserName: "alice"}}},
+					{ApproveUsers: []*commonmodels.User{{UserID: "3", UserName: "carol"}}},
+				},
+			}
+			Expect(pendingNativeApprovers(approval)).To(Equal([]string{"alice"}))
+		})
+
+		It("should return the pending users of the current lark node", func() {
+			approval := &commonmodels.LarkApproval{
+				ApprovalNodes: []*commonmodels.LarkApprovalNode{
+					{Type: "AND", RejectOrApprove: config.Approve, ApproveUsers: []*commonmodels.LarkApprovalUser{larkUser("ou_1", config.Approve)}},
+					{Type: "AND", ApproveUsers: []*commonmodels.LarkApprovalUser{larkUser("ou_2", config.Approve), larkUser("ou_3", "")}},
+					{Type: "OR", ApproveUsers: []*commonmodels.LarkApprovalUser{larkUser("ou_4", "")}},
+				},
+			}
+			Expect(pendingLarkApprovers(approval)).To(Equal([]string{"ou_3"}))
+		})
+
+		It("should return the pending users of the current dingtalk node", func() {
+			approval := &commonmodels.DingTalkApproval{
+				ApprovalNodes: []*commonmodels.DingTalkApprovalNode{
+					{Type: "OR", ApproveUsers: []*commonmodels.DingTalkApprovalUser{{ID: "d1"}, {ID: "d2", RejectOrApprove: config.Approve}}},
+					{Type: "OR", ApproveUsers: []*commonmodels.DingTalkApprovalUser{{ID: "d3"}}},
+				},
+			}
+			Expect(pendingDingTalkApprovers(approval)).To(Equal([]string{"d1"}))
+		})
+	})
+
+	Context("escalationApprovers", func() {
+		It("should return the names of the escalated group", func() {
+			Expect(escalationApprovers(stage.Approval.Escalations[0])).To(Equal([]string{"ou_1", "ou_2"}))
+			Expect(escalationApprovers(&commonmodels.ApprovalEscalation{
+				ApproveUsers: []*commonmodels.User{{UserName: "alice"}},
+			})).To(Equal([]string{"alice"}))
+		})
+	})
+})
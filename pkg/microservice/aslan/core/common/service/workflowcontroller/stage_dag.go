@@ -0,0 +1,250 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflowcontroller
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/workflowcontroller/jobcontroller"
+)
+
+// dagStage wraps a stage task when the workflow is scheduled as a DAG,
+// the stage is started (and approved) by the first of its jobs which becomes ready.
+type dagStage struct {
+	stage   *commonmodels.StageTask
+	once    sync.Once
+	started bool
+	err     error
+	// pending is the number of nodes in this stage that are not finished yet
+	pending int
+}
+
+// dagNode is a workflow job in the DAG, it holds all the job tasks generated from the same workflow job.
+type dagNode struct {
+	name     string
+	stage    *dagStage
+	jobs     []*commonmodels.JobTask
+	upstream []string
+	status   config.Status
+}
+
+func dagEnabled(stages []*commonmodels.StageTask) bool {
+	for _, stage := range stages {
+		for _, job := range stage.Jobs {
+			if len(job.DependsOn) > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// buildDAG groups the job tasks by their origin workflow job.
+// jobs without depends_on keep the stage semantics: they depend on all jobs of the previous stage,
+// and on the previous job of the same stage if the stage is not parallel.
+func buildDAG(stages []*commonmodels.StageTask) ([]*dagNode, []*dagStage) {
+	nodes := []*dagNode{}
+	dagStages := []*dagStage{}
+	nodeMap := make(map[string]*dagNode)
+	previousStageNodes := []string{}
+	for _, stage := range stages {
+		ds := &dagStage{stage: stage}
+		dagStages = append(dagStages, ds)
+		currentStageNodes := []string{}
+		for _, job := range stage.Jobs {
+			name := job.OriginName
+			// job tasks created before depends_on was supported do not have origin name
+			if name == "" {
+				name = job.Name
+			}
+			if node, ok := nodeMap[name]; ok {
+				node.jobs = append(node.jobs, job)
+				continue
+			}
+			node := &dagNode{name: name, stage: ds, jobs: []*commonmodels.JobTask{job}}
+			if len(job.DependsOn) > 0 {
+				node.upstream = append(node.upstream, job.DependsOn...)
+			} else {
+				node.upstream = append(node.upstream, previousStageNodes...)
+				if !stage.Parallel && len(currentStageNodes) > 0 {
+					node.upstream = append(node.upstream, currentStageNodes[len(currentStageNodes)-1])
+				}
+			}
+			nodeMap[name] = node
+			nodes = append(nodes, node)
+			currentStageNodes = append(currentStageNodes, name)
+			ds.pending++
+		}
+		if len(currentStageNodes) > 0 {
+			previousStageNodes = currentStageNodes
+		}
+	}
+
+	// upstream jobs which are not in the task, like skipped jobs, are treated as satisfied.
+	for _, node := range nodes {
+		upstream := []string{}
+		for _, name := range node.upstream {
+			if _, ok := nodeMap[name]; ok {
+				upstream = append(upstream, name)
+			}
+		}
+		node.upstream = upstream
+	}
+	return nodes, dagStages
+}
+
+// RunStagesDAG starts every job as soon as all of its upstream jobs passed, regardless of the stage it belongs to.
+// jobs whose upstream failed are never started.
+func RunStagesDAG(ctx context.Context, stages []*commonmodels.StageTask, workflowCtx *commonmodels.WorkflowTaskCtx, concurrency int, logger *zap.SugaredLogger, ack func()) {
+	nodes, dagStages := buildDAG(stages)
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	finished := make(map[string]config.Status, len(nodes))
+	// should skip passed job when workflow task be restarted
+	for _, node := range nodes {
+		if status := dagNodeStatus(node); status == config.StatusPassed {
+			finished[node.name] = status
+			node.stage.pending--
+		}
+	}
+
+	// the job tasks of all the nodes share one semaphore, so that the workflow concurrency is not exceeded
+	ctx = jobcontroller.ContextWithJobSemaphore(ctx, make(chan struct{}, concurrency))
+	resultChan := make(chan *dagNode)
+	started := make(map[string]bool, len(nodes))
+	running := 0
+	for {
+		if ctx.Err() == nil {
+			for _, node := range nodes {
+				if started[node.name] {
+					continue
+				}
+				if _, ok := finished[node.name]; ok {
+					continue
+				}
+				if !dagUpstreamPassed(node, finished) {
+					continue
+				}
+				started[node.name] = true
+				running++
+				go func(node *dagNode) {
+					runDAGNode(ctx, node, workflowCtx, logger, ack)
+					resultChan <- node
+				}(node)
+			}
+		}
+		if running == 0 {
+			break
+		}
+		node := <-resultChan
+		running--
+		finished[node.name] = node.status
+		node.stage.pending--
+		if node.stage.pending == 0 {
+			finishDAGStage(node.stage, logger, ack)
+		}
+	}
+
+	// stages with jobs that can not be started because of upstream failure
+	for _, ds := range dagStages {
+		if ds.started && ds.pending > 0 {
+			finishDAGStage(ds, logger, ack)
+		}
+	}
+}
+
+// runDAGNode runs the job tasks of the node, every job task takes a slot of the semaphore in ctx while it is running,
+// and the approval of the stage does not take any.
+func runDAGNode(ctx context.Context, node *dagNode, workflowCtx *commonmodels.WorkflowTaskCtx, logger *zap.SugaredLogger, ack func()) {
+	if err := node.stage.start(ctx, workflowCtx, logger, ack); err != nil {
+		node.status = node.stage.stage.Status
+		return
+	}
+
+	var workerConcurrency = 1
+	if node.stage.stage.Parallel {
+		workerConcurrency = len(node.jobs)
+	}
+	logger.Infof("start dag job: %s in stage: %s", node.name, node.stage.stage.Name)
+	jobcontroller.RunJobs(ctx, node.jobs, workflowCtx, workerConcurrency, logger, ack)
+	node.status = dagNodeStatus(node)
+	logger.Infof("finish dag job: %s in stage: %s, status: %s", node.name, node.stage.stage.Name, node.status)
+}
+
+func (s *dagStage) start(ctx context.Context, workflowCtx *commonmodels.WorkflowTaskCtx, logger *zap.SugaredLogger, ack func()) error {
+	s.once.Do(func() {
+		s.started = true
+		s.stage.Status = config.StatusRunning
+		ack()
+		logger.Infof("start stage: %s,status: %s", s.stage.Name, s.stage.Status)
+		if err := waitForApprove(ctx, s.stage, workflowCtx, logger, ack); err != nil {
+			s.stage.Error = err.Error()
+			s.err = err
+			logger.Errorf("finish stage: %s,status: %s error: %s", s.stage.Name, s.stage.Status, s.stage.Error)
+			ack()
+			return
+		}
+		s.stage.StartTime = time.Now().Unix()
+		ack()
+	})
+	return s.err
+}
+
+func finishDAGStage(s *dagStage, logger *zap.SugaredLogger, ack func()) {
+	// the status of stage was already set when approval failed
+	if s.err != nil {
+		return
+	}
+	updateStageStatus(s.stage)
+	s.stage.EndTime = time.Now().Unix()
+	logger.Infof("finish stage: %s,status: %s", s.stage.Name, s.stage.Status)
+	ack()
+}
+
+func dagUpstreamPassed(node *dagNode, finished map[string]config.Status) bool {
+	for _, upstream := range node.upstream {
+		status, ok := finished[upstream]
+		if !ok {
+			return false
+		}
+		if status != config.StatusPassed && status != config.StatusSkipped {
+			return false
+		}
+	}
+	return true
+}
+
+func dagNodeStatus(node *dagNode) config.Status {
+	status := config.StatusPassed
+	for _, job := range node.jobs {
+		if statusFailed(job.Status) {
+			return job.Status
+		}
+		if job.Status != config.StatusPassed && job.Status != config.StatusSkipped {
+			status = job.Status
+		}
+	}
+	return status
+}
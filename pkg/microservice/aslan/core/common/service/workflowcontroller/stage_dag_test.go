@@ -0,0 +1,135 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflowcontroller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+)
+
+func dagJob(name string, dependsOn ...string) *commonmodels.JobTask {
+	return &commonmodels.JobTask{Name: name, OriginName: name, DependsOn: dependsOn}
+}
+
+func dagUpstreams(nodes []*dagNode) map[string][]string {
+	upstreams := make(map[string][]string)
+	for _, node := range nodes {
+		upstreams[node.name] = node.upstream
+	}
+	return upstreams
+}
+
+var _ = Describe("Testing workflow DAG", func() {
+
+	Context("dagEnabled", func() {
+		It("should only be enabled when a job declares depends_on", func() {
+			Expect(dagEnabled([]*commonmodels.StageTask{{Jobs: []*commonmodels.JobTask{dagJob("a")}}})).To(BeFalse())
+			Expect(dagEnabled([]*commonmodels.StageTask{
+				{Jobs: []*commonmodels.JobTask{dagJob("a")}},
+				{Jobs: []*commonmodels.JobTask{dagJob("b", "a")}},
+			})).To(BeTrue())
+		})
+	})
+
+	Context("buildDAG", func() {
+		It("should keep the stage semantics for jobs without depends_on", func() {
+			nodes, stages := buildDAG([]*commonmodels.StageTask{
+				{Name: "build", Parallel: true, Jobs: []*commonmodels.JobTask{dagJob("build-a"), dagJob("build-b")}},
+				{Name: "deploy", Jobs: []*commonmodels.JobTask{dagJob("deploy"), dagJob("test")}},
+			})
+			Expect(stages).To(HaveLen(2))
+			Expect(stages[0].pending).To(Equal(2))
+			Expect(dagUpstreams(nodes)).To(Equal(map[string][]string{
+				"build-a": {},
+				"build-b": {},
+				"deploy":  {"build-a", "build-b"},
+				// jobs of a sequential stage depend on the previous job of the stage too
+				"test": {"build-a", "build-b", "deploy"},
+			}))
+		})
+
+		It("should only depend on the declared jobs", func() {
+			nodes, _ := buildDAG([]*commonmodels.StageTask{
+				{Name: "build", Parallel: true, Jobs: []*commonmodels.JobTask{dagJob("build-a"), dagJob("build-b")}},
+				{Name: "deploy", Parallel: true, Jobs: []*commonmodels.JobTask{dagJob("deploy-a", "build-a"), dagJob("deploy-b", "build-b")}},
+			})
+			Expect(dagUpstreams(nodes)).To(Equal(map[string][]string{
+				"build-a":  {},
+				"build-b":  {},
+				"deploy-a": {"build-a"},
+				"deploy-b": {"build-b"},
+			}))
+		})
+
+		It("should group the job tasks of the same job into one node", func() {
+			jobA1, jobA2 := dagJob("a"), dagJob("a")
+			jobA1.Name, jobA2.Name = "a-1", "a-2"
+			nodes, stages := buildDAG([]*commonmodels.StageTask{
+				{Name: "build", Parallel: true, Jobs: []*commonmodels.JobTask{jobA1, jobA2}},
+			})
+			Expect(nodes).To(HaveLen(1))
+			Expect(nodes[0].jobs).To(ConsistOf(jobA1, jobA2))
+			Expect(stages[0].pending).To(Equal(1))
+		})
+
+		It("should ignore the upstream jobs which are not in the task", func() {
+			nodes, _ := buildDAG([]*commonmodels.StageTask{
+				{Name: "deploy", Jobs: []*commonmodels.JobTask{dagJob("deploy", "skipped-build")}},
+			})
+			Expect(nodes[0].upstream).To(BeEmpty())
+		})
+
+		It("should use the job name for job tasks without origin name", func() {
+			nodes, _ := buildDAG([]*commonmodels.StageTask{
+				{Name: "build", Jobs: []*commonmodels.JobTask{{Name: "legacy"}}},
+			})
+			Expect(nodes[0].name).To(Equal("legacy"))
+		})
+	})
+
+	Context("dagUpstreamPassed", func() {
+		node := &dagNode{name: "deploy", upstream: []string{"build", "lint"}}
+
+		It("should wait for all the upstream jobs", func() {
+			Expect(dagUpstreamPassed(node, map[string]config.Status{"build": config.StatusPassed})).To(BeFalse())
+		})
+		It("should pass when the upstream jobs passed or were skipped", func() {
+			Expect(dagUpstreamPassed(node, map[string]config.Status{"build": config.StatusPassed, "lint": config.StatusSkipped})).To(BeTrue())
+		})
+		It("should not pass when an upstream job failed", func() {
+			Expect(dagUpstreamPassed(node, map[string]config.Status{"build": config.StatusPassed, "lint": config.StatusFailed})).To(BeFalse())
+		})
+	})
+
+	Context("dagNodeStatus", func() {
+		It("should return the failed status of any job task", func() {
+			node := &dagNode{jobs: []*commonmodels.JobTask{{Status: config.StatusPassed}, {Status: config.StatusTimeout}, {Status: config.StatusRunning}}}
+			Expect(dagNodeStatus(node)).To(Equal(config.StatusTimeout))
+		})
+		It("should pass when all job tasks passed or were skipped", func() {
+			node := &dagNode{jobs: []*commonmodels.JobTask{{Status: config.StatusPassed}, {Status: config.StatusSkipped}}}
+			Expect(dagNodeStatus(node)).To(Equal(config.StatusPassed))
+		})
+		It("should return the status of the unfinished job task", func() {
+			node := &dagNode{jobs: []*commonmodels.JobTask{{Status: config.StatusPassed}, {Status: ""}}}
+			Expect(dagNodeStatus(node)).To(Equal(config.Status("")))
+		})
+	})
+})
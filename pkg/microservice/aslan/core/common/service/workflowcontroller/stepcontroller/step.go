@@ -36,7 +36,7 @@ type StepCtl interface {
 func PrepareSteps(ctx context.Context, workflowCtx *commonmodels.WorkflowTaskCtx, jobPath *string, jobName string, steps []*commonmodels.StepTask, logger *zap.SugaredLogger) error {
 	stepCtls := []StepCtl{}
 	for _, step := range steps {
-		stepCtl, err := instantiateStepCtl(step, workflowCtx, jobPath, jobName, logger)
+		stepCtl, err := instantiateStepCtl(step, steps, workflowCtx, jobPath, jobName, logger)
 		if err != nil {
 			return err
 		}
@@ -53,7 +53,7 @@ func PrepareSteps(ctx context.Context, workflowCtx *commonmodels.WorkflowTaskCtx
 func SummarizeSteps(ctx context.Context, workflowCtx *commonmodels.WorkflowTaskCtx, jobPath *string, jobName string, steps []*commonmodels.StepTask, logger *zap.SugaredLogger) error {
 	stepCtls := []StepCtl{}
 	for _, step := range steps {
-		stepCtl, err := instantiateStepCtl(step, workflowCtx, jobPath, jobName, logger)
+		stepCtl, err := instantiateStepCtl(step, steps, workflowCtx, jobPath, jobName, logger)
 		if err != nil {
 			return err
 		}
@@ -67,7 +67,7 @@ func SummarizeSteps(ctx context.Context, workflowCtx *commonmodels.WorkflowTaskC
 	return nil
 }
 
-func instantiateStepCtl(step *commonmodels.StepTask, workflowCtx *commonmodels.WorkflowTaskCtx, jobPath *string, jobName string, logger *zap.SugaredLogger) (StepCtl, error) {
+func instantiateStepCtl(step *commonmodels.StepTask, steps []*commonmodels.StepTask, workflowCtx *commonmodels.WorkflowTaskCtx, jobPath *string, jobName string, logger *zap.SugaredLogger) (StepCtl, error) {
 	var stepCtl StepCtl
 	var err error
 	switch step.StepType {
@@ -83,12 +83,24 @@ func instantiateStepCtl(step *commonmodels.StepTask, workflowCtx *commonmodels.W
 		stepCtl, err = NewArchiveCtl(step, logger)
 	case config.StepJunitReport:
 		stepCtl, err = NewJunitReportCtl(step, logger)
+	case config.StepTestReport:
+		stepCtl, err = NewTestReportCtl(step, steps, workflowCtx, jobName, logger)
+	case config.StepTestImpact:
+		stepCtl, err = NewTestImpactCtl(step, logger)
+	case config.StepArtifactPublish:
+		stepCtl, err = NewArtifactPublishCtl(step, logger)
 	case config.StepTarArchive:
 		stepCtl, err = NewTarArchiveCtl(step, logger)
 	case config.StepSonarCheck:
 		stepCtl, err = NewSonarCheckCtl(step, logger)
 	case config.StepDistributeImage:
 		stepCtl, err = NewDistributeCtl(step, workflowCtx, jobName, logger)
+	case config.StepSBOMScan:
+		stepCtl, err = NewSBOMScanCtl(step, workflowCtx, jobName, logger)
+	case config.StepImageSign:
+		stepCtl, err = NewImageSignCtl(step, steps, logger)
+	case config.StepCacheRestore, config.StepCacheSave:
+		stepCtl, err = NewCacheCtl(step, logger)
 	case config.StepDebugBefore, config.StepDebugAfter:
 		stepCtl, err = NewDebugCtl()
 	default:
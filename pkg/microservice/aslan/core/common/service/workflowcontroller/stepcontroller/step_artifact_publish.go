@@ -0,0 +1,55 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stepcontroller
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/types/step"
+)
+
+type artifactPublishCtl struct {
+	step                *commonmodels.StepTask
+	artifactPublishSpec *step.StepArtifactPublishSpec
+	log                 *zap.SugaredLogger
+}
+
+func NewArtifactPublishCtl(stepTask *commonmodels.StepTask, log *zap.SugaredLogger) (*artifactPublishCtl, error) {
+	yamlString, err := yaml.Marshal(stepTask.Spec)
+	if err != nil {
+		return nil, fmt.Errorf("marshal artifact publish spec error: %v", err)
+	}
+	artifactPublishSpec := &step.StepArtifactPublishSpec{}
+	if err := yaml.Unmarshal(yamlString, &artifactPublishSpec); err != nil {
+		return nil, fmt.Errorf("unmarshal artifact publish spec error: %v", err)
+	}
+	stepTask.Spec = artifactPublishSpec
+	return &artifactPublishCtl{artifactPublishSpec: artifactPublishSpec, log: log, step: stepTask}, nil
+}
+
+func (s *artifactPublishCtl) PreRun(ctx context.Context) error {
+	return nil
+}
+
+func (s *artifactPublishCtl) AfterRun(ctx context.Context) error {
+	return nil
+}
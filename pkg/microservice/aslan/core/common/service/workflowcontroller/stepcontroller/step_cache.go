@@ -0,0 +1,64 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stepcontroller
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/types/step"
+)
+
+type cacheCtl struct {
+	step      *commonmodels.StepTask
+	cacheSpec *step.StepCacheSpec
+	log       *zap.SugaredLogger
+}
+
+func NewCacheCtl(stepTask *commonmodels.StepTask, log *zap.SugaredLogger) (*cacheCtl, error) {
+	yamlString, err := yaml.Marshal(stepTask.Spec)
+	if err != nil {
+		return nil, fmt.Errorf("marshal cache spec error: %v", err)
+	}
+	cacheSpec := &step.StepCacheSpec{}
+	if err := yaml.Unmarshal(yamlString, &cacheSpec); err != nil {
+		return nil, fmt.Errorf("unmarshal cache spec error: %v", err)
+	}
+	stepTask.Spec = cacheSpec
+	return &cacheCtl{cacheSpec: cacheSpec, log: log, step: stepTask}, nil
+}
+
+func (s *cacheCtl) PreRun(ctx context.Context) error {
+	if s.cacheSpec.S3Storage == nil {
+		modelS3, err := commonrepo.NewS3StorageColl().FindDefault()
+		if err != nil {
+			return err
+		}
+		s.cacheSpec.S3Storage = modelS3toS3(modelS3)
+	}
+	s.step.Spec = s.cacheSpec
+	return nil
+}
+
+func (s *cacheCtl) AfterRun(ctx context.Context) error {
+	return nil
+}
@@ -51,6 +51,9 @@ func NewDockerBuildCtl(stepTask *commonmodels.StepTask, log *zap.SugaredLogger)
 }
 
 func (s *dockerBuildCtl) PreRun(ctx context.Context) error {
+	if err := s.dockerBuildSpec.ValidateBuilder(); err != nil {
+		return err
+	}
 	proxies, _ := mongodb.NewProxyColl().List(&mongodb.ProxyArgs{})
 	if len(proxies) != 0 {
 		s.dockerBuildSpec.Proxy.Address = proxies[0].Address
@@ -0,0 +1,90 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stepcontroller
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+	"golang.org/x/exp/slices"
+	"gopkg.in/yaml.v2"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/types/step"
+)
+
+type imageSignCtl struct {
+	step          *commonmodels.StepTask
+	steps         []*commonmodels.StepTask
+	imageSignSpec *step.StepImageSignSpec
+	log           *zap.SugaredLogger
+}
+
+func NewImageSignCtl(stepTask *commonmodels.StepTask, steps []*commonmodels.StepTask, log *zap.SugaredLogger) (*imageSignCtl, error) {
+	yamlString, err := yaml.Marshal(stepTask.Spec)
+	if err != nil {
+		return nil, fmt.Errorf("marshal image sign spec error: %v", err)
+	}
+	imageSignSpec := &step.StepImageSignSpec{}
+	if err := yaml.Unmarshal(yamlString, &imageSignSpec); err != nil {
+		return nil, fmt.Errorf("unmarshal image sign spec error: %v", err)
+	}
+	stepTask.Spec = imageSignSpec
+	return &imageSignCtl{imageSignSpec: imageSignSpec, steps: steps, log: log, step: stepTask}, nil
+}
+
+// PreRun sets the signing key, and the target images of the distribute step which are resolved by its PreRun
+func (s *imageSignCtl) PreRun(ctx context.Context) error {
+	signingKey, err := commonrepo.NewImageSigningKeyColl().FindByID(s.imageSignSpec.SigningKeyID)
+	if err != nil {
+		return fmt.Errorf("failed to find image signing key %s: %v", s.imageSignSpec.SigningKeyID, err)
+	}
+	s.imageSignSpec.SigningKey = signingKey.ToStepSigningKey()
+
+	if s.imageSignSpec.ImagesFromStep != "" {
+		for _, stepTask := range s.steps {
+			if stepTask.Name != s.imageSignSpec.ImagesFromStep {
+				continue
+			}
+			distributeSpec, ok := stepTask.Spec.(*step.StepImageDistributeSpec)
+			if !ok {
+				return fmt.Errorf("step %s is not a distribute image step", stepTask.Name)
+			}
+			for _, target := range distributeSpec.DistributeTarget {
+				if !slices.Contains(s.imageSignSpec.Images, target.TargetImage) {
+					s.imageSignSpec.Images = append(s.imageSignSpec.Images, target.TargetImage)
+				}
+			}
+			if reg := distributeSpec.TargetRegistry; reg != nil && len(s.imageSignSpec.DockerRegistries) == 0 {
+				s.imageSignSpec.DockerRegistries = append(s.imageSignSpec.DockerRegistries, &step.DockerRegistry{
+					Host:      reg.RegAddr,
+					Namespace: reg.Namespace,
+					UserName:  reg.AccessKey,
+					Password:  reg.SecretKey,
+				})
+			}
+		}
+	}
+	s.step.Spec = s.imageSignSpec
+	return nil
+}
+
+func (s *imageSignCtl) AfterRun(ctx context.Context) error {
+	return nil
+}
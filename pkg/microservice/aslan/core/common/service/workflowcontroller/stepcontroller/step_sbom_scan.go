@@ -0,0 +1,154 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stepcontroller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/setting"
+	s3tool "github.com/koderover/zadig/pkg/tool/s3"
+	"github.com/koderover/zadig/pkg/types/step"
+	"github.com/koderover/zadig/pkg/util"
+)
+
+// sboms larger than this are kept in the object storage only, in case the image scan exceeds the mongo document size
+const maxStoredSBOMSize = 4 << 20
+
+type sbomScanCtl struct {
+	step         *commonmodels.StepTask
+	sbomScanSpec *step.StepSBOMScanSpec
+	workflowCtx  *commonmodels.WorkflowTaskCtx
+	jobName      string
+	log          *zap.SugaredLogger
+}
+
+func NewSBOMScanCtl(stepTask *commonmodels.StepTask, workflowCtx *commonmodels.WorkflowTaskCtx, jobName string, log *zap.SugaredLogger) (*sbomScanCtl, error) {
+	yamlString, err := yaml.Marshal(stepTask.Spec)
+	if err != nil {
+		return nil, fmt.Errorf("marshal sbom scan spec error: %v", err)
+	}
+	sbomScanSpec := &step.StepSBOMScanSpec{}
+	if err := yaml.Unmarshal(yamlString, &sbomScanSpec); err != nil {
+		return nil, fmt.Errorf("unmarshal sbom scan spec error: %v", err)
+	}
+	stepTask.Spec = sbomScanSpec
+	return &sbomScanCtl{sbomScanSpec: sbomScanSpec, workflowCtx: workflowCtx, jobName: jobName, log: log, step: stepTask}, nil
+}
+
+func (s *sbomScanCtl) PreRun(ctx context.Context) error {
+	if !step.IsValidSeverity(s.sbomScanSpec.SeverityThreshold) {
+		return fmt.Errorf("unknown severity threshold %s", s.sbomScanSpec.SeverityThreshold)
+	}
+	if s.sbomScanSpec.S3Storage == nil {
+		modelS3, err := commonrepo.NewS3StorageColl().FindDefault()
+		if err != nil {
+			return err
+		}
+		s.sbomScanSpec.S3Storage = modelS3toS3(modelS3)
+	}
+	s.step.Spec = s.sbomScanSpec
+	return nil
+}
+
+// AfterRun saves the sbom and vulnerabilities uploaded by the step, and attaches them to the delivery version of the task
+func (s *sbomScanCtl) AfterRun(ctx context.Context) error {
+	storage := s.sbomScanSpec.S3Storage
+	if storage == nil {
+		return nil
+	}
+	forcedPathStyle := true
+	if storage.Provider == setting.ProviderSourceAli {
+		forcedPathStyle = false
+	}
+	client, err := s3tool.NewClient(storage.Endpoint, storage.Ak, storage.Sk, storage.Region, storage.Insecure, forcedPathStyle)
+	if err != nil {
+		s.log.Errorf("failed to create s3 client, error: %v", err)
+		return err
+	}
+	destDir := strings.TrimLeft(path.Join(storage.Subfolder, s.sbomScanSpec.S3DestDir), "/")
+
+	reportContent, err := s.download(client, storage.Bucket, path.Join(destDir, step.VulnerabilityReportFileName))
+	if err != nil {
+		// the step failed before the image was scanned
+		s.log.Warnf("failed to download vulnerability report, error: %v", err)
+		return nil
+	}
+	report := &step.ImageVulnerabilityReport{}
+	if err := json.Unmarshal(reportContent, report); err != nil {
+		s.log.Errorf("failed to unmarshal vulnerability report, error: %v", err)
+		return err
+	}
+
+	imageScan := &commonmodels.ImageScan{
+		ImageName:         report.ImageName,
+		ProjectName:       s.workflowCtx.ProjectName,
+		WorkflowName:      s.workflowCtx.WorkflowName,
+		TaskID:            s.workflowCtx.TaskID,
+		JobName:           s.jobName,
+		SBOMFormat:        s.sbomScanSpec.GetSBOMFormat(),
+		SeverityThreshold: report.SeverityThreshold,
+		Passed:            report.Passed,
+		SeverityCount:     report.SeverityCount,
+		Findings:          report.Findings,
+		CreatedAt:         time.Now().Unix(),
+	}
+	sbomContent, err := s.download(client, storage.Bucket, path.Join(destDir, step.SBOMFileName))
+	if err != nil {
+		s.log.Errorf("failed to download sbom, error: %v", err)
+	} else if len(sbomContent) <= maxStoredSBOMSize {
+		imageScan.SBOM = string(sbomContent)
+	}
+	if err := commonrepo.NewImageScanColl().Create(imageScan); err != nil {
+		s.log.Errorf("failed to save image scan of %s, error: %v", imageScan.ImageName, err)
+		return err
+	}
+
+	deliveryVersion, err := commonrepo.NewDeliveryVersionColl().Get(&commonrepo.DeliveryVersionArgs{
+		ProductName:  s.workflowCtx.ProjectName,
+		WorkflowName: s.workflowCtx.WorkflowName,
+		TaskID:       int(s.workflowCtx.TaskID),
+	})
+	if err == nil {
+		if err := commonrepo.NewDeliveryVersionColl().AddImageScan(deliveryVersion.ID, imageScan.ID.Hex()); err != nil {
+			s.log.Errorf("failed to add image scan to delivery version %s, error: %v", deliveryVersion.Version, err)
+		}
+	}
+	return nil
+}
+
+func (s *sbomScanCtl) download(client *s3tool.Client, bucket, objectKey string) ([]byte, error) {
+	filename, err := util.GenerateTmpFile()
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(filename)
+	if err := client.Download(bucket, objectKey, filename); err != nil {
+		return nil, err
+	}
+	return os.ReadFile(filename)
+}
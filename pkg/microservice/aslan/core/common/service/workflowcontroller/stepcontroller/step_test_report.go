@@ -0,0 +1,214 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stepcontroller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/setting"
+	s3tool "github.com/koderover/zadig/pkg/tool/s3"
+	"github.com/koderover/zadig/pkg/types/step"
+	"github.com/koderover/zadig/pkg/util"
+)
+
+type testReportCtl struct {
+	step           *commonmodels.StepTask
+	steps          []*commonmodels.StepTask
+	testReportSpec *step.StepTestReportSpec
+	workflowCtx    *commonmodels.WorkflowTaskCtx
+	jobName        string
+	log            *zap.SugaredLogger
+}
+
+func NewTestReportCtl(stepTask *commonmodels.StepTask, steps []*commonmodels.StepTask, workflowCtx *commonmodels.WorkflowTaskCtx, jobName string, log *zap.SugaredLogger) (*testReportCtl, error) {
+	yamlString, err := yaml.Marshal(stepTask.Spec)
+	if err != nil {
+		return nil, fmt.Errorf("marshal test report spec error: %v", err)
+	}
+	testReportSpec := &step.StepTestReportSpec{}
+	if err := yaml.Unmarshal(yamlString, &testReportSpec); err != nil {
+		return nil, fmt.Errorf("unmarshal test report spec error: %v", err)
+	}
+	stepTask.Spec = testReportSpec
+	return &testReportCtl{testReportSpec: testReportSpec, steps: steps, workflowCtx: workflowCtx, jobName: jobName, log: log, step: stepTask}, nil
+}
+
+func (s *testReportCtl) PreRun(ctx context.Context) error {
+	if s.testReportSpec.S3Storage == nil {
+		modelS3, err := commonrepo.NewS3StorageColl().FindDefault()
+		if err != nil {
+			return err
+		}
+		s.testReportSpec.S3Storage = modelS3toS3(modelS3)
+	}
+	if s.testReportSpec.TestName != "" {
+		caseNames, err := commonrepo.NewTestCaseQuarantineColl().ListQuarantinedCaseNames(s.workflowCtx.ProjectName, s.testReportSpec.TestName)
+		if err != nil {
+			return fmt.Errorf("failed to list quarantined cases of test %s: %v", s.testReportSpec.TestName, err)
+		}
+		s.testReportSpec.QuarantinedCases = caseNames
+	}
+	s.step.Spec = s.testReportSpec
+	return nil
+}
+
+// AfterRun saves the normalized report uploaded by the step, which is the source of the coverage trend,
+// flaky test and duration history of the test
+func (s *testReportCtl) AfterRun(ctx context.Context) error {
+	storage := s.testReportSpec.S3Storage
+	if storage == nil {
+		return nil
+	}
+	forcedPathStyle := true
+	if storage.Provider == setting.ProviderSourceAli {
+		forcedPathStyle = false
+	}
+	client, err := s3tool.NewClient(storage.Endpoint, storage.Ak, storage.Sk, storage.Region, storage.Insecure, forcedPathStyle)
+	if err != nil {
+		s.log.Errorf("failed to create s3 client, error: %v", err)
+		return err
+	}
+	objectKey := strings.TrimLeft(path.Join(storage.Subfolder, s.testReportSpec.S3DestDir, step.TestReportFileName), "/")
+
+	filename, err := util.GenerateTmpFile()
+	if err != nil {
+		return err
+	}
+	defer os.Remove(filename)
+	if err := client.Download(storage.Bucket, objectKey, filename); err != nil {
+		// the step failed before the report was uploaded
+		s.log.Warnf("failed to download test report, error: %v", err)
+		return nil
+	}
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	result := &commonmodels.TestReportResult{
+		ProjectName:  s.workflowCtx.ProjectName,
+		WorkflowName: s.workflowCtx.WorkflowName,
+		TaskID:       s.workflowCtx.TaskID,
+		JobName:      s.jobName,
+		TestName:     s.testReportSpec.TestName,
+		CreatedAt:    time.Now().Unix(),
+	}
+	if err := json.Unmarshal(content, &result.TestReport); err != nil {
+		s.log.Errorf("failed to unmarshal test report, error: %v", err)
+		return err
+	}
+	for _, stepTask := range s.steps {
+		if stepTask.StepType != config.StepGit {
+			continue
+		}
+		gitSpec := &step.StepGitSpec{}
+		if err := commonmodels.IToi(stepTask.Spec, gitSpec); err != nil || len(gitSpec.Repos) == 0 {
+			continue
+		}
+		result.RepoName = gitSpec.Repos[0].RepoName
+		result.Branch = gitSpec.Repos[0].Branch
+		result.CommitID = gitSpec.Repos[0].CommitID
+	}
+	if err := commonrepo.NewTestReportResultColl().Create(result); err != nil {
+		s.log.Errorf("failed to save test report of job %s, error: %v", s.jobName, err)
+		return err
+	}
+	s.detectFlakyCases(result)
+
+	// the junit report step updates the stat of the test by itself
+	for _, stepTask := range s.steps {
+		if stepTask.StepType == config.StepJunitReport {
+			return nil
+		}
+	}
+	if s.testReportSpec.TestName == "" || result.Tests == 0 {
+		return nil
+	}
+	testTaskStat, _ := commonrepo.NewTestTaskStatColl().FindTestTaskStat(&commonrepo.TestTaskStatOption{Name: s.testReportSpec.TestName})
+	if testTaskStat == nil {
+		testTaskStat = &commonmodels.TestTaskStat{
+			Name:         s.testReportSpec.TestName,
+			TestCaseNum:  result.Tests,
+			TotalSuccess: 1,
+			CreateTime:   time.Now().Unix(),
+			UpdateTime:   time.Now().Unix(),
+		}
+		_ = commonrepo.NewTestTaskStatColl().Create(testTaskStat)
+		return nil
+	}
+	testTaskStat.TestCaseNum = result.Tests
+	testTaskStat.TotalSuccess++
+	testTaskStat.UpdateTime = time.Now().Unix()
+	_ = commonrepo.NewTestTaskStatColl().Update(testTaskStat)
+	return nil
+}
+
+// detectFlakyCases flags the cases which passed after reruns, or flipped between passed and failed on the same commit
+func (s *testReportCtl) detectFlakyCases(result *commonmodels.TestReportResult) {
+	if result.TestName == "" {
+		return
+	}
+	flakyCases := make(map[string]bool)
+	for _, c := range result.Cases {
+		if c.Flaky {
+			flakyCases[c.FullName()] = true
+		}
+	}
+	if result.CommitID != "" {
+		history, err := commonrepo.NewTestReportResultColl().List(&commonrepo.TestReportResultListOption{
+			ProjectName: result.ProjectName,
+			TestName:    result.TestName,
+			CommitID:    result.CommitID,
+		})
+		if err != nil {
+			s.log.Errorf("failed to list test reports of commit %s, error: %v", result.CommitID, err)
+		}
+		statuses := make(map[string]string)
+		for _, c := range result.Cases {
+			if c.Status != step.TestCaseStatusSkipped {
+				statuses[c.FullName()] = c.Status
+			}
+		}
+		for _, previous := range history {
+			if previous.ID == result.ID {
+				continue
+			}
+			for _, c := range previous.Cases {
+				status, ok := statuses[c.FullName()]
+				if ok && c.Status != step.TestCaseStatusSkipped && c.Status != status {
+					flakyCases[c.FullName()] = true
+				}
+			}
+		}
+	}
+	for caseName := range flakyCases {
+		if err := commonrepo.NewTestCaseQuarantineColl().MarkFlaky(result.ProjectName, result.TestName, caseName, result.Branch, result.CommitID); err != nil {
+			s.log.Errorf("failed to mark case %s as flaky, error: %v", caseName, err)
+		}
+	}
+}
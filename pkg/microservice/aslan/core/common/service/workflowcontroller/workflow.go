@@ -154,12 +154,16 @@ func (c *workflowCtl) Run(ctx context.Context, concurrency int) {
 		DockerMountDir:              fmt.Sprintf("/tmp/%s/docker/%d", uuid.NewString(), time.Now().Unix()),
 		ConfigMapMountDir:           fmt.Sprintf("/tmp/%s/cm/%d", uuid.NewString(), time.Now().Unix()),
 		WorkflowKeyVals:             c.workflowTask.KeyVals,
+		WorkflowParams:              c.workflowTask.Params,
 		GlobalContextGet:            c.getGlobalContext,
 		GlobalContextSet:            c.setGlobalContext,
 		GlobalContextEach:           c.globalContextEach,
 		ClusterIDAdd:                c.addCluterID,
 		SetStatus:                   c.setWorkflowStatus,
 	}
+	if c.workflowTask.WorkflowArgs != nil {
+		workflowCtx.HookPayload = c.workflowTask.WorkflowArgs.HookPayload
+	}
 	defer jobcontroller.CleanWorkflowJobs(ctx, c.workflowTask, workflowCtx, c.logger, c.ack)
 	if err := scmnotify.NewService().UpdateWebhookCommentForWorkflowV4(c.workflowTask, c.logger); err != nil {
 		log.Warnf("Failed to update comment for custom workflow %s, taskID: %d the error is: %s", c.workflowTask.WorkflowName, c.workflowTask.TaskID, err)
@@ -41,7 +41,8 @@ import (
 )
 
 var (
-	imageParseRegex = regexp.MustCompile(`(?P<repo>.+/)?(?P<image>[^:]+){1}(:)?(?P<tag>.+)?`)
+	// the digest of an image pinned by the signature policy, e.g. repo/image:tag@sha256:hex, is matched separately
+	imageParseRegex = regexp.MustCompile(`(?P<repo>.+/)?(?P<image>[^:@]+){1}(:)?(?P<tag>[^@]+)?(?:@(?P<digest>.+))?`)
 )
 
 func DownloadServiceManifests(base, projectName, serviceName string, production bool) error {
@@ -207,6 +208,8 @@ func preLoadServiceManifestsFromSource(svc *commonmodels.Service, isProductionSe
 }
 
 // parse image url to map: repo=>xxx/xx/xx image=>xx tag=>xxx
+// the digest of a pinned image is kept in the tag, e.g. tag=>xxx@sha256:hex, so the charts rendering repo:tag
+// still deploy the pinned image
 func resolveImageUrl(imageUrl string) map[string]string {
 	subMatchAll := imageParseRegex.FindStringSubmatch(imageUrl)
 	result := make(map[string]string)
@@ -216,6 +219,14 @@ func resolveImageUrl(imageUrl string) map[string]string {
 			result[exNames[i]] = matchedStr
 		}
 	}
+	if digest, ok := result["digest"]; ok {
+		tag := result[setting.PathSearchComponentTag]
+		if tag == "" {
+			tag = "latest"
+		}
+		result[setting.PathSearchComponentTag] = tag + "@" + digest
+		delete(result, "digest")
+	}
 	return result
 }
 
@@ -42,6 +42,7 @@ func (*Router) Inject(router *gin.RouterGroup) {
 	deliveryRelease := router.Group("releases")
 	{
 		deliveryRelease.GET("/:id", GetDeliveryVersion)
+		deliveryRelease.GET("/:id/imageScans/:scanID", GetDeliveryImageScan)
 		deliveryRelease.GET("", ListDeliveryVersion)
 		deliveryRelease.DELETE("/:id", GetProductNameByDelivery, DeleteDeliveryVersion)
 		deliveryRelease.POST("/helm", CreateHelmDeliveryVersion)
@@ -80,6 +80,34 @@ func GetDeliveryVersion(c *gin.Context) {
 	ctx.Resp, ctx.Err = deliveryservice.GetDetailReleaseData(version, ctx.Logger)
 }
 
+func GetDeliveryImageScan(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Logger.Errorf("failed to generate authorization info for user: %s, error: %s", ctx.UserID, err)
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	// authorization checks
+	if !ctx.Resources.IsSystemAdmin {
+		if !ctx.Resources.SystemActions.DeliveryCenter.ViewVersion {
+			ctx.UnAuthorized = true
+			return
+		}
+	}
+
+	ID := c.Param("id")
+	scanID := c.Param("scanID")
+	if ID == "" || scanID == "" {
+		ctx.Err = e.ErrInvalidParam.AddDesc("id and scanID can't be empty!")
+		return
+	}
+	ctx.Resp, ctx.Err = deliveryservice.GetDeliveryImageScan(ID, scanID, ctx.Logger)
+}
+
 func ListDeliveryVersion(c *gin.Context) {
 	ctx, err := internalhandler.NewContextWithAuthorization(c)
 	defer func() { internalhandler.JSONResponse(c, ctx) }()
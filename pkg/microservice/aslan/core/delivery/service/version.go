@@ -160,6 +160,7 @@ type ReleaseInfo struct {
 	TestInfo       []*commonmodels.DeliveryTest       `json:"testInfo,omitempty"`
 	DistributeInfo []*commonmodels.DeliveryDistribute `json:"distributeInfo,omitempty"`
 	SecurityInfo   []*DeliverySecurityStats           `json:"securityStatsInfo,omitempty"`
+	ImageScans     []*commonmodels.ImageScan          `json:"imageScans,omitempty"`
 }
 
 type DeliverySecurityStatsInfo struct {
@@ -373,6 +374,13 @@ func buildDetailedRelease(deliveryVersion *commonmodels.DeliveryVersion, filterO
 	deliveryDistributes, _ := FindDeliveryDistribute(deliveryDistributeArgs, logger)
 	releaseInfo.DistributeInfo = deliveryDistributes
 
+	//imageScans
+	imageScans, err := commonrepo.NewImageScanColl().ListByIDs(deliveryVersion.ImageScanIDs)
+	if err != nil {
+		logger.Errorf("failed to list image scans of delivery version %s, err: %s", deliveryVersion.Version, err)
+	}
+	releaseInfo.ImageScans = imageScans
+
 	// fill some data for helm delivery releases
 	processReleaseRespData(releaseInfo)
 
@@ -1010,6 +1018,11 @@ func buildDeliveryCharts(chartDataMap map[string]*DeliveryChartData, deliveryVer
 	// offline docker images are not supported
 	taskArgs := buildArtifactTaskArgs(deliveryVersion.ProductName, deliveryVersion.ProductEnvInfo.EnvName, imagesDataMap)
 	taskArgs.TargetRegistries = []string{args.ImageRegistryID}
+	for _, imagesByService := range taskArgs.Images {
+		for _, image := range imagesByService.Images {
+			attachImageScan(deliveryVersion, image.ImageUrl)
+		}
+	}
 	taskID, err := workflowservice.CreateArtifactPackageTask(taskArgs, deliveryVersion.Version, logger)
 	if err != nil {
 		return err
@@ -1740,3 +1753,32 @@ func ApplyDeliveryGlobalVariables(args *DeliveryVariablesApplyArgs, logger *zap.
 	}
 	return ret, nil
 }
+
+// GetDeliveryImageScan returns the image scan of the delivery version, including the sbom
+func GetDeliveryImageScan(versionID, scanID string, log *zap.SugaredLogger) (*commonmodels.ImageScan, error) {
+	deliveryVersion, err := commonrepo.NewDeliveryVersionColl().Get(&commonrepo.DeliveryVersionArgs{ID: versionID})
+	if err != nil {
+		log.Errorf("failed to get delivery version %s, err: %s", versionID, err)
+		return nil, e.ErrGetDeliveryVersion.AddErr(err)
+	}
+	if !sets.NewString(deliveryVersion.ImageScanIDs...).Has(scanID) {
+		return nil, e.ErrGetDeliveryVersion.AddDesc(fmt.Sprintf("image scan %s not found in the version", scanID))
+	}
+	imageScan, err := commonrepo.NewImageScanColl().FindByID(scanID)
+	if err != nil {
+		log.Errorf("failed to find image scan %s, err: %s", scanID, err)
+		return nil, e.ErrGetDeliveryVersion.AddErr(err)
+	}
+	return imageScan, nil
+}
+
+// attachImageScan adds the last sbom scan of the image to the delivery version, if the image has been scanned
+func attachImageScan(deliveryVersion *commonmodels.DeliveryVersion, imageName string) {
+	imageScan, err := commonrepo.NewImageScanColl().FindLatestByImage(imageName)
+	if err != nil {
+		return
+	}
+	if err := commonrepo.NewDeliveryVersionColl().AddImageScan(deliveryVersion.ID, imageScan.ID.Hex()); err != nil {
+		log.Errorf("failed to add image scan of %s to delivery version %s, err: %s", imageName, deliveryVersion.Version, err)
+	}
+}
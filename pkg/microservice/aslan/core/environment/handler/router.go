@@ -62,6 +62,8 @@ func (*Router) Inject(router *gin.RouterGroup) {
 	cron := router.Group("cron")
 	{
 		cron.GET("/cleanproduct", CleanProductCronJob)
+		cron.GET("/drift", DetectEnvDriftCronJob)
+		cron.GET("/gitops", RefreshEnvGitOpsStatusCronJob)
 	}
 
 	// ---------------------------------------------------------------------------------------
@@ -208,6 +210,17 @@ func (*Router) Inject(router *gin.RouterGroup) {
 		production.POST("/environments/:name/sleep", ProductionEnvSleep)
 		production.GET("/environments/:name/sleep/cron", GetProductionEnvSleepCron)
 		production.PUT("/environments/:name/sleep/cron", UpsertProductionEnvSleepCron)
+		production.GET("/environments/:name/gitops", GetProductionEnvGitOps)
+		production.PUT("/environments/:name/gitops", UpdateProductionEnvGitOps)
+		production.GET("/environments/:name/gitops/status", GetProductionEnvGitOpsStatus)
+		production.GET("/environments/:name/drift", GetProductionEnvDrift)
+		production.POST("/environments/:name/drift/detect", DetectProductionEnvDrift)
+		production.POST("/environments/:name/drift/reconcile", ReconcileProductionEnvDrift)
+		production.GET("/environments/:name/snapshots", ListProductionEnvSnapshots)
+		production.POST("/environments/:name/snapshots", CreateProductionEnvSnapshot)
+		production.GET("/environments/:name/snapshots/:id", GetProductionEnvSnapshot)
+		production.GET("/environments/:name/snapshots/:id/diff", DiffProductionEnvSnapshot)
+		production.POST("/environments/:name/snapshots/:id/rollback", RollbackProductionEnvSnapshot)
 	}
 
 	// ---------------------------------------------------------------------------------------
@@ -281,6 +294,17 @@ func (*Router) Inject(router *gin.RouterGroup) {
 		environments.POST("/:name/sleep", EnvSleep)
 		environments.GET("/:name/sleep/cron", GetEnvSleepCron)
 		environments.PUT("/:name/sleep/cron", UpsertEnvSleepCron)
+		environments.GET("/:name/gitops", GetEnvGitOps)
+		environments.PUT("/:name/gitops", UpdateEnvGitOps)
+		environments.GET("/:name/gitops/status", GetEnvGitOpsStatus)
+		environments.GET("/:name/drift", GetEnvDrift)
+		environments.POST("/:name/drift/detect", DetectEnvDrift)
+		environments.POST("/:name/drift/reconcile", ReconcileEnvDrift)
+		environments.GET("/:name/snapshots", ListEnvSnapshots)
+		environments.POST("/:name/snapshots", CreateEnvSnapshot)
+		environments.GET("/:name/snapshots/:id", GetEnvSnapshot)
+		environments.GET("/:name/snapshots/:id/diff", DiffEnvSnapshot)
+		environments.POST("/:name/snapshots/:id/rollback", RollbackEnvSnapshot)
 	}
 
 	// ---------------------------------------------------------------------------------------
@@ -0,0 +1,525 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+	"helm.sh/helm/v3/pkg/releaseutil"
+	versionedclient "istio.io/client-go/pkg/clientset/versioned"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/version"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	configbase "github.com/koderover/zadig/pkg/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	templatemodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models/template"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	templaterepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb/template"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/imnotify"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/kube"
+	commonutil "github.com/koderover/zadig/pkg/microservice/aslan/core/common/util"
+	"github.com/koderover/zadig/pkg/setting"
+	kubeclient "github.com/koderover/zadig/pkg/shared/kube/client"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/tool/kube/drift"
+	"github.com/koderover/zadig/pkg/tool/kube/getter"
+	"github.com/koderover/zadig/pkg/tool/kube/informer"
+	"github.com/koderover/zadig/pkg/tool/kube/serializer"
+	"github.com/koderover/zadig/pkg/util"
+)
+
+const (
+	// maxNotifiedDriftResources is the max number of drifted resources listed in a notification
+	maxNotifiedDriftResources = 10
+	// envDriftDetectConcurrency is the max number of envs detected at the same time by the cron job
+	envDriftDetectConcurrency = 10
+)
+
+type ReconcileEnvDriftArgs struct {
+	// ServiceNames are the services to reconcile, all the drifted services are reconciled if it's empty
+	ServiceNames []string `json:"service_names"`
+}
+
+// DetectEnvDriftCronJob detects the drift of all the k8s yaml envs and notifies when the drift of an env changes, the
+// envs of the projects which disable the drift detection are skipped
+func DetectEnvDriftCronJob(log *zap.SugaredLogger) {
+	log.Info("[DetectEnvDriftCronJob] started ...")
+	defer log.Info("[DetectEnvDriftCronJob] end")
+
+	envs, err := commonrepo.NewProductColl().List(&commonrepo.ProductListOptions{})
+	if err != nil {
+		log.Errorf("[Product.List] error: %v", err)
+		return
+	}
+	projects, err := templaterepo.NewProductColl().List()
+	if err != nil {
+		log.Errorf("[ProductTmpl.List] error: %v", err)
+		return
+	}
+
+	g := new(errgroup.Group)
+	g.SetLimit(envDriftDetectConcurrency)
+	for _, env := range envsToDetectDrift(envs, projects) {
+		env := env
+		g.Go(func() error {
+			if _, err := detectEnvDrift(env, true, log); err != nil {
+				log.Errorf("failed to detect drift of env %s/%s, err: %s", env.ProductName, env.EnvName, err)
+			}
+			return nil
+		})
+	}
+	_ = g.Wait()
+}
+
+// envsToDetectDrift filters the envs whose drift is detected by the cron job
+func envsToDetectDrift(envs []*commonmodels.Product, projects []*templatemodels.Product) []*commonmodels.Product {
+	disabledProjects := sets.NewString()
+	for _, project := range projects {
+		if project.DisableEnvDriftDetection {
+			disabledProjects.Insert(project.ProductName)
+		}
+	}
+
+	ret := make([]*commonmodels.Product, 0)
+	for _, env := range envs {
+		if disabledProjects.Has(env.ProductName) || checkEnvDriftDetectable(env) != nil {
+			continue
+		}
+		ret = append(ret, env)
+	}
+	return ret
+}
+
+func GetEnvDrift(projectName, envName string, production bool, log *zap.SugaredLogger) (*commonmodels.EnvDrift, error) {
+	_, err := commonrepo.NewProductColl().Find(&commonrepo.ProductFindOptions{Name: projectName, EnvName: envName, Production: &production})
+	if err != nil {
+		log.Errorf("failed to find env %s/%s, err: %s", projectName, envName, err)
+		return nil, e.ErrGetEnvDrift.AddErr(err)
+	}
+
+	envDrift, err := commonrepo.NewEnvDriftColl().Find(projectName, envName)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return &commonmodels.EnvDrift{ProjectName: projectName, EnvName: envName, Production: production, Resources: []*commonmodels.DriftResource{}}, nil
+		}
+		log.Errorf("failed to find drift of env %s/%s, err: %s", projectName, envName, err)
+		return nil, e.ErrGetEnvDrift.AddErr(err)
+	}
+	return envDrift, nil
+}
+
+// DetectEnvDrift detects the drift of the env at once, the result is recorded without notification
+func DetectEnvDrift(projectName, envName string, production bool, log *zap.SugaredLogger) (*commonmodels.EnvDrift, error) {
+	env, err := commonrepo.NewProductColl().Find(&commonrepo.ProductFindOptions{Name: projectName, EnvName: envName, Production: &production})
+	if err != nil {
+		log.Errorf("failed to find env %s/%s, err: %s", projectName, envName, err)
+		return nil, e.ErrDetectEnvDrift.AddErr(err)
+	}
+	if err := checkEnvDriftDetectable(env); err != nil {
+		return nil, e.ErrDetectEnvDrift.AddErr(err)
+	}
+
+	envDrift, err := detectEnvDrift(env, false, log)
+	if err != nil {
+		log.Errorf("failed to detect drift of env %s/%s, err: %s", projectName, envName, err)
+		return nil, e.ErrDetectEnvDrift.AddErr(err)
+	}
+	return envDrift, nil
+}
+
+// ReconcileEnvDrift reapplies the manifests last rendered by zadig of the services to the env, the jobs of the services
+// are recreated as they are in deployments
+func ReconcileEnvDrift(projectName, envName string, production bool, args *ReconcileEnvDriftArgs, log *zap.SugaredLogger) error {
+	env, err := commonrepo.NewProductColl().Find(&commonrepo.ProductFindOptions{Name: projectName, EnvName: envName, Production: &production})
+	if err != nil {
+		log.Errorf("failed to find env %s/%s, err: %s", projectName, envName, err)
+		return e.ErrReconcileEnvDrift.AddErr(err)
+	}
+	if err := checkEnvDriftDetectable(env); err != nil {
+		return e.ErrReconcileEnvDrift.AddErr(err)
+	}
+
+	serviceNames := args.ServiceNames
+	if len(serviceNames) == 0 {
+		envDrift, err := commonrepo.NewEnvDriftColl().Find(projectName, envName)
+		if err != nil && err != mongo.ErrNoDocuments {
+			log.Errorf("failed to find drift of env %s/%s, err: %s", projectName, envName, err)
+			return e.ErrReconcileEnvDrift.AddErr(err)
+		}
+		driftedServices := sets.NewString()
+		if envDrift != nil {
+			for _, resource := range envDrift.Resources {
+				driftedServices.Insert(resource.ServiceName)
+			}
+		}
+		serviceNames = driftedServices.List()
+	}
+	if len(serviceNames) == 0 {
+		return nil
+	}
+
+	kubeClient, err := kubeclient.GetKubeClient(config.HubServerAddress(), env.ClusterID)
+	if err != nil {
+		return e.ErrReconcileEnvDrift.AddErr(fmt.Errorf("failed to get kube client, err: %s", err))
+	}
+	clientset, err := kubeclient.GetKubeClientSet(config.HubServerAddress(), env.ClusterID)
+	if err != nil {
+		return e.ErrReconcileEnvDrift.AddErr(fmt.Errorf("failed to get kube clientset, err: %s", err))
+	}
+	inf, err := informer.NewInformer(env.ClusterID, env.Namespace, clientset)
+	if err != nil {
+		return e.ErrReconcileEnvDrift.AddErr(fmt.Errorf("failed to get informer, err: %s", err))
+	}
+	restConfig, err := kubeclient.GetRESTConfig(config.HubServerAddress(), env.ClusterID)
+	if err != nil {
+		return e.ErrReconcileEnvDrift.AddErr(fmt.Errorf("failed to get rest config, err: %s", err))
+	}
+	istioClient, err := versionedclient.NewForConfig(restConfig)
+	if err != nil {
+		return e.ErrReconcileEnvDrift.AddErr(fmt.Errorf("failed to new istio client, err: %s", err))
+	}
+
+	svcMap := env.GetServiceMap()
+	errList := &multierror.Error{}
+	for _, name := range serviceNames {
+		svc, ok := svcMap[name]
+		if !ok || svc.Type != setting.K8SDeployType || !commonutil.ServiceDeployed(name, env.ServiceDeployStrategy) {
+			errList = multierror.Append(errList, fmt.Errorf("service %s is not deployed by zadig in the env", name))
+			continue
+		}
+
+		renderedYaml, _, err := kube.FetchCurrentAppliedYaml(&kube.GeneSvcYamlOption{ProductName: projectName, EnvName: envName, ServiceName: name})
+		if err != nil {
+			errList = multierror.Append(errList, fmt.Errorf("failed to render service %s, err: %s", name, err))
+			continue
+		}
+
+		_, err = kube.CreateOrPatchResource(&kube.ResourceApplyParam{
+			ProductInfo:         env,
+			ServiceName:         name,
+			CurrentResourceYaml: renderedYaml,
+			UpdateResourceYaml:  renderedYaml,
+			Informer:            inf,
+			KubeClient:          kubeClient,
+			IstioClient:         istioClient,
+			InjectSecrets:       true,
+			AddZadigLabel:       true,
+			SharedEnvHandler:    EnsureUpdateZadigService,
+		}, log)
+		if err != nil {
+			errList = multierror.Append(errList, fmt.Errorf("failed to reconcile service %s, err: %s", name, err))
+		}
+	}
+
+	if _, err := detectEnvDrift(env, false, log); err != nil {
+		log.Errorf("failed to detect drift of env %s/%s after reconciled, err: %s", projectName, envName, err)
+	}
+
+	if err := errList.ErrorOrNil(); err != nil {
+		log.Errorf("failed to reconcile drift of env %s/%s, err: %s", projectName, envName, err)
+		return e.ErrReconcileEnvDrift.AddErr(err)
+	}
+	return nil
+}
+
+// checkEnvDriftDetectable returns an error if the resources of the env are not expected to be the rendered manifests
+func checkEnvDriftDetectable(env *commonmodels.Product) error {
+	if env.Source == setting.SourceFromExternal || env.Source == setting.SourceFromPM {
+		return fmt.Errorf("drift detection is not supported by the env of source %s", env.Source)
+	}
+	if env.GitOps.IsEnabled() {
+		return fmt.Errorf("the resources of the gitops env are reconciled by the gitops controller")
+	}
+	switch env.Status {
+	case setting.ProductStatusSleeping:
+		return fmt.Errorf("env is sleeping")
+	case setting.ProductStatusCreating, setting.ProductStatusUpdating, setting.ProductStatusDeleting:
+		return fmt.Errorf("env is %s", env.Status)
+	}
+	return nil
+}
+
+func detectEnvDrift(env *commonmodels.Product, notify bool, log *zap.SugaredLogger) (*commonmodels.EnvDrift, error) {
+	reader, err := kube.GetKubeAPIReader(env.ClusterID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get kube api reader, err: %s", err)
+	}
+	clientset, err := kubeclient.GetKubeClientSet(config.HubServerAddress(), env.ClusterID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get kube clientset, err: %s", err)
+	}
+	versionInfo, err := clientset.Discovery().ServerVersion()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get server version, err: %s", err)
+	}
+
+	// the replicas of the workloads scaled by hpa are not drift
+	hpaTargets := sets.NewString()
+	hpas, err := getter.ListHorizontalPodAutoscalers(env.Namespace, clientset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list hpas, err: %s", err)
+	}
+	for _, hpa := range hpas {
+		hpaTargets.Insert(hpa.Spec.ScaleTargetRef.Kind + "/" + hpa.Spec.ScaleTargetRef.Name)
+	}
+
+	prev, err := commonrepo.NewEnvDriftColl().Find(env.ProductName, env.EnvName)
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("failed to find drift of env, err: %s", err)
+		}
+		prev = nil
+	}
+
+	envDrift := buildEnvDrift(env, prev, time.Now().Unix(), func(serviceName string) ([]*commonmodels.DriftResource, error) {
+		renderedYaml, _, err := kube.FetchCurrentAppliedYaml(&kube.GeneSvcYamlOption{ProductName: env.ProductName, EnvName: env.EnvName, ServiceName: serviceName})
+		if err != nil {
+			return nil, err
+		}
+		return detectServiceDrift(env, serviceName, renderedYaml, reader, versionInfo, hpaTargets)
+	}, log)
+
+	if err := commonrepo.NewEnvDriftColl().Upsert(envDrift); err != nil {
+		return nil, fmt.Errorf("failed to record drift of env, err: %s", err)
+	}
+
+	if notify && len(envDrift.Resources) > 0 && envDriftChanged(prev, envDrift) {
+		util.Go(func() {
+			if err := envDriftNotification(env, envDrift); err != nil {
+				log.Errorf("failed to send env drift notification, err: %s", err)
+			}
+		})
+	}
+	return envDrift, nil
+}
+
+// buildEnvDrift collects the drift of the k8s yaml services deployed by zadig in the env by detectService, the drift
+// of a service detected last time is kept if it fails to be detected, and so is the time a resource started drifting
+func buildEnvDrift(env *commonmodels.Product, prev *commonmodels.EnvDrift, now int64, detectService func(serviceName string) ([]*commonmodels.DriftResource, error), log *zap.SugaredLogger) *commonmodels.EnvDrift {
+	prevResources := make(map[string]*commonmodels.DriftResource)
+	if prev != nil {
+		for _, resource := range prev.Resources {
+			prevResources[driftResourceKey(resource)] = resource
+		}
+	}
+
+	envDrift := &commonmodels.EnvDrift{
+		ProjectName: env.ProductName,
+		EnvName:     env.EnvName,
+		Production:  env.Production,
+		Resources:   make([]*commonmodels.DriftResource, 0),
+		DetectTime:  now,
+	}
+	for _, group := range env.Services {
+		for _, svc := range group {
+			if svc.Type != setting.K8SDeployType || !commonutil.ServiceDeployed(svc.ServiceName, env.ServiceDeployStrategy) {
+				continue
+			}
+
+			resources, err := detectService(svc.ServiceName)
+			if err != nil {
+				// keep the drift detected last time
+				log.Errorf("failed to detect drift of service %s in env %s/%s, err: %s", svc.ServiceName, env.ProductName, env.EnvName, err)
+				if prev != nil {
+					for _, resource := range prev.Resources {
+						if resource.ServiceName == svc.ServiceName {
+							envDrift.Resources = append(envDrift.Resources, resource)
+						}
+					}
+				}
+				continue
+			}
+
+			for _, resource := range resources {
+				resource.DetectTime = now
+				if prevResource, ok := prevResources[driftResourceKey(resource)]; ok {
+					resource.DetectTime = prevResource.DetectTime
+				}
+				envDrift.Resources = append(envDrift.Resources, resource)
+			}
+		}
+	}
+	return envDrift
+}
+
+// detectServiceDrift compares the manifests rendered for the service with the live objects read from the cluster
+func detectServiceDrift(env *commonmodels.Product, serviceName, renderedYaml string, reader client.Reader, versionInfo *version.Info, hpaTargets sets.String) ([]*commonmodels.DriftResource, error) {
+	var ret []*commonmodels.DriftResource
+	for _, manifest := range releaseutil.SplitManifests(renderedYaml) {
+		u, err := serializer.NewDecoder().YamlToUnstructured([]byte(manifest))
+		if err != nil {
+			return nil, err
+		}
+		// jobs are recreated by every deployment and may be cleaned up after they are finished
+		if u.GetKind() == setting.Job {
+			continue
+		}
+
+		live := &unstructured.Unstructured{}
+		live.SetGroupVersionKind(kube.GetValidGVK(u.GroupVersionKind(), versionInfo))
+		found, err := getter.GetResourceInCache(env.Namespace, u.GetName(), live, reader)
+		if err != nil {
+			return nil, err
+		}
+
+		resource := &commonmodels.DriftResource{
+			ServiceName: serviceName,
+			Kind:        u.GetKind(),
+			Name:        u.GetName(),
+		}
+		if !found {
+			resource.Missing = true
+			ret = append(ret, resource)
+			continue
+		}
+
+		resource.Fields = drift.Compare(u.Object, live.Object, driftIgnorePaths(u.GetKind(), hpaTargets.Has(u.GetKind()+"/"+u.GetName()))...)
+		if len(resource.Fields) > 0 {
+			ret = append(ret, resource)
+		}
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		return driftResourceKey(ret[i]) < driftResourceKey(ret[j])
+	})
+	return ret, nil
+}
+
+// driftIgnorePaths returns the fields of the resource changed by zadig when it's applied, or by the cluster
+func driftIgnorePaths(kind string, scaledByHPA bool) []string {
+	podSpecPath := "spec.template.spec"
+	if kind == setting.CronJob {
+		podSpecPath = "spec.jobTemplate.spec.template.spec"
+	}
+
+	// the api version of the live object is the one it's read with
+	paths := []string{"apiVersion", fmt.Sprintf("%s.imagePullSecrets[%s]", podSpecPath, setting.DefaultImagePullSecret)}
+	if scaledByHPA {
+		paths = append(paths, "spec.replicas")
+	}
+	return paths
+}
+
+func driftResourceKey(resource *commonmodels.DriftResource) string {
+	return fmt.Sprintf("%s/%s/%s", resource.ServiceName, resource.Kind, resource.Name)
+}
+
+// envDriftChanged returns true if any resource drifts differently from the last detection
+func envDriftChanged(prev, cur *commonmodels.EnvDrift) bool {
+	if prev == nil {
+		return true
+	}
+	signature := func(envDrift *commonmodels.EnvDrift) []string {
+		ret := make([]string, 0, len(envDrift.Resources))
+		for _, resource := range envDrift.Resources {
+			ret = append(ret, fmt.Sprintf("%s:%t:%s", driftResourceKey(resource), resource.Missing, strings.Join(resource.Fields, ",")))
+		}
+		sort.Strings(ret)
+		return ret
+	}
+	return strings.Join(signature(prev), ";") != strings.Join(signature(cur), ";")
+}
+
+func envDriftNotification(env *commonmodels.Product, envDrift *commonmodels.EnvDrift) error {
+	for _, notifyConfig := range env.NotificationConfigs {
+		eventSet := sets.NewString()
+		for _, event := range notifyConfig.Events {
+			eventSet.Insert(string(event))
+		}
+		if !eventSet.Has(string(commonmodels.NotificationEventEnvDrift)) {
+			continue
+		}
+
+		webHookType := imnotify.IMNotifyType(notifyConfig.WebHookType)
+		title, content, larkCard := getEnvDriftNotificationContent(env, envDrift, webHookType)
+
+		imnotifyClient := imnotify.NewIMNotifyClient()
+		switch webHookType {
+		case imnotify.IMNotifyTypeDingDing:
+			if err := imnotifyClient.SendDingDingMessage(notifyConfig.WebHookURL, title, content, nil, false); err != nil {
+				return err
+			}
+		case imnotify.IMNotifyTypeLark:
+			if err := imnotifyClient.SendFeishuMessage(notifyConfig.WebHookURL, larkCard); err != nil {
+				return err
+			}
+		case imnotify.IMNotifyTypeWeChat:
+			if err := imnotifyClient.SendWeChatWorkMessage(imnotify.WeChatTextTypeMarkdown, notifyConfig.WebHookURL, content); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func getEnvDriftNotificationContent(env *commonmodels.Product, envDrift *commonmodels.EnvDrift, webHookType imnotify.IMNotifyType) (string, string, *imnotify.LarkCard) {
+	title := fmt.Sprintf("⚠️ %s / %s 环境配置漂移", env.ProductName, env.EnvName)
+	switch webHookType {
+	case imnotify.IMNotifyTypeWeChat:
+		title = fmt.Sprintf("### ⚠️<font color=\"warning\">%s/%s 环境配置漂移</font>\n", env.ProductName, env.EnvName)
+	case imnotify.IMNotifyTypeDingDing:
+		title = fmt.Sprintf("### %s\n", title)
+	}
+
+	detectTime := fmt.Sprintf("**检测时间：%s** \n", time.Unix(envDrift.DetectTime, 0).Format("2006-01-02 15:04:05"))
+	if webHookType == imnotify.IMNotifyTypeDingDing {
+		detectTime = "##### " + detectTime
+	}
+
+	resources := make([]string, 0, len(envDrift.Resources))
+	for i, resource := range envDrift.Resources {
+		if i == maxNotifiedDriftResources {
+			resources = append(resources, fmt.Sprintf("- 等 %d 个资源", len(envDrift.Resources)))
+			break
+		}
+		if resource.Missing {
+			resources = append(resources, fmt.Sprintf("- %s %s/%s：已被删除", resource.ServiceName, resource.Kind, resource.Name))
+			continue
+		}
+		resources = append(resources, fmt.Sprintf("- %s %s/%s：%s", resource.ServiceName, resource.Kind, resource.Name, strings.Join(resource.Fields, ", ")))
+	}
+	resourceContent := strings.Join(resources, "\n") + " \n"
+
+	buttonContent := "点击查看更多信息"
+	envDetailURL := fmt.Sprintf("%s/v1/projects/detail/%s/envs/detail?envName=%s", configbase.SystemAddress(), env.ProductName, env.EnvName)
+
+	if webHookType == imnotify.IMNotifyTypeLark {
+		lc := imnotify.NewLarkCard()
+		lc.SetConfig(true)
+		lc.SetHeader(imnotify.GetColorTemplateWithStatus(config.StatusFailed), title, "plain_text")
+		lc.AddI18NElementsZhcnFeild(detectTime, true)
+		lc.AddI18NElementsZhcnFeild(resourceContent, false)
+		lc.AddI18NElementsZhcnAction(buttonContent, envDetailURL)
+		return "", "", lc
+	}
+
+	moreInformation := fmt.Sprintf("\n\n[%s](%s)", buttonContent, envDetailURL)
+	if webHookType == imnotify.IMNotifyTypeDingDing {
+		moreInformation = fmt.Sprintf("\n\n---\n\n[%s](%s)", buttonContent, envDetailURL)
+	}
+	return title, title + detectTime + resourceContent + moreInformation, nil
+}
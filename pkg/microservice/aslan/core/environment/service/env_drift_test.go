@@ -0,0 +1,181 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/version"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	templatemodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models/template"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/log"
+)
+
+const testDriftServiceYaml = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  labels:
+    app: app
+spec:
+  replicas: 2
+  template:
+    spec:
+      containers:
+      - name: app
+        image: app:v1
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: app
+spec:
+  ports:
+  - name: http
+    port: 80
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app-config
+data:
+  mode: dev
+---
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: app-migration
+`
+
+var _ = Describe("Testing env drift", func() {
+	env := &commonmodels.Product{ProductName: "project", EnvName: "dev", Namespace: "project-env-dev"}
+	versionInfo := &version.Info{GitVersion: "v1.25.0"}
+
+	Describe("test detecting the drift of a service", func() {
+		replicas := int32(3)
+		reader := fake.NewClientBuilder().WithObjects(
+			&appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: env.Namespace, Labels: map[string]string{"app": "app", "s-product": "project"}},
+				Spec: appsv1.DeploymentSpec{
+					Replicas: &replicas,
+					Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+						Containers:       []corev1.Container{{Name: "app", Image: "app:v2"}},
+						ImagePullSecrets: []corev1.LocalObjectReference{{Name: setting.DefaultImagePullSecret}},
+					}},
+				},
+			},
+			&corev1.Service{
+				ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: env.Namespace},
+				Spec: corev1.ServiceSpec{
+					Ports:     []corev1.ServicePort{{Name: "http", Port: 80, Protocol: corev1.ProtocolTCP}},
+					ClusterIP: "10.0.0.1",
+				},
+			},
+		).Build()
+
+		It("should report the changed fields and the missing resources", func() {
+			resources, err := detectServiceDrift(env, "app", testDriftServiceYaml, reader, versionInfo, sets.NewString())
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(resources).To(Equal([]*commonmodels.DriftResource{
+				{ServiceName: "app", Kind: setting.ConfigMap, Name: "app-config", Missing: true},
+				{ServiceName: "app", Kind: setting.Deployment, Name: "app", Fields: []string{"spec.replicas", "spec.template.spec.containers[app].image"}},
+			}))
+		})
+
+		It("should ignore the replicas of the workloads scaled by hpa", func() {
+			resources, err := detectServiceDrift(env, "app", testDriftServiceYaml, reader, versionInfo, sets.NewString(setting.Deployment+"/app"))
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(resources).To(HaveLen(2))
+			Expect(resources[1].Fields).To(Equal([]string{"spec.template.spec.containers[app].image"}))
+		})
+
+		It("should fail if the rendered manifests can't be decoded", func() {
+			_, err := detectServiceDrift(env, "app", "kind: [", reader, versionInfo, sets.NewString())
+			Expect(err).Should(HaveOccurred())
+		})
+	})
+
+	Describe("test collecting the drift of an env", func() {
+		env := &commonmodels.Product{
+			ProductName: "project",
+			EnvName:     "dev",
+			Services: [][]*commonmodels.ProductService{{
+				{ServiceName: "a", Type: setting.K8SDeployType},
+				{ServiceName: "b", Type: setting.K8SDeployType},
+				{ServiceName: "imported", Type: setting.K8SDeployType},
+				{ServiceName: "chart", Type: setting.HelmDeployType},
+			}},
+			ServiceDeployStrategy: map[string]string{"imported": setting.ServiceDeployStrategyImport},
+		}
+		prev := &commonmodels.EnvDrift{Resources: []*commonmodels.DriftResource{
+			{ServiceName: "a", Kind: setting.Deployment, Name: "a", Fields: []string{"spec.replicas"}, DetectTime: 100},
+			{ServiceName: "b", Kind: setting.ConfigMap, Name: "b", Missing: true, DetectTime: 100},
+		}}
+
+		It("should keep the time the resources started drifting and the drift of the services failed to detect", func() {
+			detected := []string{}
+			envDrift := buildEnvDrift(env, prev, 200, func(serviceName string) ([]*commonmodels.DriftResource, error) {
+				detected = append(detected, serviceName)
+				if serviceName == "b" {
+					return nil, errors.New("cluster unreachable")
+				}
+				return []*commonmodels.DriftResource{
+					{ServiceName: "a", Kind: setting.Deployment, Name: "a", Fields: []string{"spec.template.spec.containers[a].image"}},
+					{ServiceName: "a", Kind: setting.Service, Name: "a", Missing: true},
+				}, nil
+			}, log.SugaredLogger())
+
+			Expect(detected).To(Equal([]string{"a", "b"}))
+			Expect(envDrift.ProjectName).To(Equal("project"))
+			Expect(envDrift.DetectTime).To(Equal(int64(200)))
+			Expect(envDrift.Resources).To(Equal([]*commonmodels.DriftResource{
+				{ServiceName: "a", Kind: setting.Deployment, Name: "a", Fields: []string{"spec.template.spec.containers[a].image"}, DetectTime: 100},
+				{ServiceName: "a", Kind: setting.Service, Name: "a", Missing: true, DetectTime: 200},
+				prev.Resources[1],
+			}))
+			Expect(envDriftChanged(prev, envDrift)).To(BeTrue())
+			Expect(envDriftChanged(envDrift, envDrift)).To(BeFalse())
+		})
+	})
+
+	Describe("test the envs detected by the cron job", func() {
+
+		It("should skip the envs of the projects which disable the detection and the undetectable envs", func() {
+			envs := []*commonmodels.Product{
+				{ProductName: "project", EnvName: "dev"},
+				{ProductName: "project", EnvName: "sleeping", Status: setting.ProductStatusSleeping},
+				{ProductName: "project", EnvName: "external", Source: setting.SourceFromExternal},
+				{ProductName: "disabled", EnvName: "dev"},
+			}
+			projects := []*templatemodels.Product{
+				{ProductName: "project"},
+				{ProductName: "disabled", DisableEnvDriftDetection: true},
+			}
+			Expect(envsToDetectDrift(envs, projects)).To(Equal([]*commonmodels.Product{envs[0]}))
+		})
+	})
+})
@@ -0,0 +1,690 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"go.uber.org/zap"
+	versionedclient "istio.io/client-go/pkg/clientset/versioned"
+	"k8s.io/client-go/informers"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/kube"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/notify"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/render"
+	commontypes "github.com/koderover/zadig/pkg/microservice/aslan/core/common/types"
+	commonutil "github.com/koderover/zadig/pkg/microservice/aslan/core/common/util"
+	"github.com/koderover/zadig/pkg/setting"
+	kubeclient "github.com/koderover/zadig/pkg/shared/kube/client"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	helmtool "github.com/koderover/zadig/pkg/tool/helmclient"
+	"github.com/koderover/zadig/pkg/tool/kube/informer"
+)
+
+// actions of the services and env resources when the env is rolled back to a snapshot
+const (
+	SnapshotDiffAdded     = "added"
+	SnapshotDiffDeleted   = "deleted"
+	SnapshotDiffChanged   = "changed"
+	SnapshotDiffUnchanged = "unchanged"
+)
+
+type CreateEnvSnapshotArgs struct {
+	Description string `json:"description"`
+}
+
+// EnvSnapshotDiff is the changes of the env if it's rolled back to the snapshot
+type EnvSnapshotDiff struct {
+	Services []*SnapshotServiceDiff `json:"services"`
+	// GlobalVariables are used by the k8s yaml envs and DefaultValues are used by the helm envs
+	CurrentGlobalVariables  []*commontypes.GlobalVariableKV `json:"current_global_variables"`
+	SnapshotGlobalVariables []*commontypes.GlobalVariableKV `json:"snapshot_global_variables"`
+	CurrentDefaultValues    string                          `json:"current_default_values"`
+	SnapshotDefaultValues   string                          `json:"snapshot_default_values"`
+	EnvResources            []*SnapshotEnvResourceDiff      `json:"env_resources"`
+}
+
+type SnapshotServiceDiff struct {
+	ServiceName      string   `json:"service_name"`
+	ReleaseName      string   `json:"release_name"`
+	Type             string   `json:"type"`
+	Action           string   `json:"action"`
+	CurrentRevision  int64    `json:"current_revision"`
+	SnapshotRevision int64    `json:"snapshot_revision"`
+	CurrentImages    []string `json:"current_images"`
+	SnapshotImages   []string `json:"snapshot_images"`
+	// Current and Snapshot are the rendered yaml of the k8s service or the values of the helm service
+	Current  string `json:"current"`
+	Snapshot string `json:"snapshot"`
+}
+
+type SnapshotEnvResourceDiff struct {
+	Type     string `json:"type"`
+	Name     string `json:"name"`
+	Action   string `json:"action"`
+	Current  string `json:"current"`
+	Snapshot string `json:"snapshot"`
+}
+
+func ListEnvSnapshots(projectName, envName string, production bool, pageNum, pageSize int64, log *zap.SugaredLogger) ([]*commonmodels.EnvSnapshot, int64, error) {
+	if _, err := commonrepo.NewProductColl().Find(&commonrepo.ProductFindOptions{Name: projectName, EnvName: envName, Production: &production}); err != nil {
+		log.Errorf("failed to find env %s/%s, err: %s", projectName, envName, err)
+		return nil, 0, e.ErrListEnvSnapshots.AddErr(err)
+	}
+
+	snapshots, count, err := commonrepo.NewEnvSnapshotColl().List(&commonrepo.EnvSnapshotListOption{
+		ProjectName: projectName,
+		EnvName:     envName,
+		PageNum:     pageNum,
+		PageSize:    pageSize,
+	})
+	if err != nil {
+		log.Errorf("failed to list snapshots of env %s/%s, err: %s", projectName, envName, err)
+		return nil, 0, e.ErrListEnvSnapshots.AddErr(err)
+	}
+	return snapshots, count, nil
+}
+
+func CreateEnvSnapshot(projectName, envName string, production bool, args *CreateEnvSnapshotArgs, userName string, log *zap.SugaredLogger) (*commonmodels.EnvSnapshot, error) {
+	env, err := commonrepo.NewProductColl().Find(&commonrepo.ProductFindOptions{Name: projectName, EnvName: envName, Production: &production})
+	if err != nil {
+		log.Errorf("failed to find env %s/%s, err: %s", projectName, envName, err)
+		return nil, e.ErrCreateEnvSnapshot.AddErr(err)
+	}
+	if err := checkEnvSnapshotSupported(env); err != nil {
+		return nil, e.ErrCreateEnvSnapshot.AddErr(err)
+	}
+
+	snapshot, err := kube.CreateEnvSnapshot(env, &kube.EnvSnapshotOption{
+		Type:        config.EnvSnapshotTypeManual,
+		Description: args.Description,
+		CreatedBy:   userName,
+	})
+	if err != nil {
+		log.Errorf("failed to take snapshot of env %s/%s, err: %s", projectName, envName, err)
+		return nil, e.ErrCreateEnvSnapshot.AddErr(err)
+	}
+	return snapshot, nil
+}
+
+func GetEnvSnapshot(projectName, envName string, production bool, snapshotID string, log *zap.SugaredLogger) (*commonmodels.EnvSnapshot, error) {
+	snapshot, err := findEnvSnapshot(projectName, envName, production, snapshotID)
+	if err != nil {
+		log.Errorf("failed to find snapshot %s of env %s/%s, err: %s", snapshotID, projectName, envName, err)
+		return nil, e.ErrGetEnvSnapshot.AddErr(err)
+	}
+	return snapshot, nil
+}
+
+// DiffEnvSnapshot previews the changes of the env if it's rolled back to the snapshot
+func DiffEnvSnapshot(projectName, envName string, production bool, snapshotID string, log *zap.SugaredLogger) (*EnvSnapshotDiff, error) {
+	snapshot, err := findEnvSnapshot(projectName, envName, production, snapshotID)
+	if err != nil {
+		log.Errorf("failed to find snapshot %s of env %s/%s, err: %s", snapshotID, projectName, envName, err)
+		return nil, e.ErrDiffEnvSnapshot.AddErr(err)
+	}
+	env, err := commonrepo.NewProductColl().Find(&commonrepo.ProductFindOptions{Name: projectName, EnvName: envName, Production: &production})
+	if err != nil {
+		return nil, e.ErrDiffEnvSnapshot.AddErr(err)
+	}
+	current, err := kube.BuildEnvSnapshot(env)
+	if err != nil {
+		log.Errorf("failed to build snapshot of env %s/%s, err: %s", projectName, envName, err)
+		return nil, e.ErrDiffEnvSnapshot.AddErr(err)
+	}
+
+	resp := &EnvSnapshotDiff{
+		Services:     diffSnapshotServices(current, snapshot),
+		EnvResources: make([]*SnapshotEnvResourceDiff, 0),
+	}
+	if current.RenderSet != nil {
+		resp.CurrentGlobalVariables = current.RenderSet.GlobalVariables
+		resp.CurrentDefaultValues = current.RenderSet.DefaultValues
+	}
+	if snapshot.RenderSet != nil {
+		resp.SnapshotGlobalVariables = snapshot.RenderSet.GlobalVariables
+		resp.SnapshotDefaultValues = snapshot.RenderSet.DefaultValues
+	}
+
+	resp.EnvResources, err = diffSnapshotEnvResources(current, snapshot)
+	if err != nil {
+		log.Errorf("failed to diff resources of env %s/%s, err: %s", projectName, envName, err)
+		return nil, e.ErrDiffEnvSnapshot.AddErr(err)
+	}
+	return resp, nil
+}
+
+// RollbackEnvSnapshot restores the services, variables and resources of the env to the snapshot. The env is snapshotted
+// before it's rolled back, and it's restored to that snapshot if any part of the rollback fails.
+func RollbackEnvSnapshot(projectName, envName string, production bool, snapshotID, userName, requestID string, log *zap.SugaredLogger) error {
+	env, err := commonrepo.NewProductColl().Find(&commonrepo.ProductFindOptions{Name: projectName, EnvName: envName, Production: &production})
+	if err != nil {
+		log.Errorf("failed to find env %s/%s, err: %s", projectName, envName, err)
+		return e.ErrRollbackEnvSnapshot.AddErr(err)
+	}
+	if err := checkEnvSnapshotSupported(env); err != nil {
+		return e.ErrRollbackEnvSnapshot.AddErr(err)
+	}
+	switch env.Status {
+	case setting.ProductStatusCreating, setting.ProductStatusUpdating, setting.ProductStatusDeleting:
+		return e.ErrRollbackEnvSnapshot.AddDesc(e.EnvCantUpdatedMsg)
+	case setting.ProductStatusSleeping:
+		return e.ErrRollbackEnvSnapshot.AddDesc("env is sleeping")
+	}
+
+	snapshot, err := findEnvSnapshot(projectName, envName, production, snapshotID)
+	if err != nil {
+		log.Errorf("failed to find snapshot %s of env %s/%s, err: %s", snapshotID, projectName, envName, err)
+		return e.ErrRollbackEnvSnapshot.AddErr(err)
+	}
+
+	current, err := kube.CreateEnvSnapshot(env, &kube.EnvSnapshotOption{
+		Type:        config.EnvSnapshotTypeRollback,
+		Description: fmt.Sprintf("rolled back to %s", time.Unix(snapshot.CreateTime, 0).Format("2006-01-02 15:04:05")),
+		CreatedBy:   userName,
+	})
+	if err != nil {
+		log.Errorf("failed to take snapshot of env %s/%s, err: %s", projectName, envName, err)
+		return e.ErrRollbackEnvSnapshot.AddErr(err)
+	}
+
+	if err := commonrepo.NewProductColl().UpdateStatus(envName, projectName, setting.ProductStatusUpdating); err != nil {
+		log.Errorf("[%s][P:%s] Product.UpdateStatus error: %v", envName, projectName, err)
+		return e.ErrRollbackEnvSnapshot.AddDesc(e.UpdateEnvStatusErrMsg)
+	}
+
+	go func() {
+		status, errMsg := setting.ProductStatusSuccess, ""
+		restorer, err := newSnapshotRestorer(env, userName, log)
+		if err == nil {
+			err = rollbackEnv(restorer, env, current, snapshot)
+		}
+		if err != nil {
+			log.Errorf("failed to roll back env %s/%s to snapshot %s, err: %s", projectName, envName, snapshotID, err)
+			status, errMsg = setting.ProductStatusFailed, err.Error()
+			title := fmt.Sprintf("回滚 [%s] 的 [%s] 环境失败", projectName, envName)
+			notify.SendErrorMessage(userName, title, requestID, err, log)
+		}
+		if err := commonrepo.NewProductColl().UpdateStatusAndError(envName, projectName, status, errMsg); err != nil {
+			log.Errorf("[%s][%s] Product.Update set product status error: %v", envName, projectName, err)
+		}
+	}()
+	return nil
+}
+
+// rollbackEnv restores the env from the current snapshot to the target one, the env is restored to the current snapshot
+// if the rollback fails
+func rollbackEnv(restorer snapshotRestorer, env *commonmodels.Product, current, snapshot *commonmodels.EnvSnapshot) error {
+	err := restoreEnvSnapshot(restorer, env, current, snapshot)
+	if err == nil {
+		return nil
+	}
+	// any service of the snapshot may have been applied by the failed rollback, so the env is restored from it
+	if restoreErr := restoreEnvSnapshot(restorer, env, snapshot, current); restoreErr != nil {
+		return fmt.Errorf("%s, failed to restore the env to snapshot %s: %s", err, current.ID.Hex(), restoreErr)
+	}
+	return err
+}
+
+func checkEnvSnapshotSupported(env *commonmodels.Product) error {
+	if env.Source == setting.SourceFromExternal || env.Source == setting.SourceFromPM {
+		return fmt.Errorf("snapshot is not supported by the env of source %s", env.Source)
+	}
+	return nil
+}
+
+func findEnvSnapshot(projectName, envName string, production bool, snapshotID string) (*commonmodels.EnvSnapshot, error) {
+	snapshot, err := commonrepo.NewEnvSnapshotColl().FindByID(snapshotID)
+	if err != nil {
+		return nil, err
+	}
+	if snapshot.ProjectName != projectName || snapshot.EnvName != envName || snapshot.Production != production {
+		return nil, fmt.Errorf("snapshot %s is not taken from env %s/%s", snapshotID, projectName, envName)
+	}
+	return snapshot, nil
+}
+
+func snapshotServiceKey(manifest *commonmodels.SnapshotServiceManifest) string {
+	if manifest.Type == setting.HelmChartDeployType {
+		return manifest.ReleaseName
+	}
+	return manifest.ServiceName
+}
+
+func snapshotProductServiceMap(snapshot *commonmodels.EnvSnapshot) map[string]*commonmodels.ProductService {
+	ret := make(map[string]*commonmodels.ProductService)
+	for _, group := range snapshot.Services {
+		for _, svc := range group {
+			if svc.FromZadig() {
+				ret[svc.ServiceName] = svc
+			} else {
+				ret[svc.ReleaseName] = svc
+			}
+		}
+	}
+	return ret
+}
+
+func snapshotManifestMap(snapshot *commonmodels.EnvSnapshot) map[string]*commonmodels.SnapshotServiceManifest {
+	ret := make(map[string]*commonmodels.SnapshotServiceManifest)
+	for _, manifest := range snapshot.ServiceManifests {
+		ret[snapshotServiceKey(manifest)] = manifest
+	}
+	return ret
+}
+
+func diffSnapshotServices(current, snapshot *commonmodels.EnvSnapshot) []*SnapshotServiceDiff {
+	currentSvcs, snapshotSvcs := snapshotProductServiceMap(current), snapshotProductServiceMap(snapshot)
+	currentManifests := snapshotManifestMap(current)
+
+	images := func(svc *commonmodels.ProductService) []string {
+		ret := make([]string, 0)
+		if svc != nil {
+			for _, container := range svc.Containers {
+				ret = append(ret, container.Image)
+			}
+		}
+		return ret
+	}
+
+	ret := make([]*SnapshotServiceDiff, 0)
+	visited := make(map[string]bool)
+	for _, manifest := range snapshot.ServiceManifests {
+		key := snapshotServiceKey(manifest)
+		visited[key] = true
+		diff := &SnapshotServiceDiff{
+			ServiceName:    manifest.ServiceName,
+			ReleaseName:    manifest.ReleaseName,
+			Type:           manifest.Type,
+			Action:         SnapshotDiffAdded,
+			SnapshotImages: images(snapshotSvcs[key]),
+			CurrentImages:  images(currentSvcs[key]),
+			Snapshot:       manifest.Yaml,
+		}
+		if svc := snapshotSvcs[key]; svc != nil {
+			diff.SnapshotRevision = svc.Revision
+		}
+		if currentManifest, ok := currentManifests[key]; ok {
+			diff.Current = currentManifest.Yaml
+			diff.CurrentRevision = currentSvcs[key].Revision
+			diff.Action = SnapshotDiffUnchanged
+			if diff.Current != diff.Snapshot || diff.CurrentRevision != diff.SnapshotRevision {
+				diff.Action = SnapshotDiffChanged
+			}
+		}
+		ret = append(ret, diff)
+	}
+
+	for _, manifest := range current.ServiceManifests {
+		key := snapshotServiceKey(manifest)
+		if visited[key] {
+			continue
+		}
+		ret = append(ret, &SnapshotServiceDiff{
+			ServiceName:     manifest.ServiceName,
+			ReleaseName:     manifest.ReleaseName,
+			Type:            manifest.Type,
+			Action:          SnapshotDiffDeleted,
+			CurrentRevision: currentSvcs[key].Revision,
+			CurrentImages:   images(currentSvcs[key]),
+			SnapshotImages:  []string{},
+			Current:         manifest.Yaml,
+		})
+	}
+	return ret
+}
+
+func snapshotEnvResourceMap(resources []*commonmodels.SnapshotEnvResource) map[string]*commonmodels.SnapshotEnvResource {
+	ret := make(map[string]*commonmodels.SnapshotEnvResource)
+	for _, resource := range resources {
+		ret[resource.Type+"/"+resource.Name] = resource
+	}
+	return ret
+}
+
+func findEnvResourceYaml(resource *commonmodels.SnapshotEnvResource) (string, error) {
+	envResource, err := commonrepo.NewEnvResourceColl().Find(&commonrepo.QueryEnvResourceOption{Id: resource.ResourceID})
+	if err != nil {
+		return "", fmt.Errorf("failed to find %s %s, err: %s", resource.Type, resource.Name, err)
+	}
+	return envResource.YamlData, nil
+}
+
+func diffSnapshotEnvResources(current, snapshot *commonmodels.EnvSnapshot) ([]*SnapshotEnvResourceDiff, error) {
+	currentResources := snapshotEnvResourceMap(current.EnvResources)
+
+	ret := make([]*SnapshotEnvResourceDiff, 0)
+	visited := make(map[string]bool)
+	for _, resource := range snapshot.EnvResources {
+		key := resource.Type + "/" + resource.Name
+		visited[key] = true
+		snapshotYaml, err := findEnvResourceYaml(resource)
+		if err != nil {
+			return nil, err
+		}
+		diff := &SnapshotEnvResourceDiff{
+			Type:     resource.Type,
+			Name:     resource.Name,
+			Action:   SnapshotDiffAdded,
+			Snapshot: snapshotYaml,
+		}
+		if currentResource, ok := currentResources[key]; ok {
+			diff.Action = SnapshotDiffUnchanged
+			diff.Current = snapshotYaml
+			if currentResource.ResourceID != resource.ResourceID {
+				if diff.Current, err = findEnvResourceYaml(currentResource); err != nil {
+					return nil, err
+				}
+				if diff.Current != diff.Snapshot {
+					diff.Action = SnapshotDiffChanged
+				}
+			}
+		}
+		ret = append(ret, diff)
+	}
+
+	for _, resource := range current.EnvResources {
+		if visited[resource.Type+"/"+resource.Name] {
+			continue
+		}
+		currentYaml, err := findEnvResourceYaml(resource)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, &SnapshotEnvResourceDiff{
+			Type:    resource.Type,
+			Name:    resource.Name,
+			Action:  SnapshotDiffDeleted,
+			Current: currentYaml,
+		})
+	}
+	return ret, nil
+}
+
+// snapshotRestorer applies the services and resources recorded in a snapshot to the env
+type snapshotRestorer interface {
+	// RemoveService uninstalls the service which is not in the target snapshot
+	RemoveService(manifest *commonmodels.SnapshotServiceManifest, svc *commonmodels.ProductService) error
+	// ApplyService applies the manifest or the values of the service in the target snapshot to the restored env,
+	// currentYaml is the manifest of the service applied now
+	ApplyService(restored *commonmodels.Product, renderSet *commonmodels.RenderSet, svc *commonmodels.ProductService, manifest *commonmodels.SnapshotServiceManifest, currentYaml string) error
+	// RestoreEnvResources restores the configmaps, secrets, ingresses and pvcs of the env to the versions in the snapshot
+	RestoreEnvResources(target []*commonmodels.SnapshotEnvResource) error
+	// SaveEnv saves the services and the render set of the target snapshot to the env
+	SaveEnv(restored *commonmodels.Product, renderSet *commonmodels.RenderSet) error
+}
+
+// newSnapshotRestorer creates the restorer of the env, it's replaced in tests
+var newSnapshotRestorer = newClusterSnapshotRestorer
+
+// restoreEnvSnapshot moves the env from the state recorded in `from` to the state recorded in `to`: the services which
+// are only in `from` are removed, the manifests and values of `to` are applied and the resources of the env are diffed
+// against `to`. The render set and services of `to` are saved to the env only after all of them are restored.
+func restoreEnvSnapshot(restorer snapshotRestorer, env *commonmodels.Product, from, to *commonmodels.EnvSnapshot) error {
+	restored := *env
+	restored.Services = to.Services
+	restored.ServiceDeployStrategy = to.ServiceDeployStrategy
+
+	fromSvcs, toSvcs := snapshotProductServiceMap(from), snapshotProductServiceMap(to)
+	fromManifests, toManifests := snapshotManifestMap(from), snapshotManifestMap(to)
+	errList := &multierror.Error{}
+
+	// remove the services which are not in the target snapshot
+	for key, manifest := range fromManifests {
+		if _, ok := toManifests[key]; ok {
+			continue
+		}
+		if err := restorer.RemoveService(manifest, fromSvcs[key]); err != nil {
+			errList = multierror.Append(errList, fmt.Errorf("failed to remove service %s, err: %s", key, err))
+		}
+	}
+
+	for key, manifest := range toManifests {
+		svc := toSvcs[key]
+		if svc == nil {
+			continue
+		}
+		currentYaml := ""
+		if fromManifest, ok := fromManifests[key]; ok {
+			currentYaml = fromManifest.Yaml
+		}
+		if err := restorer.ApplyService(&restored, to.RenderSet, svc, manifest, currentYaml); err != nil {
+			errList = multierror.Append(errList, fmt.Errorf("failed to restore service %s, err: %s", key, err))
+		}
+	}
+	if err := errList.ErrorOrNil(); err != nil {
+		return err
+	}
+
+	if err := restorer.RestoreEnvResources(to.EnvResources); err != nil {
+		return err
+	}
+	return restorer.SaveEnv(&restored, to.RenderSet)
+}
+
+// clusterSnapshotRestorer restores the env in its cluster
+type clusterSnapshotRestorer struct {
+	env         *commonmodels.Product
+	userName    string
+	log         *zap.SugaredLogger
+	kubeClient  client.Client
+	informer    informers.SharedInformerFactory
+	istioClient versionedclient.Interface
+	helmClient  *helmtool.HelmClient
+}
+
+func newClusterSnapshotRestorer(env *commonmodels.Product, userName string, log *zap.SugaredLogger) (snapshotRestorer, error) {
+	kubeClient, err := kubeclient.GetKubeClient(config.HubServerAddress(), env.ClusterID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get kube client, err: %s", err)
+	}
+	clientset, err := kubeclient.GetKubeClientSet(config.HubServerAddress(), env.ClusterID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get kube clientset, err: %s", err)
+	}
+	inf, err := informer.NewInformer(env.ClusterID, env.Namespace, clientset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get informer, err: %s", err)
+	}
+	restConfig, err := kubeclient.GetRESTConfig(config.HubServerAddress(), env.ClusterID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rest config, err: %s", err)
+	}
+	istioClient, err := versionedclient.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to new istio client, err: %s", err)
+	}
+	return &clusterSnapshotRestorer{
+		env:         env,
+		userName:    userName,
+		log:         log,
+		kubeClient:  kubeClient,
+		informer:    inf,
+		istioClient: istioClient,
+	}, nil
+}
+
+func (r *clusterSnapshotRestorer) getHelmClient() (*helmtool.HelmClient, error) {
+	if r.helmClient == nil {
+		helmClient, err := helmtool.NewClientFromNamespace(r.env.ClusterID, r.env.Namespace)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create helm client, err: %s", err)
+		}
+		r.helmClient = helmClient
+	}
+	return r.helmClient, nil
+}
+
+func (r *clusterSnapshotRestorer) RemoveService(manifest *commonmodels.SnapshotServiceManifest, svc *commonmodels.ProductService) error {
+	switch manifest.Type {
+	case setting.K8SDeployType:
+		_, err := kube.CreateOrPatchResource(&kube.ResourceApplyParam{
+			ProductInfo:         r.env,
+			ServiceName:         manifest.ServiceName,
+			CurrentResourceYaml: manifest.Yaml,
+			KubeClient:          r.kubeClient,
+			Uninstall:           true,
+		}, r.log)
+		return err
+	case setting.HelmDeployType:
+		helmClient, err := r.getHelmClient()
+		if err != nil {
+			return err
+		}
+		return kube.UninstallServiceByName(helmClient, manifest.ServiceName, r.env, svc.Revision, true)
+	case setting.HelmChartDeployType:
+		helmClient, err := r.getHelmClient()
+		if err != nil {
+			return err
+		}
+		return kube.UninstallRelease(helmClient, r.env, manifest.ReleaseName, true)
+	}
+	return nil
+}
+
+func (r *clusterSnapshotRestorer) ApplyService(restored *commonmodels.Product, renderSet *commonmodels.RenderSet, svc *commonmodels.ProductService, manifest *commonmodels.SnapshotServiceManifest, currentYaml string) error {
+	switch manifest.Type {
+	case setting.K8SDeployType:
+		if !commonutil.ServiceDeployed(svc.ServiceName, restored.ServiceDeployStrategy) {
+			return nil
+		}
+		_, err := kube.CreateOrPatchResource(&kube.ResourceApplyParam{
+			ProductInfo:         restored,
+			ServiceName:         svc.ServiceName,
+			CurrentResourceYaml: currentYaml,
+			UpdateResourceYaml:  manifest.Yaml,
+			Informer:            r.informer,
+			KubeClient:          r.kubeClient,
+			IstioClient:         r.istioClient,
+			InjectSecrets:       true,
+			AddZadigLabel:       true,
+			SharedEnvHandler:    EnsureUpdateZadigService,
+		}, r.log)
+		return err
+	case setting.HelmDeployType, setting.HelmChartDeployType:
+		return restoreSnapshotHelmService(restored, renderSet, svc, r.getHelmClient)
+	}
+	return nil
+}
+
+func restoreSnapshotHelmService(restored *commonmodels.Product, renderSet *commonmodels.RenderSet, svc *commonmodels.ProductService, getHelmClient func() (*helmtool.HelmClient, error)) error {
+	if renderSet == nil {
+		return fmt.Errorf("renderset not found in the snapshot")
+	}
+
+	renderChart := renderSet.GetChartRenderMap()[svc.ServiceName]
+	deployed := commonutil.ServiceDeployed(svc.ServiceName, restored.ServiceDeployStrategy)
+	if !svc.FromZadig() {
+		renderChart = renderSet.GetChartDeployRenderMap()[svc.ReleaseName]
+		deployed = commonutil.ReleaseDeployed(svc.ReleaseName, restored.ServiceDeployStrategy)
+	}
+	if renderChart == nil || !deployed {
+		return nil
+	}
+
+	helmClient, err := getHelmClient()
+	if err != nil {
+		return err
+	}
+	param, err := buildInstallParam(renderSet.DefaultValues, restored, renderChart, svc)
+	if err != nil {
+		return err
+	}
+	if param == nil {
+		return fmt.Errorf("service template of revision %d not found", svc.Revision)
+	}
+	return InstallService(helmClient, param)
+}
+
+func (r *clusterSnapshotRestorer) RestoreEnvResources(target []*commonmodels.SnapshotEnvResource) error {
+	current, err := kube.BuildSnapshotEnvResources(r.env)
+	if err != nil {
+		return err
+	}
+	currentResources, snapshotResources := snapshotEnvResourceMap(current), snapshotEnvResourceMap(target)
+	errList := &multierror.Error{}
+
+	for key, resource := range snapshotResources {
+		currentResource, ok := currentResources[key]
+		if ok && currentResource.ResourceID == resource.ResourceID {
+			continue
+		}
+
+		yamlData, err := findEnvResourceYaml(resource)
+		if err != nil {
+			errList = multierror.Append(errList, err)
+			continue
+		}
+		args := &commonmodels.CreateUpdateCommonEnvCfgArgs{
+			EnvName:          r.env.EnvName,
+			ProductName:      r.env.ProductName,
+			Name:             resource.Name,
+			YamlData:         yamlData,
+			CommonEnvCfgType: config.CommonEnvCfgType(resource.Type),
+		}
+		if ok {
+			err = UpdateCommonEnvCfg(args, r.userName, true, r.log)
+		} else {
+			err = CreateCommonEnvCfg(args, r.userName, r.log)
+		}
+		if err != nil {
+			errList = multierror.Append(errList, fmt.Errorf("failed to restore %s, err: %s", key, err))
+		}
+	}
+
+	// remove the resources created after the snapshot
+	for key, resource := range currentResources {
+		if _, ok := snapshotResources[key]; ok {
+			continue
+		}
+		if err := DeleteCommonEnvCfg(r.env.EnvName, r.env.ProductName, resource.Name, config.CommonEnvCfgType(resource.Type), r.log); err != nil {
+			errList = multierror.Append(errList, fmt.Errorf("failed to remove %s, err: %s", key, err))
+		}
+	}
+	return errList.ErrorOrNil()
+}
+
+func (r *clusterSnapshotRestorer) SaveEnv(restored *commonmodels.Product, renderSet *commonmodels.RenderSet) error {
+	if renderSet != nil && r.env.Render != nil {
+		newRenderSet := *renderSet
+		newRenderSet.Name = r.env.Render.Name
+		newRenderSet.UpdateBy = r.userName
+		newRenderSet.UpdateTime = time.Now().Unix()
+		if err := render.CreateK8sHelmRenderSet(&newRenderSet, r.log); err != nil {
+			return fmt.Errorf("failed to create renderset, err: %s", err)
+		}
+		restored.Render = &commonmodels.RenderInfo{
+			Name:        newRenderSet.Name,
+			Revision:    newRenderSet.Revision,
+			ProductTmpl: newRenderSet.ProductTmpl,
+			Description: newRenderSet.Description,
+		}
+	}
+
+	restored.Status = setting.ProductStatusUpdating
+	restored.UpdateBy = r.userName
+	if err := commonrepo.NewProductColl().Update(restored); err != nil {
+		return fmt.Errorf("failed to update env, err: %s", err)
+	}
+	return nil
+}
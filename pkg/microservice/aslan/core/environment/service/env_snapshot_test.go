@@ -0,0 +1,171 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/setting"
+)
+
+// fakeSnapshotRestorer records the services and resources applied to the env, the services in failedServices fail to
+// be applied
+type fakeSnapshotRestorer struct {
+	removed        []string
+	applied        map[string]string
+	resources      []*commonmodels.SnapshotEnvResource
+	saved          []*commonmodels.Product
+	savedRenderSet *commonmodels.RenderSet
+	failedServices map[string]bool
+}
+
+func (r *fakeSnapshotRestorer) RemoveService(manifest *commonmodels.SnapshotServiceManifest, svc *commonmodels.ProductService) error {
+	r.removed = append(r.removed, manifest.ServiceName)
+	return nil
+}
+
+func (r *fakeSnapshotRestorer) ApplyService(restored *commonmodels.Product, renderSet *commonmodels.RenderSet, svc *commonmodels.ProductService, manifest *commonmodels.SnapshotServiceManifest, currentYaml string) error {
+	if r.failedServices[manifest.Yaml] {
+		return errors.New("apply failed")
+	}
+	r.applied[manifest.ServiceName] = manifest.Yaml
+	return nil
+}
+
+func (r *fakeSnapshotRestorer) RestoreEnvResources(target []*commonmodels.SnapshotEnvResource) error {
+	r.resources = target
+	return nil
+}
+
+func (r *fakeSnapshotRestorer) SaveEnv(restored *commonmodels.Product, renderSet *commonmodels.RenderSet) error {
+	r.saved = append(r.saved, restored)
+	r.savedRenderSet = renderSet
+	return nil
+}
+
+func newTestSnapshot(renderSet *commonmodels.RenderSet, resources []*commonmodels.SnapshotEnvResource, services ...string) *commonmodels.EnvSnapshot {
+	snapshot := &commonmodels.EnvSnapshot{
+		ID:           primitive.NewObjectID(),
+		ProjectName:  "project",
+		EnvName:      "dev",
+		RenderSet:    renderSet,
+		EnvResources: resources,
+	}
+	group := []*commonmodels.ProductService{}
+	for _, service := range services {
+		group = append(group, &commonmodels.ProductService{ServiceName: service, Type: setting.K8SDeployType})
+		snapshot.ServiceManifests = append(snapshot.ServiceManifests, &commonmodels.SnapshotServiceManifest{
+			ServiceName: service,
+			Type:        setting.K8SDeployType,
+			Yaml:        "name: " + service + "-" + snapshot.ID.Hex(),
+		})
+	}
+	snapshot.Services = [][]*commonmodels.ProductService{group}
+	return snapshot
+}
+
+var _ = Describe("Testing env snapshot", func() {
+	var (
+		env      *commonmodels.Product
+		current  *commonmodels.EnvSnapshot
+		snapshot *commonmodels.EnvSnapshot
+		restorer *fakeSnapshotRestorer
+	)
+
+	BeforeEach(func() {
+		env = &commonmodels.Product{ProductName: "project", EnvName: "dev", Namespace: "project-env-dev"}
+		current = newTestSnapshot(&commonmodels.RenderSet{Name: "dev", Revision: 2},
+			[]*commonmodels.SnapshotEnvResource{{Type: "ConfigMap", Name: "cm", ResourceID: "2"}}, "a", "c")
+		snapshot = newTestSnapshot(&commonmodels.RenderSet{Name: "dev", Revision: 1},
+			[]*commonmodels.SnapshotEnvResource{{Type: "ConfigMap", Name: "cm", ResourceID: "1"}}, "a", "b")
+		restorer = &fakeSnapshotRestorer{applied: map[string]string{}, failedServices: map[string]bool{}}
+	})
+
+	Describe("test rolling back an env to a snapshot", func() {
+
+		It("should restore the services, resources and render set of the snapshot", func() {
+			Expect(rollbackEnv(restorer, env, current, snapshot)).To(Succeed())
+
+			Expect(restorer.removed).To(Equal([]string{"c"}))
+			Expect(restorer.applied).To(Equal(map[string]string{
+				"a": snapshot.ServiceManifests[0].Yaml,
+				"b": snapshot.ServiceManifests[1].Yaml,
+			}))
+			Expect(restorer.resources).To(Equal(snapshot.EnvResources))
+			Expect(restorer.saved).To(HaveLen(1))
+			Expect(restorer.saved[0].Services).To(Equal(snapshot.Services))
+			Expect(restorer.saved[0].Namespace).To(Equal(env.Namespace))
+			Expect(restorer.savedRenderSet).To(Equal(snapshot.RenderSet))
+			// the env is not changed until it's saved
+			Expect(env.Services).To(BeNil())
+		})
+
+		It("should restore the env to the current state if the rollback fails", func() {
+			restorer.failedServices[snapshot.ServiceManifests[1].Yaml] = true
+
+			err := rollbackEnv(restorer, env, current, snapshot)
+			Expect(err).Should(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("failed to restore service b"))
+
+			// b is removed again by the compensation and c is restored
+			Expect(restorer.removed).To(Equal([]string{"c", "b"}))
+			Expect(restorer.applied).To(Equal(map[string]string{
+				"a": current.ServiceManifests[0].Yaml,
+				"c": current.ServiceManifests[1].Yaml,
+			}))
+			Expect(restorer.resources).To(Equal(current.EnvResources))
+			Expect(restorer.saved).To(HaveLen(1))
+			Expect(restorer.saved[0].Services).To(Equal(current.Services))
+			Expect(restorer.savedRenderSet).To(Equal(current.RenderSet))
+		})
+
+		It("should report both errors if the env can't be restored either", func() {
+			restorer.failedServices[snapshot.ServiceManifests[1].Yaml] = true
+			restorer.failedServices[current.ServiceManifests[1].Yaml] = true
+
+			err := rollbackEnv(restorer, env, current, snapshot)
+			Expect(err).Should(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("failed to restore service b"))
+			Expect(err.Error()).To(ContainSubstring("failed to restore the env to snapshot " + current.ID.Hex()))
+			Expect(err.Error()).To(ContainSubstring("failed to restore service c"))
+			Expect(restorer.saved).To(BeEmpty())
+		})
+	})
+
+	Describe("test the diff of an env and a snapshot", func() {
+
+		It("should list the services added, deleted and changed by the rollback", func() {
+			diffs := diffSnapshotServices(current, snapshot)
+			actions := map[string]string{}
+			for _, diff := range diffs {
+				actions[diff.ServiceName] = diff.Action
+			}
+			Expect(actions).To(Equal(map[string]string{
+				"a": SnapshotDiffChanged,
+				"b": SnapshotDiffAdded,
+				"c": SnapshotDiffDeleted,
+			}))
+
+			Expect(diffSnapshotServices(snapshot, snapshot)).To(HaveEach(HaveField("Action", SnapshotDiffUnchanged)))
+		})
+	})
+})
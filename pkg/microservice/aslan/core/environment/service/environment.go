@@ -43,6 +43,7 @@ import (
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/yaml"
 
@@ -187,6 +188,8 @@ func ListProducts(userID, projectName string, envNames []string, production bool
 			ShareEnvIsBase:  env.ShareEnv.IsBase,
 			ShareEnvBaseEnv: env.ShareEnv.BaseEnv,
 			IsFavorite:      favSet.Has(env.EnvName),
+			GitOpsEnabled:   env.GitOps.IsEnabled(),
+			GitOpsStatus:    env.GitOpsStatus,
 		})
 	}
 
@@ -1953,6 +1956,13 @@ func DeleteProduct(username, envName, productName, requestID string, isDelete bo
 		log.Errorf("DeleteManyFavorites product-%s env-%s error: %v", productName, envName, err)
 	}
 
+	if err := commonrepo.NewEnvDriftColl().Delete(productName, envName); err != nil {
+		log.Errorf("failed to delete drift of env %s/%s, error: %v", productName, envName, err)
+	}
+	if err := commonrepo.NewEnvSnapshotColl().DeleteByEnv(productName, envName); err != nil {
+		log.Errorf("failed to delete snapshots of env %s/%s, error: %v", productName, envName, err)
+	}
+
 	// delete informer's cache
 	informer.DeleteInformer(productInfo.ClusterID, productInfo.Namespace)
 
@@ -2772,6 +2782,8 @@ func buildInstallParam(defaultValues string, productInfo *commonmodels.Product,
 		RenderChart:    renderChart,
 		ProdService:    productSvc,
 		IsChartInstall: renderChart.IsHelmChartDeploy,
+		EnvName:        envName,
+		GitOps:         productInfo.GitOps,
 	}
 
 	if productSvc.FromZadig() {
@@ -4353,9 +4365,19 @@ func EnvSleep(productName, envName string, isEnable, isProduction bool, log *zap
 
 	oldScaleNumMap := make(map[string]int)
 	newScaleNumMap := make(map[string]int)
+	// cronjobs suspended before the env sleeps are kept suspended when it wakes up
+	suspendedCronJobs := sets.NewString()
 	prod.Status = setting.ProductStatusSleeping
 	if !isEnable {
 		oldScaleNumMap = prod.PreSleepStatus
+		if prod.SleepStatus != nil {
+			oldScaleNumMap = sleepWorkloadReplicas(prod.SleepStatus)
+			for _, cronJob := range prod.SleepStatus.CronJobs {
+				if cronJob.Suspended {
+					suspendedCronJobs.Insert(cronJob.Name)
+				}
+			}
+		}
 		prod.Status = setting.ProductStatusSuccess
 	}
 
@@ -4517,13 +4539,25 @@ func EnvSleep(productName, envName string, isEnable, isProduction bool, log *zap
 		})
 	}
 
+	sleepStatus := &commonmodels.EnvSleepStatus{SleepTime: time.Now().Unix()}
+	if isEnable {
+		recordEnvSleepStatus(prod, workLoads, sleepStatus, informer, clientset, kubeclient.VersionLessThan121(version), log)
+	} else {
+		// hpas are restored before the workloads scale up, otherwise the pinned replicas range would scale them down again
+		restoreEnvHPAs(prod, clientset, log)
+	}
+
 	for _, workload := range workLoads {
 		if !workload.DeployedFromZadig {
 			continue
 		}
 
 		scaleNum := 0
-		if num, ok := oldScaleNumMap[workload.Name]; ok {
+		scaleKey := workload.Name
+		if prod.SleepStatus != nil {
+			scaleKey = sleepObjectKey(workload.Type, prod.Namespace, workload.Name)
+		}
+		if num, ok := oldScaleNumMap[scaleKey]; ok {
 			// restore previous scale num
 			scaleNum = num
 		}
@@ -4548,6 +4582,8 @@ func EnvSleep(productName, envName string, isEnable, isProduction bool, log *zap
 				if err != nil {
 					log.Errorf("failed to suspend %s/cronjob/%s", prod.Namespace, workload.Name)
 				}
+			} else if suspendedCronJobs.Has(workload.Name) {
+				log.Infof("cronjob %s is suspended before the env sleeps, skip resuming it", workload.Name)
 			} else {
 				log.Infof("resume cronjob %s", workload.Name)
 				err := updater.ResumeCronJob(prod.Namespace, workload.Name, kubeClient, kubeclient.VersionLessThan121(version))
@@ -4558,6 +4594,13 @@ func EnvSleep(productName, envName string, isEnable, isProduction bool, log *zap
 		}
 	}
 
+	if isEnable {
+		pinEnvHPAs(sleepStatus, clientset, log)
+		prod.SleepStatus = sleepStatus
+	} else {
+		prod.SleepStatus = nil
+	}
+
 	prod.PreSleepStatus = newScaleNumMap
 	err = commonrepo.NewProductColl().Update(prod)
 	if err != nil {
@@ -4565,10 +4608,114 @@ func EnvSleep(productName, envName string, isEnable, isProduction bool, log *zap
 		log.Error(wrapErr)
 		return e.ErrEnvSleep.AddErr(wrapErr)
 	}
+	err = commonrepo.NewProductColl().UpdateSleepStatus(prod.ProductName, prod.EnvName, prod.SleepStatus)
+	if err != nil {
+		wrapErr := fmt.Errorf("failed to update sleep status of product, err: %w", err)
+		log.Error(wrapErr)
+		return e.ErrEnvSleep.AddErr(wrapErr)
+	}
 
 	return nil
 }
 
+// sleepObjectKey identifies the workloads and hpas recorded in the sleep status, a deployment and a statefulset may
+// share the same name
+func sleepObjectKey(kind, namespace, name string) string {
+	return strings.Join([]string{kind, namespace, name}, "/")
+}
+
+// sleepWorkloadReplicas returns the replicas of the workloads before the env sleeps keyed by sleepObjectKey
+func sleepWorkloadReplicas(sleepStatus *commonmodels.EnvSleepStatus) map[string]int {
+	replicas := make(map[string]int)
+	for _, workload := range sleepStatus.Workloads {
+		replicas[sleepObjectKey(workload.Kind, workload.Namespace, workload.Name)] = workload.Replicas
+	}
+	return replicas
+}
+
+// recordEnvSleepStatus records the replicas of the workloads, the replicas range of their hpas and the suspend state of
+// the cronjobs before the env sleeps
+func recordEnvSleepStatus(prod *commonmodels.Product, workLoads []*commonservice.Workload, sleepStatus *commonmodels.EnvSleepStatus, informer informers.SharedInformerFactory, clientset kubernetes.Interface, versionLessThan121 bool, log *zap.SugaredLogger) {
+	scaledWorkloads := sets.NewString()
+	for _, workload := range workLoads {
+		if !workload.DeployedFromZadig {
+			continue
+		}
+		switch workload.Type {
+		case setting.Deployment, setting.StatefulSet:
+			sleepStatus.Workloads = append(sleepStatus.Workloads, &commonmodels.SleepWorkload{
+				Kind:      workload.Type,
+				Namespace: prod.Namespace,
+				Name:      workload.Name,
+				Replicas:  int(workload.Replicas),
+			})
+			scaledWorkloads.Insert(sleepObjectKey(workload.Type, prod.Namespace, workload.Name))
+		case setting.CronJob:
+			suspended := false
+			cronJob, cronJobBeta, err := getter.GetCronJobByNameWithCache(workload.Name, prod.Namespace, informer, versionLessThan121)
+			if err != nil {
+				log.Warnf("failed to get %s/cronjob/%s, err: %s", prod.Namespace, workload.Name, err)
+			} else if cronJob != nil {
+				suspended = cronJob.Spec.Suspend != nil && *cronJob.Spec.Suspend
+			} else if cronJobBeta != nil {
+				suspended = cronJobBeta.Spec.Suspend != nil && *cronJobBeta.Spec.Suspend
+			}
+			sleepStatus.CronJobs = append(sleepStatus.CronJobs, &commonmodels.SleepCronJob{
+				Name:      workload.Name,
+				Suspended: suspended,
+			})
+		}
+	}
+
+	hpas, err := getter.ListHorizontalPodAutoscalers(prod.Namespace, clientset)
+	if err != nil {
+		log.Warnf("failed to list hpas in namespace %s, err: %s", prod.Namespace, err)
+		return
+	}
+	for _, hpa := range hpas {
+		targetRef := hpa.Spec.ScaleTargetRef
+		if !scaledWorkloads.Has(sleepObjectKey(targetRef.Kind, hpa.Namespace, targetRef.Name)) {
+			continue
+		}
+		minReplicas := int32(1)
+		if hpa.Spec.MinReplicas != nil {
+			minReplicas = *hpa.Spec.MinReplicas
+		}
+		sleepStatus.HPAs = append(sleepStatus.HPAs, &commonmodels.SleepHPA{
+			Namespace:   hpa.Namespace,
+			Name:        hpa.Name,
+			TargetKind:  targetRef.Kind,
+			TargetName:  targetRef.Name,
+			MinReplicas: minReplicas,
+			MaxReplicas: hpa.Spec.MaxReplicas,
+		})
+	}
+}
+
+// pinEnvHPAs pins the recorded hpas to 1 replica while the env sleeps
+func pinEnvHPAs(sleepStatus *commonmodels.EnvSleepStatus, clientset kubernetes.Interface, log *zap.SugaredLogger) {
+	for _, hpa := range sleepStatus.HPAs {
+		log.Infof("pin hpa %s/%s to 1 replica", hpa.Namespace, hpa.Name)
+		err := updater.PatchHorizontalPodAutoscalerReplicas(hpa.Namespace, hpa.Name, 1, 1, clientset)
+		if err != nil {
+			log.Errorf("failed to pin %s/hpa/%s to 1 replica, err: %s", hpa.Namespace, hpa.Name, err)
+		}
+	}
+}
+
+func restoreEnvHPAs(prod *commonmodels.Product, clientset kubernetes.Interface, log *zap.SugaredLogger) {
+	if prod.SleepStatus == nil {
+		return
+	}
+	for _, hpa := range prod.SleepStatus.HPAs {
+		log.Infof("restore hpa %s/%s to %d-%d replicas", hpa.Namespace, hpa.Name, hpa.MinReplicas, hpa.MaxReplicas)
+		err := updater.PatchHorizontalPodAutoscalerReplicas(hpa.Namespace, hpa.Name, hpa.MinReplicas, hpa.MaxReplicas, clientset)
+		if err != nil {
+			log.Errorf("failed to restore %s/hpa/%s, err: %s", hpa.Namespace, hpa.Name, err)
+		}
+	}
+}
+
 func GetEnvSleepCron(projectName, envName string, production *bool, logger *zap.SugaredLogger) (*EnvSleepCronArg, error) {
 	resp := &EnvSleepCronArg{}
 
@@ -67,6 +67,9 @@ type EnvResp struct {
 	ShareEnvEnable  bool   `json:"share_env_enable"`
 	ShareEnvIsBase  bool   `json:"share_env_is_base"`
 	ShareEnvBaseEnv string `json:"share_env_base_env"`
+
+	GitOpsEnabled bool                          `json:"gitops_enabled"`
+	GitOpsStatus  *commonmodels.EnvGitOpsStatus `json:"gitops_status,omitempty"`
 }
 
 type ProductResp struct {
@@ -95,6 +98,9 @@ type ProductResp struct {
 	ShareEnvEnable  bool   `json:"share_env_enable"`
 	ShareEnvIsBase  bool   `json:"share_env_is_base"`
 	ShareEnvBaseEnv string `json:"share_env_base_env"`
+
+	GitOpsEnabled bool                          `json:"gitops_enabled"`
+	GitOpsStatus  *commonmodels.EnvGitOpsStatus `json:"gitops_status,omitempty"`
 }
 
 type ProductParams struct {
@@ -0,0 +1,115 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/log"
+)
+
+func newTestHPA(name, targetKind, targetName string, minReplicas, maxReplicas int32) *autoscalingv1.HorizontalPodAutoscaler {
+	return &autoscalingv1.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test-env"},
+		Spec: autoscalingv1.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv1.CrossVersionObjectReference{Kind: targetKind, Name: targetName},
+			MinReplicas:    &minReplicas,
+			MaxReplicas:    maxReplicas,
+		},
+	}
+}
+
+var _ = Describe("Testing env sleep", func() {
+
+	Describe("test the sleep status round trip", func() {
+
+		It("should restore the replicas, hpas and suspended cronjobs when the env wakes up", func() {
+			suspend := true
+			clientset := fake.NewSimpleClientset(
+				newTestHPA("app-deploy", setting.Deployment, "app", 2, 5),
+				newTestHPA("app-sts", setting.StatefulSet, "app", 3, 6),
+				newTestHPA("other", setting.Deployment, "other", 1, 3),
+				&batchv1.CronJob{
+					ObjectMeta: metav1.ObjectMeta{Name: "suspended-job", Namespace: "test-env"},
+					Spec:       batchv1.CronJobSpec{Suspend: &suspend},
+				},
+				&batchv1.CronJob{ObjectMeta: metav1.ObjectMeta{Name: "active-job", Namespace: "test-env"}},
+			)
+			informer := informers.NewSharedInformerFactoryWithOptions(clientset, time.Minute, informers.WithNamespace("test-env"))
+			informer.Batch().V1().CronJobs().Informer()
+			stopCh := make(chan struct{})
+			defer close(stopCh)
+			informer.Start(stopCh)
+			informer.WaitForCacheSync(stopCh)
+
+			prod := &commonmodels.Product{ProductName: "test-project", EnvName: "test", Namespace: "test-env"}
+			workloads := []*commonservice.Workload{
+				{Name: "app", Type: setting.Deployment, Replicas: 4, DeployedFromZadig: true},
+				{Name: "app", Type: setting.StatefulSet, Replicas: 3, DeployedFromZadig: true},
+				{Name: "other", Type: setting.Deployment, Replicas: 1},
+				{Name: "suspended-job", Type: setting.CronJob, DeployedFromZadig: true},
+				{Name: "active-job", Type: setting.CronJob, DeployedFromZadig: true},
+			}
+
+			By("sleeping the env")
+			sleepStatus := &commonmodels.EnvSleepStatus{SleepTime: time.Now().Unix()}
+			recordEnvSleepStatus(prod, workloads, sleepStatus, informer, clientset, false, log.SugaredLogger())
+			pinEnvHPAs(sleepStatus, clientset, log.SugaredLogger())
+			prod.SleepStatus = sleepStatus
+
+			Expect(sleepStatus.HPAs).To(HaveLen(2))
+			for _, name := range []string{"app-deploy", "app-sts"} {
+				hpa, err := clientset.AutoscalingV1().HorizontalPodAutoscalers("test-env").Get(context.TODO(), name, metav1.GetOptions{})
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(*hpa.Spec.MinReplicas).To(Equal(int32(1)))
+				Expect(hpa.Spec.MaxReplicas).To(Equal(int32(1)))
+			}
+			Expect(sleepStatus.CronJobs).To(ConsistOf(
+				&commonmodels.SleepCronJob{Name: "suspended-job", Suspended: true},
+				&commonmodels.SleepCronJob{Name: "active-job", Suspended: false},
+			))
+
+			By("waking the env up")
+			replicas := sleepWorkloadReplicas(prod.SleepStatus)
+			Expect(replicas).To(Equal(map[string]int{
+				sleepObjectKey(setting.Deployment, "test-env", "app"):  4,
+				sleepObjectKey(setting.StatefulSet, "test-env", "app"): 3,
+			}))
+			restoreEnvHPAs(prod, clientset, log.SugaredLogger())
+
+			expected := map[string][2]int32{"app-deploy": {2, 5}, "app-sts": {3, 6}, "other": {1, 3}}
+			for name, replicasRange := range expected {
+				hpa, err := clientset.AutoscalingV1().HorizontalPodAutoscalers("test-env").Get(context.TODO(), name, metav1.GetOptions{})
+				Expect(err).ShouldNot(HaveOccurred())
+				Expect(*hpa.Spec.MinReplicas).To(Equal(replicasRange[0]))
+				Expect(hpa.Spec.MaxReplicas).To(Equal(replicasRange[1]))
+			}
+		})
+	})
+})
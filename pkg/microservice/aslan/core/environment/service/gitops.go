@@ -0,0 +1,148 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/kube"
+	"github.com/koderover/zadig/pkg/setting"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+func GetEnvGitOps(projectName, envName string, production bool, log *zap.SugaredLogger) (*commonmodels.EnvGitOpsConfig, error) {
+	env, err := commonrepo.NewProductColl().Find(&commonrepo.ProductFindOptions{Name: projectName, EnvName: envName, Production: &production})
+	if err != nil {
+		log.Errorf("failed to find env %s/%s, err: %s", projectName, envName, err)
+		return nil, e.ErrGetEnvGitOps.AddErr(err)
+	}
+	if env.GitOps == nil {
+		return &commonmodels.EnvGitOpsConfig{}, nil
+	}
+	return env.GitOps, nil
+}
+
+func UpdateEnvGitOps(projectName, envName string, production bool, gitOps *commonmodels.EnvGitOpsConfig, log *zap.SugaredLogger) error {
+	env, err := commonrepo.NewProductColl().Find(&commonrepo.ProductFindOptions{Name: projectName, EnvName: envName, Production: &production})
+	if err != nil {
+		log.Errorf("failed to find env %s/%s, err: %s", projectName, envName, err)
+		return e.ErrUpdateEnvGitOps.AddErr(err)
+	}
+	if gitOps.Enabled {
+		if err := validateEnvGitOps(env, gitOps); err != nil {
+			return e.ErrUpdateEnvGitOps.AddErr(err)
+		}
+	}
+
+	if err := commonrepo.NewProductColl().UpdateGitOps(envName, projectName, gitOps); err != nil {
+		log.Errorf("failed to update gitops of env %s/%s, err: %s", projectName, envName, err)
+		return e.ErrUpdateEnvGitOps.AddErr(err)
+	}
+	return nil
+}
+
+func validateEnvGitOps(env *commonmodels.Product, gitOps *commonmodels.EnvGitOpsConfig) error {
+	if env.Source == setting.SourceFromExternal || env.Source == setting.SourceFromPM {
+		return fmt.Errorf("gitops is not supported by the env of source %s", env.Source)
+	}
+	if env.ShareEnv.Enable {
+		return fmt.Errorf("gitops is not supported by the shared env")
+	}
+	if gitOps.CodehostID == 0 || gitOps.GetRepoNamespace() == "" || gitOps.RepoName == "" || gitOps.Branch == "" {
+		return fmt.Errorf("codehost, repo and branch of gitops are required")
+	}
+	switch gitOps.Controller {
+	case config.GitOpsControllerArgoCD, config.GitOpsControllerFlux:
+	default:
+		return fmt.Errorf("gitops controller %s is not supported", gitOps.Controller)
+	}
+	if gitOps.ApplicationNamespace == "" || gitOps.ApplicationName == "" {
+		return fmt.Errorf("the application of the gitops controller is required")
+	}
+	return nil
+}
+
+// GetEnvGitOpsStatus refreshes the gitops status of the env from the gitops controller
+func GetEnvGitOpsStatus(projectName, envName string, production bool, log *zap.SugaredLogger) (*commonmodels.EnvGitOpsStatus, error) {
+	env, err := commonrepo.NewProductColl().Find(&commonrepo.ProductFindOptions{Name: projectName, EnvName: envName, Production: &production})
+	if err != nil {
+		log.Errorf("failed to find env %s/%s, err: %s", projectName, envName, err)
+		return nil, e.ErrGetEnvGitOpsStatus.AddErr(err)
+	}
+	if !env.GitOps.IsEnabled() {
+		return nil, e.ErrGetEnvGitOpsStatus.AddDesc("gitops is not enabled")
+	}
+
+	status, err := refreshEnvGitOpsStatus(env)
+	if err != nil {
+		log.Errorf("failed to get gitops status of env %s/%s, err: %s", projectName, envName, err)
+		return nil, e.ErrGetEnvGitOpsStatus.AddErr(err)
+	}
+	return status, nil
+}
+
+// RefreshEnvGitOpsStatusCronJob refreshes the gitops status of all the gitops envs, the status is shown in the env list
+func RefreshEnvGitOpsStatusCronJob(log *zap.SugaredLogger) {
+	log.Info("[RefreshEnvGitOpsStatusCronJob] started ...")
+	defer log.Info("[RefreshEnvGitOpsStatusCronJob] end")
+
+	envs, err := commonrepo.NewProductColl().List(&commonrepo.ProductListOptions{})
+	if err != nil {
+		log.Errorf("[Product.List] error: %v", err)
+		return
+	}
+
+	for _, env := range envs {
+		if !env.GitOps.IsEnabled() {
+			continue
+		}
+		if _, err := refreshEnvGitOpsStatus(env); err != nil {
+			log.Errorf("failed to refresh gitops status of env %s/%s, err: %s", env.ProductName, env.EnvName, err)
+		}
+	}
+}
+
+func refreshEnvGitOpsStatus(env *commonmodels.Product) (*commonmodels.EnvGitOpsStatus, error) {
+	status, err := kube.GetGitOpsSyncStatus(env)
+	if err != nil {
+		return nil, err
+	}
+	if err := commonrepo.NewProductColl().UpdateGitOpsStatus(env.EnvName, env.ProductName, status); err != nil {
+		return nil, err
+	}
+	env.GitOpsStatus = status
+	return status, nil
+}
+
+// gitOpsEnvStatus maps the gitops status to the status of the env
+func gitOpsEnvStatus(status *commonmodels.EnvGitOpsStatus) string {
+	switch {
+	case status.HealthStatus == "Degraded" || status.HealthStatus == "Missing":
+		return setting.PodUnstable
+	case status.SyncStatus == config.GitOpsSyncStatusSynced && status.HealthStatus == "Healthy":
+		return setting.PodRunning
+	case status.SyncStatus == config.GitOpsSyncStatusUnknown:
+		return setting.ClusterUnknown
+	default:
+		return setting.PodUpdating
+	}
+}
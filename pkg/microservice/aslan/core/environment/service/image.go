@@ -76,7 +76,7 @@ func updateContainerForHelmChart(serviceName, image, containerName string, produ
 		return err
 	}
 
-	err = kube.UpgradeHelmRelease(product, renderSet, targetProductService, serviceObj, []string{image}, 0)
+	err = kube.UpgradeHelmRelease(product, renderSet, targetProductService, serviceObj, []string{image}, 0, nil)
 	if err != nil {
 		return fmt.Errorf("failed to upgrade helm release, err: %s", err.Error())
 	}
@@ -0,0 +1,144 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/render"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/scmnotify"
+	"github.com/koderover/zadig/pkg/setting"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/util"
+)
+
+// CreatePreviewEnv copies the base environment of the preview env to a new environment with the same services and
+// variables, the services changed by the pull request are deployed to it by the workflow afterwards.
+func CreatePreviewEnv(previewEnv *commonmodels.PreviewEnv, requestID string, log *zap.SugaredLogger) error {
+	baseProduct, err := commonrepo.NewProductColl().Find(&commonrepo.ProductFindOptions{
+		Name:    previewEnv.ProjectName,
+		EnvName: previewEnv.BaseEnv,
+	})
+	if err != nil {
+		return e.ErrCreateEnv.AddErr(fmt.Errorf("failed to find base environment: %s, err: %s", previewEnv.BaseEnv, err))
+	}
+	if baseProduct.Production {
+		return e.ErrCreateEnv.AddDesc(fmt.Sprintf("base environment %s of the preview environment is a production environment", previewEnv.BaseEnv))
+	}
+	baseRenderset, err := commonrepo.NewRenderSetColl().Find(&commonrepo.RenderSetFindOption{
+		Name:        baseProduct.Render.Name,
+		Revision:    baseProduct.Render.Revision,
+		ProductTmpl: baseProduct.ProductName,
+	})
+	if err != nil {
+		return e.ErrCreateEnv.AddErr(fmt.Errorf("failed to find renderset of base environment: %s, err: %s", previewEnv.BaseEnv, err))
+	}
+
+	util.Clear(&baseProduct.ID)
+	baseProduct.EnvName = previewEnv.EnvName
+	baseProduct.Namespace = commonservice.GetProductEnvNamespace(previewEnv.EnvName, previewEnv.ProjectName, "")
+	baseProduct.IsExisted = false
+	// the preview environment is recycled by its idle ttl instead of the recycle day
+	baseProduct.RecycleDay = 0
+	baseProduct.ShareEnv = commonmodels.ProductShareEnv{}
+	baseProduct.PreSleepStatus = nil
+	baseProduct.SleepStatus = nil
+
+	baseRenderset.Revision = 0
+	baseRenderset.EnvName = previewEnv.EnvName
+	baseRenderset.Name = baseProduct.Namespace
+	if err := render.CreateRenderSet(baseRenderset, log); err != nil {
+		return e.ErrCreateEnv.AddErr(fmt.Errorf("failed to create renderset of preview environment: %s, err: %s", previewEnv.EnvName, err))
+	}
+	baseProduct.Render = &commonmodels.RenderInfo{
+		Name:        baseRenderset.Name,
+		Revision:    baseRenderset.Revision,
+		ProductTmpl: baseRenderset.ProductTmpl,
+	}
+
+	return CreateProduct(setting.SystemUser, requestID, baseProduct, log)
+}
+
+// DeletePreviewEnv deletes the environment of the preview env and the preview env itself, the pull request is
+// commented that the environment is deleted
+func DeletePreviewEnv(previewEnv *commonmodels.PreviewEnv, requestID string, log *zap.SugaredLogger) error {
+	_, err := commonrepo.NewProductColl().Find(&commonrepo.ProductFindOptions{
+		Name:    previewEnv.ProjectName,
+		EnvName: previewEnv.EnvName,
+	})
+	if err == nil {
+		if err := DeleteProduct(setting.SystemUser, previewEnv.EnvName, previewEnv.ProjectName, requestID, true, log); err != nil {
+			return fmt.Errorf("failed to delete preview environment %s/%s: %s", previewEnv.ProjectName, previewEnv.EnvName, err)
+		}
+	} else if err != mongo.ErrNoDocuments {
+		return fmt.Errorf("failed to find preview environment %s/%s: %s", previewEnv.ProjectName, previewEnv.EnvName, err)
+	}
+
+	if err := commonrepo.NewPreviewEnvColl().Delete(previewEnv.ID); err != nil {
+		return fmt.Errorf("failed to delete preview env %s: %s", previewEnv.EnvName, err)
+	}
+	previewEnv.Status = config.PreviewEnvStatusDeleted
+	previewEnv.Error = ""
+	if err := scmnotify.NewService().UpdatePreviewEnvComment(previewEnv, log); err != nil {
+		log.Warnf("failed to comment the deletion of preview environment %s: %s", previewEnv.EnvName, err)
+	}
+	log.Infof("preview environment %s/%s of pr %d is deleted", previewEnv.ProjectName, previewEnv.EnvName, previewEnv.PrID)
+	return nil
+}
+
+// DeletePreviewEnvsOfPR deletes the preview envs of a closed or merged pull request
+func DeletePreviewEnvsOfPR(repoNamespace, repoName string, prID int, requestID string, log *zap.SugaredLogger) error {
+	previewEnvs, err := commonrepo.NewPreviewEnvColl().List(&commonrepo.PreviewEnvListOption{
+		RepoNamespace: repoNamespace,
+		RepoName:      repoName,
+		PrID:          prID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list preview envs of %s/%s#%d: %s", repoNamespace, repoName, prID, err)
+	}
+	for _, previewEnv := range previewEnvs {
+		if err := DeletePreviewEnv(previewEnv, requestID, log); err != nil {
+			log.Error(err)
+		}
+	}
+	return nil
+}
+
+func cleanIdlePreviewEnvs(requestID string, log *zap.SugaredLogger) {
+	// the idle ttl is at least an hour, the ones idle for less than an hour are never expired
+	previewEnvs, err := commonrepo.NewPreviewEnvColl().ListIdle(time.Now().Add(-time.Hour).Unix())
+	if err != nil {
+		log.Errorf("failed to list idle preview envs: %s", err)
+		return
+	}
+	for _, previewEnv := range previewEnvs {
+		if time.Now().Unix()-previewEnv.LastActiveTime < previewEnv.IdleTTL*60*60 {
+			continue
+		}
+		if err := DeletePreviewEnv(previewEnv, requestID, log); err != nil {
+			log.Error(err)
+		}
+	}
+}
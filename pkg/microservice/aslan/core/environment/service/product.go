@@ -89,6 +89,8 @@ func CleanProductCronJob(requestID string, log *zap.SugaredLogger) {
 			log.Warnf("[%s] product %s deleted", product.EnvName, product.ProductName)
 		}
 	}
+
+	cleanIdlePreviewEnvs(requestID, log)
 }
 
 func GetInitProduct(productTmplName string, envType types.EnvType, isBaseEnv bool, baseEnvName string, production bool, log *zap.SugaredLogger) (*commonmodels.Product, error) {
@@ -325,6 +327,23 @@ func buildProductResp(envName string, prod *commonmodels.Product, log *zap.Sugar
 		return prodResp, nil
 	}
 
+	// the gitops env is deployed by the gitops controller, its status is the sync state last reported by the controller,
+	// which is refreshed by the gitops status api
+	if prod.GitOps.IsEnabled() {
+		prodResp.GitOpsEnabled = true
+		status := prod.GitOpsStatus
+		if status == nil {
+			status = &commonmodels.EnvGitOpsStatus{SyncStatus: config.GitOpsSyncStatusUnknown}
+		}
+		prodResp.Status = gitOpsEnvStatus(status)
+		prodResp.Error = ""
+		if prodResp.Status == setting.PodUnstable {
+			prodResp.Error = status.Message
+		}
+		prodResp.GitOpsStatus = status
+		return prodResp, nil
+	}
+
 	var errObj error
 	prodResp.Error = ""
 
@@ -24,6 +24,7 @@ import (
 	"strings"
 	"time"
 
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.uber.org/zap"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/kubernetes"
@@ -38,6 +39,7 @@ import (
 	"github.com/koderover/zadig/pkg/tool/kube/getter"
 	"github.com/koderover/zadig/pkg/tool/kube/label"
 	"github.com/koderover/zadig/pkg/tool/kube/watcher"
+	"github.com/koderover/zadig/pkg/types"
 )
 
 const (
@@ -285,6 +287,10 @@ func WorkflowTaskV4ContainerLogStream(ctx context.Context, streamChan chan inter
 					return
 				}
 				options.ClusterID = jobSpec.Properties.ClusterID
+				if jobSpec.Properties.Infrastructure == types.JobVMInfrastructure {
+					vmJobLogStream(ctx, streamChan, options.PipelineName, options.SubTask, options.TaskID, log)
+					return
+				}
 			case string(config.JobPlugin):
 				jobSpec := &commonmodels.JobTaskPluginSpec{}
 				if err := commonmodels.IToi(job.Spec, jobSpec); err != nil {
@@ -313,6 +319,49 @@ func WorkflowTaskV4ContainerLogStream(ctx context.Context, streamChan chan inter
 	waitAndGetLog(ctx, streamChan, selector, options, log)
 }
 
+// vmJobLogStream streams the log sent by the vm runner until the vm job is done
+func vmJobLogStream(ctx context.Context, streamChan chan interface{}, workflowName, jobName string, taskID int64, log *zap.SugaredLogger) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	vmJobID, seq := "", int64(-1)
+	for {
+		// a new vm job is created when the job is retried
+		vmJob, err := commonrepo.NewVMJobColl().FindLatest(workflowName, taskID, jobName)
+		if err != nil && err != mongo.ErrNoDocuments {
+			log.Errorf("failed to find the vm job of %s/%d/%s: %s", workflowName, taskID, jobName, err)
+			return
+		}
+		if err == nil {
+			if vmJob.ID.Hex() != vmJobID {
+				vmJobID, seq = vmJob.ID.Hex(), -1
+			}
+			chunks, err := commonrepo.NewVMJobLogColl().List(vmJobID, seq)
+			if err != nil {
+				log.Errorf("failed to list the log of vm job %s: %s", vmJobID, err)
+				return
+			}
+			// the runner sends the log by lines
+			for _, chunk := range chunks {
+				for _, line := range strings.Split(strings.TrimSuffix(chunk.Content, "\n"), "\n") {
+					streamChan <- line
+				}
+				seq = chunk.Seq
+			}
+			if len(chunks) == 0 && vmJob.Status != string(config.StatusCreated) && vmJob.Status != string(config.StatusRunning) {
+				return
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Infof("Connection is closed, vm job log stream stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
 func TestJobContainerLogStream(ctx context.Context, streamChan chan interface{}, options *GetContainerOptions, log *zap.SugaredLogger) {
 	options.SubTask = string(config.TaskTestingV2)
 	selector := labels.Set(label.GetJobLabels(&label.JobLabel{
@@ -275,9 +275,7 @@ func createNativeApproval(plan *models.ReleasePlan, url string) error {
 
 	approveKey := uuid.New().String()
 	approval.InstanceCode = approveKey
-	approveWithL := &approvalservice.ApproveWithLock{Approval: approval}
-	approvalservice.GlobalApproveMap.SetApproval(approveKey, approveWithL)
-	return nil
+	return approvalservice.SetApproval(approveKey, approval)
 }
 
 func updateNativeApproval(ctx context.Context, approval *models.Approval) error {
@@ -285,14 +283,21 @@ func updateNativeApproval(ctx context.Context, approval *models.Approval) error
 		return errors.New("updateLarkApproval: native approval data not found")
 	}
 
-	approveWithL, ok := approvalservice.GlobalApproveMap.GetApproval(approval.NativeApproval.InstanceCode)
-	if !ok {
+	snapshot, err := approvalservice.GetApproval(approval.NativeApproval.InstanceCode)
+	if err == approvalservice.ErrApprovalNotFound {
+		// restore the approval created before approvals were persisted
 		log.Infof("updateNativeApproval: approval instance code %s not found, set it", approval.NativeApproval.InstanceCode)
-		approvalservice.GlobalApproveMap.SetApproval(approval.NativeApproval.InstanceCode, &approvalservice.ApproveWithLock{Approval: approval.NativeApproval})
+		if err := approvalservice.SetApproval(approval.NativeApproval.InstanceCode, approval.NativeApproval); err != nil {
+			return errors.Wrap(err, "set native approval")
+		}
+		snapshot, err = approvalservice.GetApproval(approval.NativeApproval.InstanceCode)
+	}
+	if err != nil {
+		return errors.Wrap(err, "get native approval")
 	}
 
-	approval.NativeApproval = approveWithL.Approval
-	approved, _, err := approveWithL.IsApproval()
+	approval.NativeApproval = snapshot.Approval
+	approved, _, err := snapshot.IsApproval()
 	if err != nil {
 		approval.Status = config.StatusReject
 		return nil
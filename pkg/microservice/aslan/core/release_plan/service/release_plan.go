@@ -346,19 +346,21 @@ func ApproveReleasePlan(c *handler.Context, id string, req *ApproveRequest) erro
 		return errors.Errorf("plan approval is nil or not native approval")
 	}
 
-	approveWithL, ok := approvalservice.GlobalApproveMap.GetApproval(plan.Approval.NativeApproval.InstanceCode)
-	if !ok {
-		// restore data after restart aslan
-		log.Infof("updateNativeApproval: approval instance code %s not found, set it", plan.Approval.NativeApproval.InstanceCode)
-		approveWithL = &approvalservice.ApproveWithLock{Approval: plan.Approval.NativeApproval}
-		approvalservice.GlobalApproveMap.SetApproval(plan.Approval.NativeApproval.InstanceCode, approveWithL)
-	}
-	if err = approveWithL.DoApproval(c.UserName, c.UserID, req.Comment, req.Approve); err != nil {
+	approveKey := plan.Approval.NativeApproval.InstanceCode
+	if _, err := approvalservice.GetApproval(approveKey); err == approvalservice.ErrApprovalNotFound {
+		// restore the approval created before approvals were persisted
+		log.Infof("updateNativeApproval: approval instance code %s not found, set it", approveKey)
+		if err := approvalservice.SetApproval(approveKey, plan.Approval.NativeApproval); err != nil {
+			return errors.Wrap(err, "set native approval")
+		}
+	}
+	snapshot, err := approvalservice.DoApproval(approveKey, c.UserName, c.UserID, req.Comment, req.Approve)
+	if err != nil {
 		return errors.Wrap(err, "do approval")
 	}
 
-	plan.Approval.NativeApproval = approveWithL.Approval
-	approved, _, err := approveWithL.IsApproval()
+	plan.Approval.NativeApproval = snapshot.Approval
+	approved, _, err := snapshot.IsApproval()
 	if err != nil {
 		plan.Approval.Status = config.StatusReject
 	}
@@ -387,6 +389,12 @@ func ApproveReleasePlan(c *handler.Context, id string, req *ApproveRequest) erro
 	if err = mongodb.NewReleasePlanColl().UpdateByID(ctx, id, plan); err != nil {
 		return errors.Wrap(err, "update plan")
 	}
+	// the result is saved in the plan, the finished approval is not needed anymore
+	if plan.Approval.Status == config.StatusPassed || plan.Approval.Status == config.StatusReject {
+		if err := approvalservice.DeleteApproval(approveKey); err != nil {
+			log.Errorf("delete native approval %s error: %v", approveKey, err)
+		}
+	}
 	return nil
 }
 
@@ -395,6 +395,18 @@ func initDatabase() {
 		commonrepo.NewDeliveryDeployColl(),
 		commonrepo.NewDeliveryDistributeColl(),
 		commonrepo.NewDeliverySecurityColl(),
+		commonrepo.NewImageScanColl(),
+		commonrepo.NewImageSigningKeyColl(),
+		commonrepo.NewArtifactRegistryColl(),
+		commonrepo.NewDebugSessionColl(),
+		commonrepo.NewVMRunnerColl(),
+		commonrepo.NewVMJobColl(),
+		commonrepo.NewVMJobLogColl(),
+		commonrepo.NewPreviewEnvColl(),
+		commonrepo.NewEnvDriftColl(),
+		commonrepo.NewEnvSnapshotColl(),
+		commonrepo.NewTestReportResultColl(),
+		commonrepo.NewTestCaseQuarantineColl(),
 		commonrepo.NewDeliveryTestColl(),
 		commonrepo.NewDeliveryVersionColl(),
 		commonrepo.NewDiffNoteColl(),
@@ -453,6 +465,7 @@ func initDatabase() {
 		commonrepo.NewImageTagsCollColl(),
 		commonrepo.NewLLMIntegrationColl(),
 		commonrepo.NewReleasePlanColl(),
+		commonrepo.NewNativeApprovalColl(),
 
 		// msg queue
 		commonrepo.NewMsgQueueCommonColl(),
@@ -48,6 +48,9 @@ func (*Router) Inject(router *gin.RouterGroup) {
 		quality.POST("/testDeliveryDeploy", GetTestDeliveryDeployMeasure)
 		quality.POST("/testHealthMeasure", GetTestHealthMeasure)
 		quality.POST("/testTrend", GetTestTrendMeasure)
+		quality.GET("/test/coverage", GetTestCoverageTrend)
+		quality.GET("/test/flaky", GetFlakyTestCases)
+		quality.GET("/test/duration", GetTestCaseDurationHistory)
 		//deployStat
 		quality.POST("/initDeployStat", InitDeployStat)
 		quality.POST("/pipelineHealthMeasure", GetPipelineHealthMeasure)
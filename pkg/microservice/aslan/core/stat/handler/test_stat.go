@@ -91,6 +91,62 @@ func GetTestTrendMeasure(c *gin.Context) {
 	ctx.Resp, ctx.Err = service.GetTestTrendMeasure(args.StartDate, args.EndDate, args.ProductNames, ctx.Logger)
 }
 
+type getTestReportStatReq struct {
+	ProjectName string `form:"projectName"`
+	TestName    string `form:"testName"`
+	CaseName    string `form:"caseName"`
+	StartDate   int64  `form:"startDate,default=0"`
+	EndDate     int64  `form:"endDate,default=0"`
+}
+
+func GetTestCoverageTrend(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(getTestReportStatReq)
+	if err := c.ShouldBindQuery(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	if args.ProjectName == "" {
+		ctx.Err = e.ErrInvalidParam.AddDesc("projectName is required")
+		return
+	}
+	ctx.Resp, ctx.Err = service.GetTestCoverageTrend(args.ProjectName, args.TestName, args.StartDate, args.EndDate, ctx.Logger)
+}
+
+func GetFlakyTestCases(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(getTestReportStatReq)
+	if err := c.ShouldBindQuery(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	if args.ProjectName == "" {
+		ctx.Err = e.ErrInvalidParam.AddDesc("projectName is required")
+		return
+	}
+	ctx.Resp, ctx.Err = service.GetFlakyTestCases(args.ProjectName, args.TestName, args.StartDate, args.EndDate, ctx.Logger)
+}
+
+func GetTestCaseDurationHistory(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(getTestReportStatReq)
+	if err := c.ShouldBindQuery(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	if args.ProjectName == "" || args.CaseName == "" {
+		ctx.Err = e.ErrInvalidParam.AddDesc("projectName and caseName are required")
+		return
+	}
+	ctx.Resp, ctx.Err = service.GetTestCaseDurationHistory(args.ProjectName, args.TestName, args.CaseName, args.StartDate, args.EndDate, ctx.Logger)
+}
+
 //func GetTestTrendOpenAPI(c *gin.Context) {
 //	ctx := internalhandler.NewContext(c)
 //	defer func() { internalhandler.JSONResponse(c, ctx) }()
@@ -581,3 +581,181 @@ func GetTestTrendMeasure(startDate, endDate int64, productNames []string, log *z
 
 	return testTrend, nil
 }
+
+type testCoveragePoint struct {
+	WorkflowName    string  `json:"workflow_name"`
+	TaskID          int64   `json:"task_id"`
+	JobName         string  `json:"job_name"`
+	TestName        string  `json:"test_name"`
+	LinesCovered    int     `json:"lines_covered"`
+	LinesValid      int     `json:"lines_valid"`
+	LineRate        float64 `json:"line_rate"`
+	BranchesCovered int     `json:"branches_covered"`
+	BranchesValid   int     `json:"branches_valid"`
+	BranchRate      float64 `json:"branch_rate"`
+	CreatedAt       int64   `json:"created_at"`
+}
+
+// GetTestCoverageTrend returns the coverage of the test reports in the order of creation, the reports without coverage are skipped
+func GetTestCoverageTrend(projectName, testName string, startDate, endDate int64, log *zap.SugaredLogger) ([]*testCoveragePoint, error) {
+	results, err := commonmongodb.NewTestReportResultColl().List(&commonmongodb.TestReportResultListOption{
+		ProjectName:  projectName,
+		TestName:     testName,
+		StartTime:    startDate,
+		EndTime:      endDate,
+		ExcludeCases: true,
+	})
+	if err != nil {
+		log.Errorf("ListTestReportResult err:%v", err)
+		return nil, fmt.Errorf("ListTestReportResult err:%v", err)
+	}
+	points := make([]*testCoveragePoint, 0)
+	for _, result := range results {
+		if result.Coverage == nil {
+			continue
+		}
+		points = append(points, &testCoveragePoint{
+			WorkflowName:    result.WorkflowName,
+			TaskID:          result.TaskID,
+			JobName:         result.JobName,
+			TestName:        result.TestName,
+			LinesCovered:    result.Coverage.LinesCovered,
+			LinesValid:      result.Coverage.LinesValid,
+			LineRate:        result.Coverage.LineRate,
+			BranchesCovered: result.Coverage.BranchesCovered,
+			BranchesValid:   result.Coverage.BranchesValid,
+			BranchRate:      result.Coverage.BranchRate,
+			CreatedAt:       result.CreatedAt,
+		})
+	}
+	return points, nil
+}
+
+type flakyTestCase struct {
+	TestName string `json:"test_name"`
+	CaseName string `json:"case_name"`
+	Runs     int    `json:"runs"`
+	Failures int    `json:"failures"`
+	// Flips is the count of the status changes between passed and failed in the consecutive runs
+	Flips int `json:"flips"`
+	// SameCommitFlips is the count of the status changes between the runs of the same commit
+	SameCommitFlips int `json:"same_commit_flips"`
+	// RetryPasses is the count of the runs passed after reruns, reported by the test runner
+	RetryPasses int     `json:"retry_passes"`
+	FlakyRate   float64 `json:"flaky_rate"`
+	LastStatus  string  `json:"last_status"`
+	LastRunAt   int64   `json:"last_run_at"`
+
+	withCommit bool
+}
+
+// GetFlakyTestCases returns the cases which passed after reruns or flipped between passed and failed without code change
+func GetFlakyTestCases(projectName, testName string, startDate, endDate int64, log *zap.SugaredLogger) ([]*flakyTestCase, error) {
+	results, err := commonmongodb.NewTestReportResultColl().List(&commonmongodb.TestReportResultListOption{
+		ProjectName: projectName,
+		TestName:    testName,
+		StartTime:   startDate,
+		EndTime:     endDate,
+	})
+	if err != nil {
+		log.Errorf("ListTestReportResult err:%v", err)
+		return nil, fmt.Errorf("ListTestReportResult err:%v", err)
+	}
+	return detectFlakyTestCases(results), nil
+}
+
+// detectFlakyTestCases expects the results in the order of creation. A case is flaky if its status changed on the same commit.
+// For the tests without repositories, a case failed once and fixed later is not flaky, so at least two flips are required.
+func detectFlakyTestCases(results []*commonmodels.TestReportResult) []*flakyTestCase {
+	caseMap := make(map[string]*flakyTestCase)
+	commitStatus := make(map[string]string)
+	keys := make([]string, 0)
+	for _, result := range results {
+		for _, c := range result.Cases {
+			if c.Status == step.TestCaseStatusSkipped {
+				continue
+			}
+			key := result.TestName + "/" + c.FullName()
+			flaky, ok := caseMap[key]
+			if !ok {
+				flaky = &flakyTestCase{TestName: result.TestName, CaseName: c.FullName()}
+				caseMap[key] = flaky
+				keys = append(keys, key)
+			}
+			if flaky.LastStatus != "" && flaky.LastStatus != c.Status {
+				flaky.Flips++
+			}
+			if result.CommitID != "" {
+				flaky.withCommit = true
+				commitKey := key + "@" + result.CommitID
+				if status, ok := commitStatus[commitKey]; ok && status != c.Status {
+					flaky.SameCommitFlips++
+				}
+				commitStatus[commitKey] = c.Status
+			}
+			flaky.Runs++
+			if c.Status == step.TestCaseStatusFailed {
+				flaky.Failures++
+			}
+			if c.Flaky {
+				flaky.RetryPasses++
+			}
+			flaky.LastStatus = c.Status
+			flaky.LastRunAt = result.CreatedAt
+		}
+	}
+
+	resp := make([]*flakyTestCase, 0)
+	for _, key := range keys {
+		flaky := caseMap[key]
+		if flaky.SameCommitFlips == 0 && flaky.RetryPasses == 0 && (flaky.withCommit || flaky.Flips < 2) {
+			continue
+		}
+		flaky.FlakyRate = float64(flaky.Flips+flaky.RetryPasses) / float64(flaky.Runs)
+		resp = append(resp, flaky)
+	}
+	sort.SliceStable(resp, func(i, j int) bool {
+		return resp[i].FlakyRate > resp[j].FlakyRate
+	})
+	return resp
+}
+
+type testCaseDurationPoint struct {
+	WorkflowName string  `json:"workflow_name"`
+	TaskID       int64   `json:"task_id"`
+	JobName      string  `json:"job_name"`
+	Status       string  `json:"status"`
+	Duration     float64 `json:"duration"`
+	CreatedAt    int64   `json:"created_at"`
+}
+
+// GetTestCaseDurationHistory returns the durations of the case in seconds, the case name is in the format of {suite}.{name}
+func GetTestCaseDurationHistory(projectName, testName, caseName string, startDate, endDate int64, log *zap.SugaredLogger) ([]*testCaseDurationPoint, error) {
+	results, err := commonmongodb.NewTestReportResultColl().List(&commonmongodb.TestReportResultListOption{
+		ProjectName: projectName,
+		TestName:    testName,
+		StartTime:   startDate,
+		EndTime:     endDate,
+	})
+	if err != nil {
+		log.Errorf("ListTestReportResult err:%v", err)
+		return nil, fmt.Errorf("ListTestReportResult err:%v", err)
+	}
+	points := make([]*testCaseDurationPoint, 0)
+	for _, result := range results {
+		for _, c := range result.Cases {
+			if c.FullName() != caseName {
+				continue
+			}
+			points = append(points, &testCaseDurationPoint{
+				WorkflowName: result.WorkflowName,
+				TaskID:       result.TaskID,
+				JobName:      result.JobName,
+				Status:       c.Status,
+				Duration:     c.Duration,
+				CreatedAt:    result.CreatedAt,
+			})
+		}
+	}
+	return points, nil
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/system/service"
+	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+// @Summary List artifact registries
+// @Description List artifact registries, the passwords are not returned
+// @Tags 	system
+// @Accept 	json
+// @Produce json
+// @Param 	type		query		string								false	"maven, npm, pypi or oci"
+// @Success 200 		{array} 	commonmodels.ArtifactRegistry
+// @Router /api/aslan/system/artifactregistry [get]
+func ListArtifactRegistries(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	ctx.Resp, ctx.Err = service.ListArtifactRegistries(c.Query("type"), ctx.Logger)
+}
+
+// @Summary Get an artifact registry
+// @Description Get an artifact registry, the password is not returned
+// @Tags 	system
+// @Accept 	json
+// @Produce json
+// @Param 	id			path		string								true	"id"
+// @Success 200 		{object} 	commonmodels.ArtifactRegistry
+// @Router /api/aslan/system/artifactregistry/{id} [get]
+func GetArtifactRegistry(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	ctx.Resp, ctx.Err = service.GetArtifactRegistry(c.Param("id"), ctx.Logger)
+}
+
+// @Summary Create an artifact registry
+// @Description Create an artifact registry
+// @Tags 	system
+// @Accept 	json
+// @Produce json
+// @Param 	body 			body 		commonmodels.ArtifactRegistry 			true 	"body"
+// @Success 200
+// @Router /api/aslan/system/artifactregistry [post]
+func CreateArtifactRegistry(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Logger.Errorf("failed to generate authorization info for user: %s, error: %s", ctx.UserID, err)
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+	if !ctx.Resources.IsSystemAdmin {
+		ctx.UnAuthorized = true
+		return
+	}
+
+	args := new(commonmodels.ArtifactRegistry)
+	if err := c.BindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc("invalid artifact registry json args")
+		return
+	}
+	// the request body contains the password, so it is not recorded
+	internalhandler.InsertOperationLog(c, ctx.UserName, "", "新增", "系统配置-制品仓库", fmt.Sprintf("name:%s", args.Name), "", ctx.Logger)
+
+	args.UpdatedBy = ctx.UserName
+	ctx.Err = service.CreateArtifactRegistry(args, ctx.Logger)
+}
+
+// @Summary Update an artifact registry
+// @Description Update an artifact registry, the password is kept if it is empty
+// @Tags 	system
+// @Accept 	json
+// @Produce json
+// @Param 	id				path		string							true	"id"
+// @Param 	body 			body 		commonmodels.ArtifactRegistry 	true 	"body"
+// @Success 200
+// @Router /api/aslan/system/artifactregistry/{id} [put]
+func UpdateArtifactRegistry(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Logger.Errorf("failed to generate authorization info for user: %s, error: %s", ctx.UserID, err)
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+	if !ctx.Resources.IsSystemAdmin {
+		ctx.UnAuthorized = true
+		return
+	}
+
+	args := new(commonmodels.ArtifactRegistry)
+	if err := c.BindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc("invalid artifact registry json args")
+		return
+	}
+	internalhandler.InsertOperationLog(c, ctx.UserName, "", "更新", "系统配置-制品仓库", fmt.Sprintf("name:%s", args.Name), "", ctx.Logger)
+
+	args.UpdatedBy = ctx.UserName
+	ctx.Err = service.UpdateArtifactRegistry(c.Param("id"), args, ctx.Logger)
+}
+
+// @Summary Delete an artifact registry
+// @Description Delete an artifact registry
+// @Tags 	system
+// @Accept 	json
+// @Produce json
+// @Param 	id				path		string							true	"id"
+// @Success 200
+// @Router /api/aslan/system/artifactregistry/{id} [delete]
+func DeleteArtifactRegistry(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Logger.Errorf("failed to generate authorization info for user: %s, error: %s", ctx.UserID, err)
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+	if !ctx.Resources.IsSystemAdmin {
+		ctx.UnAuthorized = true
+		return
+	}
+
+	internalhandler.InsertOperationLog(c, ctx.UserName, "", "删除", "系统配置-制品仓库", fmt.Sprintf("id:%s", c.Param("id")), "", ctx.Logger)
+	ctx.Err = service.DeleteArtifactRegistry(c.Param("id"), ctx.Logger)
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/system/service"
+	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+// @Summary List image signing keys
+// @Description List image signing keys, the private keys and passwords are not returned
+// @Tags 	system
+// @Accept 	json
+// @Produce json
+// @Success 200 		{array} 	commonmodels.ImageSigningKey
+// @Router /api/aslan/system/signingkey [get]
+func ListImageSigningKeys(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	ctx.Resp, ctx.Err = service.ListImageSigningKeys(ctx.Logger)
+}
+
+// @Summary Get an image signing key
+// @Description Get an image signing key, the private key and password are not returned
+// @Tags 	system
+// @Accept 	json
+// @Produce json
+// @Param 	id			path		string								true	"id"
+// @Success 200 		{object} 	commonmodels.ImageSigningKey
+// @Router /api/aslan/system/signingkey/{id} [get]
+func GetImageSigningKey(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	ctx.Resp, ctx.Err = service.GetImageSigningKey(c.Param("id"), ctx.Logger)
+}
+
+// @Summary Create an image signing key
+// @Description Create an image signing key
+// @Tags 	system
+// @Accept 	json
+// @Produce json
+// @Param 	body 			body 		commonmodels.ImageSigningKey 			true 	"body"
+// @Success 200
+// @Router /api/aslan/system/signingkey [post]
+func CreateImageSigningKey(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Logger.Errorf("failed to generate authorization info for user: %s, error: %s", ctx.UserID, err)
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+	if !ctx.Resources.IsSystemAdmin {
+		ctx.UnAuthorized = true
+		return
+	}
+
+	args := new(commonmodels.ImageSigningKey)
+	if err := c.BindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc("invalid image signing key json args")
+		return
+	}
+	// the request body contains the private key, so it is not recorded
+	internalhandler.InsertOperationLog(c, ctx.UserName, "", "新增", "系统配置-镜像签名密钥", fmt.Sprintf("name:%s", args.Name), "", ctx.Logger)
+
+	args.UpdatedBy = ctx.UserName
+	ctx.Err = service.CreateImageSigningKey(args, ctx.Logger)
+}
+
+// @Summary Update an image signing key
+// @Description Update an image signing key, the private key and password are kept if they are empty
+// @Tags 	system
+// @Accept 	json
+// @Produce json
+// @Param 	id				path		string							true	"id"
+// @Param 	body 			body 		commonmodels.ImageSigningKey 	true 	"body"
+// @Success 200
+// @Router /api/aslan/system/signingkey/{id} [put]
+func UpdateImageSigningKey(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Logger.Errorf("failed to generate authorization info for user: %s, error: %s", ctx.UserID, err)
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+	if !ctx.Resources.IsSystemAdmin {
+		ctx.UnAuthorized = true
+		return
+	}
+
+	args := new(commonmodels.ImageSigningKey)
+	if err := c.BindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc("invalid image signing key json args")
+		return
+	}
+	internalhandler.InsertOperationLog(c, ctx.UserName, "", "更新", "系统配置-镜像签名密钥", fmt.Sprintf("name:%s", args.Name), "", ctx.Logger)
+
+	args.UpdatedBy = ctx.UserName
+	ctx.Err = service.UpdateImageSigningKey(c.Param("id"), args, ctx.Logger)
+}
+
+// @Summary Delete an image signing key
+// @Description Delete an image signing key
+// @Tags 	system
+// @Accept 	json
+// @Produce json
+// @Param 	id				path		string							true	"id"
+// @Success 200
+// @Router /api/aslan/system/signingkey/{id} [delete]
+func DeleteImageSigningKey(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Logger.Errorf("failed to generate authorization info for user: %s, error: %s", ctx.UserID, err)
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+	if !ctx.Resources.IsSystemAdmin {
+		ctx.UnAuthorized = true
+		return
+	}
+
+	internalhandler.InsertOperationLog(c, ctx.UserName, "", "删除", "系统配置-镜像签名密钥", fmt.Sprintf("id:%s", c.Param("id")), "", ctx.Logger)
+	ctx.Err = service.DeleteImageSigningKey(c.Param("id"), ctx.Logger)
+}
@@ -372,6 +372,45 @@ func (*Router) Inject(router *gin.RouterGroup) {
 		llm.DELETE("/integration/:id", DeleteLLMIntegration)
 	}
 
+	// ---------------------------------------------------------------------------------------
+	// image signing key API
+	// ---------------------------------------------------------------------------------------
+	signingKey := router.Group("signingkey")
+	{
+		signingKey.GET("", ListImageSigningKeys)
+		signingKey.GET("/:id", GetImageSigningKey)
+		signingKey.POST("", CreateImageSigningKey)
+		signingKey.PUT("/:id", UpdateImageSigningKey)
+		signingKey.DELETE("/:id", DeleteImageSigningKey)
+	}
+
+	// ---------------------------------------------------------------------------------------
+	// artifact registry API
+	// ---------------------------------------------------------------------------------------
+	artifactRegistry := router.Group("artifactregistry")
+	{
+		artifactRegistry.GET("", ListArtifactRegistries)
+		artifactRegistry.GET("/:id", GetArtifactRegistry)
+		artifactRegistry.POST("", CreateArtifactRegistry)
+		artifactRegistry.PUT("/:id", UpdateArtifactRegistry)
+		artifactRegistry.DELETE("/:id", DeleteArtifactRegistry)
+	}
+
+	// ---------------------------------------------------------------------------------------
+	// vm runner, the agent APIs are authenticated by the runner token
+	// ---------------------------------------------------------------------------------------
+	vmRunner := router.Group("vmrunner")
+	{
+		vmRunner.GET("", ListVMRunners)
+		vmRunner.POST("", CreateVMRunner)
+		vmRunner.PUT("/:id", UpdateVMRunner)
+		vmRunner.DELETE("/:id", DeleteVMRunner)
+		vmRunner.POST("/agent/register", RegisterVMRunner)
+		vmRunner.POST("/agent/job/poll", PollVMJob)
+		vmRunner.POST("/agent/job/:id/log", AppendVMJobLog)
+		vmRunner.POST("/agent/job/:id/result", FinishVMJob)
+	}
+
 	// ---------------------------------------------------------------------------------------
 	// webhook config
 	// ---------------------------------------------------------------------------------------
@@ -0,0 +1,223 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/system/service"
+	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/types"
+)
+
+// @Summary List vm runners
+// @Description List vm runners, the status is online if the agent sent a heartbeat in the last minute
+// @Tags 	system
+// @Accept 	json
+// @Produce json
+// @Success 200 		{array} 	commonmodels.VMRunner
+// @Router /api/aslan/system/vmrunner [get]
+func ListVMRunners(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Logger.Errorf("failed to generate authorization info for user: %s, error: %s", ctx.UserID, err)
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+	// the hostnames and ips of the runners are only shown to the system admins
+	if !ctx.Resources.IsSystemAdmin {
+		ctx.UnAuthorized = true
+		return
+	}
+
+	ctx.Resp, ctx.Err = service.ListVMRunners(ctx.Logger)
+}
+
+// @Summary Create a vm runner
+// @Description Create a vm runner, the token of the agent is returned only once
+// @Tags 	system
+// @Accept 	json
+// @Produce json
+// @Param 	body 			body 		commonmodels.VMRunner 			true 	"body"
+// @Success 200 		{object} 	commonmodels.VMRunner
+// @Router /api/aslan/system/vmrunner [post]
+func CreateVMRunner(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Logger.Errorf("failed to generate authorization info for user: %s, error: %s", ctx.UserID, err)
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+	if !ctx.Resources.IsSystemAdmin {
+		ctx.UnAuthorized = true
+		return
+	}
+
+	args := new(commonmodels.VMRunner)
+	if err := c.BindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc("invalid vm runner json args")
+		return
+	}
+	internalhandler.InsertOperationLog(c, ctx.UserName, "", "新增", "系统配置-VM执行器", fmt.Sprintf("name:%s", args.Name), "", ctx.Logger)
+
+	args.UpdatedBy = ctx.UserName
+	ctx.Resp, ctx.Err = service.CreateVMRunner(args, ctx.Logger)
+}
+
+// @Summary Update a vm runner
+// @Description Update the name, description and labels of a vm runner
+// @Tags 	system
+// @Accept 	json
+// @Produce json
+// @Param 	id				path		string							true	"id"
+// @Param 	body 			body 		commonmodels.VMRunner 			true 	"body"
+// @Success 200
+// @Router /api/aslan/system/vmrunner/{id} [put]
+func UpdateVMRunner(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Logger.Errorf("failed to generate authorization info for user: %s, error: %s", ctx.UserID, err)
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+	if !ctx.Resources.IsSystemAdmin {
+		ctx.UnAuthorized = true
+		return
+	}
+
+	args := new(commonmodels.VMRunner)
+	if err := c.BindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc("invalid vm runner json args")
+		return
+	}
+	internalhandler.InsertOperationLog(c, ctx.UserName, "", "更新", "系统配置-VM执行器", fmt.Sprintf("name:%s", args.Name), "", ctx.Logger)
+
+	args.UpdatedBy = ctx.UserName
+	ctx.Err = service.UpdateVMRunner(c.Param("id"), args, ctx.Logger)
+}
+
+// @Summary Delete a vm runner
+// @Description Delete a vm runner
+// @Tags 	system
+// @Accept 	json
+// @Produce json
+// @Param 	id				path		string							true	"id"
+// @Success 200
+// @Router /api/aslan/system/vmrunner/{id} [delete]
+func DeleteVMRunner(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Logger.Errorf("failed to generate authorization info for user: %s, error: %s", ctx.UserID, err)
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+	if !ctx.Resources.IsSystemAdmin {
+		ctx.UnAuthorized = true
+		return
+	}
+
+	internalhandler.InsertOperationLog(c, ctx.UserName, "", "删除", "系统配置-VM执行器", fmt.Sprintf("id:%s", c.Param("id")), "", ctx.Logger)
+	ctx.Err = service.DeleteVMRunner(c.Param("id"), ctx.Logger)
+}
+
+// RegisterVMRunner is called by the runner agent when it starts, the agents authenticate with the runner token
+func RegisterVMRunner(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	runner, err := service.AuthVMRunner(c.GetHeader(types.VMRunnerTokenHeader))
+	if err != nil {
+		ctx.Err = err
+		ctx.UnAuthorized = true
+		return
+	}
+
+	args := new(types.VMRunnerRegisterArgs)
+	if err := c.BindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc("invalid vm runner register args")
+		return
+	}
+	ctx.Err = service.RegisterVMRunner(runner, args, ctx.Logger)
+}
+
+func PollVMJob(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	runner, err := service.AuthVMRunner(c.GetHeader(types.VMRunnerTokenHeader))
+	if err != nil {
+		ctx.Err = err
+		ctx.UnAuthorized = true
+		return
+	}
+
+	ctx.Resp, ctx.Err = service.PollVMJob(runner, ctx.Logger)
+}
+
+func AppendVMJobLog(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	runner, err := service.AuthVMRunner(c.GetHeader(types.VMRunnerTokenHeader))
+	if err != nil {
+		ctx.Err = err
+		ctx.UnAuthorized = true
+		return
+	}
+
+	args := new(types.VMJobLogArgs)
+	if err := c.BindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc("invalid vm job log args")
+		return
+	}
+	ctx.Resp, ctx.Err = service.AppendVMJobLog(runner, c.Param("id"), args, ctx.Logger)
+}
+
+func FinishVMJob(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	runner, err := service.AuthVMRunner(c.GetHeader(types.VMRunnerTokenHeader))
+	if err != nil {
+		ctx.Err = err
+		ctx.UnAuthorized = true
+		return
+	}
+
+	args := new(types.VMJobResultArgs)
+	if err := c.BindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc("invalid vm job result args")
+		return
+	}
+	ctx.Err = service.FinishVMJob(runner, c.Param("id"), args, ctx.Logger)
+}
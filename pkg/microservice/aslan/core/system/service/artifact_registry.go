@@ -0,0 +1,103 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/types/step"
+)
+
+// ListArtifactRegistries returns the artifact registries of the type without the passwords
+func ListArtifactRegistries(registryType string, log *zap.SugaredLogger) ([]*commonmodels.ArtifactRegistry, error) {
+	registries, err := commonrepo.NewArtifactRegistryColl().List(registryType)
+	if err != nil {
+		log.Errorf("failed to list artifact registries, error: %s", err)
+		return nil, e.ErrListArtifactRegistry.AddErr(err)
+	}
+	for _, registry := range registries {
+		registry.Password = ""
+	}
+	return registries, nil
+}
+
+func GetArtifactRegistry(id string, log *zap.SugaredLogger) (*commonmodels.ArtifactRegistry, error) {
+	registry, err := commonrepo.NewArtifactRegistryColl().FindByID(id)
+	if err != nil {
+		log.Errorf("failed to find artifact registry %s, error: %s", id, err)
+		return nil, e.ErrGetArtifactRegistry.AddErr(err)
+	}
+	registry.Password = ""
+	return registry, nil
+}
+
+func CreateArtifactRegistry(args *commonmodels.ArtifactRegistry, log *zap.SugaredLogger) error {
+	if err := checkArtifactRegistry(args); err != nil {
+		return e.ErrCreateArtifactRegistry.AddErr(err)
+	}
+	if err := commonrepo.NewArtifactRegistryColl().Create(args); err != nil {
+		log.Errorf("failed to create artifact registry %s, error: %s", args.Name, err)
+		return e.ErrCreateArtifactRegistry.AddErr(err)
+	}
+	return nil
+}
+
+// UpdateArtifactRegistry keeps the password if it is empty, since it is not returned to users
+func UpdateArtifactRegistry(id string, args *commonmodels.ArtifactRegistry, log *zap.SugaredLogger) error {
+	origin, err := commonrepo.NewArtifactRegistryColl().FindByID(id)
+	if err != nil {
+		log.Errorf("failed to find artifact registry %s, error: %s", id, err)
+		return e.ErrUpdateArtifactRegistry.AddErr(err)
+	}
+	if args.Password == "" {
+		args.Password = origin.Password
+	}
+	if err := checkArtifactRegistry(args); err != nil {
+		return e.ErrUpdateArtifactRegistry.AddErr(err)
+	}
+	if err := commonrepo.NewArtifactRegistryColl().Update(id, args); err != nil {
+		log.Errorf("failed to update artifact registry %s, error: %s", id, err)
+		return e.ErrUpdateArtifactRegistry.AddErr(err)
+	}
+	return nil
+}
+
+func DeleteArtifactRegistry(id string, log *zap.SugaredLogger) error {
+	if err := commonrepo.NewArtifactRegistryColl().Delete(id); err != nil {
+		log.Errorf("failed to delete artifact registry %s, error: %s", id, err)
+		return e.ErrDeleteArtifactRegistry.AddErr(err)
+	}
+	return nil
+}
+
+func checkArtifactRegistry(registry *commonmodels.ArtifactRegistry) error {
+	if registry.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if registry.Address == "" {
+		return fmt.Errorf("address is required")
+	}
+	if !step.IsValidArtifactType(registry.Type) {
+		return fmt.Errorf("unknown artifact registry type %s", registry.Type)
+	}
+	return nil
+}
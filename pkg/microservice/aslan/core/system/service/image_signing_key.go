@@ -0,0 +1,122 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/tool/cosign"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/types/step"
+)
+
+// ListImageSigningKeys returns the signing keys without the private keys and passwords
+func ListImageSigningKeys(log *zap.SugaredLogger) ([]*commonmodels.ImageSigningKey, error) {
+	keys, err := commonrepo.NewImageSigningKeyColl().List()
+	if err != nil {
+		log.Errorf("failed to list image signing keys, error: %s", err)
+		return nil, e.ErrListImageSigningKey.AddErr(err)
+	}
+	for _, key := range keys {
+		maskImageSigningKey(key)
+	}
+	return keys, nil
+}
+
+func GetImageSigningKey(id string, log *zap.SugaredLogger) (*commonmodels.ImageSigningKey, error) {
+	key, err := commonrepo.NewImageSigningKeyColl().FindByID(id)
+	if err != nil {
+		log.Errorf("failed to find image signing key %s, error: %s", id, err)
+		return nil, e.ErrGetImageSigningKey.AddErr(err)
+	}
+	maskImageSigningKey(key)
+	return key, nil
+}
+
+func CreateImageSigningKey(args *commonmodels.ImageSigningKey, log *zap.SugaredLogger) error {
+	if args.Mode == step.ImageSignModeKey && args.PrivateKey == "" {
+		return e.ErrCreateImageSigningKey.AddDesc("private key is required")
+	}
+	if err := checkImageSigningKey(args); err != nil {
+		return e.ErrCreateImageSigningKey.AddErr(err)
+	}
+	if err := commonrepo.NewImageSigningKeyColl().Create(args); err != nil {
+		log.Errorf("failed to create image signing key %s, error: %s", args.Name, err)
+		return e.ErrCreateImageSigningKey.AddErr(err)
+	}
+	return nil
+}
+
+// UpdateImageSigningKey keeps the private key and password if they are not changed, since they are not returned to users
+func UpdateImageSigningKey(id string, args *commonmodels.ImageSigningKey, log *zap.SugaredLogger) error {
+	origin, err := commonrepo.NewImageSigningKeyColl().FindByID(id)
+	if err != nil {
+		log.Errorf("failed to find image signing key %s, error: %s", id, err)
+		return e.ErrUpdateImageSigningKey.AddErr(err)
+	}
+	if args.PrivateKey == "" {
+		args.PrivateKey = origin.PrivateKey
+		if args.Password == "" {
+			args.Password = origin.Password
+		}
+	}
+	if err := checkImageSigningKey(args); err != nil {
+		return e.ErrUpdateImageSigningKey.AddErr(err)
+	}
+	if err := commonrepo.NewImageSigningKeyColl().Update(id, args); err != nil {
+		log.Errorf("failed to update image signing key %s, error: %s", id, err)
+		return e.ErrUpdateImageSigningKey.AddErr(err)
+	}
+	return nil
+}
+
+func DeleteImageSigningKey(id string, log *zap.SugaredLogger) error {
+	if err := commonrepo.NewImageSigningKeyColl().Delete(id); err != nil {
+		log.Errorf("failed to delete image signing key %s, error: %s", id, err)
+		return e.ErrDeleteImageSigningKey.AddErr(err)
+	}
+	return nil
+}
+
+// checkImageSigningKey makes sure the signatures created with the key can be verified by the deploy jobs
+func checkImageSigningKey(key *commonmodels.ImageSigningKey) error {
+	if key.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	switch key.Mode {
+	case step.ImageSignModeKey:
+		if _, err := cosign.NewKeyVerifier(key.Name, key.PublicKey); err != nil {
+			return err
+		}
+	case step.ImageSignModeKeyless:
+		if _, err := cosign.NewCertificateVerifier(key.Name, key.FulcioRootCA, key.RekorPublicKey, key.Identity, key.OIDCIssuer); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown signing mode %s", key.Mode)
+	}
+	return nil
+}
+
+func maskImageSigningKey(key *commonmodels.ImageSigningKey) {
+	key.PrivateKey = ""
+	key.Password = ""
+}
@@ -23,6 +23,7 @@ import (
 	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
 	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
 	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/tool/llm"
 	"github.com/koderover/zadig/pkg/tool/log"
 )
 
@@ -73,6 +74,9 @@ func CreateLLMIntegration(ctx context.Context, args *commonmodels.LLMIntegration
 	if count > 0 {
 		return e.ErrCreateLLMIntegration.AddDesc("llm integration already exists")
 	}
+	if _, err := llm.NewClient(args.GetProvider()); err != nil {
+		return e.ErrCreateLLMIntegration.AddErr(err)
+	}
 
 	if err := commonrepo.NewLLMIntegrationColl().Create(ctx, args); err != nil {
 		fmtErr := fmt.Errorf("CreateLLMIntegration err: %w", err)
@@ -83,6 +87,9 @@ func CreateLLMIntegration(ctx context.Context, args *commonmodels.LLMIntegration
 }
 
 func UpdateLLMIntegration(ctx context.Context, ID string, args *commonmodels.LLMIntegration) error {
+	if _, err := llm.NewClient(args.GetProvider()); err != nil {
+		return e.ErrUpdateLLMIntegration.AddErr(err)
+	}
 	if err := commonrepo.NewLLMIntegrationColl().Update(ctx, ID, args); err != nil {
 		fmtErr := fmt.Errorf("UpdateLLMIntegration err: %w", err)
 		log.Error(fmtErr)
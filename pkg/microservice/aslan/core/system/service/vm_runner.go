@@ -0,0 +1,214 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/types"
+)
+
+const (
+	VMRunnerStatusOnline  = "online"
+	VMRunnerStatusOffline = "offline"
+
+	// vmRunnerOnlineTimeout is how long a runner is online after its last heartbeat
+	vmRunnerOnlineTimeout = time.Minute
+)
+
+func ListVMRunners(log *zap.SugaredLogger) ([]*commonmodels.VMRunner, error) {
+	runners, err := commonrepo.NewVMRunnerColl().List()
+	if err != nil {
+		log.Errorf("failed to list vm runners, error: %s", err)
+		return nil, e.ErrListVMRunner.AddErr(err)
+	}
+	for _, runner := range runners {
+		runner.Status = getVMRunnerStatus(runner)
+	}
+	return runners, nil
+}
+
+// CreateVMRunner creates the runner with a new token, the token is returned only once
+func CreateVMRunner(args *commonmodels.VMRunner, log *zap.SugaredLogger) (*commonmodels.VMRunner, error) {
+	if err := checkVMRunner(args); err != nil {
+		return nil, e.ErrCreateVMRunner.AddErr(err)
+	}
+	token, err := generateVMRunnerToken()
+	if err != nil {
+		log.Errorf("failed to generate token for vm runner %s, error: %s", args.Name, err)
+		return nil, e.ErrCreateVMRunner.AddErr(err)
+	}
+	args.TokenHash = hashVMRunnerToken(token)
+	if err := commonrepo.NewVMRunnerColl().Create(args); err != nil {
+		log.Errorf("failed to create vm runner %s, error: %s", args.Name, err)
+		return nil, e.ErrCreateVMRunner.AddErr(err)
+	}
+	args.Token = token
+	return args, nil
+}
+
+// UpdateVMRunner updates the name, description and labels of the runner, the token is not changed
+func UpdateVMRunner(id string, args *commonmodels.VMRunner, log *zap.SugaredLogger) error {
+	if err := checkVMRunner(args); err != nil {
+		return e.ErrUpdateVMRunner.AddErr(err)
+	}
+	if err := commonrepo.NewVMRunnerColl().Update(id, args); err != nil {
+		log.Errorf("failed to update vm runner %s, error: %s", id, err)
+		return e.ErrUpdateVMRunner.AddErr(err)
+	}
+	return nil
+}
+
+func DeleteVMRunner(id string, log *zap.SugaredLogger) error {
+	if err := commonrepo.NewVMRunnerColl().Delete(id); err != nil {
+		log.Errorf("failed to delete vm runner %s, error: %s", id, err)
+		return e.ErrDeleteVMRunner.AddErr(err)
+	}
+	return nil
+}
+
+// AuthVMRunner returns the runner of the token sent by the agent
+func AuthVMRunner(token string) (*commonmodels.VMRunner, error) {
+	if token == "" {
+		return nil, fmt.Errorf("vm runner token is required")
+	}
+	runner, err := commonrepo.NewVMRunnerColl().FindByTokenHash(hashVMRunnerToken(token))
+	if err != nil {
+		return nil, fmt.Errorf("invalid vm runner token")
+	}
+	return runner, nil
+}
+
+// RegisterVMRunner records the host of the runner when its agent starts
+func RegisterVMRunner(runner *commonmodels.VMRunner, args *types.VMRunnerRegisterArgs, log *zap.SugaredLogger) error {
+	if err := commonrepo.NewVMRunnerColl().UpdateHost(runner.ID, args.Hostname, args.IP, args.OS, args.Arch, args.Version); err != nil {
+		log.Errorf("failed to register vm runner %s, error: %s", runner.Name, err)
+		return e.ErrRegisterVMRunner.AddErr(err)
+	}
+	log.Infof("vm runner %s is registered from %s(%s)", runner.Name, args.Hostname, args.IP)
+	return nil
+}
+
+// PollVMJob assigns a queued job matching the labels of the runner to it, it also works as the heartbeat of the runner
+func PollVMJob(runner *commonmodels.VMRunner, log *zap.SugaredLogger) (*types.VMJobPollResp, error) {
+	if err := commonrepo.NewVMRunnerColl().Heartbeat(runner.ID); err != nil {
+		log.Errorf("failed to update the heartbeat of vm runner %s, error: %s", runner.Name, err)
+	}
+	vmJob, err := commonrepo.NewVMJobColl().Poll(runner)
+	if err != nil {
+		log.Errorf("vm runner %s failed to poll job, error: %s", runner.Name, err)
+		return nil, e.ErrPollVMJob.AddErr(err)
+	}
+	resp := &types.VMJobPollResp{}
+	if vmJob != nil {
+		log.Infof("vm job %s of %s/%d/%s is assigned to vm runner %s", vmJob.ID.Hex(), vmJob.WorkflowName, vmJob.TaskID, vmJob.JobName, runner.Name)
+		resp.Job = &types.VMJob{ID: vmJob.ID.Hex(), JobCtx: vmJob.JobCtx}
+	}
+	return resp, nil
+}
+
+// AppendVMJobLog saves the log sent by the runner, the runner stops the job if it is cancelled or timed out
+func AppendVMJobLog(runner *commonmodels.VMRunner, jobID string, args *types.VMJobLogArgs, log *zap.SugaredLogger) (*types.VMJobLogResp, error) {
+	vmJob, err := findRunnerVMJob(runner, jobID)
+	if err != nil {
+		return nil, e.ErrUpdateVMJob.AddErr(err)
+	}
+	if err := commonrepo.NewVMRunnerColl().Heartbeat(runner.ID); err != nil {
+		log.Errorf("failed to update the heartbeat of vm runner %s, error: %s", runner.Name, err)
+	}
+	if args.Content != "" {
+		if err := commonrepo.NewVMJobLogColl().Upsert(&commonmodels.VMJobLog{JobID: jobID, Seq: args.Seq, Content: args.Content}); err != nil {
+			log.Errorf("failed to save the log of vm job %s, error: %s", jobID, err)
+			return nil, e.ErrUpdateVMJob.AddErr(err)
+		}
+	}
+	return &types.VMJobLogResp{Cancelled: vmJob.Status != string(config.StatusRunning)}, nil
+}
+
+// FinishVMJob records the result of the job, the result is ignored if the job is already cancelled or timed out
+func FinishVMJob(runner *commonmodels.VMRunner, jobID string, args *types.VMJobResultArgs, log *zap.SugaredLogger) error {
+	vmJob, err := findRunnerVMJob(runner, jobID)
+	if err != nil {
+		return e.ErrUpdateVMJob.AddErr(err)
+	}
+	status := config.StatusFailed
+	if args.Result == types.JobSuccess {
+		status = config.StatusPassed
+	}
+	finished, err := commonrepo.NewVMJobColl().Finish(vmJob.ID, status, args.Outputs, args.ExitCode, args.Error)
+	if err != nil {
+		log.Errorf("failed to finish vm job %s, error: %s", jobID, err)
+		return e.ErrUpdateVMJob.AddErr(err)
+	}
+	if !finished {
+		log.Warnf("vm job %s is already %s, the result %s of vm runner %s is ignored", jobID, vmJob.Status, status, runner.Name)
+	}
+	return nil
+}
+
+func findRunnerVMJob(runner *commonmodels.VMRunner, jobID string) (*commonmodels.VMJob, error) {
+	vmJob, err := commonrepo.NewVMJobColl().FindByID(jobID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find vm job %s: %s", jobID, err)
+	}
+	if vmJob.RunnerID != runner.ID.Hex() {
+		return nil, fmt.Errorf("vm job %s is not assigned to vm runner %s", jobID, runner.Name)
+	}
+	return vmJob, nil
+}
+
+func getVMRunnerStatus(runner *commonmodels.VMRunner) string {
+	if time.Since(time.Unix(runner.LastHeartbeatTime, 0)) < vmRunnerOnlineTimeout {
+		return VMRunnerStatusOnline
+	}
+	return VMRunnerStatusOffline
+}
+
+func checkVMRunner(runner *commonmodels.VMRunner) error {
+	if runner.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	for _, label := range runner.Labels {
+		if label == "" {
+			return fmt.Errorf("empty label is not allowed")
+		}
+	}
+	return nil
+}
+
+func generateVMRunnerToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func hashVMRunnerToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
@@ -233,8 +233,11 @@ func (*Router) Inject(router *gin.RouterGroup) {
 		taskV4.POST("/breakpoint/:workflowName/:jobName/task/:taskID/:position", SetWorkflowTaskV4Breakpoint)
 		taskV4.POST("/debug/:workflowName/task/:taskID", EnableDebugWorkflowTaskV4)
 		taskV4.DELETE("/debug/:workflowName/:jobName/task/:taskID/:position", StopDebugWorkflowTaskJobV4)
+		taskV4.GET("/debug/:workflowName/task/:taskID/session", ListDebugSessionsV4)
+		taskV4.GET("/debug/:workflowName/session/:id", GetDebugSessionV4)
 		taskV4.POST("/approve", ApproveStage)
 		taskV4.GET("/workflow/:workflowName/taskId/:taskId/job/:jobName", GetWorkflowV4ArtifactFileContent)
+		taskV4.POST("/diagnosis/workflow/:workflowName/task/:taskID/job/:jobName", DiagnoseWorkflowTaskV4Job)
 		taskV4.POST("/trigger", CreateWorkflowTaskV4ByBuildInTrigger)
 	}
 
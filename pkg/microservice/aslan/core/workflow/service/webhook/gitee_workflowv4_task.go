@@ -90,10 +90,11 @@ func (gpem *giteePushEventMatcherForWorkflowV4) GetHookRepo(hookRepo *commonmode
 }
 
 type giteeMergeEventMatcherForWorkflowV4 struct {
-	diffFunc giteePullRequestDiffFunc
-	log      *zap.SugaredLogger
-	workflow *commonmodels.WorkflowV4
-	event    *gitee.PullRequestEvent
+	diffFunc     giteePullRequestDiffFunc
+	log          *zap.SugaredLogger
+	workflow     *commonmodels.WorkflowV4
+	event        *gitee.PullRequestEvent
+	changedFiles []string
 }
 
 func (gmem *giteeMergeEventMatcherForWorkflowV4) Match(hookRepo *commonmodels.MainHookRepo) (bool, error) {
@@ -123,6 +124,7 @@ func (gmem *giteeMergeEventMatcherForWorkflowV4) Match(hookRepo *commonmodels.Ma
 				return false, err
 			}
 			gmem.log.Debugf("succeed to get %d changes in merge event", len(changedFiles))
+			gmem.changedFiles = changedFiles
 
 			return MatchChanges(hookRepo, changedFiles), nil
 		}
@@ -205,6 +207,9 @@ func createGiteeEventMatcherForWorkflowV4(
 }
 
 func TriggerWorkflowV4ByGiteeEvent(event interface{}, baseURI, requestID string, log *zap.SugaredLogger) error {
+	if ev, ok := event.(*gitee.PullRequestEvent); ok && (ev.PullRequest.State == "closed" || ev.PullRequest.State == "merged") {
+		deletePreviewEnvsOfClosedPR(ev.PullRequest.Base.Repo.FullName, ev.PullRequest.Number, requestID, log)
+	}
 	workflows, _, err := commonrepo.NewWorkflowV4Coll().List(&commonrepo.ListWorkflowV4Option{}, 0, 0)
 	if err != nil {
 		errMsg := fmt.Sprintf("list workflow v4 error: %v", err)
@@ -272,6 +277,9 @@ func TriggerWorkflowV4ByGiteeEvent(event interface{}, baseURI, requestID string,
 					CommitID:       commitID,
 					EventType:      eventType,
 				}
+				if m, ok := matcher.(*giteeMergeEventMatcherForWorkflowV4); ok {
+					hookPayload.ChangedFiles = m.changedFiles
+				}
 			case *gitee.PushEvent:
 				eventType = EventTypePush
 				ref = ev.Ref
@@ -325,6 +333,14 @@ func TriggerWorkflowV4ByGiteeEvent(event interface{}, baseURI, requestID string,
 				workflow.NotificationID = notification.ID.Hex()
 			}
 			workflow.HookPayload = hookPayload
+			if eventType == EventTypePR && item.PreviewEnv != nil && item.PreviewEnv.Enabled {
+				if err := triggerPreviewEnvTask(workflow, item.PreviewEnv, eventRepo, hookPayload.ChangedFiles, baseURI, requestID, log); err != nil {
+					errMsg := fmt.Sprintf("failed to trigger preview environment of workflow %s due to %v ", workflow.Name, err)
+					log.Error(errMsg)
+					mErr = multierror.Append(mErr, fmt.Errorf(errMsg))
+				}
+				continue
+			}
 			if resp, err := workflowservice.CreateWorkflowTaskV4(&workflowservice.CreateWorkflowTaskV4Args{
 				Name: setting.WebhookTaskCreator,
 			}, workflow, log); err != nil {
@@ -92,10 +92,11 @@ func (gpem *githubPushEventMatcheForWorkflowV4) GetHookRepo(hookRepo *commonmode
 }
 
 type githubMergeEventMatcherForWorkflowV4 struct {
-	diffFunc githubPullRequestDiffFunc
-	log      *zap.SugaredLogger
-	workflow *commonmodels.WorkflowV4
-	event    *github.PullRequestEvent
+	diffFunc     githubPullRequestDiffFunc
+	log          *zap.SugaredLogger
+	workflow     *commonmodels.WorkflowV4
+	event        *github.PullRequestEvent
+	changedFiles []string
 }
 
 func (gmem *githubMergeEventMatcherForWorkflowV4) Match(hookRepo *commonmodels.MainHookRepo) (bool, error) {
@@ -128,6 +129,7 @@ func (gmem *githubMergeEventMatcherForWorkflowV4) Match(hookRepo *commonmodels.M
 			return false, err
 		}
 		gmem.log.Debugf("succeed to get %d changes in merge event", len(changedFiles))
+		gmem.changedFiles = changedFiles
 
 		return MatchChanges(hookRepo, changedFiles), nil
 	}
@@ -215,6 +217,9 @@ func createGithubEventMatcherForWorkflowV4(
 }
 
 func TriggerWorkflowV4ByGithubEvent(event interface{}, baseURI, deliveryID, requestID string, log *zap.SugaredLogger) error {
+	if ev, ok := event.(*github.PullRequestEvent); ok && ev.GetAction() == "closed" {
+		deletePreviewEnvsOfClosedPR(ev.GetPullRequest().GetBase().GetRepo().GetFullName(), ev.GetPullRequest().GetNumber(), requestID, log)
+	}
 	workflows, _, err := commonrepo.NewWorkflowV4Coll().List(&commonrepo.ListWorkflowV4Option{}, 0, 0)
 	if err != nil {
 		errMsg := fmt.Sprintf("list workflow v4 error: %v", err)
@@ -278,6 +283,9 @@ func TriggerWorkflowV4ByGithubEvent(event interface{}, baseURI, deliveryID, requ
 					CommitID:       commitID,
 					EventType:      eventType,
 				}
+				if m, ok := matcher.(*githubMergeEventMatcherForWorkflowV4); ok {
+					hookPayload.ChangedFiles = m.changedFiles
+				}
 			case *github.PushEvent:
 				if ev.GetRef() != "" && ev.GetHeadCommit().GetID() != "" {
 					eventType = EventTypePush
@@ -326,6 +334,14 @@ func TriggerWorkflowV4ByGithubEvent(event interface{}, baseURI, deliveryID, requ
 				continue
 			}
 			workflow.HookPayload = hookPayload
+			if eventType == EventTypePR && item.PreviewEnv != nil && item.PreviewEnv.Enabled {
+				if err := triggerPreviewEnvTask(workflow, item.PreviewEnv, eventRepo, hookPayload.ChangedFiles, baseURI, requestID, log); err != nil {
+					errMsg := fmt.Sprintf("failed to trigger preview environment of workflow %s due to %v ", workflow.Name, err)
+					log.Error(errMsg)
+					mErr = multierror.Append(mErr, fmt.Errorf(errMsg))
+				}
+				continue
+			}
 			if resp, err := workflowservice.CreateWorkflowTaskV4(&workflowservice.CreateWorkflowTaskV4Args{
 				Name: setting.WebhookTaskCreator,
 			}, workflow, log); err != nil {
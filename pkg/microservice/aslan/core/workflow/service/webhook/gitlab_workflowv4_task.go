@@ -46,6 +46,7 @@ type gitlabMergeEventMatcherForWorkflowV4 struct {
 	trigger            *TriggerYaml
 	isYaml             bool
 	yamlServiceChanged []BuildServices
+	changedFiles       []string
 }
 
 func (gmem *gitlabMergeEventMatcherForWorkflowV4) Match(hookRepo *commonmodels.MainHookRepo) (bool, error) {
@@ -89,6 +90,7 @@ func (gmem *gitlabMergeEventMatcherForWorkflowV4) Match(hookRepo *commonmodels.M
 			return false, err
 		}
 		gmem.log.Debugf("succeed to get %d changes in merge event", len(changedFiles))
+		gmem.changedFiles = changedFiles
 		if gmem.isYaml {
 			serviceChangeds := ServicesMatchChangesFiles(gmem.trigger.Rules.MatchFolders, changedFiles)
 			gmem.yamlServiceChanged = serviceChangeds
@@ -272,6 +274,9 @@ func (gpem *gitlabTagEventMatcherForWorkflowV4) GetHookRepo(hookRepo *commonmode
 }
 
 func TriggerWorkflowV4ByGitlabEvent(event interface{}, baseURI, requestID string, log *zap.SugaredLogger) error {
+	if ev, ok := event.(*gitlab.MergeEvent); ok && (ev.ObjectAttributes.State == "closed" || ev.ObjectAttributes.State == "merged") {
+		deletePreviewEnvsOfClosedPR(ev.ObjectAttributes.Target.PathWithNamespace, ev.ObjectAttributes.IID, requestID, log)
+	}
 	// TODO: cache workflow
 	// 1. find configured workflow
 	workflows, _, err := commonrepo.NewWorkflowV4Coll().List(&commonrepo.ListWorkflowV4Option{}, 0, 0)
@@ -363,6 +368,9 @@ func TriggerWorkflowV4ByGitlabEvent(event interface{}, baseURI, requestID string
 					CodehostID:     eventRepo.CodehostID,
 					EventType:      eventType,
 				}
+				if m, ok := matcher.(*gitlabMergeEventMatcherForWorkflowV4); ok {
+					hookPayload.ChangedFiles = m.changedFiles
+				}
 			case *gitlab.PushEvent:
 				eventType = EventTypePush
 				ref = ev.Ref
@@ -415,6 +423,14 @@ func TriggerWorkflowV4ByGitlabEvent(event interface{}, baseURI, requestID string
 				workflow.NotificationID = notification.ID.Hex()
 			}
 			workflow.HookPayload = hookPayload
+			if eventType == EventTypePR && item.PreviewEnv != nil && item.PreviewEnv.Enabled {
+				if err := triggerPreviewEnvTask(workflow, item.PreviewEnv, eventRepo, hookPayload.ChangedFiles, baseURI, requestID, log); err != nil {
+					errMsg := fmt.Sprintf("failed to trigger preview environment of workflow %s due to %v ", workflow.Name, err)
+					log.Error(errMsg)
+					mErr = multierror.Append(mErr, fmt.Errorf(errMsg))
+				}
+				continue
+			}
 			if resp, err := workflowservice.CreateWorkflowTaskV4(&workflowservice.CreateWorkflowTaskV4Args{
 				Name: setting.WebhookTaskCreator,
 			}, workflow, log); err != nil {
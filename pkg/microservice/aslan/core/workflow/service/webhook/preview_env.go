@@ -0,0 +1,247 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/scmnotify"
+	environmentservice "github.com/koderover/zadig/pkg/microservice/aslan/core/environment/service"
+	workflowservice "github.com/koderover/zadig/pkg/microservice/aslan/core/workflow/service/workflow"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/workflow/service/workflow/job"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/testimpact"
+	"github.com/koderover/zadig/pkg/types"
+	"github.com/koderover/zadig/pkg/util"
+)
+
+// triggerPreviewEnvTask creates the preview environment of the pull request if it does not exist yet, and creates a
+// task of the workflow which builds the services changed by the pull request and deploys them to it.
+// The task is created in the background once the preview environment is ready.
+func triggerPreviewEnvTask(workflow *commonmodels.WorkflowV4, cfg *commonmodels.PreviewEnvConfig, repo *types.Repository, changedFiles []string, baseURI, requestID string, log *zap.SugaredLogger) error {
+	if cfg.BaseEnv == "" {
+		return fmt.Errorf("base environment of the preview environment of workflow %s is not set", workflow.Name)
+	}
+	buildServices, err := job.ListRepoBuildServices(workflow, repo)
+	if err != nil {
+		return fmt.Errorf("failed to list services built from %s/%s: %s", repo.GetRepoNamespace(), repo.RepoName, err)
+	}
+	services := changedPreviewServices(cfg, buildServices, changedFiles)
+
+	coll := commonrepo.NewPreviewEnvColl()
+	previewEnv, err := coll.Find(&commonrepo.PreviewEnvFindOption{
+		ProjectName:   workflow.Project,
+		WorkflowName:  workflow.Name,
+		RepoNamespace: repo.GetRepoNamespace(),
+		RepoName:      repo.RepoName,
+		PrID:          repo.PR,
+	})
+	created := false
+	if err == mongo.ErrNoDocuments {
+		envName := fmt.Sprintf("pr-%d-%s", repo.PR, strings.ToLower(util.GetRandomString(6)))
+		previewEnv = &commonmodels.PreviewEnv{
+			ProjectName:   workflow.Project,
+			WorkflowName:  workflow.Name,
+			EnvName:       envName,
+			BaseEnv:       cfg.BaseEnv,
+			CodehostID:    repo.CodehostID,
+			RepoOwner:     repo.RepoOwner,
+			RepoNamespace: repo.GetRepoNamespace(),
+			RepoName:      repo.RepoName,
+			PrID:          repo.PR,
+			Status:        config.PreviewEnvStatusCreating,
+			IdleTTL:       cfg.IdleTTL,
+			BaseURI:       baseURI,
+		}
+		namespace := commonservice.GetProductEnvNamespace(previewEnv.EnvName, previewEnv.ProjectName, "")
+		previewEnv.AccessURL, err = renderPreviewEnvURL(cfg.URLTemplate, previewEnv, namespace)
+		if err != nil {
+			log.Warnf("failed to render the access url of preview environment %s: %s", envName, err)
+		}
+		if err := coll.Create(previewEnv); err != nil {
+			return fmt.Errorf("failed to create preview env of %s/%s#%d: %s", repo.GetRepoNamespace(), repo.RepoName, repo.PR, err)
+		}
+		created = true
+	} else if err != nil {
+		return fmt.Errorf("failed to find preview env of %s/%s#%d: %s", repo.GetRepoNamespace(), repo.RepoName, repo.PR, err)
+	}
+
+	previewEnv.Services = services.List()
+	if err := coll.Touch(previewEnv.ID, previewEnv.Services); err != nil {
+		log.Errorf("failed to update preview env %s: %s", previewEnv.EnvName, err)
+	}
+
+	if created {
+		log.Infof("create preview environment %s/%s for %s/%s#%d", workflow.Project, previewEnv.EnvName, repo.GetRepoNamespace(), repo.RepoName, repo.PR)
+		if err := environmentservice.CreatePreviewEnv(previewEnv, requestID, log); err != nil {
+			updatePreviewEnvStatus(previewEnv, config.PreviewEnvStatusFailed, err.Error(), log)
+			return err
+		}
+	}
+	// the workflow is reused by the other hooks, the task is created from a copy of it
+	taskWorkflow := new(commonmodels.WorkflowV4)
+	if err := commonmodels.IToi(workflow, taskWorkflow); err != nil {
+		return fmt.Errorf("failed to copy workflow %s: %s", workflow.Name, err)
+	}
+	if err := job.SetPreviewEnv(taskWorkflow, previewEnv.EnvName, services); err != nil {
+		updatePreviewEnvStatus(previewEnv, config.PreviewEnvStatusFailed, err.Error(), log)
+		return err
+	}
+	if err := scmnotify.NewService().UpdatePreviewEnvComment(previewEnv, log); err != nil {
+		log.Warnf("failed to comment preview environment %s: %s", previewEnv.EnvName, err)
+	}
+	if services.Len() == 0 {
+		log.Infof("no service of preview environment %s is changed", previewEnv.EnvName)
+		if created {
+			go waitPreviewEnvReady(previewEnv, log)
+		}
+		return nil
+	}
+
+	go func() {
+		if !waitPreviewEnvReady(previewEnv, log) {
+			return
+		}
+		if resp, err := workflowservice.CreateWorkflowTaskV4(&workflowservice.CreateWorkflowTaskV4Args{
+			Name: setting.WebhookTaskCreator,
+		}, taskWorkflow, log); err != nil {
+			log.Errorf("failed to create workflow task for preview environment %s: %s", previewEnv.EnvName, err)
+		} else {
+			log.Infof("succeed to create task %v for preview environment %s", resp, previewEnv.EnvName)
+		}
+	}()
+	return nil
+}
+
+// waitPreviewEnvReady waits for the creation of the preview environment and comments its status on the pull request
+func waitPreviewEnvReady(previewEnv *commonmodels.PreviewEnv, log *zap.SugaredLogger) bool {
+	if previewEnv.Status == config.PreviewEnvStatusRunning {
+		return true
+	}
+
+	timeout := time.After(time.Duration(config.ServiceStartTimeout()) * time.Second)
+	for {
+		select {
+		case <-timeout:
+			updatePreviewEnvStatus(previewEnv, config.PreviewEnvStatusFailed, "timed out waiting for the environment to be created", log)
+			return false
+		case <-time.After(3 * time.Second):
+		}
+
+		product, err := commonrepo.NewProductColl().Find(&commonrepo.ProductFindOptions{
+			Name:    previewEnv.ProjectName,
+			EnvName: previewEnv.EnvName,
+		})
+		if err != nil {
+			log.Errorf("failed to find preview environment %s: %s", previewEnv.EnvName, err)
+			continue
+		}
+		switch product.Status {
+		case setting.ProductStatusCreating:
+			continue
+		case setting.ProductStatusFailed:
+			updatePreviewEnvStatus(previewEnv, config.PreviewEnvStatusFailed, product.Error, log)
+			return false
+		default:
+			updatePreviewEnvStatus(previewEnv, config.PreviewEnvStatusRunning, "", log)
+			return true
+		}
+	}
+}
+
+func updatePreviewEnvStatus(previewEnv *commonmodels.PreviewEnv, status, errMsg string, log *zap.SugaredLogger) {
+	previewEnv.Status = status
+	previewEnv.Error = errMsg
+	if err := commonrepo.NewPreviewEnvColl().UpdateStatus(previewEnv.ID, status, errMsg); err != nil {
+		log.Errorf("failed to update status of preview env %s: %s", previewEnv.EnvName, err)
+	}
+	if err := scmnotify.NewService().UpdatePreviewEnvComment(previewEnv, log); err != nil {
+		log.Warnf("failed to comment preview environment %s: %s", previewEnv.EnvName, err)
+	}
+}
+
+// changedPreviewServices returns the keys of the services built from the repo which are changed by the changed files
+func changedPreviewServices(cfg *commonmodels.PreviewEnvConfig, buildServices []*commonmodels.ServiceAndBuild, changedFiles []string) sets.String {
+	pathMap := make(map[string][]string)
+	for _, servicePath := range cfg.ServicePaths {
+		pathMap[job.PreviewServiceKey(servicePath.ServiceName, servicePath.ServiceModule)] = servicePath.Paths
+	}
+
+	resp := sets.NewString()
+	for _, build := range buildServices {
+		key := job.PreviewServiceKey(build.ServiceName, build.ServiceModule)
+		if paths := pathMap[key]; len(paths) == 0 || matchChangedFiles(paths, changedFiles) {
+			resp.Insert(key)
+		}
+	}
+	return resp
+}
+
+func matchChangedFiles(paths, changedFiles []string) bool {
+	for _, file := range changedFiles {
+		for _, path := range paths {
+			if testimpact.Match(path, file) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func renderPreviewEnvURL(urlTemplate string, previewEnv *commonmodels.PreviewEnv, namespace string) (string, error) {
+	if urlTemplate == "" {
+		return "", nil
+	}
+	tmpl, err := template.New("url").Parse(urlTemplate)
+	if err != nil {
+		return "", err
+	}
+	buf := bytes.NewBufferString("")
+	err = tmpl.Execute(buf, struct {
+		EnvName   string
+		Namespace string
+		PR        int
+	}{
+		EnvName:   previewEnv.EnvName,
+		Namespace: namespace,
+		PR:        previewEnv.PrID,
+	})
+	return buf.String(), err
+}
+
+// deletePreviewEnvsOfClosedPR deletes the preview environments of the pull request once it is closed or merged
+func deletePreviewEnvsOfClosedPR(repoFullName string, prID int, requestID string, log *zap.SugaredLogger) {
+	index := strings.LastIndex(repoFullName, "/")
+	if index < 0 {
+		return
+	}
+	if err := environmentservice.DeletePreviewEnvsOfPR(repoFullName[:index], repoFullName[index+1:], prID, requestID, log); err != nil {
+		log.Errorf("failed to delete preview environments of %s#%d: %s", repoFullName, prID, err)
+	}
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+)
+
+var _ = Describe("Testing preview env", func() {
+
+	Context("test changedPreviewServices", func() {
+		buildServices := []*commonmodels.ServiceAndBuild{
+			{ServiceName: "web", ServiceModule: "web"},
+			{ServiceName: "api", ServiceModule: "api"},
+			{ServiceName: "api", ServiceModule: "worker"},
+		}
+		cfg := &commonmodels.PreviewEnvConfig{
+			ServicePaths: []*commonmodels.PreviewServicePath{
+				{ServiceName: "web", ServiceModule: "web", Paths: []string{"web/**", "package.json"}},
+				{ServiceName: "api", ServiceModule: "api", Paths: []string{"api/"}},
+			},
+		}
+
+		It("should return the services whose paths match the changed files", func() {
+			services := changedPreviewServices(cfg, buildServices, []string{"web/src/index.ts"})
+			Expect(services.List()).To(Equal([]string{"api/worker", "web/web"}))
+
+			services = changedPreviewServices(cfg, buildServices, []string{"api/main.go", "package.json"})
+			Expect(services.List()).To(Equal([]string{"api/api", "api/worker", "web/web"}))
+		})
+
+		It("should return the services without paths for any change", func() {
+			services := changedPreviewServices(cfg, buildServices, []string{"README.md"})
+			Expect(services.List()).To(Equal([]string{"api/worker"}))
+
+			services = changedPreviewServices(&commonmodels.PreviewEnvConfig{}, buildServices, nil)
+			Expect(services.List()).To(Equal([]string{"api/api", "api/worker", "web/web"}))
+		})
+
+		It("should return nothing if no service is built from the repo", func() {
+			services := changedPreviewServices(cfg, nil, []string{"web/src/index.ts"})
+			Expect(services.Len()).To(Equal(0))
+		})
+	})
+
+	Context("test renderPreviewEnvURL", func() {
+		previewEnv := &commonmodels.PreviewEnv{EnvName: "pr-12-abcdef", ProjectName: "demo", PrID: 12}
+
+		It("should render the env name, namespace and pull request", func() {
+			url, err := renderPreviewEnvURL("https://{{.EnvName}}.{{.Namespace}}.example.com/?pr={{.PR}}", previewEnv, "demo-env-pr-12-abcdef")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(url).To(Equal("https://pr-12-abcdef.demo-env-pr-12-abcdef.example.com/?pr=12"))
+		})
+
+		It("should return empty for an empty template", func() {
+			url, err := renderPreviewEnvURL("", previewEnv, "demo-env-pr-12-abcdef")
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(url).To(BeEmpty())
+		})
+
+		It("should fail for invalid templates", func() {
+			_, err := renderPreviewEnvURL("https://{{.EnvName", previewEnv, "")
+			Expect(err).Should(HaveOccurred())
+
+			_, err = renderPreviewEnvURL("https://{{.Unknown}}", previewEnv, "")
+			Expect(err).Should(HaveOccurred())
+		})
+	})
+})
@@ -28,11 +28,13 @@ import (
 
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/sets"
 
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
 	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
 	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/joboutput"
 	"github.com/koderover/zadig/pkg/types"
 	"github.com/koderover/zadig/pkg/types/job"
 )
@@ -205,8 +207,104 @@ func MergeWebhookRepo(workflow *commonmodels.WorkflowV4, repo *types.Repository)
 	return nil
 }
 
+// ListRepoBuildServices lists the services built by the workflow from the repo
+func ListRepoBuildServices(workflow *commonmodels.WorkflowV4, repo *types.Repository) ([]*commonmodels.ServiceAndBuild, error) {
+	resp := []*commonmodels.ServiceAndBuild{}
+	for _, stage := range workflow.Stages {
+		for _, job := range stage.Jobs {
+			if job.JobType != config.JobZadigBuild {
+				continue
+			}
+			spec := &commonmodels.ZadigBuildJobSpec{}
+			if err := commonmodels.IToi(job.Spec, spec); err != nil {
+				return resp, warpJobError(job.Name, err)
+			}
+			for _, build := range spec.ServiceAndBuilds {
+				for _, buildRepo := range build.Repos {
+					if buildRepo.Source == repo.Source && buildRepo.GetRepoNamespace() == repo.GetRepoNamespace() && buildRepo.RepoName == repo.RepoName {
+						resp = append(resp, build)
+						break
+					}
+				}
+			}
+		}
+	}
+	return resp, nil
+}
+
+// SetPreviewEnv deploys the workflow to the preview environment, only the services in the set are built and deployed.
+// The services are keyed by PreviewServiceKey.
+func SetPreviewEnv(workflow *commonmodels.WorkflowV4, envName string, services sets.String) error {
+	serviceNames := sets.NewString()
+	for _, key := range services.List() {
+		serviceNames.Insert(strings.SplitN(key, "/", 2)[0])
+	}
+	for _, stage := range workflow.Stages {
+		for _, job := range stage.Jobs {
+			switch job.JobType {
+			case config.JobZadigBuild:
+				spec := &commonmodels.ZadigBuildJobSpec{}
+				if err := commonmodels.IToi(job.Spec, spec); err != nil {
+					return warpJobError(job.Name, err)
+				}
+				builds := []*commonmodels.ServiceAndBuild{}
+				for _, build := range spec.ServiceAndBuilds {
+					if services.Has(PreviewServiceKey(build.ServiceName, build.ServiceModule)) {
+						builds = append(builds, build)
+					}
+				}
+				spec.ServiceAndBuilds = builds
+				job.Spec = spec
+			case config.JobZadigDeploy:
+				spec := &commonmodels.ZadigDeployJobSpec{}
+				if err := commonmodels.IToi(job.Spec, spec); err != nil {
+					return warpJobError(job.Name, err)
+				}
+				if spec.Production {
+					return warpJobError(job.Name, fmt.Errorf("production environments can not be used as preview environments"))
+				}
+				spec.Env = envName
+				images := []*commonmodels.ServiceAndImage{}
+				for _, image := range spec.ServiceAndImages {
+					if services.Has(PreviewServiceKey(image.ServiceName, image.ServiceModule)) {
+						images = append(images, image)
+					}
+				}
+				spec.ServiceAndImages = images
+				deployServices := []*commonmodels.DeployService{}
+				for _, svc := range spec.Services {
+					if serviceNames.Has(svc.ServiceName) {
+						deployServices = append(deployServices, svc)
+					}
+				}
+				spec.Services = deployServices
+				job.Spec = spec
+			}
+		}
+	}
+	return nil
+}
+
+func PreviewServiceKey(serviceName, serviceModule string) string {
+	return serviceName + "/" + serviceModule
+}
+
 func GetWorkflowOutputs(workflow *commonmodels.WorkflowV4, currentJobName string, log *zap.SugaredLogger) []string {
 	resp := []string{}
+	for _, def := range getWorkflowOutputDefs(workflow, currentJobName, log) {
+		resp = append(resp, def.Key)
+	}
+	return resp
+}
+
+// OutputDef is an output of a job and the key the following jobs reference it by
+type OutputDef struct {
+	Key    string
+	Output *commonmodels.Output
+}
+
+func getWorkflowOutputDefs(workflow *commonmodels.WorkflowV4, currentJobName string, log *zap.SugaredLogger) []*OutputDef {
+	resp := []*OutputDef{}
 	jobRankMap := getJobRankMap(workflow.Stages)
 	for _, stage := range workflow.Stages {
 		for _, job := range stage.Jobs {
@@ -242,6 +340,45 @@ func GetWorkflowOutputs(workflow *commonmodels.WorkflowV4, currentJobName string
 	return resp
 }
 
+// LintOutputRefs makes sure the json paths referenced by the jobs belong to the json outputs of the previous jobs,
+// and are allowed by the schemas of the outputs
+func LintOutputRefs(workflow *commonmodels.WorkflowV4, log *zap.SugaredLogger) error {
+	for _, stage := range workflow.Stages {
+		for _, j := range stage.Jobs {
+			b, err := json.Marshal(j.Spec)
+			if err != nil {
+				return warpJobError(j.Name, err)
+			}
+			var defs map[string]*commonmodels.Output
+			for _, ref := range joboutput.ParseRefs(string(b)) {
+				if ref.Path == "" {
+					continue
+				}
+				if defs == nil {
+					defs = make(map[string]*commonmodels.Output)
+					for _, def := range getWorkflowOutputDefs(workflow, j.Name, log) {
+						defs[def.Key] = def.Output
+					}
+				}
+				output, ok := defs[job.GetJobOutputKey(ref.JobKey, ref.Name)]
+				if !ok {
+					return warpJobError(j.Name, fmt.Errorf("output %s of job %s is not found in the previous jobs", ref.Name, ref.JobKey))
+				}
+				if output.Type != job.OutputTypeJSON {
+					return warpJobError(j.Name, fmt.Errorf("json path %s is referenced, but output %s of job %s is not a json output", ref.Path, ref.Name, ref.JobKey))
+				}
+				if output.Schema == "" {
+					continue
+				}
+				if ok, err := joboutput.HasPath(output.Schema, ref.Path); err != nil || !ok {
+					return warpJobError(j.Name, fmt.Errorf("json path %s is not allowed by the schema of output %s of job %s", ref.Path, ref.Name, ref.JobKey))
+				}
+			}
+		}
+	}
+	return nil
+}
+
 type RepoIndex struct {
 	JobName       string `json:"job_name"`
 	ServiceName   string `json:"service_name"`
@@ -569,10 +706,10 @@ func getJobRankMap(stages []*commonmodels.WorkflowStage) map[string]int {
 	return resp
 }
 
-func getOutputKey(jobKey string, outputs []*commonmodels.Output) []string {
-	resp := []string{}
+func getOutputDefs(jobKey string, outputs []*commonmodels.Output) []*OutputDef {
+	resp := []*OutputDef{}
 	for _, output := range outputs {
-		resp = append(resp, job.GetJobOutputKey(jobKey, output.Name))
+		resp = append(resp, &OutputDef{Key: job.GetJobOutputKey(jobKey, output.Name), Output: output})
 	}
 	return resp
 }
@@ -586,11 +723,14 @@ func outputScript(outputs []*commonmodels.Output) []string {
 	return resp
 }
 
-func checkOutputNames(outputs []*commonmodels.Output) error {
+func checkOutputs(outputs []*commonmodels.Output) error {
 	for _, output := range outputs {
 		if match := OutputNameRegex.MatchString(output.Name); !match {
 			return fmt.Errorf("output name must match %s", OutputNameRegexString)
 		}
+		if err := joboutput.ValidateSpec(output.ToSpec()); err != nil {
+			return err
+		}
 	}
 	return nil
 }
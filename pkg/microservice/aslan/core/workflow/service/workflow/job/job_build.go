@@ -255,6 +255,8 @@ func (j *BuildJob) ToJobs(taskID int64) ([]*commonmodels.JobTask, error) {
 			ImageFrom:           buildInfo.PreBuild.ImageFrom,
 			Registries:          registries,
 			ShareStorageDetails: getShareStorageDetail(j.workflow.ShareStorages, build.ShareStorageInfo, j.workflow.Name, taskID),
+			Infrastructure:      buildInfo.PreBuild.Infrastructure,
+			VMLabels:            buildInfo.PreBuild.VMLabels,
 		}
 		clusterInfo, err := commonrepo.NewK8SClusterColl().Get(buildInfo.PreBuild.ClusterID)
 		if err != nil {
@@ -353,6 +355,11 @@ func (j *BuildJob) ToJobs(taskID int64) ([]*commonmodels.JobTask, error) {
 					ImageReleaseTag:       imageTag,
 					BuildArgs:             buildInfo.PostBuild.DockerBuild.BuildArgs,
 					DockerTemplateContent: dockefileContent,
+					BuilderMode:           buildInfo.PostBuild.DockerBuild.BuilderMode,
+					BuildKitUnconfined:    buildInfo.PostBuild.DockerBuild.BuildKitUnconfined,
+					Platforms:             buildInfo.PostBuild.DockerBuild.Platforms,
+					CacheRepo:             buildInfo.PostBuild.DockerBuild.CacheRepo,
+					Secrets:               getDockerBuildSecrets(buildInfo.PostBuild.DockerBuild.Secrets),
 					DockerRegistry: &step.DockerRegistry{
 						DockerRegistryID: j.spec.DockerRegistryID,
 						Host:             registry.RegAddr,
@@ -363,6 +370,52 @@ func (j *BuildJob) ToJobs(taskID int64) ([]*commonmodels.JobTask, error) {
 				},
 			}
 			jobTaskSpec.Steps = append(jobTaskSpec.Steps, dockerBuildStep)
+
+			// init sbom scan step for the built image
+			if sbomScan := buildInfo.PostBuild.DockerBuild.SBOMScan; sbomScan != nil && sbomScan.Enabled {
+				sbomScanStep := &commonmodels.StepTask{
+					Name:     build.ServiceName + "-sbom-scan",
+					JobName:  jobTask.Name,
+					StepType: config.StepSBOMScan,
+					Spec: &step.StepSBOMScanSpec{
+						ImageName:         "$IMAGE",
+						SBOMFormat:        sbomScan.SBOMFormat,
+						SeverityThreshold: sbomScan.SeverityThreshold,
+						VulnDBURL:         sbomScan.VulnDBURL,
+						VulnDBCacheDir:    sbomVulnDBCacheDir(&jobTaskSpec.Properties),
+						DockerRegistry: &step.DockerRegistry{
+							DockerRegistryID: j.spec.DockerRegistryID,
+							Host:             registry.RegAddr,
+							UserName:         registry.AccessKey,
+							Password:         registry.SecretKey,
+							Namespace:        registry.Namespace,
+						},
+						S3DestDir: path.Join(j.workflow.Name, fmt.Sprint(taskID), jobTask.Name, "sbom"),
+					},
+				}
+				jobTaskSpec.Steps = append(jobTaskSpec.Steps, sbomScanStep)
+			}
+
+			// init image sign step, the image is signed after it passes the scan
+			if signingKeyID := buildInfo.PostBuild.DockerBuild.SigningKeyID; signingKeyID != "" {
+				imageSignStep := &commonmodels.StepTask{
+					Name:     build.ServiceName + "-image-sign",
+					JobName:  jobTask.Name,
+					StepType: config.StepImageSign,
+					Spec: &step.StepImageSignSpec{
+						Images:       []string{"$IMAGE"},
+						SigningKeyID: signingKeyID,
+						DockerRegistries: []*step.DockerRegistry{{
+							DockerRegistryID: j.spec.DockerRegistryID,
+							Host:             registry.RegAddr,
+							UserName:         registry.AccessKey,
+							Password:         registry.SecretKey,
+							Namespace:        registry.Namespace,
+						}},
+					},
+				}
+				jobTaskSpec.Steps = append(jobTaskSpec.Steps, imageSignStep)
+			}
 		}
 
 		// init archive step
@@ -413,6 +466,44 @@ func (j *BuildJob) ToJobs(taskID int64) ([]*commonmodels.JobTask, error) {
 			jobTaskSpec.Steps = append(jobTaskSpec.Steps, archiveStep)
 		}
 
+		// init artifact publish steps
+		if buildInfo.PostBuild != nil {
+			for i, publish := range buildInfo.PostBuild.ArtifactPublishes {
+				registry, err := commonrepo.NewArtifactRegistryColl().FindByID(publish.RegistryID)
+				if err != nil {
+					return resp, fmt.Errorf("find artifact registry: %s failed, err: %v", publish.RegistryID, err)
+				}
+				// the credential is injected as secret envs, so that it is masked in the logs
+				usernameEnv := fmt.Sprintf("ARTIFACT_REGISTRY_USERNAME_%d", i)
+				passwordEnv := fmt.Sprintf("ARTIFACT_REGISTRY_PASSWORD_%d", i)
+				jobTaskSpec.Properties.Envs = append(jobTaskSpec.Properties.Envs,
+					&commonmodels.KeyVal{Key: usernameEnv, Value: registry.Username, IsCredential: false},
+					&commonmodels.KeyVal{Key: passwordEnv, Value: registry.Password, IsCredential: true},
+				)
+				publishSpec := &step.StepArtifactPublishSpec{
+					Type:         registry.Type,
+					RegistryURL:  registry.Address,
+					Insecure:     registry.Insecure,
+					UsernameEnv:  usernameEnv,
+					PasswordEnv:  passwordEnv,
+					Path:         publish.Path,
+					Files:        publish.Files,
+					Repository:   renderEnv(publish.Repository, jobTaskSpec.Properties.Envs),
+					Tag:          renderEnv(publish.Tag, jobTaskSpec.Properties.Envs),
+					ArtifactType: publish.ArtifactType,
+					OutputName:   publish.OutputName,
+				}
+				jobTask.Outputs = ensureArtifactOutput(jobTask.Outputs, publishSpec.GetOutputName())
+				publishStep := &commonmodels.StepTask{
+					Name:     fmt.Sprintf("%s-artifact-publish-%d", build.ServiceName, i),
+					JobName:  jobTask.Name,
+					StepType: config.StepArtifactPublish,
+					Spec:     publishSpec,
+				}
+				jobTaskSpec.Steps = append(jobTaskSpec.Steps, publishStep)
+			}
+		}
+
 		// init post build shell step
 		if buildInfo.PostBuild != nil && buildInfo.PostBuild.Scripts != "" {
 			scripts := append([]string{dockerLoginCmd}, strings.Split(replaceWrapLine(buildInfo.PostBuild.Scripts), "\n")...)
@@ -429,7 +520,7 @@ func (j *BuildJob) ToJobs(taskID int64) ([]*commonmodels.JobTask, error) {
 		resp = append(resp, jobTask)
 	}
 	j.job.Spec = j.spec
-	return resp, nil
+	return expandMatrixJobTasks(resp, j.spec.Matrix)
 }
 
 func renderKeyVals(input, origin []*commonmodels.KeyVal) []*commonmodels.KeyVal {
@@ -590,11 +681,54 @@ func mergeRepos(templateRepos []*types.Repository, customRepos []*types.Reposito
 }
 
 func (j *BuildJob) LintJob() error {
+	j.spec = &commonmodels.ZadigBuildJobSpec{}
+	if err := commonmodels.IToiYaml(j.job.Spec, j.spec); err != nil {
+		return err
+	}
+	if err := lintJobMatrix(j.spec.Matrix); err != nil {
+		return err
+	}
+	for _, build := range j.spec.ServiceAndBuilds {
+		if err := lintSBOMScan(build, j.workflow.Project); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lintSBOMScan rejects the sbom scan without the mirrored vulnerability database, grype falls back to download the
+// public database silently
+func lintSBOMScan(build *commonmodels.ServiceAndBuild, project string) error {
+	buildInfo, err := commonrepo.NewBuildColl().Find(&commonrepo.BuildFindOption{Name: build.BuildName, ProductName: project})
+	if err != nil {
+		return fmt.Errorf("find build: %s error: %v", build.BuildName, err)
+	}
+	if err := fillBuildDetail(buildInfo, build.ServiceName, build.ServiceModule); err != nil {
+		return err
+	}
+	if buildInfo.PostBuild == nil || buildInfo.PostBuild.DockerBuild == nil {
+		return nil
+	}
+	sbomScan := buildInfo.PostBuild.DockerBuild.SBOMScan
+	if sbomScan != nil && sbomScan.Enabled && sbomScan.VulnDBURL == "" {
+		return fmt.Errorf("the vulnerability database url of the sbom scan in build %s is empty", build.BuildName)
+	}
 	return nil
 }
 
-func (j *BuildJob) GetOutPuts(log *zap.SugaredLogger) []string {
-	resp := []string{}
+// sbomVulnDBCacheDir keeps the vulnerability database on the build cache volume, so it is not downloaded in every scan
+func sbomVulnDBCacheDir(properties *commonmodels.JobProperties) string {
+	if !properties.CacheEnable || properties.Cache.MediumType != types.NFSMedium {
+		return ""
+	}
+	if properties.CacheDirType == types.WorkspaceCacheDir {
+		return path.Join("$WORKSPACE", ".grype", "db")
+	}
+	return path.Join(properties.CacheUserDir, ".grype", "db")
+}
+
+func (j *BuildJob) GetOutPuts(log *zap.SugaredLogger) []*OutputDef {
+	resp := []*OutputDef{}
 	j.spec = &commonmodels.ZadigBuildJobSpec{}
 	if err := commonmodels.IToiYaml(j.job.Spec, j.spec); err != nil {
 		return resp
@@ -607,7 +741,7 @@ func (j *BuildJob) GetOutPuts(log *zap.SugaredLogger) []string {
 			continue
 		}
 		if buildInfo.TemplateID == "" {
-			resp = append(resp, getOutputKey(jobKey, ensureBuildInOutputs(buildInfo.Outputs))...)
+			resp = append(resp, getOutputDefs(jobKey, ensureArtifactOutputs(ensureBuildInOutputs(buildInfo.Outputs), buildInfo.PostBuild))...)
 			continue
 		}
 		buildTemplate, err := commonrepo.NewBuildTemplateColl().Find(&commonrepo.BuildTemplateQueryOption{ID: buildInfo.TemplateID})
@@ -615,7 +749,7 @@ func (j *BuildJob) GetOutPuts(log *zap.SugaredLogger) []string {
 			log.Errorf("found build template %s failed, err: %s", buildInfo.TemplateID, err)
 			continue
 		}
-		resp = append(resp, getOutputKey(jobKey, ensureBuildInOutputs(buildTemplate.Outputs))...)
+		resp = append(resp, getOutputDefs(jobKey, ensureArtifactOutputs(ensureBuildInOutputs(buildTemplate.Outputs), buildTemplate.PostBuild))...)
 	}
 	return resp
 }
@@ -642,3 +776,35 @@ func ensureBuildInOutputs(outputs []*commonmodels.Output) []*commonmodels.Output
 	}
 	return outputs
 }
+
+// ensureArtifactOutputs adds the outputs of the coordinates of the artifacts published by the build
+func ensureArtifactOutputs(outputs []*commonmodels.Output, postBuild *commonmodels.PostBuild) []*commonmodels.Output {
+	if postBuild == nil {
+		return outputs
+	}
+	for _, publish := range postBuild.ArtifactPublishes {
+		outputName := publish.OutputName
+		if outputName == "" {
+			outputName = step.DefaultArtifactOutput
+		}
+		outputs = ensureArtifactOutput(outputs, outputName)
+	}
+	return outputs
+}
+
+func ensureArtifactOutput(outputs []*commonmodels.Output, name string) []*commonmodels.Output {
+	for _, output := range outputs {
+		if output.Name == name {
+			return outputs
+		}
+	}
+	return append(outputs, &commonmodels.Output{Name: name})
+}
+
+func getDockerBuildSecrets(secrets []*commonmodels.DockerBuildSecret) []*step.DockerBuildSecret {
+	resp := []*step.DockerBuildSecret{}
+	for _, secret := range secrets {
+		resp = append(resp, &step.DockerBuildSecret{ID: secret.ID, Env: secret.Env})
+	}
+	return resp
+}
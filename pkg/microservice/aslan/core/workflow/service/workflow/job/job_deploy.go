@@ -210,6 +210,10 @@ func (j *DeployJob) ToJobs(taskID int64) ([]*commonmodels.JobTask, error) {
 	if err != nil {
 		return resp, fmt.Errorf("env %s not exists", envName)
 	}
+	signaturePolicy, err := j.getSignaturePolicy()
+	if err != nil {
+		return resp, err
+	}
 
 	project, err := templaterepo.NewProductColl().Find(j.workflow.Project)
 	if err != nil {
@@ -283,6 +287,7 @@ func (j *DeployJob) ToJobs(taskID int64) ([]*commonmodels.JobTask, error) {
 				Production:         j.spec.Production,
 				DeployContents:     j.spec.DeployContents,
 				Timeout:            timeout,
+				SignaturePolicy:    signaturePolicy,
 			}
 
 			for _, deploy := range deploys {
@@ -454,6 +459,7 @@ func (j *DeployJob) ToJobs(taskID int64) ([]*commonmodels.JobTask, error) {
 				ReleaseName:        releaseName,
 				Timeout:            timeout,
 				IsProduction:       j.spec.Production,
+				SignaturePolicy:    signaturePolicy,
 			}
 
 			for _, deploy := range deploys {
@@ -499,11 +505,35 @@ func checkServiceExsistsInEnv(serviceMap map[string]*commonmodels.ProductService
 	return nil
 }
 
+// getSignaturePolicy returns the signature policy of the saved workflow, so that it can't be bypassed by the args of the task
+func (j *DeployJob) getSignaturePolicy() (*commonmodels.ImageSignaturePolicy, error) {
+	workflow, err := commonrepo.NewWorkflowV4Coll().Find(j.workflow.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find workflow %s to get the signature policy, err: %v", j.workflow.Name, err)
+	}
+	for _, stage := range workflow.Stages {
+		for _, job := range stage.Jobs {
+			if job.Name != j.job.Name || job.JobType != config.JobZadigDeploy {
+				continue
+			}
+			spec := &commonmodels.ZadigDeployJobSpec{}
+			if err := commonmodels.IToi(job.Spec, spec); err != nil {
+				return nil, fmt.Errorf("failed to decode job %s to get the signature policy, err: %v", j.job.Name, err)
+			}
+			return spec.SignaturePolicy, nil
+		}
+	}
+	return nil, fmt.Errorf("job %s not found in workflow %s to get the signature policy", j.job.Name, j.workflow.Name)
+}
+
 func (j *DeployJob) LintJob() error {
 	j.spec = &commonmodels.ZadigDeployJobSpec{}
 	if err := commonmodels.IToiYaml(j.job.Spec, j.spec); err != nil {
 		return err
 	}
+	if j.spec.SignaturePolicy != nil && j.spec.SignaturePolicy.Enabled && len(j.spec.SignaturePolicy.TrustedKeyIDs) == 0 {
+		return fmt.Errorf("trusted signing keys are required by the signature policy of job %s", j.job.Name)
+	}
 	if j.spec.Source != config.SourceFromJob {
 		return nil
 	}
@@ -515,8 +545,8 @@ func (j *DeployJob) LintJob() error {
 	return nil
 }
 
-func (j *DeployJob) GetOutPuts(log *zap.SugaredLogger) []string {
-	return getOutputKey(j.job.Name, ensureDeployInOutputs())
+func (j *DeployJob) GetOutPuts(log *zap.SugaredLogger) []*OutputDef {
+	return getOutputDefs(j.job.Name, ensureDeployInOutputs())
 }
 
 func ensureDeployInOutputs() []*commonmodels.Output {
@@ -223,6 +223,16 @@ func (j *ImageDistributeJob) ToJobs(taskID int64) ([]*commonmodels.JobTask, erro
 			},
 		},
 	}
+	if j.spec.SigningKeyID != "" {
+		jobTaskSpec.Steps = append(jobTaskSpec.Steps, &commonmodels.StepTask{
+			Name:     "sign",
+			StepType: config.StepImageSign,
+			Spec: &step.StepImageSignSpec{
+				ImagesFromStep: "distribute",
+				SigningKeyID:   j.spec.SigningKeyID,
+			},
+		})
+	}
 	jobTask := &commonmodels.JobTask{
 		Name: j.job.Name,
 		Key:  j.job.Name,
@@ -308,15 +318,15 @@ func getTimeout(timeout int64) int64 {
 	return timeout
 }
 
-func (j *ImageDistributeJob) GetOutPuts(log *zap.SugaredLogger) []string {
-	resp := []string{}
+func (j *ImageDistributeJob) GetOutPuts(log *zap.SugaredLogger) []*OutputDef {
+	resp := []*OutputDef{}
 	j.spec = &commonmodels.ZadigDistributeImageJobSpec{}
 	if err := commonmodels.IToiYaml(j.job.Spec, j.spec); err != nil {
 		return resp
 	}
 	for _, target := range j.spec.Targets {
 		targetKey := strings.Join([]string{j.job.Name, target.ServiceName, target.ServiceModule}, ".")
-		resp = append(resp, getOutputKey(targetKey, []*commonmodels.Output{{Name: "IMAGE"}})...)
+		resp = append(resp, getOutputDefs(targetKey, []*commonmodels.Output{{Name: "IMAGE"}})...)
 	}
 	return resp
 }
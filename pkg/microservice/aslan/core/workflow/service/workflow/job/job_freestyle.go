@@ -18,6 +18,7 @@ package job
 
 import (
 	"fmt"
+	"path"
 	"strings"
 
 	"go.uber.org/zap"
@@ -75,6 +76,12 @@ func (j *FreeStyleJob) Instantiate() error {
 				return fmt.Errorf("parse archive step spec error: %v", err)
 			}
 			step.Spec = stepSpec
+		case config.StepCacheRestore, config.StepCacheSave:
+			stepSpec := &steptypes.StepCacheSpec{}
+			if err := commonmodels.IToiYaml(step.Spec, stepSpec); err != nil {
+				return fmt.Errorf("parse cache step spec error: %v", err)
+			}
+			step.Spec = stepSpec
 		default:
 			return fmt.Errorf("freestyle job step type %s not supported", step.StepType)
 		}
@@ -218,6 +225,8 @@ func (j *FreeStyleJob) ToJobs(taskID int64) ([]*commonmodels.JobTask, error) {
 func (j *FreeStyleJob) stepsToStepTasks(step []*commonmodels.Step) []*commonmodels.StepTask {
 	logger := log.SugaredLogger()
 	resp := []*commonmodels.StepTask{}
+	repos := []*types.Repository{}
+	cacheSpecs := []*steptypes.StepCacheSpec{}
 	for _, step := range step {
 		stepTask := &commonmodels.StepTask{
 			Name:     step.Name,
@@ -244,6 +253,7 @@ func (j *FreeStyleJob) stepsToStepTasks(step []*commonmodels.Step) []*commonmode
 			}
 			stepTaskSpec.Repos = newRepos
 			stepTask.Spec = stepTaskSpec
+			repos = append(repos, newRepos...)
 
 		}
 		if stepTask.StepType == config.StepDockerBuild {
@@ -268,6 +278,14 @@ func (j *FreeStyleJob) stepsToStepTasks(step []*commonmodels.Step) []*commonmode
 			}
 			stepTask.Spec = stepTaskSpec
 		}
+		if stepTask.StepType == config.StepCacheRestore || stepTask.StepType == config.StepCacheSave {
+			stepTaskSpec := &steptypes.StepCacheSpec{}
+			if err := commonmodels.IToi(stepTask.Spec, stepTaskSpec); err != nil {
+				continue
+			}
+			cacheSpecs = append(cacheSpecs, stepTaskSpec)
+			stepTask.Spec = stepTaskSpec
+		}
 		if stepTask.StepType == config.StepShell {
 			stepTaskSpec := &steptypes.StepShellSpec{}
 			if err := commonmodels.IToi(stepTask.Spec, stepTaskSpec); err != nil {
@@ -293,9 +311,40 @@ func (j *FreeStyleJob) stepsToStepTasks(step []*commonmodels.Step) []*commonmode
 			resp = append(resp, debugAfterStep)
 		}
 	}
+	cacheDir := j.cacheDir(repos)
+	for _, cacheSpec := range cacheSpecs {
+		cacheSpec.S3DestDir = cacheDir
+	}
 	return resp
 }
 
+// cacheDir returns the s3 dir of the caches of the job. Caches are scoped to the primary repo, so the builds of all
+// the branches, pull requests and tags of the repo share warm caches, the keys computed from the content of the
+// dependency files keep a build from restoring a mismatched cache. The caches of jobs without repos are scoped to the job.
+func (j *FreeStyleJob) cacheDir(repos []*types.Repository) string {
+	var repo *types.Repository
+	for _, r := range repos {
+		if r.IsPrimary {
+			repo = r
+			break
+		}
+	}
+	if repo == nil && len(repos) > 0 {
+		repo = repos[0]
+	}
+	if repo == nil {
+		return path.Join(j.workflow.Project, "cache", "workflow", j.workflow.Name, j.job.Name)
+	}
+
+	repoName := repo.RepoName
+	if repo.RepoNamespace != "" {
+		repoName = path.Join(repo.RepoNamespace, repo.RepoName)
+	} else if repo.RepoOwner != "" {
+		repoName = path.Join(repo.RepoOwner, repo.RepoName)
+	}
+	return path.Join(j.workflow.Project, "cache", "repo", repoName)
+}
+
 func getfreestyleJobVariables(steps []*commonmodels.StepTask, taskID int64, project, workflowName string) []*commonmodels.KeyVal {
 	ret := []*commonmodels.KeyVal{}
 	repos := []*types.Repository{}
@@ -326,17 +375,29 @@ func (j *FreeStyleJob) LintJob() error {
 	if err := commonmodels.IToiYaml(j.job.Spec, j.spec); err != nil {
 		return err
 	}
-	return checkOutputNames(j.spec.Outputs)
+	for _, step := range j.spec.Steps {
+		if step.StepType != config.StepCacheRestore && step.StepType != config.StepCacheSave {
+			continue
+		}
+		stepSpec := &steptypes.StepCacheSpec{}
+		if err := commonmodels.IToiYaml(step.Spec, stepSpec); err != nil {
+			return fmt.Errorf("parse cache step spec error: %v", err)
+		}
+		if stepSpec.Key == "" || len(stepSpec.Paths) == 0 {
+			return fmt.Errorf("key and paths of cache step %s should not be empty", step.Name)
+		}
+	}
+	return checkOutputs(j.spec.Outputs)
 }
 
-func (j *FreeStyleJob) GetOutPuts(log *zap.SugaredLogger) []string {
-	resp := []string{}
+func (j *FreeStyleJob) GetOutPuts(log *zap.SugaredLogger) []*OutputDef {
+	resp := []*OutputDef{}
 	j.spec = &commonmodels.FreestyleJobSpec{}
 	if err := commonmodels.IToiYaml(j.job.Spec, j.spec); err != nil {
 		return resp
 	}
 
 	jobKey := j.job.Name
-	resp = append(resp, getOutputKey(jobKey, j.spec.Outputs)...)
+	resp = append(resp, getOutputDefs(jobKey, j.spec.Outputs)...)
 	return resp
 }
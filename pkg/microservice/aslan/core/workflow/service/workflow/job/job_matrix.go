@@ -0,0 +1,317 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package job
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/types/step"
+)
+
+const (
+	// MatrixKey is the key of job info which shows the axis values of a matrix job task
+	MatrixKey = "matrix"
+	// maxMatrixCombinations limits the number of job tasks one job task can be expanded to
+	maxMatrixCombinations = 256
+	// matrixCombinationIDLength is the length of the hash suffix of the expanded job task names
+	matrixCombinationIDLength = 8
+)
+
+var matrixAxisNameRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+func lintJobMatrix(matrix *commonmodels.JobMatrix) error {
+	if matrix == nil || len(matrix.Axes) == 0 {
+		return nil
+	}
+	if matrix.MaxParallel < 0 {
+		return fmt.Errorf("matrix max_parallel can not be negative")
+	}
+	names := sets.NewString()
+	combinations := 1
+	for _, axis := range matrix.Axes {
+		if !matrixAxisNameRegex.MatchString(axis.Name) {
+			return fmt.Errorf("invalid matrix axis name: %s", axis.Name)
+		}
+		if names.Has(axis.Name) {
+			return fmt.Errorf("duplicated matrix axis: %s", axis.Name)
+		}
+		names.Insert(axis.Name)
+		if axis.Type != config.MatrixAxisEnv && axis.Type != config.MatrixAxisBuildOS {
+			return fmt.Errorf("unsupported type %s of matrix axis %s", axis.Type, axis.Name)
+		}
+		if len(axis.Values) == 0 {
+			return fmt.Errorf("matrix axis %s has no value", axis.Name)
+		}
+		combinations *= len(axis.Values)
+		if combinations > maxMatrixCombinations {
+			return fmt.Errorf("matrix can not be expanded to more than %d job tasks", maxMatrixCombinations)
+		}
+	}
+	return nil
+}
+
+// expandMatrixJobTasks replaces every job task with one job task per combination of the matrix axis values.
+// the first combination keeps the key of the origin job task, so outputs referred by other jobs are still available.
+func expandMatrixJobTasks(jobTasks []*commonmodels.JobTask, matrix *commonmodels.JobMatrix) ([]*commonmodels.JobTask, error) {
+	if matrix == nil || len(matrix.Axes) == 0 {
+		return jobTasks, nil
+	}
+	if err := lintJobMatrix(matrix); err != nil {
+		return nil, err
+	}
+	basicImages := make(map[string]*commonmodels.BasicImage)
+	for _, axis := range matrix.Axes {
+		if axis.Type != config.MatrixAxisBuildOS {
+			continue
+		}
+		for _, imageID := range axis.Values {
+			basicImage, err := commonrepo.NewBasicImageColl().Find(imageID)
+			if err != nil {
+				return nil, fmt.Errorf("find basic image: %s of matrix axis %s error: %v", imageID, axis.Name, err)
+			}
+			basicImages[imageID] = basicImage
+		}
+	}
+
+	combinations := matrixCombinations(matrix.Axes)
+	resp := []*commonmodels.JobTask{}
+	for _, jobTask := range jobTasks {
+		for i, values := range combinations {
+			matrixJobTask, err := newMatrixJobTask(jobTask, i, values, matrix, basicImages)
+			if err != nil {
+				return nil, err
+			}
+			resp = append(resp, matrixJobTask)
+		}
+	}
+	return resp, nil
+}
+
+func matrixCombinations(axes []*commonmodels.MatrixAxis) []map[string]string {
+	combinations := []map[string]string{{}}
+	for _, axis := range axes {
+		next := make([]map[string]string, 0, len(combinations)*len(axis.Values))
+		for _, combination := range combinations {
+			for _, value := range axis.Values {
+				values := make(map[string]string, len(combination)+1)
+				for k, v := range combination {
+					values[k] = v
+				}
+				values[axis.Name] = value
+				next = append(next, values)
+			}
+		}
+		combinations = next
+	}
+	return combinations
+}
+
+func newMatrixJobTask(origin *commonmodels.JobTask, index int, values map[string]string, matrix *commonmodels.JobMatrix, basicImages map[string]*commonmodels.BasicImage) (*commonmodels.JobTask, error) {
+	// IToi makes a deep copy of the spec, so the expanded job tasks do not share steps and envs
+	spec := &commonmodels.JobTaskFreestyleSpec{}
+	if err := commonmodels.IToi(origin.Spec, spec); err != nil {
+		return nil, err
+	}
+
+	combinationID := matrixCombinationID(origin.Name, values)
+	jobTask := *origin
+	jobTask.Name = matrixJobTaskName(origin.Name, combinationID)
+	if index > 0 {
+		jobTask.Key = fmt.Sprintf("%s.matrix-%d", origin.Key, index+1)
+	}
+	jobTask.Spec = spec
+	jobTask.Matrix = &commonmodels.JobTaskMatrix{
+		Parent:      origin.Name,
+		Values:      values,
+		FailFast:    matrix.FailFast,
+		MaxParallel: matrix.MaxParallel,
+	}
+
+	display := []string{}
+	for _, axis := range matrix.Axes {
+		value := values[axis.Name]
+		display = append(display, fmt.Sprintf("%s=%s", axis.Name, value))
+		switch axis.Type {
+		case config.MatrixAxisBuildOS:
+			basicImage := basicImages[value]
+			spec.Properties.BuildOS = basicImage.Value
+			spec.Properties.ImageFrom = basicImage.ImageFrom
+			spec.Properties.ImageID = value
+		default:
+			kv := &commonmodels.KeyVal{Key: axis.Name, Value: value, Type: commonmodels.StringType}
+			spec.Properties.CustomEnvs = setKeyVal(spec.Properties.CustomEnvs, kv)
+			spec.Properties.Envs = setKeyVal(spec.Properties.Envs, kv)
+		}
+	}
+	jobInfo := map[string]string{}
+	if err := commonmodels.IToi(origin.JobInfo, &jobInfo); err != nil {
+		return nil, err
+	}
+	jobInfo[MatrixKey] = strings.Join(display, ",")
+	jobTask.JobInfo = jobInfo
+
+	// the first combination keeps the image and artifact tags, since other jobs refer to its outputs
+	tagSuffix := ""
+	if index > 0 {
+		tagSuffix = "-" + combinationID
+		for _, kv := range spec.Properties.Envs {
+			if kv.Key == IMAGEKEY && kv.Value != "" {
+				kv.Value += tagSuffix
+			}
+		}
+	}
+	steps := make([]*commonmodels.StepTask, 0, len(spec.Steps))
+	for _, stepTask := range spec.Steps {
+		stepTask.JobName = jobTask.Name
+		keep, err := renameStepDestination(stepTask, origin.Name, jobTask.Name, tagSuffix)
+		if err != nil {
+			return nil, err
+		}
+		if keep {
+			steps = append(steps, stepTask)
+		}
+	}
+	spec.Steps = steps
+	return &jobTask, nil
+}
+
+// matrixCombinationID is a short hash of the job task name and the axis values of a combination,
+// it is unique among the job tasks expanded from the same workflow task.
+func matrixCombinationID(name string, values map[string]string) string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	h := sha1.New()
+	h.Write([]byte(name))
+	for _, k := range keys {
+		h.Write([]byte(fmt.Sprintf("\x00%s=%s", k, values[k])))
+	}
+	return hex.EncodeToString(h.Sum(nil))[:matrixCombinationIDLength]
+}
+
+// matrixJobTaskName truncates the job task name to keep the combination id within the 63 characters limit
+func matrixJobTaskName(name, combinationID string) string {
+	suffix := "-" + combinationID
+	if len(name)+len(suffix) > 63 {
+		name = name[:63-len(suffix)]
+	}
+	return jobNameFormat(name + suffix)
+}
+
+func setKeyVal(kvs []*commonmodels.KeyVal, kv *commonmodels.KeyVal) []*commonmodels.KeyVal {
+	for _, item := range kvs {
+		if item.Key == kv.Key {
+			item.Value = kv.Value
+			return kvs
+		}
+	}
+	return append(kvs, &commonmodels.KeyVal{Key: kv.Key, Value: kv.Value, Type: kv.Type})
+}
+
+// renameStepDestination makes sure the artifacts of the expanded job tasks do not overwrite each other.
+// The object storage path of a job task is in the format of {workflow name}/{task id}/{job task name}/...,
+// the paths without the job task name, like the user defined object storage paths, are put in a dir of the job task.
+// Image and oci artifact tags get the tag suffix, the maven, npm and pypi packages are published by the
+// first combination only, since the registries reject publishing the same version again.
+// It returns false if the step should be dropped from the job task.
+func renameStepDestination(stepTask *commonmodels.StepTask, oldName, newName, tagSuffix string) (bool, error) {
+	rename := func(p string) string {
+		if p == "" {
+			return p
+		}
+		if strings.Contains(p, "/"+oldName+"/") {
+			return strings.Replace(p, "/"+oldName+"/", "/"+newName+"/", 1)
+		}
+		return path.Join(p, newName)
+	}
+	switch stepTask.StepType {
+	case config.StepArchive:
+		spec := &step.StepArchiveSpec{}
+		if err := commonmodels.IToi(stepTask.Spec, spec); err != nil {
+			return false, err
+		}
+		for _, upload := range spec.UploadDetail {
+			upload.DestinationPath = rename(upload.DestinationPath)
+		}
+		stepTask.Spec = spec
+	case config.StepTarArchive:
+		spec := &step.StepTarArchiveSpec{}
+		if err := commonmodels.IToi(stepTask.Spec, spec); err != nil {
+			return false, err
+		}
+		spec.S3DestDir = rename(spec.S3DestDir)
+		stepTask.Spec = spec
+	case config.StepJunitReport:
+		spec := &step.StepJunitReportSpec{}
+		if err := commonmodels.IToi(stepTask.Spec, spec); err != nil {
+			return false, err
+		}
+		spec.S3DestDir = rename(spec.S3DestDir)
+		stepTask.Spec = spec
+	case config.StepTestReport:
+		spec := &step.StepTestReportSpec{}
+		if err := commonmodels.IToi(stepTask.Spec, spec); err != nil {
+			return false, err
+		}
+		spec.S3DestDir = rename(spec.S3DestDir)
+		stepTask.Spec = spec
+	case config.StepSBOMScan:
+		spec := &step.StepSBOMScanSpec{}
+		if err := commonmodels.IToi(stepTask.Spec, spec); err != nil {
+			return false, err
+		}
+		spec.S3DestDir = rename(spec.S3DestDir)
+		stepTask.Spec = spec
+	case config.StepCacheRestore, config.StepCacheSave:
+		spec := &step.StepCacheSpec{}
+		if err := commonmodels.IToi(stepTask.Spec, spec); err != nil {
+			return false, err
+		}
+		spec.S3DestDir = rename(spec.S3DestDir)
+		stepTask.Spec = spec
+	case config.StepArtifactPublish:
+		spec := &step.StepArtifactPublishSpec{}
+		if err := commonmodels.IToi(stepTask.Spec, spec); err != nil {
+			return false, err
+		}
+		stepTask.Spec = spec
+		if tagSuffix == "" {
+			return true, nil
+		}
+		if spec.Type != step.ArtifactTypeOCI {
+			return false, nil
+		}
+		if spec.Tag == "" {
+			spec.Tag = "latest"
+		}
+		spec.Tag += tagSuffix
+	}
+	return true, nil
+}
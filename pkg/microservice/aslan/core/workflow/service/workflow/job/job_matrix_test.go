@@ -0,0 +1,159 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package job
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/types/step"
+)
+
+func envMatrix(axes map[string][]string) *commonmodels.JobMatrix {
+	matrix := &commonmodels.JobMatrix{FailFast: true, MaxParallel: 2}
+	for _, name := range []string{"GO_VERSION", "ARCH"} {
+		if values, ok := axes[name]; ok {
+			matrix.Axes = append(matrix.Axes, &commonmodels.MatrixAxis{Name: name, Type: config.MatrixAxisEnv, Values: values})
+		}
+	}
+	return matrix
+}
+
+func matrixOriginJobTask(name string) *commonmodels.JobTask {
+	return &commonmodels.JobTask{
+		Name: name,
+		Key:  "build." + name,
+		Spec: &commonmodels.JobTaskFreestyleSpec{
+			Properties: commonmodels.JobProperties{
+				Envs: []*commonmodels.KeyVal{{Key: IMAGEKEY, Value: "koderover/app:v1"}},
+			},
+			Steps: []*commonmodels.StepTask{
+				{
+					Name:     "archive",
+					StepType: config.StepArchive,
+					Spec: &step.StepArchiveSpec{UploadDetail: []*step.Upload{
+						{DestinationPath: "wf/1/" + name + "/archive"},
+						{DestinationPath: "custom/dir"},
+					}},
+				},
+				{Name: "oci", StepType: config.StepArtifactPublish, Spec: &step.StepArtifactPublishSpec{Type: step.ArtifactTypeOCI, Tag: "v1"}},
+				{Name: "maven", StepType: config.StepArtifactPublish, Spec: &step.StepArtifactPublishSpec{Type: step.ArtifactTypeMaven}},
+				{Name: "shell", StepType: config.StepShell, Spec: &step.StepShellSpec{Scripts: []string{"make"}}},
+			},
+		},
+	}
+}
+
+var _ = Describe("Testing job matrix", func() {
+
+	Context("matrixCombinations", func() {
+		It("should return the cartesian product of the axes", func() {
+			combinations := matrixCombinations(envMatrix(map[string][]string{
+				"GO_VERSION": {"1.19", "1.20"},
+				"ARCH":       {"amd64", "arm64", "s390x"},
+			}).Axes)
+			Expect(combinations).To(HaveLen(6))
+			Expect(combinations[0]).To(Equal(map[string]string{"GO_VERSION": "1.19", "ARCH": "amd64"}))
+			Expect(combinations[5]).To(Equal(map[string]string{"GO_VERSION": "1.20", "ARCH": "s390x"}))
+		})
+
+		It("should return one empty combination without axes", func() {
+			Expect(matrixCombinations(nil)).To(Equal([]map[string]string{{}}))
+		})
+	})
+
+	Context("expandMatrixJobTasks", func() {
+		It("should not expand job tasks without a matrix", func() {
+			jobTasks := []*commonmodels.JobTask{matrixOriginJobTask("build")}
+			Expect(expandMatrixJobTasks(jobTasks, nil)).To(Equal(jobTasks))
+		})
+
+		It("should reject invalid matrixes", func() {
+			_, err := expandMatrixJobTasks([]*commonmodels.JobTask{matrixOriginJobTask("build")}, envMatrix(map[string][]string{"GO_VERSION": {}}))
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("should expand every job task to one job task per combination", func() {
+			jobTasks, err := expandMatrixJobTasks(
+				[]*commonmodels.JobTask{matrixOriginJobTask("build-a"), matrixOriginJobTask("build-b")},
+				envMatrix(map[string][]string{"GO_VERSION": {"1.19", "1.20"}}),
+			)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(jobTasks).To(HaveLen(4))
+
+			names := map[string]bool{}
+			for _, jobTask := range jobTasks {
+				names[jobTask.Name] = true
+				Expect(jobTask.Matrix.FailFast).To(BeTrue())
+				Expect(jobTask.Matrix.MaxParallel).To(Equal(2))
+				spec := jobTask.Spec.(*commonmodels.JobTaskFreestyleSpec)
+				Expect(spec.Properties.Envs).To(ContainElement(&commonmodels.KeyVal{Key: "GO_VERSION", Value: jobTask.Matrix.Values["GO_VERSION"], Type: commonmodels.StringType}))
+			}
+			Expect(names).To(HaveLen(4))
+
+			Expect(jobTasks[0].Matrix.Parent).To(Equal("build-a"))
+			Expect(jobTasks[0].Key).To(Equal("build.build-a"))
+			Expect(jobTasks[1].Key).To(Equal("build.build-a.matrix-2"))
+			Expect(jobTasks[0].JobInfo).To(HaveKeyWithValue(MatrixKey, "GO_VERSION=1.19"))
+		})
+
+		It("should keep the artifacts of the combinations apart", func() {
+			jobTasks, err := expandMatrixJobTasks([]*commonmodels.JobTask{matrixOriginJobTask("build")}, envMatrix(map[string][]string{"GO_VERSION": {"1.19", "1.20"}}))
+			Expect(err).NotTo(HaveOccurred())
+
+			first := jobTasks[0].Spec.(*commonmodels.JobTaskFreestyleSpec)
+			second := jobTasks[1].Spec.(*commonmodels.JobTaskFreestyleSpec)
+
+			archive := first.Steps[0].Spec.(*step.StepArchiveSpec)
+			Expect(archive.UploadDetail[0].DestinationPath).To(Equal("wf/1/" + jobTasks[0].Name + "/archive"))
+			Expect(archive.UploadDetail[1].DestinationPath).To(Equal("custom/dir/" + jobTasks[0].Name))
+
+			Expect(first.Properties.Envs[0].Value).To(Equal("koderover/app:v1"))
+			Expect(second.Properties.Envs[0].Value).To(HavePrefix("koderover/app:v1-"))
+
+			Expect(first.Steps).To(HaveLen(4))
+			Expect(first.Steps[1].Spec.(*step.StepArtifactPublishSpec).Tag).To(Equal("v1"))
+			Expect(second.Steps).To(HaveLen(3))
+			Expect(second.Steps[1].Spec.(*step.StepArtifactPublishSpec).Tag).To(HavePrefix("v1-"))
+			for _, stepTask := range second.Steps {
+				Expect(stepTask.JobName).To(Equal(jobTasks[1].Name))
+				Expect(stepTask.Name).NotTo(Equal("maven"))
+			}
+		})
+	})
+
+	Context("matrixJobTaskName", func() {
+		It("should keep long names of different combinations unique", func() {
+			name := strings.Repeat("a", 70)
+			first := matrixJobTaskName(name, matrixCombinationID(name, map[string]string{"GO_VERSION": "1.19"}))
+			second := matrixJobTaskName(name, matrixCombinationID(name, map[string]string{"GO_VERSION": "1.20"}))
+			Expect(len(first)).To(BeNumerically("<=", 63))
+			Expect(first).NotTo(Equal(second))
+		})
+
+		It("should keep the combinations of job tasks sharing a long prefix unique", func() {
+			prefix := strings.Repeat("a", 60)
+			values := map[string]string{"GO_VERSION": "1.19"}
+			Expect(matrixJobTaskName(prefix+"-x", matrixCombinationID(prefix+"-x", values))).
+				NotTo(Equal(matrixJobTaskName(prefix+"-y", matrixCombinationID(prefix+"-y", values))))
+		})
+	})
+})
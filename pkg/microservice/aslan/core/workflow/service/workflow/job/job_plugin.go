@@ -127,17 +127,17 @@ func (j *PluginJob) LintJob() error {
 	if err := commonmodels.IToiYaml(j.job.Spec, j.spec); err != nil {
 		return err
 	}
-	return checkOutputNames(j.spec.Plugin.Outputs)
+	return checkOutputs(j.spec.Plugin.Outputs)
 }
 
-func (j *PluginJob) GetOutPuts(log *zap.SugaredLogger) []string {
-	resp := []string{}
+func (j *PluginJob) GetOutPuts(log *zap.SugaredLogger) []*OutputDef {
+	resp := []*OutputDef{}
 	j.spec = &commonmodels.PluginJobSpec{}
 	if err := commonmodels.IToiYaml(j.job.Spec, j.spec); err != nil {
 		return resp
 	}
 
 	jobKey := j.job.Name
-	resp = append(resp, getOutputKey(jobKey, j.spec.Plugin.Outputs)...)
+	resp = append(resp, getOutputDefs(jobKey, j.spec.Plugin.Outputs)...)
 	return resp
 }
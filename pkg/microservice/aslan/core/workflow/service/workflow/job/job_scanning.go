@@ -314,8 +314,8 @@ func (j *ScanningJob) LintJob() error {
 	return nil
 }
 
-func (j *ScanningJob) GetOutPuts(log *zap.SugaredLogger) []string {
-	resp := []string{}
+func (j *ScanningJob) GetOutPuts(log *zap.SugaredLogger) []*OutputDef {
+	resp := []*OutputDef{}
 	j.spec = &commonmodels.ZadigScanningJobSpec{}
 	if err := commonmodels.IToiYaml(j.job.Spec, j.spec); err != nil {
 		return resp
@@ -331,7 +331,7 @@ func (j *ScanningJob) GetOutPuts(log *zap.SugaredLogger) []string {
 	}
 	for _, scanningInfo := range scanningInfos {
 		jobKey := strings.Join([]string{j.job.Name, scanningInfo.Name}, ".")
-		resp = append(resp, getOutputKey(jobKey, scanningInfo.Outputs)...)
+		resp = append(resp, getOutputDefs(jobKey, scanningInfo.Outputs)...)
 	}
 	return resp
 }
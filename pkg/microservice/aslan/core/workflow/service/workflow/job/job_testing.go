@@ -239,7 +239,7 @@ func (j *TestingJob) ToJobs(taskID int64) ([]*commonmodels.JobTask, error) {
 	}
 
 	j.job.Spec = j.spec
-	return resp, nil
+	return expandMatrixJobTasks(resp, j.spec.Matrix)
 }
 
 func (j *TestingJob) getOriginReferedJobTargets(jobName string) ([]*commonmodels.ServiceTestTarget, error) {
@@ -388,6 +388,21 @@ func (j *TestingJob) toJobtask(testing *commonmodels.TestModule, defaultS3 *comm
 		Spec:     step.StepGitSpec{Repos: renderRepos(testing.Repos, testingInfo.Repos, jobTaskSpec.Properties.Envs)},
 	}
 	jobTaskSpec.Steps = append(jobTaskSpec.Steps, gitStep)
+	// init test impact step
+	if testingInfo.TestImpact != nil && testingInfo.TestImpact.Enabled {
+		testImpactStep := &commonmodels.StepTask{
+			Name:     testing.Name + "-test-impact",
+			JobName:  jobTask.Name,
+			StepType: config.StepTestImpact,
+			Spec:     j.getTestImpactSpec(testingInfo, testing.Repos),
+		}
+		jobTaskSpec.Steps = append(jobTaskSpec.Steps, testImpactStep)
+		// the outputs of the step are shared with the shell step as envs
+		jobTask.Outputs = append([]*commonmodels.Output{
+			{Name: step.TestImpactFullEnv},
+			{Name: step.TestImpactTargetsEnv},
+		}, jobTask.Outputs...)
+	}
 	// init debug before step
 	debugBeforeStep := &commonmodels.StepTask{
 		Name:     testing.Name + "-debug_before",
@@ -453,7 +468,7 @@ func (j *TestingJob) toJobtask(testing *commonmodels.TestModule, defaultS3 *comm
 	}
 
 	// init junit report step
-	if len(testingInfo.TestResultPath) > 0 {
+	if len(testingInfo.TestResultPath) > 0 && testingInfo.TestResultFormat == "" {
 		junitStep := &commonmodels.StepTask{
 			Name:      config.TestJobJunitReportStepName,
 			JobName:   jobTask.Name,
@@ -465,11 +480,41 @@ func (j *TestingJob) toJobtask(testing *commonmodels.TestModule, defaultS3 *comm
 				TestName:  testing.Name,
 				DestDir:   "/tmp",
 				FileName:  "merged.xml",
+				// the failed cases are checked by the test report step, which knows the quarantined cases and fails
+				// if any result can not be parsed
+				IgnoreFailedCases: true,
 			},
 		}
 		jobTaskSpec.Steps = append(jobTaskSpec.Steps, junitStep)
 	}
 
+	// init test report step, the normalized report is kept for the test stats and flaky case detection,
+	// the failures of the quarantined cases do not fail the job
+	if len(testingInfo.TestResultPath) > 0 || len(testingInfo.CoverageReportPaths) > 0 {
+		testReportSpec := &step.StepTestReportSpec{
+			Format:            testingInfo.TestResultFormat,
+			CoveragePaths:     testingInfo.CoverageReportPaths,
+			CoverageFormat:    step.CoverageFormatAuto,
+			FailOnFailedCases: len(testingInfo.TestResultPath) > 0,
+			TestName:          testing.Name,
+			S3DestDir:         path.Join(j.workflow.Name, fmt.Sprint(taskID), jobTask.Name, "test-report"),
+		}
+		if len(testingInfo.TestResultPath) > 0 {
+			testReportSpec.ReportPaths = []string{testingInfo.TestResultPath}
+		}
+		if testReportSpec.Format == "" {
+			testReportSpec.Format = step.TestReportFormatJUnit
+		}
+		testReportStep := &commonmodels.StepTask{
+			Name:      config.TestJobTestReportStepName,
+			JobName:   jobTask.Name,
+			StepType:  config.StepTestReport,
+			Onfailure: true,
+			Spec:      testReportSpec,
+		}
+		jobTaskSpec.Steps = append(jobTaskSpec.Steps, testReportStep)
+	}
+
 	// init object storage step
 	if testingInfo.PostTest != nil && testingInfo.PostTest.ObjectStorageUpload != nil && testingInfo.PostTest.ObjectStorageUpload.Enabled {
 		modelS3, err := commonrepo.NewS3StorageColl().Find(testingInfo.PostTest.ObjectStorageUpload.ObjectStorageID)
@@ -500,11 +545,42 @@ func (j *TestingJob) toJobtask(testing *commonmodels.TestModule, defaultS3 *comm
 	return jobTask, nil
 }
 
+// getTestImpactSpec passes the changed files of the pull request which triggers the task to the test impact step,
+// the step runs the full suite for the other tasks
+func (j *TestingJob) getTestImpactSpec(testingInfo *commonmodels.Testing, repos []*types.Repository) *step.StepTestImpactSpec {
+	spec := &step.StepTestImpactSpec{
+		ChangedFiles:  []string{},
+		Mappings:      testingInfo.TestImpact.Mappings,
+		IgnorePaths:   testingInfo.TestImpact.IgnorePaths,
+		GoImportGraph: testingInfo.TestImpact.GoImportGraph,
+		OutputFile:    testingInfo.TestImpact.OutputFile,
+	}
+	hookPayload := j.workflow.HookPayload
+	if hookPayload == nil || !hookPayload.IsPr || len(hookPayload.ChangedFiles) == 0 {
+		return spec
+	}
+	for _, repo := range repos {
+		if repo.RepoName != hookPayload.Repo || (hookPayload.Owner != "" && repo.RepoOwner != hookPayload.Owner) {
+			continue
+		}
+		spec.ChangedFiles = hookPayload.ChangedFiles
+		spec.RepoDir = repo.RepoName
+		if repo.CheckoutPath != "" {
+			spec.RepoDir = repo.CheckoutPath
+		}
+		break
+	}
+	return spec
+}
+
 func (j *TestingJob) LintJob() error {
 	j.spec = &commonmodels.ZadigTestingJobSpec{}
 	if err := commonmodels.IToiYaml(j.job.Spec, j.spec); err != nil {
 		return err
 	}
+	if err := lintJobMatrix(j.spec.Matrix); err != nil {
+		return err
+	}
 	if j.spec.Source != config.SourceFromJob {
 		return nil
 	}
@@ -516,8 +592,8 @@ func (j *TestingJob) LintJob() error {
 	return nil
 }
 
-func (j *TestingJob) GetOutPuts(log *zap.SugaredLogger) []string {
-	resp := []string{}
+func (j *TestingJob) GetOutPuts(log *zap.SugaredLogger) []*OutputDef {
+	resp := []*OutputDef{}
 	j.spec = &commonmodels.ZadigTestingJobSpec{}
 	if err := commonmodels.IToiYaml(j.job.Spec, j.spec); err != nil {
 		return resp
@@ -533,7 +609,7 @@ func (j *TestingJob) GetOutPuts(log *zap.SugaredLogger) []string {
 	}
 	for _, testInfo := range testingInfos {
 		jobKey := strings.Join([]string{j.job.Name, testInfo.Name}, ".")
-		resp = append(resp, getOutputKey(jobKey, testInfo.Outputs)...)
+		resp = append(resp, getOutputDefs(jobKey, testInfo.Outputs)...)
 	}
 	return resp
 }
@@ -64,6 +64,10 @@ const (
 	checkShellStepStart  = "ls /zadig/debug/shell_step"
 	checkShellStepDone   = "ls /zadig/debug/shell_step_done"
 	setOrUnsetBreakpoint = "%s /zadig/debug/breakpoint_%s"
+
+	// defaultDebugOnFailureTTL and maxDebugOnFailureTTL are the minutes the pod of the failed job is kept for debugging
+	defaultDebugOnFailureTTL = 30
+	maxDebugOnFailureTTL     = 24 * 60
 )
 
 type CreateTaskV4Resp struct {
@@ -114,6 +118,10 @@ type JobTaskPreview struct {
 	Spec             interface{}   `bson:"spec"           json:"spec"`
 	// JobInfo contains the fields that make up the job task name, for frontend display
 	JobInfo interface{} `bson:"job_info" json:"job_info"`
+	// Matrix is set when the job task is expanded from a job with matrix, for frontend grouping
+	Matrix *commonmodels.JobTaskMatrix `bson:"matrix,omitempty" json:"matrix,omitempty"`
+	// Attempts is the run history of the job task with retry policy, log of every attempt is queried by its log name
+	Attempts []*commonmodels.JobTaskAttempt `bson:"attempts,omitempty" json:"attempts,omitempty"`
 }
 
 type ZadigBuildJobSpec struct {
@@ -459,11 +467,18 @@ func CreateWorkflowTaskV4(args *CreateWorkflowTaskV4Args, workflow *commonmodels
 			}
 			// add breakpoint_before when workflowTask is debug mode
 			for _, jobTask := range jobs {
+				jobTask.OriginName = job.Name
+				jobTask.DependsOn = job.DependsOn
+				jobTask.RunIf = job.RunIf
+				jobTask.RetryPolicy = job.RetryPolicy
 				switch config.JobType(jobTask.JobType) {
 				case config.JobFreestyle, config.JobZadigTesting, config.JobZadigBuild, config.JobZadigScanning:
 					if workflowTask.IsDebug {
 						jobTask.BreakpointBefore = true
 					}
+					if workflow.DebugOnFailure != nil && workflow.DebugOnFailure.Enabled {
+						jobTask.DebugOnFailureTTL = getDebugOnFailureTTL(workflow.DebugOnFailure) * 60
+					}
 				}
 			}
 
@@ -575,6 +590,8 @@ func RetryWorkflowTaskV4(workflowName string, taskID int64, logger *zap.SugaredL
 			jobTask.StartTime = 0
 			jobTask.EndTime = 0
 			jobTask.Error = ""
+			jobTask.FailureReason = ""
+			jobTask.ExitCode = 0
 			if t, ok := jobTaskMap[jobTask.Key]; ok {
 				jobTask.Spec = t.Spec
 			} else {
@@ -728,6 +745,14 @@ FOR:
 	return e.ErrSetBreakpoint.AddDesc("当前任务状态无法修改断点 ")
 }
 
+// getDebugOnFailureTTL returns the minutes the pod of the failed job is kept for debugging
+func getDebugOnFailureTTL(debugOnFailure *commonmodels.DebugOnFailure) int64 {
+	if debugOnFailure.TTL <= 0 {
+		return defaultDebugOnFailureTTL
+	}
+	return debugOnFailure.TTL
+}
+
 func EnableDebugWorkflowTaskV4(workflowName string, taskID int64, logger *zap.SugaredLogger) error {
 	w := workflowcontroller.GetWorkflowTaskInMap(workflowName, taskID)
 	if w == nil {
@@ -752,6 +777,27 @@ func EnableDebugWorkflowTaskV4(workflowName string, taskID int64, logger *zap.Su
 	return nil
 }
 
+func ListDebugSessionsV4(workflowName string, taskID int64, logger *zap.SugaredLogger) ([]*commonmodels.DebugSession, error) {
+	sessions, err := commonrepo.NewDebugSessionColl().ListByTask(workflowName, taskID)
+	if err != nil {
+		logger.Errorf("list debug sessions of %s/%d error: %s", workflowName, taskID, err)
+		return nil, e.ErrListDebugSession.AddErr(err)
+	}
+	return sessions, nil
+}
+
+func GetDebugSessionV4(workflowName, id string, logger *zap.SugaredLogger) (*commonmodels.DebugSession, error) {
+	session, err := commonrepo.NewDebugSessionColl().FindByID(id)
+	if err != nil {
+		logger.Errorf("get debug session %s error: %s", id, err)
+		return nil, e.ErrGetDebugSession.AddErr(err)
+	}
+	if session.WorkflowName != workflowName {
+		return nil, e.ErrGetDebugSession.AddDesc(fmt.Sprintf("debug session %s not found in workflow %s", id, workflowName))
+	}
+	return session, nil
+}
+
 func StopDebugWorkflowTaskJobV4(workflowName, jobName string, taskID int64, position string, logger *zap.SugaredLogger) error {
 	w := workflowcontroller.GetWorkflowTaskInMap(workflowName, taskID)
 	if w == nil {
@@ -1232,6 +1278,8 @@ func jobsToJobPreviews(jobs []*commonmodels.JobTask, context map[string]string,
 			BreakpointAfter:  job.BreakpointAfter,
 			CostSeconds:      costSeconds,
 			JobInfo:          job.JobInfo,
+			Matrix:           job.Matrix,
+			Attempts:         job.Attempts,
 		}
 		switch job.JobType {
 		case string(config.JobFreestyle):
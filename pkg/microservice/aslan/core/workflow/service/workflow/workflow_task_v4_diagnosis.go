@@ -0,0 +1,229 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
+	logservice "github.com/koderover/zadig/pkg/microservice/aslan/core/log/service"
+	"github.com/koderover/zadig/pkg/tool/cache"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/tool/llm"
+	"github.com/koderover/zadig/pkg/types/step"
+)
+
+const (
+	diagnosisLogTailLines  = 200
+	diagnosisMaxLogLength  = 12000
+	diagnosisMaxTestCases  = 20
+	diagnosisMaxTextLength = 1000
+	diagnosisRedacted      = "******"
+
+	jobFailureDiagnosisPrompt = `你是一个资深devops开发专家，我会提供一个执行失败的工作流任务的信息，包括任务基本信息、执行步骤、失败的测试用例以及用三重引号分割的日志最后部分。
+你需要找出任务失败的根本原因并给出修复建议，你的回答必须是一个json对象，不要包含其他任何内容，json格式如下：
+{"root_cause": "失败的根本原因", "evidence": ["支持该结论的关键日志或测试用例"], "suggested_fix": "具体可执行的修复建议", "confidence": "high、medium或low"}
+`
+)
+
+// diagnosisCache keeps the diagnosis of the same failure, so the llm is not called again when the result is viewed again
+var diagnosisCache = cache.New(false)
+
+type JobFailureDiagnosis struct {
+	JobName      string        `json:"job_name"`
+	JobType      string        `json:"job_type"`
+	Status       config.Status `json:"status"`
+	RootCause    string        `json:"root_cause"`
+	Evidence     []string      `json:"evidence"`
+	SuggestedFix string        `json:"suggested_fix"`
+	Confidence   string        `json:"confidence"`
+	// Raw is the answer of the llm when it is not in the expected format
+	Raw string `json:"raw,omitempty"`
+}
+
+// DiagnoseWorkflowTaskV4Job asks the llm why the job task failed with its log tail, steps and failed test cases,
+// values of the credential envs are redacted before they are sent to the llm.
+func DiagnoseWorkflowTaskV4Job(workflowName, jobName string, taskID int64, logger *zap.SugaredLogger) (*JobFailureDiagnosis, error) {
+	task, err := commonrepo.NewworkflowTaskv4Coll().Find(workflowName, taskID)
+	if err != nil {
+		logger.Errorf("find workflow task %s #%d error: %v", workflowName, taskID, err)
+		return nil, e.ErrGetTask.AddErr(err)
+	}
+	var jobTask *commonmodels.JobTask
+	for _, stage := range task.Stages {
+		for _, job := range stage.Jobs {
+			if job.Name == jobName {
+				jobTask = job
+			}
+		}
+	}
+	if jobTask == nil {
+		return nil, e.ErrInvalidParam.AddDesc(fmt.Sprintf("job %s not found in workflow task %s #%d", jobName, workflowName, taskID))
+	}
+	if jobTask.Status != config.StatusFailed && jobTask.Status != config.StatusTimeout {
+		return nil, e.ErrInvalidParam.AddDesc(fmt.Sprintf("job %s is %s, only failed jobs can be diagnosed", jobName, jobTask.Status))
+	}
+
+	prompt, err := getJobFailureDiagnosisPrompt(task, jobTask, logger)
+	if err != nil {
+		return nil, e.ErrDiagnoseJobFailure.AddErr(err)
+	}
+
+	ctx := context.Background()
+	client, err := commonservice.GetDefaultLLMClient(ctx)
+	if err != nil {
+		logger.Errorf("failed to get llm client, error: %v", err)
+		return nil, e.ErrDiagnoseJobFailure.AddErr(err)
+	}
+	// Parse caches the answer with llm.GetCacheKey of the client name and prompt
+	answer, err := client.Parse(ctx, prompt, diagnosisCache, llm.WithTemperature(0.2))
+	if err != nil {
+		logger.Errorf("failed to get answer from ai: %v, error: %v", client.GetName(), err)
+		return nil, e.ErrDiagnoseJobFailure.AddErr(err)
+	}
+
+	resp := &JobFailureDiagnosis{}
+	// models may wrap the json in markdown code blocks
+	answer = strings.TrimSpace(answer)
+	answer = strings.TrimPrefix(strings.TrimPrefix(answer, "```json"), "```")
+	answer = strings.TrimSuffix(answer, "```")
+	if err := json.Unmarshal([]byte(answer), resp); err != nil {
+		resp.Raw = answer
+	}
+	resp.JobName = jobTask.Name
+	resp.JobType = jobTask.JobType
+	resp.Status = jobTask.Status
+	return resp, nil
+}
+
+func getJobFailureDiagnosisPrompt(task *commonmodels.WorkflowTask, jobTask *commonmodels.JobTask, logger *zap.SugaredLogger) (string, error) {
+	secrets := []string{}
+	builder := &strings.Builder{}
+	fmt.Fprintf(builder, "任务类型: %s\n任务状态: %s\n", jobTask.JobType, jobTask.Status)
+	if jobTask.Error != "" {
+		fmt.Fprintf(builder, "错误信息: %s\n", jobTask.Error)
+	}
+	if jobTask.FailureReason != "" {
+		fmt.Fprintf(builder, "失败原因: %s, 退出码: %d\n", jobTask.FailureReason, jobTask.ExitCode)
+	}
+
+	switch jobTask.JobType {
+	case string(config.JobZadigBuild), string(config.JobZadigTesting), string(config.JobZadigScanning), string(config.JobFreestyle):
+		spec := &commonmodels.JobTaskFreestyleSpec{}
+		if err := commonmodels.IToi(jobTask.Spec, spec); err != nil {
+			return "", fmt.Errorf("decode job spec error: %v", err)
+		}
+		for _, env := range spec.Properties.Envs {
+			if env.IsCredential && env.Value != "" {
+				secrets = append(secrets, env.Value)
+			}
+		}
+		builder.WriteString("执行步骤:\n")
+		for _, stepTask := range spec.Steps {
+			fmt.Fprintf(builder, "- %s (%s)\n", stepTask.Name, stepTask.StepType)
+			// only scripts are sent, other step specs may contain the credentials of code hosts and registries
+			if stepTask.StepType != config.StepShell {
+				continue
+			}
+			shellSpec := &step.StepShellSpec{}
+			if err := commonmodels.IToi(stepTask.Spec, shellSpec); err != nil {
+				return "", fmt.Errorf("decode step %s spec error: %v", stepTask.Name, err)
+			}
+			script := shellSpec.Script
+			if script == "" {
+				script = strings.Join(shellSpec.Scripts, "\n")
+			}
+			fmt.Fprintf(builder, "脚本:\n%s\n", truncateDiagnosisText(script, diagnosisMaxLogLength/4))
+		}
+	}
+
+	if jobTask.JobType == string(config.JobZadigTesting) {
+		report, err := commonservice.GetWorkflowV4LocalTestSuite(task.WorkflowName, jobTask.Name, task.TaskID, logger)
+		if err != nil {
+			logger.Warnf("get test report of job %s error: %v", jobTask.Name, err)
+		}
+		if report != nil && report.FunctionTestSuite != nil {
+			suite := report.FunctionTestSuite
+			fmt.Fprintf(builder, "测试结果: 共 %d 个用例, 失败 %d 个, 错误 %d 个, 跳过 %d 个\n", suite.Tests, suite.Failures, suite.Errors, suite.Skips)
+			failedCases := 0
+			for _, testCase := range suite.TestCases {
+				if testCase.Failure == nil && testCase.Error == nil {
+					continue
+				}
+				if failedCases >= diagnosisMaxTestCases {
+					break
+				}
+				failedCases++
+				message := ""
+				if testCase.Failure != nil {
+					message = testCase.Failure.Message + "\n" + testCase.Failure.Text
+				} else {
+					message = testCase.Error.Message + "\n" + testCase.Error.Text
+				}
+				fmt.Fprintf(builder, "- 失败用例 %s.%s: %s\n", testCase.ClassName, testCase.Name, truncateDiagnosisText(strings.TrimSpace(message), diagnosisMaxTextLength))
+			}
+		}
+	}
+
+	jobLog, err := logservice.GetWorkflowV4JobContainerLogs(task.WorkflowName, jobTask.Name, task.TaskID, logger)
+	if err != nil {
+		// the job may fail before its pod started, diagnose with the error message only
+		logger.Warnf("get log of job %s error: %v", jobTask.Name, err)
+	}
+	fmt.Fprintf(builder, "日志最后 %d 行: \"\"\"%s\"\"\"\n", diagnosisLogTailLines, getDiagnosisLogTail(jobLog))
+
+	return jobFailureDiagnosisPrompt + redactSecrets(builder.String(), secrets), nil
+}
+
+func getDiagnosisLogTail(jobLog string) string {
+	lines := strings.Split(strings.TrimSpace(jobLog), "\n")
+	if len(lines) > diagnosisLogTailLines {
+		lines = lines[len(lines)-diagnosisLogTailLines:]
+	}
+	tail := []rune(strings.Join(lines, "\n"))
+	if len(tail) > diagnosisMaxLogLength {
+		tail = tail[len(tail)-diagnosisMaxLogLength:]
+	}
+	return string(tail)
+}
+
+// truncateDiagnosisText keeps the first length characters of the text, a multi-byte character is never split
+func truncateDiagnosisText(text string, length int) string {
+	runes := []rune(text)
+	if len(runes) <= length {
+		return text
+	}
+	return string(runes[:length]) + "..."
+}
+
+func redactSecrets(text string, secrets []string) string {
+	// replace the longer secrets first, in case one secret contains another
+	sort.Slice(secrets, func(i, j int) bool { return len(secrets[i]) > len(secrets[j]) })
+	for _, secret := range secrets {
+		text = strings.ReplaceAll(text, secret, diagnosisRedacted)
+	}
+	return text
+}
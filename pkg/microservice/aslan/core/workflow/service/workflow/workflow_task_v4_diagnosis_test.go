@@ -0,0 +1,66 @@
+/*
+Copyright 2021 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Testing job failure diagnosis", func() {
+
+	Context("redactSecrets", func() {
+		It("should replace every secret", func() {
+			text := redactSecrets("token=abc123 password=abc", []string{"abc", "abc123"})
+			Expect(text).To(Equal("token=****** password=******"))
+		})
+		It("should keep the text without secrets", func() {
+			Expect(redactSecrets("nothing to hide", nil)).To(Equal("nothing to hide"))
+		})
+	})
+
+	Context("getDiagnosisLogTail", func() {
+		It("should keep the last lines of the log", func() {
+			lines := []string{}
+			for i := 0; i < diagnosisLogTailLines+10; i++ {
+				lines = append(lines, fmt.Sprintf("line %d", i))
+			}
+			tail := getDiagnosisLogTail(strings.Join(lines, "\n"))
+			Expect(strings.Split(tail, "\n")).To(HaveLen(diagnosisLogTailLines))
+			Expect(tail).To(HavePrefix("line 10\n"))
+			Expect(tail).To(HaveSuffix(fmt.Sprintf("line %d", diagnosisLogTailLines+9)))
+		})
+		It("should not split the multi-byte characters of a long log", func() {
+			tail := getDiagnosisLogTail(strings.Repeat("构建失败", diagnosisMaxLogLength))
+			Expect(utf8.ValidString(tail)).To(BeTrue())
+			Expect(utf8.RuneCountInString(tail)).To(Equal(diagnosisMaxLogLength))
+		})
+	})
+
+	Context("truncateDiagnosisText", func() {
+		It("should keep the short text", func() {
+			Expect(truncateDiagnosisText("断言失败", 4)).To(Equal("断言失败"))
+		})
+		It("should truncate the text by characters", func() {
+			Expect(truncateDiagnosisText("断言失败: expected 1", 4)).To(Equal("断言失败..."))
+		})
+	})
+})
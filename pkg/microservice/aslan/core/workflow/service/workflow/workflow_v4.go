@@ -56,11 +56,13 @@ import (
 	templaterepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb/template"
 	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/collaboration"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/instantmessage"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/kube"
 	larkservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/lark"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/repository"
 	commomtemplate "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/template"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/webhook"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/workflowcontroller/jobcontroller"
 	commontypes "github.com/koderover/zadig/pkg/microservice/aslan/core/common/types"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/workflow/service/workflow/job"
 	jobctl "github.com/koderover/zadig/pkg/microservice/aslan/core/workflow/service/workflow/job"
@@ -265,6 +267,7 @@ func UpdateWorkflowV4(name, user string, inputWorkflow *commonmodels.WorkflowV4,
 	inputWorkflow.GeneralHookCtls = workflow.GeneralHookCtls
 	inputWorkflow.MeegoHookCtls = workflow.MeegoHookCtls
 	inputWorkflow.CustomField = workflow.CustomField
+	keepWebHookNotifySecrets(inputWorkflow, workflow)
 
 	for _, stage := range inputWorkflow.Stages {
 		for _, job := range stage.Jobs {
@@ -834,9 +837,38 @@ func ensureWorkflowV4Resp(encryptedKey string, workflow *commonmodels.WorkflowV4
 			}
 		}
 	}
+	maskWebHookNotifySecrets(workflow)
 	return nil
 }
 
+// maskWebHookNotifySecrets shows the webhook notify secrets as masked, the plain secret is never returned
+func maskWebHookNotifySecrets(workflow *commonmodels.WorkflowV4) {
+	for _, notify := range workflow.NotifyCtls {
+		if notify == nil || notify.WebHookNotify == nil || notify.WebHookNotify.EncryptedSecret == "" {
+			continue
+		}
+		notify.WebHookNotify.Secret = setting.MaskValue
+	}
+}
+
+// keepWebHookNotifySecrets keeps the stored secret of the webhook notify whose secret is submitted back masked,
+// the webhook notifies are matched by id since several of them may share the address
+func keepWebHookNotifySecrets(input, existed *commonmodels.WorkflowV4) {
+	encryptedSecrets := make(map[string]string)
+	for _, notify := range existed.NotifyCtls {
+		if notify == nil || notify.WebHookNotify == nil || notify.WebHookNotify.ID == "" {
+			continue
+		}
+		encryptedSecrets[notify.WebHookNotify.ID] = notify.WebHookNotify.EncryptedSecret
+	}
+	for _, notify := range input.NotifyCtls {
+		if notify == nil || notify.WebHookNotify == nil || notify.WebHookNotify.Secret != setting.MaskValue {
+			continue
+		}
+		notify.WebHookNotify.EncryptedSecret = encryptedSecrets[notify.WebHookNotify.ID]
+	}
+}
+
 func LintWorkflowV4(workflow *commonmodels.WorkflowV4, logger *zap.SugaredLogger) error {
 	if workflow.Project == "" {
 		err := fmt.Errorf("project should not be empty")
@@ -870,6 +902,12 @@ func LintWorkflowV4(workflow *commonmodels.WorkflowV4, logger *zap.SugaredLogger
 			return e.ErrUpsertWorkflow.AddDesc("common workflow only support k8s and helm project")
 		}
 	}
+	for _, notify := range workflow.NotifyCtls {
+		if err := instantmessage.LintNotifyCtl(notify); err != nil {
+			logger.Errorf("notification info error: %v", err)
+			return e.ErrUpsertWorkflow.AddDesc(fmt.Sprintf("notification info error: %v", err))
+		}
+	}
 	stageNameMap := make(map[string]bool)
 	jobNameMap := make(map[string]string)
 
@@ -904,6 +942,108 @@ func LintWorkflowV4(workflow *commonmodels.WorkflowV4, logger *zap.SugaredLogger
 				logger.Errorf("lint job %s failed: %v", job.Name, err)
 				return e.ErrUpsertWorkflow.AddErr(err)
 			}
+			if job.RunIf != "" {
+				if err := jobcontroller.LintRunIf(job.RunIf, workflow); err != nil {
+					logger.Errorf("job %s run_if expression is invalid: %v", job.Name, err)
+					return e.ErrUpsertWorkflow.AddDesc(fmt.Sprintf("job %s run_if expression is invalid: %v", job.Name, err))
+				}
+			}
+			if err := lintJobRetryPolicy(job.RetryPolicy); err != nil {
+				logger.Errorf("job %s retry policy error: %v", job.Name, err)
+				return e.ErrUpsertWorkflow.AddDesc(fmt.Sprintf("job %s retry policy error: %v", job.Name, err))
+			}
+		}
+	}
+	if err := lintJobDependencies(workflow); err != nil {
+		logger.Errorf("lint job dependencies failed: %v", err)
+		return e.ErrUpsertWorkflow.AddErr(err)
+	}
+	if workflow.DebugOnFailure != nil && workflow.DebugOnFailure.TTL > maxDebugOnFailureTTL {
+		logger.Errorf("debug on failure ttl %d is above %d minutes", workflow.DebugOnFailure.TTL, maxDebugOnFailureTTL)
+		return e.ErrUpsertWorkflow.AddDesc(fmt.Sprintf("debug on failure ttl should be at most %d minutes", maxDebugOnFailureTTL))
+	}
+	if err := jobctl.LintOutputRefs(workflow, logger); err != nil {
+		logger.Errorf("lint job output references failed: %v", err)
+		return e.ErrUpsertWorkflow.AddErr(err)
+	}
+	return nil
+}
+
+// lintJobDependencies makes sure depends_on only refers to existing jobs in the same or earlier stages,
+// and that the jobs do not depend on each other circularly.
+func lintJobDependencies(workflow *commonmodels.WorkflowV4) error {
+	stageIndexMap := make(map[string]int)
+	dependsOnMap := make(map[string][]string)
+	for i, stage := range workflow.Stages {
+		for _, job := range stage.Jobs {
+			stageIndexMap[job.Name] = i
+			dependsOnMap[job.Name] = job.DependsOn
+		}
+	}
+	for jobName, dependsOn := range dependsOnMap {
+		for _, upstream := range dependsOn {
+			if upstream == jobName {
+				return errors.Errorf("job %s can not depend on itself", jobName)
+			}
+			index, ok := stageIndexMap[upstream]
+			if !ok {
+				return errors.Errorf("job %s depends on job %s which does not exist", jobName, upstream)
+			}
+			if index > stageIndexMap[jobName] {
+				return errors.Errorf("job %s can not depend on job %s in a later stage", jobName, upstream)
+			}
+		}
+	}
+
+	// 0: not visited, 1: visiting, 2: visited
+	visitState := make(map[string]int)
+	var visit func(jobName string) error
+	visit = func(jobName string) error {
+		switch visitState[jobName] {
+		case 1:
+			return errors.Errorf("circular dependency found on job %s", jobName)
+		case 2:
+			return nil
+		}
+		visitState[jobName] = 1
+		for _, upstream := range dependsOnMap[jobName] {
+			if err := visit(upstream); err != nil {
+				return err
+			}
+		}
+		visitState[jobName] = 2
+		return nil
+	}
+	for _, stage := range workflow.Stages {
+		for _, job := range stage.Jobs {
+			if err := visit(job.Name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func lintJobRetryPolicy(policy *commonmodels.JobRetryPolicy) error {
+	if policy == nil {
+		return nil
+	}
+	if policy.MaxAttempts < 1 || policy.MaxAttempts > config.MaxJobRetryAttempts {
+		return errors.Errorf("max attempts should be between 1 and %d", config.MaxJobRetryAttempts)
+	}
+	if policy.Backoff < 0 || policy.MaxBackoff < 0 || policy.BackoffFactor < 0 {
+		return errors.New("backoff can not be negative")
+	}
+	for _, reason := range policy.RetryOn {
+		switch reason {
+		case config.JobFailureTimeout, config.JobFailurePodEvicted, config.JobFailureExitCode:
+		default:
+			return errors.Errorf("unsupported failure reason: %s", reason)
+		}
+	}
+	for _, code := range policy.ExitCodes {
+		if code <= 0 || code > 255 {
+			return errors.Errorf("invalid exit code: %d", code)
 		}
 	}
 	return nil
@@ -968,73 +1108,133 @@ func lintApprovals(approval *commonmodels.Approval) error {
 		return errors.Errorf("invalid approval type %s", approval.Type)
 	}
 
+	return lintApprovalEscalations(approval)
+}
+
+func lintApprovalEscalations(approval *commonmodels.Approval) error {
+	if approval.ReminderInterval < 0 {
+		return errors.New("approval reminder interval should not be negative")
+	}
+	if approval.TimeoutAction != "" && approval.TimeoutAction != config.ApprovalTimeoutApprove && approval.TimeoutAction != config.ApprovalTimeoutReject {
+		return errors.Errorf("invalid approval timeout action %s", approval.TimeoutAction)
+	}
+	timeout := approval.GetTimeout()
+	after := 0
+	for i, escalation := range approval.Escalations {
+		if escalation.After <= after {
+			return errors.Errorf("approval escalation %d should start later than the previous one", i)
+		}
+		if escalation.After >= timeout {
+			return errors.Errorf("approval escalation %d should start before the approval timeout", i)
+		}
+		if err := lintApprovalEscalationGroup(escalation); err != nil {
+			return errors.Wrapf(err, "approval escalation %d", i)
+		}
+		after = escalation.After
+	}
 	return nil
 }
 
+// lintApprovalEscalationGroup checks the escalated approver group the same way as the approval of a stage
+func lintApprovalEscalationGroup(escalation *commonmodels.ApprovalEscalation) error {
+	switch escalation.GetType() {
+	case config.NativeApproval:
+		if len(escalation.ApproveUsers) == 0 {
+			return errors.New("num of approver is 0")
+		}
+		return nil
+	case config.LarkApproval, config.DingTalkApproval:
+		return lintApprovals(&commonmodels.Approval{
+			Enabled:          true,
+			Type:             escalation.GetType(),
+			LarkApproval:     escalation.LarkApproval,
+			DingTalkApproval: escalation.DingTalkApproval,
+		})
+	default:
+		return errors.Errorf("invalid approval type %s", escalation.Type)
+	}
+}
+
 func createLarkApprovalDefinition(workflow *commonmodels.WorkflowV4) error {
 	for _, stage := range workflow.Stages {
 		if stage.Approval == nil {
 			continue
 		}
-		if data := stage.Approval.LarkApproval; data != nil && data.ID != "" {
-			larkInfo, err := commonrepo.NewIMAppColl().GetByID(context.Background(), stage.Approval.LarkApproval.ID)
-			if err != nil {
-				return errors.Wrapf(err, "get lark app %s", stage.Approval.LarkApproval.ID)
-			}
-			if larkInfo.Type != string(config.LarkApproval) {
-				return errors.Errorf("lark app %s is not lark approval", stage.Approval.LarkApproval.ID)
+		larkApprovals := []*commonmodels.LarkApproval{stage.Approval.LarkApproval}
+		for _, escalation := range stage.Approval.Escalations {
+			larkApprovals = append(larkApprovals, escalation.LarkApproval)
+		}
+		for _, data := range larkApprovals {
+			if err := createLarkApprovalDefinitionForNodes(data); err != nil {
+				return err
 			}
+		}
+	}
+	return nil
+}
 
-			if larkInfo.LarkApprovalCodeList == nil {
-				larkInfo.LarkApprovalCodeList = make(map[string]string)
-			}
-			// skip if this node type approval definition already created
-			if approvalNodeTypeID := larkInfo.LarkApprovalCodeList[data.GetNodeTypeKey()]; approvalNodeTypeID != "" {
-				log.Infof("lark approval definition %s already created", approvalNodeTypeID)
-				continue
-			}
+// createLarkApprovalDefinitionForNodes creates the lark approval definition for the node types of the approval if not created yet
+func createLarkApprovalDefinitionForNodes(data *commonmodels.LarkApproval) error {
+	if data == nil || data.ID == "" {
+		return nil
+	}
+	larkInfo, err := commonrepo.NewIMAppColl().GetByID(context.Background(), data.ID)
+	if err != nil {
+		return errors.Wrapf(err, "get lark app %s", data.ID)
+	}
+	if larkInfo.Type != string(config.LarkApproval) {
+		return errors.Errorf("lark app %s is not lark approval", data.ID)
+	}
 
-			// create this node type approval definition and save to db
-			client, err := larkservice.GetLarkClientByIMAppID(data.ID)
-			if err != nil {
-				return errors.Wrapf(err, "get lark client by im app id %s", data.ID)
-			}
-			nodesArgs := make([]*lark.ApprovalNode, 0)
-			for _, node := range data.ApprovalNodes {
-				nodesArgs = append(nodesArgs, &lark.ApprovalNode{
-					Type: node.Type,
-					ApproverIDList: func() (re []string) {
-						for _, user := range node.ApproveUsers {
-							re = append(re, user.ID)
-						}
-						return
-					}(),
-				})
-			}
+	if larkInfo.LarkApprovalCodeList == nil {
+		larkInfo.LarkApprovalCodeList = make(map[string]string)
+	}
+	// skip if this node type approval definition already created
+	if approvalNodeTypeID := larkInfo.LarkApprovalCodeList[data.GetNodeTypeKey()]; approvalNodeTypeID != "" {
+		log.Infof("lark approval definition %s already created", approvalNodeTypeID)
+		return nil
+	}
 
-			approvalCode, err := client.CreateApprovalDefinition(&lark.CreateApprovalDefinitionArgs{
-				Name:        "Zadig 工作流",
-				Description: "Zadig 工作流-" + data.GetNodeTypeKey(),
-				Nodes:       nodesArgs,
-			})
-			if err != nil {
-				return errors.Wrap(err, "create lark approval definition")
-			}
-			err = client.SubscribeApprovalDefinition(&lark.SubscribeApprovalDefinitionArgs{
-				ApprovalID: approvalCode,
-			})
-			if err != nil {
-				return errors.Wrap(err, "subscribe lark approval definition")
-			}
-			larkInfo.LarkApprovalCodeList[data.GetNodeTypeKey()] = approvalCode
-			if err := commonrepo.NewIMAppColl().Update(context.Background(), stage.Approval.LarkApproval.ID, larkInfo); err != nil {
-				return errors.Wrap(err, "update lark approval data")
-			}
-			log.Infof("create lark approval definition %s, key: %s", approvalCode, data.GetNodeTypeKey())
-		}
+	// create this node type approval definition and save to db
+	client, err := larkservice.GetLarkClientByIMAppID(data.ID)
+	if err != nil {
+		return errors.Wrapf(err, "get lark client by im app id %s", data.ID)
 	}
+	nodesArgs := make([]*lark.ApprovalNode, 0)
+	for _, node := range data.ApprovalNodes {
+		nodesArgs = append(nodesArgs, &lark.ApprovalNode{
+			Type: node.Type,
+			ApproverIDList: func() (re []string) {
+				for _, user := range node.ApproveUsers {
+					re = append(re, user.ID)
+				}
+				return
+			}(),
+		})
+	}
+
+	approvalCode, err := client.CreateApprovalDefinition(&lark.CreateApprovalDefinitionArgs{
+		Name:        "Zadig 工作流",
+		Description: "Zadig 工作流-" + data.GetNodeTypeKey(),
+		Nodes:       nodesArgs,
+	})
+	if err != nil {
+		return errors.Wrap(err, "create lark approval definition")
+	}
+	err = client.SubscribeApprovalDefinition(&lark.SubscribeApprovalDefinitionArgs{
+		ApprovalID: approvalCode,
+	})
+	if err != nil {
+		return errors.Wrap(err, "subscribe lark approval definition")
+	}
+	larkInfo.LarkApprovalCodeList[data.GetNodeTypeKey()] = approvalCode
+	if err := commonrepo.NewIMAppColl().Update(context.Background(), data.ID, larkInfo); err != nil {
+		return errors.Wrap(err, "update lark approval data")
+	}
+	log.Infof("create lark approval definition %s, key: %s", approvalCode, data.GetNodeTypeKey())
 	return nil
 }
+
 func CreateWebhookForWorkflowV4(workflowName string, input *commonmodels.WorkflowV4Hook, logger *zap.SugaredLogger) error {
 	if err := jobctl.InstantiateWorkflow(input.WorkflowArg); err != nil {
 		logger.Errorf("instantiate hook args error: %s", err)
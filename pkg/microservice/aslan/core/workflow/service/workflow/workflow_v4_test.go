@@ -0,0 +1,134 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/setting"
+)
+
+func dependentJob(name string, dependsOn ...string) *commonmodels.Job {
+	return &commonmodels.Job{Name: name, DependsOn: dependsOn}
+}
+
+func workflowWithStages(stages ...[]*commonmodels.Job) *commonmodels.WorkflowV4 {
+	workflow := &commonmodels.WorkflowV4{}
+	for _, jobs := range stages {
+		workflow.Stages = append(workflow.Stages, &commonmodels.WorkflowStage{Jobs: jobs})
+	}
+	return workflow
+}
+
+var _ = Describe("Testing workflow v4 lint", func() {
+
+	Context("lintJobDependencies", func() {
+		It("should accept dependencies on jobs of the same and earlier stages", func() {
+			workflow := workflowWithStages(
+				[]*commonmodels.Job{dependentJob("build-a"), dependentJob("build-b"), dependentJob("lint", "build-a")},
+				[]*commonmodels.Job{dependentJob("deploy", "build-a", "lint"), dependentJob("test", "deploy", "build-b")},
+			)
+			Expect(lintJobDependencies(workflow)).To(Succeed())
+		})
+
+		It("should reject a job depending on itself", func() {
+			workflow := workflowWithStages([]*commonmodels.Job{dependentJob("build", "build")})
+			Expect(lintJobDependencies(workflow)).To(MatchError(ContainSubstring("itself")))
+		})
+
+		It("should reject dependencies on missing jobs", func() {
+			workflow := workflowWithStages([]*commonmodels.Job{dependentJob("deploy", "build")})
+			Expect(lintJobDependencies(workflow)).To(MatchError(ContainSubstring("does not exist")))
+		})
+
+		It("should reject dependencies on jobs of later stages", func() {
+			workflow := workflowWithStages(
+				[]*commonmodels.Job{dependentJob("deploy", "build")},
+				[]*commonmodels.Job{dependentJob("build")},
+			)
+			Expect(lintJobDependencies(workflow)).To(MatchError(ContainSubstring("later stage")))
+		})
+
+		It("should reject circular dependencies", func() {
+			workflow := workflowWithStages(
+				[]*commonmodels.Job{dependentJob("a", "c"), dependentJob("b", "a"), dependentJob("c", "b")},
+			)
+			Expect(lintJobDependencies(workflow)).To(MatchError(ContainSubstring("circular dependency")))
+		})
+
+		It("should reject cycles through jobs of later stages", func() {
+			workflow := workflowWithStages(
+				[]*commonmodels.Job{dependentJob("a"), dependentJob("b", "d")},
+				[]*commonmodels.Job{dependentJob("c", "b"), dependentJob("d", "c")},
+			)
+			Expect(lintJobDependencies(workflow)).To(HaveOccurred())
+		})
+	})
+
+	Context("webhook notify secrets", func() {
+		webHookWorkflow := func(notifies ...*commonmodels.WebHookNotify) *commonmodels.WorkflowV4 {
+			workflow := &commonmodels.WorkflowV4{}
+			for _, notify := range notifies {
+				workflow.NotifyCtls = append(workflow.NotifyCtls, &commonmodels.NotifyCtl{WebHookNotify: notify})
+			}
+			return workflow
+		}
+
+		It("should mask only the stored secrets", func() {
+			workflow := webHookWorkflow(
+				&commonmodels.WebHookNotify{Address: "https://a", EncryptedSecret: "encrypted"},
+				&commonmodels.WebHookNotify{Address: "https://b"},
+			)
+			maskWebHookNotifySecrets(workflow)
+			Expect(workflow.NotifyCtls[0].WebHookNotify.Secret).To(Equal(setting.MaskValue))
+			Expect(workflow.NotifyCtls[1].WebHookNotify.Secret).To(BeEmpty())
+		})
+
+		It("should keep the stored secret when it is submitted back masked", func() {
+			existed := webHookWorkflow(
+				&commonmodels.WebHookNotify{ID: "a", Address: "https://a", EncryptedSecret: "encrypted-a"},
+				&commonmodels.WebHookNotify{ID: "b", Address: "https://b", EncryptedSecret: "encrypted-b"},
+			)
+			input := webHookWorkflow(
+				&commonmodels.WebHookNotify{ID: "a", Address: "https://a", Secret: setting.MaskValue},
+				&commonmodels.WebHookNotify{ID: "b", Address: "https://b", Secret: "new-secret"},
+				&commonmodels.WebHookNotify{Address: "https://c", Secret: setting.MaskValue},
+			)
+			keepWebHookNotifySecrets(input, existed)
+			Expect(input.NotifyCtls[0].WebHookNotify.EncryptedSecret).To(Equal("encrypted-a"))
+			Expect(input.NotifyCtls[1].WebHookNotify.EncryptedSecret).To(BeEmpty())
+			Expect(input.NotifyCtls[2].WebHookNotify.EncryptedSecret).To(BeEmpty())
+		})
+
+		It("should keep the secrets of the webhook notifies with the same address", func() {
+			existed := webHookWorkflow(
+				&commonmodels.WebHookNotify{ID: "a", Address: "https://hook", EncryptedSecret: "encrypted-a"},
+				&commonmodels.WebHookNotify{ID: "b", Address: "https://hook", EncryptedSecret: "encrypted-b"},
+			)
+			// the webhook notifies are reordered and the address of the first one is changed
+			input := webHookWorkflow(
+				&commonmodels.WebHookNotify{ID: "b", Address: "https://hook", Secret: setting.MaskValue},
+				&commonmodels.WebHookNotify{ID: "a", Address: "https://other", Secret: setting.MaskValue},
+			)
+			keepWebHookNotifySecrets(input, existed)
+			Expect(input.NotifyCtls[0].WebHookNotify.EncryptedSecret).To(Equal("encrypted-b"))
+			Expect(input.NotifyCtls[1].WebHookNotify.EncryptedSecret).To(Equal("encrypted-a"))
+		})
+	})
+})
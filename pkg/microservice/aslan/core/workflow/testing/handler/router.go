@@ -53,6 +53,17 @@ func (*Router) Inject(router *gin.RouterGroup) {
 		tester.DELETE("/:name", DeleteTestModule)
 	}
 
+	// ---------------------------------------------------------------------------------------
+	// flaky test case quarantine APIs
+	// ---------------------------------------------------------------------------------------
+	quarantine := router.Group("quarantine")
+	{
+		quarantine.GET("", ListTestCaseQuarantines)
+		quarantine.POST("", CreateTestCaseQuarantine)
+		quarantine.PUT("/:id", UpdateTestCaseQuarantine)
+		quarantine.DELETE("/:id", DeleteTestCaseQuarantine)
+	}
+
 	// ---------------------------------------------------------------------------------------
 	// Code scan APIs
 	// ---------------------------------------------------------------------------------------
@@ -0,0 +1,216 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/workflow/testing/service"
+	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+// @Summary List flaky and quarantined test cases
+// @Description List the test cases detected as flaky or quarantined by users
+// @Tags 	testing
+// @Accept 	json
+// @Produce json
+// @Param 	projectName		query		string										true	"project name"
+// @Param 	testName		query		string										false	"test name"
+// @Param 	quarantined		query		bool										false	"filter by the quarantine status"
+// @Success 200 			{array} 	commonmodels.TestCaseQuarantine
+// @Router /api/aslan/testing/quarantine [get]
+func ListTestCaseQuarantines(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Logger.Errorf("failed to generate authorization info for user: %s, error: %s", ctx.UserID, err)
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	projectKey := c.Query("projectName")
+	if projectKey == "" {
+		ctx.Err = e.ErrInvalidParam.AddDesc("projectName is required")
+		return
+	}
+
+	// authorization check
+	if !ctx.Resources.IsSystemAdmin {
+		if _, ok := ctx.Resources.ProjectAuthInfo[projectKey]; !ok {
+			ctx.UnAuthorized = true
+			return
+		}
+
+		if !ctx.Resources.ProjectAuthInfo[projectKey].IsProjectAdmin &&
+			!ctx.Resources.ProjectAuthInfo[projectKey].Test.View {
+			ctx.UnAuthorized = true
+			return
+		}
+	}
+
+	var quarantined *bool
+	if value := c.Query("quarantined"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			ctx.Err = e.ErrInvalidParam.AddDesc("invalid quarantined")
+			return
+		}
+		quarantined = &parsed
+	}
+
+	ctx.Resp, ctx.Err = service.ListTestCaseQuarantines(projectKey, c.Query("testName"), quarantined, ctx.Logger)
+}
+
+// @Summary Quarantine a test case
+// @Description Quarantine a test case, the failures of it do not fail the testing jobs
+// @Tags 	testing
+// @Accept 	json
+// @Produce json
+// @Param 	projectName		query		string								true	"project name"
+// @Param 	body 			body 		commonmodels.TestCaseQuarantine 	true 	"body"
+// @Success 200
+// @Router /api/aslan/testing/quarantine [post]
+func CreateTestCaseQuarantine(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Logger.Errorf("failed to generate authorization info for user: %s, error: %s", ctx.UserID, err)
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	projectKey := c.Query("projectName")
+	args := new(commonmodels.TestCaseQuarantine)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc("invalid test case quarantine args")
+		return
+	}
+	args.ProjectName = projectKey
+	internalhandler.InsertOperationLog(c, ctx.UserName, projectKey, "新增", "项目管理-测试-隔离用例", fmt.Sprintf("%s/%s", args.TestName, args.CaseName), "", ctx.Logger)
+
+	// authorization check
+	if !ctx.Resources.IsSystemAdmin {
+		if _, ok := ctx.Resources.ProjectAuthInfo[projectKey]; !ok {
+			ctx.UnAuthorized = true
+			return
+		}
+
+		if !ctx.Resources.ProjectAuthInfo[projectKey].IsProjectAdmin &&
+			!ctx.Resources.ProjectAuthInfo[projectKey].Test.Edit {
+			ctx.UnAuthorized = true
+			return
+		}
+	}
+
+	args.UpdatedBy = ctx.UserName
+	ctx.Err = service.CreateTestCaseQuarantine(args, ctx.Logger)
+}
+
+// @Summary Update the quarantine of a test case
+// @Description Quarantine or release a flaky test case
+// @Tags 	testing
+// @Accept 	json
+// @Produce json
+// @Param 	id				path		string									true	"id"
+// @Param 	projectName		query		string									true	"project name"
+// @Param 	body 			body 		service.UpdateTestCaseQuarantineArgs 	true 	"body"
+// @Success 200
+// @Router /api/aslan/testing/quarantine/{id} [put]
+func UpdateTestCaseQuarantine(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Logger.Errorf("failed to generate authorization info for user: %s, error: %s", ctx.UserID, err)
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	projectKey := c.Query("projectName")
+	args := new(service.UpdateTestCaseQuarantineArgs)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc("invalid test case quarantine args")
+		return
+	}
+	internalhandler.InsertOperationLog(c, ctx.UserName, projectKey, "更新", "项目管理-测试-隔离用例", c.Param("id"), fmt.Sprintf("quarantined: %t", args.Quarantined), ctx.Logger)
+
+	// authorization check
+	if !ctx.Resources.IsSystemAdmin {
+		if _, ok := ctx.Resources.ProjectAuthInfo[projectKey]; !ok {
+			ctx.UnAuthorized = true
+			return
+		}
+
+		if !ctx.Resources.ProjectAuthInfo[projectKey].IsProjectAdmin &&
+			!ctx.Resources.ProjectAuthInfo[projectKey].Test.Edit {
+			ctx.UnAuthorized = true
+			return
+		}
+	}
+
+	ctx.Err = service.UpdateTestCaseQuarantine(projectKey, c.Param("id"), ctx.UserName, args, ctx.Logger)
+}
+
+// @Summary Delete a flaky or quarantined test case
+// @Description Delete a test case from the list, it is added again if it is detected as flaky later
+// @Tags 	testing
+// @Accept 	json
+// @Produce json
+// @Param 	id				path		string		true	"id"
+// @Param 	projectName		query		string		true	"project name"
+// @Success 200
+// @Router /api/aslan/testing/quarantine/{id} [delete]
+func DeleteTestCaseQuarantine(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Logger.Errorf("failed to generate authorization info for user: %s, error: %s", ctx.UserID, err)
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	projectKey := c.Query("projectName")
+	internalhandler.InsertOperationLog(c, ctx.UserName, projectKey, "删除", "项目管理-测试-隔离用例", c.Param("id"), "", ctx.Logger)
+
+	// authorization check
+	if !ctx.Resources.IsSystemAdmin {
+		if _, ok := ctx.Resources.ProjectAuthInfo[projectKey]; !ok {
+			ctx.UnAuthorized = true
+			return
+		}
+
+		if !ctx.Resources.ProjectAuthInfo[projectKey].IsProjectAdmin &&
+			!ctx.Resources.ProjectAuthInfo[projectKey].Test.Edit {
+			ctx.UnAuthorized = true
+			return
+		}
+	}
+
+	ctx.Err = service.DeleteTestCaseQuarantine(projectKey, c.Param("id"), ctx.Logger)
+}
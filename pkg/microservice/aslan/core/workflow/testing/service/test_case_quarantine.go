@@ -0,0 +1,84 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"go.uber.org/zap"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+type UpdateTestCaseQuarantineArgs struct {
+	Quarantined bool   `json:"quarantined"`
+	Reason      string `json:"reason"`
+}
+
+// ListTestCaseQuarantines returns the flaky and quarantined cases of the project, filtered by the test if it is not empty
+func ListTestCaseQuarantines(projectName, testName string, quarantined *bool, log *zap.SugaredLogger) ([]*commonmodels.TestCaseQuarantine, error) {
+	resp, err := commonrepo.NewTestCaseQuarantineColl().List(&commonrepo.TestCaseQuarantineListOption{
+		ProjectName: projectName,
+		TestName:    testName,
+		Quarantined: quarantined,
+	})
+	if err != nil {
+		log.Errorf("failed to list test case quarantines of project %s, error: %s", projectName, err)
+		return nil, e.ErrListTestCaseQuarantine.AddErr(err)
+	}
+	return resp, nil
+}
+
+// CreateTestCaseQuarantine quarantines a case which is not detected as flaky yet
+func CreateTestCaseQuarantine(args *commonmodels.TestCaseQuarantine, log *zap.SugaredLogger) error {
+	if args.ProjectName == "" || args.TestName == "" || args.CaseName == "" {
+		return e.ErrCreateTestCaseQuarantine.AddDesc("project, test and case are required")
+	}
+	if _, err := commonrepo.NewTestingColl().Find(args.TestName, args.ProjectName); err != nil {
+		return e.ErrCreateTestCaseQuarantine.AddDesc("test not found")
+	}
+	args.Quarantined = true
+	if err := commonrepo.NewTestCaseQuarantineColl().Create(args); err != nil {
+		log.Errorf("failed to quarantine case %s of test %s, error: %s", args.CaseName, args.TestName, err)
+		return e.ErrCreateTestCaseQuarantine.AddErr(err)
+	}
+	return nil
+}
+
+func UpdateTestCaseQuarantine(projectName, id, updatedBy string, args *UpdateTestCaseQuarantineArgs, log *zap.SugaredLogger) error {
+	testCase, err := commonrepo.NewTestCaseQuarantineColl().FindByID(id)
+	if err != nil || testCase.ProjectName != projectName {
+		return e.ErrUpdateTestCaseQuarantine.AddDesc("test case not found")
+	}
+	if err := commonrepo.NewTestCaseQuarantineColl().UpdateQuarantine(id, args.Quarantined, args.Reason, updatedBy); err != nil {
+		log.Errorf("failed to update quarantine of case %s, error: %s", testCase.CaseName, err)
+		return e.ErrUpdateTestCaseQuarantine.AddErr(err)
+	}
+	return nil
+}
+
+func DeleteTestCaseQuarantine(projectName, id string, log *zap.SugaredLogger) error {
+	testCase, err := commonrepo.NewTestCaseQuarantineColl().FindByID(id)
+	if err != nil || testCase.ProjectName != projectName {
+		return e.ErrDeleteTestCaseQuarantine.AddDesc("test case not found")
+	}
+	if err := commonrepo.NewTestCaseQuarantineColl().Delete(id); err != nil {
+		log.Errorf("failed to delete quarantine of case %s, error: %s", testCase.CaseName, err)
+		return e.ErrDeleteTestCaseQuarantine.AddErr(err)
+	}
+	return nil
+}
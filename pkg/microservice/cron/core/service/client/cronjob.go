@@ -67,6 +67,28 @@ func (c *Client) TriggerCleanProducts(log *zap.SugaredLogger) error {
 	return err
 }
 
+// TriggerDetectEnvDrift triggers the drift detection of the env resources
+func (c *Client) TriggerDetectEnvDrift(log *zap.SugaredLogger) error {
+	url := fmt.Sprintf("%s/environment/cron/drift", c.APIBase)
+	log.Info("start detect env drift..")
+	err := c.sendRequest(url)
+	if err != nil {
+		log.Errorf("trigger detect env drift error :%v", err)
+	}
+	return err
+}
+
+// TriggerRefreshEnvGitOpsStatus triggers the refresh of the sync status of the gitops envs
+func (c *Client) TriggerRefreshEnvGitOpsStatus(log *zap.SugaredLogger) error {
+	url := fmt.Sprintf("%s/environment/cron/gitops", c.APIBase)
+	log.Info("start refresh env gitops status..")
+	err := c.sendRequest(url)
+	if err != nil {
+		log.Errorf("trigger refresh env gitops status error :%v", err)
+	}
+	return err
+}
+
 // TriggerCleanCIResources trigger clean CollaborationInstance Resources
 func (c *Client) TriggerCleanCIResources(log *zap.SugaredLogger) error {
 	url := fmt.Sprintf("%s/collaboration/collaborations/cron/clean", c.APIBase)
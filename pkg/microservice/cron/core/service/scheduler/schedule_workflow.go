@@ -124,7 +124,8 @@ func (c *CronClient) UpsertWorkflowScheduler(log *zap.SugaredLogger) {
 		CleanJobScheduler, UpsertWorkflowScheduler, UpsertTestScheduler,
 		InitStatScheduler, InitOperationStatScheduler,
 		CleanProductScheduler, InitHealthCheckScheduler, InitHealthCheckPmHostScheduler,
-		UpsertColliePipelineScheduler, InitHelmEnvSyncValuesScheduler, EnvResourceSyncScheduler)
+		UpsertColliePipelineScheduler, InitHelmEnvSyncValuesScheduler, EnvResourceSyncScheduler,
+		EnvDriftDetectScheduler, EnvGitOpsStatusScheduler)
 
 	// 停掉已被删除的pipeline对应的scheduler
 	for name := range c.Schedulers {
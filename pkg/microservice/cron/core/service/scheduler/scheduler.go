@@ -131,6 +131,10 @@ const (
 	InitHelmEnvSyncValuesScheduler = "InitHelmEnvSyncValuesScheduler"
 
 	EnvResourceSyncScheduler = "EnvResourceSyncScheduler"
+
+	EnvDriftDetectScheduler = "EnvDriftDetectScheduler"
+
+	EnvGitOpsStatusScheduler = "EnvGitOpsStatusScheduler"
 )
 
 // NewCronClient ...
@@ -215,6 +219,10 @@ func (c *CronClient) Init() {
 	c.InitHelmEnvSyncValuesScheduler()
 	// sync env resources from git at regular intervals
 	c.InitEnvResourceSyncScheduler()
+	// detect the drift of env resources from the rendered manifests at regular intervals
+	c.InitEnvDriftDetectScheduler()
+	// refresh the sync status of the gitops envs at regular intervals
+	c.InitEnvGitOpsStatusScheduler()
 }
 
 func (c *CronClient) InitCleanJobScheduler() {
@@ -331,3 +339,19 @@ func (c *CronClient) InitEnvResourceSyncScheduler() {
 
 	c.Schedulers[EnvResourceSyncScheduler].Start()
 }
+
+func (c *CronClient) InitEnvDriftDetectScheduler() {
+	c.Schedulers[EnvDriftDetectScheduler] = gocron.NewScheduler()
+
+	c.Schedulers[EnvDriftDetectScheduler].Every(30).Minutes().Do(c.AslanCli.TriggerDetectEnvDrift, c.log)
+
+	c.Schedulers[EnvDriftDetectScheduler].Start()
+}
+
+func (c *CronClient) InitEnvGitOpsStatusScheduler() {
+	c.Schedulers[EnvGitOpsStatusScheduler] = gocron.NewScheduler()
+
+	c.Schedulers[EnvGitOpsStatusScheduler].Every(1).Minute().Do(c.AslanCli.TriggerRefreshEnvGitOpsStatus, c.log)
+
+	c.Schedulers[EnvGitOpsStatusScheduler].Start()
+}
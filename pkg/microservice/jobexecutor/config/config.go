@@ -27,6 +27,12 @@ func JobConfigFile() string {
 	return viper.GetString(setting.JobConfigFile)
 }
 
+// JobResultFile is the file the job result is written to instead of the job context ConfigMap,
+// it is set when the job runs on a vm runner
+func JobResultFile() string {
+	return viper.GetString(setting.JobResultFile)
+}
+
 func Path() string {
 	return viper.GetString(setting.Path)
 }
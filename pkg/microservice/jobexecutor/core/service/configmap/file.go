@@ -0,0 +1,70 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package configmap
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+)
+
+type fileUpdater struct {
+	path string
+}
+
+// NewFileUpdater returns an updater keeping the ConfigMap in a local file, it is used when the job runs on
+// a vm runner, which reads the job result from the file
+func NewFileUpdater(path string) Updater {
+	return &fileUpdater{path: path}
+}
+
+func (u *fileUpdater) Get() (*v1.ConfigMap, error) {
+	cm := &v1.ConfigMap{Data: map[string]string{}}
+	data, err := os.ReadFile(u.path)
+	if os.IsNotExist(err) {
+		return cm, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "read configMap file")
+	}
+	if err := json.Unmarshal(data, cm); err != nil {
+		return nil, errors.Wrap(err, "unmarshal configMap file")
+	}
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	return cm, nil
+}
+
+func (u *fileUpdater) Update(cm *v1.ConfigMap) error {
+	data, err := json.Marshal(cm)
+	if err != nil {
+		return err
+	}
+	// write to a temp file first, so the runner never reads a partial file
+	tmp := u.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, u.path)
+}
+
+func (u *fileUpdater) UpdateWithRetry(cm *v1.ConfigMap, retryCount int, retryInterval time.Duration) error {
+	return u.Update(cm)
+}
@@ -19,6 +19,7 @@ package job
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -32,7 +33,9 @@ import (
 	"github.com/koderover/zadig/pkg/microservice/jobexecutor/core/service/configmap"
 	"github.com/koderover/zadig/pkg/microservice/jobexecutor/core/service/meta"
 	"github.com/koderover/zadig/pkg/microservice/jobexecutor/core/service/step"
+	"github.com/koderover/zadig/pkg/tool/joboutput"
 	"github.com/koderover/zadig/pkg/tool/log"
+	"github.com/koderover/zadig/pkg/types"
 	"github.com/koderover/zadig/pkg/types/job"
 )
 
@@ -142,18 +145,50 @@ func (j *Job) Run(ctx context.Context) error {
 	}
 	hasFailed := false
 	var respErr error
+	// failedStepType is the type of the step failed last, the failure of a test script is carried forward to the
+	// test report step, which clears it if all the failed cases are quarantined
+	failedStepType := ""
 	for _, stepInfo := range j.Ctx.Steps {
 		if hasFailed && !stepInfo.Onfailure {
 			continue
 		}
-		if err := step.RunStep(ctx, stepInfo, j.ActiveWorkspace, j.Ctx.Paths, j.getUserEnvs(), j.Ctx.SecretEnvs, j.ConfigMapUpdater); err != nil {
+		err := step.RunStep(ctx, stepInfo, j.ActiveWorkspace, j.Ctx.Paths, j.getUserEnvs(), j.Ctx.SecretEnvs, j.ConfigMapUpdater)
+		if errors.Is(err, step.ErrOnlyQuarantinedCasesFailed) {
+			if hasFailed && failedStepType == "shell" {
+				log.Infof("The failed cases are all quarantined, ignore the failure of the test script: %s", respErr)
+				hasFailed = false
+				respErr = nil
+				failedStepType = ""
+			}
+			continue
+		}
+		if err != nil {
 			hasFailed = true
 			respErr = err
+			failedStepType = stepInfo.StepType
 		}
 	}
+	if hasFailed && j.Ctx.DebugOnFailureTTL > 0 {
+		j.debugOnFailure(ctx)
+	}
 	return respErr
 }
 
+// debugOnFailure keeps the pod alive for debugging until the on_failure breakpoint is removed or the ttl expires,
+// the breakpoint file is created by aslan when the job starts
+func (j *Job) debugOnFailure(ctx context.Context) {
+	debugStep, err := step.NewDebugStep(types.JobDebugStatusOnFailure, j.ActiveWorkspace, j.getUserEnvs(), j.Ctx.SecretEnvs, j.ConfigMapUpdater)
+	if err != nil {
+		log.Errorf("failed to init debug on failure step: %s", err)
+		return
+	}
+	debugStep.TTL = time.Duration(j.Ctx.DebugOnFailureTTL) * time.Second
+	fmt.Printf("Job failed, the pod is kept for debugging for %s.\n", debugStep.TTL)
+	if err := debugStep.Run(ctx); err != nil {
+		log.Errorf("failed to run debug on failure step: %s", err)
+	}
+}
+
 func (j *Job) AfterRun(ctx context.Context) error {
 	return j.collectJobResult(ctx)
 }
@@ -163,6 +198,9 @@ func (j *Job) collectJobResult(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("get job output vars error: %v", err)
 	}
+	if err := j.validateOutputs(outputs); err != nil {
+		return err
+	}
 	jsonOutput, err := json.Marshal(outputs)
 	if err != nil {
 		return err
@@ -190,3 +228,31 @@ func (j *Job) getJobOutputVars(ctx context.Context) ([]*job.JobOutput, error) {
 	}
 	return outputs, nil
 }
+
+// validateOutputs checks the typed outputs, the files of file outputs are relative to the workspace
+func (j *Job) validateOutputs(outputs []*job.JobOutput) error {
+	specMap := make(map[string]*job.OutputSpec)
+	for _, spec := range j.Ctx.OutputSpecs {
+		specMap[spec.Name] = spec
+	}
+	for _, output := range outputs {
+		spec, ok := specMap[output.Name]
+		if !ok {
+			continue
+		}
+		if err := joboutput.Validate(spec, output.Value); err != nil {
+			return err
+		}
+		if spec.Type != job.OutputTypeFile {
+			continue
+		}
+		file := output.Value
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(j.ActiveWorkspace, file)
+		}
+		if _, err := os.Stat(file); err != nil {
+			return fmt.Errorf("file %s of output %s is not found", output.Value, output.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package job
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/koderover/zadig/pkg/microservice/jobexecutor/core/service/meta"
+	"github.com/koderover/zadig/pkg/types/step"
+	_ "github.com/koderover/zadig/pkg/util/testing"
+)
+
+const junitResult = `<testsuites>
+  <testsuite name="cart" tests="2" failures="1">
+    <testcase classname="cart" name="TestAdd"/>
+    <testcase classname="cart" name="TestFlaky"><failure message="timeout"/></testcase>
+  </testsuite>
+</testsuites>`
+
+func newTestingJob(t *testing.T, quarantinedCases []string, steps ...*meta.Step) *Job {
+	workspace := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(workspace, "junit.xml"), []byte(junitResult), 0644))
+	steps = append([]*meta.Step{{
+		Name:     "test",
+		StepType: "shell",
+		Spec:     &step.StepShellSpec{Scripts: []string{"exit 1"}, SkipPrepare: true},
+	}}, steps...)
+	steps = append(steps, &meta.Step{
+		Name:      "test-report",
+		StepType:  "test_report",
+		Onfailure: true,
+		Spec: &step.StepTestReportSpec{
+			ReportPaths:       []string{"junit.xml"},
+			Format:            step.TestReportFormatJUnit,
+			FailOnFailedCases: true,
+			QuarantinedCases:  quarantinedCases,
+		},
+	})
+	return &Job{
+		Ctx:             &meta.JobContext{Paths: os.Getenv("PATH"), Steps: steps},
+		ActiveWorkspace: workspace,
+	}
+}
+
+func TestRunWithQuarantinedFailures(t *testing.T) {
+	j := newTestingJob(t, []string{"cart.TestFlaky"})
+	require.NoError(t, j.Run(context.Background()))
+}
+
+func TestRunWithFailures(t *testing.T) {
+	j := newTestingJob(t, nil)
+	require.EqualError(t, j.Run(context.Background()), "1 case(s) failed")
+}
+
+func TestRunWithQuarantinedFailuresAfterOtherFailure(t *testing.T) {
+	// the failure of a step other than the test script is not cleared
+	j := newTestingJob(t, []string{"cart.TestFlaky"}, &meta.Step{
+		Name:      "archive",
+		StepType:  "unknown",
+		Onfailure: true,
+	})
+	require.EqualError(t, j.Run(context.Background()), "step type: unknown does not match any known type")
+}
+
+func TestRunWithScriptFailureWithoutFailedCases(t *testing.T) {
+	// the test script fails for other reasons if no case failed
+	j := newTestingJob(t, nil)
+	require.NoError(t, os.WriteFile(filepath.Join(j.ActiveWorkspace, "junit.xml"), []byte(`<testsuite name="cart"><testcase classname="cart" name="TestAdd"/></testsuite>`), 0644))
+	require.EqualError(t, j.Run(context.Background()), "exit status 1")
+}
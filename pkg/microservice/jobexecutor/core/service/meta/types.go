@@ -16,6 +16,10 @@ limitations under the License.
 
 package meta
 
+import (
+	"github.com/koderover/zadig/pkg/types/job"
+)
+
 type JobContext struct {
 	Name string `yaml:"name"`
 	// Workspace 容器工作目录 [必填]
@@ -35,6 +39,10 @@ type JobContext struct {
 
 	Steps   []*Step  `yaml:"steps"`
 	Outputs []string `yaml:"outputs"`
+	// OutputSpecs are the typed outputs, they are validated before the job finishes
+	OutputSpecs []*job.OutputSpec `yaml:"output_specs"`
+	// DebugOnFailureTTL is the seconds the job waits for debugging after a step failed, 0 means disabled
+	DebugOnFailureTTL int64 `yaml:"debug_on_failure_ttl"`
 }
 
 type Step struct {
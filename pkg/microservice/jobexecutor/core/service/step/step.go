@@ -19,6 +19,7 @@ package step
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -71,6 +72,21 @@ func RunStep(ctx context.Context, step *meta.Step, workspace, paths string, envs
 		if err != nil {
 			return err
 		}
+	case "test_report":
+		stepInstance, err = NewTestReportStep(step.Spec, workspace, envs, secretEnvs)
+		if err != nil {
+			return err
+		}
+	case "test_impact":
+		stepInstance, err = NewTestImpactStep(step.Spec, workspace, envs, secretEnvs)
+		if err != nil {
+			return err
+		}
+	case "artifact_publish":
+		stepInstance, err = NewArtifactPublishStep(step.Spec, workspace, envs, secretEnvs)
+		if err != nil {
+			return err
+		}
 	case "tar_archive":
 		stepInstance, err = NewTararchiveStep(step.Spec, workspace, envs, secretEnvs)
 		if err != nil {
@@ -86,6 +102,26 @@ func RunStep(ctx context.Context, step *meta.Step, workspace, paths string, envs
 		if err != nil {
 			return err
 		}
+	case "sbom_scan":
+		stepInstance, err = NewSBOMScanStep(step.Spec, workspace, envs, secretEnvs)
+		if err != nil {
+			return err
+		}
+	case "image_sign":
+		stepInstance, err = NewImageSignStep(step.Spec, workspace, envs, secretEnvs)
+		if err != nil {
+			return err
+		}
+	case "cache_restore":
+		stepInstance, err = NewCacheStep(cacheRestoreAction, step.Spec, workspace, envs, secretEnvs)
+		if err != nil {
+			return err
+		}
+	case "cache_save":
+		stepInstance, err = NewCacheStep(cacheSaveAction, step.Spec, workspace, envs, secretEnvs)
+		if err != nil {
+			return err
+		}
 	case "debug_before":
 		stepInstance, err = NewDebugStep("before", workspace, envs, secretEnvs, updater)
 		if err != nil {
@@ -102,7 +138,9 @@ func RunStep(ctx context.Context, step *meta.Step, workspace, paths string, envs
 		return err
 	}
 	if err := stepInstance.Run(ctx); err != nil {
-		log.Error(err)
+		if !errors.Is(err, ErrOnlyQuarantinedCasesFailed) {
+			log.Error(err)
+		}
 		return err
 	}
 	return nil
@@ -0,0 +1,444 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package step
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/exp/slices"
+	"gopkg.in/yaml.v2"
+
+	"github.com/koderover/zadig/pkg/tool/log"
+	"github.com/koderover/zadig/pkg/types/job"
+	"github.com/koderover/zadig/pkg/types/step"
+)
+
+// the oras binary is shipped in the executor image and copied to the directory of jobexecutor,
+// mvn, npm and twine are installed in the build image or by the tools step
+const orasExe = "oras"
+
+// mavenServerID is the id of the deployment repository in the generated maven settings
+const mavenServerID = "zadig"
+
+// orasDigestRegex matches the digest of the manifest printed by oras push
+var orasDigestRegex = regexp.MustCompile(`Digest:\s*(sha256:[a-f0-9]{64})`)
+
+// ArtifactPublishStep publishes the maven, npm or pypi packages, or files as an oci artifact, to the registry.
+// The coordinates of the published artifacts are appended to the job output.
+type ArtifactPublishStep struct {
+	spec       *step.StepArtifactPublishSpec
+	envs       []string
+	secretEnvs []string
+	workspace  string
+}
+
+func NewArtifactPublishStep(spec interface{}, workspace string, envs, secretEnvs []string) (*ArtifactPublishStep, error) {
+	artifactPublishStep := &ArtifactPublishStep{workspace: workspace, envs: envs, secretEnvs: secretEnvs}
+	yamlBytes, err := yaml.Marshal(spec)
+	if err != nil {
+		return artifactPublishStep, fmt.Errorf("marshal spec %+v failed", spec)
+	}
+	if err := yaml.Unmarshal(yamlBytes, &artifactPublishStep.spec); err != nil {
+		return artifactPublishStep, fmt.Errorf("unmarshal spec %s to artifact publish spec failed", yamlBytes)
+	}
+	return artifactPublishStep, nil
+}
+
+func (s *ArtifactPublishStep) Run(ctx context.Context) error {
+	start := time.Now()
+	log.Infof("Start publish %s artifact.", s.spec.Type)
+	defer func() {
+		log.Infof("Publish artifact ended. Duration: %.2f seconds.", time.Since(start).Seconds())
+	}()
+
+	envMap := makeEnvMap(s.envs, s.secretEnvs)
+	dir := filepath.Join(s.workspace, replaceEnvWithValue(s.spec.Path, envMap))
+	registryURL := replaceEnvWithValue(s.spec.RegistryURL, envMap)
+	if registryURL == "" {
+		return fmt.Errorf("registry url is required")
+	}
+
+	var coordinates []string
+	var err error
+	switch s.spec.Type {
+	case step.ArtifactTypeMaven:
+		coordinates, err = s.publishMaven(dir, registryURL)
+	case step.ArtifactTypeNpm:
+		coordinates, err = s.publishNpm(dir, registryURL)
+	case step.ArtifactTypePyPI:
+		coordinates, err = s.publishPyPI(dir, registryURL, envMap)
+	case step.ArtifactTypeOCI:
+		coordinates, err = s.publishOCI(dir, registryURL, envMap)
+	default:
+		return fmt.Errorf("unknown artifact type %s", s.spec.Type)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, coordinate := range coordinates {
+		fmt.Printf("Published %s.\n", coordinate)
+	}
+	return appendJobOutput(s.spec.GetOutputName(), coordinates)
+}
+
+func (s *ArtifactPublishStep) publishMaven(dir, registryURL string) ([]string, error) {
+	settingsDir, err := os.MkdirTemp("", "maven")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(settingsDir)
+	// the credential is read from the envs by maven, so it is not written to the disk
+	settings := fmt.Sprintf(`<settings><servers><server><id>%s</id><username>${env.%s}</username><password>${env.%s}</password></server></servers></settings>`,
+		mavenServerID, s.spec.UsernameEnv, s.spec.PasswordEnv)
+	settingsFile := filepath.Join(settingsDir, "settings.xml")
+	if err := os.WriteFile(settingsFile, []byte(settings), 0600); err != nil {
+		return nil, fmt.Errorf("failed to write maven settings: %s", err)
+	}
+
+	args := []string{"-B", "-s", settingsFile, "-DskipTests", fmt.Sprintf("-DaltDeploymentRepository=%s::default::%s", mavenServerID, registryURL)}
+	if s.spec.Insecure {
+		args = append(args, "-Dmaven.wagon.http.ssl.insecure=true", "-Dmaven.wagon.http.ssl.allowall=true", "-Daether.connector.https.securityMode=insecure")
+	}
+	if _, err := s.runCmd(dir, "mvn", append(args, "deploy")...); err != nil {
+		return nil, fmt.Errorf("failed to deploy maven artifact: %s", err)
+	}
+
+	coordinate, err := s.mavenCoordinate(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the coordinate of the maven artifact: %s", err)
+	}
+	return []string{coordinate}, nil
+}
+
+type mavenPom struct {
+	GroupID    string `xml:"groupId"`
+	ArtifactID string `xml:"artifactId"`
+	Version    string `xml:"version"`
+	Parent     struct {
+		GroupID string `xml:"groupId"`
+		Version string `xml:"version"`
+	} `xml:"parent"`
+}
+
+// mavenCoordinate returns groupId:artifactId:version of the project, the values with properties are evaluated by maven
+func (s *ArtifactPublishStep) mavenCoordinate(dir string) (string, error) {
+	content, err := os.ReadFile(filepath.Join(dir, "pom.xml"))
+	if err != nil {
+		return "", err
+	}
+	pom := &mavenPom{}
+	if err := xml.Unmarshal(content, pom); err != nil {
+		return "", err
+	}
+	if pom.GroupID == "" {
+		pom.GroupID = pom.Parent.GroupID
+	}
+	if pom.Version == "" {
+		pom.Version = pom.Parent.Version
+	}
+
+	values := map[string]*string{"project.groupId": &pom.GroupID, "project.artifactId": &pom.ArtifactID, "project.version": &pom.Version}
+	for expression, value := range values {
+		if !strings.Contains(*value, "${") {
+			continue
+		}
+		output, err := s.runCmd(dir, "mvn", "-B", "-q", "-DforceStdout", "help:evaluate", "-Dexpression="+expression)
+		if err != nil {
+			return "", err
+		}
+		*value = strings.TrimSpace(output)
+	}
+	return fmt.Sprintf("%s:%s:%s", pom.GroupID, pom.ArtifactID, pom.Version), nil
+}
+
+func (s *ArtifactPublishStep) publishNpm(dir, registryURL string) ([]string, error) {
+	u, err := url.Parse(registryURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid npm registry %s: %s", registryURL, err)
+	}
+	// the auth settings of npm are scoped to the registry url without the scheme
+	scope := "//" + u.Host + strings.TrimSuffix(u.Path, "/") + "/:"
+	npmrc := []string{"registry=" + registryURL, "always-auth=true"}
+	if s.spec.Insecure {
+		npmrc = append(npmrc, "strict-ssl=false")
+	}
+	username, password := lookupEnv(s.envs, s.spec.UsernameEnv), lookupEnv(s.envs, s.spec.PasswordEnv)
+	if username == "" {
+		npmrc = append(npmrc, fmt.Sprintf("%s_authToken=${%s}", scope, s.spec.PasswordEnv))
+	} else {
+		npmrc = append(npmrc, scope+"username="+username, scope+"_password="+base64.StdEncoding.EncodeToString([]byte(password)))
+	}
+
+	npmrcDir, err := os.MkdirTemp("", "npm")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(npmrcDir)
+	npmrcFile := filepath.Join(npmrcDir, ".npmrc")
+	if err := os.WriteFile(npmrcFile, []byte(strings.Join(npmrc, "\n")+"\n"), 0600); err != nil {
+		return nil, fmt.Errorf("failed to write npmrc: %s", err)
+	}
+	if _, err := s.runCmd(dir, "npm", "publish", "--userconfig", npmrcFile, "--registry", registryURL); err != nil {
+		return nil, fmt.Errorf("failed to publish npm package: %s", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, "package.json"))
+	if err != nil {
+		return nil, err
+	}
+	pkg := &struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	}{}
+	if err := json.Unmarshal(content, pkg); err != nil {
+		return nil, fmt.Errorf("failed to parse package.json: %s", err)
+	}
+	return []string{fmt.Sprintf("%s@%s", pkg.Name, pkg.Version)}, nil
+}
+
+func (s *ArtifactPublishStep) publishPyPI(dir, registryURL string, envMap map[string]string) ([]string, error) {
+	patterns := s.spec.Files
+	if len(patterns) == 0 {
+		patterns = []string{"dist/*"}
+	}
+	files, err := resolveGlobs(dir, patterns, envMap)
+	if err != nil {
+		return nil, err
+	}
+	if s.spec.Insecure {
+		log.Warnf("insecure is not supported by twine, the certificate of %s is verified", registryURL)
+	}
+
+	username := lookupEnv(s.envs, s.spec.UsernameEnv)
+	if username == "" {
+		username = "__token__"
+	}
+	envs := append(append([]string{}, s.envs...),
+		"TWINE_USERNAME="+username,
+		"TWINE_PASSWORD="+lookupEnv(s.envs, s.spec.PasswordEnv),
+	)
+	args := append([]string{"upload", "--non-interactive", "--disable-progress-bar", "--repository-url", registryURL}, files...)
+	if _, err := s.runCmdWithEnvs(dir, envs, "twine", args...); err != nil {
+		return nil, fmt.Errorf("failed to upload python packages: %s", err)
+	}
+
+	coordinates := []string{}
+	for _, file := range files {
+		if coordinate := pythonDistCoordinate(filepath.Base(file)); coordinate != "" && !slices.Contains(coordinates, coordinate) {
+			coordinates = append(coordinates, coordinate)
+		}
+	}
+	return coordinates, nil
+}
+
+// pythonDistCoordinate returns name==version of the wheel or the sdist
+func pythonDistCoordinate(filename string) string {
+	if strings.HasSuffix(filename, ".whl") {
+		parts := strings.Split(strings.TrimSuffix(filename, ".whl"), "-")
+		if len(parts) < 2 {
+			return ""
+		}
+		return parts[0] + "==" + parts[1]
+	}
+	for _, ext := range []string{".tar.gz", ".zip"} {
+		if strings.HasSuffix(filename, ext) {
+			name := strings.TrimSuffix(filename, ext)
+			if i := strings.LastIndex(name, "-"); i > 0 {
+				return name[:i] + "==" + name[i+1:]
+			}
+		}
+	}
+	return ""
+}
+
+func (s *ArtifactPublishStep) publishOCI(dir, registryURL string, envMap map[string]string) ([]string, error) {
+	files, err := resolveGlobs(dir, s.spec.Files, envMap)
+	if err != nil {
+		return nil, err
+	}
+	repository := replaceEnvWithValue(s.spec.Repository, envMap)
+	if repository == "" {
+		return nil, fmt.Errorf("repository of the oci artifact is required")
+	}
+	tag := replaceEnvWithValue(s.spec.Tag, envMap)
+	if tag == "" {
+		tag = "latest"
+	}
+	host := strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(registryURL, "https://"), "http://"), "/")
+	reference := fmt.Sprintf("%s/%s:%s", host, strings.Trim(repository, "/"), tag)
+
+	dockerConfigDir, err := writeDockerConfig(&step.DockerRegistry{
+		Host:     host,
+		UserName: lookupEnv(s.envs, s.spec.UsernameEnv),
+		Password: lookupEnv(s.envs, s.spec.PasswordEnv),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to write registry auth: %s", err)
+	}
+	defer os.RemoveAll(dockerConfigDir)
+
+	args := []string{"push", reference, "--registry-config", filepath.Join(dockerConfigDir, "config.json")}
+	if s.spec.ArtifactType != "" {
+		args = append(args, "--artifact-type", s.spec.ArtifactType)
+	}
+	if strings.HasPrefix(registryURL, "http://") {
+		args = append(args, "--plain-http")
+	} else if s.spec.Insecure {
+		args = append(args, "--insecure")
+	}
+	// oras names the layers by the paths, so the files are pushed with the paths relative to the dir
+	for _, file := range files {
+		rel, err := filepath.Rel(dir, file)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, rel)
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the oras binary: %s", err)
+	}
+	output, err := s.runCmd(dir, filepath.Join(filepath.Dir(executable), orasExe), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to push oci artifact %s: %s", reference, err)
+	}
+	if match := orasDigestRegex.FindStringSubmatch(output); match != nil {
+		reference += "@" + match[1]
+	}
+	return []string{reference}, nil
+}
+
+func (s *ArtifactPublishStep) runCmd(dir, name string, args ...string) (string, error) {
+	return s.runCmdWithEnvs(dir, s.envs, name, args...)
+}
+
+// runCmdWithEnvs prints the masked output of the command and returns it.
+// The command is looked up in the PATH of the envs, which is set by the tools step.
+func (s *ArtifactPublishStep) runCmdWithEnvs(dir string, envs []string, name string, args ...string) (string, error) {
+	executable, err := lookPath(name, envs)
+	if err != nil {
+		return "", err
+	}
+	cmd := exec.Command(executable, args...)
+	cmd.Dir = dir
+	cmd.Env = envs
+
+	reader, writer := io.Pipe()
+	cmd.Stdout = writer
+	cmd.Stderr = writer
+	output := &bytes.Buffer{}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		handleCmdOutput(io.NopCloser(io.TeeReader(reader, output)), false, "", s.secretEnvs)
+	}()
+
+	err = cmd.Run()
+	writer.Close()
+	wg.Wait()
+	return output.String(), err
+}
+
+// lookPath finds the executable in the PATH of the envs, the name is returned if it is a path
+func lookPath(name string, envs []string) (string, error) {
+	if strings.Contains(name, "/") {
+		return name, nil
+	}
+	for _, dir := range filepath.SplitList(lookupEnv(envs, "PATH")) {
+		file := filepath.Join(dir, name)
+		if info, err := os.Stat(file); err == nil && !info.IsDir() && info.Mode()&0111 != 0 {
+			return file, nil
+		}
+	}
+	return "", fmt.Errorf("%s is not found in PATH, please install it in the build image or by the tools", name)
+}
+
+// lookupEnv returns the value of the last env with the key, the value may contain "="
+func lookupEnv(envs []string, key string) string {
+	if key == "" {
+		return ""
+	}
+	value := ""
+	for _, env := range envs {
+		if k, v, found := strings.Cut(env, "="); found && k == key {
+			value = v
+		}
+	}
+	return value
+}
+
+// resolveGlobs returns the files matching the globs relative to the dir
+func resolveGlobs(dir string, patterns []string, envMap map[string]string) ([]string, error) {
+	files := []string{}
+	for _, pattern := range patterns {
+		pattern = replaceEnvWithValue(pattern, envMap)
+		if pattern == "" {
+			continue
+		}
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(dir, pattern)
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid path %s: %s", pattern, err)
+		}
+		for _, match := range matches {
+			if info, err := os.Stat(match); err == nil && !info.IsDir() && !slices.Contains(files, match) {
+				files = append(files, match)
+			}
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no file is found in %s", strings.Join(patterns, ", "))
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// appendJobOutput appends the values to the job output, so the coordinates of all the publish steps of the job are kept
+func appendJobOutput(name string, values []string) error {
+	if len(values) == 0 {
+		return nil
+	}
+	file := filepath.Join(job.JobOutputDir, name)
+	content, err := os.ReadFile(file)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if existing := strings.TrimSpace(string(content)); existing != "" {
+		values = append([]string{existing}, values...)
+	}
+	return os.WriteFile(file, []byte(strings.Join(values, ",")), 0644)
+}
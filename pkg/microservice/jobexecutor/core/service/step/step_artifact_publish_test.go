@@ -0,0 +1,228 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package step
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/koderover/zadig/pkg/types/step"
+)
+
+// fakeCommandScript records the arguments, the generated config files and the twine credential, then prints the output
+const fakeCommandScript = `#!/bin/sh
+echo "$(basename "$0") $*" >> "$FAKE_CMD_LOG"
+for arg in "$@"; do
+  case "$arg" in
+    *.npmrc|*settings.xml|*config.json) cat "$arg" >> "$FAKE_CMD_LOG"; echo >> "$FAKE_CMD_LOG";;
+  esac
+done
+if [ -n "$TWINE_USERNAME" ]; then echo "twine auth $TWINE_USERNAME:$TWINE_PASSWORD" >> "$FAKE_CMD_LOG"; fi
+printf '%s' "$FAKE_CMD_OUTPUT"
+`
+
+type fakeCommands struct {
+	binDir string
+	log    string
+}
+
+func newFakeCommands(t *testing.T, names ...string) *fakeCommands {
+	c := &fakeCommands{binDir: t.TempDir(), log: filepath.Join(t.TempDir(), "cmd.log")}
+	for _, name := range names {
+		require.NoError(t, os.WriteFile(filepath.Join(c.binDir, name), []byte(fakeCommandScript), 0755))
+	}
+	return c
+}
+
+func (c *fakeCommands) envs(output string, envs ...string) []string {
+	return append([]string{
+		"PATH=" + c.binDir + string(os.PathListSeparator) + os.Getenv("PATH"),
+		"FAKE_CMD_LOG=" + c.log,
+		"FAKE_CMD_OUTPUT=" + output,
+	}, envs...)
+}
+
+func (c *fakeCommands) output(t *testing.T) string {
+	content, err := os.ReadFile(c.log)
+	require.NoError(t, err)
+	return string(content)
+}
+
+func writeProjectFile(t *testing.T, dir, name, content string) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+}
+
+func TestMavenCoordinate(t *testing.T) {
+	dir := t.TempDir()
+	writeProjectFile(t, dir, "pom.xml", `<project>
+  <parent><groupId>io.koderover</groupId><version>1.0.0</version></parent>
+  <artifactId>app</artifactId>
+</project>`)
+	s := &ArtifactPublishStep{spec: &step.StepArtifactPublishSpec{}}
+	coordinate, err := s.mavenCoordinate(dir)
+	require.NoError(t, err)
+	require.Equal(t, "io.koderover:app:1.0.0", coordinate)
+}
+
+func TestMavenCoordinateWithProperties(t *testing.T) {
+	dir := t.TempDir()
+	writeProjectFile(t, dir, "pom.xml", `<project>
+  <groupId>io.koderover</groupId>
+  <artifactId>app</artifactId>
+  <version>${revision}</version>
+</project>`)
+	commands := newFakeCommands(t, "mvn")
+	s := &ArtifactPublishStep{spec: &step.StepArtifactPublishSpec{}, envs: commands.envs("2.1.0\n")}
+	coordinate, err := s.mavenCoordinate(dir)
+	require.NoError(t, err)
+	require.Equal(t, "io.koderover:app:2.1.0", coordinate)
+	require.Equal(t, "mvn -B -q -DforceStdout help:evaluate -Dexpression=project.version\n", commands.output(t))
+}
+
+func TestPythonDistCoordinate(t *testing.T) {
+	testCases := map[string]string{
+		"zadig_sdk-1.2.0-py3-none-any.whl": "zadig_sdk==1.2.0",
+		"zadig-sdk-1.2.0.tar.gz":           "zadig-sdk==1.2.0",
+		"zadig-sdk-1.2.0.zip":              "zadig-sdk==1.2.0",
+		"invalid.whl":                      "",
+		"zadig.tar.gz":                     "",
+		"README.md":                        "",
+	}
+	for filename, expected := range testCases {
+		require.Equal(t, expected, pythonDistCoordinate(filename), filename)
+	}
+}
+
+func TestPublishMaven(t *testing.T) {
+	dir := t.TempDir()
+	writeProjectFile(t, dir, "pom.xml", `<project><groupId>io.koderover</groupId><artifactId>app</artifactId><version>1.0.0</version></project>`)
+	commands := newFakeCommands(t, "mvn")
+	s := &ArtifactPublishStep{
+		spec: &step.StepArtifactPublishSpec{Type: step.ArtifactTypeMaven, UsernameEnv: "MAVEN_USER", PasswordEnv: "MAVEN_PASSWORD", Insecure: true},
+		envs: commands.envs("", "MAVEN_USER=admin", "MAVEN_PASSWORD=secret"),
+	}
+
+	coordinates, err := s.publishMaven(dir, "https://nexus.example.com/repository/releases")
+	require.NoError(t, err)
+	require.Equal(t, []string{"io.koderover:app:1.0.0"}, coordinates)
+
+	output := commands.output(t)
+	require.Contains(t, output, "-DaltDeploymentRepository=zadig::default::https://nexus.example.com/repository/releases")
+	require.Contains(t, output, "-Dmaven.wagon.http.ssl.insecure=true")
+	require.Contains(t, output, " deploy\n")
+	// the credential is referred by the envs instead of written to the settings
+	require.Contains(t, output, "<username>${env.MAVEN_USER}</username><password>${env.MAVEN_PASSWORD}</password>")
+	require.NotContains(t, output, "secret")
+}
+
+func TestPublishNpm(t *testing.T) {
+	dir := t.TempDir()
+	writeProjectFile(t, dir, "package.json", `{"name": "@koderover/app", "version": "1.0.0"}`)
+
+	t.Run("token", func(t *testing.T) {
+		commands := newFakeCommands(t, "npm")
+		s := &ArtifactPublishStep{
+			spec: &step.StepArtifactPublishSpec{Type: step.ArtifactTypeNpm, PasswordEnv: "NPM_TOKEN"},
+			envs: commands.envs("", "NPM_TOKEN=token"),
+		}
+		coordinates, err := s.publishNpm(dir, "https://npm.example.com/repo/")
+		require.NoError(t, err)
+		require.Equal(t, []string{"@koderover/app@1.0.0"}, coordinates)
+
+		output := commands.output(t)
+		require.Contains(t, output, "npm publish --userconfig ")
+		require.Contains(t, output, "--registry https://npm.example.com/repo/\n")
+		require.Contains(t, output, "//npm.example.com/repo/:_authToken=${NPM_TOKEN}")
+		require.NotContains(t, output, "strict-ssl")
+	})
+
+	t.Run("username and password", func(t *testing.T) {
+		commands := newFakeCommands(t, "npm")
+		s := &ArtifactPublishStep{
+			spec: &step.StepArtifactPublishSpec{Type: step.ArtifactTypeNpm, UsernameEnv: "NPM_USER", PasswordEnv: "NPM_PASSWORD", Insecure: true},
+			envs: commands.envs("", "NPM_USER=admin", "NPM_PASSWORD=secret"),
+		}
+		_, err := s.publishNpm(dir, "https://npm.example.com")
+		require.NoError(t, err)
+
+		output := commands.output(t)
+		require.Contains(t, output, "//npm.example.com/:username=admin")
+		require.Contains(t, output, "//npm.example.com/:_password=c2VjcmV0")
+		require.Contains(t, output, "strict-ssl=false")
+	})
+}
+
+func TestPublishPyPI(t *testing.T) {
+	dir := t.TempDir()
+	writeProjectFile(t, dir, "dist/zadig_sdk-1.2.0-py3-none-any.whl", "")
+	writeProjectFile(t, dir, "dist/zadig-sdk-1.2.0.tar.gz", "")
+	commands := newFakeCommands(t, "twine")
+	s := &ArtifactPublishStep{
+		spec: &step.StepArtifactPublishSpec{Type: step.ArtifactTypePyPI, PasswordEnv: "PYPI_TOKEN"},
+		envs: commands.envs("", "PYPI_TOKEN=token"),
+	}
+
+	coordinates, err := s.publishPyPI(dir, "https://pypi.example.com/simple", nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"zadig-sdk==1.2.0", "zadig_sdk==1.2.0"}, coordinates)
+
+	output := commands.output(t)
+	require.Contains(t, output, "twine upload --non-interactive --disable-progress-bar --repository-url https://pypi.example.com/simple "+
+		filepath.Join(dir, "dist/zadig-sdk-1.2.0.tar.gz")+" "+filepath.Join(dir, "dist/zadig_sdk-1.2.0-py3-none-any.whl"))
+	// the password is used as the token without the username
+	require.Contains(t, output, "twine auth __token__:token")
+}
+
+func TestPublishOCI(t *testing.T) {
+	// oras is looked up in the directory of the executable
+	executable, err := os.Executable()
+	require.NoError(t, err)
+	oras := filepath.Join(filepath.Dir(executable), orasExe)
+	require.NoError(t, os.WriteFile(oras, []byte(fakeCommandScript), 0755))
+	t.Cleanup(func() { os.Remove(oras) })
+
+	dir := t.TempDir()
+	writeProjectFile(t, dir, "out/app.tar.gz", "")
+	writeProjectFile(t, dir, "out/checksum.txt", "")
+	commands := newFakeCommands(t)
+	digest := "sha256:" + strings.Repeat("a", 64)
+	s := &ArtifactPublishStep{
+		spec: &step.StepArtifactPublishSpec{
+			Type:         step.ArtifactTypeOCI,
+			Files:        []string{"out/*"},
+			Repository:   "/koderover/$APP/",
+			ArtifactType: "application/vnd.koderover.app",
+			UsernameEnv:  "REGISTRY_USER",
+			PasswordEnv:  "REGISTRY_PASSWORD",
+		},
+		envs: commands.envs("Pushed registry.example.com/koderover/app:latest\nDigest: "+digest+"\n", "REGISTRY_USER=admin", "REGISTRY_PASSWORD=secret"),
+	}
+
+	coordinates, err := s.publishOCI(dir, "http://registry.example.com/", map[string]string{"APP": "app"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"registry.example.com/koderover/app:latest@" + digest}, coordinates)
+
+	output := commands.output(t)
+	require.Contains(t, output, orasExe+" push registry.example.com/koderover/app:latest --registry-config ")
+	require.Contains(t, output, "--artifact-type application/vnd.koderover.app --plain-http out/app.tar.gz out/checksum.txt\n")
+	require.Contains(t, output, "registry.example.com")
+}
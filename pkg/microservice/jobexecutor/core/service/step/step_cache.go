@@ -0,0 +1,442 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package step
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/log"
+	"github.com/koderover/zadig/pkg/tool/s3"
+	"github.com/koderover/zadig/pkg/types/step"
+)
+
+const (
+	cacheRestoreAction = "restore"
+	cacheSaveAction    = "save"
+	cacheFileSuffix    = ".tar.gz"
+)
+
+// CacheStep restores or saves the cache paths as a tarball in s3, the object key is rendered from the key of spec
+// with the hashes of dependency files. S3DestDir of spec is scoped to the repo of the job, so the builds of different
+// branches share the cache.
+type CacheStep struct {
+	action     string
+	spec       *step.StepCacheSpec
+	envs       []string
+	secretEnvs []string
+	workspace  string
+}
+
+func NewCacheStep(action string, spec interface{}, workspace string, envs, secretEnvs []string) (*CacheStep, error) {
+	cacheStep := &CacheStep{action: action, workspace: workspace, envs: envs, secretEnvs: secretEnvs}
+	yamlBytes, err := yaml.Marshal(spec)
+	if err != nil {
+		return cacheStep, fmt.Errorf("marshal spec %+v failed", spec)
+	}
+	if err := yaml.Unmarshal(yamlBytes, &cacheStep.spec); err != nil {
+		return cacheStep, fmt.Errorf("unmarshal spec %s to cache spec failed", yamlBytes)
+	}
+	return cacheStep, nil
+}
+
+func (s *CacheStep) Run(ctx context.Context) error {
+	start := time.Now()
+	defer func() {
+		log.Infof("Cache %s ended. Duration: %.2f seconds", s.action, time.Since(start).Seconds())
+	}()
+
+	if len(s.spec.Paths) == 0 {
+		return nil
+	}
+	if s.spec.S3Storage == nil {
+		return fmt.Errorf("s3 storage of cache not found")
+	}
+	envMap := makeEnvMap(s.envs, s.secretEnvs)
+	key, err := s.renderKey(s.spec.Key, envMap)
+	if err != nil {
+		return err
+	}
+	if key == "" {
+		return fmt.Errorf("cache key is empty")
+	}
+
+	forcedPathStyle := true
+	if s.spec.S3Storage.Provider == setting.ProviderSourceAli {
+		forcedPathStyle = false
+	}
+	client, err := s3.NewClient(s.spec.S3Storage.Endpoint, s.spec.S3Storage.Ak, s.spec.S3Storage.Sk, s.spec.S3Storage.Region, s.spec.S3Storage.Insecure, forcedPathStyle)
+	if err != nil {
+		return fmt.Errorf("failed to create s3 client for cache, err: %s", err)
+	}
+
+	if s.action == cacheRestoreAction {
+		restoreKeys := []string{}
+		for _, restoreKey := range s.spec.RestoreKeys {
+			restoreKey, err = s.renderKey(restoreKey, envMap)
+			if err != nil {
+				return err
+			}
+			if restoreKey != "" {
+				restoreKeys = append(restoreKeys, restoreKey)
+			}
+		}
+		return s.restore(client, key, restoreKeys, envMap)
+	}
+	return s.save(client, key, envMap)
+}
+
+// cachePaths returns the absolute paths of the cache, relative paths are in the workspace
+func (s *CacheStep) cachePaths(envMap map[string]string) []string {
+	paths := []string{}
+	for _, cachePath := range s.spec.Paths {
+		cachePath = replaceEnvWithValue(cachePath, envMap)
+		if cachePath == "" {
+			continue
+		}
+		if !filepath.IsAbs(cachePath) {
+			cachePath = filepath.Join(s.workspace, cachePath)
+		}
+		paths = append(paths, filepath.Clean(cachePath))
+	}
+	return paths
+}
+
+func (s *CacheStep) restore(client *s3.Client, key string, restoreKeys []string, envMap map[string]string) error {
+	objectKey := s.objectKey(key)
+	exists, err := client.FileExists(s.spec.S3Storage.Bucket, objectKey)
+	if err != nil {
+		return fmt.Errorf("failed to find cache %s, err: %s", key, err)
+	}
+	if !exists {
+		objectKey = ""
+		for _, restoreKey := range restoreKeys {
+			latestKey, err := client.GetLatestFile(s.spec.S3Storage.Bucket, s.objectPrefix(restoreKey))
+			if err != nil {
+				return fmt.Errorf("failed to find cache with prefix %s, err: %s", restoreKey, err)
+			}
+			if latestKey != "" {
+				objectKey = latestKey
+				break
+			}
+		}
+	}
+	if objectKey == "" {
+		log.Infof("Cache not found for key %s.", key)
+		return nil
+	}
+
+	log.Infof("Start restoring cache from %s.", path.Base(objectKey))
+	tarFile := filepath.Join(os.TempDir(), fmt.Sprintf("cache-%d%s", time.Now().UnixNano(), cacheFileSuffix))
+	defer os.Remove(tarFile)
+	if err := client.Download(s.spec.S3Storage.Bucket, objectKey, tarFile); err != nil {
+		return fmt.Errorf("failed to download cache %s, err: %s", objectKey, err)
+	}
+	if err := extractCache(tarFile, "/", s.cachePaths(envMap)); err != nil {
+		return fmt.Errorf("failed to extract cache %s, err: %s", objectKey, err)
+	}
+	log.Infof("Finish restoring cache from %s.", path.Base(objectKey))
+	return nil
+}
+
+func (s *CacheStep) save(client *s3.Client, key string, envMap map[string]string) error {
+	objectKey := s.objectKey(key)
+	// the key is computed from the content of the dependency files, the cache with the same key does not need saving again
+	exists, err := client.FileExists(s.spec.S3Storage.Bucket, objectKey)
+	if err != nil {
+		return fmt.Errorf("failed to find cache %s, err: %s", key, err)
+	}
+	if exists {
+		log.Infof("Cache %s already exists, skip saving.", key)
+		return nil
+	}
+
+	tarArgs := []string{}
+	for _, cachePath := range s.cachePaths(envMap) {
+		if _, err := os.Stat(cachePath); err != nil {
+			log.Warnf("Cache path %s not found, skip it.", cachePath)
+			continue
+		}
+		// paths are archived relative to the root, so they are restored to the same place
+		tarArgs = append(tarArgs, strings.TrimPrefix(cachePath, "/"))
+	}
+	if len(tarArgs) == 0 {
+		log.Infof("No cache path found, skip saving.")
+		return nil
+	}
+
+	log.Infof("Start saving cache %s.", key)
+	tarFile := filepath.Join(os.TempDir(), fmt.Sprintf("cache-%d%s", time.Now().UnixNano(), cacheFileSuffix))
+	defer os.Remove(tarFile)
+	cmd := exec.Command("tar", append([]string{"-czf", tarFile, "-C", "/"}, tarArgs...)...)
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to compress cache %s, err: %s", key, err)
+	}
+	if err := client.Upload(s.spec.S3Storage.Bucket, tarFile, objectKey); err != nil {
+		return fmt.Errorf("failed to upload cache %s, err: %s", key, err)
+	}
+	log.Infof("Finish saving cache %s.", key)
+	return nil
+}
+
+// extractCache extracts the gzipped tarball to root, only the entries in the cache paths, which are absolute paths
+// under root, are extracted. The tarball is rejected if any entry is absolute or contains "..".
+func extractCache(tarFile, root string, cachePaths []string) error {
+	f, err := os.Open(tarFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gzipReader, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gzipReader.Close()
+
+	inCachePaths := func(target string) bool {
+		for _, cachePath := range cachePaths {
+			if target == cachePath || strings.HasPrefix(target, cachePath+string(filepath.Separator)) {
+				return true
+			}
+		}
+		return false
+	}
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if !isSafeArchivePath(header.Name) {
+			return fmt.Errorf("invalid entry %s in cache", header.Name)
+		}
+		// entries are relative to root, see save
+		name := filepath.Join("/", filepath.FromSlash(header.Name))
+		target := filepath.Join(root, name)
+		if !inCachePaths(name) {
+			continue
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, os.FileMode(header.Mode)|0700); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			// remove the existing file or link, so the file is never written through a link
+			if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(header.Mode))
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(file, tarReader); err != nil {
+				file.Close()
+				return err
+			}
+			if err := file.Close(); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			// links out of the cache paths are skipped, files under them would be written out of the cache paths
+			linkTarget := header.Linkname
+			if !filepath.IsAbs(linkTarget) {
+				linkTarget = filepath.Join(filepath.Dir(name), linkTarget)
+			}
+			if !inCachePaths(filepath.Clean(linkTarget)) {
+				continue
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			if err := os.Symlink(header.Linkname, target); err != nil {
+				return err
+			}
+		case tar.TypeLink:
+			if !isSafeArchivePath(header.Linkname) {
+				return fmt.Errorf("invalid link %s of entry %s in cache", header.Linkname, header.Name)
+			}
+			linkName := filepath.Join("/", filepath.FromSlash(header.Linkname))
+			if !inCachePaths(linkName) {
+				continue
+			}
+			if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			if err := os.Link(filepath.Join(root, linkName), target); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func isSafeArchivePath(name string) bool {
+	if name == "" || strings.HasPrefix(name, "/") {
+		return false
+	}
+	for _, part := range strings.Split(name, "/") {
+		if part == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *CacheStep) objectPrefix(prefix string) string {
+	return strings.TrimLeft(path.Join(s.spec.S3Storage.Subfolder, s.spec.S3DestDir, prefix), "/")
+}
+
+func (s *CacheStep) objectKey(key string) string {
+	return s.objectPrefix(key) + cacheFileSuffix
+}
+
+// renderKey replaces the envs in the key and executes it as a template with the hashFiles function
+func (s *CacheStep) renderKey(key string, envMap map[string]string) (string, error) {
+	key = replaceEnvWithValue(key, envMap)
+	tmpl, err := template.New("key").Funcs(template.FuncMap{
+		"hashFiles": func(patterns ...string) (string, error) {
+			return hashFiles(s.workspace, patterns...)
+		},
+	}).Parse(key)
+	if err != nil {
+		return "", fmt.Errorf("invalid cache key %s: %s", key, err)
+	}
+	buffer := &bytes.Buffer{}
+	if err := tmpl.Execute(buffer, nil); err != nil {
+		return "", fmt.Errorf("failed to render cache key %s: %s", key, err)
+	}
+	rendered := strings.TrimSpace(buffer.String())
+	if strings.Contains(rendered, "..") {
+		return "", fmt.Errorf("invalid cache key %s", rendered)
+	}
+	return rendered, nil
+}
+
+// hashFiles returns the sha256 of the paths and contents of the files matched by the patterns in the workspace,
+// ** matches any directories. An error is returned if no file matched, so keys of different dependencies never collide.
+func hashFiles(workspace string, patterns ...string) (string, error) {
+	files := []string{}
+	err := filepath.Walk(workspace, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		relPath, err := filepath.Rel(workspace, filePath)
+		if err != nil {
+			return err
+		}
+		for _, pattern := range patterns {
+			if matchGlob(strings.Split(strings.TrimPrefix(pattern, "/"), "/"), strings.Split(filepath.ToSlash(relPath), "/")) {
+				files = append(files, filePath)
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to hash files %v: %s", patterns, err)
+	}
+	if len(files) == 0 {
+		return "", fmt.Errorf("no file matches %v", patterns)
+	}
+
+	sort.Strings(files)
+	hash := sha256.New()
+	for _, file := range files {
+		fileHash, err := hashFile(file)
+		if err != nil {
+			return "", err
+		}
+		relPath, err := filepath.Rel(workspace, file)
+		if err != nil {
+			return "", err
+		}
+		hash.Write([]byte(filepath.ToSlash(relPath)))
+		hash.Write([]byte{0})
+		hash.Write(fileHash)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func hashFile(file string) ([]byte, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %s", file, err)
+	}
+	defer f.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %s", file, err)
+	}
+	return hash.Sum(nil), nil
+}
+
+func matchGlob(pattern, names []string) bool {
+	if len(pattern) == 0 {
+		return len(names) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(names); i++ {
+			if matchGlob(pattern[1:], names[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(names) == 0 {
+		return false
+	}
+	matched, err := filepath.Match(pattern[0], names[0])
+	if err != nil || !matched {
+		return false
+	}
+	return matchGlob(pattern[1:], names[1:])
+}
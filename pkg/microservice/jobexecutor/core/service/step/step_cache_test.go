@@ -0,0 +1,206 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package step
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/koderover/zadig/pkg/types/step"
+)
+
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	for name, content := range files {
+		file := filepath.Join(root, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(file), 0755))
+		require.NoError(t, os.WriteFile(file, []byte(content), 0644))
+	}
+}
+
+func TestMatchGlob(t *testing.T) {
+	testCases := []struct {
+		pattern string
+		name    string
+		matched bool
+	}{
+		{pattern: "go.sum", name: "go.sum", matched: true},
+		{pattern: "go.sum", name: "sub/go.sum", matched: false},
+		{pattern: "**/go.sum", name: "go.sum", matched: true},
+		{pattern: "**/go.sum", name: "a/b/go.sum", matched: true},
+		{pattern: "a/**/go.sum", name: "a/go.sum", matched: true},
+		{pattern: "a/**/go.sum", name: "b/go.sum", matched: false},
+		{pattern: "*.lock", name: "yarn.lock", matched: true},
+		{pattern: "*.lock", name: "web/yarn.lock", matched: false},
+		{pattern: "web/*/package.json", name: "web/app/package.json", matched: true},
+		{pattern: "web/*/package.json", name: "web/app/x/package.json", matched: false},
+		{pattern: "**", name: "any/file", matched: true},
+		{pattern: "[", name: "[", matched: false},
+	}
+	for _, tc := range testCases {
+		require.Equal(t, tc.matched, matchGlob(strings.Split(tc.pattern, "/"), strings.Split(tc.name, "/")), "%s %s", tc.pattern, tc.name)
+	}
+}
+
+func TestHashFiles(t *testing.T) {
+	workspace := t.TempDir()
+	writeFiles(t, workspace, map[string]string{
+		"go.sum":          "a",
+		"sub/go.sum":      "b",
+		".git/go.sum":     "c",
+		"sub/main.go":     "d",
+		"other/go.sum.go": "e",
+	})
+
+	hash, err := hashFiles(workspace, "go.sum", "**/go.sum")
+	require.NoError(t, err)
+	require.Len(t, hash, 64)
+
+	again, err := hashFiles(workspace, "**/go.sum")
+	require.NoError(t, err)
+	require.Equal(t, hash, again)
+
+	// files in .git are not hashed
+	writeFiles(t, workspace, map[string]string{".git/go.sum": "changed"})
+	again, err = hashFiles(workspace, "**/go.sum")
+	require.NoError(t, err)
+	require.Equal(t, hash, again)
+
+	// the content is hashed
+	writeFiles(t, workspace, map[string]string{"sub/go.sum": "changed"})
+	changed, err := hashFiles(workspace, "**/go.sum")
+	require.NoError(t, err)
+	require.NotEqual(t, hash, changed)
+
+	_, err = hashFiles(workspace, "package-lock.json")
+	require.Error(t, err)
+}
+
+func TestHashFilesPaths(t *testing.T) {
+	// the same contents in different paths have different hashes
+	first, second := t.TempDir(), t.TempDir()
+	writeFiles(t, first, map[string]string{"a/go.sum": "a"})
+	writeFiles(t, second, map[string]string{"b/go.sum": "a"})
+
+	firstHash, err := hashFiles(first, "**/go.sum")
+	require.NoError(t, err)
+	secondHash, err := hashFiles(second, "**/go.sum")
+	require.NoError(t, err)
+	require.NotEqual(t, firstHash, secondHash)
+}
+
+func TestRenderKey(t *testing.T) {
+	workspace := t.TempDir()
+	writeFiles(t, workspace, map[string]string{"go.sum": "a"})
+	s := &CacheStep{spec: &step.StepCacheSpec{}, workspace: workspace}
+	envMap := map[string]string{"SERVICE": "aslan"}
+
+	hash, err := hashFiles(workspace, "go.sum")
+	require.NoError(t, err)
+	key, err := s.renderKey(`go-$SERVICE-{{ hashFiles "go.sum" }}`, envMap)
+	require.NoError(t, err)
+	require.Equal(t, "go-aslan-"+hash, key)
+
+	key, err = s.renderKey(" go-${SERVICE} ", envMap)
+	require.NoError(t, err)
+	require.Equal(t, "go-aslan", key)
+
+	_, err = s.renderKey(`go-{{ hashFiles "go.sum"`, envMap)
+	require.Error(t, err)
+
+	_, err = s.renderKey(`go-{{ hashFiles "yarn.lock" }}`, envMap)
+	require.Error(t, err)
+
+	_, err = s.renderKey("../$SERVICE", envMap)
+	require.Error(t, err)
+}
+
+type testTarEntry struct {
+	name     string
+	typeflag byte
+	linkname string
+	content  string
+}
+
+func writeTestTarball(t *testing.T, entries []testTarEntry) string {
+	tarFile := filepath.Join(t.TempDir(), "cache.tar.gz")
+	f, err := os.Create(tarFile)
+	require.NoError(t, err)
+	defer f.Close()
+	gzipWriter := gzip.NewWriter(f)
+	tarWriter := tar.NewWriter(gzipWriter)
+	for _, entry := range entries {
+		header := &tar.Header{Name: entry.name, Typeflag: entry.typeflag, Linkname: entry.linkname, Mode: 0644, Size: int64(len(entry.content))}
+		if entry.typeflag == tar.TypeDir {
+			header.Mode = 0755
+		}
+		require.NoError(t, tarWriter.WriteHeader(header))
+		if entry.content != "" {
+			_, err := tarWriter.Write([]byte(entry.content))
+			require.NoError(t, err)
+		}
+	}
+	require.NoError(t, tarWriter.Close())
+	require.NoError(t, gzipWriter.Close())
+	return tarFile
+}
+
+func TestExtractCache(t *testing.T) {
+	root := t.TempDir()
+	tarFile := writeTestTarball(t, []testTarEntry{
+		{name: "cache/go/", typeflag: tar.TypeDir},
+		{name: "cache/go/mod.txt", typeflag: tar.TypeReg, content: "mod"},
+		{name: "cache/go/link", typeflag: tar.TypeSymlink, linkname: "mod.txt"},
+		{name: "cache/go/hard", typeflag: tar.TypeLink, linkname: "cache/go/mod.txt"},
+		{name: "cache/go/escape", typeflag: tar.TypeSymlink, linkname: "/etc"},
+		{name: "etc/profile", typeflag: tar.TypeReg, content: "outside"},
+		{name: "cache/gobin/tool", typeflag: tar.TypeReg, content: "outside"},
+	})
+	require.NoError(t, extractCache(tarFile, root, []string{"/cache/go"}))
+
+	content, err := os.ReadFile(filepath.Join(root, "cache/go/mod.txt"))
+	require.NoError(t, err)
+	require.Equal(t, "mod", string(content))
+	content, err = os.ReadFile(filepath.Join(root, "cache/go/link"))
+	require.NoError(t, err)
+	require.Equal(t, "mod", string(content))
+	content, err = os.ReadFile(filepath.Join(root, "cache/go/hard"))
+	require.NoError(t, err)
+	require.Equal(t, "mod", string(content))
+
+	for _, name := range []string{"cache/go/escape", "etc/profile", "cache/gobin/tool"} {
+		_, err := os.Lstat(filepath.Join(root, name))
+		require.True(t, os.IsNotExist(err), name)
+	}
+}
+
+func TestExtractCacheInvalidEntries(t *testing.T) {
+	for _, entry := range []testTarEntry{
+		{name: "/cache/go/abs", typeflag: tar.TypeReg, content: "abs"},
+		{name: "cache/go/../../etc/profile", typeflag: tar.TypeReg, content: "dotdot"},
+		{name: "cache/go/hard", typeflag: tar.TypeLink, linkname: "cache/go/../../etc/passwd"},
+	} {
+		root := t.TempDir()
+		tarFile := writeTestTarball(t, []testTarEntry{entry})
+		require.Error(t, extractCache(tarFile, root, []string{"/cache/go"}), entry.name)
+	}
+}
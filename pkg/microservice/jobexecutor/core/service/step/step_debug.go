@@ -28,7 +28,9 @@ import (
 )
 
 type DebugStep struct {
-	Type       string
+	Type string
+	// TTL ends the debugging even if the breakpoint is not removed, 0 means no limit
+	TTL        time.Duration
 	envs       []string
 	secretEnvs []string
 	workspace  string
@@ -78,7 +80,12 @@ func (s *DebugStep) Run(ctx context.Context) (err error) {
 	}()
 
 	log.Infof("Running debugger %s job, Use debugger console.", s.Type)
+	start := time.Now()
 	for _, err := os.Stat(path); err == nil; {
+		if s.TTL > 0 && time.Since(start) > s.TTL {
+			log.Infof("debug step %s expired after %s", s.Type, s.TTL)
+			break
+		}
 		time.Sleep(time.Second)
 		_, err = os.Stat(path)
 	}
@@ -67,6 +67,9 @@ func (s *DockerBuildStep) Run(ctx context.Context) error {
 	s.spec.DockerFile = replaceEnvWithValue(s.spec.DockerFile, envMap)
 	s.spec.BuildArgs = replaceEnvWithValue(s.spec.BuildArgs, envMap)
 
+	if s.spec.GetBuilderMode() != step.DockerBuilderModeDocker {
+		return s.runDaemonlessBuild(envMap)
+	}
 	if err := s.dockerLogin(); err != nil {
 		return err
 	}
@@ -119,7 +122,7 @@ func (s *DockerBuildStep) runDockerBuild() error {
 		c.Dir = s.workspace
 		c.Env = envs
 		if err := c.Run(); err != nil {
-			return fmt.Errorf("failed to run docker build: %s", err)
+			return fmt.Errorf("failed to run docker build: %w", err)
 		}
 	}
 	fmt.Printf("Docker build ended. Duration: %.2f seconds.\n", time.Since(startTimeDockerBuild).Seconds())
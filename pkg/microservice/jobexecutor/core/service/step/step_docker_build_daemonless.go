@@ -0,0 +1,194 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package step
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/shlex"
+
+	"github.com/koderover/zadig/pkg/types/step"
+)
+
+const (
+	// buildctl is shipped in the executor image and copied to the directory of jobexecutor
+	buildctlExe = "buildctl"
+)
+
+type dockerConfig struct {
+	Auths map[string]*dockerAuth `json:"auths"`
+}
+
+type dockerAuth struct {
+	Auth string `json:"auth"`
+}
+
+// runDaemonlessBuild builds and pushes the image with the rootless buildkitd sidecar instead of a docker daemon
+func (s *DockerBuildStep) runDaemonlessBuild(envMap map[string]string) error {
+	if err := s.spec.ValidateBuilder(); err != nil {
+		return err
+	}
+	// the job container runs as root, so buildkitd is not started in it but in a sidecar running as non-root user
+	buildkitHost := envMap[step.BuildKitHostEnv]
+	if buildkitHost == "" {
+		return fmt.Errorf("%s is not set, the buildkit builder is only supported by the jobs running in kubernetes", step.BuildKitHostEnv)
+	}
+	s.spec.ImageName = replaceEnvWithValue(s.spec.ImageName, envMap)
+	s.spec.Platforms = replaceEnvWithValue(s.spec.Platforms, envMap)
+	s.spec.CacheRepo = replaceEnvWithValue(s.spec.CacheRepo, envMap)
+
+	fmt.Printf("Preparing Dockerfile.\n")
+	if err := prepareDockerfile(s.spec.Source, s.spec.DockerTemplateContent); err != nil {
+		return fmt.Errorf("failed to prepare dockerfile: %s", err)
+	}
+	if s.spec.Proxy != nil {
+		setProxy(s.spec)
+	}
+
+	dockerConfigDir, err := writeDockerConfig(s.spec.DockerRegistry)
+	if err != nil {
+		return fmt.Errorf("failed to write registry auth: %s", err)
+	}
+	defer os.RemoveAll(dockerConfigDir)
+
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to find the builder binaries: %s", err)
+	}
+	binDir := filepath.Dir(executable)
+
+	c, err := s.buildkitCommand(binDir)
+	if err != nil {
+		return err
+	}
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	c.Dir = s.workspace
+	c.Env = append(append(append([]string{}, s.envs...), s.secretEnvs...),
+		fmt.Sprintf("PATH=%s:%s", binDir, os.Getenv("PATH")),
+		fmt.Sprintf("DOCKER_CONFIG=%s", dockerConfigDir),
+		fmt.Sprintf("%s=%s", step.BuildKitHostEnv, buildkitHost),
+	)
+
+	fmt.Printf("Runing %s Build.\n", s.spec.GetBuilderMode())
+	startTimeBuild := time.Now()
+	if err := c.Run(); err != nil {
+		return fmt.Errorf("failed to run %s build: %w", s.spec.GetBuilderMode(), err)
+	}
+	fmt.Printf("%s build ended. Duration: %.2f seconds.\n", s.spec.GetBuilderMode(), time.Since(startTimeBuild).Seconds())
+	return nil
+}
+
+func (s *DockerBuildStep) buildkitCommand(binDir string) (*exec.Cmd, error) {
+	dockerfile := s.absPath(s.spec.GetDockerFile())
+	// buildkitd pushes to the registry served with http only if it is insecure
+	insecure := ""
+	if s.spec.DockerRegistry != nil && strings.HasPrefix(s.spec.DockerRegistry.Host, "http://") {
+		insecure = ",registry.insecure=true"
+	}
+	args := []string{
+		"build",
+		"--frontend", "dockerfile.v0",
+		"--local", "context=" + s.absPath(s.spec.WorkDir),
+		"--local", "dockerfile=" + filepath.Dir(dockerfile),
+		"--opt", "filename=" + filepath.Base(dockerfile),
+		"--output", fmt.Sprintf("type=image,name=%s,push=true%s", s.spec.ImageName, insecure),
+	}
+	if s.spec.Platforms != "" {
+		args = append(args, "--opt", "platform="+s.spec.Platforms)
+	}
+	buildArgs, err := parseBuildArgs(s.spec.BuildArgs)
+	if err != nil {
+		return nil, err
+	}
+	for _, buildArg := range buildArgs {
+		args = append(args, "--opt", "build-arg:"+buildArg)
+	}
+	if s.spec.IgnoreCache {
+		args = append(args, "--no-cache")
+	}
+	if s.spec.CacheRepo != "" {
+		args = append(args,
+			"--import-cache", fmt.Sprintf("type=registry,ref=%s%s", s.spec.CacheRepo, insecure),
+			"--export-cache", fmt.Sprintf("type=registry,ref=%s,mode=max%s", s.spec.CacheRepo, insecure),
+		)
+	}
+	for _, secret := range s.spec.Secrets {
+		args = append(args, "--secret", fmt.Sprintf("id=%s,env=%s", secret.ID, secret.Env))
+	}
+	return exec.Command(filepath.Join(binDir, buildctlExe), args...), nil
+}
+
+func (s *DockerBuildStep) absPath(p string) string {
+	if filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(s.workspace, p)
+}
+
+// writeDockerConfig writes the registry auths to a docker config file in a temporary directory,
+// which is read by the daemonless builders, the image scanners and cosign with the DOCKER_CONFIG env.
+func writeDockerConfig(registries ...*step.DockerRegistry) (string, error) {
+	dir, err := os.MkdirTemp("", "docker-config")
+	if err != nil {
+		return "", err
+	}
+	conf := &dockerConfig{Auths: map[string]*dockerAuth{}}
+	for _, registry := range registries {
+		if registry == nil || registry.UserName == "" {
+			continue
+		}
+		host := strings.TrimPrefix(strings.TrimPrefix(registry.Host, "https://"), "http://")
+		conf.Auths[strings.TrimSuffix(host, "/")] = &dockerAuth{
+			Auth: base64.StdEncoding.EncodeToString([]byte(registry.UserName + ":" + registry.Password)),
+		}
+	}
+	content, err := json.Marshal(conf)
+	if err != nil {
+		return dir, err
+	}
+	return dir, os.WriteFile(filepath.Join(dir, "config.json"), content, 0600)
+}
+
+// parseBuildArgs returns the KEY=VALUE of the --build-arg flags in the docker build args, which are split with the
+// shell quoting rules. Other flags are not supported by the daemonless builder and are ignored.
+func parseBuildArgs(buildArgs string) ([]string, error) {
+	resp := []string{}
+	fields, err := shlex.Split(buildArgs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse build args %s: %s", buildArgs, err)
+	}
+	for i := 0; i < len(fields); i++ {
+		switch {
+		case fields[i] == "--build-arg" && i+1 < len(fields):
+			resp = append(resp, fields[i+1])
+			i++
+		case strings.HasPrefix(fields[i], "--build-arg="):
+			resp = append(resp, strings.TrimPrefix(fields[i], "--build-arg="))
+		default:
+			fmt.Printf("Build arg %s is ignored by the daemonless builder.\n", fields[i])
+		}
+	}
+	return resp, nil
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package step
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/koderover/zadig/pkg/types/step"
+)
+
+func TestParseBuildArgs(t *testing.T) {
+	args, err := parseBuildArgs(`--build-arg VERSION=1.0 --build-arg="GREETING=hello world" --build-arg 'PATH=/a b' --no-cache`)
+	require.NoError(t, err)
+	require.Equal(t, []string{"VERSION=1.0", "GREETING=hello world", "PATH=/a b"}, args)
+
+	args, err = parseBuildArgs("")
+	require.NoError(t, err)
+	require.Empty(t, args)
+
+	_, err = parseBuildArgs(`--build-arg "A=b`)
+	require.Error(t, err)
+}
+
+func TestBuildkitCommand(t *testing.T) {
+	s := &DockerBuildStep{
+		workspace: "/workspace",
+		spec: &step.StepDockerBuildSpec{
+			WorkDir:     "app",
+			DockerFile:  "docker/Dockerfile",
+			ImageName:   "koderover.tencentcloudcr.com/test/app:v1",
+			BuildArgs:   `--build-arg "NAME=a b"`,
+			BuilderMode: step.DockerBuilderModeBuildKit,
+			Platforms:   "linux/amd64,linux/arm64",
+			CacheRepo:   "koderover.tencentcloudcr.com/test/cache",
+			Secrets:     []*step.DockerBuildSecret{{ID: "npmrc", Env: "NPM_TOKEN"}},
+		},
+	}
+	c, err := s.buildkitCommand("/bin")
+	require.NoError(t, err)
+	require.Equal(t, "/bin/"+buildctlExe, c.Path)
+	require.Equal(t, []string{
+		"/bin/" + buildctlExe, "build",
+		"--frontend", "dockerfile.v0",
+		"--local", "context=/workspace/app",
+		"--local", "dockerfile=/workspace/docker",
+		"--opt", "filename=Dockerfile",
+		"--output", "type=image,name=koderover.tencentcloudcr.com/test/app:v1,push=true",
+		"--opt", "platform=linux/amd64,linux/arm64",
+		"--opt", "build-arg:NAME=a b",
+		"--import-cache", "type=registry,ref=koderover.tencentcloudcr.com/test/cache",
+		"--export-cache", "type=registry,ref=koderover.tencentcloudcr.com/test/cache,mode=max",
+		"--secret", "id=npmrc,env=NPM_TOKEN",
+	}, c.Args)
+}
+
+func TestBuildkitCommandWithInsecureRegistry(t *testing.T) {
+	s := &DockerBuildStep{
+		workspace: "/workspace",
+		spec: &step.StepDockerBuildSpec{
+			ImageName:      "192.168.1.10:5000/test/app:v1",
+			BuilderMode:    step.DockerBuilderModeBuildKit,
+			CacheRepo:      "192.168.1.10:5000/test/cache",
+			DockerRegistry: &step.DockerRegistry{Host: "http://192.168.1.10:5000"},
+		},
+	}
+	c, err := s.buildkitCommand("/bin")
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"/bin/" + buildctlExe, "build",
+		"--frontend", "dockerfile.v0",
+		"--local", "context=/workspace",
+		"--local", "dockerfile=/workspace",
+		"--opt", "filename=Dockerfile",
+		"--output", "type=image,name=192.168.1.10:5000/test/app:v1,push=true,registry.insecure=true",
+		"--import-cache", "type=registry,ref=192.168.1.10:5000/test/cache,registry.insecure=true",
+		"--export-cache", "type=registry,ref=192.168.1.10:5000/test/cache,mode=max,registry.insecure=true",
+	}, c.Args)
+}
+
+func TestWriteDockerConfig(t *testing.T) {
+	dir, err := writeDockerConfig(
+		&step.DockerRegistry{Host: "https://koderover.tencentcloudcr.com/", UserName: "user", Password: "pass"},
+		&step.DockerRegistry{Host: "docker.io"},
+		nil,
+	)
+	defer os.RemoveAll(dir)
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(filepath.Join(dir, "config.json"))
+	require.NoError(t, err)
+	conf := &dockerConfig{}
+	require.NoError(t, json.Unmarshal(content, conf))
+	require.Len(t, conf.Auths, 1)
+	require.Equal(t, base64.StdEncoding.EncodeToString([]byte("user:pass")), conf.Auths["koderover.tencentcloudcr.com"].Auth)
+}
+
+func TestValidateBuilder(t *testing.T) {
+	require.NoError(t, (&step.StepDockerBuildSpec{}).ValidateBuilder())
+	require.Error(t, (&step.StepDockerBuildSpec{Platforms: "linux/arm64"}).ValidateBuilder())
+	require.Error(t, (&step.StepDockerBuildSpec{BuildKitUnconfined: true}).ValidateBuilder())
+	require.Error(t, (&step.StepDockerBuildSpec{BuilderMode: "kaniko"}).ValidateBuilder())
+	require.NoError(t, (&step.StepDockerBuildSpec{BuilderMode: step.DockerBuilderModeBuildKit, Platforms: "linux/arm64", BuildKitUnconfined: true}).ValidateBuilder())
+	require.Error(t, (&step.StepDockerBuildSpec{BuilderMode: step.DockerBuilderModeBuildKit, Secrets: []*step.DockerBuildSecret{{ID: "npmrc"}}}).ValidateBuilder())
+}
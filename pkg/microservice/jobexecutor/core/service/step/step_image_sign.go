@@ -0,0 +1,141 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package step
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/koderover/zadig/pkg/tool/log"
+	"github.com/koderover/zadig/pkg/types/step"
+)
+
+// the cosign binary is shipped in the executor image and copied to the directory of jobexecutor
+const cosignExe = "cosign"
+
+// ImageSignStep signs the images with cosign, the signatures are pushed to the registries of the images
+type ImageSignStep struct {
+	spec       *step.StepImageSignSpec
+	envs       []string
+	secretEnvs []string
+	workspace  string
+}
+
+func NewImageSignStep(spec interface{}, workspace string, envs, secretEnvs []string) (*ImageSignStep, error) {
+	imageSignStep := &ImageSignStep{workspace: workspace, envs: envs, secretEnvs: secretEnvs}
+	yamlBytes, err := yaml.Marshal(spec)
+	if err != nil {
+		return imageSignStep, fmt.Errorf("marshal spec %+v failed", spec)
+	}
+	if err := yaml.Unmarshal(yamlBytes, &imageSignStep.spec); err != nil {
+		return imageSignStep, fmt.Errorf("unmarshal spec %s to image sign spec failed", yamlBytes)
+	}
+	return imageSignStep, nil
+}
+
+func (s *ImageSignStep) Run(ctx context.Context) error {
+	start := time.Now()
+	log.Infof("Start image sign.")
+	defer func() {
+		log.Infof("Image sign ended. Duration: %.2f seconds.", time.Since(start).Seconds())
+	}()
+
+	signingKey := s.spec.SigningKey
+	if signingKey == nil {
+		return fmt.Errorf("signing key of image sign not found")
+	}
+	envMap := makeEnvMap(s.envs, s.secretEnvs)
+	images := []string{}
+	for _, image := range s.spec.Images {
+		if image = replaceEnvWithValue(image, envMap); image != "" {
+			images = append(images, image)
+		}
+	}
+	if len(images) == 0 {
+		return fmt.Errorf("no image to sign")
+	}
+
+	dockerConfigDir, err := writeDockerConfig(s.spec.DockerRegistries...)
+	if err != nil {
+		return fmt.Errorf("failed to write registry auth: %s", err)
+	}
+	defer os.RemoveAll(dockerConfigDir)
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to find the cosign binary: %s", err)
+	}
+	binDir := filepath.Dir(executable)
+
+	args := []string{"sign", "--yes"}
+	envs := append(append([]string{}, s.envs...), fmt.Sprintf("DOCKER_CONFIG=%s", dockerConfigDir))
+	switch signingKey.Mode {
+	case step.ImageSignModeKey:
+		keyEnv, passwordEnv := step.ImageSigningKeyEnvs(s.spec.SigningKeyID)
+		privateKey := lookupEnv(s.secretEnvs, keyEnv)
+		if privateKey == "" {
+			return fmt.Errorf("private key of signing key %s not found", signingKey.Name)
+		}
+		keyDir, err := os.MkdirTemp("", "cosign")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(keyDir)
+		keyFile := filepath.Join(keyDir, "cosign.key")
+		if err := os.WriteFile(keyFile, []byte(privateKey), 0600); err != nil {
+			return fmt.Errorf("failed to write signing key: %s", err)
+		}
+		args = append(args, "--key", keyFile)
+		envs = append(envs, fmt.Sprintf("COSIGN_PASSWORD=%s", lookupEnv(s.secretEnvs, passwordEnv)))
+		if signingKey.RekorURL == "" {
+			args = append(args, "--tlog-upload=false")
+		}
+	case step.ImageSignModeKeyless:
+		token := envMap[signingKey.GetIdentityTokenEnv()]
+		if token == "" {
+			return fmt.Errorf("identity token is required for keyless signing, env %s is empty", signingKey.GetIdentityTokenEnv())
+		}
+		// cosign reads the token from the env, so it is not printed in the process list
+		envs = append(envs, fmt.Sprintf("%s=%s", step.DefaultIdentityTokenEnv, token))
+		if signingKey.FulcioURL != "" {
+			args = append(args, "--fulcio-url", signingKey.FulcioURL)
+		}
+	default:
+		return fmt.Errorf("unknown signing mode %s", signingKey.Mode)
+	}
+	if signingKey.RekorURL != "" {
+		args = append(args, "--rekor-url", signingKey.RekorURL)
+	}
+
+	for _, image := range images {
+		fmt.Printf("Signing image %s with %s.\n", image, signingKey.Name)
+		c := exec.Command(filepath.Join(binDir, cosignExe), append(args, image)...)
+		c.Stdout = os.Stdout
+		c.Stderr = os.Stderr
+		c.Dir = s.workspace
+		c.Env = envs
+		if err := c.Run(); err != nil {
+			return fmt.Errorf("failed to sign image %s: %s", image, err)
+		}
+	}
+	return nil
+}
@@ -114,7 +114,7 @@ func (s *JunitReportStep) Run(ctx context.Context) error {
 		}
 	}
 	log.Infof("Finish archive %s.", s.spec.FileName)
-	if failedCaseCount > 0 {
+	if failedCaseCount > 0 && !s.spec.IgnoreFailedCases {
 		return fmt.Errorf("%d case(s) failed", failedCaseCount)
 	}
 	return nil
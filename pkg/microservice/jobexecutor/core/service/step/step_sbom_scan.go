@@ -0,0 +1,251 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package step
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/log"
+	"github.com/koderover/zadig/pkg/tool/s3"
+	"github.com/koderover/zadig/pkg/types/step"
+)
+
+const (
+	// the scanner binaries are shipped in the executor image and copied to the directory of jobexecutor
+	syftExe  = "syft"
+	grypeExe = "grype"
+)
+
+type grypeReport struct {
+	Matches []*grypeMatch `json:"matches"`
+}
+
+type grypeMatch struct {
+	Vulnerability struct {
+		ID         string `json:"id"`
+		DataSource string `json:"dataSource"`
+		Severity   string `json:"severity"`
+		Fix        struct {
+			Versions []string `json:"versions"`
+		} `json:"fix"`
+	} `json:"vulnerability"`
+	Artifact struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+		Type    string `json:"type"`
+	} `json:"artifact"`
+}
+
+// SBOMScanStep generates the sbom of the image with syft and scans it with grype against the mirrored vulnerability database
+type SBOMScanStep struct {
+	spec       *step.StepSBOMScanSpec
+	envs       []string
+	secretEnvs []string
+	workspace  string
+}
+
+func NewSBOMScanStep(spec interface{}, workspace string, envs, secretEnvs []string) (*SBOMScanStep, error) {
+	sbomScanStep := &SBOMScanStep{workspace: workspace, envs: envs, secretEnvs: secretEnvs}
+	yamlBytes, err := yaml.Marshal(spec)
+	if err != nil {
+		return sbomScanStep, fmt.Errorf("marshal spec %+v failed", spec)
+	}
+	if err := yaml.Unmarshal(yamlBytes, &sbomScanStep.spec); err != nil {
+		return sbomScanStep, fmt.Errorf("unmarshal spec %s to sbom scan spec failed", yamlBytes)
+	}
+	return sbomScanStep, nil
+}
+
+func (s *SBOMScanStep) Run(ctx context.Context) error {
+	start := time.Now()
+	log.Infof("Start sbom scan.")
+	defer func() {
+		log.Infof("Sbom scan ended. Duration: %.2f seconds.", time.Since(start).Seconds())
+	}()
+
+	envMap := makeEnvMap(s.envs, s.secretEnvs)
+	imageName := replaceEnvWithValue(s.spec.ImageName, envMap)
+	if imageName == "" {
+		return fmt.Errorf("image to scan is empty")
+	}
+	if !step.IsValidSeverity(s.spec.SeverityThreshold) {
+		return fmt.Errorf("unknown severity threshold %s", s.spec.SeverityThreshold)
+	}
+	// grype downloads the public vulnerability database if the mirror is not set
+	if s.spec.VulnDBURL == "" {
+		return fmt.Errorf("vulnerability database url is empty")
+	}
+
+	dockerConfigDir, err := writeDockerConfig(s.spec.DockerRegistry)
+	if err != nil {
+		return fmt.Errorf("failed to write registry auth: %s", err)
+	}
+	defer os.RemoveAll(dockerConfigDir)
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to find the scanner binaries: %s", err)
+	}
+	binDir := filepath.Dir(executable)
+
+	resultDir, err := os.MkdirTemp("", "sbom-scan")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(resultDir)
+	sbomFile := filepath.Join(resultDir, step.SBOMFileName)
+	grypeFile := filepath.Join(resultDir, "grype.json")
+	reportFile := filepath.Join(resultDir, step.VulnerabilityReportFileName)
+	envs := append(append([]string{}, s.envs...),
+		fmt.Sprintf("DOCKER_CONFIG=%s", dockerConfigDir),
+		fmt.Sprintf("GRYPE_DB_CACHE_DIR=%s", s.dbCacheDir(envMap)),
+		fmt.Sprintf("GRYPE_DB_UPDATE_URL=%s", s.spec.VulnDBURL),
+		// the database is only updated from the mirror before the scan
+		"GRYPE_DB_AUTO_UPDATE=false",
+	)
+
+	fmt.Printf("Updating vulnerability database from %s.\n", s.spec.VulnDBURL)
+	dbUpdateCmd := exec.Command(filepath.Join(binDir, grypeExe), "db", "update")
+	if err := s.runCmd(dbUpdateCmd, envs); err != nil {
+		return fmt.Errorf("failed to update vulnerability database: %s", err)
+	}
+
+	fmt.Printf("Generating %s sbom of %s.\n", s.spec.GetSBOMFormat(), imageName)
+	// pull the image from the registry directly, there is no docker daemon in the job container
+	syftCmd := exec.Command(filepath.Join(binDir, syftExe), "registry:"+imageName, "-o", fmt.Sprintf("%s=%s", s.spec.GetSBOMFormat(), sbomFile))
+	if err := s.runCmd(syftCmd, envs); err != nil {
+		return fmt.Errorf("failed to generate sbom: %s", err)
+	}
+
+	fmt.Printf("Scanning vulnerabilities of %s.\n", imageName)
+	grypeCmd := exec.Command(filepath.Join(binDir, grypeExe), "sbom:"+sbomFile, "-o", "json", "--file", grypeFile)
+	if err := s.runCmd(grypeCmd, envs); err != nil {
+		return fmt.Errorf("failed to scan vulnerabilities: %s", err)
+	}
+	report, err := s.buildReport(imageName, grypeFile)
+	if err != nil {
+		return err
+	}
+	content, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(reportFile, content, 0644); err != nil {
+		return err
+	}
+	if err := s.upload(sbomFile, reportFile); err != nil {
+		return err
+	}
+
+	counts := []string{}
+	for _, severity := range []string{"critical", "high", "medium", "low", "negligible"} {
+		counts = append(counts, fmt.Sprintf("%s: %d", severity, report.SeverityCount[severity]))
+	}
+	fmt.Printf("Found %d vulnerabilities, %s.\n", len(report.Findings), strings.Join(counts, ", "))
+	if !report.Passed {
+		return fmt.Errorf("vulnerabilities of severity %s or higher are found in image %s", s.spec.SeverityThreshold, imageName)
+	}
+	return nil
+}
+
+// dbCacheDir is the directory of the vulnerability database, the database is kept in the home of the job if there is no cache volume
+func (s *SBOMScanStep) dbCacheDir(envMap map[string]string) string {
+	cacheDir := replaceEnvWithValue(s.spec.VulnDBCacheDir, envMap)
+	if cacheDir == "" {
+		return filepath.Join(envMap["HOME"], ".cache", "grype", "db")
+	}
+	if !filepath.IsAbs(cacheDir) {
+		cacheDir = filepath.Join(s.workspace, cacheDir)
+	}
+	return filepath.Clean(cacheDir)
+}
+
+func (s *SBOMScanStep) runCmd(c *exec.Cmd, envs []string) error {
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	c.Dir = s.workspace
+	c.Env = envs
+	return c.Run()
+}
+
+func (s *SBOMScanStep) buildReport(imageName, grypeFile string) (*step.ImageVulnerabilityReport, error) {
+	content, err := os.ReadFile(grypeFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scan result: %s", err)
+	}
+	result := &grypeReport{}
+	if err := json.Unmarshal(content, result); err != nil {
+		return nil, fmt.Errorf("failed to parse scan result: %s", err)
+	}
+
+	report := &step.ImageVulnerabilityReport{
+		ImageName:         imageName,
+		SeverityThreshold: s.spec.SeverityThreshold,
+		Passed:            true,
+		SeverityCount:     map[string]int{},
+		Findings:          []*step.VulnerabilityFinding{},
+	}
+	threshold := step.SeverityLevel(s.spec.SeverityThreshold)
+	for _, match := range result.Matches {
+		severity := strings.ToLower(match.Vulnerability.Severity)
+		report.SeverityCount[severity]++
+		report.Findings = append(report.Findings, &step.VulnerabilityFinding{
+			ID:       match.Vulnerability.ID,
+			Severity: severity,
+			Package:  match.Artifact.Name,
+			Version:  match.Artifact.Version,
+			Type:     match.Artifact.Type,
+			FixedIn:  strings.Join(match.Vulnerability.Fix.Versions, ","),
+			URL:      match.Vulnerability.DataSource,
+		})
+		if s.spec.SeverityThreshold != "" && step.SeverityLevel(severity) >= threshold {
+			report.Passed = false
+		}
+	}
+	return report, nil
+}
+
+func (s *SBOMScanStep) upload(files ...string) error {
+	if s.spec.S3Storage == nil {
+		return fmt.Errorf("s3 storage of sbom scan not found")
+	}
+	forcedPathStyle := true
+	if s.spec.S3Storage.Provider == setting.ProviderSourceAli {
+		forcedPathStyle = false
+	}
+	client, err := s3.NewClient(s.spec.S3Storage.Endpoint, s.spec.S3Storage.Ak, s.spec.S3Storage.Sk, s.spec.S3Storage.Region, s.spec.S3Storage.Insecure, forcedPathStyle)
+	if err != nil {
+		return fmt.Errorf("failed to create s3 client to upload file, err: %s", err)
+	}
+	destDir := strings.TrimLeft(path.Join(s.spec.S3Storage.Subfolder, s.spec.S3DestDir), "/")
+	for _, file := range files {
+		if err := client.Upload(s.spec.S3Storage.Bucket, file, path.Join(destDir, filepath.Base(file))); err != nil {
+			return fmt.Errorf("failed to upload %s: %s", filepath.Base(file), err)
+		}
+	}
+	return nil
+}
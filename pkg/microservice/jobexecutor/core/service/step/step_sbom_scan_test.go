@@ -0,0 +1,133 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package step
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/koderover/zadig/pkg/types/step"
+)
+
+const testGrypeReport = `{
+  "matches": [
+    {
+      "vulnerability": {"id": "CVE-2023-0001", "dataSource": "https://nvd.nist.gov/vuln/detail/CVE-2023-0001", "severity": "High", "fix": {"versions": ["1.2.1", "1.3.0"]}},
+      "artifact": {"name": "openssl", "version": "1.2.0", "type": "deb"}
+    },
+    {
+      "vulnerability": {"id": "CVE-2023-0002", "severity": "Medium", "fix": {"versions": []}},
+      "artifact": {"name": "zlib", "version": "1.2.11", "type": "deb"}
+    },
+    {
+      "vulnerability": {"id": "CVE-2023-0003", "severity": "Unknown", "fix": {"versions": []}},
+      "artifact": {"name": "busybox", "version": "1.35", "type": "apk"}
+    }
+  ]
+}`
+
+func writeGrypeReport(t *testing.T, content string) string {
+	file := filepath.Join(t.TempDir(), "grype.json")
+	require.NoError(t, os.WriteFile(file, []byte(content), 0644))
+	return file
+}
+
+func TestBuildReport(t *testing.T) {
+	s := &SBOMScanStep{spec: &step.StepSBOMScanSpec{}}
+	report, err := s.buildReport("koderover/app:v1", writeGrypeReport(t, testGrypeReport))
+	require.NoError(t, err)
+
+	require.Equal(t, "koderover/app:v1", report.ImageName)
+	require.True(t, report.Passed)
+	require.Equal(t, map[string]int{"high": 1, "medium": 1, "unknown": 1}, report.SeverityCount)
+	require.Len(t, report.Findings, 3)
+	require.Equal(t, &step.VulnerabilityFinding{
+		ID:       "CVE-2023-0001",
+		Severity: "high",
+		Package:  "openssl",
+		Version:  "1.2.0",
+		Type:     "deb",
+		FixedIn:  "1.2.1,1.3.0",
+		URL:      "https://nvd.nist.gov/vuln/detail/CVE-2023-0001",
+	}, report.Findings[0])
+	require.Equal(t, "", report.Findings[1].FixedIn)
+}
+
+func TestBuildReportSeverityThreshold(t *testing.T) {
+	grypeFile := writeGrypeReport(t, testGrypeReport)
+	testCases := []struct {
+		threshold string
+		passed    bool
+	}{
+		{threshold: "", passed: true},
+		{threshold: "critical", passed: true},
+		{threshold: "high", passed: false},
+		{threshold: "medium", passed: false},
+		{threshold: "negligible", passed: false},
+	}
+	for _, tc := range testCases {
+		s := &SBOMScanStep{spec: &step.StepSBOMScanSpec{SeverityThreshold: tc.threshold}}
+		report, err := s.buildReport("koderover/app:v1", grypeFile)
+		require.NoError(t, err)
+		require.Equal(t, tc.passed, report.Passed, "threshold %q", tc.threshold)
+		require.Equal(t, tc.threshold, report.SeverityThreshold)
+	}
+
+	// unknown severities never fail the scan
+	s := &SBOMScanStep{spec: &step.StepSBOMScanSpec{SeverityThreshold: "negligible"}}
+	report, err := s.buildReport("koderover/app:v1", writeGrypeReport(t, `{"matches": [{"vulnerability": {"id": "CVE-2023-0003", "severity": "Unknown"}}]}`))
+	require.NoError(t, err)
+	require.True(t, report.Passed)
+}
+
+func TestBuildReportEmpty(t *testing.T) {
+	s := &SBOMScanStep{spec: &step.StepSBOMScanSpec{SeverityThreshold: "low"}}
+	report, err := s.buildReport("koderover/app:v1", writeGrypeReport(t, `{"matches": []}`))
+	require.NoError(t, err)
+	require.True(t, report.Passed)
+	require.Empty(t, report.Findings)
+	require.Empty(t, report.SeverityCount)
+}
+
+func TestBuildReportInvalid(t *testing.T) {
+	s := &SBOMScanStep{spec: &step.StepSBOMScanSpec{}}
+	_, err := s.buildReport("koderover/app:v1", writeGrypeReport(t, "not json"))
+	require.Error(t, err)
+
+	_, err = s.buildReport("koderover/app:v1", filepath.Join(t.TempDir(), "missing.json"))
+	require.Error(t, err)
+}
+
+func TestDBCacheDir(t *testing.T) {
+	envMap := map[string]string{"HOME": "/home/zadig", "WORKSPACE": "/workspace"}
+	testCases := []struct {
+		cacheDir string
+		expected string
+	}{
+		{cacheDir: "", expected: "/home/zadig/.cache/grype/db"},
+		{cacheDir: "$WORKSPACE/.grype/db", expected: "/workspace/.grype/db"},
+		{cacheDir: "/cache/.grype/db", expected: "/cache/.grype/db"},
+		{cacheDir: "cache/.grype/db", expected: "/workspace/repo/cache/.grype/db"},
+	}
+	for _, tc := range testCases {
+		s := &SBOMScanStep{spec: &step.StepSBOMScanSpec{VulnDBCacheDir: tc.cacheDir}, workspace: "/workspace/repo"}
+		require.Equal(t, tc.expected, s.dbCacheDir(envMap), tc.cacheDir)
+	}
+}
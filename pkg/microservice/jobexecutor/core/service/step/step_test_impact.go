@@ -0,0 +1,128 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package step
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/koderover/zadig/pkg/tool/log"
+	"github.com/koderover/zadig/pkg/tool/testimpact"
+	"github.com/koderover/zadig/pkg/types/job"
+	"github.com/koderover/zadig/pkg/types/step"
+)
+
+// maxTestImpactTargetsLength keeps the targets env within the termination message of the job,
+// the full suite is run if the targets are longer than it
+const maxTestImpactTargetsLength = 1024
+
+// TestImpactStep computes the test targets affected by the changed files, the targets are written to the output file
+// and shared with the following steps by the TEST_IMPACT_FULL and TEST_IMPACT_TARGETS outputs.
+// It never fails the job, the full suite is run if the impact can not be computed.
+type TestImpactStep struct {
+	spec       *step.StepTestImpactSpec
+	envs       []string
+	secretEnvs []string
+	workspace  string
+}
+
+func NewTestImpactStep(spec interface{}, workspace string, envs, secretEnvs []string) (*TestImpactStep, error) {
+	testImpactStep := &TestImpactStep{workspace: workspace, envs: envs, secretEnvs: secretEnvs}
+	yamlBytes, err := yaml.Marshal(spec)
+	if err != nil {
+		return testImpactStep, fmt.Errorf("marshal spec %+v failed", spec)
+	}
+	if err := yaml.Unmarshal(yamlBytes, &testImpactStep.spec); err != nil {
+		return testImpactStep, fmt.Errorf("unmarshal spec %s to test impact spec failed", yamlBytes)
+	}
+	return testImpactStep, nil
+}
+
+func (s *TestImpactStep) Run(ctx context.Context) error {
+	log.Info("Start analyze test impact.")
+	repoDir := filepath.Join(s.workspace, s.spec.RepoDir)
+
+	var goPackages []*testimpact.GoPackage
+	if s.spec.GoImportGraph && len(s.spec.ChangedFiles) > 0 {
+		packages, err := s.listGoPackages(repoDir)
+		if err != nil {
+			log.Warnf("failed to list go packages, the go import graph is not used: %s", err)
+		} else {
+			goPackages = packages
+		}
+	}
+	result := testimpact.Analyze(s.spec.ChangedFiles, s.spec.Mappings, s.spec.IgnorePaths, goPackages)
+	targets := strings.Join(result.Targets, " ")
+	if !result.Full && len(targets) > maxTestImpactTargetsLength {
+		result = &step.TestImpactResult{Full: true, Reason: fmt.Sprintf("%d targets are affected", len(result.Targets)), Targets: []string{}}
+		targets = ""
+	}
+
+	if result.Full {
+		fmt.Printf("Run the full suite: %s.\n", result.Reason)
+	} else {
+		fmt.Printf("%d changed file(s) affect %d test target(s):\n", len(s.spec.ChangedFiles), len(result.Targets))
+		for _, target := range result.Targets {
+			fmt.Printf("  %s\n", target)
+		}
+	}
+
+	outputFile := s.spec.OutputFile
+	if outputFile == "" {
+		outputFile = step.TestImpactFileName
+	}
+	if !filepath.IsAbs(outputFile) {
+		outputFile = filepath.Join(s.workspace, outputFile)
+	}
+	if err := os.MkdirAll(filepath.Dir(outputFile), os.ModePerm); err != nil {
+		log.Warnf("failed to create dir of %s: %s", outputFile, err)
+	} else if err := os.WriteFile(outputFile, []byte(strings.Join(result.Targets, "\n")), 0644); err != nil {
+		log.Warnf("failed to write test impact targets to %s: %s", outputFile, err)
+	}
+	if err := os.WriteFile(filepath.Join(job.JobOutputDir, step.TestImpactFullEnv), []byte(strconv.FormatBool(result.Full)), 0644); err != nil {
+		log.Warnf("failed to write output %s: %s", step.TestImpactFullEnv, err)
+	}
+	if err := os.WriteFile(filepath.Join(job.JobOutputDir, step.TestImpactTargetsEnv), []byte(targets), 0644); err != nil {
+		log.Warnf("failed to write output %s: %s", step.TestImpactTargetsEnv, err)
+	}
+	log.Info("Finish analyze test impact.")
+	return nil
+}
+
+func (s *TestImpactStep) listGoPackages(repoDir string) ([]*testimpact.GoPackage, error) {
+	if _, err := os.Stat(filepath.Join(repoDir, "go.mod")); err != nil {
+		return nil, fmt.Errorf("go.mod is not found in %s", repoDir)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("go", testimpact.GoListArgs...)
+	cmd.Dir = repoDir
+	cmd.Env = s.envs
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%s: %s", err, maskSecretEnvs(stderr.String(), s.secretEnvs))
+	}
+	return testimpact.ParseGoList(stdout.Bytes(), repoDir)
+}
@@ -0,0 +1,233 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package step
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/exp/slices"
+	"gopkg.in/yaml.v2"
+
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/log"
+	"github.com/koderover/zadig/pkg/tool/s3"
+	"github.com/koderover/zadig/pkg/tool/testreport"
+	"github.com/koderover/zadig/pkg/types/step"
+)
+
+// ErrOnlyQuarantinedCasesFailed is returned by the gating test report step if all the failed cases are quarantined,
+// the job clears the failure of the test script with it since the script exits non-zero for the failed cases
+var ErrOnlyQuarantinedCasesFailed = errors.New("only quarantined cases failed")
+
+// TestReportStep parses the test results and coverage into the normalized step.TestReport and uploads it
+type TestReportStep struct {
+	spec       *step.StepTestReportSpec
+	envs       []string
+	secretEnvs []string
+	workspace  string
+}
+
+func NewTestReportStep(spec interface{}, workspace string, envs, secretEnvs []string) (*TestReportStep, error) {
+	testReportStep := &TestReportStep{workspace: workspace, envs: envs, secretEnvs: secretEnvs}
+	yamlBytes, err := yaml.Marshal(spec)
+	if err != nil {
+		return testReportStep, fmt.Errorf("marshal spec %+v failed", spec)
+	}
+	if err := yaml.Unmarshal(yamlBytes, &testReportStep.spec); err != nil {
+		return testReportStep, fmt.Errorf("unmarshal spec %s to test report spec failed", yamlBytes)
+	}
+	return testReportStep, nil
+}
+
+func (s *TestReportStep) Run(ctx context.Context) error {
+	log.Info("Start parse test report.")
+	envMap := makeEnvMap(s.envs, s.secretEnvs)
+	report := &step.TestReport{
+		Formats: []string{},
+		Cases:   []*step.TestCaseResult{},
+	}
+
+	reportFiles, err := s.resolveFiles(s.spec.ReportPaths, reportExtensions(s.spec.Format), envMap)
+	if err != nil {
+		return err
+	}
+	for _, file := range reportFiles {
+		// the failed cases in an unreadable result are unknown, so the job can't pass if the step gates it
+		content, err := os.ReadFile(file)
+		if err != nil {
+			if s.spec.FailOnFailedCases {
+				return fmt.Errorf("failed to read test result %s: %s", file, err)
+			}
+			log.Warnf("failed to read test result %s: %s", file, err)
+			continue
+		}
+		format, cases, err := testreport.ParseTestResult(s.spec.Format, file, content)
+		if err != nil {
+			if s.spec.FailOnFailedCases {
+				return fmt.Errorf("failed to parse test result %s as %s: %s", file, format, err)
+			}
+			log.Warnf("failed to parse test result %s as %s: %s", file, format, err)
+			continue
+		}
+		if !slices.Contains(report.Formats, format) {
+			report.Formats = append(report.Formats, format)
+		}
+		report.Cases = append(report.Cases, cases...)
+	}
+	if len(s.spec.ReportPaths) > 0 && len(report.Formats) == 0 {
+		if s.spec.FailOnFailedCases {
+			return fmt.Errorf("no test result is found in %s", strings.Join(s.spec.ReportPaths, ", "))
+		}
+		log.Warnf("no test result is found in %s", strings.Join(s.spec.ReportPaths, ", "))
+	}
+	testreport.Summarize(report)
+	testreport.Quarantine(report, s.spec.QuarantinedCases)
+
+	coverageFiles, err := s.resolveFiles(s.spec.CoveragePaths, []string{".xml", ".info", ".lcov"}, envMap)
+	if err != nil {
+		return err
+	}
+	for _, file := range coverageFiles {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			log.Warnf("failed to read coverage %s: %s", file, err)
+			continue
+		}
+		coverage, err := testreport.ParseCoverage(s.spec.CoverageFormat, file, content)
+		if err != nil {
+			log.Warnf("failed to parse coverage %s: %s", file, err)
+			continue
+		}
+		report.Coverage = testreport.MergeCoverage(report.Coverage, coverage)
+	}
+
+	fmt.Printf("Tests: %d, failures: %d, skips: %d, flakes: %d.\n", report.Tests, report.Failures, report.Skips, report.Flakes)
+	for _, c := range report.Cases {
+		if c.Quarantined && c.Status == step.TestCaseStatusFailed {
+			fmt.Printf("Quarantined case %s failed, it is ignored.\n", c.FullName())
+		}
+	}
+	if report.Coverage != nil {
+		fmt.Printf("Line coverage: %.2f%%, branch coverage: %.2f%%.\n", report.Coverage.LineRate*100, report.Coverage.BranchRate*100)
+	}
+	if err := s.upload(report); err != nil {
+		// the report only feeds the test stats if the step does not gate the job
+		if s.spec.FailOnFailedCases {
+			return err
+		}
+		log.Warnf("failed to upload test report: %s", err)
+	}
+	log.Info("Finish parse test report.")
+
+	if !s.spec.FailOnFailedCases || report.Failures == 0 {
+		return nil
+	}
+	if failures := report.Failures - report.QuarantinedFailures; failures > 0 {
+		return fmt.Errorf("%d case(s) failed", failures)
+	}
+	return ErrOnlyQuarantinedCasesFailed
+}
+
+// resolveFiles returns the files matching the glob patterns, the files with the extensions are returned if a pattern is a directory
+func (s *TestReportStep) resolveFiles(patterns, extensions []string, envMap map[string]string) ([]string, error) {
+	files := []string{}
+	for _, pattern := range patterns {
+		pattern = replaceEnvWithValue(pattern, envMap)
+		if pattern == "" {
+			continue
+		}
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(s.workspace, pattern)
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid path %s: %s", pattern, err)
+		}
+		for _, match := range matches {
+			err := filepath.WalkDir(match, func(p string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if d.IsDir() || slices.Contains(files, p) {
+					return nil
+				}
+				if p != match && !slices.Contains(extensions, strings.ToLower(filepath.Ext(p))) {
+					return nil
+				}
+				files = append(files, p)
+				return nil
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to list files in %s: %s", match, err)
+			}
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func (s *TestReportStep) upload(report *step.TestReport) error {
+	if s.spec.S3Storage == nil || s.spec.S3DestDir == "" {
+		return nil
+	}
+	content, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	reportFile := filepath.Join(os.TempDir(), step.TestReportFileName)
+	if err := os.WriteFile(reportFile, content, 0644); err != nil {
+		return err
+	}
+	defer os.Remove(reportFile)
+
+	forcedPathStyle := true
+	if s.spec.S3Storage.Provider == setting.ProviderSourceAli {
+		forcedPathStyle = false
+	}
+	client, err := s3.NewClient(s.spec.S3Storage.Endpoint, s.spec.S3Storage.Ak, s.spec.S3Storage.Sk, s.spec.S3Storage.Region, s.spec.S3Storage.Insecure, forcedPathStyle)
+	if err != nil {
+		return fmt.Errorf("failed to create s3 client to upload file, err: %s", err)
+	}
+	destDir := strings.TrimLeft(path.Join(s.spec.S3Storage.Subfolder, s.spec.S3DestDir), "/")
+	if err := client.Upload(s.spec.S3Storage.Bucket, reportFile, path.Join(destDir, step.TestReportFileName)); err != nil {
+		return fmt.Errorf("failed to upload test report: %s", err)
+	}
+	return nil
+}
+
+// reportExtensions returns the extensions of the test result files in a directory
+func reportExtensions(format string) []string {
+	switch format {
+	case step.TestReportFormatJUnit:
+		return []string{".xml"}
+	case step.TestReportFormatTRX:
+		return []string{".trx"}
+	case step.TestReportFormatGoTest:
+		return []string{".json", ".jsonl", ".txt", ".log"}
+	default:
+		return []string{".xml", ".trx", ".json", ".jsonl"}
+	}
+}
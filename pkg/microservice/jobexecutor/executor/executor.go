@@ -20,6 +20,8 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"os/exec"
+	"strconv"
 	"time"
 
 	"github.com/pkg/errors"
@@ -27,6 +29,7 @@ import (
 	"k8s.io/client-go/rest"
 
 	commonconfig "github.com/koderover/zadig/pkg/config"
+	"github.com/koderover/zadig/pkg/microservice/jobexecutor/config"
 	job "github.com/koderover/zadig/pkg/microservice/jobexecutor/core/service"
 	"github.com/koderover/zadig/pkg/microservice/jobexecutor/core/service/configmap"
 	"github.com/koderover/zadig/pkg/setting"
@@ -63,29 +66,40 @@ func Execute(ctx context.Context) error {
 		return err
 	}
 
-	ns, err := os.ReadFile("/var/run/secrets/kubernetes.io/serviceaccount/namespace")
-	if err != nil {
-		log.Errorf("Failed to get namespace, err: %v", err)
-		return errors.Wrap(err, "get namespace")
-	}
-	config, err := rest.InClusterConfig()
-	if err != nil {
-		log.Errorf("failed to get InClusterConfig, err: %v", err)
-		return errors.Wrap(err, "get InClusterConfig")
-	}
-	clientset, err := kubernetes.NewForConfig(config)
-	if err != nil {
-		log.Errorf("failed to get ClientSet, err: %v", err)
-		return errors.Wrap(err, "get ClientSet")
-	}
+	if resultFile := config.JobResultFile(); resultFile != "" {
+		// the job runs on a vm runner, which reads the job result from the file
+		j.ConfigMapUpdater = configmap.NewFileUpdater(resultFile)
+	} else {
+		ns, err := os.ReadFile("/var/run/secrets/kubernetes.io/serviceaccount/namespace")
+		if err != nil {
+			log.Errorf("Failed to get namespace, err: %v", err)
+			return errors.Wrap(err, "get namespace")
+		}
+		restConfig, err := rest.InClusterConfig()
+		if err != nil {
+			log.Errorf("failed to get InClusterConfig, err: %v", err)
+			return errors.Wrap(err, "get InClusterConfig")
+		}
+		clientset, err := kubernetes.NewForConfig(restConfig)
+		if err != nil {
+			log.Errorf("failed to get ClientSet, err: %v", err)
+			return errors.Wrap(err, "get ClientSet")
+		}
 
-	j.ConfigMapUpdater = configmap.NewUpdater(j.Ctx.ConfigMapName, string(ns), clientset)
+		j.ConfigMapUpdater = configmap.NewUpdater(j.Ctx.ConfigMapName, string(ns), clientset)
+	}
 
 	defer func() {
 		resultMsg := types.JobSuccess
+		exitCode := 0
 		if err != nil {
 			resultMsg = types.JobFail
 			fmt.Printf("Failed to run: %s.\n", err)
+			// aslan decides whether to retry the job by the exit code
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				exitCode = exitErr.ExitCode()
+			}
 		}
 		fmt.Printf("Job Status: %s\n", resultMsg)
 
@@ -97,6 +111,9 @@ func Execute(ctx context.Context) error {
 		}
 		cm.Data[types.JobResultKey] = string(resultMsg)
 		cm.Data[types.JobOutputsKey] = string(j.OutputsJsonBytes)
+		if exitCode != 0 {
+			cm.Data[types.JobExitCodeKey] = strconv.Itoa(exitCode)
+		}
 		if j.ConfigMapUpdater.UpdateWithRetry(cm, 3, 3*time.Second) != nil {
 			log.Errorf("failed to update job context ConfigMap: %v", err)
 			return
@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -35,8 +36,12 @@ import (
 	e "github.com/koderover/zadig/pkg/tool/errors"
 	"github.com/koderover/zadig/pkg/tool/kube/getter"
 	"github.com/koderover/zadig/pkg/tool/log"
+	"github.com/koderover/zadig/pkg/types"
 )
 
+// maxDebugTranscriptSize is the max size of the recorded transcript of a debug session
+const maxDebugTranscriptSize = 1 << 20
+
 func ServeWs(c *gin.Context) {
 	ctx := internalhandler.NewContext(c)
 	defer func() { internalhandler.JSONResponse(c, ctx) }()
@@ -105,8 +110,16 @@ func ServeWs(c *gin.Context) {
 }
 
 func DebugWorkflow(c *gin.Context) {
-	ctx := internalhandler.NewContext(c)
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
 	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Logger.Errorf("failed to generate authorization info for user: %s, error: %s", ctx.UserID, err)
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
 	logger := ctx.Logger
 	taskID, err := strconv.ParseInt(c.Param("taskID"), 10, 64)
 	if err != nil {
@@ -114,11 +127,37 @@ func DebugWorkflow(c *gin.Context) {
 		return
 	}
 
-	ctx.Err = debugWorkflow(c, c.Param("workflowName"), c.Param("jobName"), taskID, logger)
+	workflowName := c.Param("workflowName")
+	workflow, err := commonrepo.NewWorkflowV4Coll().Find(workflowName)
+	if err != nil {
+		logger.Errorf("debug workflow failed: find workflow %s error: %v", workflowName, err)
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+
+	// authorization check, the same as enabling the debug of the workflow task
+	if !ctx.Resources.IsSystemAdmin {
+		if _, ok := ctx.Resources.ProjectAuthInfo[workflow.Project]; !ok {
+			ctx.UnAuthorized = true
+			return
+		}
+
+		if !ctx.Resources.ProjectAuthInfo[workflow.Project].IsProjectAdmin &&
+			!ctx.Resources.ProjectAuthInfo[workflow.Project].Workflow.Debug {
+			// check if the permission is given by collaboration mode
+			permitted, err := internalhandler.GetCollaborationModePermission(ctx.UserID, workflow.Project, types.ResourceTypeWorkflow, workflowName, types.WorkflowActionDebug)
+			if err != nil || !permitted {
+				ctx.UnAuthorized = true
+				return
+			}
+		}
+	}
+
+	ctx.Err = debugWorkflow(c, workflowName, c.Param("jobName"), taskID, ctx.UserName, logger)
 	return
 }
 
-func debugWorkflow(c *gin.Context, workflowName, jobName string, taskID int64, logger *zap.SugaredLogger) error {
+func debugWorkflow(c *gin.Context, workflowName, jobName string, taskID int64, userName string, logger *zap.SugaredLogger) error {
 	w := workflowcontroller.GetWorkflowTaskInMap(workflowName, taskID)
 	if w == nil {
 		logger.Error("debug workflow failed: not found task")
@@ -146,6 +185,7 @@ FOR:
 		return e.ErrGetDebugShell.AddDesc("启动调试终端意外失败")
 	}
 
+	recorder := &TranscriptRecorder{MaxSize: maxDebugTranscriptSize}
 	pty, err := NewTerminalSession(c.Writer, c.Request, nil, &TerminalSessionOption{
 		SecretEnvs: func() (secrets []string) {
 			for _, v := range jobTaskSpec.Properties.Envs {
@@ -155,7 +195,8 @@ FOR:
 			}
 			return secrets
 		}(),
-		Type: Workflow,
+		Type:     Workflow,
+		Recorder: recorder,
 	})
 	if err != nil {
 		log.Errorf("get pty failed: %v", err)
@@ -209,6 +250,25 @@ FOR:
 	}
 	script += "bash\n"
 
+	session := &commonmodels.DebugSession{
+		WorkflowName: workflowName,
+		TaskID:       taskID,
+		JobName:      jobName,
+		PodName:      pod.Name,
+		UserName:     userName,
+		StartTime:    time.Now().Unix(),
+	}
+	defer func() {
+		session.EndTime = time.Now().Unix()
+		// the output held back by the secret masker is recorded before the transcript is saved
+		_ = pty.Flush()
+		session.Transcript = recorder.String()
+		session.Truncated = recorder.Truncated
+		if err := commonrepo.NewDebugSessionColl().Create(session); err != nil {
+			logger.Errorf("failed to save debug session of %s/%d/%s: %s", workflowName, taskID, jobName, err)
+		}
+	}()
+
 	err = ExecPod(clientSet, restConfig, []string{"/bin/sh", "-c", script}, pty, jobTaskSpec.Properties.Namespace, pod.Name, pod.Spec.Containers[0].Name)
 	if err != nil {
 		msg := fmt.Sprintf("Exec to pod error! err: %v", err)
@@ -23,6 +23,8 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -34,6 +36,7 @@ import (
 	"k8s.io/client-go/tools/remotecommand"
 
 	conf "github.com/koderover/zadig/pkg/microservice/podexec/config"
+	"github.com/koderover/zadig/pkg/setting"
 	"github.com/koderover/zadig/pkg/shared/kube/client"
 	"github.com/koderover/zadig/pkg/shared/kube/wrapper"
 	"github.com/koderover/zadig/pkg/tool/log"
@@ -50,6 +53,9 @@ var upgrader = websocket.Upgrader{
 
 const (
 	EndOfTransmission = "\u0004"
+
+	// secretMaskFlushDelay is how long the output held back by the secret masker waits for the rest of a secret
+	secretMaskFlushDelay = 100 * time.Millisecond
 )
 
 // TerminalMessage is the messaging protocol between ShellController and TerminalSession.
@@ -84,11 +90,19 @@ type TerminalSession struct {
 	// SecretEnvs is a list of environment variables that should be hidden from the client.
 	SecretEnvs []string
 	Type       TerminalSessionType
+	// Recorder records the output of the session with the secret envs masked
+	Recorder io.Writer
+
+	masker *secretMasker
+	// writeMu keeps the output in order when the held back output is flushed by flushTimer
+	writeMu    sync.Mutex
+	flushTimer *time.Timer
 }
 
 type TerminalSessionOption struct {
 	SecretEnvs []string
 	Type       TerminalSessionType
+	Recorder   io.Writer
 }
 
 func NewTerminalSession(w http.ResponseWriter, r *http.Request, responseHeader http.Header, opt ...*TerminalSessionOption) (*TerminalSession, error) {
@@ -105,7 +119,9 @@ func NewTerminalSession(w http.ResponseWriter, r *http.Request, responseHeader h
 	if len(opt) > 0 {
 		session.SecretEnvs = opt[0].SecretEnvs
 		session.Type = opt[0].Type
+		session.Recorder = opt[0].Recorder
 	}
+	session.masker = newSecretMasker(session.SecretEnvs)
 	return session, nil
 }
 
@@ -150,28 +166,178 @@ func (t *TerminalSession) Read(p []byte) (int, error) {
 
 // Write called from remotecommand whenever there is any output
 func (t *TerminalSession) Write(p []byte) (int, error) {
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+
+	data := p
+	if t.Type == Workflow {
+		data = t.masker.mask(p)
+		t.scheduleFlush()
+	}
+	if err := t.write(data); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Flush writes out the output held back by the secret masker
+func (t *TerminalSession) Flush() error {
+	if t.Type != Workflow {
+		return nil
+	}
+
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+
+	if t.flushTimer != nil {
+		t.flushTimer.Stop()
+	}
+	return t.write(t.masker.flush())
+}
+
+// scheduleFlush flushes the held back output if no more output comes in secretMaskFlushDelay,
+// so a prompt ending like the beginning of a secret is not held back until the next output.
+// It's called with writeMu held.
+func (t *TerminalSession) scheduleFlush() {
+	if !t.masker.holding() {
+		if t.flushTimer != nil {
+			t.flushTimer.Stop()
+		}
+		return
+	}
+
+	if t.flushTimer == nil {
+		t.flushTimer = time.AfterFunc(secretMaskFlushDelay, func() {
+			_ = t.Flush()
+		})
+		return
+	}
+	t.flushTimer.Reset(secretMaskFlushDelay)
+}
+
+func (t *TerminalSession) write(data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
 	msg, err := json.Marshal(TerminalMessage{
 		Operation: "stdout",
-		Data:      string(p),
+		Data:      string(data),
 	})
 	if err != nil {
 		log.Errorf("write parse message err: %v", err)
-		return 0, err
+		return err
 	}
-	if t.Type == Workflow {
-		for _, secretEnv := range t.SecretEnvs {
-			msg = bytes.ReplaceAll(msg, []byte(secretEnv), []byte("********"))
-		}
+	if t.Recorder != nil {
+		_, _ = t.Recorder.Write(data)
 	}
 	if err := t.wsConn.WriteMessage(websocket.TextMessage, msg); err != nil {
 		log.Errorf("write message err: %v", err)
-		return 0, err
+		return err
 	}
-	return len(p), nil
+	return nil
+}
+
+// secretMasker masks the secrets in a stream of output. A secret may be split across two writes,
+// so the tail of a write which may be the beginning of a secret is held back until the next write.
+type secretMasker struct {
+	mu      sync.Mutex
+	secrets [][]byte
+	pending []byte
+}
+
+func newSecretMasker(secrets []string) *secretMasker {
+	m := &secretMasker{}
+	for _, secret := range secrets {
+		if secret != "" {
+			m.secrets = append(m.secrets, []byte(secret))
+		}
+	}
+	// longer secrets are masked first in case a secret contains another one
+	sort.Slice(m.secrets, func(i, j int) bool {
+		return len(m.secrets[i]) > len(m.secrets[j])
+	})
+	return m
+}
+
+// mask returns the masked output which is safe to send, the rest is held back
+func (m *secretMasker) mask(p []byte) []byte {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	data := append(m.pending, p...)
+	for _, secret := range m.secrets {
+		data = bytes.ReplaceAll(data, secret, []byte(setting.MaskValue))
+	}
+	held := m.heldBackLength(data)
+	m.pending = append([]byte(nil), data[len(data)-held:]...)
+	return data[:len(data)-held]
+}
+
+// flush returns the output held back, it does not contain a whole secret
+func (m *secretMasker) flush() []byte {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	data := m.pending
+	m.pending = nil
+	return data
+}
+
+// holding returns true if some output is held back
+func (m *secretMasker) holding() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.pending) > 0
+}
+
+// heldBackLength is the length of the longest suffix of data which is a proper prefix of a secret
+func (m *secretMasker) heldBackLength(data []byte) int {
+	longest := 0
+	for _, secret := range m.secrets {
+		for n := len(secret) - 1; n > longest; n-- {
+			if n <= len(data) && bytes.HasSuffix(data, secret[:n]) {
+				longest = n
+				break
+			}
+		}
+	}
+	return longest
+}
+
+// TranscriptRecorder keeps the first MaxSize bytes written to it, the rest is dropped and Truncated is set
+type TranscriptRecorder struct {
+	MaxSize   int
+	Truncated bool
+
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (r *TranscriptRecorder) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if left := r.MaxSize - r.buf.Len(); len(p) > left {
+		r.Truncated = true
+		if left > 0 {
+			r.buf.Write(p[:left])
+		}
+		return len(p), nil
+	}
+	return r.buf.Write(p)
+}
+
+func (r *TranscriptRecorder) String() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.buf.String()
 }
 
 // Close close session
 func (t *TerminalSession) Close() error {
+	_ = t.Flush()
 	return t.wsConn.Close()
 }
 
@@ -20,7 +20,12 @@ import (
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
 )
 
 func TestGetProduct(t *testing.T) {
@@ -48,3 +53,100 @@ func TestGetProduct(t *testing.T) {
 	}
 	t.Logf("result:%v", *result)
 }
+
+func TestSecretMaskerAcrossWrites(t *testing.T) {
+	masker := newSecretMasker([]string{"s3cr3t", "", "token-123"})
+
+	var out []byte
+	for _, chunk := range []string{"export A=s3", "cr", "3t B=tok", "en-12", "3 done s", "3"} {
+		out = append(out, masker.mask([]byte(chunk))...)
+	}
+	out = append(out, masker.flush()...)
+
+	if got, want := string(out), "export A=******** B=******** done s3"; got != want {
+		t.Errorf("masked output = %q, want %q", got, want)
+	}
+}
+
+func TestSecretMaskerHoldsBackOnlySecretPrefixes(t *testing.T) {
+	masker := newSecretMasker([]string{"s3cr3t"})
+
+	if got := string(masker.mask([]byte("plain output\n"))); got != "plain output\n" {
+		t.Errorf("output without a secret prefix should not be held back, got %q", got)
+	}
+	if got := string(masker.mask([]byte("value s3c"))); got != "value " {
+		t.Errorf("the secret prefix should be held back, got %q", got)
+	}
+	if got := string(masker.mask([]byte("ret"))); got != "s3cret" {
+		t.Errorf("the held back output should be released once it is not a secret, got %q", got)
+	}
+}
+
+func TestTerminalSessionFlushesHeldBackOutput(t *testing.T) {
+	recorder := &TranscriptRecorder{MaxSize: 1024}
+	written := make(chan error, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		session, err := NewTerminalSession(w, r, nil, &TerminalSessionOption{
+			SecretEnvs: []string{"s3cr3t"},
+			Type:       Workflow,
+			Recorder:   recorder,
+		})
+		if err != nil {
+			written <- err
+			return
+		}
+		for _, chunk := range []string{"token s3cr3t\n", "password: s3"} {
+			if _, err := session.Write([]byte(chunk)); err != nil {
+				written <- err
+				return
+			}
+		}
+		written <- nil
+	}))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("failed to dial the terminal: %v", err)
+	}
+	defer conn.Close()
+	if err := <-written; err != nil {
+		t.Fatalf("failed to write to the terminal: %v", err)
+	}
+
+	var out string
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for out != "token ********\npassword: s3" {
+		var msg TerminalMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("output = %q, the held back output is not flushed: %v", out, err)
+		}
+		out += msg.Data
+	}
+	if got := recorder.String(); got != out {
+		t.Errorf("transcript = %q, want %q", got, out)
+	}
+}
+
+func TestTranscriptRecorder(t *testing.T) {
+	recorder := &TranscriptRecorder{MaxSize: 10}
+
+	for _, chunk := range []string{"12345", "6789", "abc", "def"} {
+		n, err := recorder.Write([]byte(chunk))
+		if err != nil || n != len(chunk) {
+			t.Fatalf("Write(%q) = %d, %v", chunk, n, err)
+		}
+	}
+	if got := recorder.String(); got != "123456789a" {
+		t.Errorf("transcript = %q, want %q", got, "123456789a")
+	}
+	if !recorder.Truncated {
+		t.Error("transcript should be truncated")
+	}
+
+	small := &TranscriptRecorder{MaxSize: 10}
+	_, _ = small.Write([]byte(strings.Repeat("x", 10)))
+	if small.Truncated {
+		t.Error("transcript of exactly MaxSize should not be truncated")
+	}
+}
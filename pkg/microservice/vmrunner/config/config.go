@@ -0,0 +1,51 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"github.com/spf13/viper"
+
+	// init the config first
+	_ "github.com/koderover/zadig/pkg/config"
+	"github.com/koderover/zadig/pkg/setting"
+)
+
+func AslanBaseAddr() string {
+	return viper.GetString(setting.AslanBaseAddr)
+}
+
+func Token() string {
+	return viper.GetString(setting.VMRunnerToken)
+}
+
+// Workspace is the dir of the jobs, the workspaces of the jobs are kept for the caches
+func Workspace() string {
+	workspace := viper.GetString(setting.VMRunnerWorkspace)
+	if workspace == "" {
+		return "/var/lib/zadig-vm-runner"
+	}
+	return workspace
+}
+
+// Executor is the path of the jobexecutor binary, it is looked up in the PATH by default
+func Executor() string {
+	executor := viper.GetString(setting.VMRunnerExecutor)
+	if executor == "" {
+		return "jobexecutor"
+	}
+	return executor
+}
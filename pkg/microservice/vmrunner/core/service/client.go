@@ -0,0 +1,69 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/koderover/zadig/pkg/tool/httpclient"
+	"github.com/koderover/zadig/pkg/types"
+)
+
+// client calls the vm runner agent APIs of aslan
+type client struct {
+	*httpclient.Client
+}
+
+func newClient(aslanAddr, token string) *client {
+	return &client{
+		Client: httpclient.New(
+			httpclient.SetHostURL(strings.TrimSuffix(aslanAddr, "/")+"/api/aslan/system/vmrunner/agent"),
+			httpclient.SetClientHeader(types.VMRunnerTokenHeader, token),
+			httpclient.SetRetryCount(3),
+			httpclient.SetRetryWaitTime(time.Second),
+		),
+	}
+}
+
+func (c *client) register(args *types.VMRunnerRegisterArgs) error {
+	_, err := c.Post("/register", httpclient.SetBody(args))
+	return err
+}
+
+// poll returns the job assigned to the runner, nil is returned if there is no job
+func (c *client) poll() (*types.VMJob, error) {
+	resp := &types.VMJobPollResp{}
+	if _, err := c.Post("/job/poll", httpclient.SetResult(resp)); err != nil {
+		return nil, err
+	}
+	return resp.Job, nil
+}
+
+func (c *client) appendLog(jobID string, args *types.VMJobLogArgs) (*types.VMJobLogResp, error) {
+	resp := &types.VMJobLogResp{}
+	if _, err := c.Post(fmt.Sprintf("/job/%s/log", jobID), httpclient.SetBody(args), httpclient.SetResult(resp)); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *client) finish(jobID string, args *types.VMJobResultArgs) error {
+	_, err := c.Post(fmt.Sprintf("/job/%s/result", jobID), httpclient.SetBody(args))
+	return err
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"syscall"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/log"
+	"github.com/koderover/zadig/pkg/types"
+	"github.com/koderover/zadig/pkg/types/job"
+)
+
+// runJob runs the job with the jobexecutor and reports the result to aslan
+func (r *Runner) runJob(ctx context.Context, vmJob *types.VMJob) {
+	log.Infof("start to run vm job %s", vmJob.ID)
+	result := r.execute(ctx, vmJob)
+	if err := r.client.finish(vmJob.ID, result); err != nil {
+		log.Errorf("failed to report the result of vm job %s: %s", vmJob.ID, err)
+		return
+	}
+	log.Infof("vm job %s is finished, result: %s", vmJob.ID, result.Result)
+}
+
+func (r *Runner) execute(ctx context.Context, vmJob *types.VMJob) *types.VMJobResultArgs {
+	jobDir := filepath.Join(r.workspace, "jobs", vmJob.ID)
+	if err := os.MkdirAll(jobDir, os.ModePerm); err != nil {
+		return failedResult(fmt.Errorf("failed to create job dir: %s", err))
+	}
+	defer func() {
+		_ = os.RemoveAll(jobDir)
+	}()
+	// the outputs of the former jobs must not be read by this job
+	if err := os.RemoveAll(job.JobOutputDir); err != nil {
+		return failedResult(fmt.Errorf("failed to clean job outputs: %s", err))
+	}
+
+	jobCtx, err := r.renderJobContext(vmJob.JobCtx)
+	if err != nil {
+		return failedResult(err)
+	}
+	configFile := filepath.Join(jobDir, "job.yaml")
+	if err := os.WriteFile(configFile, jobCtx, 0600); err != nil {
+		return failedResult(fmt.Errorf("failed to write job config file: %s", err))
+	}
+	resultFile := filepath.Join(jobDir, "result.json")
+
+	jobCtxCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	logs := newLogSender(r.client, vmJob.ID, cancel)
+
+	cmd := exec.Command(r.executor)
+	cmd.Dir = jobDir
+	cmd.Env = append(os.Environ(),
+		fmt.Sprintf("%s=%s", setting.JobConfigFile, configFile),
+		fmt.Sprintf("%s=%s", setting.JobResultFile, resultFile),
+	)
+	cmd.Stdout = logs
+	cmd.Stderr = logs
+	// run the executor in its own process group, so the commands of the steps are killed with it
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := cmd.Start(); err != nil {
+		return failedResult(fmt.Errorf("failed to start %s: %s", r.executor, err))
+	}
+
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-jobCtxCtx.Done():
+			log.Infof("vm job %s is cancelled, kill the executor", vmJob.ID)
+			_ = syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		case <-done:
+		}
+	}()
+	go logs.run(done)
+
+	waitErr := cmd.Wait()
+	close(done)
+	logs.flush(true)
+
+	return readResult(resultFile, waitErr)
+}
+
+// renderJobContext runs the job in a workspace of the runner, which is kept for the caches of the following jobs
+func (r *Runner) renderJobContext(jobCtx string) ([]byte, error) {
+	ctxMap := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(jobCtx), &ctxMap); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal job context: %s", err)
+	}
+	ctxMap["workspace"] = filepath.Join(r.workspace, "workspace", fmt.Sprint(ctxMap["workflow_name"]), fmt.Sprint(ctxMap["name"]))
+	return yaml.Marshal(ctxMap)
+}
+
+// readResult reads the result the jobexecutor writes to the data of the ConfigMap in the result file
+func readResult(resultFile string, waitErr error) *types.VMJobResultArgs {
+	cm := struct {
+		Data map[string]string `json:"data"`
+	}{}
+	content, err := os.ReadFile(resultFile)
+	if err == nil {
+		err = json.Unmarshal(content, &cm)
+	}
+	if err != nil || cm.Data[types.JobResultKey] == "" {
+		if waitErr != nil {
+			return failedResult(fmt.Errorf("executor exited without result: %s", waitErr))
+		}
+		return failedResult(fmt.Errorf("failed to read job result: %v", err))
+	}
+
+	result := &types.VMJobResultArgs{
+		Result:  types.JobStatus(cm.Data[types.JobResultKey]),
+		Outputs: cm.Data[types.JobOutputsKey],
+	}
+	if code, err := strconv.Atoi(cm.Data[types.JobExitCodeKey]); err == nil {
+		result.ExitCode = code
+	}
+	return result
+}
+
+func failedResult(err error) *types.VMJobResultArgs {
+	log.Error(err)
+	return &types.VMJobResultArgs{Result: types.JobFail, Error: err.Error()}
+}
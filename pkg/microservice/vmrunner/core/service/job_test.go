@@ -0,0 +1,60 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/koderover/zadig/pkg/types"
+)
+
+func writeResultFile(t *testing.T, content string) string {
+	file := filepath.Join(t.TempDir(), "result")
+	require.NoError(t, os.WriteFile(file, []byte(content), 0644))
+	return file
+}
+
+func TestReadResult(t *testing.T) {
+	file := writeResultFile(t, `{"data":{"job-result":"fail","job-outputs":"{\"a\":\"b\"}","job-exit-code":"2"}}`)
+	result := readResult(file, errors.New("exit status 1"))
+	require.Equal(t, types.JobFail, result.Result)
+	require.Equal(t, `{"a":"b"}`, result.Outputs)
+	require.Equal(t, 2, result.ExitCode)
+	require.Empty(t, result.Error)
+
+	result = readResult(writeResultFile(t, `{"data":{"job-result":"success"}}`), nil)
+	require.Equal(t, types.JobSuccess, result.Result)
+	require.Equal(t, 0, result.ExitCode)
+}
+
+func TestReadResultWithoutResult(t *testing.T) {
+	result := readResult(filepath.Join(t.TempDir(), "missing"), errors.New("signal: killed"))
+	require.Equal(t, types.JobFail, result.Result)
+	require.Contains(t, result.Error, "signal: killed")
+
+	result = readResult(writeResultFile(t, `not json`), nil)
+	require.Equal(t, types.JobFail, result.Result)
+	require.Contains(t, result.Error, "failed to read job result")
+
+	result = readResult(writeResultFile(t, `{"data":{}}`), nil)
+	require.Equal(t, types.JobFail, result.Result)
+}
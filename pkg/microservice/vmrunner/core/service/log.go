@@ -0,0 +1,120 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/koderover/zadig/pkg/tool/log"
+	"github.com/koderover/zadig/pkg/types"
+)
+
+const (
+	logInterval = 2 * time.Second
+	// maxLogChunkSize is the max size of the log sent at a time
+	maxLogChunkSize = 512 * 1024
+)
+
+// logSender buffers the output of the executor and sends it to aslan by lines periodically,
+// the job is cancelled if aslan replies it is cancelled or timed out
+type logSender struct {
+	client *client
+	jobID  string
+	cancel context.CancelFunc
+
+	// flushMu makes the flushes of the ticker and of the end of the job send the chunks in order
+	flushMu sync.Mutex
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	seq     int64
+}
+
+func newLogSender(client *client, jobID string, cancel context.CancelFunc) *logSender {
+	return &logSender{client: client, jobID: jobID, cancel: cancel}
+}
+
+func (s *logSender) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.buf.Write(p)
+}
+
+// run sends the log until done is closed, the log is sent even if it is empty to check the job is not cancelled
+func (s *logSender) run(done <-chan struct{}) {
+	ticker := time.NewTicker(logInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			s.flush(false)
+		}
+	}
+}
+
+// flush sends the complete lines in the buffer, the last incomplete line is sent too if all is true.
+// the chunk is removed from the buffer only after it is sent, it is sent again with the same seq on the next flush
+// if it failed, and aslan overwrites the chunk of the seq.
+func (s *logSender) flush(all bool) {
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+
+	for {
+		s.mu.Lock()
+		content := s.buf.Bytes()
+		if !all {
+			if i := bytes.LastIndexByte(content, '\n'); i >= 0 {
+				content = content[:i+1]
+			} else if len(content) < maxLogChunkSize {
+				content = nil
+			}
+		}
+		if len(content) > maxLogChunkSize {
+			content = content[:maxLogChunkSize]
+		}
+		chunk := string(content)
+		seq := s.seq
+		s.mu.Unlock()
+
+		resp, err := s.client.appendLog(s.jobID, &types.VMJobLogArgs{Seq: seq, Content: chunk})
+		if err != nil {
+			log.Errorf("failed to send the log of vm job %s: %s", s.jobID, err)
+			return
+		}
+
+		// the buffer is only appended by Write, so the chunk is still at the front of it
+		s.mu.Lock()
+		s.buf.Next(len(chunk))
+		if chunk != "" {
+			s.seq++
+		}
+		remaining := s.buf.Len()
+		s.mu.Unlock()
+
+		if resp.Cancelled {
+			s.cancel()
+		}
+		if !all || remaining == 0 {
+			return
+		}
+	}
+}
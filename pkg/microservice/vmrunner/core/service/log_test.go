@@ -0,0 +1,135 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/koderover/zadig/pkg/tool/httpclient"
+	"github.com/koderover/zadig/pkg/tool/log"
+	"github.com/koderover/zadig/pkg/types"
+)
+
+func init() {
+	log.Init(&log.Config{Level: "error"})
+}
+
+// fakeAslan records the log chunks the runner sends, the requests fail while failing is true
+type fakeAslan struct {
+	mu        sync.Mutex
+	chunks    map[int64]string
+	failing   bool
+	cancelled bool
+}
+
+func (f *fakeAslan) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.failing {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	args := &types.VMJobLogArgs{}
+	if err := json.NewDecoder(r.Body).Decode(args); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	f.chunks[args.Seq] = args.Content
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(&types.VMJobLogResp{Cancelled: f.cancelled})
+}
+
+func (f *fakeAslan) log() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	content := ""
+	for i := int64(0); i < int64(len(f.chunks)); i++ {
+		content += f.chunks[i]
+	}
+	return content
+}
+
+func newTestLogSender(t *testing.T, aslan *fakeAslan, cancel func()) *logSender {
+	srv := httptest.NewServer(aslan)
+	t.Cleanup(srv.Close)
+	c := &client{Client: httpclient.New(httpclient.SetHostURL(srv.URL))}
+	return newLogSender(c, "job", cancel)
+}
+
+func TestLogSenderSendsCompleteLines(t *testing.T) {
+	aslan := &fakeAslan{chunks: map[int64]string{}}
+	s := newTestLogSender(t, aslan, func() {})
+
+	_, err := s.Write([]byte("line 1\nline 2\npartial"))
+	require.NoError(t, err)
+	s.flush(false)
+	require.Equal(t, "line 1\nline 2\n", aslan.log())
+
+	_, err = s.Write([]byte(" line 3\n"))
+	require.NoError(t, err)
+	s.flush(false)
+	require.Equal(t, "line 1\nline 2\npartial line 3\n", aslan.log())
+	require.Len(t, aslan.chunks, 2)
+}
+
+func TestLogSenderKeepsLogWhenSendFails(t *testing.T) {
+	aslan := &fakeAslan{chunks: map[int64]string{}, failing: true}
+	s := newTestLogSender(t, aslan, func() {})
+
+	_, err := s.Write([]byte("line 1\n"))
+	require.NoError(t, err)
+	s.flush(false)
+	require.Empty(t, aslan.log())
+
+	aslan.failing = false
+	_, err = s.Write([]byte("line 2\nlast"))
+	require.NoError(t, err)
+	s.flush(true)
+	require.Equal(t, "line 1\nline 2\nlast", aslan.log())
+	require.Equal(t, int64(1), s.seq)
+}
+
+func TestLogSenderSplitsLargeLog(t *testing.T) {
+	aslan := &fakeAslan{chunks: map[int64]string{}}
+	s := newTestLogSender(t, aslan, func() {})
+
+	content := strings.Repeat("a", maxLogChunkSize) + strings.Repeat("b", 10)
+	_, err := s.Write([]byte(content))
+	require.NoError(t, err)
+	s.flush(true)
+	require.Len(t, aslan.chunks, 2)
+	require.Equal(t, content, aslan.log())
+}
+
+func TestLogSenderCancelsJob(t *testing.T) {
+	aslan := &fakeAslan{chunks: map[int64]string{}, cancelled: true}
+	cancelled := false
+	s := newTestLogSender(t, aslan, func() { cancelled = true })
+
+	s.flush(false)
+	require.True(t, cancelled)
+	require.Equal(t, int64(0), s.seq)
+}
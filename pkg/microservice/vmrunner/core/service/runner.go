@@ -0,0 +1,104 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/koderover/zadig/pkg/tool/log"
+	"github.com/koderover/zadig/pkg/types"
+)
+
+const (
+	AgentVersion = "1.0.0"
+
+	pollInterval = 5 * time.Second
+)
+
+// Runner polls the jobs matching its labels from aslan and runs them on the host one by one
+type Runner struct {
+	client    *client
+	workspace string
+	executor  string
+}
+
+func NewRunner(aslanAddr, token, workspace, executor string) *Runner {
+	return &Runner{
+		client:    newClient(aslanAddr, token),
+		workspace: workspace,
+		executor:  executor,
+	}
+}
+
+// Run registers the runner and runs the polled jobs until the context is done, polling works as the heartbeat
+func (r *Runner) Run(ctx context.Context) error {
+	if err := r.client.register(getHostInfo()); err != nil {
+		return fmt.Errorf("failed to register the runner: %s", err)
+	}
+	log.Infof("vm runner is registered, workspace: %s, executor: %s", r.workspace, r.executor)
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info("vm runner is stopped")
+			return nil
+		case <-ticker.C:
+		}
+
+		vmJob, err := r.client.poll()
+		if err != nil {
+			log.Errorf("failed to poll job: %s", err)
+			continue
+		}
+		if vmJob == nil {
+			continue
+		}
+		r.runJob(ctx, vmJob)
+	}
+}
+
+func getHostInfo() *types.VMRunnerRegisterArgs {
+	hostname, _ := os.Hostname()
+	return &types.VMRunnerRegisterArgs{
+		Hostname: hostname,
+		IP:       getHostIP(),
+		OS:       runtime.GOOS,
+		Arch:     runtime.GOARCH,
+		Version:  AgentVersion,
+	}
+}
+
+// getHostIP returns the first non-loopback ipv4 address of the host
+func getHostIP() string {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return ""
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLoopback() && ipNet.IP.To4() != nil {
+			return ipNet.IP.String()
+		}
+	}
+	return ""
+}
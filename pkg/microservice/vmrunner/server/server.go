@@ -0,0 +1,45 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/koderover/zadig/pkg/config"
+	runnerconfig "github.com/koderover/zadig/pkg/microservice/vmrunner/config"
+	"github.com/koderover/zadig/pkg/microservice/vmrunner/core/service"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/log"
+)
+
+func Serve(ctx context.Context) error {
+	log.Init(&log.Config{
+		Level:       config.LogLevel(),
+		Filename:    config.LogFile(),
+		SendToFile:  config.SendLogToFile(),
+		Development: config.Mode() != setting.ReleaseMode,
+	})
+
+	if runnerconfig.AslanBaseAddr() == "" || runnerconfig.Token() == "" {
+		return fmt.Errorf("%s and %s are required", setting.AslanBaseAddr, setting.VMRunnerToken)
+	}
+
+	log.Infof("Starting vm runner %s", service.AgentVersion)
+	runner := service.NewRunner(runnerconfig.AslanBaseAddr(), runnerconfig.Token(), runnerconfig.Workspace(), runnerconfig.Executor())
+	return runner.Run(ctx)
+}
@@ -94,12 +94,18 @@ const (
 	Home            = "HOME"
 	PkgFile         = "PKG_FILE"
 	JobConfigFile   = "JOB_CONFIG_FILE"
+	JobResultFile   = "JOB_RESULT_FILE"
 	DockerAuthDir   = "DOCKER_AUTH_DIR"
 	Path            = "PATH"
 	DockerHost      = "DOCKER_HOST"
 	BuildURL        = "BUILD_URL"
 	DefaultDockSock = "/var/run/docker.sock"
 
+	// vm runner
+	VMRunnerToken     = "VM_RUNNER_TOKEN"
+	VMRunnerWorkspace = "VM_RUNNER_WORKSPACE"
+	VMRunnerExecutor  = "VM_RUNNER_EXECUTOR"
+
 	// jenkins
 	JenkinsBuildImage = "JENKINS_BUILD_IMAGE"
 
@@ -73,7 +73,7 @@ func (s *MemCache) Store(key string, data string) error {
 	if _, ok := s.items[key]; !ok {
 		s.items[key] = &item{
 			value:   data,
-			expires: s.expires,
+			expires: time.Now().UnixNano() + s.expires,
 		}
 	}
 	s.mu.Unlock()
@@ -102,11 +102,9 @@ func (s *MemCache) List() ([]string, error) {
 
 func (s *MemCache) Exists(key string) bool {
 	s.mu.Lock()
-	if _, ok := s.items[key]; ok {
-		return true
-	}
-	s.mu.Unlock()
-	return false
+	defer s.mu.Unlock()
+	_, ok := s.items[key]
+	return ok
 }
 
 func (s *MemCache) IsCacheDisabled() bool {
@@ -0,0 +1,252 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cosign
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// fulcio extensions of the oidc issuer, the first one is deprecated but still set by fulcio
+	oidIssuerV1 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 1}
+	oidIssuerV2 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 8}
+)
+
+// KeyVerifier verifies the signatures created with `cosign sign --key`
+type KeyVerifier struct {
+	name      string
+	publicKey crypto.PublicKey
+}
+
+func NewKeyVerifier(name, publicKeyPEM string) (*KeyVerifier, error) {
+	publicKey, _, err := parsePublicKey(publicKeyPEM)
+	if err != nil {
+		return nil, err
+	}
+	return &KeyVerifier{name: name, publicKey: publicKey}, nil
+}
+
+func (v *KeyVerifier) Name() string {
+	return v.name
+}
+
+func (v *KeyVerifier) Verify(payload, signature []byte, _ *x509.Certificate, _ []*x509.Certificate, _ *Bundle) error {
+	return verifySignature(v.publicKey, payload, signature)
+}
+
+// CertificateVerifier verifies the keyless signatures, whose signing certificates are issued by fulcio to the
+// identity of the oidc token. The certificates are short-lived, so the signature must be recorded in rekor while the
+// certificate is valid, which is proved by the signed entry timestamp of the rekor bundle attached to the signature.
+type CertificateVerifier struct {
+	name       string
+	roots      *x509.CertPool
+	rekorKey   crypto.PublicKey
+	rekorLogID string
+	identity   string
+	issuer     string
+}
+
+// NewCertificateVerifier creates a verifier trusting the certificates issued by the root certificates to the identity,
+// which is the email or the uri in the subject alternative names, and the oidc issuer. The signatures must be recorded
+// in the transparency log signed by the rekor public key.
+func NewCertificateVerifier(name, rootsPEM, rekorPublicKeyPEM, identity, issuer string) (*CertificateVerifier, error) {
+	roots, err := ParseCertificates([]byte(rootsPEM))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse root certificates: %s", err)
+	}
+	if len(roots) == 0 {
+		return nil, errors.New("root certificate not found")
+	}
+	rekorKey, rekorKeyDER, err := parsePublicKey(rekorPublicKeyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rekor public key: %s", err)
+	}
+	if identity == "" || issuer == "" {
+		return nil, errors.New("identity and issuer are required")
+	}
+	pool := x509.NewCertPool()
+	for _, root := range roots {
+		pool.AddCert(root)
+	}
+	// the log id of rekor is the sha256 of its public key
+	logID := sha256.Sum256(rekorKeyDER)
+	return &CertificateVerifier{
+		name:       name,
+		roots:      pool,
+		rekorKey:   rekorKey,
+		rekorLogID: hex.EncodeToString(logID[:]),
+		identity:   identity,
+		issuer:     issuer,
+	}, nil
+}
+
+func (v *CertificateVerifier) Name() string {
+	return v.name
+}
+
+func (v *CertificateVerifier) Verify(payload, signature []byte, cert *x509.Certificate, chain []*x509.Certificate, bundle *Bundle) error {
+	if cert == nil {
+		return errors.New("signing certificate not found")
+	}
+	// anyone holding a leaked key of an expired certificate can sign, the signing time recorded by rekor must be in
+	// the validity period of the certificate
+	if bundle == nil {
+		return errors.New("rekor bundle not found")
+	}
+	integratedTime, err := v.verifyBundle(bundle, payload, signature, cert)
+	if err != nil {
+		return fmt.Errorf("invalid rekor bundle: %s", err)
+	}
+	if integratedTime.Before(cert.NotBefore) || integratedTime.After(cert.NotAfter) {
+		return fmt.Errorf("signature is recorded at %s, out of the validity period of the signing certificate", integratedTime)
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, c := range chain {
+		intermediates.AddCert(c)
+	}
+	if _, err := cert.Verify(x509.VerifyOptions{
+		Roots:         v.roots,
+		Intermediates: intermediates,
+		CurrentTime:   integratedTime,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	}); err != nil {
+		return fmt.Errorf("untrusted signing certificate: %s", err)
+	}
+	if !v.matchIdentity(cert) {
+		return fmt.Errorf("signing certificate is not issued to %s", v.identity)
+	}
+	if issuer := certificateIssuer(cert); issuer != v.issuer {
+		return fmt.Errorf("signing certificate is issued by %s instead of %s", issuer, v.issuer)
+	}
+	return verifySignature(cert.PublicKey, payload, signature)
+}
+
+// verifyBundle checks the signed entry timestamp of the bundle, and that the entry is the hashedrekord of the signature,
+// the time the entry is integrated into the log is returned
+func (v *CertificateVerifier) verifyBundle(bundle *Bundle, payload, signature []byte, cert *x509.Certificate) (time.Time, error) {
+	if bundle.Payload.LogID != v.rekorLogID {
+		return time.Time{}, fmt.Errorf("entry is recorded in the unknown log %s", bundle.Payload.LogID)
+	}
+	// the fields of the payload are in the order of the canonical json
+	canonical, err := json.Marshal(bundle.Payload)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if err := verifySignature(v.rekorKey, canonical, bundle.SignedEntryTimestamp); err != nil {
+		return time.Time{}, fmt.Errorf("invalid signed entry timestamp: %s", err)
+	}
+
+	body, err := base64.StdEncoding.DecodeString(bundle.Payload.Body)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid entry body: %s", err)
+	}
+	entry := &hashedRekord{}
+	if err := json.Unmarshal(body, entry); err != nil {
+		return time.Time{}, fmt.Errorf("invalid entry body: %s", err)
+	}
+	if entry.Kind != hashedRekordKind {
+		return time.Time{}, fmt.Errorf("unsupported entry kind %s", entry.Kind)
+	}
+	hashed := sha256.Sum256(payload)
+	if entry.Spec.Data.Hash.Algorithm != "sha256" || entry.Spec.Data.Hash.Value != hex.EncodeToString(hashed[:]) {
+		return time.Time{}, errors.New("entry is not recorded for the payload")
+	}
+	if !bytes.Equal(entry.Spec.Signature.Content, signature) {
+		return time.Time{}, errors.New("entry is not recorded for the signature")
+	}
+	entryCerts, err := ParseCertificates(entry.Spec.Signature.PublicKey.Content)
+	if err != nil || len(entryCerts) == 0 || !entryCerts[0].Equal(cert) {
+		return time.Time{}, errors.New("entry is not recorded for the signing certificate")
+	}
+	return time.Unix(bundle.Payload.IntegratedTime, 0), nil
+}
+
+func (v *CertificateVerifier) matchIdentity(cert *x509.Certificate) bool {
+	for _, email := range cert.EmailAddresses {
+		if email == v.identity {
+			return true
+		}
+	}
+	for _, uri := range cert.URIs {
+		if uri.String() == v.identity {
+			return true
+		}
+	}
+	return false
+}
+
+func certificateIssuer(cert *x509.Certificate) string {
+	for _, ext := range cert.Extensions {
+		switch {
+		case ext.Id.Equal(oidIssuerV2):
+			var issuer string
+			if _, err := asn1.Unmarshal(ext.Value, &issuer); err == nil {
+				return issuer
+			}
+		case ext.Id.Equal(oidIssuerV1):
+			return string(ext.Value)
+		}
+	}
+	return ""
+}
+
+func parsePublicKey(publicKeyPEM string) (crypto.PublicKey, []byte, error) {
+	block, _ := pem.Decode([]byte(publicKeyPEM))
+	if block == nil {
+		return nil, nil, errors.New("invalid public key pem")
+	}
+	publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse public key: %s", err)
+	}
+	return publicKey, block.Bytes, nil
+}
+
+func verifySignature(publicKey crypto.PublicKey, payload, signature []byte) error {
+	hashed := sha256.Sum256(payload)
+	switch key := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(key, hashed[:], signature) {
+			return errors.New("invalid ecdsa signature")
+		}
+		return nil
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, hashed[:], signature)
+	case ed25519.PublicKey:
+		if !ed25519.Verify(key, payload, signature) {
+			return errors.New("invalid ed25519 signature")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported public key type %T", publicKey)
+	}
+}
@@ -0,0 +1,177 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cosign
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+var testPayload = []byte(`{"critical":{"identity":{"docker-reference":"koderover.tencentcloudcr.com/test/app"},"image":{"docker-manifest-digest":"sha256:0000"},"type":"cosign container image signature"},"optional":null}`)
+
+func sign(t *testing.T, key *ecdsa.PrivateKey, payload []byte) []byte {
+	hashed := sha256.Sum256(payload)
+	signature, err := ecdsa.SignASN1(rand.Reader, key, hashed[:])
+	require.NoError(t, err)
+	return signature
+}
+
+func TestKeyVerifier(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	verifier, err := NewKeyVerifier("release", string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})))
+	require.NoError(t, err)
+
+	signature := sign(t, key, testPayload)
+	require.NoError(t, verifier.Verify(testPayload, signature, nil, nil, nil))
+	require.Error(t, verifier.Verify(append(testPayload, ' '), signature, nil, nil, nil))
+
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	require.Error(t, verifier.Verify(testPayload, sign(t, otherKey, testPayload), nil, nil, nil))
+}
+
+// newBundle records the signature in a fake rekor signed by the key
+func newBundle(t *testing.T, rekorKey *ecdsa.PrivateKey, payload, signature []byte, cert *x509.Certificate, integratedTime time.Time) *Bundle {
+	hashed := sha256.Sum256(payload)
+	entry := &hashedRekord{Kind: hashedRekordKind}
+	entry.Spec.Data.Hash.Algorithm = "sha256"
+	entry.Spec.Data.Hash.Value = hex.EncodeToString(hashed[:])
+	entry.Spec.Signature.Content = signature
+	entry.Spec.Signature.PublicKey.Content = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	body, err := json.Marshal(entry)
+	require.NoError(t, err)
+
+	der, err := x509.MarshalPKIXPublicKey(&rekorKey.PublicKey)
+	require.NoError(t, err)
+	logID := sha256.Sum256(der)
+	bundle := &Bundle{Payload: BundlePayload{
+		Body:           base64.StdEncoding.EncodeToString(body),
+		IntegratedTime: integratedTime.Unix(),
+		LogID:          hex.EncodeToString(logID[:]),
+		LogIndex:       1,
+	}}
+	canonical, err := json.Marshal(bundle.Payload)
+	require.NoError(t, err)
+	bundle.SignedEntryTimestamp = sign(t, rekorKey, canonical)
+	return bundle
+}
+
+func TestCertificateVerifier(t *testing.T) {
+	rootKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	rootTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "fulcio"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	rootDER, err := x509.CreateCertificate(rand.Reader, rootTemplate, rootTemplate, &rootKey.PublicKey, rootKey)
+	require.NoError(t, err)
+	root, err := x509.ParseCertificate(rootDER)
+	require.NoError(t, err)
+
+	issueCert := func(email, issuer string) (*x509.Certificate, *ecdsa.PrivateKey) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		issuerValue, err := asn1.Marshal(issuer)
+		require.NoError(t, err)
+		template := &x509.Certificate{
+			SerialNumber:    big.NewInt(2),
+			NotBefore:       time.Now().Add(-20 * time.Minute),
+			NotAfter:        time.Now().Add(-10 * time.Minute),
+			EmailAddresses:  []string{email},
+			KeyUsage:        x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+			ExtraExtensions: []pkix.Extension{{Id: oidIssuerV2, Value: issuerValue}},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, root, &key.PublicKey, rootKey)
+		require.NoError(t, err)
+		cert, err := x509.ParseCertificate(der)
+		require.NoError(t, err)
+		return cert, key
+	}
+
+	rekorKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	rekorDER, err := x509.MarshalPKIXPublicKey(&rekorKey.PublicKey)
+	require.NoError(t, err)
+	rootPEM := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: rootDER}))
+	rekorPEM := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: rekorDER}))
+	verifier, err := NewCertificateVerifier("keyless", rootPEM, rekorPEM, "ci@koderover.com", "https://dex.koderover.com")
+	require.NoError(t, err)
+	_, err = NewCertificateVerifier("keyless", rootPEM, "", "ci@koderover.com", "https://dex.koderover.com")
+	require.Error(t, err)
+
+	// the short-lived certificate has expired, it is verified at the time the signature is recorded in rekor
+	cert, key := issueCert("ci@koderover.com", "https://dex.koderover.com")
+	signature := sign(t, key, testPayload)
+	signedAt := cert.NotBefore.Add(time.Minute)
+	require.NoError(t, verifier.Verify(testPayload, signature, cert, nil, newBundle(t, rekorKey, testPayload, signature, cert, signedAt)))
+	require.Error(t, verifier.Verify(testPayload, signature, nil, nil, newBundle(t, rekorKey, testPayload, signature, cert, signedAt)))
+
+	// the signature created with the leaked key after the certificate expired
+	require.Error(t, verifier.Verify(testPayload, signature, cert, nil, nil))
+	require.Error(t, verifier.Verify(testPayload, signature, cert, nil, newBundle(t, rekorKey, testPayload, signature, cert, time.Now())))
+
+	// the bundle must be signed by the trusted rekor and recorded for the signature
+	otherRekorKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	require.Error(t, verifier.Verify(testPayload, signature, cert, nil, newBundle(t, otherRekorKey, testPayload, signature, cert, signedAt)))
+	bundle := newBundle(t, rekorKey, testPayload, signature, cert, signedAt)
+	bundle.Payload.IntegratedTime = time.Now().Unix()
+	require.Error(t, verifier.Verify(testPayload, signature, cert, nil, bundle))
+	otherSignature := sign(t, key, testPayload)
+	require.Error(t, verifier.Verify(testPayload, otherSignature, cert, nil, newBundle(t, rekorKey, testPayload, signature, cert, signedAt)))
+	otherCert, _ := issueCert("ci@koderover.com", "https://dex.koderover.com")
+	require.Error(t, verifier.Verify(testPayload, signature, cert, nil, newBundle(t, rekorKey, testPayload, signature, otherCert, signedAt)))
+
+	cert, key = issueCert("dev@koderover.com", "https://dex.koderover.com")
+	signature = sign(t, key, testPayload)
+	require.Error(t, verifier.Verify(testPayload, signature, cert, nil, newBundle(t, rekorKey, testPayload, signature, cert, signedAt)))
+
+	cert, key = issueCert("ci@koderover.com", "https://accounts.google.com")
+	signature = sign(t, key, testPayload)
+	require.Error(t, verifier.Verify(testPayload, signature, cert, nil, newBundle(t, rekorKey, testPayload, signature, cert, signedAt)))
+}
+
+func TestPinDigest(t *testing.T) {
+	digest := "sha256:0000"
+	require.Equal(t, "koderover.tencentcloudcr.com/test/app:v1@sha256:0000", PinDigest("koderover.tencentcloudcr.com/test/app:v1", digest))
+	require.Equal(t, "localhost:5000/test/app:v1@sha256:0000", PinDigest("localhost:5000/test/app:v1", digest))
+	require.Equal(t, "localhost:5000/test/app@sha256:0000", PinDigest("localhost:5000/test/app", digest))
+	require.Equal(t, "test/app:v1@sha256:0000", PinDigest("test/app:v1@sha256:1111", digest))
+}
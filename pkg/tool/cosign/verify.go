@@ -0,0 +1,259 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package cosign verifies the image signatures created by cosign, which are stored in the registry as OCI artifacts
+// tagged with sha256-<digest>.sig next to the signed image.
+package cosign
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/opencontainers/go-digest"
+	"github.com/regclient/regclient"
+	"github.com/regclient/regclient/config"
+	"github.com/regclient/regclient/types/manifest"
+	"github.com/regclient/regclient/types/ref"
+)
+
+const (
+	SimpleSigningMediaType = "application/vnd.dev.cosign.simplesigning.v1+json"
+	SignatureAnnotation    = "dev.cosignproject.cosign/signature"
+	CertificateAnnotation  = "dev.sigstore.cosign/certificate"
+	ChainAnnotation        = "dev.sigstore.cosign/chain"
+	BundleAnnotation       = "dev.sigstore.cosign/bundle"
+
+	signatureTagSuffix = ".sig"
+	// a simple signing payload is a small json document
+	maxPayloadSize = 1 << 20
+)
+
+var (
+	ErrUnsigned  = errors.New("no signature found")
+	ErrUntrusted = errors.New("no signature is signed by a trusted key")
+)
+
+// Verifier verifies a signature of the payload, cert is the signing certificate attached to the signature
+// by keyless signing, it is nil for signatures created with a key. bundle is the rekor entry of the signature,
+// it is nil if the signature is not uploaded to the transparency log.
+type Verifier interface {
+	Name() string
+	Verify(payload, signature []byte, cert *x509.Certificate, chain []*x509.Certificate, bundle *Bundle) error
+}
+
+// Bundle is the rekor entry attached to the signature by cosign, SignedEntryTimestamp is signed by rekor over the
+// canonical json of Payload
+type Bundle struct {
+	SignedEntryTimestamp []byte        `json:"SignedEntryTimestamp"`
+	Payload              BundlePayload `json:"Payload"`
+}
+
+// BundlePayload is marshaled as the canonical json, so the fields are sorted by the json keys
+type BundlePayload struct {
+	Body           string `json:"body"`
+	IntegratedTime int64  `json:"integratedTime"`
+	LogID          string `json:"logID"`
+	LogIndex       int64  `json:"logIndex"`
+}
+
+const hashedRekordKind = "hashedrekord"
+
+// hashedRekord is the rekor entry of a signature, which is the body of the bundle payload
+type hashedRekord struct {
+	Kind string `json:"kind"`
+	Spec struct {
+		Data struct {
+			Hash struct {
+				Algorithm string `json:"algorithm"`
+				Value     string `json:"value"`
+			} `json:"hash"`
+		} `json:"data"`
+		Signature struct {
+			Content   []byte `json:"content"`
+			PublicKey struct {
+				Content []byte `json:"content"`
+			} `json:"publicKey"`
+		} `json:"signature"`
+	} `json:"spec"`
+}
+
+type Client struct {
+	rc *regclient.RegClient
+}
+
+// NewClient creates a client to read the signatures, hosts provides the credentials of the registries
+func NewClient(hosts []config.Host) *Client {
+	return &Client{rc: regclient.New(regclient.WithConfigHosts(hosts))}
+}
+
+type simpleSigningPayload struct {
+	Critical struct {
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+		Type string `json:"type"`
+	} `json:"critical"`
+}
+
+// VerifyImage checks that the image has a signature verified by any of the verifiers, the name of the
+// verifier and the verified digest of the image are returned. The tag of the image may be moved after it is
+// verified, so the image should be pulled by the digest.
+func (c *Client) VerifyImage(ctx context.Context, image string, verifiers []Verifier) (string, string, error) {
+	imageRef, err := ref.New(image)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to parse image %s: %s", image, err)
+	}
+	defer c.rc.Close(ctx, imageRef)
+
+	imageDigest := imageRef.Digest
+	if imageDigest == "" {
+		m, err := c.rc.ManifestHead(ctx, imageRef, regclient.WithManifestRequireDigest())
+		if err != nil {
+			return "", "", fmt.Errorf("failed to get manifest of image %s: %s", image, err)
+		}
+		imageDigest = m.GetDescriptor().Digest.String()
+	}
+
+	sigRef := imageRef
+	sigRef.Digest = ""
+	sigRef.Tag = strings.Replace(imageDigest, ":", "-", 1) + signatureTagSuffix
+	sigManifest, err := c.rc.ManifestGet(ctx, sigRef)
+	if err != nil {
+		return "", "", fmt.Errorf("%w for image %s: %s", ErrUnsigned, image, err)
+	}
+	imager, ok := sigManifest.(manifest.Imager)
+	if !ok {
+		return "", "", fmt.Errorf("%w for image %s: unexpected media type %s", ErrUnsigned, image, sigManifest.GetDescriptor().MediaType)
+	}
+	layers, err := imager.GetLayers()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get signatures of image %s: %s", image, err)
+	}
+
+	found := false
+	for _, layer := range layers {
+		if layer.MediaType != SimpleSigningMediaType || layer.Annotations[SignatureAnnotation] == "" {
+			continue
+		}
+		found = true
+		signature, err := base64.StdEncoding.DecodeString(layer.Annotations[SignatureAnnotation])
+		if err != nil {
+			continue
+		}
+		cert, chain, err := parseCertificates(layer.Annotations[CertificateAnnotation], layer.Annotations[ChainAnnotation])
+		if err != nil {
+			continue
+		}
+		bundle, err := parseBundle(layer.Annotations[BundleAnnotation])
+		if err != nil {
+			continue
+		}
+
+		blob, err := c.rc.BlobGet(ctx, sigRef, layer)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to get signature payload of image %s: %s", image, err)
+		}
+		payload, err := io.ReadAll(io.LimitReader(blob, maxPayloadSize))
+		blob.Close()
+		if err != nil {
+			return "", "", fmt.Errorf("failed to read signature payload of image %s: %s", image, err)
+		}
+		if digest.FromBytes(payload) != layer.Digest {
+			continue
+		}
+		simpleSigning := &simpleSigningPayload{}
+		if err := json.Unmarshal(payload, simpleSigning); err != nil {
+			continue
+		}
+		// the signature of another image can be copied, the payload must claim the digest of this image
+		if simpleSigning.Critical.Image.DockerManifestDigest != imageDigest {
+			continue
+		}
+
+		for _, verifier := range verifiers {
+			if err := verifier.Verify(payload, signature, cert, chain, bundle); err == nil {
+				return verifier.Name(), imageDigest, nil
+			}
+		}
+	}
+	if !found {
+		return "", "", fmt.Errorf("%w for image %s", ErrUnsigned, image)
+	}
+	return "", "", fmt.Errorf("%w for image %s", ErrUntrusted, image)
+}
+
+func parseCertificates(certPEM, chainPEM string) (*x509.Certificate, []*x509.Certificate, error) {
+	if certPEM == "" {
+		return nil, nil, nil
+	}
+	certs, err := ParseCertificates([]byte(certPEM))
+	if err != nil || len(certs) == 0 {
+		return nil, nil, fmt.Errorf("invalid signing certificate")
+	}
+	chain, err := ParseCertificates([]byte(chainPEM))
+	if err != nil {
+		return nil, nil, err
+	}
+	return certs[0], chain, nil
+}
+
+func parseBundle(content string) (*Bundle, error) {
+	if content == "" {
+		return nil, nil
+	}
+	bundle := &Bundle{}
+	if err := json.Unmarshal([]byte(content), bundle); err != nil {
+		return nil, fmt.Errorf("invalid rekor bundle: %s", err)
+	}
+	return bundle, nil
+}
+
+// ParseCertificates parses all the certificates in the pem content
+func ParseCertificates(content []byte) ([]*x509.Certificate, error) {
+	certs := make([]*x509.Certificate, 0)
+	for {
+		var block *pem.Block
+		block, content = pem.Decode(content)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	return certs, nil
+}
+
+// PinDigest replaces the digest of the image with the digest, the tag is kept for the image parsers and the display,
+// it is ignored by the container runtimes if the digest is set
+func PinDigest(image, imageDigest string) string {
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+	return image + "@" + imageDigest
+}
@@ -205,3 +205,15 @@ func (c *Client) GetApprovalInstance(id string) (resp *ApprovalInstanceInfo, err
 		Get("https://api.dingtalk.com/v1.0/workflow/processInstances")
 	return
 }
+
+// TerminateApprovalInstance terminates the running approval instance as the system
+func (c *Client) TerminateApprovalInstance(id, remark string) (err error) {
+	_, err = c.R().
+		SetBodyJsonMarshal(map[string]interface{}{
+			"processInstanceId": id,
+			"isSystem":          true,
+			"remark":            remark,
+		}).
+		Post("https://api.dingtalk.com/v1.0/workflow/processInstances/terminate")
+	return
+}
@@ -0,0 +1,51 @@
+/*
+ * Copyright 2023 The KodeRover Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dingtalk
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// maxMessageUsers is the max num of users in one batch send request
+const maxMessageUsers = 20
+
+// SendTextMessage sends the text to the users by the robot of the app,
+// the robot code of an internal app is the app key.
+func (c *Client) SendTextMessage(userIDs []string, text string) error {
+	param, err := json.Marshal(map[string]string{"content": text})
+	if err != nil {
+		return errors.Wrap(err, "marshal message")
+	}
+	for start := 0; start < len(userIDs); start += maxMessageUsers {
+		end := start + maxMessageUsers
+		if end > len(userIDs) {
+			end = len(userIDs)
+		}
+		_, err := c.R().SetBodyJsonMarshal(map[string]interface{}{
+			"robotCode": c.AppKey,
+			"userIds":   userIDs[start:end],
+			"msgKey":    "sampleText",
+			"msgParam":  string(param),
+		}).Post("https://api.dingtalk.com/v1.0/robot/oToMessages/batchSend")
+		if err != nil {
+			return errors.Wrap(err, "send message")
+		}
+	}
+	return nil
+}
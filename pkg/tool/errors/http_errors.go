@@ -313,6 +313,12 @@ var (
 
 	// ErrGetDebugShell
 	ErrGetDebugShell = NewHTTPError(6172, "获取调试 Shell 失败")
+
+	// ErrListDebugSession
+	ErrListDebugSession = NewHTTPError(6173, "获取调试记录列表失败")
+
+	// ErrGetDebugSession
+	ErrGetDebugSession = NewHTTPError(6174, "获取调试记录失败")
 	//-----------------------------------------------------------------------------------------------
 	// Keystore APIs Range: 6180 - 6189
 	//-----------------------------------------------------------------------------------------------
@@ -868,6 +874,7 @@ var (
 	ErrUpdateLLMIntegration = NewHTTPError(7012, "更新llm集成失败")
 	ErrDeleteLLMIntegration = NewHTTPError(7013, "删除llm集成失败")
 	ErrGetLLMIntegration    = NewHTTPError(7014, "获取llm集成详情失败")
+	ErrDiagnoseJobFailure   = NewHTTPError(7015, "AI任务失败诊断失败")
 
 	//-----------------------------------------------------------------------------------------------
 	// observability integration Error Range: 7020 - 7029
@@ -877,4 +884,64 @@ var (
 	ErrUpdateObservabilityIntegration = NewHTTPError(7022, "更新 观测工具 集成失败")
 	ErrDeleteObservabilityIntegration = NewHTTPError(7023, "删除 观测工具 集成失败")
 	ErrGetObservabilityIntegration    = NewHTTPError(7024, "获取 观测工具 集成详情失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// image signing key Error Range: 7030 - 7039
+	//-----------------------------------------------------------------------------------------------
+	ErrCreateImageSigningKey = NewHTTPError(7030, "创建镜像签名密钥失败")
+	ErrListImageSigningKey   = NewHTTPError(7031, "获取镜像签名密钥列表失败")
+	ErrUpdateImageSigningKey = NewHTTPError(7032, "更新镜像签名密钥失败")
+	ErrDeleteImageSigningKey = NewHTTPError(7033, "删除镜像签名密钥失败")
+	ErrGetImageSigningKey    = NewHTTPError(7034, "获取镜像签名密钥详情失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// test case quarantine Error Range: 7040 - 7049
+	//-----------------------------------------------------------------------------------------------
+	ErrListTestCaseQuarantine   = NewHTTPError(7040, "获取测试用例隔离列表失败")
+	ErrCreateTestCaseQuarantine = NewHTTPError(7041, "新增隔离测试用例失败")
+	ErrUpdateTestCaseQuarantine = NewHTTPError(7042, "更新隔离测试用例失败")
+	ErrDeleteTestCaseQuarantine = NewHTTPError(7043, "删除隔离测试用例失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// artifact registry Error Range: 7050 - 7059
+	//-----------------------------------------------------------------------------------------------
+	ErrCreateArtifactRegistry = NewHTTPError(7050, "创建制品仓库失败")
+	ErrListArtifactRegistry   = NewHTTPError(7051, "获取制品仓库列表失败")
+	ErrUpdateArtifactRegistry = NewHTTPError(7052, "更新制品仓库失败")
+	ErrDeleteArtifactRegistry = NewHTTPError(7053, "删除制品仓库失败")
+	ErrGetArtifactRegistry    = NewHTTPError(7054, "获取制品仓库详情失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// vm runner Error Range: 7060 - 7069
+	//-----------------------------------------------------------------------------------------------
+	ErrCreateVMRunner   = NewHTTPError(7060, "创建 VM 执行器失败")
+	ErrListVMRunner     = NewHTTPError(7061, "获取 VM 执行器列表失败")
+	ErrUpdateVMRunner   = NewHTTPError(7062, "更新 VM 执行器失败")
+	ErrDeleteVMRunner   = NewHTTPError(7063, "删除 VM 执行器失败")
+	ErrRegisterVMRunner = NewHTTPError(7064, "注册 VM 执行器失败")
+	ErrPollVMJob        = NewHTTPError(7065, "获取 VM 任务失败")
+	ErrUpdateVMJob      = NewHTTPError(7066, "更新 VM 任务失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// env gitops Error Range: 7070 - 7079
+	//-----------------------------------------------------------------------------------------------
+	ErrGetEnvGitOps       = NewHTTPError(7070, "获取环境 GitOps 配置失败")
+	ErrUpdateEnvGitOps    = NewHTTPError(7071, "更新环境 GitOps 配置失败")
+	ErrGetEnvGitOpsStatus = NewHTTPError(7072, "获取环境 GitOps 同步状态失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// env drift Error Range: 7080 - 7089
+	//-----------------------------------------------------------------------------------------------
+	ErrGetEnvDrift       = NewHTTPError(7080, "获取环境配置漂移失败")
+	ErrDetectEnvDrift    = NewHTTPError(7081, "检测环境配置漂移失败")
+	ErrReconcileEnvDrift = NewHTTPError(7082, "修复环境配置漂移失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// env snapshot Error Range: 7090 - 7099
+	//-----------------------------------------------------------------------------------------------
+	ErrListEnvSnapshots    = NewHTTPError(7090, "获取环境快照列表失败")
+	ErrCreateEnvSnapshot   = NewHTTPError(7091, "创建环境快照失败")
+	ErrGetEnvSnapshot      = NewHTTPError(7092, "获取环境快照失败")
+	ErrDiffEnvSnapshot     = NewHTTPError(7093, "对比环境快照失败")
+	ErrRollbackEnvSnapshot = NewHTTPError(7094, "回滚环境快照失败")
 )
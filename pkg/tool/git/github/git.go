@@ -15,6 +15,8 @@ package github
 
 import (
 	"context"
+	"net/http"
+	"sort"
 
 	"github.com/google/go-github/v35/github"
 )
@@ -27,3 +29,102 @@ func (c *Client) GetTree(ctx context.Context, owner string, repo string, sha str
 
 	return nil, err
 }
+
+// commitFilesRetries is the number of attempts to commit when the branch is moved by others during the commit
+const commitFilesRetries = 3
+
+// CommitFiles commits the files to the branch in a single commit, the branch is created from startBranch if it doesn't
+// exist. A file with nil content is deleted, it's skipped if it doesn't exist. Nil commit is returned if nothing changes.
+// The commit is recreated on the new head if the branch is updated by others before it's pushed.
+func (c *Client) CommitFiles(ctx context.Context, owner, repo, branch, startBranch, message string, files map[string]*string) (*github.Commit, error) {
+	var commit *github.Commit
+	var err error
+	for i := 0; i < commitFilesRetries; i++ {
+		var retry bool
+		commit, retry, err = c.commitFiles(ctx, owner, repo, branch, startBranch, message, files)
+		if !retry {
+			break
+		}
+	}
+	return commit, err
+}
+
+// commitFiles returns retry as true if the ref of the branch can't be updated since it's not a fast forward, or the
+// branch is created by others
+func (c *Client) commitFiles(ctx context.Context, owner, repo, branch, startBranch, message string, files map[string]*string) (*github.Commit, bool, error) {
+	branchExists := true
+	ref, resp, err := c.Git.GetRef(ctx, owner, repo, "refs/heads/"+branch)
+	if err != nil {
+		if resp == nil || resp.StatusCode != http.StatusNotFound {
+			return nil, false, err
+		}
+		branchExists = false
+		ref, resp, err = c.Git.GetRef(ctx, owner, repo, "refs/heads/"+startBranch)
+		if err = wrapError(resp, err); err != nil {
+			return nil, false, err
+		}
+	}
+	parent, resp, err := c.Git.GetCommit(ctx, owner, repo, ref.GetObject().GetSHA())
+	if err = wrapError(resp, err); err != nil {
+		return nil, false, err
+	}
+
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var entries []*github.TreeEntry
+	for _, path := range paths {
+		content := files[path]
+		if content == nil {
+			_, _, resp, err := c.Repositories.GetContents(ctx, owner, repo, path, &github.RepositoryContentGetOptions{Ref: parent.GetSHA()})
+			if err != nil {
+				if resp != nil && resp.StatusCode == http.StatusNotFound {
+					continue
+				}
+				return nil, false, err
+			}
+		}
+		entries = append(entries, &github.TreeEntry{
+			Path:    github.String(path),
+			Mode:    github.String("100644"),
+			Type:    github.String("blob"),
+			Content: content,
+		})
+	}
+	if len(entries) == 0 {
+		return nil, false, nil
+	}
+
+	tree, resp, err := c.Git.CreateTree(ctx, owner, repo, parent.GetTree().GetSHA(), entries)
+	if err = wrapError(resp, err); err != nil {
+		return nil, false, err
+	}
+	if tree.GetSHA() == parent.GetTree().GetSHA() {
+		return nil, false, nil
+	}
+	commit, resp, err := c.Git.CreateCommit(ctx, owner, repo, &github.Commit{
+		Message: github.String(message),
+		Tree:    tree,
+		Parents: []*github.Commit{{SHA: parent.SHA}},
+	})
+	if err = wrapError(resp, err); err != nil {
+		return nil, false, err
+	}
+
+	newRef := &github.Reference{
+		Ref:    github.String("refs/heads/" + branch),
+		Object: &github.GitObject{SHA: commit.SHA},
+	}
+	if branchExists {
+		_, resp, err = c.Git.UpdateRef(ctx, owner, repo, newRef, false)
+	} else {
+		_, resp, err = c.Git.CreateRef(ctx, owner, repo, newRef)
+	}
+	if err = wrapError(resp, err); err != nil {
+		return nil, resp != nil && resp.StatusCode == http.StatusUnprocessableEntity, err
+	}
+	return commit, false, nil
+}
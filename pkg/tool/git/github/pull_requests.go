@@ -91,3 +91,17 @@ func (c *Client) ListFiles(ctx context.Context, owner string, repo string, numbe
 
 	return res, err
 }
+
+func (c *Client) CreatePullRequest(ctx context.Context, owner, repo, head, base, title, body string) (*github.PullRequest, error) {
+	pr, err := wrap(c.PullRequests.Create(ctx, owner, repo, &github.NewPullRequest{
+		Title: &title,
+		Head:  &head,
+		Base:  &base,
+		Body:  &body,
+	}))
+	if p, ok := pr.(*github.PullRequest); ok {
+		return p, err
+	}
+
+	return nil, err
+}
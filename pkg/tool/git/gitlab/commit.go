@@ -17,7 +17,11 @@ limitations under the License.
 package gitlab
 
 import (
+	"sort"
+
 	"github.com/xanzy/go-gitlab"
+
+	"github.com/koderover/zadig/pkg/tool/httpclient"
 )
 
 func (c *Client) GetLatestRepositoryCommit(owner, repo string, path, branch string) (*gitlab.Commit, error) {
@@ -53,3 +57,66 @@ func (c *Client) GetSingleCommitOfProject(owner, repo, commitSha string) (*gitla
 
 	return nil, err
 }
+
+// CommitFiles commits the files to the branch in a single commit, the branch is created from startBranch if it doesn't
+// exist. A file with nil content is deleted, it's skipped if it doesn't exist. Nil commit is returned if nothing changes.
+func (c *Client) CommitFiles(owner, repo, branch, startBranch, message string, files map[string]*string) (*gitlab.Commit, error) {
+	pid := generateProjectName(owner, repo)
+	opts := &gitlab.CreateCommitOptions{
+		Branch:        &branch,
+		CommitMessage: &message,
+	}
+	ref := branch
+	if _, err := wrap(c.Branches.GetBranch(pid, branch)); err != nil {
+		if !httpclient.IsNotFound(err) {
+			return nil, err
+		}
+		ref = startBranch
+		opts.StartBranch = &startBranch
+	}
+
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		filePath, content := path, files[path]
+		_, err := wrap(c.RepositoryFiles.GetFileMetaData(pid, filePath, &gitlab.GetFileMetaDataOptions{Ref: &ref}))
+		if err != nil && !httpclient.IsNotFound(err) {
+			return nil, err
+		}
+		exists := err == nil
+
+		var action gitlab.FileActionValue
+		switch {
+		case content == nil && !exists:
+			continue
+		case content == nil:
+			action = gitlab.FileDelete
+		case exists:
+			action = gitlab.FileUpdate
+		default:
+			action = gitlab.FileCreate
+		}
+		opts.Actions = append(opts.Actions, &gitlab.CommitActionOptions{
+			Action:   &action,
+			FilePath: &filePath,
+			Content:  content,
+		})
+	}
+	if len(opts.Actions) == 0 {
+		return nil, nil
+	}
+
+	commit, err := wrap(c.Commits.CreateCommit(pid, opts))
+	if err != nil {
+		return nil, err
+	}
+	if ct, ok := commit.(*gitlab.Commit); ok {
+		return ct, nil
+	}
+
+	return nil, err
+}
@@ -72,6 +72,25 @@ func (c *Client) ListChangedFiles(event *gitlab.MergeEvent) ([]string, error) {
 	return files, nil
 }
 
+func (c *Client) CreateMergeRequest(owner, repo, sourceBranch, targetBranch, title, description string) (*gitlab.MergeRequest, error) {
+	removeSourceBranch := true
+	mergeRequest, err := wrap(c.MergeRequests.CreateMergeRequest(generateProjectName(owner, repo), &gitlab.CreateMergeRequestOptions{
+		Title:              &title,
+		Description:        &description,
+		SourceBranch:       &sourceBranch,
+		TargetBranch:       &targetBranch,
+		RemoveSourceBranch: &removeSourceBranch,
+	}))
+	if err != nil {
+		return nil, err
+	}
+	if mr, ok := mergeRequest.(*gitlab.MergeRequest); ok {
+		return mr, nil
+	}
+
+	return nil, err
+}
+
 //func (c *Client) CreateCommitDiscussion(owner, repo, commitHash, comment string) error {
 //	args := &gitlab.CreateCommitDiscussionOptions{Body: &comment}
 //	_, err := wrap(c.Discussions.CreateCommitDiscussion(generateProjectName(owner, repo), commitHash, args))
@@ -0,0 +1,235 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package joboutput validates the typed job outputs and resolves the json paths referenced by the following jobs
+package joboutput
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/xeipuuv/gojsonschema"
+
+	"github.com/koderover/zadig/pkg/types/job"
+)
+
+// refRegex matches the output references like {{.job.jobKey.output.outputName}} and {{.job.jobKey.output.outputName.a.b}},
+// the output names never contain dots, so the rest of the reference is the json path
+var refRegex = regexp.MustCompile(`\{\{\.job\.([^{}\s]+?)\.output\.([a-zA-Z0-9_]+)((?:\.[^.{}\s]+)*)\}\}`)
+
+// Ref is a reference to the output of a job
+type Ref struct {
+	JobKey string
+	Name   string
+	// Path is the json path of json outputs, it is empty if the whole output is referenced
+	Path string
+}
+
+// ParseRefs returns the output references in the content
+func ParseRefs(content string) []*Ref {
+	refs := []*Ref{}
+	for _, match := range refRegex.FindAllStringSubmatch(content, -1) {
+		refs = append(refs, &Ref{JobKey: match[1], Name: match[2], Path: strings.TrimPrefix(match[3], ".")})
+	}
+	return refs
+}
+
+// ValidateSpec checks the type, schema and max length of the output spec
+func ValidateSpec(spec *job.OutputSpec) error {
+	switch spec.Type {
+	case "", job.OutputTypeString, job.OutputTypeNumber, job.OutputTypeBool, job.OutputTypeFile:
+		if spec.Schema != "" {
+			return fmt.Errorf("output %s: schema is only supported by json outputs", spec.Name)
+		}
+	case job.OutputTypeJSON:
+		if spec.Schema != "" {
+			if _, err := gojsonschema.NewSchema(gojsonschema.NewStringLoader(spec.Schema)); err != nil {
+				return fmt.Errorf("output %s: invalid json schema: %s", spec.Name, err)
+			}
+		}
+	default:
+		return fmt.Errorf("output %s: unknown type %s", spec.Name, spec.Type)
+	}
+	if spec.MaxLength < 0 || spec.MaxLength > job.MaxOutputValueLength {
+		return fmt.Errorf("output %s: max length must be between 0 and %d", spec.Name, job.MaxOutputValueLength)
+	}
+	return nil
+}
+
+// Validate checks the output value against the type, schema and max length of the spec.
+// The existence of the files of file outputs is not checked, since it depends on where the job runs.
+func Validate(spec *job.OutputSpec, value string) error {
+	maxLength := spec.MaxLength
+	if maxLength == 0 {
+		maxLength = job.MaxOutputValueLength
+	}
+	if len(value) > maxLength {
+		return fmt.Errorf("output %s is %d bytes, above the max length %d", spec.Name, len(value), maxLength)
+	}
+
+	switch spec.Type {
+	case job.OutputTypeNumber:
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return fmt.Errorf("output %s is not a number: %q", spec.Name, value)
+		}
+	case job.OutputTypeBool:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("output %s is not a bool: %q", spec.Name, value)
+		}
+	case job.OutputTypeFile:
+		if value == "" {
+			return fmt.Errorf("output %s is not a file path", spec.Name)
+		}
+	case job.OutputTypeJSON:
+		if !json.Valid([]byte(value)) {
+			return fmt.Errorf("output %s is not valid json", spec.Name)
+		}
+		if spec.Schema == "" {
+			return nil
+		}
+		result, err := gojsonschema.Validate(gojsonschema.NewStringLoader(spec.Schema), gojsonschema.NewStringLoader(value))
+		if err != nil {
+			return fmt.Errorf("output %s: %s", spec.Name, err)
+		}
+		if !result.Valid() {
+			errs := []string{}
+			for _, e := range result.Errors() {
+				errs = append(errs, e.String())
+			}
+			return fmt.Errorf("output %s does not match the schema: %s", spec.Name, strings.Join(errs, "; "))
+		}
+	}
+	return nil
+}
+
+// Flatten returns the values of all the json paths of the json output, the elements of arrays are referenced by
+// their indexes. For example, {"a":{"b":[1]}} is flattened to "a": {"b":[1]}, "a.b": [1] and "a.b.0": 1.
+// Strings are returned without quotes, the other values are compact json.
+func Flatten(value string) (map[string]string, error) {
+	decoder := json.NewDecoder(strings.NewReader(value))
+	decoder.UseNumber()
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+	resp := make(map[string]string)
+	if err := flatten("", doc, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func flatten(path string, node interface{}, resp map[string]string) error {
+	if path != "" {
+		switch v := node.(type) {
+		case string:
+			resp[path] = v
+		default:
+			b, err := marshal(v)
+			if err != nil {
+				return err
+			}
+			resp[path] = string(b)
+		}
+	}
+
+	switch v := node.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if err := flatten(joinPath(path, key), child, resp); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for i, child := range v {
+			if err := flatten(joinPath(path, strconv.Itoa(i)), child, resp); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// marshal does not escape html characters, so the values are kept as they are
+func marshal(v interface{}) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// HasPath reports whether the json path may exist in the documents matching the schema.
+// It is true if the schema does not restrict the path, e.g. the object allows additional properties.
+func HasPath(schema, path string) (bool, error) {
+	node := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(schema), &node); err != nil {
+		return false, err
+	}
+	for _, segment := range strings.Split(path, ".") {
+		child, restricted := childSchema(node, segment)
+		if !restricted {
+			return true, nil
+		}
+		if child == nil {
+			return false, nil
+		}
+		node = child
+	}
+	return true, nil
+}
+
+// childSchema returns the schema of the segment, restricted is false if the schema does not restrict it
+func childSchema(node map[string]interface{}, segment string) (child map[string]interface{}, restricted bool) {
+	if properties, ok := node["properties"].(map[string]interface{}); ok {
+		if child, ok := properties[segment].(map[string]interface{}); ok {
+			return child, true
+		}
+	}
+	if _, err := strconv.Atoi(segment); err == nil {
+		if items, ok := node["items"].(map[string]interface{}); ok {
+			return items, true
+		}
+	} else if node["type"] == "array" {
+		return nil, true
+	}
+	switch additional := node["additionalProperties"].(type) {
+	case bool:
+		if !additional {
+			return nil, true
+		}
+	case map[string]interface{}:
+		return additional, true
+	}
+	switch node["type"] {
+	case "string", "number", "integer", "boolean", "null":
+		return nil, true
+	}
+	return nil, false
+}
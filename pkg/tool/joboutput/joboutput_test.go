@@ -0,0 +1,95 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package joboutput
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/koderover/zadig/pkg/types/job"
+)
+
+const schema = `{
+	"type": "object",
+	"properties": {
+		"image": {"type": "string"},
+		"replicas": {"type": "integer"},
+		"ports": {"type": "array", "items": {"type": "object", "properties": {"port": {"type": "integer"}}}},
+		"labels": {"type": "object"}
+	},
+	"required": ["image"],
+	"additionalProperties": false
+}`
+
+func TestParseRefs(t *testing.T) {
+	refs := ParseRefs(`echo {{.job.build.svc.module.output.IMAGE}} {{.job.plan.output.CONFIG.ports.0.port}} {{.workflow.params.a}}`)
+	require.Len(t, refs, 2)
+	require.Equal(t, &Ref{JobKey: "build.svc.module", Name: "IMAGE"}, refs[0])
+	require.Equal(t, &Ref{JobKey: "plan", Name: "CONFIG", Path: "ports.0.port"}, refs[1])
+}
+
+func TestValidate(t *testing.T) {
+	require.Error(t, ValidateSpec(&job.OutputSpec{Name: "A", Type: "yaml"}))
+	require.Error(t, ValidateSpec(&job.OutputSpec{Name: "A", Type: job.OutputTypeString, Schema: schema}))
+	require.Error(t, ValidateSpec(&job.OutputSpec{Name: "A", Type: job.OutputTypeJSON, Schema: `{"type": 1}`}))
+	require.Error(t, ValidateSpec(&job.OutputSpec{Name: "A", MaxLength: job.MaxOutputValueLength + 1}))
+	require.NoError(t, ValidateSpec(&job.OutputSpec{Name: "A", Type: job.OutputTypeJSON, Schema: schema}))
+
+	require.NoError(t, Validate(&job.OutputSpec{Name: "A", Type: job.OutputTypeNumber}, "-1.5"))
+	require.Error(t, Validate(&job.OutputSpec{Name: "A", Type: job.OutputTypeNumber}, "one"))
+	require.NoError(t, Validate(&job.OutputSpec{Name: "A", Type: job.OutputTypeBool}, "true"))
+	require.Error(t, Validate(&job.OutputSpec{Name: "A", Type: job.OutputTypeBool}, "yes"))
+	require.Error(t, Validate(&job.OutputSpec{Name: "A", Type: job.OutputTypeString, MaxLength: 3}, "abcd"))
+	require.Error(t, Validate(&job.OutputSpec{Name: "A", Type: job.OutputTypeJSON}, "{"))
+
+	spec := &job.OutputSpec{Name: "A", Type: job.OutputTypeJSON, Schema: schema}
+	require.NoError(t, Validate(spec, `{"image": "nginx:1.25", "replicas": 2}`))
+	require.Error(t, Validate(spec, `{"replicas": 2}`))
+	require.Error(t, Validate(spec, `{"image": "nginx", "replicas": "2"}`))
+}
+
+func TestFlatten(t *testing.T) {
+	values, err := Flatten(`{"image": "nginx:1.25", "replicas": 2, "ports": [{"port": 80}], "labels": {"a": "<b>"}, "debug": null}`)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		"image":        "nginx:1.25",
+		"replicas":     "2",
+		"ports":        `[{"port":80}]`,
+		"ports.0":      `{"port":80}`,
+		"ports.0.port": "80",
+		"labels":       `{"a":"<b>"}`,
+		"labels.a":     "<b>",
+		"debug":        "null",
+	}, values)
+}
+
+func TestHasPath(t *testing.T) {
+	for path, expected := range map[string]bool{
+		"image":        true,
+		"ports.0.port": true,
+		"ports.0.name": true,
+		"labels.app":   true,
+		"image.name":   false,
+		"tag":          false,
+		"ports.first":  false,
+	} {
+		ok, err := HasPath(schema, path)
+		require.NoError(t, err)
+		require.Equal(t, expected, ok, path)
+	}
+}
@@ -0,0 +1,194 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package drift
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// serverManagedFields are the top-level fields maintained by the api server and the controllers
+var serverManagedFields = map[string]bool{
+	"status": true,
+}
+
+// Compare returns the paths of the fields of the desired object which are changed in the live object.
+// Only the fields set in the desired object are compared, so the fields defaulted or maintained by the api server are
+// ignored, except for the lists which must have the same items. Items of the lists of objects with names, like
+// containers and envs, are matched by their names and reported as path[name], the others are matched by their indexes.
+// A changed path is ignored if it's one of ignorePaths or a field of them.
+func Compare(desired, live map[string]interface{}, ignorePaths ...string) []string {
+	var paths []string
+	for _, key := range sortedKeys(desired) {
+		if serverManagedFields[key] {
+			continue
+		}
+		if key == "metadata" {
+			paths = compareMetadata(desired[key], live[key], paths)
+			continue
+		}
+		paths = compareValue(key, desired[key], live[key], paths)
+	}
+
+	var ret []string
+	for _, path := range paths {
+		if !ignored(path, ignorePaths) {
+			ret = append(ret, path)
+		}
+	}
+	return ret
+}
+
+// compareMetadata compares the labels and annotations, the namespace is ignored since it's set when applied
+func compareMetadata(desired, live interface{}, paths []string) []string {
+	desiredMeta, ok := desired.(map[string]interface{})
+	if !ok {
+		return paths
+	}
+	liveMeta, _ := live.(map[string]interface{})
+	for _, key := range []string{"labels", "annotations"} {
+		if value, ok := desiredMeta[key]; ok {
+			paths = compareValue("metadata."+key, value, liveMeta[key], paths)
+		}
+	}
+	return paths
+}
+
+func compareValue(path string, desired, live interface{}, paths []string) []string {
+	switch desiredValue := desired.(type) {
+	case nil:
+		return paths
+	case map[string]interface{}:
+		liveValue, ok := live.(map[string]interface{})
+		if !ok {
+			if live == nil && len(desiredValue) == 0 {
+				return paths
+			}
+			return append(paths, path)
+		}
+		for _, key := range sortedKeys(desiredValue) {
+			paths = compareValue(path+"."+key, desiredValue[key], liveValue[key], paths)
+		}
+		return paths
+	case []interface{}:
+		liveValue, ok := live.([]interface{})
+		if !ok {
+			if live == nil && len(desiredValue) == 0 {
+				return paths
+			}
+			return append(paths, path)
+		}
+		return compareList(path, desiredValue, liveValue, paths)
+	default:
+		if !scalarEqual(desired, live) {
+			return append(paths, path)
+		}
+		return paths
+	}
+}
+
+func compareList(path string, desired, live []interface{}, paths []string) []string {
+	desiredByName, ok := itemsByName(desired)
+	liveByName, liveOK := itemsByName(live)
+	if ok && liveOK {
+		for _, name := range sortedKeys(desiredByName) {
+			itemPath := fmt.Sprintf("%s[%s]", path, name)
+			liveItem, found := liveByName[name]
+			if !found {
+				paths = append(paths, itemPath)
+				continue
+			}
+			paths = compareValue(itemPath, desiredByName[name], liveItem, paths)
+		}
+		for _, name := range sortedKeys(liveByName) {
+			if _, found := desiredByName[name]; !found {
+				paths = append(paths, fmt.Sprintf("%s[%s]", path, name))
+			}
+		}
+		return paths
+	}
+
+	if len(desired) != len(live) {
+		return append(paths, path)
+	}
+	for i := range desired {
+		paths = compareValue(fmt.Sprintf("%s[%d]", path, i), desired[i], live[i], paths)
+	}
+	return paths
+}
+
+// itemsByName indexes the items of the list by their names, false is returned if any item is not an object with a name
+func itemsByName(items []interface{}) (map[string]interface{}, bool) {
+	if len(items) == 0 {
+		return nil, false
+	}
+	ret := make(map[string]interface{}, len(items))
+	for _, item := range items {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		name, ok := m["name"].(string)
+		if !ok || name == "" {
+			return nil, false
+		}
+		ret[name] = item
+	}
+	return ret, true
+}
+
+// scalarEqual compares the scalars by their string forms, so that the numbers decoded from yaml and json are equal, and
+// the quantities normalized by the api server, like 1000m and 1, are equal as well
+func scalarEqual(desired, live interface{}) bool {
+	if live == nil {
+		return false
+	}
+	desiredStr, liveStr := fmt.Sprint(desired), fmt.Sprint(live)
+	if desiredStr == liveStr {
+		return true
+	}
+	desiredQuantity, err := resource.ParseQuantity(desiredStr)
+	if err != nil {
+		return false
+	}
+	liveQuantity, err := resource.ParseQuantity(liveStr)
+	if err != nil {
+		return false
+	}
+	return desiredQuantity.Cmp(liveQuantity) == 0
+}
+
+func ignored(path string, ignorePaths []string) bool {
+	for _, ignorePath := range ignorePaths {
+		if path == ignorePath || strings.HasPrefix(path, ignorePath+".") || strings.HasPrefix(path, ignorePath+"[") {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
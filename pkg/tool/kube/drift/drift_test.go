@@ -0,0 +1,131 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package drift
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/yaml"
+)
+
+const desiredDeployment = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  labels:
+    app: app
+spec:
+  replicas: 2
+  template:
+    spec:
+      containers:
+      - name: app
+        image: app:v1
+        ports:
+        - containerPort: 80
+        env:
+        - name: MODE
+          value: dev
+        resources:
+          limits:
+            cpu: 1000m
+            memory: 1Gi
+`
+
+const liveDeployment = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  namespace: dev
+  resourceVersion: "12345"
+  labels:
+    app: app
+    s-product: demo
+  annotations:
+    deployment.kubernetes.io/revision: "3"
+spec:
+  replicas: 2
+  progressDeadlineSeconds: 600
+  template:
+    metadata:
+      labels:
+        s-product: demo
+    spec:
+      imagePullSecrets:
+      - name: default-registry-secret
+      containers:
+      - name: app
+        image: app:v1
+        imagePullPolicy: IfNotPresent
+        ports:
+        - containerPort: 80
+          protocol: TCP
+        env:
+        - name: MODE
+          value: dev
+        resources:
+          limits:
+            cpu: "1"
+            memory: 1024Mi
+status:
+  replicas: 2
+`
+
+func decode(t *testing.T, manifest string) map[string]interface{} {
+	obj := make(map[string]interface{})
+	require.NoError(t, yaml.Unmarshal([]byte(manifest), &obj))
+	return obj
+}
+
+func TestCompareIgnoresServerManagedFields(t *testing.T) {
+	require.Empty(t, Compare(decode(t, desiredDeployment), decode(t, liveDeployment)))
+}
+
+func TestCompareDetectsChangedFields(t *testing.T) {
+	live := decode(t, liveDeployment)
+	spec := live["spec"].(map[string]interface{})
+	spec["replicas"] = 5
+	container := spec["template"].(map[string]interface{})["spec"].(map[string]interface{})["containers"].([]interface{})[0].(map[string]interface{})
+	container["image"] = "app:debug"
+	container["env"] = append(container["env"].([]interface{}), map[string]interface{}{"name": "DEBUG", "value": "true"})
+
+	require.Equal(t, []string{
+		"spec.replicas",
+		"spec.template.spec.containers[app].env[DEBUG]",
+		"spec.template.spec.containers[app].image",
+	}, Compare(decode(t, desiredDeployment), live))
+
+	require.Equal(t, []string{
+		"spec.template.spec.containers[app].env[DEBUG]",
+	}, Compare(decode(t, desiredDeployment), live, "spec.replicas", "spec.template.spec.containers[app].image"))
+}
+
+func TestCompareDetectsRemovedItems(t *testing.T) {
+	live := decode(t, liveDeployment)
+	spec := live["spec"].(map[string]interface{})
+	container := spec["template"].(map[string]interface{})["spec"].(map[string]interface{})["containers"].([]interface{})[0].(map[string]interface{})
+	delete(container, "env")
+	delete(live["metadata"].(map[string]interface{})["labels"].(map[string]interface{}), "app")
+
+	require.Equal(t, []string{
+		"metadata.labels.app",
+		"spec.template.spec.containers[app].env",
+	}, Compare(decode(t, desiredDeployment), live))
+}
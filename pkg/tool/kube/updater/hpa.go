@@ -0,0 +1,32 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package updater
+
+import (
+	"context"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+func PatchHorizontalPodAutoscalerReplicas(ns, name string, minReplicas, maxReplicas int32, clientset kubernetes.Interface) error {
+	patchBytes := []byte(fmt.Sprintf(`{"spec":{"minReplicas":%d,"maxReplicas":%d}}`, minReplicas, maxReplicas))
+	_, err := clientset.AutoscalingV1().HorizontalPodAutoscalers(ns).Patch(context.TODO(), name, types.MergePatchType, patchBytes, metav1.PatchOptions{})
+	return err
+}
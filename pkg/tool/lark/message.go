@@ -0,0 +1,48 @@
+/*
+ * Copyright 2023 The KodeRover Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package lark
+
+import (
+	"context"
+
+	larkim "github.com/larksuite/oapi-sdk-go/v3/service/im/v1"
+	"github.com/pkg/errors"
+)
+
+// SendTextMessage sends the text to the users by the bot of the app, the app should have the bot ability.
+func (client *Client) SendTextMessage(openIDs []string, text string) error {
+	content := larkim.NewMessageTextBuilder().Text(text).Build()
+	for _, openID := range openIDs {
+		req := larkim.NewCreateMessageReqBuilder().
+			ReceiveIdType(larkim.ReceiveIdTypeOpenId).
+			Body(larkim.NewCreateMessageReqBodyBuilder().
+				ReceiveId(openID).
+				MsgType(larkim.MsgTypeText).
+				Content(content).
+				Build()).
+			Build()
+
+		resp, err := client.Im.Message.Create(context.Background(), req)
+		if err != nil {
+			return errors.Wrapf(err, "send message to %s", openID)
+		}
+		if !resp.Success() {
+			return errors.Wrapf(resp.CodeError, "send message to %s", openID)
+		}
+	}
+	return nil
+}
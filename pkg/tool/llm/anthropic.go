@@ -0,0 +1,191 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/koderover/zadig/pkg/tool/cache"
+)
+
+const (
+	DefaultAnthropicBaseURL   = "https://api.anthropic.com"
+	DefaultAnthropicModel     = "claude-sonnet-4-5"
+	DefaultAnthropicMaxTokens = 1024
+	anthropicAPIVersion       = "2023-06-01"
+)
+
+// AnthropicClient talks to the anthropic messages api, and the services compatible with it
+type AnthropicClient struct {
+	name    string
+	model   string
+	token   string
+	baseURL string
+	client  *http.Client
+}
+
+type anthropicMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type anthropicRequest struct {
+	Model         string              `json:"model"`
+	Messages      []*anthropicMessage `json:"messages"`
+	MaxTokens     int                 `json:"max_tokens"`
+	Temperature   float32             `json:"temperature,omitempty"`
+	StopSequences []string            `json:"stop_sequences,omitempty"`
+	Stream        bool                `json:"stream,omitempty"`
+}
+
+type anthropicContent struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type anthropicResponse struct {
+	Content []*anthropicContent `json:"content"`
+}
+
+type anthropicStreamEvent struct {
+	Type  string            `json:"type"`
+	Delta *anthropicContent `json:"delta"`
+	Error *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (c *AnthropicClient) Configure(config LLMConfig) error {
+	client, err := newHTTPClient(config.GetProxy())
+	if err != nil {
+		return err
+	}
+	c.client = client
+	c.name = config.GetName()
+	c.model = config.GetModel()
+	c.token = config.GetToken()
+	c.baseURL = strings.TrimSuffix(config.GetBaseURL(), "/")
+	if c.baseURL == "" {
+		c.baseURL = DefaultAnthropicBaseURL
+	}
+	return nil
+}
+
+func (c *AnthropicClient) GetCompletion(ctx context.Context, prompt string, options ...ParamOption) (string, error) {
+	resp, err := postJSON(ctx, c.client, c.baseURL+"/v1/messages", c.headers(), c.newRequest(prompt, false, options...))
+	if err != nil {
+		return "", fmt.Errorf("create message failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	result := &anthropicResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return "", fmt.Errorf("decode message failed: %v", err)
+	}
+	completion := strings.Builder{}
+	for _, content := range result.Content {
+		if content.Type == "text" {
+			completion.WriteString(content.Text)
+		}
+	}
+	return completion.String(), nil
+}
+
+func (c *AnthropicClient) GetCompletionStream(ctx context.Context, prompt string, handler StreamHandler, options ...ParamOption) (string, error) {
+	resp, err := postJSON(ctx, c.client, c.baseURL+"/v1/messages", c.headers(), c.newRequest(prompt, true, options...))
+	if err != nil {
+		return "", fmt.Errorf("create message stream failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	completion := strings.Builder{}
+	err = readEventStream(resp.Body, func(_, data string) error {
+		event := &anthropicStreamEvent{}
+		if err := json.Unmarshal([]byte(data), event); err != nil {
+			return fmt.Errorf("decode message stream event failed: %v", err)
+		}
+		switch event.Type {
+		case "error":
+			if event.Error != nil {
+				return fmt.Errorf("message stream error: %s", event.Error.Message)
+			}
+			return fmt.Errorf("message stream error")
+		case "content_block_delta":
+			if event.Delta == nil || event.Delta.Text == "" {
+				return nil
+			}
+			completion.WriteString(event.Delta.Text)
+			return handler(event.Delta.Text)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return completion.String(), nil
+}
+
+func (c *AnthropicClient) Parse(ctx context.Context, prompt string, cache cache.ICache, options ...ParamOption) (string, error) {
+	return parseWithCache(ctx, c, prompt, cache, options...)
+}
+
+func (c *AnthropicClient) GetName() string {
+	if c.name == "" {
+		return ProviderAnthropic
+	}
+	return c.name
+}
+
+func (c *AnthropicClient) headers() map[string]string {
+	return map[string]string{
+		"x-api-key":         c.token,
+		"anthropic-version": anthropicAPIVersion,
+	}
+}
+
+func (c *AnthropicClient) newRequest(prompt string, stream bool, options ...ParamOption) *anthropicRequest {
+	opts := ParamOptions{}
+	for _, opt := range options {
+		opt(&opts)
+	}
+	opts = ValidOptions(opts)
+
+	req := &anthropicRequest{
+		Model:         opts.Model,
+		Messages:      []*anthropicMessage{{Role: "user", Content: prompt}},
+		MaxTokens:     opts.MaxTokens,
+		Temperature:   opts.Temperature,
+		StopSequences: opts.StopWords,
+		Stream:        stream,
+	}
+	if req.Model == "" {
+		req.Model = c.model
+	}
+	if req.Model == "" {
+		req.Model = DefaultAnthropicModel
+	}
+	// max_tokens is required by the messages api
+	if req.MaxTokens == 0 {
+		req.MaxTokens = DefaultAnthropicMaxTokens
+	}
+	return req
+}
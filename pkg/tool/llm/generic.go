@@ -0,0 +1,166 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/koderover/zadig/pkg/tool/cache"
+)
+
+// GenericClient talks to a self-hosted gateway with a simple http protocol:
+// the request is posted to the base url in the json format of genericRequest, with the token as a bearer token if set.
+// the response is a genericResponse, or one genericResponse per line when streaming, the "data:" prefix of sse is also accepted.
+type GenericClient struct {
+	name    string
+	model   string
+	token   string
+	baseURL string
+	client  *http.Client
+}
+
+type genericRequest struct {
+	Model       string   `json:"model,omitempty"`
+	Prompt      string   `json:"prompt"`
+	MaxTokens   int      `json:"max_tokens,omitempty"`
+	Temperature float32  `json:"temperature,omitempty"`
+	Stop        []string `json:"stop,omitempty"`
+	Stream      bool     `json:"stream"`
+}
+
+type genericResponse struct {
+	Text  string `json:"text"`
+	Done  bool   `json:"done"`
+	Error string `json:"error"`
+}
+
+func (c *GenericClient) Configure(config LLMConfig) error {
+	if config.GetBaseURL() == "" {
+		return fmt.Errorf("base url of the generic llm provider is required")
+	}
+	client, err := newHTTPClient(config.GetProxy())
+	if err != nil {
+		return err
+	}
+	c.client = client
+	c.name = config.GetName()
+	c.model = config.GetModel()
+	c.token = config.GetToken()
+	c.baseURL = config.GetBaseURL()
+	return nil
+}
+
+func (c *GenericClient) GetCompletion(ctx context.Context, prompt string, options ...ParamOption) (string, error) {
+	resp, err := postJSON(ctx, c.client, c.baseURL, c.headers(), c.newRequest(prompt, false, options...))
+	if err != nil {
+		return "", fmt.Errorf("get completion failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	result := &genericResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return "", fmt.Errorf("decode completion failed: %v", err)
+	}
+	if result.Error != "" {
+		return "", fmt.Errorf("get completion failed: %s", result.Error)
+	}
+	return result.Text, nil
+}
+
+func (c *GenericClient) GetCompletionStream(ctx context.Context, prompt string, handler StreamHandler, options ...ParamOption) (string, error) {
+	resp, err := postJSON(ctx, c.client, c.baseURL, c.headers(), c.newRequest(prompt, true, options...))
+	if err != nil {
+		return "", fmt.Errorf("get completion stream failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	completion := strings.Builder{}
+	scanner := newStreamScanner(resp.Body)
+	for scanner.Scan() {
+		line := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "data:"))
+		if line == "" {
+			continue
+		}
+		if line == "[DONE]" {
+			break
+		}
+		result := &genericResponse{}
+		if err := json.Unmarshal([]byte(line), result); err != nil {
+			return "", fmt.Errorf("decode completion stream failed: %v", err)
+		}
+		if result.Error != "" {
+			return "", fmt.Errorf("get completion stream failed: %s", result.Error)
+		}
+		if result.Text != "" {
+			completion.WriteString(result.Text)
+			if err := handler(result.Text); err != nil {
+				return "", err
+			}
+		}
+		if result.Done {
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("read completion stream failed: %v", err)
+	}
+	return completion.String(), nil
+}
+
+func (c *GenericClient) Parse(ctx context.Context, prompt string, cache cache.ICache, options ...ParamOption) (string, error) {
+	return parseWithCache(ctx, c, prompt, cache, options...)
+}
+
+func (c *GenericClient) GetName() string {
+	if c.name == "" {
+		return ProviderGeneric
+	}
+	return c.name
+}
+
+func (c *GenericClient) headers() map[string]string {
+	headers := map[string]string{}
+	if c.token != "" {
+		headers["Authorization"] = "Bearer " + c.token
+	}
+	return headers
+}
+
+func (c *GenericClient) newRequest(prompt string, stream bool, options ...ParamOption) *genericRequest {
+	opts := ParamOptions{}
+	for _, opt := range options {
+		opt(&opts)
+	}
+	opts = ValidOptions(opts)
+
+	req := &genericRequest{
+		Model:       opts.Model,
+		Prompt:      prompt,
+		MaxTokens:   opts.MaxTokens,
+		Temperature: opts.Temperature,
+		Stop:        opts.StopWords,
+		Stream:      stream,
+	}
+	if req.Model == "" {
+		req.Model = c.model
+	}
+	return req
+}
@@ -18,31 +18,52 @@ package llm
 import (
 	"context"
 	"crypto/sha256"
+	"encoding/base64"
 	"encoding/hex"
 	"fmt"
 
 	"github.com/koderover/zadig/pkg/tool/cache"
+	"github.com/koderover/zadig/pkg/tool/log"
+)
+
+const (
+	ProviderOpenAI      = "openai"
+	ProviderAzureOpenAI = "azureopenai"
+	// ProviderOllama is for ollama and other services compatible with the openai chat completion api
+	ProviderOllama    = "ollama"
+	ProviderAnthropic = "anthropic"
+	// ProviderGeneric is for self-hosted gateways speaking the simple http protocol described in generic.go
+	ProviderGeneric = "generic"
 )
 
 var (
-	clients = map[string]ILLM{
-		"openai":      &OpenAIClient{},
-		"azureopenai": &OpenAIClient{},
+	// a new client is created every time, so the clients configured for different integrations do not share state
+	clients = map[string]func() ILLM{
+		ProviderOpenAI:      func() ILLM { return &OpenAIClient{} },
+		ProviderAzureOpenAI: func() ILLM { return &OpenAIClient{} },
+		ProviderOllama:      func() ILLM { return &OpenAIClient{provider: ProviderOllama} },
+		ProviderAnthropic:   func() ILLM { return &AnthropicClient{} },
+		ProviderGeneric:     func() ILLM { return &GenericClient{} },
 	}
 )
 
+// StreamHandler is called with every piece of the completion in order, returning an error stops the stream
+type StreamHandler func(delta string) error
+
 type ILLM interface {
 	Configure(config LLMConfig) error
 	GetCompletion(ctx context.Context, prompt string, options ...ParamOption) (string, error)
+	// GetCompletionStream calls the handler with the completion as it is generated, the whole completion is returned at the end
+	GetCompletionStream(ctx context.Context, prompt string, handler StreamHandler, options ...ParamOption) (string, error)
 	Parse(ctx context.Context, prompt string, cache cache.ICache, options ...ParamOption) (string, error)
 	GetName() string
 }
 
 func NewClient(provider string) (ILLM, error) {
-	if c, ok := clients[provider]; !ok {
+	if newClient, ok := clients[provider]; !ok {
 		return nil, fmt.Errorf("provider %s not supported", provider)
 	} else {
-		return c, nil
+		return newClient(), nil
 	}
 }
 
@@ -86,3 +107,36 @@ func GetCacheKey(provider string, sEnc string) string {
 
 	return hex.EncodeToString(hash[:])
 }
+
+// parseWithCache returns the cached completion of the prompt if there is one, otherwise it gets the completion and caches it
+func parseWithCache(ctx context.Context, client ILLM, prompt string, cache cache.ICache, options ...ParamOption) (string, error) {
+	cacheKey := GetCacheKey(client.GetName(), prompt)
+
+	if !cache.IsCacheDisabled() && cache.Exists(cacheKey) {
+		response, err := cache.Load(cacheKey)
+		if err != nil {
+			return "", err
+		}
+
+		if response != "" {
+			output, err := base64.StdEncoding.DecodeString(response)
+			if err == nil {
+				return string(output), nil
+			}
+			// the broken cached data is overwritten by the completion below
+			log.Errorf("error decoding cached data: %v", err)
+		}
+	}
+
+	response, err := client.GetCompletion(ctx, prompt, options...)
+	if err != nil {
+		return "", err
+	}
+
+	// a failed store only costs a completion next time, the response is still returned
+	if err := cache.Store(cacheKey, base64.StdEncoding.EncodeToString([]byte(response))); err != nil {
+		log.Errorf("error storing value to cache: %v", err)
+	}
+
+	return response, nil
+}
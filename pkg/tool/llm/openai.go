@@ -17,11 +17,10 @@ package llm
 
 import (
 	"context"
-	"encoding/base64"
 	"errors"
 	"fmt"
-	"net/http"
-	"net/url"
+	"io"
+	"strings"
 
 	"github.com/pkoukk/tiktoken-go"
 	"github.com/sashabaranov/go-openai"
@@ -33,6 +32,8 @@ import (
 const (
 	DefaultOpenAIModel           = openai.GPT3Dot5Turbo
 	DefaultOpenAIModelTokenLimit = "4096"
+	DefaultOllamaBaseURL         = "http://localhost:11434/v1"
+	DefaultOllamaModel           = "llama2"
 )
 
 type OpenAIClient struct {
@@ -40,6 +41,8 @@ type OpenAIClient struct {
 	model   string
 	client  *openai.Client
 	apiType string
+	// provider is ollama when the client talks to a service compatible with the openai api
+	provider string
 }
 
 func (c *OpenAIClient) Configure(config LLMConfig) error {
@@ -58,19 +61,19 @@ func (c *OpenAIClient) Configure(config LLMConfig) error {
 		// config.GetAPIType() == "OPEN_AI"
 		c.apiType = "OPEN_AI"
 		defaultConfig = openai.DefaultConfig(token)
+		if config.GetBaseURL() != "" {
+			defaultConfig.BaseURL = strings.TrimSuffix(config.GetBaseURL(), "/")
+		} else if c.provider == ProviderOllama {
+			defaultConfig.BaseURL = DefaultOllamaBaseURL
+		}
 	}
 
 	if config.GetProxy() != "" {
-		proxyUrl, err := url.Parse(config.GetProxy())
+		httpClient, err := newHTTPClient(config.GetProxy())
 		if err != nil {
-			return fmt.Errorf("invalid proxy url %s", config.GetProxy())
-		}
-		transport := &http.Transport{
-			Proxy: http.ProxyURL(proxyUrl),
-		}
-		defaultConfig.HTTPClient = &http.Client{
-			Transport: transport,
+			return err
 		}
+		defaultConfig.HTTPClient = httpClient
 	}
 
 	client := openai.NewClientWithConfig(defaultConfig)
@@ -92,15 +95,7 @@ func (c *OpenAIClient) GetCompletion(ctx context.Context, prompt string, options
 	}
 	opts = ValidOptions(opts)
 
-	model := opts.Model
-	if model == "" {
-		if c.model == "" {
-			model = DefaultOpenAIModel
-		} else {
-			model = c.model
-		}
-	}
-
+	model := c.getModel(opts)
 	messages := []openai.ChatCompletionMessage{
 		{
 			Role:    "user",
@@ -136,43 +131,74 @@ func (c *OpenAIClient) GetCompletion(ctx context.Context, prompt string, options
 	return resp.Choices[0].Message.Content, nil
 }
 
-func (a *OpenAIClient) Parse(ctx context.Context, prompt string, cache cache.ICache, options ...ParamOption) (string, error) {
-	// Check for cached data
-	cacheKey := GetCacheKey(a.GetName(), prompt)
+func (c *OpenAIClient) GetCompletionStream(ctx context.Context, prompt string, handler StreamHandler, options ...ParamOption) (string, error) {
+	opts := ParamOptions{}
+	for _, opt := range options {
+		opt(&opts)
+	}
+	opts = ValidOptions(opts)
 
-	if !cache.IsCacheDisabled() && cache.Exists(cacheKey) {
-		response, err := cache.Load(cacheKey)
+	stream, err := c.client.CreateChatCompletionStream(ctx, openai.ChatCompletionRequest{
+		Model: c.getModel(opts),
+		Messages: []openai.ChatCompletionMessage{
+			{
+				Role:    "user",
+				Content: prompt,
+			},
+		},
+		MaxTokens:   opts.MaxTokens,
+		Temperature: opts.Temperature,
+		Stop:        opts.StopWords,
+		LogitBias:   opts.LogitBias,
+		Stream:      true,
+	})
+	if err != nil {
+		return "", fmt.Errorf("create chat completion stream failed: %v", err)
+	}
+	defer stream.Close()
+
+	completion := strings.Builder{}
+	for {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return completion.String(), nil
+		}
 		if err != nil {
-			return "", err
+			return "", fmt.Errorf("receive chat completion stream failed: %v", err)
 		}
-
-		if response != "" {
-			output, err := base64.StdEncoding.DecodeString(response)
-			if err != nil {
-				log.Errorf("error decoding cached data: %v", err)
-				return "", nil
-			}
-			return string(output), nil
+		if len(resp.Choices) == 0 || resp.Choices[0].Delta.Content == "" {
+			continue
+		}
+		delta := resp.Choices[0].Delta.Content
+		completion.WriteString(delta)
+		if err := handler(delta); err != nil {
+			return "", err
 		}
 	}
+}
 
-	response, err := a.GetCompletion(ctx, prompt, options...)
-	if err != nil {
-		return "", err
+func (c *OpenAIClient) getModel(opts ParamOptions) string {
+	if opts.Model != "" {
+		return opts.Model
 	}
-
-	err = cache.Store(cacheKey, base64.StdEncoding.EncodeToString([]byte(response)))
-
-	if err != nil {
-		log.Errorf("error storing value to cache: %v", err)
-		return "", nil
+	if c.model != "" {
+		return c.model
+	}
+	if c.provider == ProviderOllama {
+		return DefaultOllamaModel
 	}
+	return DefaultOpenAIModel
+}
 
-	return response, nil
+func (a *OpenAIClient) Parse(ctx context.Context, prompt string, cache cache.ICache, options ...ParamOption) (string, error) {
+	return parseWithCache(ctx, a, prompt, cache, options...)
 }
 
 func (a *OpenAIClient) GetName() string {
 	if a.name == "" {
+		if a.provider != "" {
+			return a.provider
+		}
 		if a.apiType == "AZURE" || a.apiType == "AZURE_AD" {
 			return "azureopenai"
 		}
@@ -22,6 +22,7 @@ import (
 	"mime"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
@@ -295,3 +296,41 @@ func (c *Client) ListFiles(bucketName, prefix string, recursive bool) ([]string,
 
 	return ret, nil
 }
+
+// FileExists returns true if the object exists in the bucket
+func (c *Client) FileExists(bucketName, objectKey string) (bool, error) {
+	_, err := c.HeadObject(&s3.HeadObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(objectKey),
+	})
+	if err == nil {
+		return true, nil
+	}
+	if e, ok := err.(awserr.Error); ok && (e.Code() == "NotFound" || e.Code() == s3.ErrCodeNoSuchKey) {
+		return false, nil
+	}
+	return false, err
+}
+
+// GetLatestFile returns the key of the last modified object with given prefix, it returns an empty key if nothing matched
+func (c *Client) GetLatestFile(bucketName, prefix string) (string, error) {
+	latestKey := ""
+	var latestTime time.Time
+	err := c.ListObjectsPages(&s3.ListObjectsInput{
+		Bucket: aws.String(bucketName),
+		Prefix: aws.String(prefix),
+	}, func(output *s3.ListObjectsOutput, lastPage bool) bool {
+		for _, item := range output.Contents {
+			if item.LastModified != nil && item.LastModified.After(latestTime) {
+				latestKey = aws.StringValue(item.Key)
+				latestTime = *item.LastModified
+			}
+		}
+		return true
+	})
+	if err != nil {
+		log.Errorf("bucket [%s] listing objects with prefix [%v] failed, error: %v", bucketName, prefix, err)
+		return "", err
+	}
+	return latestKey, nil
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testimpact
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"path"
+	"path/filepath"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// GoPackage is the subset of the output of `go list -json` used to build the import graph
+type GoPackage struct {
+	ImportPath   string
+	Dir          string
+	Deps         []string
+	TestImports  []string
+	XTestImports []string
+	TestGoFiles  []string
+	XTestGoFiles []string
+	EmbedFiles   []string
+}
+
+// GoListArgs lists the packages of the module with their transitive dependencies
+var GoListArgs = []string{"list", "-e", "-json", "./..."}
+
+// ParseGoList decodes the packages printed by `go list -json`, the Dir of the packages is made relative to root
+func ParseGoList(content []byte, root string) ([]*GoPackage, error) {
+	packages := []*GoPackage{}
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	for {
+		pkg := &GoPackage{}
+		err := decoder.Decode(pkg)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if filepath.IsAbs(pkg.Dir) {
+			rel, err := filepath.Rel(root, pkg.Dir)
+			if err != nil {
+				return nil, err
+			}
+			pkg.Dir = rel
+		}
+		pkg.Dir = filepath.ToSlash(pkg.Dir)
+		packages = append(packages, pkg)
+	}
+	return packages, nil
+}
+
+type goGraph struct {
+	packages []*GoPackage
+	byPath   map[string]*GoPackage
+	dirs     map[string]*GoPackage
+	// files maps the embedded files to the packages containing them
+	files map[string]*GoPackage
+}
+
+func newGoGraph(packages []*GoPackage) *goGraph {
+	g := &goGraph{
+		packages: packages,
+		byPath:   make(map[string]*GoPackage, len(packages)),
+		dirs:     make(map[string]*GoPackage, len(packages)),
+		files:    make(map[string]*GoPackage),
+	}
+	for _, pkg := range packages {
+		g.byPath[pkg.ImportPath] = pkg
+		g.dirs[pkg.Dir] = pkg
+		for _, file := range pkg.EmbedFiles {
+			g.files[path.Join(pkg.Dir, file)] = pkg
+		}
+	}
+	return g
+}
+
+// packageOf returns the package of the go file or the embedded file, the file may be deleted by the change
+func (g *goGraph) packageOf(file string) *GoPackage {
+	if pkg, ok := g.files[file]; ok {
+		return pkg
+	}
+	if path.Ext(file) != ".go" {
+		return nil
+	}
+	return g.dirs[path.Dir(file)]
+}
+
+// affectedTests returns the packages with tests which are or depend on the changed packages, including the
+// dependencies of the test only imports
+func (g *goGraph) affectedTests(changed sets.String) []string {
+	if len(changed) == 0 {
+		return nil
+	}
+	isChanged := func(importPaths []string) bool {
+		for _, importPath := range importPaths {
+			if changed.Has(importPath) {
+				return true
+			}
+		}
+		return false
+	}
+
+	affected := []string{}
+	for _, pkg := range g.packages {
+		if len(pkg.TestGoFiles) == 0 && len(pkg.XTestGoFiles) == 0 {
+			continue
+		}
+		testImports := append(append([]string{}, pkg.TestImports...), pkg.XTestImports...)
+		hit := isChanged([]string{pkg.ImportPath}) || isChanged(pkg.Deps) || isChanged(testImports)
+		for i := 0; !hit && i < len(testImports); i++ {
+			if imported, ok := g.byPath[testImports[i]]; ok {
+				hit = isChanged(imported.Deps)
+			}
+		}
+		if hit {
+			affected = append(affected, pkg.ImportPath)
+		}
+	}
+	sort.Strings(affected)
+	return affected
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package testimpact computes the test targets affected by the changed files of a pull request
+package testimpact
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/koderover/zadig/pkg/types/step"
+)
+
+// Analyze returns the targets of the mappings and the go packages affected by the changed files.
+// The full suite is required if there is no changed file, or a changed file is neither ignored nor covered by
+// the mappings or the go packages, because its impact is unknown.
+// goPackages is nil if the go import graph is not used.
+func Analyze(changedFiles []string, mappings []*step.TestImpactMapping, ignorePaths []string, goPackages []*GoPackage) *step.TestImpactResult {
+	if len(changedFiles) == 0 {
+		return &step.TestImpactResult{Full: true, Reason: "no changed file", Targets: []string{}}
+	}
+
+	targets := sets.NewString()
+	graph := newGoGraph(goPackages)
+	changedPackages := sets.NewString()
+	for _, file := range changedFiles {
+		file = cleanPath(file)
+		if file == "" || matchAny(ignorePaths, file) {
+			continue
+		}
+		covered := false
+		for _, mapping := range mappings {
+			if matchAny(mapping.SourcePaths, file) {
+				targets.Insert(mapping.Targets...)
+				covered = true
+			}
+		}
+		if pkg := graph.packageOf(file); pkg != nil {
+			changedPackages.Insert(pkg.ImportPath)
+			covered = true
+		}
+		if !covered {
+			return &step.TestImpactResult{Full: true, Reason: fmt.Sprintf("changed file %s is not covered by the mappings", file), Targets: []string{}}
+		}
+	}
+	targets.Insert(graph.affectedTests(changedPackages)...)
+	targets.Delete("")
+
+	return &step.TestImpactResult{Targets: targets.List()}
+}
+
+// Match reports whether the slash separated name matches the glob pattern.
+// Besides the syntax of path.Match, "**" matches any number of directories, and a pattern ending with "/"
+// matches all the files in the directory.
+func Match(pattern, name string) bool {
+	pattern = cleanPath(pattern)
+	if strings.HasSuffix(pattern, "/") {
+		pattern += "**"
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(cleanPath(name), "/"))
+}
+
+func matchSegments(patterns, names []string) bool {
+	for len(patterns) > 0 {
+		if patterns[0] == "**" {
+			patterns = patterns[1:]
+			if len(patterns) == 0 {
+				return true
+			}
+			for i := range names {
+				if matchSegments(patterns, names[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(names) == 0 {
+			return false
+		}
+		if matched, err := path.Match(patterns[0], names[0]); err != nil || !matched {
+			return false
+		}
+		patterns, names = patterns[1:], names[1:]
+	}
+	return len(names) == 0
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if Match(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// cleanPath trims the leading "./" and "/" of the path, the trailing "/" is kept to mark a directory
+func cleanPath(p string) string {
+	p = strings.TrimSpace(p)
+	for strings.HasPrefix(p, "./") {
+		p = p[2:]
+	}
+	return strings.TrimLeft(p, "/")
+}
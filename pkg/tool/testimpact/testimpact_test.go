@@ -0,0 +1,108 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testimpact
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/koderover/zadig/pkg/types/step"
+)
+
+func TestMatch(t *testing.T) {
+	require.True(t, Match("docs/**", "docs/a/b.md"))
+	require.True(t, Match("**/*.md", "README.md"))
+	require.True(t, Match("**/*.md", "docs/a/b.md"))
+	require.True(t, Match("./web/", "web/src/app.ts"))
+	require.True(t, Match("services/*/main.go", "services/user/main.go"))
+	require.True(t, Match("src/**/test", "src/test"))
+	require.False(t, Match("services/*/main.go", "services/user/cmd/main.go"))
+	require.False(t, Match("docs/**", "pkg/docs/a.md"))
+	require.False(t, Match("*.go", "pkg/a.go"))
+}
+
+const goList = `{
+	"Dir": "/workspace/app",
+	"ImportPath": "example.com/app",
+	"Deps": ["example.com/app/pkg/db", "example.com/app/pkg/util", "fmt"],
+	"TestGoFiles": ["main_test.go"]
+}
+{
+	"Dir": "/workspace/app/pkg/db",
+	"ImportPath": "example.com/app/pkg/db",
+	"Deps": ["example.com/app/pkg/util", "fmt"],
+	"EmbedFiles": ["schema.sql"],
+	"XTestGoFiles": ["db_test.go"],
+	"XTestImports": ["example.com/app/pkg/db", "example.com/app/pkg/dbtest", "testing"]
+}
+{
+	"Dir": "/workspace/app/pkg/dbtest",
+	"ImportPath": "example.com/app/pkg/dbtest",
+	"Deps": ["example.com/app/pkg/mock"]
+}
+{
+	"Dir": "/workspace/app/pkg/mock",
+	"ImportPath": "example.com/app/pkg/mock"
+}
+{
+	"Dir": "/workspace/app/pkg/util",
+	"ImportPath": "example.com/app/pkg/util",
+	"TestGoFiles": ["util_test.go"]
+}
+`
+
+func TestAnalyze(t *testing.T) {
+	packages, err := ParseGoList([]byte(goList), "/workspace/app")
+	require.NoError(t, err)
+	require.Len(t, packages, 5)
+	require.Equal(t, ".", packages[0].Dir)
+	require.Equal(t, "pkg/db", packages[1].Dir)
+
+	mappings := []*step.TestImpactMapping{
+		{SourcePaths: []string{"web/**"}, Targets: []string{"web-unit", "web-e2e"}},
+		{SourcePaths: []string{"api/*.proto"}, Targets: []string{"web-e2e", "example.com/app"}},
+	}
+	ignores := []string{"**/*.md"}
+
+	result := Analyze(nil, mappings, ignores, packages)
+	require.True(t, result.Full)
+
+	result = Analyze([]string{"web/src/app.ts", "README.md"}, mappings, ignores, nil)
+	require.False(t, result.Full)
+	require.Equal(t, []string{"web-e2e", "web-unit"}, result.Targets)
+
+	result = Analyze([]string{"docs/index.md"}, mappings, ignores, packages)
+	require.False(t, result.Full)
+	require.Empty(t, result.Targets)
+
+	result = Analyze([]string{"pkg/util/strings.go"}, mappings, ignores, packages)
+	require.False(t, result.Full)
+	require.Equal(t, []string{"example.com/app", "example.com/app/pkg/db", "example.com/app/pkg/util"}, result.Targets)
+
+	// the mock package is only imported by the tests of db through dbtest
+	result = Analyze([]string{"pkg/mock/mock.go", "api/user.proto"}, mappings, ignores, packages)
+	require.False(t, result.Full)
+	require.Equal(t, []string{"example.com/app", "example.com/app/pkg/db", "web-e2e"}, result.Targets)
+
+	result = Analyze([]string{"pkg/db/schema.sql"}, mappings, ignores, packages)
+	require.Equal(t, []string{"example.com/app", "example.com/app/pkg/db"}, result.Targets)
+
+	result = Analyze([]string{"pkg/util/strings.go", "go.mod"}, mappings, ignores, packages)
+	require.True(t, result.Full)
+	require.Contains(t, result.Reason, "go.mod")
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testreport
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/koderover/zadig/pkg/types/step"
+)
+
+type coberturaCoverage struct {
+	LineRate        float64          `xml:"line-rate,attr"`
+	BranchRate      float64          `xml:"branch-rate,attr"`
+	LinesCovered    int              `xml:"lines-covered,attr"`
+	LinesValid      int              `xml:"lines-valid,attr"`
+	BranchesCovered int              `xml:"branches-covered,attr"`
+	BranchesValid   int              `xml:"branches-valid,attr"`
+	Lines           []*coberturaLine `xml:"packages>package>classes>class>lines>line"`
+}
+
+type coberturaLine struct {
+	Hits              int    `xml:"hits,attr"`
+	Branch            bool   `xml:"branch,attr"`
+	ConditionCoverage string `xml:"condition-coverage,attr"`
+}
+
+// conditionCoverageRegex matches the condition coverage of a line, e.g. 50% (1/2)
+var conditionCoverageRegex = regexp.MustCompile(`\((\d+)/(\d+)\)`)
+
+// ParseCobertura parses the cobertura xml, the counts are calculated by the lines if they are not in the root element
+func ParseCobertura(content []byte) (*step.TestCoverage, error) {
+	result := &coberturaCoverage{}
+	if err := xml.Unmarshal(content, result); err != nil {
+		return nil, err
+	}
+	coverage := &step.TestCoverage{
+		LinesCovered:    result.LinesCovered,
+		LinesValid:      result.LinesValid,
+		LineRate:        result.LineRate,
+		BranchesCovered: result.BranchesCovered,
+		BranchesValid:   result.BranchesValid,
+		BranchRate:      result.BranchRate,
+	}
+	if coverage.LinesValid == 0 && len(result.Lines) > 0 {
+		for _, line := range result.Lines {
+			coverage.LinesValid++
+			if line.Hits > 0 {
+				coverage.LinesCovered++
+			}
+			if !line.Branch {
+				continue
+			}
+			if match := conditionCoverageRegex.FindStringSubmatch(line.ConditionCoverage); match != nil {
+				covered, _ := strconv.Atoi(match[1])
+				valid, _ := strconv.Atoi(match[2])
+				coverage.BranchesCovered += covered
+				coverage.BranchesValid += valid
+			}
+		}
+		coverage.LineRate = float64(coverage.LinesCovered) / float64(coverage.LinesValid)
+		if coverage.BranchesValid > 0 {
+			coverage.BranchRate = float64(coverage.BranchesCovered) / float64(coverage.BranchesValid)
+		}
+	}
+	return coverage, nil
+}
+
+// ParseLCOV sums the line and branch counts of all the source files in the lcov tracefile
+func ParseLCOV(content []byte) (*step.TestCoverage, error) {
+	coverage := &step.TestCoverage{}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		key, value, found := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if !found {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			continue
+		}
+		switch key {
+		case "LF":
+			coverage.LinesValid += n
+		case "LH":
+			coverage.LinesCovered += n
+		case "BRF":
+			coverage.BranchesValid += n
+		case "BRH":
+			coverage.BranchesCovered += n
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if coverage.LinesValid > 0 {
+		coverage.LineRate = float64(coverage.LinesCovered) / float64(coverage.LinesValid)
+	}
+	if coverage.BranchesValid > 0 {
+		coverage.BranchRate = float64(coverage.BranchesCovered) / float64(coverage.BranchesValid)
+	}
+	return coverage, nil
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testreport
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/koderover/zadig/pkg/types/step"
+)
+
+// goTestEvent is the event printed by test2json, see `go doc cmd/test2json`
+type goTestEvent struct {
+	Action  string  `json:"Action"`
+	Package string  `json:"Package"`
+	Test    string  `json:"Test"`
+	Elapsed float64 `json:"Elapsed"`
+	Output  string  `json:"Output"`
+}
+
+// ParseGoTest parses the output of `go test -json`, the lines which are not test2json events are ignored.
+// A failed package without failed tests, usually a build failure, is reported as a failed case named by the package.
+func ParseGoTest(content []byte) ([]*step.TestCaseResult, error) {
+	type caseKey struct {
+		pkg  string
+		test string
+	}
+	cases := make([]*step.TestCaseResult, 0)
+	caseMap := make(map[caseKey]*step.TestCaseResult)
+	outputs := make(map[caseKey]*strings.Builder)
+	failedPackages := make(map[string]bool)
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if !bytes.HasPrefix(line, []byte("{")) {
+			continue
+		}
+		event := &goTestEvent{}
+		if err := json.Unmarshal(line, event); err != nil {
+			continue
+		}
+		key := caseKey{pkg: event.Package, test: event.Test}
+		switch event.Action {
+		case "output":
+			if _, ok := outputs[key]; !ok {
+				outputs[key] = &strings.Builder{}
+			}
+			outputs[key].WriteString(event.Output)
+		case "pass", "fail", "skip":
+			if event.Test == "" {
+				if event.Action == "fail" {
+					failedPackages[event.Package] = true
+				}
+				continue
+			}
+			c := &step.TestCaseResult{
+				Name:     event.Test,
+				Suite:    event.Package,
+				Duration: event.Elapsed,
+			}
+			switch event.Action {
+			case "pass":
+				c.Status = step.TestCaseStatusPassed
+			case "fail":
+				c.Status = step.TestCaseStatusFailed
+			default:
+				c.Status = step.TestCaseStatusSkipped
+			}
+			// the case is reported more than once if the tests are run with -count
+			if origin, ok := caseMap[key]; ok {
+				if origin.Status != c.Status && origin.Status != step.TestCaseStatusSkipped && c.Status != step.TestCaseStatusSkipped {
+					origin.Flaky = true
+				}
+				if c.Status == step.TestCaseStatusFailed {
+					origin.Status = c.Status
+				}
+				origin.Duration += c.Duration
+				continue
+			}
+			caseMap[key] = c
+			cases = append(cases, c)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	failedCasePackages := make(map[string]bool)
+	for key, c := range caseMap {
+		if c.Status != step.TestCaseStatusFailed {
+			continue
+		}
+		failedCasePackages[key.pkg] = true
+		if output, ok := outputs[key]; ok {
+			c.Message = truncateMessage(output.String())
+		}
+	}
+	packages := make([]string, 0, len(failedPackages))
+	for pkg := range failedPackages {
+		if !failedCasePackages[pkg] {
+			packages = append(packages, pkg)
+		}
+	}
+	sort.Strings(packages)
+	for _, pkg := range packages {
+		c := &step.TestCaseResult{
+			Name:   pkg,
+			Suite:  pkg,
+			Status: step.TestCaseStatusFailed,
+		}
+		if output, ok := outputs[caseKey{pkg: pkg}]; ok {
+			c.Message = truncateMessage(output.String())
+		}
+		cases = append(cases, c)
+	}
+	return cases, nil
+}
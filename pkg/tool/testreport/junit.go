@@ -0,0 +1,159 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testreport
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/koderover/zadig/pkg/types/step"
+)
+
+type junitTestSuites struct {
+	Suites []*junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name   string            `xml:"name,attr"`
+	Suites []*junitTestSuite `xml:"testsuite"`
+	Cases  []*junitTestCase  `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string          `xml:"name,attr"`
+	ClassName string          `xml:"classname,attr"`
+	Time      string          `xml:"time,attr"`
+	Failures  []*junitMessage `xml:"failure"`
+	Errors    []*junitMessage `xml:"error"`
+	Skipped   *junitMessage   `xml:"skipped"`
+	// reruns of maven surefire, the case passed in a rerun if it only has flaky failures
+	FlakyFailures []*junitMessage `xml:"flakyFailure"`
+	FlakyErrors   []*junitMessage `xml:"flakyError"`
+	RerunFailures []*junitMessage `xml:"rerunFailure"`
+	RerunErrors   []*junitMessage `xml:"rerunError"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+func (m *junitMessage) String() string {
+	text := strings.TrimSpace(m.Text)
+	if m.Message == "" || strings.Contains(text, m.Message) {
+		return text
+	}
+	if text == "" {
+		return m.Message
+	}
+	return m.Message + "\n" + text
+}
+
+// ParseJUnit parses the junit xml with the root of either testsuites or testsuite,
+// which covers the reports written by ginkgo, pytest, maven surefire and most of the other runners.
+func ParseJUnit(content []byte) ([]*step.TestCaseResult, error) {
+	root, err := rootElement(content)
+	if err != nil {
+		return nil, err
+	}
+	var suites []*junitTestSuite
+	switch strings.ToLower(root) {
+	case "testsuites":
+		result := &junitTestSuites{}
+		if err := xml.Unmarshal(content, result); err != nil {
+			return nil, err
+		}
+		suites = result.Suites
+	case "testsuite":
+		result := &junitTestSuite{}
+		if err := xml.Unmarshal(content, result); err != nil {
+			return nil, err
+		}
+		suites = []*junitTestSuite{result}
+	default:
+		return nil, fmt.Errorf("unknown junit root element %s", root)
+	}
+
+	cases := make([]*step.TestCaseResult, 0)
+	var walk func(suite *junitTestSuite)
+	walk = func(suite *junitTestSuite) {
+		for _, tc := range suite.Cases {
+			cases = append(cases, tc.toCaseResult(suite.Name))
+		}
+		for _, sub := range suite.Suites {
+			walk(sub)
+		}
+	}
+	for _, suite := range suites {
+		walk(suite)
+	}
+	return cases, nil
+}
+
+func (tc *junitTestCase) toCaseResult(suiteName string) *step.TestCaseResult {
+	c := &step.TestCaseResult{
+		Name:     tc.Name,
+		Suite:    tc.ClassName,
+		Status:   step.TestCaseStatusPassed,
+		Duration: parseSeconds(tc.Time),
+	}
+	if c.Suite == "" {
+		c.Suite = suiteName
+	}
+	failures := append(append(append(tc.Failures, tc.Errors...), tc.RerunFailures...), tc.RerunErrors...)
+	switch {
+	case len(failures) > 0:
+		c.Status = step.TestCaseStatusFailed
+		c.Message = truncateMessage(failures[0].String())
+	case tc.Skipped != nil:
+		c.Status = step.TestCaseStatusSkipped
+		c.Message = truncateMessage(tc.Skipped.String())
+	case len(tc.FlakyFailures) > 0 || len(tc.FlakyErrors) > 0:
+		c.Flaky = true
+	}
+	return c
+}
+
+func rootElement(content []byte) (string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return "", fmt.Errorf("no xml element is found")
+		}
+		if err != nil {
+			return "", err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start.Name.Local, nil
+		}
+	}
+}
+
+// parseSeconds parses the time attribute, some runners format it with thousands separators
+func parseSeconds(value string) float64 {
+	seconds, err := strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(value), ",", ""), 64)
+	if err != nil {
+		return 0
+	}
+	return seconds
+}
@@ -0,0 +1,170 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package testreport parses the test results and coverage of different test runners into step.TestReport
+package testreport
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/koderover/zadig/pkg/types/step"
+)
+
+// maxMessageLength limits the failure message kept for each case, the tail of the message is kept
+const maxMessageLength = 4096
+
+// DetectFormat returns the format of the test result file
+func DetectFormat(filename string, content []byte) string {
+	if strings.EqualFold(filepath.Ext(filename), ".trx") {
+		return step.TestReportFormatTRX
+	}
+	trimmed := bytes.TrimSpace(content)
+	if bytes.HasPrefix(trimmed, []byte("<")) {
+		if bytes.Contains(trimmed, []byte("<TestRun")) {
+			return step.TestReportFormatTRX
+		}
+		return step.TestReportFormatJUnit
+	}
+	// the output of go test may start with the build output of the packages
+	if bytes.Contains(trimmed, []byte(`"Action":`)) {
+		return step.TestReportFormatGoTest
+	}
+	return step.TestReportFormatJUnit
+}
+
+// DetectCoverageFormat returns the format of the coverage file
+func DetectCoverageFormat(filename string, content []byte) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".info", ".lcov":
+		return step.CoverageFormatLCOV
+	case ".xml":
+		return step.CoverageFormatCobertura
+	}
+	trimmed := bytes.TrimSpace(content)
+	if bytes.HasPrefix(trimmed, []byte("TN:")) || bytes.HasPrefix(trimmed, []byte("SF:")) {
+		return step.CoverageFormatLCOV
+	}
+	return step.CoverageFormatCobertura
+}
+
+// ParseTestResult parses the cases of the test result file, the format is detected if it is empty or auto.
+// The format actually used is returned.
+func ParseTestResult(format, filename string, content []byte) (string, []*step.TestCaseResult, error) {
+	if format == "" || format == step.TestReportFormatAuto {
+		format = DetectFormat(filename, content)
+	}
+	var cases []*step.TestCaseResult
+	var err error
+	switch format {
+	case step.TestReportFormatGoTest:
+		cases, err = ParseGoTest(content)
+	case step.TestReportFormatJUnit:
+		cases, err = ParseJUnit(content)
+	case step.TestReportFormatTRX:
+		cases, err = ParseTRX(content)
+	default:
+		return format, nil, fmt.Errorf("unknown test report format %s", format)
+	}
+	return format, cases, err
+}
+
+// ParseCoverage parses the coverage file, the format is detected if it is empty or auto
+func ParseCoverage(format, filename string, content []byte) (*step.TestCoverage, error) {
+	if format == "" || format == step.CoverageFormatAuto {
+		format = DetectCoverageFormat(filename, content)
+	}
+	switch format {
+	case step.CoverageFormatCobertura:
+		return ParseCobertura(content)
+	case step.CoverageFormatLCOV:
+		return ParseLCOV(content)
+	default:
+		return nil, fmt.Errorf("unknown coverage format %s", format)
+	}
+}
+
+// Summarize counts the cases of the report
+func Summarize(report *step.TestReport) {
+	report.Tests, report.Failures, report.Skips, report.Flakes, report.Duration = 0, 0, 0, 0, 0
+	for _, c := range report.Cases {
+		report.Tests++
+		report.Duration += c.Duration
+		switch c.Status {
+		case step.TestCaseStatusFailed:
+			report.Failures++
+		case step.TestCaseStatusSkipped:
+			report.Skips++
+		}
+		if c.Flaky {
+			report.Flakes++
+		}
+	}
+}
+
+// Quarantine marks the cases by their full names, and counts the failures of them
+func Quarantine(report *step.TestReport, caseNames []string) {
+	if len(caseNames) == 0 {
+		return
+	}
+	quarantined := make(map[string]bool, len(caseNames))
+	for _, name := range caseNames {
+		quarantined[name] = true
+	}
+	report.QuarantinedFailures = 0
+	for _, c := range report.Cases {
+		c.Quarantined = quarantined[c.FullName()]
+		if c.Quarantined && c.Status == step.TestCaseStatusFailed {
+			report.QuarantinedFailures++
+		}
+	}
+}
+
+// MergeCoverage adds the counts of the coverage to the total, the rates are recalculated by the counts.
+// The rates of the coverage are kept if the counts are unknown.
+func MergeCoverage(total, coverage *step.TestCoverage) *step.TestCoverage {
+	if coverage == nil {
+		return total
+	}
+	if total == nil {
+		total = &step.TestCoverage{}
+	}
+	total.LinesCovered += coverage.LinesCovered
+	total.LinesValid += coverage.LinesValid
+	total.BranchesCovered += coverage.BranchesCovered
+	total.BranchesValid += coverage.BranchesValid
+	if total.LinesValid > 0 {
+		total.LineRate = float64(total.LinesCovered) / float64(total.LinesValid)
+	} else {
+		total.LineRate = coverage.LineRate
+	}
+	if total.BranchesValid > 0 {
+		total.BranchRate = float64(total.BranchesCovered) / float64(total.BranchesValid)
+	} else {
+		total.BranchRate = coverage.BranchRate
+	}
+	return total
+}
+
+func truncateMessage(message string) string {
+	message = strings.TrimSpace(message)
+	if len(message) <= maxMessageLength {
+		return message
+	}
+	return message[len(message)-maxMessageLength:]
+}
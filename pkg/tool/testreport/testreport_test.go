@@ -0,0 +1,179 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testreport
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/koderover/zadig/pkg/types/step"
+)
+
+const goTestOutput = `# github.com/koderover/broken
+{"Action":"run","Package":"github.com/koderover/app","Test":"TestAdd"}
+{"Action":"output","Package":"github.com/koderover/app","Test":"TestAdd","Output":"=== RUN   TestAdd\n"}
+{"Action":"pass","Package":"github.com/koderover/app","Test":"TestAdd","Elapsed":0.01}
+{"Action":"run","Package":"github.com/koderover/app","Test":"TestSub"}
+{"Action":"output","Package":"github.com/koderover/app","Test":"TestSub","Output":"    app_test.go:12: expected 1, got 2\n"}
+{"Action":"fail","Package":"github.com/koderover/app","Test":"TestSub","Elapsed":0.02}
+{"Action":"skip","Package":"github.com/koderover/app","Test":"TestSkip","Elapsed":0}
+{"Action":"fail","Package":"github.com/koderover/app","Elapsed":0.05}
+{"Action":"output","Package":"github.com/koderover/broken","Output":"FAIL\tgithub.com/koderover/broken [build failed]\n"}
+{"Action":"fail","Package":"github.com/koderover/broken","Elapsed":0}
+`
+
+const pytestJUnit = `<?xml version="1.0" encoding="utf-8"?>
+<testsuites><testsuite name="pytest" errors="1" failures="1" skipped="1" tests="4" time="1.5">
+<testcase classname="tests.test_app" name="test_ok" time="0.5"/>
+<testcase classname="tests.test_app" name="test_fail" time="0.6"><failure message="assert 1 == 2">def test_fail():
+&gt;       assert 1 == 2</failure></testcase>
+<testcase classname="tests.test_app" name="test_error" time="0.1"><error message="fixture not found"/></testcase>
+<testcase classname="tests.test_app" name="test_skip" time="0"><skipped type="pytest.skip" message="not ready"/></testcase>
+</testsuite></testsuites>`
+
+const surefireJUnit = `<?xml version="1.0" encoding="UTF-8"?>
+<testsuite name="com.koderover.AppTest" time="1,001.5" tests="2" errors="0" skipped="0" failures="0">
+<testcase name="testRetry" classname="com.koderover.AppTest" time="1,000.5">
+<flakyFailure message="timeout" type="java.lang.AssertionError"/>
+</testcase>
+<testcase name="testOK" classname="com.koderover.AppTest" time="1"/>
+</testsuite>`
+
+const trxReport = `<?xml version="1.0" encoding="utf-8"?>
+<TestRun id="1" xmlns="http://microsoft.com/schemas/VisualStudio/TeamTest/2010">
+<Results>
+<UnitTestResult testId="a" testName="Add" outcome="Passed" duration="00:00:01.5000000"/>
+<UnitTestResult testId="b" testName="Sub" outcome="Failed" duration="00:01:00"><Output><ErrorInfo><Message>Assert.Equal() Failure</Message></ErrorInfo></Output></UnitTestResult>
+<UnitTestResult testId="c" testName="Mul" outcome="NotExecuted"/>
+</Results>
+<TestDefinitions>
+<UnitTest id="a" name="Add"><TestMethod className="App.Tests.MathTest" name="Add"/></UnitTest>
+<UnitTest id="b" name="Sub"><TestMethod className="App.Tests.MathTest" name="Sub"/></UnitTest>
+</TestDefinitions>
+</TestRun>`
+
+func TestParseTestResult(t *testing.T) {
+	format, cases, err := ParseTestResult(step.TestReportFormatAuto, "report.json", []byte(goTestOutput))
+	require.NoError(t, err)
+	require.Equal(t, step.TestReportFormatGoTest, format)
+	require.Len(t, cases, 4)
+	require.Equal(t, step.TestCaseStatusPassed, cases[0].Status)
+	require.Equal(t, "github.com/koderover/app.TestAdd", cases[0].FullName())
+	require.Equal(t, step.TestCaseStatusFailed, cases[1].Status)
+	require.Contains(t, cases[1].Message, "expected 1, got 2")
+	require.Equal(t, step.TestCaseStatusSkipped, cases[2].Status)
+	require.Equal(t, "github.com/koderover/broken", cases[3].Name)
+	require.Contains(t, cases[3].Message, "build failed")
+
+	format, cases, err = ParseTestResult("", "report.xml", []byte(pytestJUnit))
+	require.NoError(t, err)
+	require.Equal(t, step.TestReportFormatJUnit, format)
+	report := &step.TestReport{Cases: cases}
+	Summarize(report)
+	require.Equal(t, 4, report.Tests)
+	require.Equal(t, 2, report.Failures)
+	require.Equal(t, 1, report.Skips)
+	require.InDelta(t, 1.2, report.Duration, 0.0001)
+	require.Equal(t, "tests.test_app", cases[1].Suite)
+	require.Contains(t, cases[1].Message, "assert 1 == 2")
+	require.Equal(t, "fixture not found", cases[2].Message)
+
+	_, cases, err = ParseTestResult(step.TestReportFormatAuto, "TEST-com.koderover.AppTest.xml", []byte(surefireJUnit))
+	require.NoError(t, err)
+	require.Len(t, cases, 2)
+	require.Equal(t, step.TestCaseStatusPassed, cases[0].Status)
+	require.True(t, cases[0].Flaky)
+	require.Equal(t, 1000.5, cases[0].Duration)
+
+	format, cases, err = ParseTestResult(step.TestReportFormatAuto, "result.trx", []byte(trxReport))
+	require.NoError(t, err)
+	require.Equal(t, step.TestReportFormatTRX, format)
+	require.Len(t, cases, 3)
+	require.Equal(t, "App.Tests.MathTest", cases[0].Suite)
+	require.Equal(t, 1.5, cases[0].Duration)
+	require.Equal(t, step.TestCaseStatusFailed, cases[1].Status)
+	require.Equal(t, 60.0, cases[1].Duration)
+	require.Equal(t, "Assert.Equal() Failure", cases[1].Message)
+	require.Equal(t, step.TestCaseStatusSkipped, cases[2].Status)
+}
+
+func TestQuarantine(t *testing.T) {
+	_, cases, err := ParseTestResult(step.TestReportFormatJUnit, "report.xml", []byte(pytestJUnit))
+	require.NoError(t, err)
+	report := &step.TestReport{Cases: cases}
+	Summarize(report)
+	Quarantine(report, []string{"tests.test_app.test_fail", "tests.test_app.test_ok"})
+	require.Equal(t, 2, report.Failures)
+	require.Equal(t, 1, report.QuarantinedFailures)
+	require.True(t, cases[0].Quarantined)
+	require.True(t, cases[1].Quarantined)
+	require.False(t, cases[2].Quarantined)
+}
+
+const coberturaWithCounts = `<?xml version="1.0" ?>
+<coverage line-rate="0.75" branch-rate="0.5" lines-covered="30" lines-valid="40" branches-covered="5" branches-valid="10" version="6.5"></coverage>`
+
+const coberturaWithLines = `<?xml version="1.0" ?>
+<coverage line-rate="0.5" branch-rate="0.5"><packages><package name="app"><classes><class name="App" filename="app.go"><lines>
+<line number="1" hits="1"/>
+<line number="2" hits="0"/>
+<line number="3" hits="2" branch="true" condition-coverage="50% (1/2)"/>
+<line number="4" hits="0"/>
+</lines></class></classes></package></packages></coverage>`
+
+const lcovTracefile = `TN:
+SF:src/app.js
+FNF:2
+FNH:1
+LF:10
+LH:8
+BRF:4
+BRH:1
+end_of_record
+SF:src/util.js
+LF:10
+LH:2
+end_of_record
+`
+
+func TestParseCoverage(t *testing.T) {
+	coverage, err := ParseCoverage(step.CoverageFormatAuto, "coverage.xml", []byte(coberturaWithCounts))
+	require.NoError(t, err)
+	require.Equal(t, 40, coverage.LinesValid)
+	require.Equal(t, 0.75, coverage.LineRate)
+
+	lineCoverage, err := ParseCoverage(step.CoverageFormatCobertura, "coverage.xml", []byte(coberturaWithLines))
+	require.NoError(t, err)
+	require.Equal(t, 2, lineCoverage.LinesCovered)
+	require.Equal(t, 4, lineCoverage.LinesValid)
+	require.Equal(t, 1, lineCoverage.BranchesCovered)
+	require.Equal(t, 2, lineCoverage.BranchesValid)
+
+	lcov, err := ParseCoverage(step.CoverageFormatAuto, "lcov.info", []byte(lcovTracefile))
+	require.NoError(t, err)
+	require.Equal(t, 10, lcov.LinesCovered)
+	require.Equal(t, 20, lcov.LinesValid)
+	require.Equal(t, 0.5, lcov.LineRate)
+	require.Equal(t, 0.25, lcov.BranchRate)
+
+	total := MergeCoverage(nil, coverage)
+	total = MergeCoverage(total, lcov)
+	require.Equal(t, 40, total.LinesCovered)
+	require.Equal(t, 60, total.LinesValid)
+	require.InDelta(t, 0.6667, total.LineRate, 0.0001)
+}
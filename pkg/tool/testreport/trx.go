@@ -0,0 +1,95 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package testreport
+
+import (
+	"encoding/xml"
+	"strconv"
+	"strings"
+
+	"github.com/koderover/zadig/pkg/types/step"
+)
+
+// trxTestRun is the visual studio test result written by `dotnet test --logger trx`
+type trxTestRun struct {
+	Results     []*trxResult   `xml:"Results>UnitTestResult"`
+	Definitions []*trxUnitTest `xml:"TestDefinitions>UnitTest"`
+}
+
+type trxResult struct {
+	TestID     string `xml:"testId,attr"`
+	TestName   string `xml:"testName,attr"`
+	Outcome    string `xml:"outcome,attr"`
+	Duration   string `xml:"duration,attr"`
+	Message    string `xml:"Output>ErrorInfo>Message"`
+	StackTrace string `xml:"Output>ErrorInfo>StackTrace"`
+}
+
+type trxUnitTest struct {
+	ID     string `xml:"id,attr"`
+	Method struct {
+		ClassName string `xml:"className,attr"`
+	} `xml:"TestMethod"`
+}
+
+func ParseTRX(content []byte) ([]*step.TestCaseResult, error) {
+	run := &trxTestRun{}
+	if err := xml.Unmarshal(content, run); err != nil {
+		return nil, err
+	}
+	classNames := make(map[string]string, len(run.Definitions))
+	for _, def := range run.Definitions {
+		classNames[def.ID] = def.Method.ClassName
+	}
+
+	cases := make([]*step.TestCaseResult, 0, len(run.Results))
+	for _, result := range run.Results {
+		c := &step.TestCaseResult{
+			Name:     result.TestName,
+			Suite:    classNames[result.TestID],
+			Duration: parseTRXDuration(result.Duration),
+		}
+		switch strings.ToLower(result.Outcome) {
+		case "passed", "passedbutrunaborted":
+			c.Status = step.TestCaseStatusPassed
+		case "notexecuted", "inconclusive", "pending", "notrunnable", "disconnected":
+			c.Status = step.TestCaseStatusSkipped
+		default:
+			c.Status = step.TestCaseStatusFailed
+			c.Message = truncateMessage(strings.TrimSpace(result.Message + "\n" + result.StackTrace))
+		}
+		cases = append(cases, c)
+	}
+	return cases, nil
+}
+
+// parseTRXDuration parses the duration in the format of hh:mm:ss.fffffff
+func parseTRXDuration(value string) float64 {
+	parts := strings.Split(strings.TrimSpace(value), ":")
+	if len(parts) != 3 {
+		return 0
+	}
+	var seconds float64
+	for _, part := range parts {
+		n, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return 0
+		}
+		seconds = seconds*60 + n
+	}
+	return seconds
+}
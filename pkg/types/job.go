@@ -26,9 +26,14 @@ const (
 const (
 	JobResultKey  = "job-result"
 	JobOutputsKey = "job-outputs"
+	// JobExitCodeKey is the exit code of the failed step, it is set only when the step exited with non-zero code
+	JobExitCodeKey = "job-exit-code"
 
 	JobDebugStatusKey    = "job-debug-status"
 	JobDebugStatusBefore = "before"
 	JobDebugStatusAfter  = "after"
 	JobDebugStatusNotIn  = "not-in"
+	// JobDebugStatusOnFailure means the job failed and the pod is kept for debugging until the breakpoint is removed
+	// or the debug ttl expires
+	JobDebugStatusOnFailure = "on_failure"
 )
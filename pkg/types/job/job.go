@@ -28,11 +28,35 @@ const (
 	JobTerminationFile = "/zadig/termination"
 )
 
+const (
+	OutputTypeString = "string"
+	OutputTypeNumber = "number"
+	OutputTypeBool   = "bool"
+	// OutputTypeJSON outputs can be referenced by json path, like {{.job.jobKey.output.outputName.a.b.0}}
+	OutputTypeJSON = "json"
+	// OutputTypeFile outputs are the paths of the files in the workspace or share storage
+	OutputTypeFile = "file"
+
+	// MaxOutputValueLength is the max length of an output value, all the outputs of a job are kept within the
+	// 4096 bytes of the container termination message
+	MaxOutputValueLength = 2048
+)
+
 type JobOutput struct {
 	Name  string `json:"name"`
 	Value string `json:"value"`
 }
 
+// OutputSpec is the declared type of a job output, the values are validated against it when the job finishes
+type OutputSpec struct {
+	Name string `json:"name"       yaml:"name"`
+	Type string `json:"type"       yaml:"type"`
+	// Schema is the json schema of json outputs
+	Schema string `json:"schema"     yaml:"schema"`
+	// MaxLength is MaxOutputValueLength if it is 0
+	MaxLength int `json:"max_length" yaml:"max_length"`
+}
+
 func GetJobOutputKey(key, outputName string) string {
 	return fmt.Sprintf(setting.RenderValueTemplate, strings.Join([]string{"job", key, "output", outputName}, "."))
 }
@@ -0,0 +1,66 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package step
+
+const (
+	ArtifactTypeMaven = "maven"
+	ArtifactTypeNpm   = "npm"
+	ArtifactTypePyPI  = "pypi"
+	// ArtifactTypeOCI pushes arbitrary files as an oci artifact with oras
+	ArtifactTypeOCI = "oci"
+
+	// DefaultArtifactOutput is the job output the coordinates of the published artifacts are appended to, separated by ","
+	DefaultArtifactOutput = "ARTIFACTS"
+)
+
+type StepArtifactPublishSpec struct {
+	Type string `bson:"type"                       json:"type"                              yaml:"type"`
+	// RegistryURL is the url of the maven repository, npm registry or pypi repository, or the host of the oci registry
+	RegistryURL string `bson:"registry_url"               json:"registry_url"                      yaml:"registry_url"`
+	Insecure    bool   `bson:"insecure"                   json:"insecure"                          yaml:"insecure"`
+	// UsernameEnv and PasswordEnv are the secret envs of the registry credential, the password is used as the token
+	// if the username is empty
+	UsernameEnv string `bson:"username_env"               json:"username_env"                      yaml:"username_env"`
+	PasswordEnv string `bson:"password_env"               json:"password_env"                      yaml:"password_env"`
+	// Path is the dir of the maven or npm project, and the dir the Files are relative to, relative to the workspace
+	Path string `bson:"path"                       json:"path"                              yaml:"path"`
+	// Files are the globs of the pypi distributions, dist/* by default, or the files of the oci artifact
+	Files []string `bson:"files"                      json:"files"                             yaml:"files"`
+	// Repository, Tag and ArtifactType describe the oci artifact
+	Repository   string `bson:"repository"                 json:"repository"                        yaml:"repository"`
+	Tag          string `bson:"tag"                        json:"tag"                               yaml:"tag"`
+	ArtifactType string `bson:"artifact_type"              json:"artifact_type"                     yaml:"artifact_type"`
+	// OutputName is the job output of the coordinates, DefaultArtifactOutput by default
+	OutputName string `bson:"output_name"                json:"output_name"                       yaml:"output_name"`
+}
+
+// GetOutputName returns the job output of the coordinates
+func (s *StepArtifactPublishSpec) GetOutputName() string {
+	if s.OutputName == "" {
+		return DefaultArtifactOutput
+	}
+	return s.OutputName
+}
+
+// IsValidArtifactType reports whether the artifact type is supported by the artifact publish step
+func IsValidArtifactType(artifactType string) bool {
+	switch artifactType {
+	case ArtifactTypeMaven, ArtifactTypeNpm, ArtifactTypePyPI, ArtifactTypeOCI:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,29 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package step
+
+// StepCacheSpec is the spec of both cache_restore and cache_save steps.
+// Key is rendered with the job envs and the hashFiles function, e.g. go-{{ hashFiles "go.sum" "**/go.sum" }},
+// S3DestDir is scoped to the repo of the job, so the jobs of all the branches of the repo share the cache.
+type StepCacheSpec struct {
+	Key string `bson:"key"                        json:"key"                               yaml:"key"`
+	// RestoreKeys are the key prefixes used by cache_restore if Key does not hit, the latest matched cache is restored
+	RestoreKeys []string `bson:"restore_keys"               json:"restore_keys"                      yaml:"restore_keys"`
+	Paths       []string `bson:"paths"                      json:"paths"                             yaml:"paths"`
+	S3DestDir   string   `bson:"s3_dest_dir"                json:"s3_dest_dir"                       yaml:"s3_dest_dir"`
+	S3Storage   *S3      `bson:"s3_storage"                 json:"s3_storage"                        yaml:"s3_storage"`
+}
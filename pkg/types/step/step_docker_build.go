@@ -22,6 +22,16 @@ import (
 	"github.com/koderover/zadig/pkg/setting"
 )
 
+const (
+	// DockerBuilderModeDocker builds the image with the docker daemon of the dind service, it is the default mode
+	DockerBuilderModeDocker = "docker"
+	// DockerBuilderModeBuildKit builds the image with the rootless buildkitd sidecar of the job pod
+	DockerBuilderModeBuildKit = "buildkit"
+
+	// BuildKitHostEnv is the env of the address of the rootless buildkitd sidecar, it is set in the job container by aslan
+	BuildKitHostEnv = "BUILDKIT_HOST"
+)
+
 type StepDockerBuildSpec struct {
 	Source                string          `bson:"source"                              json:"source"                                 yaml:"source"`
 	WorkDir               string          `bson:"work_dir"                            json:"work_dir"                               yaml:"work_dir"`
@@ -34,6 +44,22 @@ type StepDockerBuildSpec struct {
 	Proxy                 *Proxy          `bson:"proxy"                               json:"proxy"                                  yaml:"proxy"`
 	IgnoreCache           bool            `bson:"ignore_cache"                        json:"ignore_cache"                           yaml:"ignore_cache"`
 	DockerRegistry        *DockerRegistry `bson:"docker_registry"                     json:"docker_registry"                        yaml:"docker_registry"`
+	// BuilderMode is one of docker and buildkit, docker is used if it is empty
+	BuilderMode string `bson:"builder_mode"                        json:"builder_mode"                           yaml:"builder_mode"`
+	// BuildKitUnconfined runs the buildkitd sidecar with the unconfined seccomp and apparmor profiles, which is
+	// required by rootless buildkit if the default profiles of the cluster block the creation of user namespaces
+	BuildKitUnconfined bool `bson:"buildkit_unconfined"                 json:"buildkit_unconfined"                    yaml:"buildkit_unconfined"`
+	// Platforms are the comma separated target platforms, e.g. linux/amd64,linux/arm64
+	Platforms string `bson:"platforms"                           json:"platforms"                              yaml:"platforms"`
+	// CacheRepo is the registry repository the layer cache is imported from and exported to
+	CacheRepo string               `bson:"cache_repo"                          json:"cache_repo"                             yaml:"cache_repo"`
+	Secrets   []*DockerBuildSecret `bson:"secrets"                             json:"secrets"                                yaml:"secrets"`
+}
+
+// DockerBuildSecret is mounted in RUN --mount=type=secret,id=<ID> with the value of the job env Env
+type DockerBuildSecret struct {
+	ID  string `bson:"id"                                json:"id"                                   yaml:"id"`
+	Env string `bson:"env"                               json:"env"                                  yaml:"env"`
 }
 
 type DockerRegistry struct {
@@ -54,3 +80,32 @@ func (s *StepDockerBuildSpec) GetDockerFile() string {
 	}
 	return s.DockerFile
 }
+
+func (s *StepDockerBuildSpec) GetBuilderMode() string {
+	if s.BuilderMode == "" {
+		return DockerBuilderModeDocker
+	}
+	return s.BuilderMode
+}
+
+// ValidateBuilder checks whether the builder mode supports the platforms and secrets of the build
+func (s *StepDockerBuildSpec) ValidateBuilder() error {
+	switch s.GetBuilderMode() {
+	case DockerBuilderModeDocker:
+		if s.Platforms != "" || s.CacheRepo != "" || len(s.Secrets) > 0 {
+			return fmt.Errorf("platforms, cache repo and secrets are only supported by the buildkit builder")
+		}
+		if s.BuildKitUnconfined {
+			return fmt.Errorf("unconfined security profiles are only used by the buildkit builder")
+		}
+	case DockerBuilderModeBuildKit:
+	default:
+		return fmt.Errorf("unknown builder mode %s", s.BuilderMode)
+	}
+	for _, secret := range s.Secrets {
+		if secret.ID == "" || secret.Env == "" {
+			return fmt.Errorf("id and env of the build secret should not be empty")
+		}
+	}
+	return nil
+}
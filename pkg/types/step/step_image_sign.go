@@ -0,0 +1,60 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package step
+
+const (
+	// ImageSignModeKey signs images with a cosign key pair
+	ImageSignModeKey = "key"
+	// ImageSignModeKeyless signs images with a short-lived certificate issued by fulcio to the oidc identity of the job
+	ImageSignModeKeyless = "keyless"
+
+	DefaultIdentityTokenEnv = "SIGSTORE_ID_TOKEN"
+)
+
+type StepImageSignSpec struct {
+	// Images are the images to sign, env variables such as $IMAGE are replaced
+	Images []string `bson:"images"                     json:"images"                            yaml:"images"`
+	// ImagesFromStep signs the target images of the distribute image step with the name in the same job
+	ImagesFromStep   string            `bson:"images_from_step"           json:"images_from_step"                  yaml:"images_from_step"`
+	SigningKeyID     string            `bson:"signing_key_id"             json:"signing_key_id"                    yaml:"signing_key_id"`
+	SigningKey       *ImageSigningKey  `bson:"signing_key"                json:"signing_key"                       yaml:"signing_key"`
+	DockerRegistries []*DockerRegistry `bson:"docker_registries"          json:"docker_registries"                 yaml:"docker_registries"`
+}
+
+type ImageSigningKey struct {
+	Name string `bson:"name"                       json:"name"                              yaml:"name"`
+	Mode string `bson:"mode"                       json:"mode"                              yaml:"mode"`
+	// the encrypted cosign private key and its password are passed in the secret envs named by ImageSigningKeyEnvs,
+	// so they are not saved in the workflow task
+	// the transparency log is skipped for key mode if RekorURL is empty
+	FulcioURL string `bson:"fulcio_url"                 json:"fulcio_url"                        yaml:"fulcio_url"`
+	RekorURL  string `bson:"rekor_url"                  json:"rekor_url"                         yaml:"rekor_url"`
+	// IdentityTokenEnv is the env of the job holding the oidc token exchanged for the signing certificate
+	IdentityTokenEnv string `bson:"identity_token_env"         json:"identity_token_env"                yaml:"identity_token_env"`
+}
+
+// ImageSigningKeyEnvs returns the names of the secret envs holding the private key and the password of the signing key
+func ImageSigningKeyEnvs(signingKeyID string) (string, string) {
+	return "ZADIG_COSIGN_KEY_" + signingKeyID, "ZADIG_COSIGN_PASSWORD_" + signingKeyID
+}
+
+func (k *ImageSigningKey) GetIdentityTokenEnv() string {
+	if k.IdentityTokenEnv == "" {
+		return DefaultIdentityTokenEnv
+	}
+	return k.IdentityTokenEnv
+}
@@ -23,4 +23,6 @@ type StepJunitReportSpec struct {
 	FileName  string `bson:"file_name"                  json:"file_name"                         yaml:"file_name"`
 	TestName  string `bson:"test_name"                  json:"test_name"                         yaml:"test_name"`
 	S3Storage *S3    `bson:"s3_storage"                 json:"s3_storage"                        yaml:"s3_storage"`
+	// IgnoreFailedCases is set if the failed cases are checked by the test report step, which knows the quarantined cases
+	IgnoreFailedCases bool `bson:"ignore_failed_cases"        json:"ignore_failed_cases"               yaml:"ignore_failed_cases"`
 }
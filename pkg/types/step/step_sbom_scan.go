@@ -0,0 +1,86 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package step
+
+import "strings"
+
+const (
+	SBOMFormatSPDX      = "spdx-json"
+	SBOMFormatCycloneDX = "cyclonedx-json"
+
+	SBOMFileName                = "sbom.json"
+	VulnerabilityReportFileName = "vulnerability-report.json"
+)
+
+// severities from low to high, SeverityThreshold of the scan is one of them
+var vulnerabilitySeverities = []string{"negligible", "low", "medium", "high", "critical"}
+
+type StepSBOMScanSpec struct {
+	// ImageName is the image to scan, usually the $IMAGE built by the docker build step
+	ImageName  string `bson:"image_name"                 json:"image_name"                        yaml:"image_name"`
+	SBOMFormat string `bson:"sbom_format"                json:"sbom_format"                       yaml:"sbom_format"`
+	// SeverityThreshold fails the step if any vulnerability of the severity or higher is found, the step never fails if it is empty
+	SeverityThreshold string `bson:"severity_threshold"         json:"severity_threshold"                yaml:"severity_threshold"`
+	// VulnDBURL is the listing url of the locally mirrored vulnerability database
+	VulnDBURL string `bson:"vuln_db_url"                json:"vuln_db_url"                       yaml:"vuln_db_url"`
+	// VulnDBCacheDir keeps the vulnerability database between the scans, it is on the build cache volume if the cache is enabled
+	VulnDBCacheDir string          `bson:"vuln_db_cache_dir"          json:"vuln_db_cache_dir"                 yaml:"vuln_db_cache_dir"`
+	DockerRegistry *DockerRegistry `bson:"docker_registry"            json:"docker_registry"                   yaml:"docker_registry"`
+	S3DestDir      string          `bson:"s3_dest_dir"                json:"s3_dest_dir"                       yaml:"s3_dest_dir"`
+	S3Storage      *S3             `bson:"s3_storage"                 json:"s3_storage"                        yaml:"s3_storage"`
+}
+
+// ImageVulnerabilityReport is the normalized result of the image scan, it is uploaded with the sbom
+type ImageVulnerabilityReport struct {
+	ImageName         string                  `bson:"image_name"                 json:"image_name"                        yaml:"image_name"`
+	SeverityThreshold string                  `bson:"severity_threshold"         json:"severity_threshold"                yaml:"severity_threshold"`
+	Passed            bool                    `bson:"passed"                     json:"passed"                            yaml:"passed"`
+	SeverityCount     map[string]int          `bson:"severity_count"             json:"severity_count"                    yaml:"severity_count"`
+	Findings          []*VulnerabilityFinding `bson:"findings"                   json:"findings"                          yaml:"findings"`
+}
+
+type VulnerabilityFinding struct {
+	ID       string `bson:"id"                         json:"id"                                yaml:"id"`
+	Severity string `bson:"severity"                   json:"severity"                          yaml:"severity"`
+	Package  string `bson:"package"                    json:"package"                           yaml:"package"`
+	Version  string `bson:"version"                    json:"version"                           yaml:"version"`
+	Type     string `bson:"type"                       json:"type"                              yaml:"type"`
+	FixedIn  string `bson:"fixed_in"                   json:"fixed_in"                          yaml:"fixed_in"`
+	URL      string `bson:"url"                        json:"url"                               yaml:"url"`
+}
+
+func (s *StepSBOMScanSpec) GetSBOMFormat() string {
+	if s.SBOMFormat == "" {
+		return SBOMFormatSPDX
+	}
+	return s.SBOMFormat
+}
+
+// IsValidSeverity returns true if the severity is empty or a known severity
+func IsValidSeverity(severity string) bool {
+	return severity == "" || SeverityLevel(severity) >= 0
+}
+
+// SeverityLevel returns the level of the severity, higher is more severe, -1 is returned for unknown severities
+func SeverityLevel(severity string) int {
+	for i, s := range vulnerabilitySeverities {
+		if strings.EqualFold(s, severity) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,54 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package step
+
+const (
+	// TestImpactFullEnv is "true" if the full suite should be run
+	TestImpactFullEnv = "TEST_IMPACT_FULL"
+	// TestImpactTargetsEnv is the space separated test targets affected by the changed files
+	TestImpactTargetsEnv = "TEST_IMPACT_TARGETS"
+	// TestImpactFileName is the default file in the workspace listing the affected targets, one per line
+	TestImpactFileName = "test-impact-targets.txt"
+)
+
+type StepTestImpactSpec struct {
+	// ChangedFiles are the files changed by the pull request, relative to RepoDir.
+	// The full suite is run if it is empty, e.g. the task is not triggered by a pull request.
+	ChangedFiles []string `bson:"changed_files"              json:"changed_files"                     yaml:"changed_files"`
+	// RepoDir is the directory of the changed repo, relative to the workspace
+	RepoDir  string               `bson:"repo_dir"                   json:"repo_dir"                          yaml:"repo_dir"`
+	Mappings []*TestImpactMapping `bson:"mappings"                   json:"mappings"                          yaml:"mappings"`
+	// IgnorePaths are the globs of the changed files which affect no test, e.g. docs/**
+	IgnorePaths []string `bson:"ignore_paths"               json:"ignore_paths"                      yaml:"ignore_paths"`
+	// GoImportGraph maps the changed go files to the packages whose tests import them
+	GoImportGraph bool `bson:"go_import_graph"            json:"go_import_graph"                   yaml:"go_import_graph"`
+	// OutputFile is the file relative to the workspace listing the targets, TestImpactFileName by default
+	OutputFile string `bson:"output_file"                json:"output_file"                       yaml:"output_file"`
+}
+
+// TestImpactMapping maps the changed files matching the SourcePaths globs to the test targets
+type TestImpactMapping struct {
+	SourcePaths []string `bson:"source_paths"               json:"source_paths"                      yaml:"source_paths"`
+	Targets     []string `bson:"targets"                    json:"targets"                           yaml:"targets"`
+}
+
+type TestImpactResult struct {
+	Full bool `bson:"full"                       json:"full"                              yaml:"full"`
+	// Reason explains why the full suite is run
+	Reason  string   `bson:"reason"                     json:"reason"                            yaml:"reason"`
+	Targets []string `bson:"targets"                    json:"targets"                           yaml:"targets"`
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package step
+
+const (
+	// TestReportFormatAuto detects the format by the file extension and content
+	TestReportFormatAuto = "auto"
+	// TestReportFormatGoTest is the output of `go test -json` or `go tool test2json`
+	TestReportFormatGoTest = "gotest"
+	// TestReportFormatJUnit covers the junit xml written by ginkgo, pytest, maven surefire and so on
+	TestReportFormatJUnit = "junit"
+	TestReportFormatTRX   = "trx"
+
+	CoverageFormatAuto      = "auto"
+	CoverageFormatCobertura = "cobertura"
+	CoverageFormatLCOV      = "lcov"
+
+	TestCaseStatusPassed  = "passed"
+	TestCaseStatusFailed  = "failed"
+	TestCaseStatusSkipped = "skipped"
+
+	TestReportFileName = "test-report.json"
+)
+
+type StepTestReportSpec struct {
+	// ReportPaths are the paths or glob patterns of the test result files, relative to the workspace
+	ReportPaths []string `bson:"report_paths"               json:"report_paths"                      yaml:"report_paths"`
+	Format      string   `bson:"format"                     json:"format"                            yaml:"format"`
+	// CoveragePaths are the paths or glob patterns of the cobertura xml or lcov files, relative to the workspace
+	CoveragePaths  []string `bson:"coverage_paths"             json:"coverage_paths"                    yaml:"coverage_paths"`
+	CoverageFormat string   `bson:"coverage_format"            json:"coverage_format"                   yaml:"coverage_format"`
+	// FailOnFailedCases fails the step if any case not in QuarantinedCases failed, or any test result can not be parsed
+	FailOnFailedCases bool `bson:"fail_on_failed_cases"       json:"fail_on_failed_cases"              yaml:"fail_on_failed_cases"`
+	// QuarantinedCases are the full names of the flaky cases allowed to fail, set by aslan before the step runs
+	QuarantinedCases []string `bson:"quarantined_cases"          json:"quarantined_cases"                 yaml:"quarantined_cases"`
+	TestName         string   `bson:"test_name"                  json:"test_name"                         yaml:"test_name"`
+	S3DestDir        string   `bson:"s3_dest_dir"                json:"s3_dest_dir"                       yaml:"s3_dest_dir"`
+	S3Storage        *S3      `bson:"s3_storage"                 json:"s3_storage"                        yaml:"s3_storage"`
+}
+
+// TestReport is the normalized result of the test result and coverage files, it is uploaded as TestReportFileName
+type TestReport struct {
+	Tests    int `bson:"tests"                      json:"tests"                             yaml:"tests"`
+	Failures int `bson:"failures"                   json:"failures"                          yaml:"failures"`
+	Skips    int `bson:"skips"                      json:"skips"                             yaml:"skips"`
+	Flakes   int `bson:"flakes"                     json:"flakes"                            yaml:"flakes"`
+	// QuarantinedFailures are the failures of the quarantined cases, which are included in Failures
+	QuarantinedFailures int     `bson:"quarantined_failures"       json:"quarantined_failures"              yaml:"quarantined_failures"`
+	Duration            float64 `bson:"duration"                   json:"duration"                          yaml:"duration"`
+	// Formats are the detected formats of the parsed files
+	Formats  []string          `bson:"formats"                    json:"formats"                           yaml:"formats"`
+	Cases    []*TestCaseResult `bson:"cases"                      json:"cases"                             yaml:"cases"`
+	Coverage *TestCoverage     `bson:"coverage"                   json:"coverage"                          yaml:"coverage"`
+}
+
+type TestCaseResult struct {
+	Name string `bson:"name"                       json:"name"                              yaml:"name"`
+	// Suite is the go package, junit classname or trx class name of the case
+	Suite  string `bson:"suite"                      json:"suite"                             yaml:"suite"`
+	Status string `bson:"status"                     json:"status"                            yaml:"status"`
+	// Duration is in seconds
+	Duration float64 `bson:"duration"                   json:"duration"                          yaml:"duration"`
+	Message  string  `bson:"message"                    json:"message"                           yaml:"message"`
+	// Flaky is true if the case passed after failed runs, reported by the test runner
+	Flaky       bool `bson:"flaky"                      json:"flaky"                             yaml:"flaky"`
+	Quarantined bool `bson:"quarantined"                json:"quarantined"                       yaml:"quarantined"`
+}
+
+type TestCoverage struct {
+	LinesCovered    int     `bson:"lines_covered"              json:"lines_covered"                     yaml:"lines_covered"`
+	LinesValid      int     `bson:"lines_valid"                json:"lines_valid"                       yaml:"lines_valid"`
+	LineRate        float64 `bson:"line_rate"                  json:"line_rate"                         yaml:"line_rate"`
+	BranchesCovered int     `bson:"branches_covered"           json:"branches_covered"                  yaml:"branches_covered"`
+	BranchesValid   int     `bson:"branches_valid"             json:"branches_valid"                    yaml:"branches_valid"`
+	BranchRate      float64 `bson:"branch_rate"                json:"branch_rate"                       yaml:"branch_rate"`
+}
+
+// FullName identifies the case in the history of the test
+func (c *TestCaseResult) FullName() string {
+	if c.Suite == "" {
+		return c.Name
+	}
+	return c.Suite + "." + c.Name
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+const (
+	// JobK8sInfrastructure runs the job as a kubernetes job, it is the default infrastructure
+	JobK8sInfrastructure = "kubernetes"
+	// JobVMInfrastructure runs the job on a self-hosted vm runner whose labels contain all the vm labels of the job
+	JobVMInfrastructure = "vm"
+
+	// VMRunnerTokenHeader is the header of the token the vm runner agent authenticates with
+	VMRunnerTokenHeader = "X-Zadig-Runner-Token"
+)
+
+// VMRunnerRegisterArgs describes the host of the vm runner agent, it is reported when the agent starts
+type VMRunnerRegisterArgs struct {
+	Hostname string `json:"hostname"`
+	IP       string `json:"ip"`
+	OS       string `json:"os"`
+	Arch     string `json:"arch"`
+	Version  string `json:"version"`
+}
+
+// VMJob is a job polled by the vm runner agent, JobCtx is the yaml job context of the jobexecutor
+type VMJob struct {
+	ID     string `json:"id"`
+	JobCtx string `json:"job_ctx"`
+}
+
+// VMJobPollResp is the response of polling jobs, Job is nil if there is no job for the runner
+type VMJobPollResp struct {
+	Job *VMJob `json:"job"`
+}
+
+// VMJobLogArgs is a chunk of the job log, Seq increases from 0 in the order the chunks are sent
+type VMJobLogArgs struct {
+	Seq     int64  `json:"seq"`
+	Content string `json:"content"`
+}
+
+// VMJobLogResp tells the runner whether the job is cancelled or timed out, so it should be stopped
+type VMJobLogResp struct {
+	Cancelled bool `json:"cancelled"`
+}
+
+// VMJobResultArgs is the result of the job, Outputs is the json job outputs of the jobexecutor
+type VMJobResultArgs struct {
+	Result   JobStatus `json:"result"`
+	Outputs  string    `json:"outputs"`
+	ExitCode int       `json:"exit_code"`
+	Error    string    `json:"error"`
+}